@@ -14,22 +14,37 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"log"
+	"net"
+	"net/http"
+	"strings"
 
 	"github.com/gin-gonic/gin"
 	swaggerFiles "github.com/swaggo/files"
 	ginSwagger "github.com/swaggo/gin-swagger"
 
-	_ "github.com/vit0-9/utils_api/docs"   // Your Swagger docs
+	"github.com/vit0-9/utils_api/docs"     // Your Swagger docs
 	"github.com/vit0-9/utils_api/handlers" // Your handlers package
+	"github.com/vit0-9/utils_api/pkg/utils/features"
 )
 
 // App encapsulates all the components of the application
 type App struct {
 	Router              *gin.Engine
+	Server              *http.Server
 	NetIntelHandlers    *handlers.NetworkIntelligenceHandlers
 	URLUtilHandlers     *handlers.URLUtilitiesHandlers
 	WebAnalysisHandlers *handlers.WebAnalysisHandlers
+	BadgeHandlers       *handlers.BadgeHandlers
+	VantageHandlers     *handlers.VantageHandlers
+	PortfolioHandlers   *handlers.PortfolioHandlers
+	ExportHandlers      *handlers.ExportHandlers
+	IngestHandlers      *handlers.IngestHandlers
+	UsageHandlers       *handlers.UsageHandlers
+	MiscHandlers        *handlers.MiscHandlers
+	AdminHandlers       *handlers.AdminHandlers
 	HealthHandler       *handlers.HealthHandler
 }
 
@@ -38,9 +53,29 @@ func NewApp() (*App, error) {
 	netIntelHandlers := handlers.NewNetworkIntelligenceHandlers()
 	urlUtilHandlers := handlers.NewURLUtilitiesHandlers()
 	webAnalysisHandlers := handlers.NewWebAnalysisHandlers()
+	badgeHandlers := handlers.NewBadgeHandlers()
+	vantageHandlers := handlers.NewVantageHandlers()
+	portfolioHandlers := handlers.NewPortfolioHandlers()
+	exportHandlers := handlers.NewExportHandlers()
+	ingestHandlers := handlers.NewIngestHandlers()
+	usageHandlers := handlers.NewUsageHandlers()
+	miscHandlers := handlers.NewMiscHandlers()
+	adminHandlers := handlers.NewAdminHandlers()
 	healthHandler := handlers.NewHealthHandler()
 
-	router := gin.Default()
+	router := gin.New()
+	if handlers.AccessLogEnabled() {
+		router.Use(gin.LoggerWithFormatter(handlers.AccessLogFormatter))
+	}
+	router.Use(handlers.RecoveryMiddleware())
+	router.Use(handlers.FeatureFlagMiddleware())
+	router.Use(handlers.OutboundProxyMiddleware())
+	router.Use(handlers.QuotaMiddleware())
+	router.Use(handlers.CanonicalJSONMiddleware())
+	router.Use(handlers.VantageMiddleware())
+	router.Use(handlers.TimingMiddleware())
+	router.Use(handlers.RequestLogMiddleware())
+	router.Use(handlers.EventLogMiddleware())
 	// Consider your proxy setup for SetTrustedProxies if deploying
 	// err := router.SetTrustedProxies(nil)
 	// if err != nil {
@@ -52,10 +87,26 @@ func NewApp() (*App, error) {
 		NetIntelHandlers:    netIntelHandlers,
 		URLUtilHandlers:     urlUtilHandlers,
 		WebAnalysisHandlers: webAnalysisHandlers,
+		BadgeHandlers:       badgeHandlers,
+		VantageHandlers:     vantageHandlers,
+		PortfolioHandlers:   portfolioHandlers,
+		ExportHandlers:      exportHandlers,
+		IngestHandlers:      ingestHandlers,
+		UsageHandlers:       usageHandlers,
+		MiscHandlers:        miscHandlers,
+		AdminHandlers:       adminHandlers,
 		HealthHandler:       healthHandler,
 	}
 
 	app.setupRoutes()
+	app.Server = &http.Server{Handler: handlers.BasePathHandler(router.Handler())}
+	var paths []string
+	for _, route := range router.Routes() {
+		paths = append(paths, route.Path)
+	}
+	if unmatched := features.Unmatched(paths); len(unmatched) > 0 {
+		log.Printf("WARN: DISABLED_ENDPOINTS entries matching no endpoint: %s", strings.Join(unmatched, ", "))
+	}
 	return app, nil
 }
 
@@ -64,41 +115,163 @@ func (app *App) setupRoutes() {
 	// Health check endpoint (can be top-level)
 	// For Swagger, this will be documented relative to @host if its @Router path starts with /
 	app.Router.GET("/api/v1/health", app.HealthHandler.HealthCheckHandler)
+	app.Router.GET("/api/v1/health/detailed", app.HealthHandler.DetailedHealthCheckHandler)
+	app.Router.GET("/api/v1/me/usage", app.UsageHandlers.UsageHandler)
 
 	// Group for Network & Domain Intelligence utilities
 	// These will be prefixed by @BasePath /api/v1
 	netIntelV1 := app.Router.Group("/api/v1/net")
 	{
-		netIntelV1.GET("/dns-lookup", app.NetIntelHandlers.DNSLookupHandler)
-		netIntelV1.GET("/ip-info", app.NetIntelHandlers.IPInfoHandler)
-		netIntelV1.GET("/whois-lookup", app.NetIntelHandlers.WhoisLookupHandler)
-		netIntelV1.GET("/ssl-check", app.NetIntelHandlers.SSLCheckHandler)
+		netIntelV1.GET("/dns-lookup", handlers.ResultCacheMiddleware(handlers.DNSCacheTTL), app.NetIntelHandlers.DNSLookupHandler)
+		netIntelV1.GET("/ip-info", handlers.ResultCacheMiddleware(handlers.GeoIPCacheTTL), app.NetIntelHandlers.IPInfoHandler)
+		netIntelV1.POST("/ip-info/bulk", app.NetIntelHandlers.BulkIPInfoHandler)
+		netIntelV1.GET("/reverse-ip", app.NetIntelHandlers.ReverseIPHandler)
+		netIntelV1.GET("/passive-dns", app.NetIntelHandlers.PassiveDNSHandler)
+		netIntelV1.GET("/asn-info", app.NetIntelHandlers.ASNInfoHandler)
+		netIntelV1.GET("/bgp-route", app.NetIntelHandlers.BGPRouteHandler)
+		netIntelV1.GET("/geofeed-check", handlers.SSRFGuardMiddleware(), app.NetIntelHandlers.GeofeedCheckHandler)
+		netIntelV1.GET("/whois-lookup", handlers.ResultCacheMiddleware(handlers.WhoisCacheTTL), app.NetIntelHandlers.WhoisLookupHandler)
+		netIntelV1.POST("/whois-lookup/bulk", app.NetIntelHandlers.BulkWhoisLookupHandler)
+		netIntelV1.GET("/rdap-lookup", app.NetIntelHandlers.RDAPLookupHandler)
+		netIntelV1.GET("/ssl-check", handlers.ResultCacheMiddleware(handlers.SSLCacheTTL), app.NetIntelHandlers.SSLCheckHandler)
+		netIntelV1.POST("/ssl-check", app.NetIntelHandlers.SSLCheckWithClientCertHandler)
+		netIntelV1.POST("/ssl-check/batch", app.NetIntelHandlers.SSLBatchCheckHandler)
+		netIntelV1.GET("/ssl-compare", app.NetIntelHandlers.SSLCompareHandler)
+		netIntelV1.POST("/cert-decode", app.NetIntelHandlers.CertDecodeHandler)
+		netIntelV1.GET("/ping", app.NetIntelHandlers.PingHandler)
+		netIntelV1.GET("/email-security", app.NetIntelHandlers.EmailSecurityHandler)
+		netIntelV1.GET("/dnssec-check", app.NetIntelHandlers.DNSSECCheckHandler)
+		netIntelV1.GET("/caa-check", app.NetIntelHandlers.CAACheckHandler)
+		netIntelV1.GET("/zone-transfer", app.NetIntelHandlers.ZoneTransferHandler)
+		netIntelV1.GET("/blacklist-check", app.NetIntelHandlers.BlacklistCheckHandler)
+		netIntelV1.GET("/domain-report", app.NetIntelHandlers.DomainReportHandler)
+		netIntelV1.GET("/domain-permutations", app.NetIntelHandlers.DomainPermutationsHandler)
 	}
 
+	// The URL inspector, QR codes and IDN check only read the URL, so internal URLs need no SSRF guard
+	app.Router.GET("/api/v1/url/parse", app.URLUtilHandlers.ParseURLHandler)
+	app.Router.GET("/api/v1/url/qr", app.URLUtilHandlers.QRCodeHandler)
+	app.Router.GET("/api/v1/url/idn", app.URLUtilHandlers.IDNHandler)
+
 	// Group for URL Manipulation utilities
-	urlUtilV1 := app.Router.Group("/api/v1/url")
+	urlUtilV1 := app.Router.Group("/api/v1/url", handlers.SSRFGuardMiddleware())
 	{
 		urlUtilV1.POST("/clean", app.URLUtilHandlers.CleanURLHandler)
 		urlUtilV1.GET("/resolve-redirect", app.URLUtilHandlers.ResolveRedirectHandler)
 		urlUtilV1.POST("/generate-utm", app.URLUtilHandlers.GenerateUTMHandler)
+		urlUtilV1.POST("/shorten", app.URLUtilHandlers.ShortenURLHandler)
+		urlUtilV1.GET("/shorten/:slug/stats", app.URLUtilHandlers.ShortURLStatsHandler)
 	}
 
+	// Short links redirect from the root, outside the API base path
+	app.Router.GET("/r/:slug", app.URLUtilHandlers.FollowShortURLHandler)
+
 	// Group for Web Analysis utilities
-	webAnalysisV1 := app.Router.Group("/api/v1/web")
+	webAnalysisV1 := app.Router.Group("/api/v1/web", handlers.SSRFGuardMiddleware())
 	{
-		webAnalysisV1.GET("/stack-analyzer", app.WebAnalysisHandlers.StackAnalyzerHandler)
+		webAnalysisV1.GET("/stack-analyzer", handlers.ResultCacheMiddleware(handlers.StackCacheTTL), app.WebAnalysisHandlers.StackAnalyzerHandler)
 		webAnalysisV1.GET("/http-headers", app.WebAnalysisHandlers.HTTPHeadersHandler)
+		webAnalysisV1.GET("/consent-check", app.WebAnalysisHandlers.ConsentCheckHandler)
+		webAnalysisV1.GET("/social-links", app.WebAnalysisHandlers.SocialLinksHandler)
+		webAnalysisV1.GET("/meta-extract", app.WebAnalysisHandlers.MetaExtractHandler)
+		webAnalysisV1.GET("/mixed-content", app.WebAnalysisHandlers.MixedContentHandler)
+		webAnalysisV1.GET("/favicon-hash", app.WebAnalysisHandlers.FaviconHashHandler)
+		webAnalysisV1.GET("/similarity", app.WebAnalysisHandlers.ContentSimilarityHandler)
+		webAnalysisV1.POST("/crawl", app.WebAnalysisHandlers.CrawlHandler)
+		webAnalysisV1.GET("/crawl/:id", app.WebAnalysisHandlers.CrawlResultHandler)
+		webAnalysisV1.GET("/har", app.WebAnalysisHandlers.HARExportHandler)
+		webAnalysisV1.GET("/captures/:id", app.WebAnalysisHandlers.CaptureHandler)
+	}
+
+	// Group for embeddable status badges
+	badgeV1 := app.Router.Group("/api/v1/badge")
+	{
+		badgeV1.GET("/ssl", app.BadgeHandlers.SSLBadgeHandler)
 	}
 
-	// Add Swagger route
+	// Group for multi-vantage (looking-glass) relaying
+	vantageV1 := app.Router.Group("/api/v1/vantage")
+	{
+		vantageV1.GET("", app.VantageHandlers.ListVantagesHandler)
+		vantageV1.POST("/register", app.VantageHandlers.RegisterVantageHandler)
+	}
+
+	// Group for the domain portfolio and jobs across it
+	portfolioV1 := app.Router.Group("/api/v1/portfolio")
+	{
+		portfolioV1.GET("/domains", app.PortfolioHandlers.ListPortfolioHandler)
+		portfolioV1.POST("/domains", app.PortfolioHandlers.PutPortfolioDomainHandler)
+		portfolioV1.DELETE("/domains/:domain", app.PortfolioHandlers.DeletePortfolioDomainHandler)
+		portfolioV1.GET("/jobs", app.PortfolioHandlers.ListPortfolioJobsHandler)
+		portfolioV1.POST("/jobs", app.PortfolioHandlers.StartPortfolioJobHandler)
+		portfolioV1.GET("/jobs/:id", app.PortfolioHandlers.PortfolioJobHandler)
+		portfolioV1.POST("/jobs/:id/retry", app.PortfolioHandlers.RetryPortfolioJobHandler)
+		portfolioV1.GET("/certificates", app.PortfolioHandlers.PortfolioCertificatesHandler)
+	}
+
+	// Group for exporting observed events to SIEM systems
+	exportV1 := app.Router.Group("/api/v1/export")
+	{
+		exportV1.GET("/events", app.ExportHandlers.ExportEventsHandler)
+	}
+
+	// Group for ingesting items to enrich from external systems
+	ingestV1 := app.Router.Group("/api/v1/ingest")
+	{
+		ingestV1.POST("", app.IngestHandlers.IngestHandler)
+		ingestV1.GET("/:id", app.IngestHandlers.IngestBatchHandler)
+	}
+
+	// Group for miscellaneous utilities
+	miscV1 := app.Router.Group("/api/v1/misc")
+	{
+		miscV1.POST("/csr-generate", app.MiscHandlers.CSRGenerateHandler)
+		miscV1.POST("/csr-decode", app.MiscHandlers.CSRDecodeHandler)
+	}
+
+	// Group for operating the instance, behind ADMIN_API_KEYS
+	adminV1 := app.Router.Group("/api/v1/admin")
+	{
+		adminV1.GET("/wappalyzer", app.AdminHandlers.WappalyzerStatusHandler)
+		adminV1.POST("/wappalyzer/refresh", app.AdminHandlers.RefreshWappalyzerHandler)
+	}
+
+	// Add Swagger route, unless disabled with SWAGGER_ENABLED
 	// This path should be absolute from the host, not affected by @BasePath
-	app.Router.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler, ginSwagger.URL("/swagger/doc.json")))
+	if !handlers.SwaggerEnabled() {
+		return
+	}
+	// The document describes the API as clients reach it; without EXTERNAL_URL the Swagger UI
+	// uses the host it was loaded from
+	docs.SwaggerInfo.BasePath, docs.SwaggerInfo.Host = handlers.APIBasePath(), ""
+	if external := handlers.ExternalURL(); external != "" {
+		scheme, host, _ := strings.Cut(external, "://")
+		docs.SwaggerInfo.Host, docs.SwaggerInfo.Schemes = host, []string{scheme}
+	}
+	swaggerPath := handlers.SwaggerPath()
+	swaggerUI := ginSwagger.WrapHandler(swaggerFiles.Handler, ginSwagger.URL(swaggerPath+"/doc.json"))
+	openAPIDoc := handlers.OpenAPIDocHandler(docs.SwaggerInfo.ReadDoc)
+	app.Router.GET(swaggerPath+"/*any", handlers.SwaggerAuthMiddleware(), func(c *gin.Context) {
+		if c.Param("any") == "/doc.json" { // Leaves out the disabled endpoints
+			openAPIDoc(c)
+			return
+		}
+		swaggerUI(c)
+	})
 	// It's good practice to explicitly set the URL for doc.json for clarity
 	// The default might be swagger.json or docs.json depending on swag version/config
 }
 
-// Start runs the Gin HTTP server
-func (app *App) Start(addr string) error {
-	log.Printf("🚀 API server starting on %s", addr)
-	return app.Router.Run(addr)
+// Start runs the Gin HTTP server on listener until Shutdown is called
+func (app *App) Start(listener net.Listener) error {
+	log.Printf("🚀 API server starting on %s", listener.Addr())
+	if err := app.Server.Serve(listener); !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+// Shutdown stops accepting connections and waits for the requests in progress, until ctx is done
+func (app *App) Shutdown(ctx context.Context) error {
+	return app.Server.Shutdown(ctx)
 }
@@ -0,0 +1,99 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"log/slog"
+	"os"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/vit0-9/utils_api/pkg/utils/redact"
+)
+
+// Log formats
+const (
+	LogFormatConsole = "console"
+	LogFormatJSON    = "json" // One JSON object per line, for log collectors
+)
+
+var (
+	accessLogEnabled = true
+	logFormat        = LogFormatConsole
+)
+
+// ConfigureLogging switches the access log on or off and sets the log format. With
+// LogFormatJSON access log lines and the server's own log messages are written as JSON.
+func ConfigureLogging(accessLog bool, format string) {
+	accessLogEnabled = accessLog
+	switch format = strings.ToLower(strings.TrimSpace(format)); format {
+	case "", LogFormatConsole:
+		logFormat = LogFormatConsole
+	case LogFormatJSON:
+		logFormat = LogFormatJSON
+		// The log package writes through the default slog handler once it is replaced
+		slog.SetDefault(slog.New(slog.NewJSONHandler(os.Stderr, nil)))
+	default:
+		log.Printf("WARN: Unknown log format %q, using %s", format, LogFormatConsole)
+		logFormat = LogFormatConsole
+	}
+}
+
+// AccessLogEnabled reports whether requests are written to the access log.
+func AccessLogEnabled() bool {
+	return accessLogEnabled
+}
+
+// ConfigureGinMode sets Gin's mode: debug, which logs the registered routes and warnings,
+// release or test. Gin reads GIN_MODE before .env is loaded, so main passes it again.
+func ConfigureGinMode(mode string) {
+	switch mode = strings.ToLower(strings.TrimSpace(mode)); mode {
+	case "":
+	case gin.DebugMode, gin.ReleaseMode, gin.TestMode:
+		gin.SetMode(mode)
+	default:
+		log.Printf("WARN: Unknown Gin mode %q, using %s", mode, gin.Mode())
+	}
+}
+
+// accessLogEntry is an access log line in the JSON format.
+type accessLogEntry struct {
+	Time      string  `json:"time"`
+	Status    int     `json:"status"`
+	LatencyMs float64 `json:"latency_ms"`
+	ClientIP  string  `json:"client_ip"`
+	Method    string  `json:"method"`
+	Path      string  `json:"path"`
+	Error     string  `json:"error,omitempty"`
+}
+
+// AccessLogFormatter formats access log lines as Gin's default logger does, without
+// colors and with the query string redacted, or as JSON with LogFormatJSON.
+func AccessLogFormatter(param gin.LogFormatterParams) string {
+	path := param.Path
+	if base, rawQuery, found := strings.Cut(path, "?"); found {
+		path = base + "?" + redact.Query(base, rawQuery)
+	}
+	if logFormat == LogFormatJSON {
+		line, _ := json.Marshal(accessLogEntry{
+			Time:      param.TimeStamp.UTC().Format("2006-01-02T15:04:05.000Z07:00"),
+			Status:    param.StatusCode,
+			LatencyMs: durationMs(param.Latency),
+			ClientIP:  param.ClientIP,
+			Method:    param.Method,
+			Path:      path,
+			Error:     strings.TrimSpace(redact.Text(param.ErrorMessage)),
+		})
+		return string(line) + "\n"
+	}
+	return fmt.Sprintf("[GIN] %v | %3d | %13v | %15s | %-7s %#v\n%s",
+		param.TimeStamp.Format("2006/01/02 - 15:04:05"),
+		param.StatusCode,
+		param.Latency,
+		param.ClientIP,
+		param.Method,
+		path,
+		redact.Text(param.ErrorMessage),
+	)
+}
@@ -0,0 +1,40 @@
+package handlers
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestAccessLogFormatter(t *testing.T) {
+	defer func() { logFormat = LogFormatConsole }()
+	param := gin.LogFormatterParams{
+		TimeStamp:  time.Date(2026, 10, 15, 12, 0, 0, 0, time.UTC),
+		StatusCode: 200,
+		Latency:    1500 * time.Microsecond,
+		ClientIP:   "192.0.2.1",
+		Method:     "GET",
+		Path:       "/api/v1/net/dns-lookup?domain=example.com&token=secret",
+	}
+
+	line := AccessLogFormatter(param)
+	if !strings.HasPrefix(line, "[GIN] 2026/10/15 - 12:00:00 | 200 |") || strings.Contains(line, "secret") {
+		t.Errorf("console line = %q", line)
+	}
+
+	logFormat = LogFormatJSON
+	line = AccessLogFormatter(param)
+	var entry accessLogEntry
+	if err := json.Unmarshal([]byte(line), &entry); err != nil || !strings.HasSuffix(line, "}\n") {
+		t.Fatalf("JSON line %q: %v", line, err)
+	}
+	if entry.Status != 200 || entry.LatencyMs != 1.5 || entry.Method != "GET" || entry.Time != "2026-10-15T12:00:00.000Z" {
+		t.Errorf("entry = %+v", entry)
+	}
+	if strings.Contains(entry.Path, "secret") || !strings.Contains(entry.Path, "domain=example.com") {
+		t.Errorf("path = %q", entry.Path)
+	}
+}
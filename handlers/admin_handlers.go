@@ -0,0 +1,74 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/vit0-9/utils_api/pkg/utils"
+	"github.com/vit0-9/utils_api/pkg/utils/admin"
+)
+
+type AdminHandlers struct{}
+
+func NewAdminHandlers() *AdminHandlers {
+	return &AdminHandlers{}
+}
+
+// checkAdminAPIKey writes the error response and returns false unless the request carries
+// a configured admin API key.
+func checkAdminAPIKey(c *gin.Context) bool {
+	err := admin.CheckAPIKey(c.GetHeader(admin.APIKeyHeader))
+	switch {
+	case errors.Is(err, admin.ErrDisabled):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return false
+	case err != nil:
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return false
+	}
+	return true
+}
+
+// WappalyzerStatusHandler godoc
+// @Summary      Show the Wappalyzer fingerprints in use
+// @Description  Returns where the technology fingerprints used by the stack analyzer come from (embedded or downloaded), how many technologies they cover, when they were last updated and the outcome of the last update check. Requires one of the API keys configured with ADMIN_API_KEYS in the X-Admin-Key header.
+// @Tags         Admin
+// @Produce      json
+// @Param        X-Admin-Key header string true "API key configured with ADMIN_API_KEYS"
+// @Success      200 {object} utils.WappalyzerStatus "Fingerprint status"
+// @Failure      401 {object} map[string]string "Error: Missing or invalid admin API key"
+// @Failure      403 {object} map[string]string "Error: Admin endpoints are disabled on this instance"
+// @Router       /admin/wappalyzer [get]
+func (h *AdminHandlers) WappalyzerStatusHandler(c *gin.Context) {
+	if !checkAdminAPIKey(c) {
+		return
+	}
+	c.JSON(http.StatusOK, utils.WappalyzerFingerprintStatus())
+}
+
+// RefreshWappalyzerHandler godoc
+// @Summary      Update the Wappalyzer fingerprints now
+// @Description  Downloads the fingerprints from WAPPALYZER_FINGERPRINTS_URL, validates them and swaps them into the stack analyzer without a restart, instead of waiting for the next scheduled update. When the download or validation fails the fingerprints in use are kept. Requires one of the API keys configured with ADMIN_API_KEYS in the X-Admin-Key header.
+// @Tags         Admin
+// @Produce      json
+// @Param        X-Admin-Key header string true "API key configured with ADMIN_API_KEYS"
+// @Success      200 {object} utils.WappalyzerStatus "Fingerprint status after the update"
+// @Failure      401 {object} map[string]string "Error: Missing or invalid admin API key"
+// @Failure      403 {object} map[string]string "Error: Admin endpoints or fingerprint updates are disabled on this instance"
+// @Failure      502 {object} map[string]string "Error: The download or validation of the fingerprints failed"
+// @Router       /admin/wappalyzer/refresh [post]
+func (h *AdminHandlers) RefreshWappalyzerHandler(c *gin.Context) {
+	if !checkAdminAPIKey(c) {
+		return
+	}
+	status, err := utils.RefreshWappalyzerFingerprints(c.Request.Context())
+	switch {
+	case errors.Is(err, utils.ErrWappalyzerUpdatesDisabled):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	case err != nil:
+		c.JSON(http.StatusBadGateway, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusOK, status)
+	}
+}
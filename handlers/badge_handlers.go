@@ -0,0 +1,65 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/vit0-9/utils_api/pkg/utils"
+	"github.com/vit0-9/utils_api/pkg/utils/domain"
+)
+
+type BadgeHandlers struct{}
+
+func NewBadgeHandlers() *BadgeHandlers {
+	return &BadgeHandlers{}
+}
+
+// SSLBadgeHandler godoc
+// @Summary      SSL certificate status badge
+// @Description  Returns a shields.io style SVG badge showing the days until a host's certificate expires, or why it is not valid (expired, untrusted, unreachable). Suitable for embedding in READMEs and wikis.
+// @Tags         Badges
+// @Produce      image/svg+xml
+// @Param        domain query string true "Host whose certificate is shown"
+// @Success      200 {string} string "SVG badge"
+// @Failure      400 {object} map[string]string "Error: Invalid input (e.g., missing domain)"
+// @Router       /badge/ssl [get]
+func (h *BadgeHandlers) SSLBadgeHandler(c *gin.Context) {
+	domainQuery := c.Query("domain")
+	if domainQuery == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "domain query parameter is required"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 20*time.Second)
+	defer cancel()
+
+	message, color := "unreachable", utils.BadgeColorGrey
+	sslInfo, err := domain.GetSSLInfo(ctx, domainQuery)
+	if err == nil {
+		message, color = sslBadgeStatus(sslInfo)
+	}
+
+	c.Header("Cache-Control", "public, max-age=3600")
+	c.Data(http.StatusOK, "image/svg+xml; charset=utf-8", utils.RenderBadge("ssl", message, color))
+}
+
+// sslBadgeStatus picks the badge message and color for a certificate.
+func sslBadgeStatus(sslInfo *domain.SSLInfo) (string, string) {
+	switch {
+	case time.Now().After(sslInfo.NotAfter):
+		return "expired", utils.BadgeColorRed
+	case !sslInfo.ChainTrusted:
+		return "untrusted", utils.BadgeColorRed
+	case sslInfo.DaysUntilExpiry == 1:
+		return "1 day", utils.BadgeColorOrange
+	case sslInfo.DaysUntilExpiry < 7:
+		return fmt.Sprintf("%d days", sslInfo.DaysUntilExpiry), utils.BadgeColorOrange
+	case sslInfo.DaysUntilExpiry < 30:
+		return fmt.Sprintf("%d days", sslInfo.DaysUntilExpiry), utils.BadgeColorYellow
+	default:
+		return fmt.Sprintf("%d days", sslInfo.DaysUntilExpiry), utils.BadgeColorGreen
+	}
+}
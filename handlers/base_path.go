@@ -0,0 +1,76 @@
+package handlers
+
+import (
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// DefaultAPIBasePath is the path the API routes are registered under.
+const DefaultAPIBasePath = "/api/v1"
+
+var (
+	apiBasePath = DefaultAPIBasePath
+	externalURL *url.URL
+)
+
+// ConfigureBasePath sets the path clients reach the API under, e.g. /tools/api/v1 behind a
+// reverse proxy that forwards /tools unchanged, and the scheme and host they use, e.g.
+// https://example.com. Both go into the Swagger document and the links the API returns.
+func ConfigureBasePath(basePath, external string) {
+	if basePath = "/" + strings.Trim(strings.TrimSpace(basePath), "/"); basePath != "/" {
+		apiBasePath = basePath
+	}
+	if external = strings.TrimSpace(external); external != "" {
+		parsed, err := url.Parse(external)
+		switch {
+		case err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "":
+			log.Printf("ERROR: Ignoring EXTERNAL_URL %q: it must be an http(s) URL such as https://example.com", external)
+		case strings.Trim(parsed.Path, "/") != "":
+			log.Printf("ERROR: Ignoring EXTERNAL_URL %q: put its path into API_BASE_PATH", external)
+		default:
+			externalURL = &url.URL{Scheme: parsed.Scheme, Host: parsed.Host}
+		}
+	}
+	if apiBasePath != DefaultAPIBasePath || externalURL != nil {
+		log.Printf("API served to clients at %s%s", ExternalURL(), apiBasePath)
+	}
+}
+
+// APIBasePath returns the path clients reach the API under.
+func APIBasePath() string {
+	return apiBasePath
+}
+
+// ExternalURL returns the scheme and host clients reach the API at, or "" when not set.
+func ExternalURL() string {
+	if externalURL == nil {
+		return ""
+	}
+	return externalURL.String()
+}
+
+// APIURL returns the link to an API path relative to the base path, such as /me/usage:
+// absolute when the external URL is set.
+func APIURL(relative string) string {
+	return ExternalURL() + apiBasePath + relative
+}
+
+// BasePathHandler serves requests under the configured base path from the routes under
+// /api/v1, so the API works behind reverse proxies that forward the prefix as well as those
+// that strip it.
+func BasePathHandler(next http.Handler) http.Handler {
+	if apiBasePath == DefaultAPIBasePath {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if rest, ok := strings.CutPrefix(r.URL.Path, apiBasePath); ok && (rest == "" || rest[0] == '/') {
+			r.URL.Path = DefaultAPIBasePath + rest
+			if r.URL.RawPath != "" {
+				r.URL.RawPath = DefaultAPIBasePath + strings.TrimPrefix(r.URL.RawPath, apiBasePath)
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,39 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestBasePathHandler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	defer func(path string) { apiBasePath, externalURL = path, nil }(apiBasePath)
+	ConfigureBasePath("/tools/api/v1/", "https://example.com/")
+	if got := APIURL("/me/usage"); got != "https://example.com/tools/api/v1/me/usage" {
+		t.Errorf("APIURL = %q", got)
+	}
+
+	router := gin.New()
+	router.GET("/api/v1/health", func(c *gin.Context) { c.String(http.StatusOK, c.Request.URL.Path) })
+	handler := BasePathHandler(router)
+	for path, want := range map[string]int{
+		"/tools/api/v1/health": http.StatusOK, // Proxy forwarding the prefix
+		"/api/v1/health":       http.StatusOK, // Proxy stripping it
+		"/tools/api/v1health":  http.StatusNotFound,
+		"/tools/health":        http.StatusNotFound,
+	} {
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, path, nil))
+		if recorder.Code != want {
+			t.Errorf("%s: status %d, want %d", path, recorder.Code, want)
+		}
+	}
+
+	ConfigureBasePath("", "https://example.com/tools")
+	if ExternalURL() != "https://example.com" {
+		t.Errorf("external URL with a path was not ignored: %q", ExternalURL())
+	}
+}
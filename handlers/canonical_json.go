@@ -0,0 +1,58 @@
+package handlers
+
+import (
+	"bytes"
+	"log"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/vit0-9/utils_api/pkg/utils"
+)
+
+// bufferedResponseWriter holds the response body so it can be rewritten before sending.
+type bufferedResponseWriter struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *bufferedResponseWriter) Write(data []byte) (int, error) {
+	return w.body.Write(data)
+}
+
+func (w *bufferedResponseWriter) WriteString(s string) (int, error) {
+	return w.body.WriteString(s)
+}
+
+// CanonicalJSONMiddleware re-encodes JSON responses as RFC 8785 canonical JSON when the
+// request has canonical=true, so results can be hashed, signed and diffed across runs.
+// The X-Canonical-JSON response header reports whether that succeeded; a response that
+// cannot be canonicalized (e.g. a number outside the double range) is sent unchanged
+// with the header set to false.
+func CanonicalJSONMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.Query("canonical") != "true" {
+			c.Next()
+			return
+		}
+
+		writer := &bufferedResponseWriter{ResponseWriter: c.Writer}
+		c.Writer = writer
+		c.Next()
+		c.Writer = writer.ResponseWriter
+
+		body := writer.body.Bytes()
+		if strings.HasPrefix(writer.Header().Get("Content-Type"), "application/json") {
+			canonical, err := utils.CanonicalizeJSON(body)
+			if err != nil {
+				log.Printf("ERROR: Could not canonicalize response for %s: %v", c.Request.URL.Path, err)
+				writer.Header().Set("X-Canonical-JSON", "false")
+			} else {
+				body = canonical
+				writer.Header().Set("X-Canonical-JSON", "true")
+			}
+		}
+		writer.Header().Set("Content-Length", strconv.Itoa(len(body)))
+		writer.ResponseWriter.Write(body)
+	}
+}
@@ -0,0 +1,45 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestCanonicalJSONMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(CanonicalJSONMiddleware())
+	router.GET("/ok", func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"b": 1.50, "a": []any{true, nil}})
+	})
+	router.GET("/huge", func(c *gin.Context) {
+		c.Data(http.StatusOK, "application/json; charset=utf-8", []byte(`{"n": 1e400}`))
+	})
+	router.GET("/text", func(c *gin.Context) {
+		c.String(http.StatusOK, "plain")
+	})
+
+	tests := []struct {
+		path       string
+		wantBody   string
+		wantHeader string
+	}{
+		{"/ok?canonical=true", `{"a":[true,null],"b":1.5}`, "true"},
+		{"/ok", `{"a":[true,null],"b":1.5}`, ""},
+		{"/huge?canonical=true", `{"n": 1e400}`, "false"},
+		{"/text?canonical=true", "plain", ""},
+	}
+	for _, tt := range tests {
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, tt.path, nil))
+		if recorder.Body.String() != tt.wantBody {
+			t.Errorf("GET %s body = %s, want %s", tt.path, recorder.Body.String(), tt.wantBody)
+		}
+		if got := recorder.Header().Get("X-Canonical-JSON"); got != tt.wantHeader {
+			t.Errorf("GET %s X-Canonical-JSON = %q, want %q", tt.path, got, tt.wantHeader)
+		}
+	}
+}
@@ -0,0 +1,39 @@
+package handlers
+
+import (
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/vit0-9/utils_api/pkg/utils"
+	"github.com/vit0-9/utils_api/pkg/utils/eventlog"
+	"github.com/vit0-9/utils_api/pkg/utils/redact"
+	"github.com/vit0-9/utils_api/pkg/utils/requestlog"
+)
+
+// EventLogMiddleware records every API request as a lookup event for the SIEM export. The
+// export and health endpoints are left out, so polling them does not flood the log.
+func EventLogMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		path := c.Request.URL.Path
+		if !strings.HasPrefix(path, "/api/v1/") || strings.HasPrefix(path, "/api/v1/export") || path == "/api/v1/health" || strings.HasPrefix(path, "/api/v1/health/") {
+			c.Next()
+			return
+		}
+		start := time.Now()
+		c.Next()
+
+		severity := 1
+		if c.Writer.Status() >= 500 {
+			severity = 5
+		}
+		eventlog.Append(eventlog.TypeLookup, path, severity, map[string]any{
+			"method":      c.Request.Method,
+			"query":       redact.Query(path, c.Request.URL.RawQuery),
+			"status":      c.Writer.Status(),
+			"client_ip":   c.ClientIP(),
+			"duration_ms": time.Since(start).Milliseconds(),
+			"outcome":     requestlog.Classify(c.Writer.Status(), utils.TimingRecorderFrom(c.Request.Context())),
+		})
+	}
+}
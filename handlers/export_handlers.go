@@ -0,0 +1,118 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/vit0-9/utils_api/pkg/utils/eventlog"
+)
+
+// Export page sizes.
+const (
+	defaultExportLimit = 1000
+	maxExportLimit     = 10000
+)
+
+type ExportHandlers struct{}
+
+func NewExportHandlers() *ExportHandlers {
+	return &ExportHandlers{}
+}
+
+// ExportEventsHandler godoc
+// @Summary      Export observed events for SIEM ingestion
+// @Description  Streams the lookups served, monitor events (job.completed, monitor.alert) and first-seen passive DNS answers, oldest first, one per line as NDJSON, CEF or LEEF. Page through with the cursor: pass the X-Next-Cursor response header back as cursor until X-More-Events is false. Event IDs keep increasing across restarts, but the log itself is in memory and holds the last 50000 events. Requires one of the API keys configured with ADMIN_API_KEYS in the X-Admin-Key header.
+// @Tags         Export
+// @Produce      application/x-ndjson,text/plain
+// @Param        X-Admin-Key header string true "API key configured with ADMIN_API_KEYS"
+// @Param        since query string false "Only events at or after this RFC 3339 time"
+// @Param        cursor query int false "Only events after this event ID (the previous page's X-Next-Cursor)"
+// @Param        limit query int false "Maximum events to return (default 1000, maximum 10000)"
+// @Param        types query []string false "Event types to include: lookup, monitor, passive_dns (all by default)" collectionFormat(csv)
+// @Param        format query string false "Line format: ndjson (default), cef or leef"
+// @Success      200 {string} string "One event per line"
+// @Header       200 {integer} X-Next-Cursor "Cursor for the next page"
+// @Header       200 {boolean} X-More-Events "Whether more events follow"
+// @Failure      400 {object} map[string]string "Error: Invalid input (e.g., malformed since, cursor, limit, type or format)"
+// @Failure      401 {object} map[string]string "Error: Missing or invalid admin API key"
+// @Failure      403 {object} map[string]string "Error: Admin endpoints are disabled on this instance"
+// @Router       /export/events [get]
+func (h *ExportHandlers) ExportEventsHandler(c *gin.Context) {
+	if !checkAdminAPIKey(c) {
+		return
+	}
+	query := eventlog.Query{Limit: defaultExportLimit}
+	if value := c.Query("since"); value != "" {
+		since, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "since must be an RFC 3339 time, e.g. 2026-01-02T15:04:05Z"})
+			return
+		}
+		query.Since = since
+	}
+	if value := c.Query("cursor"); value != "" {
+		cursor, err := strconv.ParseUint(value, 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "cursor must be an event ID"})
+			return
+		}
+		query.After = cursor
+	}
+	if value := c.Query("limit"); value != "" {
+		limit, err := strconv.Atoi(value)
+		if err != nil || limit < 1 || limit > maxExportLimit {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "limit must be between 1 and " + strconv.Itoa(maxExportLimit)})
+			return
+		}
+		query.Limit = limit
+	}
+	if value := c.Query("types"); value != "" {
+		query.Types = make(map[string]bool)
+		for _, eventType := range strings.Split(value, ",") {
+			eventType = strings.TrimSpace(eventType)
+			if eventType != eventlog.TypeLookup && eventType != eventlog.TypeMonitor && eventType != eventlog.TypePassiveDNS {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "unknown event type " + strconv.Quote(eventType) + ": use lookup, monitor or passive_dns"})
+				return
+			}
+			query.Types[eventType] = true
+		}
+	}
+
+	format := strings.ToLower(c.DefaultQuery("format", "ndjson"))
+	var formatLine func(eventlog.Event) ([]byte, error)
+	contentType := "text/plain; charset=utf-8"
+	switch format {
+	case "ndjson":
+		formatLine = func(event eventlog.Event) ([]byte, error) { return json.Marshal(event) }
+		contentType = "application/x-ndjson"
+	case "cef":
+		formatLine = func(event eventlog.Event) ([]byte, error) { return []byte(eventlog.FormatCEF(event)), nil }
+	case "leef":
+		formatLine = func(event eventlog.Event) ([]byte, error) { return []byte(eventlog.FormatLEEF(event)), nil }
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "format must be ndjson, cef or leef"})
+		return
+	}
+
+	events, more := eventlog.Read(query)
+	next := query.After
+	if len(events) > 0 {
+		next = events[len(events)-1].ID
+	}
+	c.Header("Content-Type", contentType)
+	c.Header("X-Next-Cursor", strconv.FormatUint(next, 10))
+	c.Header("X-More-Events", strconv.FormatBool(more))
+	c.Status(http.StatusOK)
+	for _, event := range events {
+		line, err := formatLine(event)
+		if err != nil {
+			continue
+		}
+		c.Writer.Write(append(line, '\n'))
+	}
+	c.Writer.Flush()
+}
@@ -0,0 +1,34 @@
+package handlers
+
+import (
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/vit0-9/utils_api/pkg/utils/features"
+)
+
+// FeatureFlagMiddleware answers requests to endpoints disabled with DISABLED_ENDPOINTS with
+// 404, as if they did not exist on this instance.
+func FeatureFlagMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !features.Enabled(c.Request.URL.Path) {
+			c.AbortWithStatusJSON(http.StatusNotFound, gin.H{"error": "this endpoint is disabled on this instance"})
+			return
+		}
+		c.Next()
+	}
+}
+
+// OpenAPIDocHandler serves the Swagger document without the disabled endpoints.
+func OpenAPIDocHandler(readDoc func() string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		doc, err := features.FilterOpenAPI([]byte(readDoc()))
+		if err != nil {
+			log.Printf("ERROR: Could not filter the OpenAPI document: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "could not build the API document"})
+			return
+		}
+		c.Data(http.StatusOK, "application/json; charset=utf-8", doc)
+	}
+}
@@ -1,9 +1,15 @@
 package handlers
 
 import (
+	"context"
 	"net/http"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/vit0-9/utils_api/models"
+	"github.com/vit0-9/utils_api/pkg/utils"
+	"github.com/vit0-9/utils_api/pkg/utils/cache"
+	"github.com/vit0-9/utils_api/pkg/utils/features"
 )
 
 type HealthHandler struct{}
@@ -14,13 +20,98 @@ func NewHealthHandler() *HealthHandler {
 
 // HealthCheckHandler godoc
 // @Summary      Health Check
-// @Description  Checks the health of the API.
+// @Description  Checks the health of the API. Endpoints switched off with DISABLED_ENDPOINTS are listed under disabled_endpoints, offline is true when outbound lookups are answered from fixtures (OFFLINE_MODE), and geoip_databases lists the MaxMind databases with when they were last updated and the last download attempt when MAXMIND_LICENSE_KEY is set.
 // @Tags         Monitoring
 // @Produce      json
-// @Success      200  {object}  map[string]string
+// @Param        canonical query bool false "Return RFC 8785 canonical JSON (sorted keys, no whitespace); the X-Canonical-JSON response header is false if that failed"
+// @Success      200  {object}  map[string]any
 // @Router       /health [get]
 func (h *HealthHandler) HealthCheckHandler(c *gin.Context) {
-	c.JSON(http.StatusOK, gin.H{
+	response := gin.H{
 		"status": "UP",
-	})
+	}
+	if utils.OfflineMode() {
+		response["offline"] = true
+	}
+	if disabled := features.Disabled(); len(disabled) > 0 {
+		response["disabled_endpoints"] = disabled
+	}
+	if databases := utils.GeoIPDatabaseStatuses(); len(databases) > 0 {
+		response["geoip_databases"] = databases
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// DetailedHealthCheckHandler godoc
+// @Summary      Detailed health check
+// @Description  Checks the API's dependencies: whether the configured MaxMind databases are loaded and when they were built, whether the Wappalyzer fingerprints initialized, whether the result cache backend (memory or Redis) answers, and whether the internet is reachable (a TCP connection to HEALTH_OUTBOUND_PROBE, skipped in offline mode), along with the build version and commit. Dependencies that are not configured are reported as disabled. MaxMind databases built longer ago than MMDB_STALE_DAYS are flagged stale, which makes the geoip and overall status warn, still with a 200. The status is degraded, with a 503, when any configured dependency fails; /health remains the liveness check.
+// @Tags         Monitoring
+// @Produce      json
+// @Param        canonical query bool false "Return RFC 8785 canonical JSON (sorted keys, no whitespace); the X-Canonical-JSON response header is false if that failed"
+// @Success      200  {object}  models.DetailedHealthResponse "Every configured dependency is healthy"
+// @Failure      503  {object}  models.DetailedHealthResponse "A configured dependency failed"
+// @Router       /health/detailed [get]
+func (h *HealthHandler) DetailedHealthCheckHandler(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 5*time.Second)
+	defer cancel()
+
+	response := models.DetailedHealthResponse{
+		Status:    models.HealthHealthy,
+		Build:     utils.GetBuildInfo(),
+		Offline:   utils.OfflineMode(),
+		Panics:    RecoveredPanics(),
+		CheckedAt: time.Now().UTC(),
+	}
+
+	response.GeoIP = models.GeoIPHealth{Status: models.DependencyDisabled, Databases: utils.GeoIPDatabaseStatuses()}
+	for _, database := range response.GeoIP.Databases {
+		switch {
+		case !database.Loaded:
+			response.GeoIP.Status = models.DependencyFailed
+		case response.GeoIP.Status == models.DependencyFailed:
+		case database.Stale:
+			response.GeoIP.Status = models.DependencyWarn
+		case response.GeoIP.Status != models.DependencyWarn:
+			response.GeoIP.Status = models.DependencyOK
+		}
+	}
+
+	response.Wappalyzer = models.WappalyzerHealth{Status: models.DependencyOK}
+	if err := utils.CheckWappalyzer(); err != nil {
+		response.Wappalyzer.Status, response.Wappalyzer.Error = models.DependencyFailed, err.Error()
+	}
+	response.Wappalyzer.Fingerprints = utils.WappalyzerFingerprintStatus()
+
+	start := time.Now()
+	backend, err := cache.Ping(ctx)
+	response.Cache = models.CacheHealth{Status: models.DependencyOK, Backend: backend, LatencyMs: durationMs(time.Since(start))}
+	if err != nil {
+		response.Cache.Status, response.Cache.Error = models.DependencyFailed, err.Error()
+	}
+
+	if response.Offline {
+		response.Outbound = models.OutboundHealth{Status: models.DependencySkipped}
+	} else {
+		target, latency, err := utils.ProbeOutbound(ctx)
+		response.Outbound = models.OutboundHealth{Status: models.DependencyOK, Target: target, LatencyMs: durationMs(latency)}
+		if err != nil {
+			response.Outbound.Status, response.Outbound.Error = models.DependencyFailed, err.Error()
+		}
+	}
+
+	status := http.StatusOK
+	for _, dependency := range []string{response.GeoIP.Status, response.Wappalyzer.Status, response.Cache.Status, response.Outbound.Status} {
+		switch {
+		case dependency == models.DependencyFailed:
+			response.Status, status = models.HealthDegraded, http.StatusServiceUnavailable
+		case dependency == models.DependencyWarn && response.Status == models.HealthHealthy:
+			response.Status = models.HealthWarn
+		}
+	}
+	c.JSON(status, response)
+}
+
+// durationMs converts a duration to fractional milliseconds.
+func durationMs(d time.Duration) float64 {
+	return float64(d.Microseconds()) / 1000
 }
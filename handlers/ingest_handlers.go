@@ -0,0 +1,91 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/vit0-9/utils_api/models"
+	"github.com/vit0-9/utils_api/pkg/utils/ingest"
+)
+
+type IngestHandlers struct{}
+
+func NewIngestHandlers() *IngestHandlers {
+	return &IngestHandlers{}
+}
+
+// checkIngestAPIKey writes the error response and returns false unless the request carries
+// a configured API key.
+func checkIngestAPIKey(c *gin.Context) bool {
+	err := ingest.CheckAPIKey(c.GetHeader(ingest.APIKeyHeader))
+	switch {
+	case errors.Is(err, ingest.ErrDisabled):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return false
+	case err != nil:
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return false
+	}
+	return true
+}
+
+// IngestHandler godoc
+// @Summary      Submit URLs, domains and IPs for analysis
+// @Description  Queues up to 100 items pushed by an external system, such as a SOAR playbook, and runs the requested analyses on each in the background: dns, whois, ssl, blacklist, ip-info, stack and redirects. Analyses that do not apply to an item's kind are skipped; URLs get the domain analyses on their host. Poll the returned batch for results, or pass a callback_url to receive the completed batch as a POST signed with CALLBACK_SIGNING_SECRET in X-Signature-256; when it completes a job.completed notification carrying the correlation ID is sent too. Requires one of the API keys configured with INGEST_API_KEYS in the X-API-Key header.
+// @Tags         Ingestion
+// @Accept       json
+// @Produce      json
+// @Param        X-API-Key header string true "API key configured with INGEST_API_KEYS"
+// @Param        batch body models.IngestRequest true "Items, optional analyses and correlation ID"
+// @Success      202 {object} ingest.Batch "The queued batch"
+// @Failure      400 {object} map[string]string "Error: Invalid input (e.g., an item that is not a URL, domain or IP, an unknown analysis, more than 100 items or an invalid callback URL)"
+// @Failure      401 {object} map[string]string "Error: Missing or invalid API key"
+// @Failure      403 {object} map[string]string "Error: Ingestion is disabled on this instance"
+// @Failure      429 {object} map[string]string "Error: The queue is full"
+// @Router       /ingest [post]
+func (h *IngestHandlers) IngestHandler(c *gin.Context) {
+	if !checkIngestAPIKey(c) {
+		return
+	}
+	var req models.IngestRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request payload: " + err.Error()})
+		return
+	}
+	batch, err := ingest.Submit(req.CorrelationID, req.Items, req.Analyses, req.CallbackURL)
+	if err != nil {
+		status := http.StatusBadRequest
+		if errors.Is(err, ingest.ErrQueueFull) {
+			status = http.StatusTooManyRequests
+		}
+		c.JSON(status, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusAccepted, batch)
+}
+
+// IngestBatchHandler godoc
+// @Summary      Get an ingested batch
+// @Description  Returns a batch's progress and the results of the items analysed so far, keyed by analysis. The last 100 batches are kept. Requires an API key like submitting.
+// @Tags         Ingestion
+// @Produce      json
+// @Param        X-API-Key header string true "API key configured with INGEST_API_KEYS"
+// @Param        id path string true "Batch ID"
+// @Param        canonical query bool false "Return RFC 8785 canonical JSON (sorted keys, no whitespace); the X-Canonical-JSON response header is false if that failed"
+// @Success      200 {object} ingest.Batch "The batch"
+// @Failure      401 {object} map[string]string "Error: Missing or invalid API key"
+// @Failure      403 {object} map[string]string "Error: Ingestion is disabled on this instance"
+// @Failure      404 {object} map[string]string "Error: Unknown or expired batch"
+// @Router       /ingest/{id} [get]
+func (h *IngestHandlers) IngestBatchHandler(c *gin.Context) {
+	if !checkIngestAPIKey(c) {
+		return
+	}
+	batch, ok := ingest.Get(c.Param("id"))
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "batch not found"})
+		return
+	}
+	c.JSON(http.StatusOK, batch)
+}
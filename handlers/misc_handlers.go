@@ -0,0 +1,80 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/vit0-9/utils_api/models"
+	"github.com/vit0-9/utils_api/pkg/utils/domain"
+)
+
+// MiscHandlers groups utilities that fit no other group
+type MiscHandlers struct{}
+
+func NewMiscHandlers() *MiscHandlers {
+	return &MiscHandlers{}
+}
+
+// CSRGenerateHandler godoc
+// @Summary      Generate a key pair and CSR
+// @Description  Creates an RSA (2048, 3072 or 4096 bits) or ECDSA (P-256, P-384 or P-521) key pair and a certificate signing request for the given subject and subject alternative names, returning both in PEM along with the CSR's analysis. The private key is never stored or logged; the response is sent with Cache-Control: no-store.
+// @Tags         Miscellaneous
+// @Accept       json
+// @Produce      json
+// @Param        request body models.CSRGenerateRequest true "Key and subject of the CSR"
+// @Param        canonical query bool false "Return RFC 8785 canonical JSON (sorted keys, no whitespace); the X-Canonical-JSON response header is false if that failed"
+// @Success      200 {object} models.CSRGenerateResponse "Private key, CSR and its analysis"
+// @Failure      400 {object} map[string]string "Error: Invalid input (e.g., unsupported key size or no names)"
+// @Router       /misc/csr-generate [post]
+func (h *MiscHandlers) CSRGenerateHandler(c *gin.Context) {
+	var request models.CSRGenerateRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request payload: " + err.Error()})
+		return
+	}
+
+	generated, err := domain.GenerateCSR(domain.CSROptions{
+		KeyType:        request.KeyType,
+		KeySize:        request.KeySize,
+		Subject:        request.Subject,
+		DNSNames:       request.DNSNames,
+		IPAddresses:    request.IPAddresses,
+		EmailAddresses: request.EmailAddresses,
+	})
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.Header("Cache-Control", "no-store")
+	c.JSON(http.StatusOK, models.CSRGenerateResponse{
+		PrivateKeyPEM: generated.PrivateKeyPEM,
+		CSRPEM:        generated.CSRPEM,
+		CSR:           generated.CSR,
+	})
+}
+
+// CSRDecodeHandler godoc
+// @Summary      Decode a CSR
+// @Description  Decodes a PEM or DER certificate signing request sent as the request body and reports its subject, requested names, key algorithm and strength, public key pin, extensions and whether it is signed by the key it requests a certificate for.
+// @Tags         Miscellaneous
+// @Accept       plain,octet-stream
+// @Produce      json
+// @Param        csr body string true "PEM or DER certificate signing request"
+// @Param        canonical query bool false "Return RFC 8785 canonical JSON (sorted keys, no whitespace); the X-Canonical-JSON response header is false if that failed"
+// @Success      200 {object} models.CSRDecodeResponse "CSR analysis"
+// @Failure      400 {object} map[string]string "Error: Invalid input (e.g., empty body or not a CSR)"
+// @Router       /misc/csr-decode [post]
+func (h *MiscHandlers) CSRDecodeHandler(c *gin.Context) {
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxCertDecodeBody)
+	data, err := c.GetRawData()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "failed to read the certificate request: " + err.Error()})
+		return
+	}
+	csr, err := domain.DecodeCSR(data)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, models.CSRDecodeResponse{CSR: csr})
+}
@@ -2,15 +2,24 @@ package handlers
 
 import (
 	"context"
+	"fmt"
+	"math"
 	"net/http"
 	"strconv"
 	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
-	"github.com/vit0-9/utils_api/models"           // Your models package
-	"github.com/vit0-9/utils_api/pkg/utils"        // Your general utils
+	"github.com/vit0-9/utils_api/models"    // Your models package
+	"github.com/vit0-9/utils_api/pkg/utils" // Your general utils
+	"github.com/vit0-9/utils_api/pkg/utils/bgp"
+	"github.com/vit0-9/utils_api/pkg/utils/caa"
+	"github.com/vit0-9/utils_api/pkg/utils/dnssec"
 	"github.com/vit0-9/utils_api/pkg/utils/domain" // Your domain specific utils
+	"github.com/vit0-9/utils_api/pkg/utils/domainreport"
+	"github.com/vit0-9/utils_api/pkg/utils/emailauth"
+	"github.com/vit0-9/utils_api/pkg/utils/notifications"
+	"github.com/vit0-9/utils_api/pkg/utils/typosquat"
 )
 
 // NetworkIntelligenceHandlers groups network and domain related utilities
@@ -26,11 +35,16 @@ var defaultDNSRecordTypes = []string{"A", "AAAA", "MX", "CNAME", "TXT", "NS"}
 
 // DNSLookupHandler godoc
 // @Summary      Perform DNS lookups for a domain
-// @Description  Retrieves DNS records for a given domain. If 'record_types' is omitted or empty, a default set (A, AAAA, MX, CNAME, TXT, NS) will be queried.
+// @Description  Retrieves DNS records for a given domain. If 'record_types' is omitted or empty, a default set (A, AAAA, MX, CNAME, TXT, NS) will be queried. SOA, SRV, CAA, PTR (pass an IP as the domain), DS and DNSKEY are also supported. Records include their TTL. Set 'resolver' to compare answers across resolvers.
 // @Tags         Network & Domain Intelligence
-// @Produce      json
+// @Produce      json,html,application/pdf
 // @Param        domain query string true "Domain to lookup"
-// @Param        record_types query []string false "DNS record types to query (e.g., A, MX, TXT). Defaults to common set if omitted." collectionFormat(csv)
+// @Param        record_types query []string false "DNS record types to query (A, AAAA, MX, TXT, CNAME, NS, SOA, SRV, CAA, PTR, DS, DNSKEY). Defaults to common set if omitted." collectionFormat(csv)
+// @Param        resolver query string false "Resolver to query instead of the system resolver: an IP/host with optional port (e.g., 1.1.1.1, 8.8.8.8:53) or a DNS-over-HTTPS URL (e.g., https://cloudflare-dns.com/dns-query)"
+// @Param        format query string false "Response format: json (default), html for a standalone report page or pdf for a printable document"
+// @Param        canonical query bool false "Return RFC 8785 canonical JSON (sorted keys, no whitespace); the X-Canonical-JSON response header is false if that failed"
+// @Param        vantage query string false "Comma-separated vantage points to run the check from, e.g. eu,local; the results are returned side by side as a models.VantageComparisonResponse"
+// @Param        cache query bool false "Set to false to skip the result cache and refresh it; the X-Cache response header reports HIT, MISS or BYPASS"
 // @Success      200 {object} models.DNSLookupResponse "Successfully retrieved DNS records or errors for specific types"
 // @Failure      400 {object} map[string]string "Error: Invalid input (e.g., missing domain)"
 // @Router       /net/dns-lookup [get]
@@ -60,27 +74,21 @@ func (h *NetworkIntelligenceHandlers) DNSLookupHandler(c *gin.Context) {
 		typesToLookup[i] = strings.ToUpper(strings.TrimSpace(rt))
 	}
 
-	utilRecords, lookupErrors := utils.LookupDNSRecords(domainQuery, typesToLookup) // Assuming this is in general utils now
-
-	responseRecords := make(map[string][]utils.DNSRecord)
-	for recordType, localRecs := range utilRecords {
-		modelRecs := make([]utils.DNSRecord, len(localRecs))
-		for i, lr := range localRecs {
-			modelRecs[i] = utils.DNSRecord{
-				Type:     lr.Type,
-				Value:    lr.Value,
-				Priority: lr.Priority,
-			}
-		}
-		responseRecords[recordType] = modelRecs
+	resolver, err := utils.NewResolver(c.Query("resolver"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid resolver: " + err.Error()})
+		return
 	}
 
+	utilRecords, lookupErrors := utils.LookupDNSRecordsWithResolver(c.Request.Context(), resolver, domainQuery, typesToLookup)
+
 	response := models.DNSLookupResponse{
-		Domain:  domainQuery,
-		Records: responseRecords,
-		Errors:  lookupErrors,
+		Domain:   domainQuery,
+		Resolver: resolver.Name(),
+		Records:  utilRecords,
+		Errors:   lookupErrors,
 	}
-	c.JSON(http.StatusOK, response) // Changed from PureJSON if SafeURLString handles escaping
+	writeReport(c, "DNS Lookup", response)
 }
 
 // IPInfoHandler godoc
@@ -89,6 +97,8 @@ func (h *NetworkIntelligenceHandlers) DNSLookupHandler(c *gin.Context) {
 // @Tags         Network & Domain Intelligence
 // @Produce      json
 // @Param        ip query string true "IP Address to get info for"
+// @Param        canonical query bool false "Return RFC 8785 canonical JSON (sorted keys, no whitespace); the X-Canonical-JSON response header is false if that failed"
+// @Param        cache query bool false "Set to false to skip the result cache and refresh it; the X-Cache response header reports HIT, MISS or BYPASS"
 // @Success      200 {object} models.IPInfoResponse "Successfully retrieved IP information"
 // @Failure      400 {object} map[string]string "Error: Invalid input (e.g., missing IP address)"
 // @Router       /net/ip-info [get]
@@ -101,7 +111,63 @@ func (h *NetworkIntelligenceHandlers) IPInfoHandler(c *gin.Context) {
 
 	utilData := utils.GetBasicIPInfo(ipAddress) // Assuming this is in general utils now
 
-	response := models.IPInfoResponse{
+	response := ipInfoResponse(utilData)
+	c.JSON(http.StatusOK, response)
+}
+
+// BulkIPInfoHandler godoc
+// @Summary      Get information about many IP addresses
+// @Description  Accepts a JSON array of IP addresses and returns validation, reverse DNS, and GeoIP/ASN information for each, in request order. Lookups run concurrently, and a job.completed notification is sent when they finish.
+// @Tags         Network & Domain Intelligence
+// @Accept       json
+// @Produce      json
+// @Param        ips body []string true "IP addresses to get info for"
+// @Param        canonical query bool false "Return RFC 8785 canonical JSON (sorted keys, no whitespace); the X-Canonical-JSON response header is false if that failed"
+// @Success      200 {object} models.BulkIPInfoResponse "Successfully retrieved IP information"
+// @Failure      400 {object} map[string]string "Error: Invalid input (e.g., empty or too many IP addresses)"
+// @Router       /net/ip-info/bulk [post]
+func (h *NetworkIntelligenceHandlers) BulkIPInfoHandler(c *gin.Context) {
+	var ipAddresses []string
+	if err := c.ShouldBindJSON(&ipAddresses); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request payload: expected a JSON array of IP addresses: " + err.Error()})
+		return
+	}
+	if len(ipAddresses) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "at least one IP address is required"})
+		return
+	}
+	if len(ipAddresses) > utils.MaxBulkIPInfoAddresses {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "too many IP addresses: maximum is " + strconv.Itoa(utils.MaxBulkIPInfoAddresses)})
+		return
+	}
+
+	start := time.Now()
+	utilResults := utils.GetBulkIPInfo(ipAddresses)
+	response := models.BulkIPInfoResponse{
+		Count:   len(utilResults),
+		Results: make([]models.IPInfoResponse, len(utilResults)),
+	}
+	failed := 0
+	for i, utilData := range utilResults {
+		response.Results[i] = ipInfoResponse(utilData)
+		if utilData.Error != "" {
+			failed++
+		}
+	}
+
+	duration := time.Since(start)
+	notifications.Notify(notifications.Notification{
+		Event:   notifications.EventJobCompleted,
+		Title:   "Bulk IP lookup completed",
+		Message: fmt.Sprintf("%d addresses looked up in %s, %d invalid", len(utilResults), duration.Round(time.Millisecond), failed),
+		Data:    map[string]any{"job": "ip-info-bulk", "count": len(utilResults), "failed": failed, "duration_ms": duration.Milliseconds()},
+	})
+	c.JSON(http.StatusOK, response)
+}
+
+// ipInfoResponse converts the util IP information into the API response.
+func ipInfoResponse(utilData utils.IPInfoData) models.IPInfoResponse {
+	return models.IPInfoResponse{
 		IPAddress:          utilData.IPAddress,
 		IsValid:            utilData.IsValid,
 		Version:            utilData.Version,
@@ -123,15 +189,191 @@ func (h *NetworkIntelligenceHandlers) IPInfoHandler(c *gin.Context) {
 		ASOrganization:     utilData.ASOrganization,
 		GeoError:           utilData.GeoError,
 	}
-	c.JSON(http.StatusOK, response)
+}
+
+// ASNInfoHandler godoc
+// @Summary      Get details about an autonomous system
+// @Description  Returns the organization, country and announced prefixes of an ASN, from RIPEstat or a configured local routing table dump. Results are cached.
+// @Tags         Network & Domain Intelligence
+// @Produce      json
+// @Param        asn query string true "AS number (e.g., AS13335 or 13335)"
+// @Param        canonical query bool false "Return RFC 8785 canonical JSON (sorted keys, no whitespace); the X-Canonical-JSON response header is false if that failed"
+// @Success      200 {object} models.ASNInfoResponse "ASN details or error during lookup"
+// @Failure      400 {object} map[string]string "Error: Invalid input (e.g., missing or malformed ASN)"
+// @Router       /net/asn-info [get]
+func (h *NetworkIntelligenceHandlers) ASNInfoHandler(c *gin.Context) {
+	asnQuery := c.Query("asn")
+	if asnQuery == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "asn query parameter is required"})
+		return
+	}
+	asn, err := utils.ParseASN(asnQuery)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 30*time.Second)
+	defer cancel()
+
+	asnInfo, err := utils.GetASNInfo(ctx, asn)
+	if err != nil {
+		c.JSON(http.StatusOK, models.ASNInfoResponse{ // Still 200 but with error in body
+			RequestASN: asnQuery,
+			Error:      err.Error(),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, models.ASNInfoResponse{
+		RequestASN: asnQuery,
+		ASNInfo:    asnInfo,
+	})
+}
+
+// BGPRouteHandler godoc
+// @Summary      Look up the BGP route and RPKI state of an IP or prefix
+// @Description  Finds the announced prefix covering an IP or prefix and reports its origin ASNs, their visible upstreams, and the RPKI validation state (valid/invalid/not-found) of each origin, using RIPEstat routing data.
+// @Tags         Network & Domain Intelligence
+// @Produce      json
+// @Param        resource query string true "IP address or prefix (e.g., 1.1.1.1 or 1.1.1.0/24)"
+// @Param        canonical query bool false "Return RFC 8785 canonical JSON (sorted keys, no whitespace); the X-Canonical-JSON response header is false if that failed"
+// @Success      200 {object} models.BGPRouteResponse "Route information or error during lookup"
+// @Failure      400 {object} map[string]string "Error: Invalid input (e.g., missing resource)"
+// @Router       /net/bgp-route [get]
+func (h *NetworkIntelligenceHandlers) BGPRouteHandler(c *gin.Context) {
+	resourceQuery := c.Query("resource")
+	if resourceQuery == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "resource query parameter is required"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 30*time.Second)
+	defer cancel()
+
+	routeInfo, err := bgp.LookupRoute(ctx, resourceQuery)
+	if err != nil {
+		c.JSON(http.StatusOK, models.BGPRouteResponse{ // Still 200 but with error in body
+			RequestResource: resourceQuery,
+			Error:           err.Error(),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, models.BGPRouteResponse{
+		RequestResource: resourceQuery,
+		RouteInfo:       routeInfo,
+	})
+}
+
+// GeofeedCheckHandler godoc
+// @Summary      Validate an IP geofeed (RFC 8805)
+// @Description  Fetches a published geofeed CSV, validates its syntax per RFC 8805 (prefixes, ISO 3166 country and region codes, duplicates), and cross-checks each entry's location against the loaded GeoLite2-City database, reporting conflicts.
+// @Tags         Network & Domain Intelligence
+// @Produce      json,html,application/pdf
+// @Param        url query string true "URL of the geofeed CSV"
+// @Param        format query string false "Response format: json (default), html for a standalone report page or pdf for a printable document"
+// @Param        canonical query bool false "Return RFC 8785 canonical JSON (sorted keys, no whitespace); the X-Canonical-JSON response header is false if that failed"
+// @Success      200 {object} models.GeofeedCheckResponse "Geofeed validation report or error during the check"
+// @Failure      400 {object} map[string]string "Error: Invalid input (e.g., missing url)"
+// @Router       /net/geofeed-check [get]
+func (h *NetworkIntelligenceHandlers) GeofeedCheckHandler(c *gin.Context) {
+	urlQuery := c.Query("url")
+	if urlQuery == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "url query parameter is required"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 45*time.Second)
+	defer cancel()
+
+	report, err := utils.CheckGeofeed(ctx, urlQuery)
+	if err != nil {
+		writeReport(c, "Geofeed Check", models.GeofeedCheckResponse{ // Still 200 but with error in body
+			RequestURL: urlQuery,
+			Error:      err.Error(),
+		})
+		return
+	}
+	writeReport(c, "Geofeed Check", models.GeofeedCheckResponse{
+		RequestURL:    urlQuery,
+		GeofeedReport: report,
+	})
+}
+
+// ReverseIPHandler godoc
+// @Summary      Find domains hosted on an IP
+// @Description  Returns the domains known to resolve to an IP, from its PTR records and, when configured, a passive DNS provider. Useful for spotting shared hosting.
+// @Tags         Network & Domain Intelligence
+// @Produce      json
+// @Param        ip query string true "IP address to look up"
+// @Param        canonical query bool false "Return RFC 8785 canonical JSON (sorted keys, no whitespace); the X-Canonical-JSON response header is false if that failed"
+// @Success      200 {object} models.ReverseIPResponse "Domains found or error during lookup"
+// @Failure      400 {object} map[string]string "Error: Invalid input (e.g., missing IP address)"
+// @Router       /net/reverse-ip [get]
+func (h *NetworkIntelligenceHandlers) ReverseIPHandler(c *gin.Context) {
+	ipAddress := c.Query("ip")
+	if ipAddress == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ip query parameter is required"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 30*time.Second)
+	defer cancel()
+
+	result, err := utils.LookupReverseIP(ctx, ipAddress)
+	if err != nil {
+		c.JSON(http.StatusOK, models.ReverseIPResponse{ // Still 200 but with error in body
+			RequestIP: ipAddress,
+			Error:     err.Error(),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, models.ReverseIPResponse{
+		RequestIP:       ipAddress,
+		ReverseIPResult: result,
+	})
+}
+
+// PassiveDNSHandler godoc
+// @Summary      Query the local passive DNS history
+// @Description  Returns the DNS answers the API itself has observed for a name (A, AAAA, MX, CNAME, TXT, ...) or pointing at an IP (A/AAAA records and PTR records of its reverse name), with first-seen and last-seen timestamps, most recently seen first. Every lookup made through the system resolver adds to the history, but not those sent to a resolver chosen with resolver=; at most 1000 observations are returned.
+// @Tags         Network & Domain Intelligence
+// @Produce      json
+// @Param        name query string false "Name to look up; *.example.com includes subdomains"
+// @Param        ip query string false "IP address to look up"
+// @Param        canonical query bool false "Return RFC 8785 canonical JSON (sorted keys, no whitespace); the X-Canonical-JSON response header is false if that failed"
+// @Success      200 {object} models.PassiveDNSResponse "Observed DNS answers"
+// @Failure      400 {object} map[string]string "Error: Invalid input (e.g., neither or both of name and ip, or a malformed value)"
+// @Router       /net/passive-dns [get]
+func (h *NetworkIntelligenceHandlers) PassiveDNSHandler(c *gin.Context) {
+	name, ip := c.Query("name"), c.Query("ip")
+	if (name == "") == (ip == "") {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "exactly one of the name and ip query parameters is required"})
+		return
+	}
+
+	var observations []utils.PassiveDNSObservation
+	var err error
+	if name != "" {
+		observations, err = utils.PassiveDNSByName(name)
+	} else {
+		observations, err = utils.PassiveDNSByIP(ip)
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, models.PassiveDNSResponse{Name: name, IP: ip, Count: len(observations), Observations: observations})
 }
 
 // WhoisLookupHandler godoc
 // @Summary      Perform WHOIS lookup for a domain
-// @Description  Retrieves WHOIS information for a given domain.
+// @Description  Retrieves WHOIS information for a given domain. When the WHOIS server refuses the query because of a rate limit or block, error_code is throttled and retry_after_seconds and the Retry-After header say when to retry; the server is not queried again until then. When a thin registry (such as .com and .net) refers to the registrar's WHOIS server, that server is queried too and its registrant and contact data merged in; registrar_whois_server names it and referral_error explains a failed referral.
 // @Tags         Network & Domain Intelligence
-// @Produce      json
+// @Produce      json,html,application/pdf
 // @Param        domain query string true "Domain for WHOIS lookup"
+// @Param        format query string false "Response format: json (default), html for a standalone report page or pdf for a printable document"
+// @Param        canonical query bool false "Return RFC 8785 canonical JSON (sorted keys, no whitespace); the X-Canonical-JSON response header is false if that failed"
+// @Param        cache query bool false "Set to false to skip the result cache and refresh it; the X-Cache response header reports HIT, MISS or BYPASS"
 // @Success      200 {object} models.WhoisLookupResponse "Successfully retrieved WHOIS information or error during lookup"
 // @Failure      400 {object} map[string]string "Error: Invalid input (e.g., missing domain)"
 // @Router       /net/whois-lookup [get]
@@ -142,43 +384,146 @@ func (h *NetworkIntelligenceHandlers) WhoisLookupHandler(c *gin.Context) {
 		return
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 30*time.Second)
 	defer cancel()
 
 	whoisInfo, err := domain.GetWhoisInfo(ctx, domainQuery) // domain.GetWhoisInfo
 	if err != nil {
-		c.JSON(http.StatusOK, models.WhoisLookupResponse{ // Still 200 but with error in body
+		response := models.WhoisLookupResponse{ // Still 200 but with error in body
 			Domain:    domainQuery,
 			QueryTime: time.Now(),
 			Error:     err.Error(),
-		})
+		}
+		if code, retryAfter := domain.WhoisErrorCode(err); code != "" {
+			response.ErrorCode = code
+			response.RetryAfterSeconds = int(math.Ceil(retryAfter.Seconds()))
+			c.Header("Retry-After", strconv.Itoa(response.RetryAfterSeconds))
+		}
+		writeReport(c, "WHOIS Lookup", response)
 		return
 	}
 	response := models.WhoisLookupResponse{
-		Domain:          whoisInfo.Domain,
-		Registrar:       whoisInfo.Registrar,
-		CreationDate:    whoisInfo.CreationDate,
-		ExpirationDate:  whoisInfo.ExpirationDate,
-		UpdatedDate:     whoisInfo.UpdatedDate,
-		NameServers:     whoisInfo.NameServers,
-		Status:          whoisInfo.Status,
-		RegistrantOrg:   whoisInfo.RegistrantOrg,
-		RegistrantEmail: whoisInfo.RegistrantEmail,
-		AdminEmail:      whoisInfo.AdminEmail,
-		TechEmail:       whoisInfo.TechEmail,
-		WhoisServer:     whoisInfo.WhoisServer,
-		QueryTime:       whoisInfo.QueryTime,
+		Domain:               whoisInfo.Domain,
+		Registrar:            whoisInfo.Registrar,
+		CreationDate:         whoisInfo.CreationDate,
+		ExpirationDate:       whoisInfo.ExpirationDate,
+		UpdatedDate:          whoisInfo.UpdatedDate,
+		RawDates:             whoisInfo.RawDates,
+		NameServers:          whoisInfo.NameServers,
+		Status:               whoisInfo.Status,
+		RegistrantOrg:        whoisInfo.RegistrantOrg,
+		RegistrantEmail:      whoisInfo.RegistrantEmail,
+		AdminEmail:           whoisInfo.AdminEmail,
+		TechEmail:            whoisInfo.TechEmail,
+		WhoisServer:          whoisInfo.WhoisServer,
+		RegistrarWhoisServer: whoisInfo.RegistrarWhoisServer,
+		ReferralError:        whoisInfo.ReferralError,
+		QueryTime:            whoisInfo.QueryTime,
+	}
+	writeReport(c, "WHOIS Lookup", response)
+}
+
+// BulkWhoisLookupHandler godoc
+// @Summary      Look up the expiry of many domains
+// @Description  Accepts a JSON array of up to 100 domains and returns the registrar, expiration date and days until expiry of each, in request order, for building renewal dashboards. Queries run through a worker pool and are rate limited per WHOIS server to avoid bans, so large batches against one registry may take a minute; a job.completed notification is sent when they finish.
+// @Tags         Network & Domain Intelligence
+// @Accept       json
+// @Produce      json
+// @Param        domains body []string true "Domains to look up"
+// @Param        canonical query bool false "Return RFC 8785 canonical JSON (sorted keys, no whitespace); the X-Canonical-JSON response header is false if that failed"
+// @Success      200 {object} models.BulkWhoisLookupResponse "Expiry information per domain, with errors in the results"
+// @Failure      400 {object} map[string]string "Error: Invalid input (e.g., empty or too many domains)"
+// @Router       /net/whois-lookup/bulk [post]
+func (h *NetworkIntelligenceHandlers) BulkWhoisLookupHandler(c *gin.Context) {
+	var domains []string
+	if err := c.ShouldBindJSON(&domains); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request payload: expected a JSON array of domains: " + err.Error()})
+		return
+	}
+	if len(domains) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "at least one domain is required"})
+		return
+	}
+	if len(domains) > domain.MaxBulkWhoisDomains {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "too many domains: maximum is " + strconv.Itoa(domain.MaxBulkWhoisDomains)})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 2*time.Minute)
+	defer cancel()
+
+	start := time.Now()
+	results := domain.GetBulkWhoisExpiry(ctx, domains)
+	response := models.BulkWhoisLookupResponse{Count: len(results), Results: results}
+	for _, result := range results {
+		if result.Error != "" {
+			response.Failed++
+		}
 	}
+
+	duration := time.Since(start)
+	notifications.Notify(notifications.Notification{
+		Event:   notifications.EventJobCompleted,
+		Title:   "Bulk WHOIS lookup completed",
+		Message: fmt.Sprintf("%d domains looked up in %s, %d failed", len(results), duration.Round(time.Millisecond), response.Failed),
+		Data:    map[string]any{"job": "whois-lookup-bulk", "count": len(results), "failed": response.Failed, "duration_ms": duration.Milliseconds()},
+	})
 	c.JSON(http.StatusOK, response)
 }
 
+// RDAPLookupHandler godoc
+// @Summary      Perform RDAP lookup for a domain
+// @Description  Retrieves structured registration data (events, entities, nameservers, status) over RDAP. The RDAP server is found through the IANA bootstrap registry and registrar referrals are followed. Falls back to WHOIS when RDAP is unavailable; the source field tells which was used.
+// @Tags         Network & Domain Intelligence
+// @Produce      json,html,application/pdf
+// @Param        domain query string true "Domain for RDAP lookup"
+// @Param        format query string false "Response format: json (default), html for a standalone report page or pdf for a printable document"
+// @Param        canonical query bool false "Return RFC 8785 canonical JSON (sorted keys, no whitespace); the X-Canonical-JSON response header is false if that failed"
+// @Success      200 {object} models.RDAPLookupResponse "Successfully retrieved registration data or error during lookup"
+// @Failure      400 {object} map[string]string "Error: Invalid input (e.g., missing domain)"
+// @Router       /net/rdap-lookup [get]
+func (h *NetworkIntelligenceHandlers) RDAPLookupHandler(c *gin.Context) {
+	domainQuery := c.Query("domain")
+	if domainQuery == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "domain query parameter is required"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 30*time.Second)
+	defer cancel()
+
+	rdapInfo, err := domain.GetRDAPInfo(ctx, domainQuery)
+	if err != nil {
+		writeReport(c, "RDAP Lookup", models.RDAPLookupResponse{ // Still 200 but with error in body
+			RequestDomain: domainQuery,
+			Error:         err.Error(),
+		})
+		return
+	}
+	writeReport(c, "RDAP Lookup", models.RDAPLookupResponse{
+		RequestDomain: domainQuery,
+		RDAPInfo:      rdapInfo,
+	})
+}
+
 // SSLCheckHandler godoc
 // @Summary      Check SSL certificate information for a domain/host
-// @Description  Retrieves SSL certificate details for a given host and optional port (defaults to 443).
+// @Description  Retrieves SSL certificate details for a given host and optional port (defaults to 443), graded A to F from findings such as SHA-1 signatures, weak keys, expiry, untrusted chains, overly long validity and missing SANs. With all_ips=true every resolved address is checked, so load balancer or dual-stack endpoints serving different certificates are flagged.
 // @Tags         Network & Domain Intelligence
-// @Produce      json
+// @Produce      json,html,application/pdf
 // @Param        host query string true "Host (domain or IP) for SSL check"
 // @Param        port query int false "Port for SSL check (defaults to 443)"
+// @Param        revocation query bool false "Check the leaf certificate's revocation status via OCSP, falling back to its CRL"
+// @Param        protocols query bool false "Probe TLS 1.0, 1.1, 1.2 and 1.3 on separate connections, reporting which are enabled; deprecated versions that are enabled are added to validation_errors"
+// @Param        session query bool false "Probe session ticket and session ID resumption, secure renegotiation, OCSP stapling and the ALPN protocols (h2, http/1.1) the server accepts, on separate connections"
+// @Param        client_profile query string false "Name of a client certificate profile (SSL_CLIENT_PROFILES_PATH) to present if the server requests one; use POST to send a certificate"
+// @Param        all_ips query bool false "Check the certificate on every A/AAAA address of the host, listing each under endpoints and any differences between them under endpoint_mismatches"
+// @Param        verify_host query string false "Another hostname to verify against the presented certificate, reported under alternate_hostname with the SAN that matched"
+// @Param        expiry_threshold query string false "Set expires_within to whether the certificate expires within this duration, e.g. 72h, or number of seconds"
+// @Param        format query string false "Response format: json (default), html for a standalone report page or pdf for a printable document"
+// @Param        canonical query bool false "Return RFC 8785 canonical JSON (sorted keys, no whitespace); the X-Canonical-JSON response header is false if that failed"
+// @Param        vantage query string false "Comma-separated vantage points to run the check from, e.g. eu,local; the results are returned side by side as a models.VantageComparisonResponse"
+// @Param        cache query bool false "Set to false to skip the result cache and refresh it; the X-Cache response header reports HIT, MISS or BYPASS"
 // @Success      200 {object} models.SSLCheckResponse "Successfully retrieved SSL certificate information or error during check"
 // @Failure      400 {object} map[string]string "Error: Invalid input (e.g., missing host)"
 // @Router       /net/ssl-check [get]
@@ -200,19 +545,228 @@ func (h *NetworkIntelligenceHandlers) SSLCheckHandler(c *gin.Context) {
 		}
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Second) // Adjusted timeout
+	options := domain.SSLCheckOptions{
+		Port:            port, // Util defaults port to 443
+		CheckRevocation: c.Query("revocation") == "true",
+		AllIPs:          c.Query("all_ips") == "true",
+		ProbeProtocols:  c.Query("protocols") == "true",
+		ProbeSession:    c.Query("session") == "true",
+		VerifyHost:      c.Query("verify_host"),
+	}
+	if profile := c.Query("client_profile"); profile != "" {
+		if options.ClientCertificate, err = domain.SSLClientProfile(profile); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+	expiryThreshold, err := parseExpiryThreshold(c.Query("expiry_threshold"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	writeSSLCheck(c, hostQuery, options, expiryThreshold)
+}
+
+// SSLCheckWithClientCertHandler godoc
+// @Summary      Check SSL certificate information presenting a client certificate
+// @Description  Runs the SSL check like GET /net/ssl-check, presenting the PEM client certificate and key from the body (or a preconfigured client_profile) if the server requests one, so servers requiring mutual TLS can be inspected. client_auth reports whether a certificate was requested and the CAs the server accepts.
+// @Tags         Network & Domain Intelligence
+// @Accept       json
+// @Produce      json,html,application/pdf
+// @Param        request body models.SSLCheckRequest true "Host to check and the client certificate to present"
+// @Param        format query string false "Response format: json (default), html for a standalone report page or pdf for a printable document"
+// @Param        canonical query bool false "Return RFC 8785 canonical JSON (sorted keys, no whitespace); the X-Canonical-JSON response header is false if that failed"
+// @Success      200 {object} models.SSLCheckResponse "Successfully retrieved SSL certificate information or error during check"
+// @Failure      400 {object} map[string]string "Error: Invalid input (e.g., missing host or an unusable client certificate)"
+// @Router       /net/ssl-check [post]
+func (h *NetworkIntelligenceHandlers) SSLCheckWithClientCertHandler(c *gin.Context) {
+	var request models.SSLCheckRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request payload: " + err.Error()})
+		return
+	}
+	if request.Port < 0 || request.Port > 65535 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid port number"})
+		return
+	}
+
+	options := domain.SSLCheckOptions{
+		Port:            request.Port,
+		CheckRevocation: request.Revocation,
+		AllIPs:          request.AllIPs,
+		ProbeProtocols:  request.Protocols,
+		ProbeSession:    request.Session,
+		VerifyHost:      request.VerifyHost,
+	}
+	var err error
+	switch {
+	case request.ClientProfile != "" && request.ClientCertPEM != "":
+		c.JSON(http.StatusBadRequest, gin.H{"error": "give either client_profile or client_cert_pem, not both"})
+		return
+	case request.ClientProfile != "":
+		options.ClientCertificate, err = domain.SSLClientProfile(request.ClientProfile)
+	case request.ClientCertPEM != "" || request.ClientKeyPEM != "":
+		options.ClientCertificate, err = domain.ParseClientCertificate(request.ClientCertPEM, request.ClientKeyPEM)
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	expiryThreshold, err := parseExpiryThreshold(request.ExpiryThreshold)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	writeSSLCheck(c, request.Domain, options, expiryThreshold)
+}
+
+// SSLBatchCheckHandler godoc
+// @Summary      Check the certificates of many hosts
+// @Description  Checks up to 500 host or host:port targets (port 443 by default) through a bounded worker pool, each with its own timeout, and returns per-target certificate details in request order plus summary counts: failed checks, invalid certificates (expired, untrusted or not covering the host), expired, expiring within 30 days, weak keys or signatures, and the grade distribution. A job.completed notification is sent when the batch finishes.
+// @Tags         Network & Domain Intelligence
+// @Accept       json
+// @Produce      json
+// @Param        request body models.SSLBatchRequest true "Targets to check"
+// @Param        canonical query bool false "Return RFC 8785 canonical JSON (sorted keys, no whitespace); the X-Canonical-JSON response header is false if that failed"
+// @Success      200 {object} models.SSLBatchResponse "Summary and certificate details per target, with errors in the results"
+// @Failure      400 {object} map[string]string "Error: Invalid input (e.g., empty or too many targets)"
+// @Router       /net/ssl-check/batch [post]
+func (h *NetworkIntelligenceHandlers) SSLBatchCheckHandler(c *gin.Context) {
+	var request models.SSLBatchRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request payload: " + err.Error()})
+		return
+	}
+	if len(request.Targets) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "at least one target is required"})
+		return
+	}
+	if len(request.Targets) > domain.MaxBatchSSLTargets {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "too many targets: maximum is " + strconv.Itoa(domain.MaxBatchSSLTargets)})
+		return
+	}
+	timeout := domain.DefaultBatchSSLTimeout
+	if request.TimeoutSeconds != 0 {
+		timeout = time.Duration(request.TimeoutSeconds) * time.Second
+		if timeout < time.Second || timeout > domain.MaxBatchSSLTimeout {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("timeout_seconds must be between 1 and %d", int(domain.MaxBatchSSLTimeout.Seconds()))})
+			return
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 5*time.Minute)
 	defer cancel()
 
-	var sslInfo *domain.SSLInfo // Assuming domain.SSLInfo is the struct from your util
+	start := time.Now()
+	results, summary := domain.CheckSSLBatch(ctx, request.Targets, timeout)
 
-	if port > 0 {
-		sslInfo, err = domain.GetSSLInfo(ctx, hostQuery, port)
-	} else {
-		sslInfo, err = domain.GetSSLInfo(ctx, hostQuery) // Util defaults port to 443
+	duration := time.Since(start)
+	notifications.Notify(notifications.Notification{
+		Event:   notifications.EventJobCompleted,
+		Title:   "Batch SSL check completed",
+		Message: fmt.Sprintf("%d targets checked in %s, %d failed, %d invalid, %d expiring within 30 days", summary.Total, duration.Round(time.Millisecond), summary.Failed, summary.Invalid, summary.ExpiringWithin30),
+		Data:    map[string]any{"job": "ssl-check-batch", "count": summary.Total, "failed": summary.Failed, "invalid": summary.Invalid, "duration_ms": duration.Milliseconds()},
+	})
+	c.JSON(http.StatusOK, models.SSLBatchResponse{Summary: summary, Results: results})
+}
+
+// maxCertDecodeBody caps the size of an uploaded certificate.
+const maxCertDecodeBody = 1 << 20
+
+// CertDecodeHandler godoc
+// @Summary      Analyze an uploaded certificate or CSR
+// @Description  Decodes a PEM certificate (optionally followed by its chain), a DER certificate or a certificate signing request sent as the request body and runs the SSL check's analysis on it without connecting anywhere: subject, issuer, SANs, validity, key strength, signature algorithm, fingerprints, public key pin, chain trust and grade, plus every extension with its OID and criticality. For a CSR the key, requested names, extensions and signature are reported. With host the certificate is also verified against that hostname.
+// @Tags         Network & Domain Intelligence
+// @Accept       plain,octet-stream
+// @Produce      json,html,application/pdf
+// @Param        certificate body string true "PEM or DER certificate or CSR"
+// @Param        host query string false "Hostname to verify the certificate against"
+// @Param        format query string false "Response format: json (default), html for a standalone report page or pdf for a printable document"
+// @Param        canonical query bool false "Return RFC 8785 canonical JSON (sorted keys, no whitespace); the X-Canonical-JSON response header is false if that failed"
+// @Success      200 {object} models.CertDecodeResponse "Certificate or CSR analysis"
+// @Failure      400 {object} map[string]string "Error: Invalid input (e.g., empty body or not a certificate)"
+// @Router       /net/cert-decode [post]
+func (h *NetworkIntelligenceHandlers) CertDecodeHandler(c *gin.Context) {
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxCertDecodeBody)
+	data, err := c.GetRawData()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "failed to read the certificate: " + err.Error()})
+		return
+	}
+	decoded, err := domain.DecodeCertificate(data, strings.TrimSpace(c.Query("host")))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
 	}
 
+	response := models.CertDecodeResponse{Type: decoded.Type, Details: decoded.Details, CSR: decoded.CSR}
+	if decoded.Certificate != nil {
+		certificate := newSSLCheckResponse(decoded.Certificate)
+		response.Certificate = &certificate
+	}
+	writeReport(c, "Certificate Decode", response)
+}
+
+// SSLCompareHandler godoc
+// @Summary      Compare the certificates served by two hosts, ports or addresses
+// @Description  Checks two targets concurrently and diffs their leaf certificates: subject, issuer, serial, SANs, validity, SHA-256 fingerprint, public key pin (RFC 7469), chain trust and TLS version. The second target defaults to the first host, so one host can be compared on two ports or IP addresses, e.g. the old and new load balancer during a migration. With vantage the comparison runs from each vantage point, exposing man-in-the-middle differences between networks.
+// @Tags         Network & Domain Intelligence
+// @Produce      json,html,application/pdf
+// @Param        host_a query string true "First host (domain or IP)"
+// @Param        host_b query string false "Second host; defaults to host_a"
+// @Param        port_a query int false "Port of the first target (defaults to 443)"
+// @Param        port_b query int false "Port of the second target (defaults to 443)"
+// @Param        ip_a query string false "Connect to this address for the first target instead of resolving its host"
+// @Param        ip_b query string false "Connect to this address for the second target instead of resolving its host"
+// @Param        format query string false "Response format: json (default), html for a standalone report page or pdf for a printable document"
+// @Param        canonical query bool false "Return RFC 8785 canonical JSON (sorted keys, no whitespace); the X-Canonical-JSON response header is false if that failed"
+// @Param        vantage query string false "Comma-separated vantage points to run the check from, e.g. eu,local; the results are returned side by side as a models.VantageComparisonResponse"
+// @Success      200 {object} models.SSLCompareResponse "Both certificates and their differences; a side whose check failed carries its error"
+// @Failure      400 {object} map[string]string "Error: Invalid input (e.g., missing host_a, a bad port or two identical targets)"
+// @Router       /net/ssl-compare [get]
+func (h *NetworkIntelligenceHandlers) SSLCompareHandler(c *gin.Context) {
+	a := domain.SSLTarget{Host: c.Query("host_a"), IP: c.Query("ip_a")}
+	if a.Host == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "host_a query parameter is required"})
+		return
+	}
+	b := domain.SSLTarget{Host: c.DefaultQuery("host_b", a.Host), IP: c.Query("ip_b")}
+
+	for _, side := range []struct {
+		param  string
+		target *domain.SSLTarget
+	}{{"port_a", &a}, {"port_b", &b}} {
+		if portStr := c.Query(side.param); portStr != "" {
+			port, err := strconv.Atoi(portStr)
+			if err != nil || port <= 0 || port > 65535 {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid port number in " + side.param})
+				return
+			}
+			side.target.Port = port
+		}
+	}
+	if a == b {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "the two targets are identical; set host_b, port_b or ip_b"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 20*time.Second)
+	defer cancel()
+
+	writeReport(c, "SSL Certificate Comparison", models.SSLCompareResponse{
+		Comparison: domain.CompareSSL(ctx, a, b),
+	})
+}
+
+// writeSSLCheck runs the SSL check and writes its report. With an expiry threshold,
+// expires_within reports whether the certificate expires within it.
+func writeSSLCheck(c *gin.Context, hostQuery string, options domain.SSLCheckOptions, expiryThreshold *time.Duration) {
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 20*time.Second) // Adjusted timeout
+	defer cancel()
+
+	sslInfo, err := domain.GetSSLInfoWithOptions(ctx, hostQuery, options)
 	if err != nil {
-		c.JSON(http.StatusOK, models.SSLCheckResponse{ // Still 200 but with error in body
+		writeReport(c, "SSL Certificate Check", models.SSLCheckResponse{ // Still 200 but with error in body
 			Domain:    hostQuery, // Use hostQuery as Domain for response consistency
 			QueryTime: time.Now(),
 			Error:     err.Error(),
@@ -220,17 +774,17 @@ func (h *NetworkIntelligenceHandlers) SSLCheckHandler(c *gin.Context) {
 		return
 	}
 
-	certificateChain := make([]models.CertificateInfo, len(sslInfo.CertificateChain))
-	for i, cert := range sslInfo.CertificateChain {
-		certificateChain[i] = models.CertificateInfo{
-			Subject:   cert.Subject,
-			Issuer:    cert.Issuer,
-			NotBefore: cert.NotBefore,
-			NotAfter:  cert.NotAfter,
-			IsCA:      cert.IsCA,
-			KeyUsage:  cert.KeyUsage,
-		}
+	response := newSSLCheckResponse(sslInfo)
+	if expiryThreshold != nil {
+		expiresWithin := response.ExpiresInSeconds <= int64(expiryThreshold.Seconds())
+		response.ExpiresWithin = &expiresWithin
 	}
+	writeReport(c, "SSL Certificate Check", response)
+}
+
+// newSSLCheckResponse maps the result of an SSL check to its response.
+func newSSLCheckResponse(sslInfo *domain.SSLInfo) models.SSLCheckResponse {
+	certificateChain := certificateInfoList(sslInfo.CertificateChain)
 
 	response := models.SSLCheckResponse{
 		Domain:             sslInfo.Domain,
@@ -238,9 +792,12 @@ func (h *NetworkIntelligenceHandlers) SSLCheckHandler(c *gin.Context) {
 		Issuer:             sslInfo.Issuer,
 		Subject:            sslInfo.Subject,
 		SerialNumber:       sslInfo.SerialNumber,
+		FingerprintSHA256:  sslInfo.FingerprintSHA256,
+		PublicKeyPin:       sslInfo.PublicKeyPin,
 		NotBefore:          sslInfo.NotBefore,
 		NotAfter:           sslInfo.NotAfter,
 		DaysUntilExpiry:    sslInfo.DaysUntilExpiry,
+		ExpiresInSeconds:   sslInfo.ExpiresInSeconds,
 		SubjectAltNames:    sslInfo.SubjectAltNames,
 		SignatureAlgorithm: sslInfo.SignatureAlgorithm,
 		PublicKeyAlgorithm: sslInfo.PublicKeyAlgorithm,
@@ -248,11 +805,494 @@ func (h *NetworkIntelligenceHandlers) SSLCheckHandler(c *gin.Context) {
 		Version:            sslInfo.Version,
 		IsSelfSigned:       sslInfo.IsSelfSigned,
 		IsWildcard:         sslInfo.IsWildcard,
+		Hostname:           &sslInfo.Hostname,
+		AlternateHostname:  sslInfo.AlternateHostname,
 		CertificateChain:   certificateChain,
 		TLSVersion:         sslInfo.TLSVersion,
 		CipherSuite:        sslInfo.CipherSuite,
 		ValidationErrors:   sslInfo.ValidationErrors,
+		Grade:              sslInfo.Grade,
+		Findings:           sslInfo.Findings,
+		ChainTrusted:       sslInfo.ChainTrusted,
+		VerificationError:  sslInfo.VerificationError,
+		TrustIssue:         sslInfo.TrustIssue,
+		UntrustedRoot:      sslInfo.UntrustedRoot,
+		VerifiedChain:      certificateInfoList(sslInfo.VerifiedChain),
+		Revocation:         sslInfo.Revocation,
+		ClientAuth:         sslInfo.ClientAuth,
+		Protocols:          sslInfo.Protocols,
+		Session:            sslInfo.Session,
+		Endpoints:          sslInfo.Endpoints,
+		EndpointMismatches: sslInfo.EndpointMismatches,
 		QueryTime:          sslInfo.QueryTime,
 	}
+	if !sslInfo.NotAfter.IsZero() {
+		response.NotAfterUTC = sslInfo.NotAfter.UTC().Format(time.RFC3339)
+	}
+	if sslInfo.Hostname.Host == "" {
+		response.Hostname = nil // No host was checked
+	}
+	return response
+}
+
+// parseExpiryThreshold reads an expiry threshold given as a duration such as 72h or as a
+// number of seconds.
+func parseExpiryThreshold(value string) (*time.Duration, error) {
+	if value == "" {
+		return nil, nil
+	}
+	threshold, err := time.ParseDuration(value)
+	if err != nil {
+		seconds, convErr := strconv.ParseInt(value, 10, 64)
+		if convErr != nil {
+			return nil, fmt.Errorf("invalid expiry_threshold %q: use a duration such as 72h or a number of seconds", value)
+		}
+		threshold = time.Duration(seconds) * time.Second
+	}
+	if threshold < 0 {
+		return nil, fmt.Errorf("expiry_threshold must not be negative")
+	}
+	return &threshold, nil
+}
+
+// EmailSecurityHandler godoc
+// @Summary      Check a domain's email authentication (SPF, DMARC, DKIM)
+// @Description  Fetches and parses the SPF record (resolving includes recursively and validating the 10 DNS lookup limit), the DMARC policy, and the DKIM keys for the given selectors, returning pass/warn/fail findings.
+// @Tags         Network & Domain Intelligence
+// @Produce      json,html,application/pdf
+// @Param        domain query string true "Domain to check"
+// @Param        selectors query []string false "DKIM selectors to check (e.g., google, selector1, k1)" collectionFormat(csv)
+// @Param        resolver query string false "Resolver to query instead of the system resolver: an IP/host with optional port or a DNS-over-HTTPS URL"
+// @Param        format query string false "Response format: json (default), html for a standalone report page or pdf for a printable document"
+// @Param        canonical query bool false "Return RFC 8785 canonical JSON (sorted keys, no whitespace); the X-Canonical-JSON response header is false if that failed"
+// @Param        vantage query string false "Comma-separated vantage points to run the check from, e.g. eu,local; the results are returned side by side as a models.VantageComparisonResponse"
+// @Success      200 {object} models.EmailSecurityResponse "Email authentication report or error during the check"
+// @Failure      400 {object} map[string]string "Error: Invalid input (e.g., missing domain)"
+// @Router       /net/email-security [get]
+func (h *NetworkIntelligenceHandlers) EmailSecurityHandler(c *gin.Context) {
+	domainQuery := c.Query("domain")
+	if domainQuery == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "domain query parameter is required"})
+		return
+	}
+
+	var selectors []string
+	for _, value := range c.QueryArray("selectors") {
+		selectors = append(selectors, strings.Split(value, ",")...)
+	}
+
+	resolver, err := utils.NewResolver(c.Query("resolver"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid resolver: " + err.Error()})
+		return
+	}
+
+	if err := utils.CheckOutboundName(domainQuery); err != nil {
+		writeReport(c, "Email Security", models.EmailSecurityResponse{ // Still 200 but with error in body
+			RequestDomain: domainQuery,
+			Resolver:      resolver.Name(),
+			Error:         err.Error(),
+		})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 45*time.Second)
+	defer cancel()
+
+	writeReport(c, "Email Security", models.EmailSecurityResponse{
+		RequestDomain: domainQuery,
+		Resolver:      resolver.Name(),
+		Report:        emailauth.Analyze(ctx, resolver, domainQuery, selectors),
+	})
+}
+
+// CAACheckHandler godoc
+// @Summary      Evaluate a domain's effective CAA policy
+// @Description  Finds the CAA records that apply to a domain by climbing the DNS tree until a name has some (RFC 8659), and reports the CAs authorized to issue certificates and wildcard certificates, whether issuance is forbidden, iodef contacts and unknown critical properties. Unless check_certificate=false, the certificate the domain serves on port 443 is checked too, reporting whether its issuer is permitted by the policy.
+// @Tags         Network & Domain Intelligence
+// @Produce      json,html,application/pdf
+// @Param        domain query string true "Domain to check"
+// @Param        check_certificate query bool false "Check whether the issuer of the served certificate is authorized (default true)"
+// @Param        resolver query string false "Resolver to query instead of the system resolver: an IP/host with optional port or a DNS-over-HTTPS URL"
+// @Param        format query string false "Response format: json (default), html for a standalone report page or pdf for a printable document"
+// @Param        canonical query bool false "Return RFC 8785 canonical JSON (sorted keys, no whitespace); the X-Canonical-JSON response header is false if that failed"
+// @Param        vantage query string false "Comma-separated vantage points to run the check from, e.g. eu,local; the results are returned side by side as a models.VantageComparisonResponse"
+// @Success      200 {object} models.CAACheckResponse "Effective CAA policy or error during the check"
+// @Failure      400 {object} map[string]string "Error: Invalid input (e.g., missing domain)"
+// @Router       /net/caa-check [get]
+func (h *NetworkIntelligenceHandlers) CAACheckHandler(c *gin.Context) {
+	domainQuery := c.Query("domain")
+	if domainQuery == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "domain query parameter is required"})
+		return
+	}
+
+	resolver, err := utils.NewResolver(c.Query("resolver"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid resolver: " + err.Error()})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 45*time.Second)
+	defer cancel()
+
+	policy := caa.Check(ctx, resolver, domainQuery, c.Query("check_certificate") != "false")
+	writeReport(c, "CAA Policy", models.CAACheckResponse{
+		RequestDomain: domainQuery,
+		Resolver:      resolver.Name(),
+		Policy:        policy,
+		Error:         policy.Error, // Still 200 but with error in body
+	})
+}
+
+// DNSSECCheckHandler godoc
+// @Summary      Validate a domain's DNSSEC chain of trust
+// @Description  Walks the delegation chain from the root to the domain, verifying the DS, DNSKEY and RRSIG records of each zone, and reports whether the zone is signed, the algorithms and key tags used, and any broken links in the chain.
+// @Tags         Network & Domain Intelligence
+// @Produce      json,html,application/pdf
+// @Param        domain query string true "Domain to check"
+// @Param        resolver query string false "Resolver to query instead of the system resolver: an IP/host with optional port or a DNS-over-HTTPS URL. It must pass DNSSEC records through."
+// @Param        format query string false "Response format: json (default), html for a standalone report page or pdf for a printable document"
+// @Param        canonical query bool false "Return RFC 8785 canonical JSON (sorted keys, no whitespace); the X-Canonical-JSON response header is false if that failed"
+// @Param        vantage query string false "Comma-separated vantage points to run the check from, e.g. eu,local; the results are returned side by side as a models.VantageComparisonResponse"
+// @Success      200 {object} models.DNSSECCheckResponse "DNSSEC report or error during the check"
+// @Failure      400 {object} map[string]string "Error: Invalid input (e.g., missing domain)"
+// @Router       /net/dnssec-check [get]
+func (h *NetworkIntelligenceHandlers) DNSSECCheckHandler(c *gin.Context) {
+	domainQuery := c.Query("domain")
+	if domainQuery == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "domain query parameter is required"})
+		return
+	}
+
+	resolver, err := utils.NewResolver(c.Query("resolver"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid resolver: " + err.Error()})
+		return
+	}
+
+	if err := utils.CheckOutboundName(domainQuery); err != nil {
+		writeReport(c, "DNSSEC Check", models.DNSSECCheckResponse{ // Still 200 but with error in body
+			RequestDomain: domainQuery,
+			Resolver:      resolver.Name(),
+			Error:         err.Error(),
+		})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 45*time.Second)
+	defer cancel()
+
+	report := dnssec.Check(ctx, resolver, domainQuery)
+	writeReport(c, "DNSSEC Check", models.DNSSECCheckResponse{
+		RequestDomain: domainQuery,
+		Resolver:      resolver.Name(),
+		Report:        report,
+		Error:         report.Error,
+	})
+}
+
+// ZoneTransferHandler godoc
+// @Summary      Test a domain's nameservers for zone transfer (AXFR) exposure
+// @Description  Attempts an AXFR of the domain against each of its authoritative nameservers and reports which of them allow the transfer, with the record count and a sample of the records received. Open zone transfers leak every host name in the zone.
+// @Tags         Network & Domain Intelligence
+// @Produce      json,html,application/pdf
+// @Param        domain query string true "Zone to test, e.g. example.com"
+// @Param        resolver query string false "Resolver used to find the nameservers instead of the system resolver: an IP/host with optional port or a DNS-over-HTTPS URL"
+// @Param        format query string false "Response format: json (default), html for a standalone report page or pdf for a printable document"
+// @Param        canonical query bool false "Return RFC 8785 canonical JSON (sorted keys, no whitespace); the X-Canonical-JSON response header is false if that failed"
+// @Param        vantage query string false "Comma-separated vantage points to run the check from, e.g. eu,local; the results are returned side by side as a models.VantageComparisonResponse"
+// @Success      200 {object} models.ZoneTransferResponse "Per-nameserver AXFR results or error during the check"
+// @Failure      400 {object} map[string]string "Error: Invalid input (e.g., missing domain)"
+// @Router       /net/zone-transfer [get]
+func (h *NetworkIntelligenceHandlers) ZoneTransferHandler(c *gin.Context) {
+	domainQuery := c.Query("domain")
+	if domainQuery == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "domain query parameter is required"})
+		return
+	}
+
+	resolver, err := utils.NewResolver(c.Query("resolver"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid resolver: " + err.Error()})
+		return
+	}
+
+	if err := utils.CheckOutboundName(domainQuery); err != nil {
+		writeReport(c, "Zone Transfer Check", models.ZoneTransferResponse{ // Still 200 but with error in body
+			RequestDomain: domainQuery,
+			Resolver:      resolver.Name(),
+			Error:         err.Error(),
+		})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 45*time.Second)
+	defer cancel()
+
+	report := utils.CheckZoneTransfer(ctx, resolver, domainQuery)
+	writeReport(c, "Zone Transfer Check", models.ZoneTransferResponse{
+		RequestDomain:      domainQuery,
+		Resolver:           resolver.Name(),
+		ZoneTransferReport: report,
+		Error:              report.Error,
+	})
+}
+
+// BlacklistCheckHandler godoc
+// @Summary      Check an IP or domain against DNS blacklists
+// @Description  Queries the configured DNSBLs concurrently (by default Spamhaus ZEN, Barracuda and SORBS for IPs, Spamhaus DBL and SURBL for domains) and reports listed/not listed per blacklist, with the returned codes and TXT reason strings when available. Some blacklists refuse queries from public resolvers; those are reported as errors.
+// @Tags         Network & Domain Intelligence
+// @Produce      json,html,application/pdf
+// @Param        target query string true "IPv4/IPv6 address or domain to check"
+// @Param        resolver query string false "Resolver to query instead of the system resolver: an IP/host with optional port or a DNS-over-HTTPS URL"
+// @Param        format query string false "Response format: json (default), html for a standalone report page or pdf for a printable document"
+// @Param        canonical query bool false "Return RFC 8785 canonical JSON (sorted keys, no whitespace); the X-Canonical-JSON response header is false if that failed"
+// @Success      200 {object} models.BlacklistCheckResponse "Per-blacklist results or error during the check"
+// @Failure      400 {object} map[string]string "Error: Invalid input (e.g., missing or malformed target)"
+// @Router       /net/blacklist-check [get]
+func (h *NetworkIntelligenceHandlers) BlacklistCheckHandler(c *gin.Context) {
+	targetQuery := strings.TrimSpace(c.Query("target"))
+	if targetQuery == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "target query parameter is required"})
+		return
+	}
+
+	resolver, err := utils.NewResolver(c.Query("resolver"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid resolver: " + err.Error()})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 30*time.Second)
+	defer cancel()
+
+	report, err := utils.CheckBlacklists(ctx, resolver, targetQuery)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	writeReport(c, "Blacklist Check", models.BlacklistCheckResponse{
+		RequestTarget:   targetQuery,
+		Resolver:        resolver.Name(),
+		BlacklistReport: report,
+	})
+}
+
+// DomainReportHandler godoc
+// @Summary      Aggregate report on a domain
+// @Description  Runs the DNS, WHOIS, SSL, email security, DNSSEC and blacklist checks concurrently and returns them as sections of one report. A check that fails is returned as {status: "failed", error, partial: true} while the other sections still succeed; completeness is the share of sections that succeeded.
+// @Tags         Network & Domain Intelligence
+// @Produce      json,html,application/pdf
+// @Param        domain query string true "Domain to report on"
+// @Param        resolver query string false "Resolver to query instead of the system resolver: an IP/host with optional port or a DNS-over-HTTPS URL"
+// @Param        format query string false "Response format: json (default), html for a standalone report page or pdf for a printable document"
+// @Param        canonical query bool false "Return RFC 8785 canonical JSON (sorted keys, no whitespace); the X-Canonical-JSON response header is false if that failed"
+// @Param        vantage query string false "Comma-separated vantage points to run the check from, e.g. eu,local; the results are returned side by side as a models.VantageComparisonResponse"
+// @Success      200 {object} models.DomainReportResponse "Aggregate report, possibly with failed sections"
+// @Failure      400 {object} map[string]string "Error: Invalid input (e.g., missing domain)"
+// @Router       /net/domain-report [get]
+func (h *NetworkIntelligenceHandlers) DomainReportHandler(c *gin.Context) {
+	domainQuery := c.Query("domain")
+	if domainQuery == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "domain query parameter is required"})
+		return
+	}
+
+	resolver, err := utils.NewResolver(c.Query("resolver"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid resolver: " + err.Error()})
+		return
+	}
+
+	if err := utils.CheckOutboundName(domainQuery); err != nil {
+		writeReport(c, "Domain Report", models.DomainReportResponse{ // Still 200 but with error in body
+			RequestDomain: domainQuery,
+			Resolver:      resolver.Name(),
+			Error:         err.Error(),
+		})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 60*time.Second)
+	defer cancel()
+
+	writeReport(c, "Domain Report", models.DomainReportResponse{
+		RequestDomain: domainQuery,
+		Resolver:      resolver.Name(),
+		Report:        domainreport.Build(ctx, resolver, domainQuery),
+	})
+}
+
+// PingHandler godoc
+// @Summary      Ping a host
+// @Description  Sends a number of probes to a host and reports min/avg/max/stddev round trip times and packet loss. ICMP echo is used when the server may open raw sockets, otherwise the TCP handshake time to a port is measured.
+// @Tags         Network & Domain Intelligence
+// @Produce      json
+// @Param        host query string true "Host (domain or IP) to ping"
+// @Param        count query int false "Number of probes (defaults to 4, max 20)"
+// @Param        protocol query string false "Probe protocol: auto (default), icmp or tcp"
+// @Param        port query int false "Port for TCP probes (defaults to 443)"
+// @Param        canonical query bool false "Return RFC 8785 canonical JSON (sorted keys, no whitespace); the X-Canonical-JSON response header is false if that failed"
+// @Param        vantage query string false "Comma-separated vantage points to run the check from, e.g. eu,local; the results are returned side by side as a models.VantageComparisonResponse"
+// @Success      200 {object} models.PingResponse "Ping statistics or error during the run"
+// @Failure      400 {object} map[string]string "Error: Invalid input (e.g., missing host)"
+// @Router       /net/ping [get]
+func (h *NetworkIntelligenceHandlers) PingHandler(c *gin.Context) {
+	hostQuery := c.Query("host")
+	if hostQuery == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "host query parameter is required"})
+		return
+	}
+
+	options := utils.PingOptions{Protocol: strings.ToLower(c.DefaultQuery("protocol", utils.PingProtocolAuto))}
+	switch options.Protocol {
+	case utils.PingProtocolAuto, utils.PingProtocolICMP, utils.PingProtocolTCP:
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "protocol must be auto, icmp or tcp"})
+		return
+	}
+	if countStr := c.Query("count"); countStr != "" {
+		count, err := strconv.Atoi(countStr)
+		if err != nil || count <= 0 || count > utils.MaxPingCount {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("count must be between 1 and %d", utils.MaxPingCount)})
+			return
+		}
+		options.Count = count
+	}
+	if portStr := c.Query("port"); portStr != "" {
+		port, err := strconv.Atoi(portStr)
+		if err != nil || port <= 0 || port > 65535 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid port number"})
+			return
+		}
+		options.Port = port
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 60*time.Second)
+	defer cancel()
+
+	pingResult, err := utils.Ping(ctx, hostQuery, options)
+	if err != nil {
+		c.JSON(http.StatusOK, models.PingResponse{ // Still 200 but with error in body
+			RequestHost: hostQuery,
+			Error:       err.Error(),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, models.PingResponse{
+		RequestHost: hostQuery,
+		PingResult:  pingResult,
+	})
+}
+
+// Domain permutation page sizes; checking sends two DNS queries per permutation.
+const (
+	defaultPermutationsLimit    = 100
+	maxPermutationsLimit        = 1000
+	maxCheckedPermutations      = 200
+	permutationCheckConcurrency = 20
+)
+
+// DomainPermutationsHandler godoc
+// @Summary      Generate typosquat permutations of a domain
+// @Description  Generates the lookalike domains a phisher or typosquatter could register: bit flips (bitsquatting), homoglyphs (rn for m, 1 for l, Cyrillic а for a), inserted hyphens, neighbouring keys on a QWERTY keyboard and other TLDs. Only the label left of the public suffix is permuted. Permutations are grouped by fuzzer and paginated: pass next_offset back as offset until it is absent. With check=true each permutation on the page is looked up in DNS, and those with A or NS records are marked registered; a page is then limited to 200 permutations.
+// @Tags         Network & Domain Intelligence
+// @Produce      json
+// @Param        domain query string true "Domain to permute"
+// @Param        fuzzers query []string false "Fuzzers to run: bitsquatting, homoglyph, hyphenation, keyboard, tld-swap (all by default)" collectionFormat(csv)
+// @Param        check query bool false "Look up the A and NS records of the permutations on the page"
+// @Param        offset query int false "Permutations to skip (default 0)"
+// @Param        limit query int false "Permutations per page (default 100, maximum 1000, or 200 with check=true)"
+// @Param        resolver query string false "Resolver to query instead of the system resolver: an IP/host with optional port or a DNS-over-HTTPS URL"
+// @Success      200 {object} models.DomainPermutationsResponse "A page of permutations"
+// @Failure      400 {object} map[string]string "Error: Invalid input (e.g., missing domain, a public suffix, unknown fuzzer or a limit out of range)"
+// @Router       /net/domain-permutations [get]
+func (h *NetworkIntelligenceHandlers) DomainPermutationsHandler(c *gin.Context) {
+	domainQuery := c.Query("domain")
+	if domainQuery == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "domain query parameter is required"})
+		return
+	}
+	var fuzzers []string
+	for _, value := range c.QueryArray("fuzzers") {
+		for _, fuzzer := range strings.Split(value, ",") {
+			if fuzzer = strings.TrimSpace(fuzzer); fuzzer != "" {
+				fuzzers = append(fuzzers, fuzzer)
+			}
+		}
+	}
+	check := c.Query("check") == "true"
+	maxLimit := maxPermutationsLimit
+	if check {
+		maxLimit = maxCheckedPermutations
+	}
+	offset, limit := 0, min(defaultPermutationsLimit, maxLimit)
+	if value := c.Query("offset"); value != "" {
+		var err error
+		if offset, err = strconv.Atoi(value); err != nil || offset < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "offset must be a non-negative integer"})
+			return
+		}
+	}
+	if value := c.Query("limit"); value != "" {
+		var err error
+		if limit, err = strconv.Atoi(value); err != nil || limit < 1 || limit > maxLimit {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("limit must be between 1 and %d", maxLimit)})
+			return
+		}
+	}
+	resolver, err := utils.NewResolver(c.Query("resolver"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid resolver: " + err.Error()})
+		return
+	}
+
+	permutations, err := typosquat.Generate(domainQuery, fuzzers...)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	response := models.DomainPermutationsResponse{
+		RequestDomain: domainQuery,
+		Total:         len(permutations),
+		Offset:        offset,
+		Limit:         limit,
+		Permutations:  permutations[min(offset, len(permutations)):min(offset+limit, len(permutations))],
+	}
+	if offset+limit < len(permutations) {
+		next := offset + limit
+		response.NextOffset = &next
+	}
+	if check {
+		ctx, cancel := context.WithTimeout(c.Request.Context(), 60*time.Second)
+		defer cancel()
+		typosquat.Check(ctx, resolver, response.Permutations, permutationCheckConcurrency)
+		response.Resolver = resolver.Name()
+		for _, permutation := range response.Permutations {
+			if permutation.Registered != nil && *permutation.Registered {
+				response.RegisteredCount++
+			}
+		}
+	}
 	c.JSON(http.StatusOK, response)
 }
+
+// certificateInfoList converts certificate summaries to their response model.
+func certificateInfoList(chain []domain.CertificateInfo) []models.CertificateInfo {
+	if chain == nil {
+		return nil
+	}
+	certificates := make([]models.CertificateInfo, len(chain))
+	for i, cert := range chain {
+		certificates[i] = models.CertificateInfo{
+			Subject:   cert.Subject,
+			Issuer:    cert.Issuer,
+			NotBefore: cert.NotBefore,
+			NotAfter:  cert.NotAfter,
+			IsCA:      cert.IsCA,
+			KeyUsage:  cert.KeyUsage,
+		}
+	}
+	return certificates
+}
@@ -0,0 +1,43 @@
+package handlers
+
+import (
+	"net/http"
+	"net/url"
+
+	"github.com/gin-gonic/gin"
+	"github.com/vit0-9/utils_api/pkg/utils"
+)
+
+// OutboundProxyHeader names the proxy a trusted caller wants its request's outbound HTTP
+// requests to go through, or "direct" to bypass the configured proxy.
+const OutboundProxyHeader = "X-Outbound-Proxy"
+
+// OutboundProxyMiddleware applies the per-request proxy override given in the
+// X-Outbound-Proxy header or the proxy query parameter. Only callers with an admin API key
+// may set it, since it makes the API connect wherever the caller says.
+func OutboundProxyMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		value := c.GetHeader(OutboundProxyHeader)
+		if value == "" {
+			value = c.Query("proxy")
+		}
+		if value == "" {
+			c.Next()
+			return
+		}
+		if !checkAdminAPIKey(c) {
+			c.Abort()
+			return
+		}
+		var proxyURL *url.URL
+		if value != "direct" {
+			var err error
+			if proxyURL, err = utils.ParseOutboundProxy(value); err != nil {
+				c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+		}
+		c.Request = c.Request.WithContext(utils.WithOutboundProxy(c.Request.Context(), proxyURL))
+		c.Next()
+	}
+}
@@ -0,0 +1,207 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/vit0-9/utils_api/models"
+	"github.com/vit0-9/utils_api/pkg/utils/portfolio"
+)
+
+type PortfolioHandlers struct{}
+
+func NewPortfolioHandlers() *PortfolioHandlers {
+	return &PortfolioHandlers{}
+}
+
+// ListPortfolioHandler godoc
+// @Summary      List the domain portfolio
+// @Description  Returns the domains registered in the portfolio with their tags, sorted by name.
+// @Tags         Portfolio
+// @Produce      json
+// @Param        tag query string false "Only list domains with this tag"
+// @Param        canonical query bool false "Return RFC 8785 canonical JSON (sorted keys, no whitespace); the X-Canonical-JSON response header is false if that failed"
+// @Success      200 {object} models.PortfolioListResponse "Domains in the portfolio"
+// @Router       /portfolio/domains [get]
+func (h *PortfolioHandlers) ListPortfolioHandler(c *gin.Context) {
+	domains := portfolio.List(c.Query("tag"))
+	c.JSON(http.StatusOK, models.PortfolioListResponse{Count: len(domains), Domains: domains})
+}
+
+// PutPortfolioDomainHandler godoc
+// @Summary      Add a domain to the portfolio
+// @Description  Registers a domain you own with optional tags. Adding a domain that is already in the portfolio replaces its tags. Requires one of the API keys configured with ADMIN_API_KEYS in the X-Admin-Key header.
+// @Tags         Portfolio
+// @Accept       json
+// @Produce      json
+// @Param        X-Admin-Key header string true "API key configured with ADMIN_API_KEYS"
+// @Param        domain body models.PortfolioDomainRequest true "Domain and tags"
+// @Success      200 {object} portfolio.Domain "The stored domain"
+// @Failure      400 {object} map[string]string "Error: Invalid input (e.g., malformed domain or tag, or the portfolio is full)"
+// @Failure      401 {object} map[string]string "Error: Missing or invalid admin API key"
+// @Failure      403 {object} map[string]string "Error: Admin endpoints are disabled on this instance"
+// @Router       /portfolio/domains [post]
+func (h *PortfolioHandlers) PutPortfolioDomainHandler(c *gin.Context) {
+	if !checkAdminAPIKey(c) {
+		return
+	}
+	var req models.PortfolioDomainRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request payload: " + err.Error()})
+		return
+	}
+	stored, err := portfolio.Put(req.Domain, req.Tags)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, stored)
+}
+
+// DeletePortfolioDomainHandler godoc
+// @Summary      Remove a domain from the portfolio
+// @Description  Requires one of the API keys configured with ADMIN_API_KEYS in the X-Admin-Key header.
+// @Tags         Portfolio
+// @Produce      json
+// @Param        X-Admin-Key header string true "API key configured with ADMIN_API_KEYS"
+// @Param        domain path string true "Domain to remove"
+// @Success      200 {object} map[string]string "Domain removed"
+// @Failure      401 {object} map[string]string "Error: Missing or invalid admin API key"
+// @Failure      403 {object} map[string]string "Error: Admin endpoints are disabled on this instance"
+// @Failure      404 {object} map[string]string "Error: The domain is not in the portfolio"
+// @Router       /portfolio/domains/{domain} [delete]
+func (h *PortfolioHandlers) DeletePortfolioDomainHandler(c *gin.Context) {
+	if !checkAdminAPIKey(c) {
+		return
+	}
+	if err := portfolio.Remove(c.Param("domain")); err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, portfolio.ErrNotFound) {
+			status = http.StatusNotFound
+		}
+		c.JSON(status, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"status": "removed"})
+}
+
+// StartPortfolioJobHandler godoc
+// @Summary      Run an operation across the portfolio
+// @Description  Starts a background job running ssl-check, whois-expiry, dns-snapshot or ct-scan on every domain in the portfolio (or those with a tag). Poll the returned job for results, or pass a callback_url to receive the completed job as a POST signed with CALLBACK_SIGNING_SECRET in X-Signature-256. When it finishes a job.completed notification is sent, plus a monitor.alert listing certificates and registrations that expire within 30 days. Requires one of the API keys configured with ADMIN_API_KEYS in the X-Admin-Key header.
+// @Tags         Portfolio
+// @Accept       json
+// @Produce      json
+// @Param        X-Admin-Key header string true "API key configured with ADMIN_API_KEYS"
+// @Param        job body models.PortfolioJobRequest true "Operation and optional tag"
+// @Success      202 {object} portfolio.Job "The started job"
+// @Failure      400 {object} map[string]string "Error: Invalid input (e.g., unknown operation, no matching domains or an invalid callback URL)"
+// @Failure      401 {object} map[string]string "Error: Missing or invalid admin API key"
+// @Failure      403 {object} map[string]string "Error: Admin endpoints are disabled on this instance"
+// @Router       /portfolio/jobs [post]
+func (h *PortfolioHandlers) StartPortfolioJobHandler(c *gin.Context) {
+	if !checkAdminAPIKey(c) {
+		return
+	}
+	var req models.PortfolioJobRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request payload: " + err.Error()})
+		return
+	}
+	job, err := portfolio.StartJob(req.Operation, req.Tag, req.CallbackURL)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusAccepted, job)
+}
+
+// ListPortfolioJobsHandler godoc
+// @Summary      List recent portfolio jobs
+// @Description  Returns the last 50 portfolio jobs, newest first, with their progress but without per-domain results.
+// @Tags         Portfolio
+// @Produce      json
+// @Success      200 {object} models.PortfolioJobListResponse "Recent jobs"
+// @Router       /portfolio/jobs [get]
+func (h *PortfolioHandlers) ListPortfolioJobsHandler(c *gin.Context) {
+	c.JSON(http.StatusOK, models.PortfolioJobListResponse{Jobs: portfolio.ListJobs()})
+}
+
+// PortfolioJobHandler godoc
+// @Summary      Get a portfolio job
+// @Description  Returns a job's progress and, once it has completed, the result for each domain. A job stopped by a shutdown is interrupted, with the results collected so far; retry it with POST /portfolio/jobs/{id}/retry.
+// @Tags         Portfolio
+// @Produce      json
+// @Param        id path string true "Job ID"
+// @Param        canonical query bool false "Return RFC 8785 canonical JSON (sorted keys, no whitespace); the X-Canonical-JSON response header is false if that failed"
+// @Success      200 {object} portfolio.Job "The job"
+// @Failure      404 {object} map[string]string "Error: Unknown or expired job"
+// @Router       /portfolio/jobs/{id} [get]
+func (h *PortfolioHandlers) PortfolioJobHandler(c *gin.Context) {
+	job, ok := portfolio.GetJob(c.Param("id"))
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "job not found"})
+		return
+	}
+	c.JSON(http.StatusOK, job)
+}
+
+// RetryPortfolioJobHandler godoc
+// @Summary      Retry an interrupted portfolio job
+// @Description  Starts a job running the operation of a job interrupted by a shutdown on the domains it had not checked (still in the portfolio with its tag). The new job's retry_of names the interrupted one; results already collected stay on it. Requires one of the API keys configured with ADMIN_API_KEYS in the X-Admin-Key header.
+// @Tags         Portfolio
+// @Produce      json
+// @Param        X-Admin-Key header string true "API key configured with ADMIN_API_KEYS"
+// @Param        id path string true "ID of the interrupted job"
+// @Success      202 {object} portfolio.Job "The started job"
+// @Failure      400 {object} map[string]string "Error: The job was not interrupted or has no domains left to check"
+// @Failure      401 {object} map[string]string "Error: Missing or invalid admin API key"
+// @Failure      403 {object} map[string]string "Error: Admin endpoints are disabled on this instance"
+// @Failure      404 {object} map[string]string "Error: Unknown or expired job"
+// @Router       /portfolio/jobs/{id}/retry [post]
+func (h *PortfolioHandlers) RetryPortfolioJobHandler(c *gin.Context) {
+	if !checkAdminAPIKey(c) {
+		return
+	}
+	job, err := portfolio.RetryJob(c.Param("id"))
+	if err != nil {
+		status := http.StatusBadRequest
+		if errors.Is(err, portfolio.ErrJobNotFound) {
+			status = http.StatusNotFound
+		}
+		c.JSON(status, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusAccepted, job)
+}
+
+// PortfolioCertificatesHandler godoc
+// @Summary      List the certificate inventory of the portfolio
+// @Description  Aggregates the certificates found across the portfolio, deduplicated by SHA-256 fingerprint: those served directly during ssl-check jobs and those found in the Certificate Transparency logs by ct-scan jobs. An issuer is known when it matches PORTFOLIO_KNOWN_ISSUERS or, without that list, when its organization also issued a trusted certificate served by a portfolio domain.
+// @Tags         Portfolio
+// @Produce      json
+// @Param        tag query string false "Only include certificates found for domains with this tag"
+// @Param        expiring_days query int false "Only include certificates expiring within this many days (expired ones included)"
+// @Param        weak_keys query bool false "Only include certificates with weak keys (RSA under 2048 bits, ECDSA under 256 bits or DSA)"
+// @Param        unknown_issuers query bool false "Only include certificates from unknown issuers"
+// @Param        canonical query bool false "Return RFC 8785 canonical JSON (sorted keys, no whitespace); the X-Canonical-JSON response header is false if that failed"
+// @Success      200 {object} models.PortfolioCertificatesResponse "Certificates, soonest expiry first"
+// @Failure      400 {object} map[string]string "Error: Invalid input (e.g., non-numeric expiring_days)"
+// @Router       /portfolio/certificates [get]
+func (h *PortfolioHandlers) PortfolioCertificatesHandler(c *gin.Context) {
+	filter := portfolio.CertificateFilter{Tag: c.Query("tag")}
+	if value := c.Query("expiring_days"); value != "" {
+		days, err := strconv.Atoi(value)
+		if err != nil || days < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "expiring_days must be a non-negative number of days"})
+			return
+		}
+		filter.ExpiringWithinDays = &days
+	}
+	filter.WeakKeys, _ = strconv.ParseBool(c.Query("weak_keys"))
+	filter.UnknownIssuers, _ = strconv.ParseBool(c.Query("unknown_issuers"))
+
+	certificates := portfolio.Certificates(filter)
+	c.JSON(http.StatusOK, models.PortfolioCertificatesResponse{Count: len(certificates), Certificates: certificates})
+}
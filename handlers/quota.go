@@ -0,0 +1,69 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/vit0-9/utils_api/pkg/utils/quota"
+)
+
+// Quota response headers.
+const (
+	quotaCostHeader             = "X-Quota-Cost"
+	quotaDailyLimitHeader       = "X-Quota-Daily-Limit"
+	quotaDailyRemainingHeader   = "X-Quota-Daily-Remaining"
+	quotaMonthlyLimitHeader     = "X-Quota-Monthly-Limit"
+	quotaMonthlyRemainingHeader = "X-Quota-Monthly-Remaining"
+)
+
+// QuotaMiddleware charges each request the cost units of its endpoint against the caller's
+// daily and monthly quotas, reporting them in X-Quota-* headers, and answers 429 once a
+// quota is spent. It does nothing unless QUOTA_CONFIG_PATH is set.
+func QuotaMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !quota.Enabled() {
+			c.Next()
+			return
+		}
+		cost := quota.Cost(c.Request.URL.Path)
+		if cost == 0 {
+			c.Next()
+			return
+		}
+		caller := quota.Identify(c.GetHeader(quota.APIKeyHeader), c.ClientIP())
+		usage, err := quota.Charge(caller, cost)
+		if err != nil && !errors.Is(err, quota.ErrExceeded) {
+			// Failing open keeps the API up when the shared counters are unreachable.
+			log.Printf("ERROR: Could not charge quota of %s: %v", caller.ID, err)
+			c.Next()
+			return
+		}
+		setQuotaHeaders(c, caller, usage, cost)
+		if err != nil {
+			reset := usage.DailyReset
+			if caller.Limits.Monthly > 0 && usage.Monthly+cost > caller.Limits.Monthly {
+				reset = usage.MonthlyReset
+			}
+			c.Header("Retry-After", strconv.Itoa(int(time.Until(reset).Seconds())+1))
+			c.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{"error": "quota exceeded: this request costs " + strconv.FormatInt(cost, 10) + " units, see " + APIURL("/me/usage")})
+			return
+		}
+		c.Next()
+	}
+}
+
+func setQuotaHeaders(c *gin.Context, caller quota.Caller, usage quota.Usage, cost int64) {
+	c.Header(quotaCostHeader, strconv.FormatInt(cost, 10))
+	if caller.Limits.Daily > 0 {
+		c.Header(quotaDailyLimitHeader, strconv.FormatInt(caller.Limits.Daily, 10))
+		c.Header(quotaDailyRemainingHeader, strconv.FormatInt(quota.Remaining(caller.Limits.Daily, usage.Daily), 10))
+	}
+	if caller.Limits.Monthly > 0 {
+		c.Header(quotaMonthlyLimitHeader, strconv.FormatInt(caller.Limits.Monthly, 10))
+		c.Header(quotaMonthlyRemainingHeader, strconv.FormatInt(quota.Remaining(caller.Limits.Monthly, usage.Monthly), 10))
+	}
+}
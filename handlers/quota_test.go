@@ -0,0 +1,50 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/vit0-9/utils_api/pkg/utils/quota"
+)
+
+func TestQuotaMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	err := quota.Configure(quota.Config{
+		Keys:  []quota.KeyConfig{{Name: "quota-test", Key: "quota-test-key", Limits: quota.Limits{Daily: 25}}},
+		Costs: map[string]int64{"net/ssl-check": 10},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	router := gin.New()
+	router.Use(QuotaMiddleware())
+	router.GET("/api/v1/net/ssl-check", func(c *gin.Context) { c.JSON(http.StatusOK, gin.H{"ok": true}) })
+	router.GET("/api/v1/me/usage", NewUsageHandlers().UsageHandler)
+
+	get := func(target string) *httptest.ResponseRecorder {
+		recorder := httptest.NewRecorder()
+		request := httptest.NewRequest(http.MethodGet, target, nil)
+		request.Header.Set(quota.APIKeyHeader, "quota-test-key")
+		router.ServeHTTP(recorder, request)
+		return recorder
+	}
+
+	for _, remaining := range []string{"15", "5"} {
+		response := get("/api/v1/net/ssl-check")
+		if response.Code != http.StatusOK || response.Header().Get("X-Quota-Cost") != "10" || response.Header().Get("X-Quota-Daily-Remaining") != remaining {
+			t.Fatalf("response %d with headers %v, want 200 with %s units remaining", response.Code, response.Header(), remaining)
+		}
+	}
+	refused := get("/api/v1/net/ssl-check")
+	if refused.Code != http.StatusTooManyRequests || refused.Header().Get("Retry-After") == "" {
+		t.Errorf("request over the quota = %d with headers %v, want 429 with Retry-After", refused.Code, refused.Header())
+	}
+
+	usage := get("/api/v1/me/usage")
+	if usage.Code != http.StatusOK || usage.Header().Get("X-Quota-Cost") != "" || !strings.Contains(usage.Body.String(), `"daily":20`) {
+		t.Errorf("usage = %d %s, want 20 units spent and no charge for the request", usage.Code, usage.Body)
+	}
+}
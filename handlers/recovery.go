@@ -0,0 +1,100 @@
+package handlers
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"log"
+	"net/http"
+	"runtime/debug"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/vit0-9/utils_api/models"
+	"github.com/vit0-9/utils_api/pkg/utils"
+	"github.com/vit0-9/utils_api/pkg/utils/requestlog"
+)
+
+// RequestIDHeader carries the ID a client gave its request, or the one assigned to it, so
+// an error can be matched with the server log.
+const RequestIDHeader = "X-Request-ID"
+
+// maxRequestIDLength bounds the request IDs accepted from clients.
+const maxRequestIDLength = 128
+
+// recoveredPanics counts the panics RecoveryMiddleware turned into error responses.
+var recoveredPanics atomic.Int64
+
+// RecoveredPanics returns how many requests panicked since the server started.
+func RecoveredPanics() int64 {
+	return recoveredPanics.Load()
+}
+
+// RecoveryMiddleware turns a panic in a handler or the utilities it calls into a 500
+// models.APIErrorResponse carrying a request ID, logged with the stack trace under the same
+// ID. It replaces gin.Recovery, which answers with an empty body. Panics from a client
+// disconnecting are not answered.
+func RecoveryMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		writer := c.Writer // Middlewares further in swap in buffering writers the panic bypasses
+		start := time.Now()
+		defer func() {
+			recovered := recover()
+			if recovered == nil {
+				return
+			}
+			c.Writer = writer
+			if err, ok := recovered.(error); ok && (errors.Is(err, http.ErrAbortHandler) || errors.Is(err, syscall.EPIPE) || errors.Is(err, syscall.ECONNRESET)) {
+				c.Abort()
+				return
+			}
+
+			requestID := requestID(c)
+			recoveredPanics.Add(1)
+			log.Printf("ERROR: panic serving %s %s (request %s): %v\n%s", c.Request.Method, c.Request.URL.Path, requestID, recovered, debug.Stack())
+			requestlog.Log(requestlog.Entry{
+				Method:  c.Request.Method,
+				Path:    c.Request.URL.Path,
+				Target:  requestlog.Target(c.Request.URL.Path, c.Request.URL.Query()),
+				Status:  http.StatusInternalServerError,
+				Outcome: requestlog.OutcomeServerError,
+				Timing:  utils.Timing{TotalMs: durationMs(time.Since(start))},
+			})
+
+			if writer.Written() { // Part of the response is out; nothing parseable can follow
+				c.Abort()
+				return
+			}
+			c.Header(RequestIDHeader, requestID)
+			c.AbortWithStatusJSON(http.StatusInternalServerError, models.APIErrorResponse{
+				StatusCode: http.StatusInternalServerError,
+				ErrorCode:  "internal_error",
+				Message:    "An unexpected error occurred while handling the request",
+				RequestID:  requestID,
+			})
+		}()
+		c.Next()
+	}
+}
+
+// requestID returns the client's X-Request-ID when it is usable, and a new random ID
+// otherwise.
+func requestID(c *gin.Context) string {
+	if id := c.GetHeader(RequestIDHeader); id != "" && len(id) <= maxRequestIDLength && isPrintableASCII(id) {
+		return id
+	}
+	var b [16]byte
+	rand.Read(b[:])
+	return hex.EncodeToString(b[:])
+}
+
+func isPrintableASCII(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] < 0x21 || s[i] > 0x7e {
+			return false
+		}
+	}
+	return true
+}
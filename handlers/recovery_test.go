@@ -0,0 +1,52 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/vit0-9/utils_api/models"
+)
+
+func TestRecoveryMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	// The buffering middleware does not get to restore the writer it swapped in
+	router.Use(RecoveryMiddleware(), TimingMiddleware())
+	router.GET("/panic", func(c *gin.Context) {
+		panic("boom")
+	})
+
+	before := RecoveredPanics()
+	for _, tc := range []struct{ header, want string }{
+		{"client-id-1", "client-id-1"},
+		{"bad id", ""},
+	} {
+		recorder := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/panic", nil)
+		req.Header.Set(RequestIDHeader, tc.header)
+		router.ServeHTTP(recorder, req)
+
+		if recorder.Code != http.StatusInternalServerError {
+			t.Fatalf("status = %d", recorder.Code)
+		}
+		var body models.APIErrorResponse
+		if err := json.Unmarshal(recorder.Body.Bytes(), &body); err != nil {
+			t.Fatalf("body %q: %v", recorder.Body.String(), err)
+		}
+		if body.StatusCode != http.StatusInternalServerError || body.ErrorCode != "internal_error" || body.RequestID == "" {
+			t.Errorf("body = %+v", body)
+		}
+		if tc.want != "" && body.RequestID != tc.want {
+			t.Errorf("request ID = %q, want %q", body.RequestID, tc.want)
+		}
+		if got := recorder.Header().Get(RequestIDHeader); got != body.RequestID {
+			t.Errorf("%s header = %q, body has %q", RequestIDHeader, got, body.RequestID)
+		}
+	}
+	if got := RecoveredPanics() - before; got != 2 {
+		t.Errorf("recovered panics = %d, want 2", got)
+	}
+}
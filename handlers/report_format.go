@@ -0,0 +1,37 @@
+package handlers
+
+import (
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/vit0-9/utils_api/pkg/utils"
+)
+
+// writeReport sends a report response as JSON, as a standalone HTML page when the request
+// asks for format=html, or as a printable PDF for format=pdf.
+func writeReport(c *gin.Context, title string, response any) {
+	switch c.Query("format") {
+	case "html":
+		page, err := utils.RenderHTMLReport(title, response)
+		if err != nil {
+			log.Printf("ERROR: Could not render HTML report %q: %v", title, err)
+			c.JSON(http.StatusOK, response)
+			return
+		}
+		c.Data(http.StatusOK, "text/html; charset=utf-8", page)
+	case "pdf":
+		document, err := utils.RenderPDFReport(title, response)
+		if err != nil {
+			log.Printf("ERROR: Could not render PDF report %q: %v", title, err)
+			c.JSON(http.StatusOK, response)
+			return
+		}
+		filename := strings.ToLower(strings.ReplaceAll(title, " ", "-")) + ".pdf"
+		c.Header("Content-Disposition", `inline; filename="`+filename+`"`)
+		c.Data(http.StatusOK, "application/pdf", document)
+	default:
+		c.JSON(http.StatusOK, response)
+	}
+}
@@ -0,0 +1,37 @@
+package handlers
+
+import (
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/vit0-9/utils_api/pkg/utils"
+	"github.com/vit0-9/utils_api/pkg/utils/requestlog"
+)
+
+// RequestLogMiddleware writes a structured log line per request with its target, outcome
+// class and upstream timing when REQUEST_LOG is set. It relies on the timing recorder that
+// TimingMiddleware puts in the request context, so it must come after it.
+func RequestLogMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !requestlog.Enabled() {
+			c.Next()
+			return
+		}
+		start := time.Now()
+		c.Next()
+
+		recorder := utils.TimingRecorderFrom(c.Request.Context())
+		timing := utils.Timing{TotalMs: float64(time.Since(start).Microseconds()) / 1000}
+		if recorder != nil {
+			timing = recorder.Timing(time.Since(start))
+		}
+		requestlog.Log(requestlog.Entry{
+			Method:  c.Request.Method,
+			Path:    c.Request.URL.Path,
+			Target:  requestlog.Target(c.Request.URL.Path, c.Request.URL.Query()),
+			Status:  c.Writer.Status(),
+			Outcome: requestlog.Classify(c.Writer.Status(), recorder),
+			Timing:  timing,
+		})
+	}
+}
@@ -0,0 +1,147 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/vit0-9/utils_api/pkg/utils"
+	"github.com/vit0-9/utils_api/pkg/utils/cache"
+)
+
+// Result cache TTLs of the cached endpoints.
+const (
+	DNSCacheTTL   = 5 * time.Minute
+	WhoisCacheTTL = time.Hour
+	SSLCacheTTL   = 15 * time.Minute
+	GeoIPCacheTTL = time.Hour
+	StackCacheTTL = 30 * time.Minute
+)
+
+// Waiting for another request computing the same result.
+const (
+	resultLockTTL      = 30 * time.Second // Longer than most lookups take
+	resultPollInterval = 100 * time.Millisecond
+)
+
+// cachedResponse is a response body as kept in the result cache.
+type cachedResponse struct {
+	ContentType string    `json:"content_type"`
+	Body        []byte    `json:"body"`
+	StoredAt    time.Time `json:"stored_at"`
+}
+
+// ResultCacheMiddleware answers repeated GET requests from the result cache for ttl.
+// Successful JSON responses without an error are cached, keyed by the path and query;
+// cache=false skips the cache and refreshes it. The X-Cache response header reports HIT,
+// MISS or BYPASS, with the entry's Age in seconds on hits. On a miss, a request for a result
+// another request (on any replica, with Redis) is already computing waits for that result
+// instead of repeating the outbound work.
+func ResultCacheMiddleware(ttl time.Duration) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.Request.Method != http.MethodGet {
+			c.Next()
+			return
+		}
+		key := resultCacheKey(c)
+		status := "MISS"
+		if c.Query("cache") == "false" || utils.OutboundProxyOverridden(c.Request.Context()) {
+			status = "BYPASS"
+		} else if serveCachedResult(c, key) {
+			return
+		} else if release, ok := cache.Acquire(c.Request.Context(), key, resultLockTTL); ok {
+			defer release()
+		} else if waitForCachedResult(c, key) {
+			return
+		}
+
+		writer := &bufferedResponseWriter{ResponseWriter: c.Writer}
+		c.Writer = writer
+		c.Next()
+		c.Writer = writer.ResponseWriter
+
+		body := writer.body.Bytes()
+		contentType := writer.Header().Get("Content-Type")
+		if writer.Status() == http.StatusOK && strings.HasPrefix(contentType, "application/json") && !hasErrorField(body) {
+			data, err := json.Marshal(cachedResponse{ContentType: contentType, Body: body, StoredAt: time.Now().UTC()})
+			if err == nil {
+				cache.Set(c.Request.Context(), key, data, ttl)
+			}
+		}
+		writer.Header().Set("X-Cache", status)
+		writer.Header().Set("Content-Length", strconv.Itoa(len(body)))
+		writer.ResponseWriter.Write(body)
+	}
+}
+
+// serveCachedResult answers the request from the cache and reports whether it could.
+func serveCachedResult(c *gin.Context, key string) bool {
+	data, ok := cache.Get(c.Request.Context(), key)
+	if !ok {
+		return false
+	}
+	var cached cachedResponse
+	if err := json.Unmarshal(data, &cached); err != nil {
+		return false
+	}
+	utils.TimingRecorderFrom(c.Request.Context()).MarkCacheHit()
+	c.Header("X-Cache", "HIT")
+	c.Header("Age", strconv.Itoa(int(time.Since(cached.StoredAt).Seconds())))
+	c.Data(http.StatusOK, cached.ContentType, cached.Body)
+	c.Abort()
+	return true
+}
+
+// waitForCachedResult polls the cache while another request holds the key's lock and
+// serves the result once it appears. It gives up, so the request computes the result
+// itself, when the lock is released without a cacheable result or expires.
+func waitForCachedResult(c *gin.Context, key string) bool {
+	ctx := c.Request.Context()
+	ticker := time.NewTicker(resultPollInterval)
+	defer ticker.Stop()
+	for deadline := time.Now().Add(resultLockTTL); time.Now().Before(deadline); {
+		select {
+		case <-ctx.Done():
+			return false
+		case <-ticker.C:
+		}
+		if serveCachedResult(c, key) {
+			return true
+		}
+		if release, ok := cache.Acquire(ctx, key, resultLockTTL); ok {
+			release() // The holder finished without caching, e.g. the lookup failed
+			return false
+		}
+	}
+	return false
+}
+
+// resultCacheKey identifies a request by its path and query, without the parameters that
+// only change how the response is delivered.
+func resultCacheKey(c *gin.Context) string {
+	query := c.Request.URL.Query()
+	query.Del("cache")
+	query.Del("canonical")
+	return c.Request.URL.Path + "?" + query.Encode()
+}
+
+// hasErrorField reports whether a JSON object response carries a non-empty "error", as
+// lookups that failed are reported with status 200.
+func hasErrorField(body []byte) bool {
+	var response struct {
+		Error any `json:"error"`
+	}
+	if json.Unmarshal(body, &response) != nil {
+		return false
+	}
+	switch e := response.Error.(type) {
+	case nil:
+		return false
+	case string:
+		return e != ""
+	}
+	return true
+}
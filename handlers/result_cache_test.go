@@ -0,0 +1,97 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/vit0-9/utils_api/pkg/utils/cache"
+)
+
+func TestResultCacheMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	cache.Configure(nil, 0)
+	calls := 0
+	router := gin.New()
+	router.GET("/lookup", ResultCacheMiddleware(time.Minute), func(c *gin.Context) {
+		calls++
+		if c.Query("domain") == "broken.example" {
+			c.JSON(http.StatusOK, gin.H{"domain": "broken.example", "error": "lookup failed"})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"domain": c.Query("domain"), "calls": calls})
+	})
+
+	get := func(target string) *httptest.ResponseRecorder {
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, target, nil))
+		return recorder
+	}
+
+	first := get("/lookup?domain=example.com&canonical=false")
+	if first.Header().Get("X-Cache") != "MISS" || calls != 1 {
+		t.Fatalf("first request X-Cache = %q after %d calls, want MISS", first.Header().Get("X-Cache"), calls)
+	}
+	second := get("/lookup?canonical=true&domain=example.com")
+	if second.Header().Get("X-Cache") != "HIT" || calls != 1 || second.Body.String() != first.Body.String() {
+		t.Errorf("second request X-Cache = %q, body %s after %d calls; want the cached body", second.Header().Get("X-Cache"), second.Body, calls)
+	}
+	if second.Header().Get("Age") == "" || second.Header().Get("Content-Type") != "application/json; charset=utf-8" {
+		t.Errorf("cached response headers = %v", second.Header())
+	}
+
+	bypass := get("/lookup?domain=example.com&cache=false")
+	if bypass.Header().Get("X-Cache") != "BYPASS" || calls != 2 {
+		t.Errorf("bypass X-Cache = %q after %d calls, want BYPASS and a fresh call", bypass.Header().Get("X-Cache"), calls)
+	}
+	if refreshed := get("/lookup?domain=example.com"); refreshed.Body.String() != bypass.Body.String() {
+		t.Errorf("the bypassed request did not refresh the cache: %s", refreshed.Body)
+	}
+
+	get("/lookup?domain=broken.example")
+	if failed := get("/lookup?domain=broken.example"); failed.Header().Get("X-Cache") != "MISS" || calls != 4 {
+		t.Errorf("a failed lookup was cached: X-Cache = %q after %d calls", failed.Header().Get("X-Cache"), calls)
+	}
+}
+
+func TestResultCacheMiddlewareWaitsForRunningLookup(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	cache.Configure(nil, 0)
+	started := make(chan struct{})
+	finish := make(chan struct{})
+	var calls atomic.Int32
+	router := gin.New()
+	router.GET("/slow", ResultCacheMiddleware(time.Minute), func(c *gin.Context) {
+		if calls.Add(1) == 1 {
+			close(started)
+			<-finish
+		}
+		c.JSON(http.StatusOK, gin.H{"domain": c.Query("domain")})
+	})
+
+	first := make(chan *httptest.ResponseRecorder)
+	go func() {
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/slow?domain=example.com", nil))
+		first <- recorder
+	}()
+	<-started
+	second := make(chan *httptest.ResponseRecorder)
+	go func() {
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/slow?domain=example.com", nil))
+		second <- recorder
+	}()
+	time.Sleep(3 * resultPollInterval)
+	close(finish)
+
+	if got := (<-first).Header().Get("X-Cache"); got != "MISS" {
+		t.Errorf("first request X-Cache = %q, want MISS", got)
+	}
+	if got := (<-second).Header().Get("X-Cache"); got != "HIT" || calls.Load() != 1 {
+		t.Errorf("waiting request X-Cache = %q after %d lookups, want a HIT from the first lookup", got, calls.Load())
+	}
+}
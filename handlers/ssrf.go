@@ -0,0 +1,31 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/vit0-9/utils_api/pkg/utils"
+)
+
+// targetURLParams are the query parameters holding URLs the /web and /url endpoints fetch.
+var targetURLParams = []string{"url", "favicon_url", "baseline_url"}
+
+// SSRFGuardMiddleware answers 400 when a URL an endpoint would fetch points at an internal
+// address. Redirects and links followed later are refused when connecting.
+func SSRFGuardMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		for _, param := range targetURLParams {
+			value := c.Query(param)
+			if value == "" {
+				continue
+			}
+			var blocked *utils.SSRFBlockedError
+			if err := utils.CheckTargetURL(c.Request.Context(), value); errors.As(err, &blocked) {
+				c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": param + ": " + err.Error()})
+				return
+			}
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,120 @@
+package handlers
+
+import (
+	"crypto/subtle"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// DefaultSwaggerPath is where the Swagger UI and document are served.
+const DefaultSwaggerPath = "/swagger"
+
+// swaggerKeyCookie keeps a browser authenticated after it opened the UI with api_key, so the
+// UI's own request for doc.json passes too.
+const swaggerKeyCookie = "swagger_key"
+
+var swaggerConfig = struct {
+	enabled   bool
+	path      string
+	basicUser string
+	basicPass string
+	apiKeys   []string
+}{enabled: true, path: DefaultSwaggerPath}
+
+// ConfigureSwagger sets whether the Swagger UI is served ("true" or "false"; when empty it is
+// served unless Gin runs in release mode), its path, and the credentials required to open it:
+// basicAuth as "user:password" and apiKeys as a comma-separated list. Either kind of
+// credential is accepted when both are set.
+func ConfigureSwagger(enabled, path, basicAuth, apiKeys string) {
+	switch enabled {
+	case "":
+		swaggerConfig.enabled = gin.Mode() != gin.ReleaseMode
+	default:
+		swaggerConfig.enabled = enabled == "true"
+	}
+	if path = "/" + strings.Trim(strings.TrimSpace(path), "/"); path != "/" {
+		swaggerConfig.path = path
+	}
+	if basicAuth != "" {
+		user, pass, ok := strings.Cut(basicAuth, ":")
+		if !ok || user == "" || pass == "" {
+			log.Println("ERROR: SWAGGER_BASIC_AUTH must be user:password; the Swagger UI is disabled")
+			swaggerConfig.enabled = false
+		}
+		swaggerConfig.basicUser, swaggerConfig.basicPass = user, pass
+	}
+	for _, key := range strings.Split(apiKeys, ",") {
+		if key = strings.TrimSpace(key); key != "" {
+			swaggerConfig.apiKeys = append(swaggerConfig.apiKeys, key)
+		}
+	}
+	if !swaggerConfig.enabled {
+		log.Println("Swagger UI disabled")
+		return
+	}
+	protection := "without authentication"
+	if swaggerConfig.basicUser != "" || len(swaggerConfig.apiKeys) > 0 {
+		protection = "with authentication"
+	}
+	log.Printf("Swagger UI served at %s/index.html %s", swaggerConfig.path, protection)
+}
+
+// SwaggerEnabled reports whether the Swagger UI and document are served.
+func SwaggerEnabled() bool {
+	return swaggerConfig.enabled
+}
+
+// SwaggerPath returns the path the Swagger UI is served under.
+func SwaggerPath() string {
+	return swaggerConfig.path
+}
+
+// SwaggerAuthMiddleware requires the configured Swagger credentials: HTTP basic auth, or an
+// API key in the X-API-Key header, the api_key query parameter or the cookie set after it.
+func SwaggerAuthMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if swaggerConfig.basicUser == "" && len(swaggerConfig.apiKeys) == 0 {
+			c.Next()
+			return
+		}
+		if user, pass, ok := c.Request.BasicAuth(); ok && swaggerConfig.basicUser != "" &&
+			subtle.ConstantTimeCompare([]byte(user), []byte(swaggerConfig.basicUser)) == 1 &&
+			subtle.ConstantTimeCompare([]byte(pass), []byte(swaggerConfig.basicPass)) == 1 {
+			c.Next()
+			return
+		}
+		if key := c.Query("api_key"); validSwaggerKey(key) {
+			c.SetSameSite(http.SameSiteStrictMode)
+			c.SetCookie(swaggerKeyCookie, key, 12*60*60, swaggerConfig.path, "", c.Request.TLS != nil, true)
+			c.Next()
+			return
+		}
+		if key, err := c.Cookie(swaggerKeyCookie); err == nil && validSwaggerKey(key) {
+			c.Next()
+			return
+		}
+		if validSwaggerKey(c.GetHeader("X-API-Key")) {
+			c.Next()
+			return
+		}
+		if swaggerConfig.basicUser != "" {
+			c.Header("WWW-Authenticate", `Basic realm="API documentation"`)
+		}
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "authentication is required for the API documentation"})
+	}
+}
+
+func validSwaggerKey(key string) bool {
+	if key == "" {
+		return false
+	}
+	for _, valid := range swaggerConfig.apiKeys {
+		if subtle.ConstantTimeCompare([]byte(key), []byte(valid)) == 1 {
+			return true
+		}
+	}
+	return false
+}
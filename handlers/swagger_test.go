@@ -0,0 +1,51 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestSwaggerAuthMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	original := swaggerConfig
+	defer func() { swaggerConfig = original }()
+	ConfigureSwagger("true", "/docs/", "admin:s3cret", "key-1, key-2")
+	if SwaggerPath() != "/docs" || !SwaggerEnabled() {
+		t.Fatalf("path %q, enabled %v", SwaggerPath(), SwaggerEnabled())
+	}
+
+	router := gin.New()
+	router.GET(SwaggerPath()+"/*any", SwaggerAuthMiddleware(), func(c *gin.Context) { c.String(http.StatusOK, "docs") })
+	serve := func(setup func(*http.Request)) *httptest.ResponseRecorder {
+		recorder := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/docs/index.html", nil)
+		setup(req)
+		router.ServeHTTP(recorder, req)
+		return recorder
+	}
+
+	if got := serve(func(*http.Request) {}); got.Code != http.StatusUnauthorized || got.Header().Get("WWW-Authenticate") == "" {
+		t.Errorf("no credentials: status %d, WWW-Authenticate %q", got.Code, got.Header().Get("WWW-Authenticate"))
+	}
+	if got := serve(func(r *http.Request) { r.SetBasicAuth("admin", "wrong") }); got.Code != http.StatusUnauthorized {
+		t.Errorf("wrong password: status %d", got.Code)
+	}
+	if got := serve(func(r *http.Request) { r.SetBasicAuth("admin", "s3cret") }); got.Code != http.StatusOK {
+		t.Errorf("basic auth: status %d", got.Code)
+	}
+	if got := serve(func(r *http.Request) { r.Header.Set("X-API-Key", "key-2") }); got.Code != http.StatusOK {
+		t.Errorf("API key header: status %d", got.Code)
+	}
+
+	withQuery := serve(func(r *http.Request) { r.URL.RawQuery = "api_key=key-1" })
+	cookies := withQuery.Result().Cookies()
+	if withQuery.Code != http.StatusOK || len(cookies) != 1 || cookies[0].Path != "/docs" || !cookies[0].HttpOnly {
+		t.Fatalf("api_key query: status %d, cookies %v", withQuery.Code, cookies)
+	}
+	if got := serve(func(r *http.Request) { r.AddCookie(cookies[0]) }); got.Code != http.StatusOK {
+		t.Errorf("cookie: status %d", got.Code)
+	}
+}
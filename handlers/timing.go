@@ -0,0 +1,62 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/vit0-9/utils_api/pkg/utils"
+)
+
+// TimingMiddleware adds a meta.timing section to JSON object responses, breaking the
+// request down into the DNS, connect and TLS time of its outbound calls, so slowness can be
+// told apart as the target's or the API's. The shared clients report to the recorder the
+// middleware puts in the request context.
+func TimingMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, recorder := utils.WithTimingRecorder(c.Request.Context())
+		c.Request = c.Request.WithContext(ctx)
+
+		writer := &bufferedResponseWriter{ResponseWriter: c.Writer}
+		c.Writer = writer
+		start := time.Now()
+		c.Next()
+		c.Writer = writer.ResponseWriter
+
+		body := writer.body.Bytes()
+		if strings.HasPrefix(writer.Header().Get("Content-Type"), "application/json") {
+			body = withTimingMeta(body, recorder.Timing(time.Since(start)))
+		}
+		writer.Header().Set("Content-Length", strconv.Itoa(len(body)))
+		writer.ResponseWriter.Write(body)
+	}
+}
+
+// withTimingMeta appends "meta":{"timing":...} to a JSON object. Anything else, such as
+// an array or a body that is not valid JSON, is returned unchanged.
+func withTimingMeta(body []byte, timing utils.Timing) []byte {
+	trimmed := bytes.TrimSpace(body)
+	if len(trimmed) < 2 || trimmed[0] != '{' || !json.Valid(trimmed) {
+		return body
+	}
+	meta, err := json.Marshal(struct {
+		Timing utils.Timing `json:"timing"`
+	}{timing})
+	if err != nil {
+		return body
+	}
+
+	fields := bytes.TrimSpace(trimmed[1 : len(trimmed)-1])
+	out := make([]byte, 0, len(fields)+len(meta)+12)
+	out = append(out, '{')
+	if len(fields) > 0 {
+		out = append(out, fields...)
+		out = append(out, ',')
+	}
+	out = append(out, `"meta":`...)
+	out = append(out, meta...)
+	return append(out, '}')
+}
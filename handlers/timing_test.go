@@ -0,0 +1,58 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/vit0-9/utils_api/pkg/utils"
+)
+
+func TestTimingMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(TimingMiddleware())
+	router.GET("/object", func(c *gin.Context) {
+		utils.TimingRecorderFrom(c.Request.Context()).MarkCacheHit()
+		c.JSON(http.StatusOK, gin.H{"a": 1})
+	})
+	router.GET("/empty", func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{})
+	})
+	router.GET("/array", func(c *gin.Context) {
+		c.JSON(http.StatusOK, []int{1})
+	})
+	router.GET("/text", func(c *gin.Context) {
+		c.String(http.StatusOK, "plain")
+	})
+
+	for _, path := range []string{"/object", "/empty"} {
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, path, nil))
+		var body struct {
+			A    *int `json:"a"`
+			Meta struct {
+				Timing *utils.Timing `json:"timing"`
+			} `json:"meta"`
+		}
+		if err := json.Unmarshal(recorder.Body.Bytes(), &body); err != nil {
+			t.Fatalf("GET %s returned invalid JSON %s: %v", path, recorder.Body.String(), err)
+		}
+		if body.Meta.Timing == nil {
+			t.Fatalf("GET %s body = %s, want meta.timing", path, recorder.Body.String())
+		}
+		if path == "/object" && (body.A == nil || *body.A != 1 || !body.Meta.Timing.CacheHit) {
+			t.Errorf("GET /object body = %s, want the original field and a cache hit", recorder.Body.String())
+		}
+	}
+
+	for path, want := range map[string]string{"/array": "[1]", "/text": "plain"} {
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, path, nil))
+		if recorder.Body.String() != want {
+			t.Errorf("GET %s body = %s, want %s unchanged", path, recorder.Body.String(), want)
+		}
+	}
+}
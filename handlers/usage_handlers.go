@@ -0,0 +1,52 @@
+package handlers
+
+import (
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/vit0-9/utils_api/models"
+	"github.com/vit0-9/utils_api/pkg/utils/quota"
+)
+
+type UsageHandlers struct{}
+
+func NewUsageHandlers() *UsageHandlers {
+	return &UsageHandlers{}
+}
+
+// UsageHandler godoc
+// @Summary      Quota usage of the caller
+// @Description  Returns the cost units the caller spent in the current UTC day and month, its limits and the configured endpoint costs. Callers are identified by an API key from the quota configuration in X-API-Key, or else by client IP under the default limits. This endpoint is free.
+// @Tags         Monitoring
+// @Produce      json
+// @Param        X-API-Key header string false "API key from the quota configuration"
+// @Success      200 {object} models.UsageResponse
+// @Failure      404 {object} map[string]string "Error: Quotas are not configured on this instance"
+// @Failure      500 {object} map[string]string "Error: The usage counters are unreachable"
+// @Router       /me/usage [get]
+func (h *UsageHandlers) UsageHandler(c *gin.Context) {
+	if !quota.Enabled() {
+		c.JSON(http.StatusNotFound, gin.H{"error": "quotas are not configured on this instance"})
+		return
+	}
+	caller := quota.Identify(c.GetHeader(quota.APIKeyHeader), c.ClientIP())
+	usage, err := quota.CurrentUsage(caller)
+	if err != nil {
+		log.Printf("ERROR: Could not read quota usage of %s: %v", caller.ID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "could not read the usage counters"})
+		return
+	}
+	c.JSON(http.StatusOK, models.UsageResponse{
+		Caller:           caller.ID,
+		Daily:            usage.Daily,
+		DailyLimit:       caller.Limits.Daily,
+		DailyRemaining:   quota.Remaining(caller.Limits.Daily, usage.Daily),
+		DailyReset:       usage.DailyReset,
+		Monthly:          usage.Monthly,
+		MonthlyLimit:     caller.Limits.Monthly,
+		MonthlyRemaining: quota.Remaining(caller.Limits.Monthly, usage.Monthly),
+		MonthlyReset:     usage.MonthlyReset,
+		Costs:            quota.Costs(),
+	})
+}
@@ -2,11 +2,19 @@ package handlers
 
 import (
 	// Keep log for potential debug/error logging if needed
+	"errors"
+	"fmt"
 	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/vit0-9/utils_api/models"
 	"github.com/vit0-9/utils_api/pkg/utils"
+	"github.com/vit0-9/utils_api/pkg/utils/qrcode"
+	"github.com/vit0-9/utils_api/pkg/utils/shortener"
 )
 
 // URLUtilitiesHandlers groups URL specific utilities
@@ -26,6 +34,7 @@ func NewURLUtilitiesHandlers() *URLUtilitiesHandlers {
 // @Accept       json
 // @Produce      json
 // @Param        urlRequest body models.CleanURLRequest true "URL to clean"
+// @Param        canonical query bool false "Return RFC 8785 canonical JSON (sorted keys, no whitespace); the X-Canonical-JSON response header is false if that failed"
 // @Success      200 {object} models.DetailedCleanURLResponse
 // @Failure      400 {object} map[string]string "Error: Invalid request payload"
 // @Failure      500 {object} map[string]string "Error: Failed to process URL"
@@ -57,12 +66,160 @@ func (h *URLUtilitiesHandlers) CleanURLHandler(c *gin.Context) {
 	c.JSON(http.StatusOK, response)
 }
 
+// ParseURLHandler godoc
+// @Summary      Parse and inspect a URL
+// @Description  Decomposes a URL into its scheme, user info, host, port, path segments, query parameters and fragment, all decoded, without fetching it. Flags embedded credentials (the password is not returned), IDN hosts in punycode or Unicode with both forms, and anomalies with their byte offset: invalid escapes, double encoding, escaped unreserved characters, escaped slashes in the path, %00, escapes decoding to invalid UTF-8 and characters that should have been escaped.
+// @Tags         URL Manipulation
+// @Produce      json
+// @Param        url query string true "URL to inspect"
+// @Param        canonical query bool false "Return RFC 8785 canonical JSON (sorted keys, no whitespace); the X-Canonical-JSON response header is false if that failed"
+// @Success      200 {object} models.URLParseResponse "The URL's parts and anomalies"
+// @Failure      400 {object} map[string]string "Error: Missing URL, or one that cannot be parsed"
+// @Router       /url/parse [get]
+func (h *URLUtilitiesHandlers) ParseURLHandler(c *gin.Context) {
+	urlQuery := c.Query("url")
+	if urlQuery == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "url query parameter is required"})
+		return
+	}
+	inspection, err := utils.InspectURL(urlQuery)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	echoed := urlQuery
+	if inspection.HasPassword {
+		echoed = "" // Unless it can be shown without the password
+		if parsed, err := url.Parse(urlQuery); err == nil {
+			echoed = parsed.Redacted()
+		}
+	}
+	c.JSON(http.StatusOK, models.URLParseResponse{URL: models.SafeURLString(echoed), URLInspection: *inspection})
+}
+
+// QR code limits, in pixels and modules.
+const (
+	defaultQRSize = 256
+	maxQRSize     = 4096
+	maxQRMargin   = 16
+)
+
+// QRCodeHandler godoc
+// @Summary      QR code for a URL
+// @Description  Returns a QR code encoding a URL, such as a link from the UTM generator, as a PNG or SVG image. The smallest QR version that holds the URL at the error correction level is used. The image is size by size pixels; sizes that are a multiple of the module count (21 for short URLs, growing by 4 per version), margin included, give modules of equal size.
+// @Tags         URL Manipulation
+// @Produce      image/png,image/svg+xml
+// @Param        url query string true "URL to encode"
+// @Param        format query string false "Image format: png (default) or svg"
+// @Param        size query int false "Width and height in pixels (default 256, at most 4096)"
+// @Param        level query string false "Error correction level: L (7%), M (15%, default), Q (25%) or H (30%)"
+// @Param        margin query int false "Quiet zone around the code in modules (0-16, default 4)"
+// @Success      200 {string} string "QR code image"
+// @Failure      400 {object} map[string]string "Error: Invalid input (e.g., missing URL, one too long for the level, or a size too small for the code)"
+// @Router       /url/qr [get]
+func (h *URLUtilitiesHandlers) QRCodeHandler(c *gin.Context) {
+	urlQuery := c.Query("url")
+	if urlQuery == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "url query parameter is required"})
+		return
+	}
+	if parsed, err := url.Parse(urlQuery); err != nil || parsed.Scheme == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "url must be an absolute URL, such as https://example.com"})
+		return
+	}
+	format := c.DefaultQuery("format", "png")
+	if format != "png" && format != "svg" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "format must be png or svg"})
+		return
+	}
+	size := defaultQRSize
+	if value := c.Query("size"); value != "" {
+		var err error
+		if size, err = strconv.Atoi(value); err != nil || size < 1 || size > maxQRSize {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("size must be between 1 and %d pixels", maxQRSize)})
+			return
+		}
+	}
+	level := qrcode.Medium
+	if value := c.Query("level"); value != "" {
+		var err error
+		if level, err = qrcode.ParseLevel(value); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+	margin := qrcode.DefaultMargin
+	if value := c.Query("margin"); value != "" {
+		var err error
+		if margin, err = strconv.Atoi(value); err != nil || margin < 0 || margin > maxQRMargin {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("margin must be between 0 and %d modules", maxQRMargin)})
+			return
+		}
+	}
+
+	code, err := qrcode.Encode([]byte(urlQuery), level)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if size < code.MinPixels(margin) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("size must be at least %d pixels for this URL", code.MinPixels(margin))})
+		return
+	}
+	c.Header("Cache-Control", "public, max-age=86400") // The image only depends on the query
+	if format == "svg" {
+		c.Data(http.StatusOK, "image/svg+xml; charset=utf-8", code.SVG(size, margin))
+		return
+	}
+	image, err := code.PNG(size, margin)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.Data(http.StatusOK, "image/png", image)
+}
+
+// IDNHandler godoc
+// @Summary      Convert and check an internationalized host
+// @Description  Converts a hostname between Unicode and punycode (in either direction) and checks it for homograph attacks: the scripts of each label, labels mixing scripts that are not normally combined, and characters that look like ASCII letters (Cyrillic а for a, Greek ο for o, ...). The skeleton is the ASCII name the host imitates. homograph_risk is none for ASCII hosts, low for internationalized hosts in one script, medium for mixed scripts or names reading as ASCII, and high for lookalikes mixed with Latin or labels written entirely in lookalikes.
+// @Tags         URL Manipulation
+// @Produce      json
+// @Param        host query string true "Hostname in Unicode or punycode, or a URL whose host to check"
+// @Success      200 {object} utils.IDNAnalysis "Both forms of the host and its homograph risk"
+// @Failure      400 {object} map[string]string "Error: Missing host, or one that is not a valid internationalized name"
+// @Router       /url/idn [get]
+func (h *URLUtilitiesHandlers) IDNHandler(c *gin.Context) {
+	host := c.Query("host")
+	if host == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "host query parameter is required"})
+		return
+	}
+	if strings.Contains(host, "://") {
+		parsed, err := url.Parse(host)
+		if err != nil || parsed.Hostname() == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid URL"})
+			return
+		}
+		host = parsed.Hostname()
+	}
+	analysis, err := utils.AnalyzeIDN(host)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, analysis)
+}
+
 // ResolveRedirectHandler godoc
 // @Summary      Resolve URL Redirects
-// @Description  Follows HTTP redirects for a given URL (e.g., a shortlink) and returns the final destination URL.
+// @Description  Follows HTTP redirects for a given URL (e.g., a shortlink) and returns the final destination URL. With trace=true the response is a models.RedirectTraceResponse recording every hop: its status code, Location, http/https upgrades and downgrades, the cookies it set and its latency.
 // @Tags         URL Manipulation
 // @Produce      json
 // @Param        url query string true "URL to resolve"
+// @Param        trace query bool false "Record every hop of the redirect chain instead of returning only the final URL"
+// @Param        include_curl query bool false "Include the equivalent curl command (method, headers, User-Agent, proxy) the server used"
+// @Param        canonical query bool false "Return RFC 8785 canonical JSON (sorted keys, no whitespace); the X-Canonical-JSON response header is false if that failed"
+// @Param        vantage query string false "Comma-separated vantage points to run the check from, e.g. eu,local; the results are returned side by side as a models.VantageComparisonResponse"
 // @Success      200 {object} models.ResolveRedirectResponse "Successfully resolved URL or error during resolution"
 // @Failure      400 {object} map[string]string "Error: Invalid input (e.g., missing URL)"
 // @Router       /url/resolve-redirect [get]
@@ -73,11 +230,32 @@ func (h *URLUtilitiesHandlers) ResolveRedirectHandler(c *gin.Context) {
 		return
 	}
 
-	finalURL, err := utils.ResolveRedirect(urlQuery) // Assuming utils.ResolveRedirect exists
+	curlCommand := ""
+	if c.Query("include_curl") == "true" {
+		curlCommand = utils.RedirectCurlCommand(urlQuery)
+	}
+
+	if c.Query("trace") == "true" {
+		trace, err := utils.TraceRedirects(c.Request.Context(), urlQuery)
+		response := models.RedirectTraceResponse{
+			OriginalURL: models.SafeURLString(urlQuery),
+			FinalURL:    models.SafeURLString(trace.FinalURL),
+			Trace:       trace,
+			Curl:        curlCommand,
+		}
+		if err != nil {
+			response.Error = err.Error() // Still 200 but with error in body
+		}
+		c.JSON(http.StatusOK, response)
+		return
+	}
+
+	finalURL, err := utils.ResolveRedirect(c.Request.Context(), urlQuery) // Assuming utils.ResolveRedirect exists
 	if err != nil {
 		c.JSON(http.StatusOK, models.ResolveRedirectResponse{ // Still 200 but with error in body
 			OriginalURL: models.SafeURLString(urlQuery),
 			FinalURL:    models.SafeURLString(finalURL), // May be empty or last known on error
+			Curl:        curlCommand,
 			Error:       err.Error(),
 		})
 		return
@@ -85,6 +263,7 @@ func (h *URLUtilitiesHandlers) ResolveRedirectHandler(c *gin.Context) {
 	c.JSON(http.StatusOK, models.ResolveRedirectResponse{
 		OriginalURL: models.SafeURLString(urlQuery),
 		FinalURL:    models.SafeURLString(finalURL),
+		Curl:        curlCommand,
 	})
 }
 
@@ -96,6 +275,7 @@ func (h *URLUtilitiesHandlers) ResolveRedirectHandler(c *gin.Context) {
 // @Accept       json
 // @Produce      json
 // @Param        utm_request body models.UTMGeneratorRequest true "UTM Generation Request"
+// @Param        canonical query bool false "Return RFC 8785 canonical JSON (sorted keys, no whitespace); the X-Canonical-JSON response header is false if that failed"
 // @Success      200 {object} models.UTMGeneratorResponse "Successfully generated UTM URLs"
 // @Failure      400 {object} map[string]string "Invalid input"
 // @Failure      500 {object} map[string]string "Error during URL generation"
@@ -155,3 +335,81 @@ func (h *URLUtilitiesHandlers) GenerateUTMHandler(c *gin.Context) {
 	}
 	c.JSON(http.StatusOK, response)
 }
+
+// ShortenURLHandler godoc
+// @Summary      Create a short link
+// @Description  Stores a short link to an http(s) URL, with a custom slug or a generated one, an optional expiry and an optional maximum number of clicks. GET /r/{slug} on this instance (outside the API base path) redirects to the target with 302 Found and counts the click; links that expired or reached their click limit answer 410 Gone. Links are kept in the STORAGE_BACKEND, or in Redis when REDIS_ADDR is set so every replica shares them.
+// @Tags         URL Manipulation
+// @Accept       json
+// @Produce      json
+// @Param        link body models.ShortenURLRequest true "Target URL and options"
+// @Success      201 {object} models.ShortURLResponse "The stored link"
+// @Failure      400 {object} map[string]string "Error: Invalid input (e.g., not an http(s) URL, malformed slug or expiry over a year)"
+// @Failure      409 {object} map[string]string "Error: The slug is already in use"
+// @Router       /url/shorten [post]
+func (h *URLUtilitiesHandlers) ShortenURLHandler(c *gin.Context) {
+	var req models.ShortenURLRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request payload: " + err.Error()})
+		return
+	}
+	link, err := shortener.Shorten(req.URL, shortener.Options{
+		Slug:      req.Slug,
+		ExpiresIn: time.Duration(req.ExpiresInSeconds) * time.Second,
+		MaxClicks: req.MaxClicks,
+	})
+	if err != nil {
+		status := http.StatusBadRequest
+		if errors.Is(err, shortener.ErrSlugTaken) {
+			status = http.StatusConflict
+		}
+		c.JSON(status, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusCreated, models.ShortURLResponse{ShortURL: shortURL(link.Slug), Link: *link})
+}
+
+// ShortURLStatsHandler godoc
+// @Summary      Get the clicks of a short link
+// @Description  Returns a short link with its click count and the time of its last click, without counting a click.
+// @Tags         URL Manipulation
+// @Produce      json
+// @Param        slug path string true "Slug of the short link"
+// @Success      200 {object} models.ShortURLResponse "The link and its clicks"
+// @Failure      404 {object} map[string]string "Error: Unknown or expired short link"
+// @Router       /url/shorten/{slug}/stats [get]
+func (h *URLUtilitiesHandlers) ShortURLStatsHandler(c *gin.Context) {
+	link, err := shortener.Stats(c.Param("slug"))
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, shortener.ErrNotFound) {
+			status = http.StatusNotFound
+		}
+		c.JSON(status, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, models.ShortURLResponse{ShortURL: shortURL(link.Slug), Link: *link})
+}
+
+// FollowShortURLHandler redirects GET /r/{slug} to the target of a short link and counts the
+// click. It is served outside the API base path, so it is not in the Swagger document.
+func (h *URLUtilitiesHandlers) FollowShortURLHandler(c *gin.Context) {
+	link, err := shortener.Follow(c.Param("slug"))
+	switch {
+	case errors.Is(err, shortener.ErrNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, shortener.ErrExpired), errors.Is(err, shortener.ErrClickLimit):
+		c.JSON(http.StatusGone, gin.H{"error": err.Error()})
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	default:
+		c.Header("Cache-Control", "no-store") // Every click must reach us to be counted
+		c.Redirect(http.StatusFound, link.URL)
+	}
+}
+
+// shortURL returns the link that redirects to a short link's target, absolute when the
+// external URL is set.
+func shortURL(slug string) string {
+	return ExternalURL() + "/r/" + url.PathEscape(slug)
+}
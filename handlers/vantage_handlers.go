@@ -0,0 +1,194 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/vit0-9/utils_api/models"
+	"github.com/vit0-9/utils_api/pkg/utils"
+	"github.com/vit0-9/utils_api/pkg/utils/vantage"
+)
+
+// maxVantages bounds how many vantage points one request may fan out to.
+const maxVantages = 10
+
+type VantageHandlers struct{}
+
+func NewVantageHandlers() *VantageHandlers {
+	return &VantageHandlers{}
+}
+
+// ListVantagesHandler godoc
+// @Summary      List vantage points
+// @Description  Returns this instance's vantage name and the agents currently registered with it. Checks whose result depends on where they run from, such as DNS lookups, SSL checks, ping and page analyses, accept `vantage=name1,name2` to run from those vantage points ("local" is this instance) and return the results side by side.
+// @Tags         Vantage Points
+// @Produce      json
+// @Success      200 {object} models.VantageListResponse "This instance and its live agents"
+// @Router       /vantage [get]
+func (h *VantageHandlers) ListVantagesHandler(c *gin.Context) {
+	c.JSON(http.StatusOK, models.VantageListResponse{
+		Self:   vantage.Name(),
+		Agents: vantage.Agents(),
+	})
+}
+
+// RegisterVantageHandler godoc
+// @Summary      Register an agent vantage point
+// @Description  Called periodically by agent instances (configured with VANTAGE_PRIMARY_URL) to register or refresh themselves. Requires the shared secret in the X-Vantage-Secret header.
+// @Tags         Vantage Points
+// @Accept       json
+// @Produce      json
+// @Param        X-Vantage-Secret header string true "Shared secret configured with VANTAGE_SECRET"
+// @Param        registration body models.VantageRegistrationRequest true "Agent name and the base URL the primary reaches it at"
+// @Success      200 {object} map[string]string "Registration accepted"
+// @Failure      400 {object} map[string]string "Error: Invalid input (e.g., missing name or URL)"
+// @Failure      401 {object} map[string]string "Error: Missing or wrong shared secret"
+// @Failure      403 {object} map[string]string "Error: This instance does not accept registrations"
+// @Router       /vantage/register [post]
+func (h *VantageHandlers) RegisterVantageHandler(c *gin.Context) {
+	if !vantage.AcceptsRegistrations() {
+		c.JSON(http.StatusForbidden, gin.H{"error": "agent registration is disabled on this instance"})
+		return
+	}
+	if !vantage.CheckSecret(c.GetHeader(vantage.SecretHeader)) {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid vantage secret"})
+		return
+	}
+	var req models.VantageRegistrationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request payload: " + err.Error()})
+		return
+	}
+	if err := vantage.Register(req.Name, req.URL); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"status": "registered"})
+}
+
+// VantageMiddleware runs GET requests carrying vantage=eu,us on each named vantage point,
+// this instance included under its own name or "local", and returns the results side by
+// side. Agents receive the request without the vantage parameter, so they answer locally.
+func VantageMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		vantageParam := c.Query("vantage")
+		if vantageParam == "" || c.Request.Method != http.MethodGet {
+			c.Next()
+			return
+		}
+		if format := c.Query("format"); format != "" && format != "json" {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "vantage queries return JSON only; remove the format parameter"})
+			return
+		}
+
+		var names []string
+		seen := make(map[string]bool)
+		for _, name := range strings.Split(vantageParam, ",") {
+			name = strings.ToLower(strings.TrimSpace(name))
+			if name == "local" {
+				name = vantage.Name()
+			}
+			if name != "" && !seen[name] {
+				seen[name] = true
+				names = append(names, name)
+			}
+		}
+		if len(names) > maxVantages {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "too many vantage points requested"})
+			return
+		}
+
+		query := c.Request.URL.Query()
+		query.Del("vantage")
+		query.Del("canonical") // The combined response is canonicalized as a whole
+		relayQuery := query.Encode()
+
+		ctx, cancel := context.WithTimeout(c.Request.Context(), 90*time.Second)
+		defer cancel()
+
+		results := make([]vantage.Result, len(names))
+		var wg sync.WaitGroup
+		localIndex := -1
+		for i, name := range names {
+			if name == vantage.Name() {
+				localIndex = i
+				continue
+			}
+			wg.Add(1)
+			go func(i int, name string) {
+				defer wg.Done()
+				results[i] = vantage.Relay(ctx, name, c.Request.URL.Path, relayQuery)
+			}(i, name)
+		}
+
+		if localIndex >= 0 {
+			writer := &bufferedResponseWriter{ResponseWriter: c.Writer}
+			c.Writer = writer
+			start := time.Now()
+			c.Next()
+			c.Writer = writer.ResponseWriter
+			result := vantage.Result{Vantage: vantage.Name(), StatusCode: writer.Status(), LatencyMs: time.Since(start).Milliseconds()}
+			if body := writer.body.Bytes(); json.Valid(body) {
+				result.Response = append(json.RawMessage{}, body...)
+			} else {
+				result.Error = "the endpoint returned a non-JSON response"
+			}
+			results[localIndex] = result
+		} else {
+			c.Abort() // Only remote vantage points were asked for
+		}
+		wg.Wait()
+
+		c.JSON(http.StatusOK, models.VantageComparisonResponse{
+			Path:      c.Request.URL.Path,
+			Vantages:  results,
+			Identical: identicalResponses(results),
+		})
+	}
+}
+
+// identicalResponses reports whether every successful result has the same canonical JSON,
+// ignoring the per-request meta section.
+func identicalResponses(results []vantage.Result) bool {
+	var first []byte
+	compared := 0
+	for _, result := range results {
+		if result.Response == nil {
+			continue
+		}
+		canonical, err := utils.CanonicalizeJSON(withoutMeta(result.Response))
+		if err != nil {
+			return false
+		}
+		if first == nil {
+			first = canonical
+		} else if !bytes.Equal(first, canonical) {
+			return false
+		}
+		compared++
+	}
+	return compared > 1
+}
+
+// withoutMeta drops the top-level meta key from a JSON object response.
+func withoutMeta(response json.RawMessage) json.RawMessage {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(response, &fields); err != nil {
+		return response
+	}
+	if _, ok := fields["meta"]; !ok {
+		return response
+	}
+	delete(fields, "meta")
+	stripped, err := json.Marshal(fields)
+	if err != nil {
+		return response
+	}
+	return stripped
+}
@@ -1,13 +1,19 @@
 package handlers
 
 import (
+	"errors"
+	"fmt"
 	"log"
 	"net/http"
+	"slices"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/vit0-9/utils_api/models"
 	"github.com/vit0-9/utils_api/pkg/utils"
+	"github.com/vit0-9/utils_api/pkg/utils/crawler"
 )
 
 // WebAnalysisHandlers groups web page analysis utilities
@@ -21,19 +27,56 @@ func NewWebAnalysisHandlers() *WebAnalysisHandlers {
 // @Summary      Analyze technology stack of a website
 // @Description  Fetches a URL and uses Wappalyzergo to identify technologies used.
 // @Tags         Web Analysis
-// @Produce      json
-// @Param        url query string true "URL of the website to analyze"
+// @Produce      json,html,application/pdf
+// @Param        url query string false "URL of the website to analyze"
+// @Param        categories query []string false "Only return technologies in these categories (e.g. cms,analytics)" collectionFormat(csv)
+// @Param        evidence query bool false "Include the fingerprint patterns that matched each technology"
+// @Param        vulns query bool false "Include known vulnerability counts for components detected with a version and CPE"
+// @Param        include_curl query bool false "Include the equivalent curl command (method, headers, User-Agent, proxy) the server used"
+// @Param        capture_id query string false "Re-run the analysis on a stored capture instead of fetching (url is then optional)"
+// @Param        method query string false "HTTP method of the fetch: GET (default) or HEAD to analyze the headers only"
+// @Param        timeout query int false "Seconds the fetch may take, redirects included (1-60, default 30)"
+// @Param        max_size query int false "Bytes of the page body analyzed (default 10 MB, at most 50 MB); longer bodies are cut and body_truncated is set"
+// @Param        format query string false "Response format: json (default), html for a standalone report page or pdf for a printable document"
+// @Param        canonical query bool false "Return RFC 8785 canonical JSON (sorted keys, no whitespace); the X-Canonical-JSON response header is false if that failed"
+// @Param        vantage query string false "Comma-separated vantage points to run the check from, e.g. eu,local; the results are returned side by side as a models.VantageComparisonResponse"
+// @Param        cache query bool false "Set to false to skip the result cache and refresh it; the X-Cache response header reports HIT, MISS or BYPASS"
 // @Success      200 {object} models.StackAnalyzerResponse "Successfully analyzed stack or error during analysis"
 // @Failure      400 {object} map[string]string "Error: Invalid input (e.g., missing URL)"
 // @Router       /web/stack-analyzer [get]
 func (h *WebAnalysisHandlers) StackAnalyzerHandler(c *gin.Context) {
-	urlQuery := c.Query("url")
-	if urlQuery == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "url query parameter is required"})
+	urlQuery, captureID, ok := urlOrCaptureQuery(c)
+	if !ok {
 		return
 	}
+	fetchOptions, ok := fetchOptionsQuery(c)
+	if !ok {
+		return
+	}
+	includeVulns := c.Query("vulns") == "true"
+	includeEvidence := c.Query("evidence") == "true"
+	includeCurl := c.Query("include_curl") == "true"
+	var categories []string
+	for _, category := range strings.Split(c.Query("categories"), ",") {
+		if category = strings.TrimSpace(category); category != "" {
+			categories = append(categories, category)
+		}
+	}
 
-	utilTechInfo, finalURL, err := utils.AnalyzeStack(urlQuery)
+	fetchResult, urlQuery, captureID, err := utils.FetchOrReplayWithOptions(c.Request.Context(), urlQuery, captureID, fetchOptions)
+	var utilTechInfo []utils.DetectedTechnologyInfo
+	if err == nil {
+		utilTechInfo, err = utils.AnalyzeFetchedStack(urlQuery, fetchResult)
+	}
+	finalURL, curlCommand := urlQuery, ""
+	if fetchResult != nil {
+		if fetchResult.FinalURL != "" {
+			finalURL = fetchResult.FinalURL
+		}
+		if includeCurl {
+			curlCommand = fetchResult.CurlCommand
+		}
+	}
 	if err != nil {
 		errMsg := err.Error()
 		if strings.Contains(errMsg, "wappalyzer client not available") || strings.Contains(errMsg, "failed to initialize wappalyzer client") {
@@ -44,14 +87,18 @@ func (h *WebAnalysisHandlers) StackAnalyzerHandler(c *gin.Context) {
 			log.Printf("StackAnalyzerHandler critical error: %v", err)
 			return
 		}
-		c.JSON(http.StatusOK, models.StackAnalyzerResponse{ // Still 200 but with error in body
+		writeReport(c, "Technology Stack", models.StackAnalyzerResponse{ // Still 200 but with error in body
 			RequestURL: urlQuery,
 			FinalURL:   finalURL,
+			CaptureID:  captureID,
+			Curl:       curlCommand,
 			Error:      errMsg,
 		})
 		return
 	}
 
+	utilTechInfo = utils.FilterTechnologiesByCategory(utilTechInfo, categories)
+
 	responseTechnologies := make([]models.DetectedTechnology, len(utilTechInfo))
 	for i, uti := range utilTechInfo {
 		responseTechnologies[i] = models.DetectedTechnology{
@@ -62,40 +109,89 @@ func (h *WebAnalysisHandlers) StackAnalyzerHandler(c *gin.Context) {
 			Website:     uti.Website,
 			Icon:        uti.Icon,
 			CPE:         uti.CPE,
+
+			Confidence:      uti.Confidence,
+			EvidenceSources: uti.EvidenceSources,
+			MatchedPatterns: len(uti.Evidence),
+		}
+		if includeEvidence {
+			responseTechnologies[i].Evidence = uti.Evidence
+		}
+		if includeVulns {
+			responseTechnologies[i].Vulnerabilities = utils.LookupVulnerabilities(uti.CPE, uti.Version)
 		}
 	}
 	response := models.StackAnalyzerResponse{
-		RequestURL:   urlQuery,
-		FinalURL:     finalURL,
-		Technologies: responseTechnologies,
+		RequestURL:    urlQuery,
+		FinalURL:      finalURL,
+		CaptureID:     captureID,
+		Technologies:  responseTechnologies,
+		BodyTruncated: fetchResult.Truncated,
+		Curl:          curlCommand,
 	}
-	c.JSON(http.StatusOK, response)
+	writeReport(c, "Technology Stack", response)
 }
 
 // HTTPHeadersHandler godoc
 // @Summary      View HTTP response headers for a URL
-// @Description  Fetches and displays the HTTP response headers from a given URL. Uses the advanced HTTP client which follows redirects by default.
+// @Description  Fetches and displays the HTTP response headers from a given URL. Uses the advanced HTTP client which follows redirects by default. Sends a HEAD request unless another method is given, and repeats it as GET when the server does not support HEAD (405 or 501); method reports the method of the response shown. Only GET responses are stored as captures. With body_preview or hashes the request is a GET by default and body describes the response body: its size, the type sniffed from its content next to the declared one, a preview and its SHA-256 and MD5 hashes for integrity checks and sample triage.
 // @Tags         Web Analysis
-// @Produce      json
-// @Param        url query string true "URL to fetch headers from"
-// @Param        method query string false "HTTP method (GET or HEAD). Note: FetchURL currently defaults to GET."
+// @Produce      json,html,application/pdf
+// @Param        url query string false "URL to fetch headers from"
+// @Param        method query string false "HTTP method: HEAD (default), GET or OPTIONS"
+// @Param        follow_redirects query bool false "Set to false to return the first response, e.g. a redirect with its Location header, instead of the final one"
+// @Param        timeout query int false "Seconds the fetch may take, redirects included (1-60, default 30)"
+// @Param        max_size query int false "Bytes of the body read (default 10 MB, at most 50 MB); the rest is not downloaded"
+// @Param        body_preview query int false "Include the first N bytes of the body (at most 65536), as UTF-8 text or base64 for binary content"
+// @Param        hashes query bool false "Include the SHA-256 and MD5 hashes of the body"
+// @Param        include_curl query bool false "Include the equivalent curl command (method, headers, User-Agent, proxy) the server used"
+// @Param        capture_id query string false "Re-run the analysis on a stored capture instead of fetching (url is then optional)"
+// @Param        format query string false "Response format: json (default), html for a standalone report page or pdf for a printable document"
+// @Param        canonical query bool false "Return RFC 8785 canonical JSON (sorted keys, no whitespace); the X-Canonical-JSON response header is false if that failed"
+// @Param        vantage query string false "Comma-separated vantage points to run the check from, e.g. eu,local; the results are returned side by side as a models.VantageComparisonResponse"
 // @Success      200 {object} models.HTTPHeadersResponse "Successfully retrieved HTTP headers or error during fetch"
 // @Failure      400 {object} map[string]string "Error: Invalid input (e.g., missing URL)"
 // @Router       /web/http-headers [get]
 func (h *WebAnalysisHandlers) HTTPHeadersHandler(c *gin.Context) {
-	urlQuery := c.Query("url")
-	if urlQuery == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "url query parameter is required"})
+	urlQuery, captureID, ok := urlOrCaptureQuery(c)
+	if !ok {
 		return
 	}
 
-	// The `method` query parameter is noted in Swagger, but our current `utils.FetchURL`
-	// defaults to GET. If you need to support different methods like HEAD specifically
-	// for this handler, `utils.FetchURL` would need to be extended or a different
-	// utility function called. For now, we'll assume GET via FetchURL.
-	// String methodQuery := c.Query("method")
+	fetchOptions, ok := fetchOptionsQuery(c, http.MethodHead, http.MethodGet, http.MethodOptions)
+	if !ok {
+		return
+	}
+	previewBytes := 0
+	if value := c.Query("body_preview"); value != "" {
+		var err error
+		previewBytes, err = strconv.Atoi(value)
+		if err != nil || previewBytes < 0 || previewBytes > utils.MaxBodyPreviewBytes {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("body_preview must be between 0 and %d bytes", utils.MaxBodyPreviewBytes)})
+			return
+		}
+	}
+	hashes := c.Query("hashes") == "true"
+	describeBody := previewBytes > 0 || hashes
+	defaultMethod := fetchOptions.Method == ""
+	if defaultMethod && describeBody {
+		fetchOptions.Method = http.MethodGet
+		defaultMethod = false
+	} else if defaultMethod {
+		fetchOptions.Method = http.MethodHead // Headers only, so nothing but them is downloaded
+	}
+	fetchOptions.NoRedirects = c.Query("follow_redirects") == "false"
 
-	fetchResult, err := utils.FetchURL(urlQuery) // Use the FetchURL utility
+	includeCurl := c.Query("include_curl") == "true"
+	fetchResult, urlQuery, captureID, err := utils.FetchOrReplayWithOptions(c.Request.Context(), urlQuery, captureID, fetchOptions)
+	if err == nil && captureID == "" && defaultMethod && (fetchResult.StatusCode == http.StatusMethodNotAllowed || fetchResult.StatusCode == http.StatusNotImplemented) {
+		fetchOptions.Method = http.MethodGet
+		fetchResult, urlQuery, captureID, err = utils.FetchOrReplayWithOptions(c.Request.Context(), urlQuery, "", fetchOptions)
+	}
+	method := fetchOptions.Method
+	if c.Query("capture_id") != "" {
+		method = http.MethodGet // Captures are GET responses
+	}
 
 	if err != nil {
 		// FetchURL returns a formatted error. We can pass it along.
@@ -103,25 +199,575 @@ func (h *WebAnalysisHandlers) HTTPHeadersHandler(c *gin.Context) {
 		// with the error detailed in the JSON body.
 		response := models.HTTPHeadersResponse{
 			RequestURL: urlQuery,
+			Method:     method,
 			Error:      err.Error(),
 		}
 		if fetchResult != nil { // If fetchResult is not nil, some partial info might exist
 			response.FinalURL = fetchResult.FinalURL
 			response.StatusCode = fetchResult.StatusCode
 			response.Status = fetchResult.Status
+			if includeCurl {
+				response.Curl = fetchResult.CurlCommand
+			}
 		}
-		c.JSON(http.StatusOK, response)
+		writeReport(c, "HTTP Headers", response)
 		return
 	}
 
 	// Successfully fetched
 	response := models.HTTPHeadersResponse{
 		RequestURL: urlQuery,
+		Method:     method,
 		StatusCode: fetchResult.StatusCode,
 		Status:     fetchResult.Status,
 		Headers:    fetchResult.Headers,
 		FinalURL:   fetchResult.FinalURL,
+		CaptureID:  captureID,
+	}
+	if describeBody && method != http.MethodHead {
+		response.Body = utils.DescribeBody(fetchResult, previewBytes, hashes)
+	}
+	if includeCurl {
+		response.Curl = fetchResult.CurlCommand
+	}
+
+	writeReport(c, "HTTP Headers", response)
+}
+
+// ConsentCheckHandler godoc
+// @Summary      Detect consent management platforms and pre-consent trackers
+// @Description  Fetches a page, identifies consent management platforms (OneTrust, Cookiebot, Didomi, ...) and lists tracking scripts, flagging those that execute before the visitor consents. Detection is static and does not execute JavaScript.
+// @Tags         Web Analysis
+// @Produce      json,html,application/pdf
+// @Param        url query string false "URL of the page to check"
+// @Param        include_curl query bool false "Include the equivalent curl command (method, headers, User-Agent, proxy) the server used"
+// @Param        capture_id query string false "Re-run the analysis on a stored capture instead of fetching (url is then optional)"
+// @Param        format query string false "Response format: json (default), html for a standalone report page or pdf for a printable document"
+// @Param        canonical query bool false "Return RFC 8785 canonical JSON (sorted keys, no whitespace); the X-Canonical-JSON response header is false if that failed"
+// @Param        vantage query string false "Comma-separated vantage points to run the check from, e.g. eu,local; the results are returned side by side as a models.VantageComparisonResponse"
+// @Success      200 {object} models.ConsentCheckResponse "Successfully analyzed consent setup or error during fetch"
+// @Failure      400 {object} map[string]string "Error: Invalid input (e.g., missing URL)"
+// @Router       /web/consent-check [get]
+func (h *WebAnalysisHandlers) ConsentCheckHandler(c *gin.Context) {
+	urlQuery, captureID, ok := urlOrCaptureQuery(c)
+	if !ok {
+		return
+	}
+
+	includeCurl := c.Query("include_curl") == "true"
+	fetchResult, urlQuery, captureID, err := utils.FetchOrReplay(c.Request.Context(), urlQuery, captureID)
+	var analysis *utils.ConsentAnalysis
+	if err != nil {
+		if fetchResult != nil {
+			analysis = &utils.ConsentAnalysis{CurlCommand: fetchResult.CurlCommand}
+		}
+	} else {
+		analysis, err = utils.AnalyzeFetchedConsent(urlQuery, fetchResult)
+	}
+	if err != nil {
+		response := models.ConsentCheckResponse{
+			RequestURL: urlQuery,
+			Error:      err.Error(),
+		}
+		if analysis != nil {
+			response.FinalURL = analysis.FinalURL
+			if includeCurl {
+				response.Curl = analysis.CurlCommand
+			}
+		}
+		writeReport(c, "Consent Check", response)
+		return
+	}
+
+	response := models.ConsentCheckResponse{
+		RequestURL:            urlQuery,
+		FinalURL:              analysis.FinalURL,
+		CaptureID:             captureID,
+		DetectionMethod:       analysis.DetectionMethod,
+		CMPDetected:           len(analysis.Platforms) > 0,
+		Platforms:             analysis.Platforms,
+		Trackers:              analysis.Trackers,
+		TrackersBeforeConsent: analysis.TrackersBeforeConsent,
+	}
+	if includeCurl {
+		response.Curl = analysis.CurlCommand
 	}
+	writeReport(c, "Consent Check", response)
+}
+
+// SocialLinksHandler godoc
+// @Summary      Extract social profiles and contact details from a page
+// @Description  Fetches a page and returns normalized, deduplicated social profile URLs (X/Twitter, LinkedIn, Facebook, Instagram, GitHub, YouTube) plus validated contact emails and phone numbers. Common obfuscations (HTML entities, "name [at] domain [dot] com", Cloudflare email protection, concatenated JavaScript strings) are decoded, and each contact reports the element it was found in.
+// @Tags         Web Analysis
+// @Produce      json,html,application/pdf
+// @Param        url query string false "URL of the page to extract from"
+// @Param        include_curl query bool false "Include the equivalent curl command (method, headers, User-Agent, proxy) the server used"
+// @Param        capture_id query string false "Re-run the analysis on a stored capture instead of fetching (url is then optional)"
+// @Param        format query string false "Response format: json (default), html for a standalone report page or pdf for a printable document"
+// @Param        canonical query bool false "Return RFC 8785 canonical JSON (sorted keys, no whitespace); the X-Canonical-JSON response header is false if that failed"
+// @Param        vantage query string false "Comma-separated vantage points to run the check from, e.g. eu,local; the results are returned side by side as a models.VantageComparisonResponse"
+// @Success      200 {object} models.SocialLinksResponse "Successfully extracted links or error during fetch"
+// @Failure      400 {object} map[string]string "Error: Invalid input (e.g., missing URL)"
+// @Router       /web/social-links [get]
+func (h *WebAnalysisHandlers) SocialLinksHandler(c *gin.Context) {
+	urlQuery, captureID, ok := urlOrCaptureQuery(c)
+	if !ok {
+		return
+	}
+
+	includeCurl := c.Query("include_curl") == "true"
+	fetchResult, urlQuery, captureID, err := utils.FetchOrReplay(c.Request.Context(), urlQuery, captureID)
+	var contacts *utils.ExtractedContacts
+	if err != nil {
+		if fetchResult != nil {
+			contacts = &utils.ExtractedContacts{CurlCommand: fetchResult.CurlCommand}
+		}
+	} else {
+		contacts, err = utils.ExtractFetchedSocialLinks(urlQuery, fetchResult)
+	}
+	if err != nil {
+		response := models.SocialLinksResponse{
+			RequestURL: urlQuery,
+			Error:      err.Error(),
+		}
+		if contacts != nil {
+			response.FinalURL = contacts.FinalURL
+			if includeCurl {
+				response.Curl = contacts.CurlCommand
+			}
+		}
+		writeReport(c, "Social Links & Contacts", response)
+		return
+	}
+
+	response := models.SocialLinksResponse{
+		RequestURL:     urlQuery,
+		FinalURL:       contacts.FinalURL,
+		CaptureID:      captureID,
+		SocialProfiles: contacts.SocialProfiles,
+		Emails:         contacts.Emails,
+		Phones:         contacts.Phones,
+		Contacts:       contacts.Contacts,
+	}
+	if includeCurl {
+		response.Curl = contacts.CurlCommand
+	}
+	if response.SocialProfiles == nil {
+		response.SocialProfiles = []utils.SocialProfile{}
+	}
+	if response.Emails == nil {
+		response.Emails = []string{}
+	}
+	if response.Phones == nil {
+		response.Phones = []string{}
+	}
+	if response.Contacts == nil {
+		response.Contacts = []utils.ContactDetail{}
+	}
+	writeReport(c, "Social Links & Contacts", response)
+}
+
+// MetaExtractHandler godoc
+// @Summary      Extract HTML metadata, Open Graph and Twitter Card tags from a page
+// @Description  Fetches a page and returns its title, meta description, canonical URL, Open Graph and Twitter Card tags (in document order, repeats kept), declared favicons, hreflang alternates and parsed JSON-LD structured data blocks. Link URLs are resolved against the final URL; JSON-LD blocks that are not valid JSON are listed under json_ld_errors.
+// @Tags         Web Analysis
+// @Produce      json,html,application/pdf
+// @Param        url query string false "URL of the page to extract from"
+// @Param        include_curl query bool false "Include the equivalent curl command (method, headers, User-Agent, proxy) the server used"
+// @Param        capture_id query string false "Re-run the analysis on a stored capture instead of fetching (url is then optional)"
+// @Param        format query string false "Response format: json (default), html for a standalone report page or pdf for a printable document"
+// @Param        canonical query bool false "Return RFC 8785 canonical JSON (sorted keys, no whitespace); the X-Canonical-JSON response header is false if that failed"
+// @Param        vantage query string false "Comma-separated vantage points to run the check from, e.g. eu,local; the results are returned side by side as a models.VantageComparisonResponse"
+// @Success      200 {object} models.MetaExtractResponse "Successfully extracted metadata or error during fetch"
+// @Failure      400 {object} map[string]string "Error: Invalid input (e.g., missing URL)"
+// @Router       /web/meta-extract [get]
+func (h *WebAnalysisHandlers) MetaExtractHandler(c *gin.Context) {
+	urlQuery, captureID, ok := urlOrCaptureQuery(c)
+	if !ok {
+		return
+	}
+
+	includeCurl := c.Query("include_curl") == "true"
+	fetchResult, urlQuery, captureID, err := utils.FetchOrReplay(c.Request.Context(), urlQuery, captureID)
+	var metadata *utils.PageMetadata
+	if err != nil {
+		if fetchResult != nil {
+			metadata = &utils.PageMetadata{CurlCommand: fetchResult.CurlCommand}
+		}
+	} else {
+		metadata, err = utils.ExtractFetchedMetadata(urlQuery, fetchResult)
+	}
+	if err != nil {
+		response := models.MetaExtractResponse{
+			RequestURL: urlQuery,
+			Error:      err.Error(),
+		}
+		if metadata != nil {
+			response.FinalURL = metadata.FinalURL
+			if includeCurl {
+				response.Curl = metadata.CurlCommand
+			}
+		}
+		writeReport(c, "Page Metadata", response)
+		return
+	}
+
+	response := models.MetaExtractResponse{
+		RequestURL:   urlQuery,
+		FinalURL:     metadata.FinalURL,
+		CaptureID:    captureID,
+		Title:        metadata.Title,
+		Description:  metadata.Description,
+		CanonicalURL: metadata.CanonicalURL,
+		OpenGraph:    metadata.OpenGraph,
+		TwitterCard:  metadata.TwitterCard,
+		Favicons:     metadata.Favicons,
+		Hreflang:     metadata.Hreflang,
+		JSONLD:       metadata.JSONLD,
+		JSONLDErrors: metadata.JSONLDErrors,
+	}
+	if includeCurl {
+		response.Curl = metadata.CurlCommand
+	}
+	if response.OpenGraph == nil {
+		response.OpenGraph = []utils.MetaTag{}
+	}
+	if response.TwitterCard == nil {
+		response.TwitterCard = []utils.MetaTag{}
+	}
+	if response.Favicons == nil {
+		response.Favicons = []utils.Favicon{}
+	}
+	if response.Hreflang == nil {
+		response.Hreflang = []utils.HreflangLink{}
+	}
+	if response.JSONLD == nil {
+		response.JSONLD = []any{}
+	}
+	writeReport(c, "Page Metadata", response)
+}
 
-	c.JSON(http.StatusOK, response)
+// MixedContentHandler godoc
+// @Summary      Find insecure http:// resources on an HTTPS page
+// @Description  Fetches a page and lists every http:// script, stylesheet, image, media source, frame, plugin and form action with its tag, attribute, line and markup. Resources are classed as active (scripts, stylesheets, frames: blocked by browsers), passive (images and media: loaded with a warning) or form (submits over http). Relative and protocol-relative URLs are secure on an HTTPS page and not listed.
+// @Tags         Web Analysis
+// @Produce      json,html,application/pdf
+// @Param        url query string false "URL of the page to scan"
+// @Param        include_curl query bool false "Include the equivalent curl command (method, headers, User-Agent, proxy) the server used"
+// @Param        capture_id query string false "Re-run the analysis on a stored capture instead of fetching (url is then optional)"
+// @Param        format query string false "Response format: json (default), html for a standalone report page or pdf for a printable document"
+// @Param        canonical query bool false "Return RFC 8785 canonical JSON (sorted keys, no whitespace); the X-Canonical-JSON response header is false if that failed"
+// @Param        vantage query string false "Comma-separated vantage points to run the check from, e.g. eu,local; the results are returned side by side as a models.VantageComparisonResponse"
+// @Success      200 {object} models.MixedContentResponse "Insecure resources found or error during fetch"
+// @Failure      400 {object} map[string]string "Error: Invalid input (e.g., missing URL)"
+// @Router       /web/mixed-content [get]
+func (h *WebAnalysisHandlers) MixedContentHandler(c *gin.Context) {
+	urlQuery, captureID, ok := urlOrCaptureQuery(c)
+	if !ok {
+		return
+	}
+
+	includeCurl := c.Query("include_curl") == "true"
+	fetchResult, urlQuery, captureID, err := utils.FetchOrReplay(c.Request.Context(), urlQuery, captureID)
+	var report *utils.MixedContentReport
+	if err != nil {
+		if fetchResult != nil {
+			report = &utils.MixedContentReport{FinalURL: fetchResult.FinalURL, CurlCommand: fetchResult.CurlCommand}
+		}
+	} else {
+		report, err = utils.ScanFetchedMixedContent(urlQuery, fetchResult)
+	}
+	if err != nil {
+		response := models.MixedContentResponse{
+			RequestURL: urlQuery,
+			Resources:  []utils.MixedContentResource{},
+			Error:      err.Error(),
+		}
+		if report != nil {
+			response.FinalURL = report.FinalURL
+			if includeCurl {
+				response.Curl = report.CurlCommand
+			}
+		}
+		writeReport(c, "Mixed Content", response)
+		return
+	}
+
+	response := models.MixedContentResponse{
+		RequestURL:  urlQuery,
+		FinalURL:    report.FinalURL,
+		CaptureID:   captureID,
+		PageIsHTTPS: report.PageIsHTTPS,
+		Resources:   report.Resources,
+	}
+	for _, resource := range report.Resources {
+		switch resource.Kind {
+		case utils.MixedContentActive:
+			response.ActiveCount++
+		case utils.MixedContentPassive:
+			response.PassiveCount++
+		case utils.MixedContentForm:
+			response.InsecureForms++
+		}
+	}
+	if includeCurl {
+		response.Curl = report.CurlCommand
+	}
+	writeReport(c, "Mixed Content", response)
+}
+
+// FaviconHashHandler godoc
+// @Summary      Hash a site's favicon for Shodan-style fingerprinting
+// @Description  Downloads the favicon a page declares with <link rel="icon"> (or the site's /favicon.ico, or favicon_url when given) and returns its MurmurHash3 as indexed by Shodan (http.favicon.hash), its MD5 and SHA-256, content type and size, for pivoting to other hosts serving the same icon.
+// @Tags         Web Analysis
+// @Produce      json,html,application/pdf
+// @Param        url query string false "URL of the page whose favicon to hash"
+// @Param        favicon_url query string false "URL of the favicon itself; url is then optional"
+// @Param        include_curl query bool false "Include the equivalent curl command (method, headers, User-Agent, proxy) the server used"
+// @Param        format query string false "Response format: json (default), html for a standalone report page or pdf for a printable document"
+// @Param        canonical query bool false "Return RFC 8785 canonical JSON (sorted keys, no whitespace); the X-Canonical-JSON response header is false if that failed"
+// @Param        vantage query string false "Comma-separated vantage points to run the check from, e.g. eu,local; the results are returned side by side as a models.VantageComparisonResponse"
+// @Success      200 {object} models.FaviconHashResponse "Favicon hashes or error during fetch"
+// @Failure      400 {object} map[string]string "Error: Invalid input (e.g., neither url nor favicon_url)"
+// @Router       /web/favicon-hash [get]
+func (h *WebAnalysisHandlers) FaviconHashHandler(c *gin.Context) {
+	urlQuery := c.Query("url")
+	faviconURL := c.Query("favicon_url")
+	if urlQuery == "" && faviconURL == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "url or favicon_url query parameter is required"})
+		return
+	}
+
+	favicon, err := utils.HashFavicon(c.Request.Context(), urlQuery, faviconURL)
+	response := models.FaviconHashResponse{RequestURL: urlQuery}
+	if favicon != nil {
+		response.FaviconURL = favicon.FaviconURL
+		response.Source = favicon.Source
+		if c.Query("include_curl") == "true" {
+			response.Curl = favicon.CurlCommand
+		}
+	}
+	if err != nil {
+		response.Error = err.Error() // Still 200 but with error in body
+		writeReport(c, "Favicon Hash", response)
+		return
+	}
+	response.ContentType = favicon.ContentType
+	response.Size = favicon.Size
+	response.MMH3 = favicon.MMH3
+	response.MD5 = favicon.MD5
+	response.SHA256 = favicon.SHA256
+	response.ShodanQuery = "http.favicon.hash:" + strconv.FormatInt(int64(favicon.MMH3), 10)
+	writeReport(c, "Favicon Hash", response)
+}
+
+// CrawlHandler godoc
+// @Summary      Crawl a site
+// @Description  Starts a background crawl that follows same-origin links from the start URL, breadth first, up to max_depth links deep and max_pages pages, skipping paths robots.txt disallows for all user agents. Poll the returned crawl for the discovered pages with their status codes, titles and links, and the technologies detected across them; pass a callback_url to receive the completed crawl as a POST signed with CALLBACK_SIGNING_SECRET. At most 5 crawls run at once.
+// @Tags         Web Analysis
+// @Accept       json
+// @Produce      json
+// @Param        crawl body models.CrawlRequest true "Start URL and limits"
+// @Success      202 {object} crawler.Crawl "The started crawl"
+// @Failure      400 {object} map[string]string "Error: Invalid input (e.g., a URL that is not http(s), limits out of range or an invalid callback URL)"
+// @Failure      429 {object} map[string]string "Error: Too many crawls are running"
+// @Router       /web/crawl [post]
+func (h *WebAnalysisHandlers) CrawlHandler(c *gin.Context) {
+	var req models.CrawlRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request payload: " + err.Error()})
+		return
+	}
+	crawl, err := crawler.Start(req.URL, req.MaxDepth, req.MaxPages, req.CallbackURL)
+	if err != nil {
+		status := http.StatusBadRequest
+		if errors.Is(err, crawler.ErrTooManyCrawls) {
+			status = http.StatusTooManyRequests
+		}
+		c.JSON(status, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusAccepted, crawl)
+}
+
+// CrawlResultHandler godoc
+// @Summary      Get a crawl
+// @Description  Returns a crawl's status and, once completed, the pages found and the technologies detected on them. The last 50 crawls are kept in memory.
+// @Tags         Web Analysis
+// @Produce      json
+// @Param        id path string true "Crawl ID"
+// @Param        canonical query bool false "Return RFC 8785 canonical JSON (sorted keys, no whitespace); the X-Canonical-JSON response header is false if that failed"
+// @Success      200 {object} crawler.Crawl "The crawl"
+// @Failure      404 {object} map[string]string "Error: Unknown or expired crawl"
+// @Router       /web/crawl/{id} [get]
+func (h *WebAnalysisHandlers) CrawlResultHandler(c *gin.Context) {
+	crawl, ok := crawler.Get(c.Param("id"))
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "crawl not found"})
+		return
+	}
+	c.JSON(http.StatusOK, crawl)
+}
+
+// HARExportHandler godoc
+// @Summary      Export the request/response log of a page fetch as HAR
+// @Description  Fetches a page the same way the fetch-based analyses do and returns every exchange, including redirect hops, as an HTTP Archive (HAR 1.2) document with headers, cookies, decoded bodies and timing breakdowns. If the fetch fails part way, the captured entries are still returned and the error is in log.comment. Set download=true to receive it as a .har attachment.
+// @Tags         Web Analysis
+// @Produce      json
+// @Param        url query string true "URL of the page to capture"
+// @Param        download query bool false "Send as a file attachment"
+// @Param        canonical query bool false "Return RFC 8785 canonical JSON (sorted keys, no whitespace); the X-Canonical-JSON response header is false if that failed"
+// @Success      200 {object} models.HARResponse "HAR document of the fetch"
+// @Failure      400 {object} map[string]string "Error: Invalid input (e.g., missing URL)"
+// @Router       /web/har [get]
+func (h *WebAnalysisHandlers) HARExportHandler(c *gin.Context) {
+	urlQuery := c.Query("url")
+	if urlQuery == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "url query parameter is required"})
+		return
+	}
+
+	harLog, err := utils.CaptureHAR(c.Request.Context(), urlQuery)
+	if harLog == nil {
+		c.JSON(http.StatusOK, gin.H{"request_url": urlQuery, "error": err.Error()})
+		return
+	}
+	if err != nil {
+		harLog.Comment = err.Error()
+	}
+
+	if download, _ := strconv.ParseBool(c.Query("download")); download {
+		c.Header("Content-Disposition", `attachment; filename="capture.har"`)
+	}
+	c.JSON(http.StatusOK, models.HARResponse{Log: *harLog})
+}
+
+// ContentSimilarityHandler godoc
+// @Summary      Compare a page with a baseline to detect defacements
+// @Description  Fetches a page and a baseline, either another URL or a stored capture of an earlier fetch, and scores how similar they are: Jaccard similarity of 4-word shingles of the visible text, the distance between 64-bit SimHashes of the text, and Jaccard similarity of the element sequence. The structure summary lists title changes, elements added or removed and external scripts that appeared or disappeared. The verdict is identical, similar (only dynamic parts changed), modified or different (a defacement, parked page or another site). Store the capture_id of a known-good fetch and pass it as baseline_capture_id to monitor a page over time.
+// @Tags         Web Analysis
+// @Produce      json,html,application/pdf
+// @Param        url query string false "URL of the page to check"
+// @Param        capture_id query string false "Re-run the analysis on a stored capture instead of fetching (url is then optional)"
+// @Param        baseline_url query string false "URL of the baseline page"
+// @Param        baseline_capture_id query string false "Stored capture to use as the baseline (baseline_url is then optional)"
+// @Param        format query string false "Response format: json (default), html for a standalone report page or pdf for a printable document"
+// @Param        canonical query bool false "Return RFC 8785 canonical JSON (sorted keys, no whitespace); the X-Canonical-JSON response header is false if that failed"
+// @Param        vantage query string false "Comma-separated vantage points to run the check from, e.g. eu,local; the results are returned side by side as a models.VantageComparisonResponse"
+// @Success      200 {object} models.ContentSimilarityResponse "Similarity scores or error during fetch"
+// @Failure      400 {object} map[string]string "Error: Invalid input (e.g., missing URL or baseline)"
+// @Router       /web/similarity [get]
+func (h *WebAnalysisHandlers) ContentSimilarityHandler(c *gin.Context) {
+	urlQuery, captureID, ok := urlOrCaptureQuery(c)
+	if !ok {
+		return
+	}
+	baselineURL, baselineCaptureID := c.Query("baseline_url"), c.Query("baseline_capture_id")
+	if baselineURL == "" && baselineCaptureID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "baseline_url or baseline_capture_id query parameter is required"})
+		return
+	}
+
+	response := models.ContentSimilarityResponse{RequestURL: urlQuery, BaselineURL: baselineURL}
+	baseline, baselineURL, baselineCaptureID, err := utils.FetchOrReplay(c.Request.Context(), baselineURL, baselineCaptureID)
+	response.BaselineURL, response.BaselineCaptureID = baselineURL, baselineCaptureID
+	if err != nil {
+		response.Error = "failed to fetch the baseline: " + err.Error()
+		writeReport(c, "Content Similarity", response)
+		return
+	}
+	response.BaselineStatusCode = baseline.StatusCode
+
+	current, urlQuery, captureID, err := utils.FetchOrReplay(c.Request.Context(), urlQuery, captureID)
+	response.RequestURL, response.CaptureID = urlQuery, captureID
+	if current != nil {
+		response.FinalURL = current.FinalURL
+	}
+	if err != nil {
+		response.Error = err.Error()
+		writeReport(c, "Content Similarity", response)
+		return
+	}
+	response.StatusCode = current.StatusCode
+	response.Similarity = utils.CompareContent(utils.DecodeResponseBody(baseline), utils.DecodeResponseBody(current))
+	writeReport(c, "Content Similarity", response)
+}
+
+// urlOrCaptureQuery reads the url and capture_id query parameters. At least one is required;
+// when neither is set a 400 response is written and ok is false.
+func urlOrCaptureQuery(c *gin.Context) (urlQuery string, captureID string, ok bool) {
+	urlQuery = c.Query("url")
+	captureID = c.Query("capture_id")
+	if urlQuery == "" && captureID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "url or capture_id query parameter is required"})
+		return "", "", false
+	}
+	return urlQuery, captureID, true
+}
+
+// Bounds of the fetch options callers can set.
+const (
+	maxFetchTimeoutSeconds = 60
+	maxFetchSize           = 50 << 20
+)
+
+// fetchOptionsQuery reads the method (one of methods, GET and HEAD when none are given),
+// timeout (seconds) and max_size (bytes) query parameters. When one is invalid a 400 response
+// is written and ok is false.
+func fetchOptionsQuery(c *gin.Context, methods ...string) (options utils.FetchOptions, ok bool) {
+	if len(methods) == 0 {
+		methods = []string{http.MethodGet, http.MethodHead}
+	}
+	if method := strings.ToUpper(c.Query("method")); method != "" {
+		if !slices.Contains(methods, method) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "method must be one of " + strings.Join(methods, ", ")})
+			return options, false
+		}
+		options.Method = method
+	}
+	if value := c.Query("timeout"); value != "" {
+		seconds, err := strconv.Atoi(value)
+		if err != nil || seconds < 1 || seconds > maxFetchTimeoutSeconds {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("timeout must be between 1 and %d seconds", maxFetchTimeoutSeconds)})
+			return options, false
+		}
+		options.Timeout = time.Duration(seconds) * time.Second
+	}
+	if value := c.Query("max_size"); value != "" {
+		size, err := strconv.ParseInt(value, 10, 64)
+		if err != nil || size < 1 || size > maxFetchSize {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("max_size must be between 1 and %d bytes", maxFetchSize)})
+			return options, false
+		}
+		options.MaxBytes = size
+	}
+	return options, true
+}
+
+// CaptureHandler godoc
+// @Summary      Retrieve a stored capture
+// @Description  Returns a page fetch stored by one of the fetch-based analyses: request and final URL, status, response headers and the decoded body. Pass its ID as capture_id to those analyses to re-run them without re-fetching.
+// @Tags         Web Analysis
+// @Produce      json
+// @Param        id path string true "Capture ID"
+// @Param        canonical query bool false "Return RFC 8785 canonical JSON (sorted keys, no whitespace); the X-Canonical-JSON response header is false if that failed"
+// @Success      200 {object} models.CaptureResponse "Stored capture"
+// @Failure      404 {object} map[string]string "Error: Capture not found or expired"
+// @Router       /web/captures/{id} [get]
+func (h *WebAnalysisHandlers) CaptureHandler(c *gin.Context) {
+	capture, err := utils.LoadCapture(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, models.CaptureResponse{
+		CaptureID:  capture.ID,
+		RequestURL: capture.RequestURL,
+		FinalURL:   capture.FinalURL,
+		CapturedAt: capture.CapturedAt,
+		StatusCode: capture.StatusCode,
+		Status:     capture.Status,
+		Headers:    capture.Headers,
+		Body:       string(utils.DecodeResponseBody(capture.FetchResult())),
+	})
 }
@@ -1,48 +1,173 @@
 package main
 
 import (
+	"context"
 	"log"
 	"os"
 	"os/signal"
+	"path/filepath"
+	"strconv"
 	"syscall"
+	"time"
 
 	"github.com/joho/godotenv"
+	"github.com/vit0-9/utils_api/handlers"
 	"github.com/vit0-9/utils_api/pkg/utils"
+	"github.com/vit0-9/utils_api/pkg/utils/admin"
+	"github.com/vit0-9/utils_api/pkg/utils/cache"
+	"github.com/vit0-9/utils_api/pkg/utils/callback"
+	"github.com/vit0-9/utils_api/pkg/utils/domain"
+	"github.com/vit0-9/utils_api/pkg/utils/features"
+	"github.com/vit0-9/utils_api/pkg/utils/ingest"
+	"github.com/vit0-9/utils_api/pkg/utils/lifecycle"
+	"github.com/vit0-9/utils_api/pkg/utils/listener"
+	"github.com/vit0-9/utils_api/pkg/utils/notifications"
+	"github.com/vit0-9/utils_api/pkg/utils/portfolio"
+	"github.com/vit0-9/utils_api/pkg/utils/quota"
+	"github.com/vit0-9/utils_api/pkg/utils/redact"
+	"github.com/vit0-9/utils_api/pkg/utils/redis"
+	"github.com/vit0-9/utils_api/pkg/utils/requestlog"
+	"github.com/vit0-9/utils_api/pkg/utils/shortener"
+	"github.com/vit0-9/utils_api/pkg/utils/storage"
+	"github.com/vit0-9/utils_api/pkg/utils/vantage"
 )
 
+// defaultShutdownTimeout is how long a shutdown waits for requests and background work.
+const defaultShutdownTimeout = 30 * time.Second
+
 func main() {
 	err := godotenv.Load()
 	if err != nil {
 		log.Println("WARN: Error loading .env file, using environment variables from system if set.")
 	}
+	handlers.ConfigureGinMode(os.Getenv("GIN_MODE"))
+	handlers.ConfigureLogging(os.Getenv("ACCESS_LOG") != "false", os.Getenv("LOG_FORMAT"))
+	handlers.ConfigureBasePath(os.Getenv("API_BASE_PATH"), os.Getenv("EXTERNAL_URL"))
+	handlers.ConfigureSwagger(os.Getenv("SWAGGER_ENABLED"), os.Getenv("SWAGGER_PATH"), os.Getenv("SWAGGER_BASIC_AUTH"), os.Getenv("SWAGGER_API_KEYS"))
 	cityDBPath := os.Getenv("MMDB_CITY_PATH")
 	asnDBPath := os.Getenv("MMDB_ASN_PATH")
 
+	mmdbStaleDays, _ := strconv.Atoi(os.Getenv("MMDB_STALE_DAYS"))
+	utils.ConfigureGeoIPStaleness(mmdbStaleDays) // Before loading, which warns about stale databases
 	utils.LoadMaxMindDBs(cityDBPath, asnDBPath)
+	lifecycle.Register("GeoIP databases", func(context.Context) error {
+		utils.CloseMaxMindDBs()
+		return nil
+	})
+	maxmindUpdateHours, _ := strconv.Atoi(os.Getenv("MAXMIND_UPDATE_HOURS"))
+	utils.ConfigureMaxMindUpdates(os.Getenv("MAXMIND_LICENSE_KEY"), time.Duration(maxmindUpdateHours)*time.Hour)
+	features.Configure(os.Getenv("DISABLED_ENDPOINTS"))
+	requestlog.Configure(os.Getenv("REQUEST_LOG") == "true")
+	redact.Configure(os.Getenv("REDACT_ENDPOINTS"), os.Getenv("REDACT_QUERY_PARAMS"), os.Getenv("REDACT_EMAILS") != "false")
+	redisDB, _ := strconv.Atoi(os.Getenv("REDIS_DB"))
+	sharedRedis := utils.ConfigureSharedState(os.Getenv("REDIS_ADDR"), os.Getenv("REDIS_PASSWORD"), redisDB)
+	utils.ConfigureOfflineMode(os.Getenv("OFFLINE_MODE") == "true", os.Getenv("OFFLINE_CASSETTE_PATH"))
+	utils.LoadOutboundPolicy(os.Getenv("OUTBOUND_POLICY_PATH"))
+	hostRateLimit, _ := strconv.Atoi(os.Getenv("OUTBOUND_HOST_RATE_LIMIT"))
+	utils.ConfigureHostThrottle(hostRateLimit)
+	utils.ConfigureSSRFProtection(os.Getenv("SSRF_PROTECTION") != "false", os.Getenv("SSRF_ALLOW_CIDRS"))
+	outboundProxy := os.Getenv("OUTBOUND_PROXY")
+	if outboundProxy == "" {
+		outboundProxy = os.Getenv("HTTP_PROXY")
+	}
+	utils.ConfigureOutboundProxy(outboundProxy)
+	captureTTLHours, _ := strconv.Atoi(os.Getenv("CAPTURE_TTL_HOURS"))
+	captureMaxMB, _ := strconv.Atoi(os.Getenv("CAPTURE_STORE_MAX_MB"))
+	utils.ConfigureCaptureStore(os.Getenv("CAPTURE_STORE_DIR"), time.Duration(captureTTLHours)*time.Hour, int64(captureMaxMB)<<20)
+	domain.ConfigureSSLCABundle(os.Getenv("SSL_CA_BUNDLE_PATH"))
+	domain.LoadSSLClientProfiles(os.Getenv("SSL_CLIENT_PROFILES_PATH"))
+	whoisRateLimit, _ := strconv.Atoi(os.Getenv("WHOIS_SERVER_RATE_LIMIT"))
+	domain.ConfigureWhoisRateLimit(whoisRateLimit)
+	domain.ConfigureWhoisServerCache(os.Getenv("WHOIS_SERVER_CACHE_PATH"))
+	whoisMaxResponseKB, _ := strconv.Atoi(os.Getenv("WHOIS_MAX_RESPONSE_KB"))
+	domain.ConfigureWhoisMaxResponseSize(whoisMaxResponseKB)
+	utils.ConfigureASNLookup(os.Getenv("ASN_PREFIX_FILE"))
+	utils.ConfigurePassiveDNS(os.Getenv("PASSIVE_DNS_PROVIDER"), os.Getenv("PASSIVE_DNS_API_KEY"))
+	utils.ConfigurePassiveDNSStore(os.Getenv("PASSIVE_DNS_STORE_PATH"))
+	utils.ConfigureDNSBL(os.Getenv("DNSBL_IP_LISTS"), os.Getenv("DNSBL_DOMAIN_LISTS"))
+	cacheRedis := sharedRedis
+	if addr := os.Getenv("CACHE_REDIS_ADDR"); addr != "" {
+		cacheRedisDB, _ := strconv.Atoi(os.Getenv("CACHE_REDIS_DB"))
+		cacheRedis = redis.New(addr, os.Getenv("CACHE_REDIS_PASSWORD"), cacheRedisDB)
+	}
+	cacheMaxEntries, _ := strconv.Atoi(os.Getenv("CACHE_MAX_ENTRIES"))
+	cache.Configure(cacheRedis, cacheMaxEntries)
+	notifications.LoadConfig(os.Getenv("NOTIFICATIONS_CONFIG_PATH"))
+	storageBackend, storageDSN := os.Getenv("STORAGE_BACKEND"), os.Getenv("STORAGE_DSN")
+	if storageBackend == "" && os.Getenv("PORTFOLIO_PATH") != "" {
+		// Deployments that only set the older PORTFOLIO_PATH stay persistent, next to that file
+		storageBackend, storageDSN = storage.BackendFile, filepath.Dir(os.Getenv("PORTFOLIO_PATH"))
+	}
+	store := storage.Configure(storageBackend, storageDSN)
+	lifecycle.Register("storage", func(context.Context) error { return store.Close() })
+	portfolio.Configure(store, os.Getenv("PORTFOLIO_PATH"))
+	portfolio.ConfigureKnownIssuers(os.Getenv("PORTFOLIO_KNOWN_ISSUERS"))
+	shortener.Configure(store)
+	shortener.ConfigureSharedStore(sharedRedis)
+	callback.Configure(os.Getenv("CALLBACK_SIGNING_SECRET"))
+	ingest.Configure(os.Getenv("INGEST_API_KEYS"), os.Getenv("INGEST_ANALYSES"))
+	ingest.ConfigureSharedQueue(sharedRedis)
+	quota.LoadConfig(os.Getenv("QUOTA_CONFIG_PATH"))
+	quota.ConfigureSharedCounters(sharedRedis)
+	vantage.Configure(vantage.Config{
+		Name:       os.Getenv("VANTAGE_NAME"),
+		PrimaryURL: os.Getenv("VANTAGE_PRIMARY_URL"),
+		PublicURL:  os.Getenv("VANTAGE_PUBLIC_URL"),
+		Secret:     os.Getenv("VANTAGE_SECRET"),
+	})
+	utils.ConfigureVulnerabilityLookup(os.Getenv("VULN_DATASET_PATH"), os.Getenv("VULN_NVD_API_URL"), os.Getenv("VULN_NVD_API_KEY"))
+	wappalyzerUpdateHours, _ := strconv.Atoi(os.Getenv("WAPPALYZER_UPDATE_HOURS"))
+	utils.ConfigureWappalyzerUpdates(os.Getenv("WAPPALYZER_FINGERPRINTS_URL"), time.Duration(wappalyzerUpdateHours)*time.Hour, os.Getenv("WAPPALYZER_FINGERPRINTS_PATH"))
+	admin.Configure(os.Getenv("ADMIN_API_KEYS"))
+	utils.ConfigureOutboundProbe(os.Getenv("HEALTH_OUTBOUND_PROBE"))
+
+	port := os.Getenv("PORT")
+	if port == "" {
+		port = "8080"
+	}
+	socketMode := listener.DefaultSocketMode
+	if value := os.Getenv("UNIX_SOCKET_MODE"); value != "" {
+		mode, err := strconv.ParseUint(value, 8, 32)
+		if err != nil {
+			log.Fatalf("Invalid UNIX_SOCKET_MODE %q: expected octal permissions such as 660", value)
+		}
+		socketMode = os.FileMode(mode)
+	}
+	serverListener, err := listener.Listen(":"+port, os.Getenv("UNIX_SOCKET"), socketMode)
+	if err != nil {
+		log.Fatalf("Failed to listen: %v", err)
+	}
+
+	shutdownTimeout := defaultShutdownTimeout
+	if seconds, err := strconv.Atoi(os.Getenv("SHUTDOWN_TIMEOUT")); err == nil && seconds > 0 {
+		shutdownTimeout = time.Duration(seconds) * time.Second
+	}
 
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
 
-	go func() {
-		<-quit
-		log.Println("Shutting down server...")
-		utils.CloseMaxMindDBs() // Close both databases
-		os.Exit(0)
-	}()
-
 	app, err := NewApp()
 	if err != nil {
 		log.Fatalf("Failed to initialize application: %v", err)
 	}
 
-	port := os.Getenv("PORT")
-	if port == "" {
-		port = "8080"
-	}
-	addr := ":" + port
+	go func() {
+		if err := app.Start(serverListener); err != nil {
+			log.Fatalf("Failed to start server: %v", err)
+		}
+	}()
 
-	if err := app.Start(addr); err != nil {
-		log.Fatalf("Failed to start server: %v", err)
-		utils.CloseMaxMindDBs()
+	<-quit
+	log.Printf("Shutting down server (waiting up to %s for running work)...", shutdownTimeout)
+	ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	// Requests first, so no new jobs start, then the background subsystems, which checkpoint
+	// what they cannot finish
+	if err := app.Shutdown(ctx); err != nil {
+		log.Printf("WARN: Requests still running were cut off: %v", err)
+	}
+	if err := lifecycle.Shutdown(ctx); err != nil {
+		log.Printf("WARN: Shutdown did not complete cleanly: %v", err)
 	}
+	log.Println("Server stopped")
 }
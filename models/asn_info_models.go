@@ -0,0 +1,10 @@
+package models
+
+import "github.com/vit0-9/utils_api/pkg/utils"
+
+// ASNInfoResponse is the output of an ASN lookup.
+type ASNInfoResponse struct {
+	RequestASN string `json:"request_asn"`
+	*utils.ASNInfo
+	Error string `json:"error,omitempty"`
+}
@@ -0,0 +1,10 @@
+package models
+
+import "github.com/vit0-9/utils_api/pkg/utils/bgp"
+
+// BGPRouteResponse is the output of a BGP route / RPKI lookup.
+type BGPRouteResponse struct {
+	RequestResource string `json:"request_resource"`
+	*bgp.RouteInfo
+	Error string `json:"error,omitempty"`
+}
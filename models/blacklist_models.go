@@ -0,0 +1,11 @@
+package models
+
+import "github.com/vit0-9/utils_api/pkg/utils"
+
+// BlacklistCheckResponse is the output of a DNS blacklist (DNSBL) reputation check.
+type BlacklistCheckResponse struct {
+	RequestTarget string `json:"request_target"`
+	Resolver      string `json:"resolver,omitempty"`
+	*utils.BlacklistReport
+	Error string `json:"error,omitempty"`
+}
@@ -0,0 +1,11 @@
+package models
+
+import "github.com/vit0-9/utils_api/pkg/utils/caa"
+
+// CAACheckResponse is the effective CAA policy of a domain.
+type CAACheckResponse struct {
+	RequestDomain string `json:"request_domain"`
+	Resolver      string `json:"resolver,omitempty"`
+	*caa.Policy
+	Error string `json:"error,omitempty"`
+}
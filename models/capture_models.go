@@ -0,0 +1,15 @@
+package models
+
+import "time"
+
+// CaptureResponse is a stored page fetch.
+type CaptureResponse struct {
+	CaptureID  string              `json:"capture_id"`
+	RequestURL string              `json:"request_url"`
+	FinalURL   string              `json:"final_url"`
+	CapturedAt time.Time           `json:"captured_at"`
+	StatusCode int                 `json:"status_code"`
+	Status     string              `json:"status"`
+	Headers    map[string][]string `json:"headers"`
+	Body       string              `json:"body"` // Decoded response body
+}
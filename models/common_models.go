@@ -3,8 +3,9 @@ package models
 
 // APIErrorResponse represents a standard error response format.
 type APIErrorResponse struct {
-	StatusCode int    `json:"status_code"`       // HTTP status code
-	ErrorCode  string `json:"error_code"`        // Application-specific error code (optional)
-	Message    string `json:"message"`           // User-friendly error message
-	Details    string `json:"details,omitempty"` // More detailed information, if available
+	StatusCode int    `json:"status_code"`          // HTTP status code
+	ErrorCode  string `json:"error_code"`           // Application-specific error code (optional)
+	Message    string `json:"message"`              // User-friendly error message
+	Details    string `json:"details,omitempty"`    // More detailed information, if available
+	RequestID  string `json:"request_id,omitempty"` // Matches the server log entry of the failure
 }
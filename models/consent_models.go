@@ -0,0 +1,17 @@
+package models
+
+import "github.com/vit0-9/utils_api/pkg/utils"
+
+// ConsentCheckResponse is the output of the consent banner / CMP check.
+type ConsentCheckResponse struct {
+	RequestURL            string                  `json:"request_url"`
+	FinalURL              string                  `json:"final_url,omitempty"`
+	CaptureID             string                  `json:"capture_id,omitempty"`       // Pass as capture_id to re-run the analysis on the same response
+	DetectionMethod       string                  `json:"detection_method,omitempty"` // "static": only scripts present in the served HTML are inspected
+	CMPDetected           bool                    `json:"cmp_detected"`
+	Platforms             []utils.ConsentPlatform `json:"platforms"`
+	Trackers              []utils.TrackerScript   `json:"trackers"`
+	TrackersBeforeConsent int                     `json:"trackers_before_consent"` // Trackers not gated behind consent
+	Curl                  string                  `json:"curl,omitempty"`          // Equivalent curl command, only when include_curl=true
+	Error                 string                  `json:"error,omitempty"`
+}
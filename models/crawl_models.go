@@ -0,0 +1,9 @@
+package models
+
+// CrawlRequest starts a crawl of a site.
+type CrawlRequest struct {
+	URL         string `json:"url" binding:"required" example:"https://example.com/"`
+	MaxDepth    int    `json:"max_depth,omitempty" example:"2"`                                     // Links to follow from the start page, 1-5 (default 2)
+	MaxPages    int    `json:"max_pages,omitempty" example:"50"`                                    // Pages to fetch at most, 1-500 (default 50)
+	CallbackURL string `json:"callback_url,omitempty" example:"https://ci.example.com/hooks/crawl"` // Receives the completed crawl as a signed POST
+}
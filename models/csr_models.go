@@ -0,0 +1,25 @@
+package models
+
+import "github.com/vit0-9/utils_api/pkg/utils/domain"
+
+// CSRGenerateRequest describes the key pair and CSR to generate
+type CSRGenerateRequest struct {
+	KeyType        string            `json:"key_type,omitempty" example:"ecdsa"` // rsa (default) or ecdsa
+	KeySize        int               `json:"key_size,omitempty" example:"256"`   // RSA: 2048 (default), 3072 or 4096; ECDSA: 256 (default), 384 or 521
+	Subject        domain.CSRSubject `json:"subject"`
+	DNSNames       []string          `json:"dns_names,omitempty" example:"www.example.com,example.com"`
+	IPAddresses    []string          `json:"ip_addresses,omitempty"`
+	EmailAddresses []string          `json:"email_addresses,omitempty"`
+}
+
+// CSRGenerateResponse holds the generated private key and CSR
+type CSRGenerateResponse struct {
+	PrivateKeyPEM string          `json:"private_key_pem"` // PKCS #8; not stored by the API
+	CSRPEM        string          `json:"csr_pem"`
+	CSR           *domain.CSRInfo `json:"csr"`
+}
+
+// CSRDecodeResponse represents the analysis of a CSR
+type CSRDecodeResponse struct {
+	CSR *domain.CSRInfo `json:"csr"`
+}
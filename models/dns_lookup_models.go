@@ -10,7 +10,8 @@ type DNSLookupRequest struct {
 
 // DNSLookupResponse is the output of a DNS lookup.
 type DNSLookupResponse struct {
-	Domain  string                       `json:"domain"`
-	Records map[string][]utils.DNSRecord `json:"records"`          // Keyed by record type
-	Errors  map[string]string            `json:"errors,omitempty"` // Errors for specific record type lookups
+	Domain   string                       `json:"domain"`
+	Resolver string                       `json:"resolver"`         // "system", the server address, or the DoH URL queried
+	Records  map[string][]utils.DNSRecord `json:"records"`          // Keyed by record type
+	Errors   map[string]string            `json:"errors,omitempty"` // Errors for specific record type lookups
 }
@@ -0,0 +1,11 @@
+package models
+
+import "github.com/vit0-9/utils_api/pkg/utils/dnssec"
+
+// DNSSECCheckResponse is the output of a DNSSEC chain of trust check.
+type DNSSECCheckResponse struct {
+	RequestDomain string `json:"request_domain"`
+	Resolver      string `json:"resolver,omitempty"`
+	*dnssec.Report
+	Error string `json:"error,omitempty"`
+}
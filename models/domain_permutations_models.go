@@ -0,0 +1,16 @@
+package models
+
+import "github.com/vit0-9/utils_api/pkg/utils/typosquat"
+
+// DomainPermutationsResponse is a page of the typosquat permutations of a domain.
+type DomainPermutationsResponse struct {
+	RequestDomain string `json:"request_domain"`
+	Resolver      string `json:"resolver,omitempty"` // Set when the permutations were checked
+	Total         int    `json:"total"`              // Permutations across all pages
+	Offset        int    `json:"offset"`
+	Limit         int    `json:"limit"`
+	NextOffset    *int   `json:"next_offset,omitempty"` // Offset of the next page, unless this is the last
+	// Registered permutations on this page, when they were checked
+	RegisteredCount int                     `json:"registered_count"`
+	Permutations    []typosquat.Permutation `json:"permutations"`
+}
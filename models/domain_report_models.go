@@ -0,0 +1,12 @@
+package models
+
+import "github.com/vit0-9/utils_api/pkg/utils/domainreport"
+
+// DomainReportResponse is the aggregate report on a domain. Each section reports its own
+// status, so a failed check does not fail the report.
+type DomainReportResponse struct {
+	RequestDomain string `json:"request_domain"`
+	Resolver      string `json:"resolver,omitempty"`
+	*domainreport.Report
+	Error string `json:"error,omitempty"`
+}
@@ -0,0 +1,11 @@
+package models
+
+import "github.com/vit0-9/utils_api/pkg/utils/emailauth"
+
+// EmailSecurityResponse is the output of an email authentication (SPF/DMARC/DKIM) check.
+type EmailSecurityResponse struct {
+	RequestDomain string `json:"request_domain"`
+	Resolver      string `json:"resolver,omitempty"`
+	*emailauth.Report
+	Error string `json:"error,omitempty"`
+}
@@ -0,0 +1,16 @@
+package models
+
+// FaviconHashResponse is a site's favicon and the hashes threat-intel tools index it by.
+type FaviconHashResponse struct {
+	RequestURL  string `json:"request_url,omitempty"`
+	FaviconURL  string `json:"favicon_url,omitempty"`
+	Source      string `json:"source,omitempty"` // given, declared (a <link rel="icon">) or default (/favicon.ico)
+	ContentType string `json:"content_type,omitempty"`
+	Size        int    `json:"size"`
+	MMH3        int32  `json:"mmh3"` // Shodan's http.favicon.hash
+	MD5         string `json:"md5,omitempty"`
+	SHA256      string `json:"sha256,omitempty"`
+	ShodanQuery string `json:"shodan_query,omitempty" example:"http.favicon.hash:-1234567890"`
+	Curl        string `json:"curl,omitempty"` // Equivalent curl command for the favicon, only when include_curl=true
+	Error       string `json:"error,omitempty"`
+}
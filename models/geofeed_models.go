@@ -0,0 +1,10 @@
+package models
+
+import "github.com/vit0-9/utils_api/pkg/utils"
+
+// GeofeedCheckResponse is the output of an RFC 8805 geofeed validation.
+type GeofeedCheckResponse struct {
+	RequestURL string `json:"request_url"`
+	*utils.GeofeedReport
+	Error string `json:"error,omitempty"`
+}
@@ -0,0 +1,8 @@
+package models
+
+import "github.com/vit0-9/utils_api/pkg/utils"
+
+// HARResponse is an HTTP Archive (HAR 1.2) document, loadable in browser devtools.
+type HARResponse struct {
+	Log utils.HARLog `json:"log"`
+}
@@ -0,0 +1,66 @@
+package models
+
+import (
+	"time"
+
+	"github.com/vit0-9/utils_api/pkg/utils"
+)
+
+// Overall verdicts of the detailed health check
+const (
+	HealthHealthy  = "healthy"
+	HealthWarn     = "warn"     // Every dependency works, but one needs attention
+	HealthDegraded = "degraded" // A configured dependency is failing
+)
+
+// Statuses of a dependency in the detailed health check
+const (
+	DependencyOK       = "ok"
+	DependencyWarn     = "warn" // Works, but needs attention, e.g. stale GeoIP databases
+	DependencyFailed   = "failed"
+	DependencyDisabled = "disabled" // Not configured, so it does not affect the verdict
+	DependencySkipped  = "skipped"  // Not checked, e.g. outbound access in offline mode
+)
+
+// DetailedHealthResponse reports the state of the API's dependencies
+type DetailedHealthResponse struct {
+	Status     string           `json:"status" example:"healthy"` // healthy, warn when a dependency needs attention, or degraded when one failed
+	Build      utils.BuildInfo  `json:"build"`
+	Offline    bool             `json:"offline"`
+	GeoIP      GeoIPHealth      `json:"geoip"`
+	Wappalyzer WappalyzerHealth `json:"wappalyzer"`
+	Cache      CacheHealth      `json:"cache"`
+	Outbound   OutboundHealth   `json:"outbound"`
+	Panics     int64            `json:"recovered_panics"` // Requests that panicked since the server started
+	CheckedAt  time.Time        `json:"checked_at"`
+}
+
+// GeoIPHealth reports the MaxMind databases, failed when a configured one is not loaded and
+// warn when one is stale
+type GeoIPHealth struct {
+	Status    string                      `json:"status" example:"ok"`
+	Databases []utils.GeoIPDatabaseStatus `json:"databases"`
+}
+
+// WappalyzerHealth reports whether stack analysis can run and the fingerprints it uses
+type WappalyzerHealth struct {
+	Status       string                 `json:"status" example:"ok"`
+	Fingerprints utils.WappalyzerStatus `json:"fingerprints"`
+	Error        string                 `json:"error,omitempty"`
+}
+
+// CacheHealth reports whether the result cache backend answers
+type CacheHealth struct {
+	Status    string  `json:"status" example:"ok"`
+	Backend   string  `json:"backend" example:"memory"` // memory or redis
+	LatencyMs float64 `json:"latency_ms"`
+	Error     string  `json:"error,omitempty"`
+}
+
+// OutboundHealth reports whether the internet can be reached
+type OutboundHealth struct {
+	Status    string  `json:"status" example:"ok"`
+	Target    string  `json:"target,omitempty" example:"1.1.1.1:443"` // The probe target that answered, or the last one tried
+	LatencyMs float64 `json:"latency_ms,omitempty"`
+	Error     string  `json:"error,omitempty"`
+}
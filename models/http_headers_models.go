@@ -1,5 +1,7 @@
 package models
 
+import "github.com/vit0-9/utils_api/pkg/utils"
+
 // HTTPHeadersRequest defines the input for fetching HTTP headers.
 type HTTPHeadersRequest struct {
 	URL    string `json:"url" binding:"required,url"`
@@ -10,8 +12,12 @@ type HTTPHeadersRequest struct {
 type HTTPHeadersResponse struct {
 	RequestURL string              `json:"request_url"`
 	FinalURL   string              `json:"final_url,omitempty"`
+	CaptureID  string              `json:"capture_id,omitempty"` // Pass as capture_id to re-run the analysis on the same response
+	Method     string              `json:"method,omitempty"`     // Method of the request whose response is shown
 	StatusCode int                 `json:"status_code,omitempty"`
 	Status     string              `json:"status,omitempty"`
 	Headers    map[string][]string `json:"headers,omitempty"`
+	Body       *utils.BodyInfo     `json:"body,omitempty"` // Only with body_preview or hashes
+	Curl       string              `json:"curl,omitempty"` // Equivalent curl command, only when include_curl=true
 	Error      string              `json:"error,omitempty"`
 }
@@ -0,0 +1,9 @@
+package models
+
+// IngestRequest submits URLs, domains and IP addresses for analysis.
+type IngestRequest struct {
+	CorrelationID string   `json:"correlation_id,omitempty" example:"soar-case-4711"`                                  // Echoed on the batch and its job.completed notification
+	Items         []string `json:"items" binding:"required" example:"https://example.com/login,example.org,192.0.2.1"` // Kind is detected per item
+	Analyses      []string `json:"analyses,omitempty" example:"dns,whois,blacklist"`                                   // Defaults to INGEST_ANALYSES, or all analyses
+	CallbackURL   string   `json:"callback_url,omitempty" example:"https://soar.example.com/hooks/ingest"`             // Receives the completed batch as a signed POST
+}
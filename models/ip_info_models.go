@@ -30,3 +30,9 @@ type IPInfoResponse struct {
 	ASOrganization string  `json:"as_organization,omitempty"`
 	GeoError       string  `json:"geo_error,omitempty"` // Errors specific to GeoIP lookup
 }
+
+// BulkIPInfoResponse is the output for a bulk IP information lookup.
+type BulkIPInfoResponse struct {
+	Count   int              `json:"count"`
+	Results []IPInfoResponse `json:"results"` // Same order as the request
+}
@@ -0,0 +1,48 @@
+package models
+
+import "github.com/vit0-9/utils_api/pkg/utils"
+
+// MetaExtractResponse is the metadata a page declares in its markup.
+type MetaExtractResponse struct {
+	RequestURL   string               `json:"request_url"`
+	FinalURL     string               `json:"final_url,omitempty"`
+	CaptureID    string               `json:"capture_id,omitempty"` // Pass as capture_id to re-run the analysis on the same response
+	Title        string               `json:"title"`
+	Description  string               `json:"description"`
+	CanonicalURL string               `json:"canonical_url,omitempty"`
+	OpenGraph    []utils.MetaTag      `json:"open_graph"`
+	TwitterCard  []utils.MetaTag      `json:"twitter_card"`
+	Favicons     []utils.Favicon      `json:"favicons"`
+	Hreflang     []utils.HreflangLink `json:"hreflang"`
+	JSONLD       []any                `json:"json_ld"`                  // Parsed application/ld+json blocks
+	JSONLDErrors []string             `json:"json_ld_errors,omitempty"` // Blocks that are not valid JSON
+	Curl         string               `json:"curl,omitempty"`           // Equivalent curl command, only when include_curl=true
+	Error        string               `json:"error,omitempty"`
+}
+
+// MixedContentResponse lists the insecure resources an HTTPS page references.
+type MixedContentResponse struct {
+	RequestURL    string                       `json:"request_url"`
+	FinalURL      string                       `json:"final_url,omitempty"`
+	CaptureID     string                       `json:"capture_id,omitempty"` // Pass as capture_id to re-run the analysis on the same response
+	PageIsHTTPS   bool                         `json:"page_is_https"`        // On an http page every resource is insecure anyway
+	ActiveCount   int                          `json:"active_count"`         // Blocked by browsers
+	PassiveCount  int                          `json:"passive_count"`        // Loaded with a warning
+	InsecureForms int                          `json:"insecure_forms"`
+	Resources     []utils.MixedContentResource `json:"resources"`
+	Curl          string                       `json:"curl,omitempty"` // Equivalent curl command, only when include_curl=true
+	Error         string                       `json:"error,omitempty"`
+}
+
+// ContentSimilarityResponse compares a page with a baseline version.
+type ContentSimilarityResponse struct {
+	RequestURL         string                   `json:"request_url"`
+	FinalURL           string                   `json:"final_url,omitempty"`
+	CaptureID          string                   `json:"capture_id,omitempty"` // Pass as baseline_capture_id to compare later versions against this one
+	StatusCode         int                      `json:"status_code,omitempty"`
+	BaselineURL        string                   `json:"baseline_url"`
+	BaselineCaptureID  string                   `json:"baseline_capture_id,omitempty"`
+	BaselineStatusCode int                      `json:"baseline_status_code,omitempty"`
+	Similarity         *utils.ContentSimilarity `json:"similarity,omitempty"`
+	Error              string                   `json:"error,omitempty"`
+}
@@ -0,0 +1,11 @@
+package models
+
+import "github.com/vit0-9/utils_api/pkg/utils"
+
+// PassiveDNSResponse lists the local passive DNS history of a name or IP.
+type PassiveDNSResponse struct {
+	Name         string                        `json:"name,omitempty"`
+	IP           string                        `json:"ip,omitempty"`
+	Count        int                           `json:"count"`
+	Observations []utils.PassiveDNSObservation `json:"observations"`
+}
@@ -0,0 +1,10 @@
+package models
+
+import "github.com/vit0-9/utils_api/pkg/utils"
+
+// PingResponse is the output of a ping run.
+type PingResponse struct {
+	RequestHost string `json:"request_host"`
+	*utils.PingResult
+	Error string `json:"error,omitempty"`
+}
@@ -0,0 +1,33 @@
+package models
+
+import "github.com/vit0-9/utils_api/pkg/utils/portfolio"
+
+// PortfolioDomainRequest adds a domain to the portfolio or replaces its tags.
+type PortfolioDomainRequest struct {
+	Domain string   `json:"domain" binding:"required" example:"example.com"`
+	Tags   []string `json:"tags" example:"production,eu"`
+}
+
+// PortfolioListResponse lists the domains in the portfolio.
+type PortfolioListResponse struct {
+	Count   int                `json:"count"`
+	Domains []portfolio.Domain `json:"domains"`
+}
+
+// PortfolioJobRequest starts an operation across the portfolio.
+type PortfolioJobRequest struct {
+	Operation   string `json:"operation" binding:"required" example:"ssl-check"`                        // ssl-check, whois-expiry, dns-snapshot or ct-scan
+	Tag         string `json:"tag,omitempty" example:"production"`                                      // Only run on domains with this tag
+	CallbackURL string `json:"callback_url,omitempty" example:"https://ci.example.com/hooks/portfolio"` // Receives the completed job as a signed POST
+}
+
+// PortfolioJobListResponse lists recent portfolio jobs without their per-domain results.
+type PortfolioJobListResponse struct {
+	Jobs []portfolio.Job `json:"jobs"`
+}
+
+// PortfolioCertificatesResponse lists the certificate inventory of the portfolio.
+type PortfolioCertificatesResponse struct {
+	Count        int                     `json:"count"`
+	Certificates []portfolio.Certificate `json:"certificates"`
+}
@@ -0,0 +1,10 @@
+package models
+
+import "github.com/vit0-9/utils_api/pkg/utils/domain"
+
+// RDAPLookupResponse represents the response from an RDAP lookup
+type RDAPLookupResponse struct {
+	RequestDomain string `json:"request_domain"`
+	*domain.RDAPInfo
+	Error string `json:"error,omitempty"`
+}
@@ -1,5 +1,7 @@
 package models
 
+import "github.com/vit0-9/utils_api/pkg/utils"
+
 // ResolveRedirectRequest defines the expected JSON input
 type ResolveRedirectRequest struct {
 	URL string `json:"url" binding:"required,url"`
@@ -9,5 +11,15 @@ type ResolveRedirectRequest struct {
 type ResolveRedirectResponse struct {
 	OriginalURL SafeURLString `json:"original_url"`
 	FinalURL    SafeURLString `json:"final_url,omitempty"`
+	Curl        string        `json:"curl,omitempty"` // Equivalent curl command, only when include_curl=true
 	Error       string        `json:"error,omitempty"`
 }
+
+// RedirectTraceResponse defines the JSON output of a traced redirect resolution
+type RedirectTraceResponse struct {
+	OriginalURL SafeURLString        `json:"original_url"`
+	FinalURL    SafeURLString        `json:"final_url,omitempty"`
+	Trace       *utils.RedirectTrace `json:"trace"`
+	Curl        string               `json:"curl,omitempty"` // Equivalent curl command, only when include_curl=true
+	Error       string               `json:"error,omitempty"`
+}
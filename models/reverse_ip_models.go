@@ -0,0 +1,10 @@
+package models
+
+import "github.com/vit0-9/utils_api/pkg/utils"
+
+// ReverseIPResponse is the output of a reverse IP (shared hosting) lookup.
+type ReverseIPResponse struct {
+	RequestIP string `json:"request_ip"`
+	*utils.ReverseIPResult
+	Error string `json:"error,omitempty"`
+}
@@ -0,0 +1,16 @@
+package models
+
+import "github.com/vit0-9/utils_api/pkg/utils"
+
+// SocialLinksResponse is the output of the social link and contact extractor.
+type SocialLinksResponse struct {
+	RequestURL     string                `json:"request_url"`
+	FinalURL       string                `json:"final_url,omitempty"`
+	CaptureID      string                `json:"capture_id,omitempty"` // Pass as capture_id to re-run the analysis on the same response
+	SocialProfiles []utils.SocialProfile `json:"social_profiles"`
+	Emails         []string              `json:"emails"`
+	Phones         []string              `json:"phones"`
+	Contacts       []utils.ContactDetail `json:"contacts"`       // Validated contacts with the element they were found in and any obfuscation decoded
+	Curl           string                `json:"curl,omitempty"` // Equivalent curl command, only when include_curl=true
+	Error          string                `json:"error,omitempty"`
+}
@@ -1,36 +1,69 @@
 package models
 
-import "time"
+import (
+	"time"
+
+	"github.com/vit0-9/utils_api/pkg/utils/domain"
+)
 
 // SSLCheckRequest represents the request for SSL certificate check
 type SSLCheckRequest struct {
-	Domain string `json:"domain" binding:"required" example:"example.com"`
-	Port   int    `json:"port,omitempty" example:"443"`
+	Domain          string `json:"domain" binding:"required" example:"example.com"`
+	Port            int    `json:"port,omitempty" example:"443"`
+	Revocation      bool   `json:"revocation,omitempty"`
+	AllIPs          bool   `json:"all_ips,omitempty"`
+	Protocols       bool   `json:"protocols,omitempty"`
+	Session         bool   `json:"session,omitempty"`
+	VerifyHost      string `json:"verify_host,omitempty" example:"www.example.com"` // Another hostname to verify against the certificate
+	ExpiryThreshold string `json:"expiry_threshold,omitempty" example:"72h"`        // Sets expires_within: a duration such as 72h or a number of seconds
+	ClientProfile   string `json:"client_profile,omitempty" example:"partner-api"`  // A preconfigured client certificate
+	ClientCertPEM   string `json:"client_cert_pem,omitempty"`                       // PEM client certificate, with any intermediates after it
+	ClientKeyPEM    string `json:"client_key_pem,omitempty"`                        // PEM private key of the client certificate
 }
 
 // SSLCheckResponse represents the response from SSL certificate check
 type SSLCheckResponse struct {
-	Domain             string            `json:"domain"`
-	IsValid            bool              `json:"is_valid"`
-	Issuer             string            `json:"issuer"`
-	Subject            string            `json:"subject"`
-	SerialNumber       string            `json:"serial_number"`
-	NotBefore          time.Time         `json:"not_before"`
-	NotAfter           time.Time         `json:"not_after"`
-	DaysUntilExpiry    int               `json:"days_until_expiry"`
-	SubjectAltNames    []string          `json:"subject_alt_names"`
-	SignatureAlgorithm string            `json:"signature_algorithm"`
-	PublicKeyAlgorithm string            `json:"public_key_algorithm"`
-	KeySize            int               `json:"key_size"`
-	Version            int               `json:"version"`
-	IsSelfSigned       bool              `json:"is_self_signed"`
-	IsWildcard         bool              `json:"is_wildcard"`
-	CertificateChain   []CertificateInfo `json:"certificate_chain"`
-	TLSVersion         string            `json:"tls_version"`
-	CipherSuite        string            `json:"cipher_suite"`
-	ValidationErrors   []string          `json:"validation_errors,omitempty"`
-	QueryTime          time.Time         `json:"query_time"`
-	Error              string            `json:"error,omitempty"`
+	Domain             string                   `json:"domain"`
+	IsValid            bool                     `json:"is_valid"`
+	Issuer             string                   `json:"issuer"`
+	Subject            string                   `json:"subject"`
+	SerialNumber       string                   `json:"serial_number"`
+	FingerprintSHA256  string                   `json:"fingerprint_sha256"`
+	PublicKeyPin       string                   `json:"public_key_pin_sha256"`
+	NotBefore          time.Time                `json:"not_before"`
+	NotAfter           time.Time                `json:"not_after"`
+	DaysUntilExpiry    int                      `json:"days_until_expiry"`
+	ExpiresInSeconds   int64                    `json:"expires_in_seconds"`       // Measured at query_time; negative once expired
+	NotAfterUTC        string                   `json:"not_after_utc,omitempty"`  // not_after in RFC 3339, UTC
+	ExpiresWithin      *bool                    `json:"expires_within,omitempty"` // Expires within the expiry_threshold given, or has expired
+	SubjectAltNames    []string                 `json:"subject_alt_names"`
+	SignatureAlgorithm string                   `json:"signature_algorithm"`
+	PublicKeyAlgorithm string                   `json:"public_key_algorithm"`
+	KeySize            int                      `json:"key_size"`
+	Version            int                      `json:"version"`
+	IsSelfSigned       bool                     `json:"is_self_signed"`
+	IsWildcard         bool                     `json:"is_wildcard"`
+	Hostname           *domain.HostnameCheck    `json:"hostname,omitempty"`
+	AlternateHostname  *domain.HostnameCheck    `json:"alternate_hostname,omitempty"`
+	CertificateChain   []CertificateInfo        `json:"certificate_chain"`
+	TLSVersion         string                   `json:"tls_version"`
+	CipherSuite        string                   `json:"cipher_suite"`
+	ValidationErrors   []string                 `json:"validation_errors,omitempty"`
+	Grade              string                   `json:"grade,omitempty"`
+	Findings           []domain.SSLFinding      `json:"findings,omitempty"`
+	ChainTrusted       bool                     `json:"chain_trusted"`
+	VerificationError  string                   `json:"verification_error,omitempty"`
+	TrustIssue         string                   `json:"trust_issue,omitempty"`
+	UntrustedRoot      string                   `json:"untrusted_root,omitempty"`
+	VerifiedChain      []CertificateInfo        `json:"verified_chain,omitempty"`
+	Revocation         *domain.RevocationInfo   `json:"revocation,omitempty"`
+	ClientAuth         *domain.ClientAuthInfo   `json:"client_auth,omitempty"`
+	Protocols          []domain.ProtocolSupport `json:"protocols,omitempty"`
+	Session            *domain.SessionInfo      `json:"session,omitempty"`
+	Endpoints          []domain.SSLEndpoint     `json:"endpoints,omitempty"`
+	EndpointMismatches []string                 `json:"endpoint_mismatches,omitempty"`
+	QueryTime          time.Time                `json:"query_time"`
+	Error              string                   `json:"error,omitempty"`
 }
 
 // CertificateInfo represents information about a certificate in the chain
@@ -42,3 +75,28 @@ type CertificateInfo struct {
 	IsCA      bool      `json:"is_ca"`
 	KeyUsage  []string  `json:"key_usage"`
 }
+
+// SSLCompareResponse represents the response from comparing the certificates of two targets
+type SSLCompareResponse struct {
+	Comparison *domain.SSLComparison `json:"comparison"`
+}
+
+// SSLBatchRequest represents the request for a batch SSL check
+type SSLBatchRequest struct {
+	Targets        []string `json:"targets" binding:"required" example:"example.com,example.org:8443"` // host or host:port, up to 500
+	TimeoutSeconds int      `json:"timeout_seconds,omitempty" example:"15"`                            // Per target, defaults to 15 and at most 60
+}
+
+// SSLBatchResponse represents the response from a batch SSL check
+type SSLBatchResponse struct {
+	Summary domain.SSLBatchSummary  `json:"summary"`
+	Results []domain.SSLBatchResult `json:"results"` // Same order as the request
+}
+
+// CertDecodeResponse represents the analysis of an uploaded certificate or CSR
+type CertDecodeResponse struct {
+	Type        string                     `json:"type" example:"certificate"` // certificate or csr
+	Certificate *SSLCheckResponse          `json:"certificate,omitempty"`      // The same analysis as an SSL check, without the connection details
+	Details     *domain.CertificateDetails `json:"details,omitempty"`          // Extensions and other fields of the leaf certificate
+	CSR         *domain.CSRInfo            `json:"csr,omitempty"`
+}
@@ -1,5 +1,7 @@
 package models
 
+import "github.com/vit0-9/utils_api/pkg/utils"
+
 // StackAnalyzerRequest remains the same
 type StackAnalyzerRequest struct {
 	URL string `json:"url" binding:"required,url"`
@@ -14,12 +16,21 @@ type DetectedTechnology struct {
 	Website     string   `json:"website,omitempty"`     // Provided by AppInfo
 	Icon        string   `json:"icon,omitempty"`        // Provided by AppInfo
 	CPE         string   `json:"cpe,omitempty"`         // Provided by AppInfo
+
+	Confidence      int                         `json:"confidence"`                // 0-100, based on how much evidence matched
+	EvidenceSources []string                    `json:"evidence_sources"`          // header, cookie, html, script, meta or implied
+	MatchedPatterns int                         `json:"matched_patterns"`          // Number of fingerprint patterns that matched
+	Evidence        []utils.TechEvidence        `json:"evidence,omitempty"`        // Only when evidence=true
+	Vulnerabilities *utils.VulnerabilitySummary `json:"vulnerabilities,omitempty"` // Only when vulnerability hints are requested
 }
 
 // StackAnalyzerResponse remains the same structure but will be populated from the new util output.
 type StackAnalyzerResponse struct {
-	RequestURL   string               `json:"request_url"`
-	FinalURL     string               `json:"final_url"`
-	Technologies []DetectedTechnology `json:"technologies"`
-	Error        string               `json:"error,omitempty"`
+	RequestURL    string               `json:"request_url"`
+	FinalURL      string               `json:"final_url"`
+	CaptureID     string               `json:"capture_id,omitempty"` // Pass as capture_id to re-run the analysis on the same response
+	Technologies  []DetectedTechnology `json:"technologies"`
+	BodyTruncated bool                 `json:"body_truncated,omitempty"` // Only the first max_size bytes of the page were analyzed
+	Curl          string               `json:"curl,omitempty"`           // Equivalent curl command, only when include_curl=true
+	Error         string               `json:"error,omitempty"`
 }
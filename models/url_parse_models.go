@@ -0,0 +1,9 @@
+package models
+
+import "github.com/vit0-9/utils_api/pkg/utils"
+
+// URLParseResponse is a URL decomposed by the URL inspector.
+type URLParseResponse struct {
+	URL SafeURLString `json:"url"` // The password of embedded credentials is masked
+	utils.URLInspection
+}
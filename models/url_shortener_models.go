@@ -0,0 +1,17 @@
+package models
+
+import "github.com/vit0-9/utils_api/pkg/utils/shortener"
+
+// ShortenURLRequest creates a short link.
+type ShortenURLRequest struct {
+	URL              string `json:"url" binding:"required" example:"https://example.com/documentation"`
+	Slug             string `json:"slug,omitempty" example:"docs"`                // Generated when empty
+	ExpiresInSeconds int64  `json:"expires_in_seconds,omitempty" example:"86400"` // No expiry when 0, at most a year
+	MaxClicks        int64  `json:"max_clicks,omitempty" example:"100"`           // No limit when 0
+}
+
+// ShortURLResponse is a short link with the URL that redirects to its target.
+type ShortURLResponse struct {
+	ShortURL string `json:"short_url" example:"https://example.com/r/docs"`
+	shortener.Link
+}
@@ -0,0 +1,21 @@
+package models
+
+import (
+	"time"
+
+	"github.com/vit0-9/utils_api/pkg/utils/quota"
+)
+
+// UsageResponse is the caller's quota usage in the current UTC day and month.
+type UsageResponse struct {
+	Caller           string               `json:"caller" example:"key:team-a"` // "key:<name>" for configured API keys, "ip:<address>" otherwise
+	Daily            int64                `json:"daily" example:"120"`
+	DailyLimit       int64                `json:"daily_limit" example:"1000"`    // 0 is unlimited
+	DailyRemaining   int64                `json:"daily_remaining" example:"880"` // -1 when unlimited
+	DailyReset       time.Time            `json:"daily_reset"`                   // Start of the next UTC day
+	Monthly          int64                `json:"monthly" example:"2400"`
+	MonthlyLimit     int64                `json:"monthly_limit" example:"20000"`     // 0 is unlimited
+	MonthlyRemaining int64                `json:"monthly_remaining" example:"17600"` // -1 when unlimited
+	MonthlyReset     time.Time            `json:"monthly_reset"`                     // Start of the next UTC month
+	Costs            []quota.EndpointCost `json:"costs"`                             // Configured endpoint costs; any other endpoint costs 1
+}
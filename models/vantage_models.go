@@ -0,0 +1,22 @@
+package models
+
+import "github.com/vit0-9/utils_api/pkg/utils/vantage"
+
+// VantageRegistrationRequest is sent by an agent to register with the primary.
+type VantageRegistrationRequest struct {
+	Name string `json:"name" binding:"required" example:"eu"`
+	URL  string `json:"url" binding:"required" example:"https://eu.utils.example.com"`
+}
+
+// VantageListResponse lists this instance and the agents registered with it.
+type VantageListResponse struct {
+	Self   string          `json:"self"`
+	Agents []vantage.Agent `json:"agents"`
+}
+
+// VantageComparisonResponse holds one check run from several vantage points.
+type VantageComparisonResponse struct {
+	Path      string           `json:"path"`
+	Vantages  []vantage.Result `json:"vantages"`  // In the order requested
+	Identical bool             `json:"identical"` // At least two vantage points answered with the same canonical JSON
+}
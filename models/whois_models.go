@@ -1,7 +1,11 @@
 // File: models/domain.go
 package models
 
-import "time"
+import (
+	"time"
+
+	"github.com/vit0-9/utils_api/pkg/utils/domain"
+)
 
 // WhoisLookupRequest represents the request for WHOIS lookup
 type WhoisLookupRequest struct {
@@ -10,18 +14,32 @@ type WhoisLookupRequest struct {
 
 // WhoisLookupResponse represents the response from WHOIS lookup
 type WhoisLookupResponse struct {
-	Domain          string    `json:"domain"`
-	Registrar       string    `json:"registrar"`
-	CreationDate    time.Time `json:"creation_date"`
-	ExpirationDate  time.Time `json:"expiration_date"`
-	UpdatedDate     time.Time `json:"updated_date"`
-	NameServers     []string  `json:"name_servers"`
-	Status          []string  `json:"status"`
-	RegistrantOrg   string    `json:"registrant_org,omitempty"`
-	RegistrantEmail string    `json:"registrant_email,omitempty"`
-	AdminEmail      string    `json:"admin_email,omitempty"`
-	TechEmail       string    `json:"tech_email,omitempty"`
-	WhoisServer     string    `json:"whois_server"`
-	QueryTime       time.Time `json:"query_time"`
-	Error           string    `json:"error,omitempty"`
+	Domain         string    `json:"domain"`
+	Registrar      string    `json:"registrar"`
+	CreationDate   time.Time `json:"creation_date"`
+	ExpirationDate time.Time `json:"expiration_date"`
+	UpdatedDate    time.Time `json:"updated_date"`
+	// The date values as the server wrote them, by field; a zero date with a raw value could not be parsed
+	RawDates        map[string]string `json:"raw_dates,omitempty"`
+	NameServers     []string          `json:"name_servers"`
+	Status          []string          `json:"status"`
+	RegistrantOrg   string            `json:"registrant_org,omitempty"`
+	RegistrantEmail string            `json:"registrant_email,omitempty"`
+	AdminEmail      string            `json:"admin_email,omitempty"`
+	TechEmail       string            `json:"tech_email,omitempty"`
+	WhoisServer     string            `json:"whois_server"`
+	// Set when a thin registry referred the lookup to the registrar's WHOIS server
+	RegistrarWhoisServer string    `json:"registrar_whois_server,omitempty"`
+	ReferralError        string    `json:"referral_error,omitempty"`
+	QueryTime            time.Time `json:"query_time"`
+	Error                string    `json:"error,omitempty"`
+	ErrorCode            string    `json:"error_code,omitempty" example:"throttled"` // "throttled" when the WHOIS server refused the query for now
+	RetryAfterSeconds    int       `json:"retry_after_seconds,omitempty"`            // Set for throttled lookups, also in the Retry-After header
+}
+
+// BulkWhoisLookupResponse is the output for a bulk WHOIS expiry lookup.
+type BulkWhoisLookupResponse struct {
+	Count   int                  `json:"count"`
+	Failed  int                  `json:"failed"`
+	Results []domain.WhoisExpiry `json:"results"` // Same order as the request
 }
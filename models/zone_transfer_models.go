@@ -0,0 +1,11 @@
+package models
+
+import "github.com/vit0-9/utils_api/pkg/utils"
+
+// ZoneTransferResponse is the output of an AXFR exposure check.
+type ZoneTransferResponse struct {
+	RequestDomain string `json:"request_domain"`
+	Resolver      string `json:"resolver,omitempty"`
+	*utils.ZoneTransferReport
+	Error string `json:"error,omitempty"`
+}
@@ -0,0 +1,55 @@
+// Package admin guards the endpoints that operate the instance rather than query targets.
+package admin
+
+import (
+	"crypto/subtle"
+	"errors"
+	"log"
+	"strings"
+	"sync"
+)
+
+// APIKeyHeader carries the API key on admin requests.
+const APIKeyHeader = "X-Admin-Key"
+
+// Errors returned by CheckAPIKey.
+var (
+	ErrDisabled      = errors.New("admin endpoints are disabled: no ADMIN_API_KEYS are configured")
+	ErrInvalidAPIKey = errors.New("missing or invalid admin API key")
+)
+
+var (
+	configMu sync.RWMutex
+	apiKeys  [][]byte
+)
+
+// Configure sets the comma-separated API keys accepted by the admin endpoints. Without keys,
+// the admin endpoints are disabled.
+func Configure(keys string) {
+	configMu.Lock()
+	defer configMu.Unlock()
+	apiKeys = nil
+	for _, key := range strings.Split(keys, ",") {
+		if key = strings.TrimSpace(key); key != "" {
+			apiKeys = append(apiKeys, []byte(key))
+		}
+	}
+	if len(apiKeys) > 0 {
+		log.Printf("Admin endpoints enabled with %d API keys", len(apiKeys))
+	}
+}
+
+// CheckAPIKey reports whether key is one of the configured admin API keys.
+func CheckAPIKey(key string) error {
+	configMu.RLock()
+	defer configMu.RUnlock()
+	if len(apiKeys) == 0 {
+		return ErrDisabled
+	}
+	for _, valid := range apiKeys {
+		if subtle.ConstantTimeCompare([]byte(key), valid) == 1 {
+			return nil
+		}
+	}
+	return ErrInvalidAPIKey
+}
@@ -0,0 +1,269 @@
+package utils
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ASN lookup cache settings.
+const (
+	asnCacheTTL        = 6 * time.Hour
+	asnCacheMaxEntries = 1000
+)
+
+// ASNInfo describes an autonomous system and the prefixes it announces.
+type ASNInfo struct {
+	ASN             uint32    `json:"asn"`
+	Name            string    `json:"name,omitempty"` // Holder / organization
+	Country         string    `json:"country,omitempty"`
+	Announced       bool      `json:"announced"`
+	Prefixes        []string  `json:"prefixes"`
+	IPv4PrefixCount int       `json:"ipv4_prefix_count"`
+	IPv6PrefixCount int       `json:"ipv6_prefix_count"`
+	Source          string    `json:"source"` // "ripestat" or "prefix-file"
+	Cached          bool      `json:"cached"`
+	QueryTime       time.Time `json:"query_time"`
+}
+
+// asnDataSource looks up an ASN in one backing data set.
+type asnDataSource interface {
+	name() string
+	lookup(ctx context.Context, asn uint32) (*ASNInfo, error)
+}
+
+type cachedASNInfo struct {
+	info    ASNInfo
+	expires time.Time
+}
+
+var (
+	asnSource asnDataSource = &ripeStatSource{}
+
+	asnCacheMu sync.Mutex
+	asnCache   = make(map[uint32]cachedASNInfo)
+)
+
+// ConfigureASNLookup selects the ASN data source. With a prefix file (a CAIDA RouteViews
+// prefix2as dump, or "prefix/len asn" lines) lookups are served locally; otherwise RIPEstat
+// is queried.
+func ConfigureASNLookup(prefixFile string) {
+	if prefixFile == "" {
+		return
+	}
+	source, err := loadPrefixFileSource(prefixFile)
+	if err != nil {
+		log.Printf("ERROR: Could not load ASN prefix file %s: %v. ASN lookups will use RIPEstat.", prefixFile, err)
+		return
+	}
+	asnSource = source
+	log.Printf("Loaded %d ASNs from prefix file %s", len(source.prefixes), prefixFile)
+}
+
+// ParseASN accepts "AS13335", "as13335" or "13335".
+func ParseASN(value string) (uint32, error) {
+	value = strings.TrimSpace(value)
+	if len(value) > 2 && strings.EqualFold(value[:2], "AS") {
+		value = value[2:]
+	}
+	asn, err := strconv.ParseUint(value, 10, 32)
+	if err != nil || asn == 0 {
+		return 0, fmt.Errorf("invalid ASN: %s", value)
+	}
+	return uint32(asn), nil
+}
+
+// GetASNInfo returns the organization, country and announced prefixes of an ASN, from the
+// cache when a recent result exists.
+func GetASNInfo(ctx context.Context, asn uint32) (*ASNInfo, error) {
+	asnCacheMu.Lock()
+	cached, ok := asnCache[asn]
+	asnCacheMu.Unlock()
+	if ok && time.Now().Before(cached.expires) {
+		info := cached.info
+		info.Cached = true
+		TimingRecorderFrom(ctx).MarkCacheHit()
+		return &info, nil
+	}
+
+	info, err := asnSource.lookup(ctx, asn)
+	if err != nil {
+		return nil, err
+	}
+	info.ASN = asn
+	info.Source = asnSource.name()
+	info.QueryTime = time.Now()
+	for _, prefix := range info.Prefixes {
+		if strings.Contains(prefix, ":") {
+			info.IPv6PrefixCount++
+		} else {
+			info.IPv4PrefixCount++
+		}
+	}
+
+	asnCacheMu.Lock()
+	if len(asnCache) >= asnCacheMaxEntries {
+		for key, entry := range asnCache {
+			if time.Now().After(entry.expires) {
+				delete(asnCache, key)
+			}
+		}
+		if len(asnCache) >= asnCacheMaxEntries {
+			asnCache = make(map[uint32]cachedASNInfo)
+		}
+	}
+	asnCache[asn] = cachedASNInfo{info: *info, expires: time.Now().Add(asnCacheTTL)}
+	asnCacheMu.Unlock()
+	return info, nil
+}
+
+// ripeStatSource queries the RIPEstat data API.
+type ripeStatSource struct{}
+
+var (
+	ripeStatBaseURL = "https://stat.ripe.net/data"
+	ripeStatClient  = &http.Client{Timeout: 20 * time.Second, Transport: NewOutboundTransport()}
+)
+
+func (s *ripeStatSource) name() string { return "ripestat" }
+
+func (s *ripeStatSource) lookup(ctx context.Context, asn uint32) (*ASNInfo, error) {
+	resource := url.Values{"resource": {fmt.Sprintf("AS%d", asn)}}
+	info := &ASNInfo{Prefixes: []string{}}
+
+	var overview struct {
+		Holder    string `json:"holder"`
+		Announced bool   `json:"announced"`
+	}
+	if err := RIPEStatGet(ctx, "as-overview", resource, &overview); err != nil {
+		return nil, err
+	}
+	info.Name = overview.Holder
+	info.Announced = overview.Announced
+
+	var announced struct {
+		Prefixes []struct {
+			Prefix string `json:"prefix"`
+		} `json:"prefixes"`
+	}
+	if err := RIPEStatGet(ctx, "announced-prefixes", resource, &announced); err != nil {
+		return nil, err
+	}
+	for _, prefix := range announced.Prefixes {
+		info.Prefixes = append(info.Prefixes, prefix.Prefix)
+	}
+
+	var country struct {
+		LocatedResources []struct {
+			Location string `json:"location"`
+		} `json:"located_resources"`
+	}
+	if err := RIPEStatGet(ctx, "rir-stats-country", resource, &country); err == nil && len(country.LocatedResources) > 0 {
+		info.Country = country.LocatedResources[0].Location
+	}
+	return info, nil
+}
+
+// RIPEStatGet fetches one RIPEstat data API call and decodes its "data" member into out.
+func RIPEStatGet(ctx context.Context, call string, params url.Values, out any) error {
+	endpoint := fmt.Sprintf("%s/%s/data.json?%s", ripeStatBaseURL, call, params.Encode())
+	req, err := http.NewRequestWithContext(ctx, "GET", endpoint, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := ripeStatClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("RIPEstat %s request failed: %w", call, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("RIPEstat %s returned status %d", call, resp.StatusCode)
+	}
+	var envelope struct {
+		Status string          `json:"status"`
+		Data   json.RawMessage `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&envelope); err != nil {
+		return fmt.Errorf("invalid RIPEstat %s response: %w", call, err)
+	}
+	if envelope.Status != "ok" {
+		return fmt.Errorf("RIPEstat %s returned status %q", call, envelope.Status)
+	}
+	return json.Unmarshal(envelope.Data, out)
+}
+
+// prefixFileSource serves lookups from a local routing table dump. Names and countries
+// come from the MaxMind databases, when loaded, for the first announced prefix.
+type prefixFileSource struct {
+	prefixes map[uint32][]string
+}
+
+func (s *prefixFileSource) name() string { return "prefix-file" }
+
+func (s *prefixFileSource) lookup(ctx context.Context, asn uint32) (*ASNInfo, error) {
+	prefixes := s.prefixes[asn]
+	info := &ASNInfo{Prefixes: append([]string{}, prefixes...), Announced: len(prefixes) > 0}
+	if len(prefixes) == 0 {
+		return info, nil
+	}
+
+	ip, _, err := net.ParseCIDR(prefixes[0])
+	if err != nil {
+		return info, nil
+	}
+	if asnDB.loaded() {
+		if record, err := asnDB.asn(ip); err == nil && record.AutonomousSystemNumber == uint(asn) {
+			info.Name = record.AutonomousSystemOrganization
+		}
+	}
+	if cityDB.loaded() {
+		if record, err := cityDB.city(ip); err == nil {
+			info.Country = record.Country.IsoCode
+		}
+	}
+	return info, nil
+}
+
+// loadPrefixFileSource reads "prefix<TAB>length<TAB>asn" (prefix2as) or "prefix/len asn" lines.
+// Multi-origin entries ("13335_209242", "13335,209242") are listed under each origin.
+func loadPrefixFileSource(path string) (*prefixFileSource, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	source := &prefixFileSource{prefixes: make(map[uint32][]string)}
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		var prefix, origins string
+		switch {
+		case len(fields) == 3 && !strings.HasPrefix(fields[0], "#"):
+			prefix, origins = fields[0]+"/"+fields[1], fields[2]
+		case len(fields) == 2 && !strings.HasPrefix(fields[0], "#"):
+			prefix, origins = fields[0], fields[1]
+		default:
+			continue
+		}
+		if _, _, err := net.ParseCIDR(prefix); err != nil {
+			continue
+		}
+		for _, origin := range strings.FieldsFunc(origins, func(r rune) bool { return r == '_' || r == ',' }) {
+			if asn, err := ParseASN(origin); err == nil {
+				source.prefixes[asn] = append(source.prefixes[asn], prefix)
+			}
+		}
+	}
+	return source, scanner.Err()
+}
@@ -0,0 +1,171 @@
+// Package bgp reports how a prefix is routed: its origin ASNs, their upstreams and the
+// RPKI validation state of each origin, using RIPEstat's routing data.
+package bgp
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/vit0-9/utils_api/pkg/utils"
+)
+
+// RPKI validation states (RFC 6811).
+const (
+	RPKIValid    = "valid"
+	RPKIInvalid  = "invalid"
+	RPKINotFound = "not-found"
+)
+
+// ROA is a Route Origin Authorization covering the prefix.
+type ROA struct {
+	Origin    uint32 `json:"origin"`
+	Prefix    string `json:"prefix"`
+	MaxLength int    `json:"max_length"`
+	Validity  string `json:"validity"` // How this ROA matches the route, as reported by the validator
+}
+
+// RPKIValidation is the validation state of a (prefix, origin ASN) route.
+type RPKIValidation struct {
+	State  string `json:"state"`            // valid, invalid or not-found
+	Reason string `json:"reason,omitempty"` // For invalid routes: "asn" or "length"
+	ROAs   []ROA  `json:"roas,omitempty"`
+	Error  string `json:"error,omitempty"`
+}
+
+// Neighbour is an adjacent ASN seen in BGP paths.
+type Neighbour struct {
+	ASN   uint32 `json:"asn"`
+	Power int    `json:"power"` // Number of route collector peers seeing the adjacency
+}
+
+// Origin is an ASN originating the prefix.
+type Origin struct {
+	ASN       uint32         `json:"asn"`
+	Holder    string         `json:"holder,omitempty"`
+	RPKI      RPKIValidation `json:"rpki"`
+	Upstreams []Neighbour    `json:"upstreams"` // Neighbours to the left of the origin in AS paths
+	Error     string         `json:"error,omitempty"`
+}
+
+// RouteInfo describes the routing of the most specific announced prefix covering a resource.
+type RouteInfo struct {
+	Resource  string    `json:"resource"`
+	Prefix    string    `json:"prefix,omitempty"`
+	Announced bool      `json:"announced"`
+	Origins   []Origin  `json:"origins"`
+	QueryTime time.Time `json:"query_time"`
+}
+
+// LookupRoute finds the announced prefix covering an IP or prefix and reports its origins,
+// their upstreams and RPKI validation state.
+func LookupRoute(ctx context.Context, resource string) (*RouteInfo, error) {
+	resource = strings.TrimSpace(resource)
+	if ip := net.ParseIP(resource); ip == nil {
+		if _, _, err := net.ParseCIDR(resource); err != nil {
+			return nil, fmt.Errorf("invalid IP address or prefix: %s", resource)
+		}
+	}
+
+	var overview struct {
+		Resource  string `json:"resource"`
+		Announced bool   `json:"announced"`
+		ASNs      []struct {
+			ASN    uint32 `json:"asn"`
+			Holder string `json:"holder"`
+		} `json:"asns"`
+	}
+	if err := utils.RIPEStatGet(ctx, "prefix-overview", url.Values{"resource": {resource}}, &overview); err != nil {
+		return nil, err
+	}
+
+	info := &RouteInfo{
+		Resource:  resource,
+		Prefix:    overview.Resource,
+		Announced: overview.Announced,
+		Origins:   []Origin{},
+		QueryTime: time.Now(),
+	}
+	if !overview.Announced {
+		return info, nil
+	}
+
+	for _, asn := range overview.ASNs {
+		origin := Origin{ASN: asn.ASN, Holder: asn.Holder, Upstreams: []Neighbour{}}
+		origin.RPKI = validateRPKI(ctx, info.Prefix, asn.ASN)
+		upstreams, err := upstreamsOf(ctx, asn.ASN)
+		if err != nil {
+			origin.Error = err.Error()
+		} else {
+			origin.Upstreams = upstreams
+		}
+		info.Origins = append(info.Origins, origin)
+	}
+	return info, nil
+}
+
+// validateRPKI asks RIPEstat's RPKI validator about a (prefix, origin) route.
+func validateRPKI(ctx context.Context, prefix string, asn uint32) RPKIValidation {
+	var response struct {
+		Status         string `json:"status"`
+		ValidatingROAs []struct {
+			Origin    string `json:"origin"`
+			Prefix    string `json:"prefix"`
+			MaxLength int    `json:"max_length"`
+			Validity  string `json:"validity"`
+		} `json:"validating_roas"`
+	}
+	params := url.Values{"resource": {"AS" + strconv.FormatUint(uint64(asn), 10)}, "prefix": {prefix}}
+	if err := utils.RIPEStatGet(ctx, "rpki-validation", params, &response); err != nil {
+		return RPKIValidation{State: RPKINotFound, Error: err.Error()}
+	}
+
+	validation := RPKIValidation{}
+	switch response.Status {
+	case "valid":
+		validation.State = RPKIValid
+	case "invalid", "invalid_asn", "invalid_length":
+		validation.State = RPKIInvalid
+		if reason, ok := strings.CutPrefix(response.Status, "invalid_"); ok {
+			validation.Reason = reason
+		}
+	default: // "unknown": no ROA covers the prefix
+		validation.State = RPKINotFound
+	}
+	for _, roa := range response.ValidatingROAs {
+		origin, _ := utils.ParseASN(roa.Origin)
+		validation.ROAs = append(validation.ROAs, ROA{
+			Origin:    origin,
+			Prefix:    roa.Prefix,
+			MaxLength: roa.MaxLength,
+			Validity:  roa.Validity,
+		})
+	}
+	return validation
+}
+
+// upstreamsOf returns the ASN's left-hand (upstream) neighbours in observed AS paths.
+func upstreamsOf(ctx context.Context, asn uint32) ([]Neighbour, error) {
+	var response struct {
+		Neighbours []struct {
+			ASN   uint32 `json:"asn"`
+			Type  string `json:"type"`
+			Power int    `json:"power"`
+		} `json:"neighbours"`
+	}
+	params := url.Values{"resource": {"AS" + strconv.FormatUint(uint64(asn), 10)}}
+	if err := utils.RIPEStatGet(ctx, "asn-neighbours", params, &response); err != nil {
+		return nil, err
+	}
+	upstreams := []Neighbour{}
+	for _, neighbour := range response.Neighbours {
+		if neighbour.Type == "left" {
+			upstreams = append(upstreams, Neighbour{ASN: neighbour.ASN, Power: neighbour.Power})
+		}
+	}
+	return upstreams, nil
+}
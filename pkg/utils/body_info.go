@@ -0,0 +1,63 @@
+package utils
+
+import (
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"net/http"
+	"strconv"
+	"unicode/utf8"
+)
+
+// MaxBodyPreviewBytes caps the preview DescribeBody includes.
+const MaxBodyPreviewBytes = 64 << 10
+
+// BodyInfo describes a fetched response body.
+type BodyInfo struct {
+	Size            int    `json:"size"`                      // Bytes of the body after decompression
+	Truncated       bool   `json:"truncated,omitempty"`       // Cut at the fetch's size limit; size and hashes cover the part read
+	DeclaredLength  int64  `json:"declared_length,omitempty"` // Content-Length header, as sent (compressed when encoded)
+	DeclaredType    string `json:"declared_type,omitempty"`   // Content-Type header
+	SniffedType     string `json:"sniffed_type"`              // Content type detected from the first 512 bytes
+	SHA256          string `json:"sha256,omitempty"`
+	MD5             string `json:"md5,omitempty"`
+	Preview         string `json:"preview,omitempty"`          // The first bytes of the body
+	PreviewEncoding string `json:"preview_encoding,omitempty"` // utf-8, or base64 for binary content
+}
+
+// DescribeBody sizes and sniffs the decompressed body of a fetch, with a preview of its first
+// previewBytes bytes (none when 0) and its SHA-256 and MD5 hashes when hashes is set.
+func DescribeBody(fetchResult *FetchResult, previewBytes int, hashes bool) *BodyInfo {
+	body := DecodeResponseBody(fetchResult)
+	info := &BodyInfo{
+		Size:         len(body),
+		Truncated:    fetchResult.Truncated,
+		DeclaredType: fetchResult.Headers.Get("Content-Type"),
+		SniffedType:  http.DetectContentType(body),
+	}
+	if length, err := strconv.ParseInt(fetchResult.Headers.Get("Content-Length"), 10, 64); err == nil {
+		info.DeclaredLength = length
+	}
+	if hashes {
+		sha := sha256.Sum256(body)
+		sum := md5.Sum(body)
+		info.SHA256, info.MD5 = hex.EncodeToString(sha[:]), hex.EncodeToString(sum[:])
+	}
+	if previewBytes > 0 && len(body) > 0 {
+		preview := body[:min(previewBytes, MaxBodyPreviewBytes, len(body))]
+		// Drop a multi-byte character cut at the end before deciding the content is binary
+		for end := len(preview); end > 0 && end > len(preview)-utf8.UTFMax; end-- {
+			if utf8.Valid(preview[:end]) {
+				preview = preview[:end]
+				break
+			}
+		}
+		if utf8.Valid(preview) {
+			info.Preview, info.PreviewEncoding = string(preview), "utf-8"
+		} else {
+			info.Preview, info.PreviewEncoding = base64.StdEncoding.EncodeToString(preview), "base64"
+		}
+	}
+	return info
+}
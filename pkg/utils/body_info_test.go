@@ -0,0 +1,38 @@
+package utils
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestDescribeBody(t *testing.T) {
+	fetchResult := &FetchResult{
+		Headers: http.Header{"Content-Type": {"text/plain"}, "Content-Length": {"11"}},
+		Body:    []byte("hello world"),
+	}
+	info := DescribeBody(fetchResult, 5, true)
+	if info.Size != 11 || info.DeclaredLength != 11 || info.DeclaredType != "text/plain" {
+		t.Errorf("size %d, declared length %d, declared type %q", info.Size, info.DeclaredLength, info.DeclaredType)
+	}
+	if info.SniffedType != "text/plain; charset=utf-8" {
+		t.Errorf("sniffed type = %q", info.SniffedType)
+	}
+	// sha256sum and md5sum of "hello world"
+	if info.SHA256 != "b94d27b9934d3e08a52e52d7da7dabfac484efe37a5380ee9088f7ace2efcde9" || info.MD5 != "5eb63bbbe01eeed093cb22bb8f5acdc3" {
+		t.Errorf("hashes = %s, %s", info.SHA256, info.MD5)
+	}
+	if info.Preview != "hello" || info.PreviewEncoding != "utf-8" {
+		t.Errorf("preview = %q (%s)", info.Preview, info.PreviewEncoding)
+	}
+
+	// A multi-byte character cut by the preview is dropped rather than making the text binary
+	info = DescribeBody(&FetchResult{Headers: http.Header{}, Body: []byte("añb")}, 2, false)
+	if info.Preview != "a" || info.PreviewEncoding != "utf-8" || info.SHA256 != "" {
+		t.Errorf("cut preview = %q (%s), sha256 %q", info.Preview, info.PreviewEncoding, info.SHA256)
+	}
+
+	info = DescribeBody(&FetchResult{Headers: http.Header{}, Body: []byte("\x89PNG\r\n\x1a\n\x00\xff")}, 64, false)
+	if info.SniffedType != "image/png" || info.PreviewEncoding != "base64" || info.Preview != "iVBORw0KGgoA/w==" {
+		t.Errorf("binary body = %q, preview %q (%s)", info.SniffedType, info.Preview, info.PreviewEncoding)
+	}
+}
@@ -0,0 +1,44 @@
+package utils
+
+import (
+	"net/http"
+	"testing"
+)
+
+// TestAllocationBudgets keeps the hot paths of the batch endpoints within an allocation
+// budget, so a change that makes them allocate much more fails here rather than showing up
+// as lower throughput. The budgets leave some headroom over the measured counts; lower them
+// when a path gets cheaper. The benchmarks next to each function give the timings.
+func TestAllocationBudgets(t *testing.T) {
+	if testing.Short() {
+		t.Skip("allocation budgets are not checked in short mode")
+	}
+	offlineMu.Lock()
+	offlineEnabled = true // GetBasicIPInfo makes no reverse DNS lookup
+	offlineMu.Unlock()
+	defer func() {
+		offlineMu.Lock()
+		offlineEnabled = false
+		offlineMu.Unlock()
+	}()
+
+	_, compressed := compressedPage(t, "gzip")
+	gzipped := &FetchResult{Body: compressed, Headers: http.Header{"Content-Encoding": {"gzip"}}}
+	utmParams := FullUTMParams{Source: "Newsletter", Medium: "Email", Campaign: "Spring Sale 2026"}
+	utmOptions := &UTMGeneratorOptions{ForceLowercase: true, SpaceReplacement: "_"}
+
+	for _, tt := range []struct {
+		name   string
+		budget float64
+		run    func()
+	}{
+		{"CleanURL", 25, func() { CleanURL(cleanURLSample) }},
+		{"GenerateUTMLink", 25, func() { GenerateUTMLink("https://shop.example.com/products/boots?id=42", utmParams, utmOptions) }},
+		{"GetBasicIPInfo", 4, func() { GetBasicIPInfo("192.0.2.1") }},
+		{"DecodeResponseBody", 15, func() { DecodeResponseBody(gzipped) }},
+	} {
+		if allocs := testing.AllocsPerRun(100, tt.run); allocs > tt.budget {
+			t.Errorf("%s allocates %.0f times per call, over its budget of %.0f", tt.name, allocs, tt.budget)
+		}
+	}
+}
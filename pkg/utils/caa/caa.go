@@ -0,0 +1,280 @@
+// Package caa evaluates the CAA records (RFC 8659) that decide which certificate
+// authorities may issue for a domain.
+package caa
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/vit0-9/utils_api/pkg/utils"
+	"github.com/vit0-9/utils_api/pkg/utils/domain"
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+// dnsTypeCAA is the CAA record type, which dnsmessage has no constant for.
+const dnsTypeCAA dnsmessage.Type = 257
+
+// certificateCheckTimeout bounds the SSL check of the served certificate.
+const certificateCheckTimeout = 20 * time.Second
+
+// Property is one CAA record.
+type Property struct {
+	Flags        uint8             `json:"flags"`
+	Critical     bool              `json:"critical"` // The issuer critical flag
+	Tag          string            `json:"tag"`
+	Value        string            `json:"value"`
+	IssuerDomain string            `json:"issuer_domain,omitempty"` // Of issue and issuewild; empty when issuance is forbidden
+	Parameters   map[string]string `json:"parameters,omitempty"`    // Of issue and issuewild, e.g. accounturi or validationmethods
+}
+
+// Policy is the effective CAA policy of a name and how the served certificate fits it.
+type Policy struct {
+	Domain        string     `json:"domain"`
+	EffectiveName string     `json:"effective_name,omitempty"` // The name whose records apply; empty when there are none
+	Inherited     bool       `json:"inherited"`                // The records come from a parent domain
+	CheckedNames  []string   `json:"checked_names"`            // The names queried, from the domain up
+	Records       []Property `json:"records"`
+	AnyCAAllowed  bool       `json:"any_ca_allowed"` // No CAA records apply, so every CA may issue
+
+	// Issue and IssueWild list the CA domains authorized for names and for wildcards; they
+	// are empty when issuance is forbidden (a lone ";"). Without issuewild records, IssueWild
+	// is Issue.
+	Issue                     []string `json:"issue"`
+	IssueWild                 []string `json:"issue_wild"`
+	IssuanceForbidden         bool     `json:"issuance_forbidden"`
+	WildcardIssuanceForbidden bool     `json:"wildcard_issuance_forbidden"`
+	IODEF                     []string `json:"iodef,omitempty"`            // Where CAs report refused requests
+	UnknownCritical           []string `json:"unknown_critical,omitempty"` // Critical tags no CA understands, forbidding issuance
+
+	Certificate *CertificateCheck `json:"certificate,omitempty"`
+	Error       string            `json:"error,omitempty"`
+}
+
+// CertificateCheck reports whether the certificate a host serves was issued by a CA the
+// policy authorizes.
+type CertificateCheck struct {
+	Issuer          string `json:"issuer,omitempty"`
+	IssuerCAADomain string `json:"issuer_caa_domain,omitempty"` // The CAA domain of the issuer, when it is a known CA
+	Wildcard        bool   `json:"wildcard"`                    // Covers the name through a wildcard SAN, so issuewild applies
+	Permitted       *bool  `json:"permitted,omitempty"`         // Unset when the issuer is not a known CA and the policy restricts issuance
+	MatchedCAA      string `json:"matched_caa_domain,omitempty"`
+	Error           string `json:"error,omitempty"`
+}
+
+// Check finds the effective CAA policy of name and, when checkCertificate is set, whether
+// the certificate served on port 443 of name is permitted by it.
+func Check(ctx context.Context, resolver utils.Resolver, name string, checkCertificate bool) *Policy {
+	name = strings.TrimSuffix(strings.ToLower(strings.TrimSpace(name)), ".")
+	policy := Evaluate(ctx, resolver, name)
+	if !checkCertificate || policy.Error != "" {
+		return policy
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, certificateCheckTimeout)
+	defer cancel()
+	sslInfo, err := domain.GetSSLInfo(ctx, name)
+	if err != nil {
+		policy.Certificate = &CertificateCheck{Error: err.Error()}
+		return policy
+	}
+	policy.Certificate = policy.checkIssuer(sslInfo.Issuer, strings.HasPrefix(sslInfo.Hostname.MatchedName, "*."))
+	return policy
+}
+
+// Evaluate climbs from name towards the root until a name has CAA records, per RFC 8659
+// section 3, and summarizes the policy they set.
+func Evaluate(ctx context.Context, resolver utils.Resolver, name string) *Policy {
+	name = strings.TrimSuffix(strings.ToLower(strings.TrimSpace(name)), ".")
+	policy := &Policy{Domain: name, CheckedNames: []string{}, Records: []Property{}, Issue: []string{}, IssueWild: []string{}}
+	if err := utils.CheckOutboundName(name); err != nil {
+		policy.Error = err.Error()
+		return policy
+	}
+
+	for candidate := name; candidate != ""; candidate = parentDomain(candidate) {
+		policy.CheckedNames = append(policy.CheckedNames, candidate)
+		records, err := lookupCAA(ctx, resolver, candidate)
+		if err != nil {
+			policy.Error = err.Error()
+			return policy
+		}
+		if len(records) > 0 {
+			policy.EffectiveName = candidate
+			policy.Inherited = candidate != name
+			policy.Records = records
+			break
+		}
+	}
+	policy.summarize()
+	return policy
+}
+
+// summarize fills in the authorized CAs from the records.
+func (p *Policy) summarize() {
+	if len(p.Records) == 0 {
+		p.AnyCAAllowed = true
+		return
+	}
+	hasIssue, hasIssueWild := false, false
+	for _, record := range p.Records {
+		switch record.Tag {
+		case "issue":
+			hasIssue = true
+			if record.IssuerDomain != "" {
+				p.Issue = appendUnique(p.Issue, record.IssuerDomain)
+			}
+		case "issuewild":
+			hasIssueWild = true
+			if record.IssuerDomain != "" {
+				p.IssueWild = appendUnique(p.IssueWild, record.IssuerDomain)
+			}
+		case "iodef":
+			p.IODEF = append(p.IODEF, record.Value)
+		default:
+			if record.Critical {
+				p.UnknownCritical = append(p.UnknownCritical, record.Tag)
+			}
+		}
+	}
+
+	// Without issue records any CA may issue, unless another property restricts it
+	p.IssuanceForbidden = len(p.UnknownCritical) > 0 || (hasIssue && len(p.Issue) == 0)
+	if !hasIssueWild { // issue also governs wildcards
+		p.IssueWild = p.Issue
+		p.WildcardIssuanceForbidden = p.IssuanceForbidden
+	} else {
+		p.WildcardIssuanceForbidden = len(p.UnknownCritical) > 0 || len(p.IssueWild) == 0
+	}
+	p.AnyCAAllowed = !hasIssue && !hasIssueWild && len(p.UnknownCritical) == 0
+}
+
+// checkIssuer decides whether a certificate from issuer may be issued under the policy.
+func (p *Policy) checkIssuer(issuer string, wildcard bool) *CertificateCheck {
+	check := &CertificateCheck{Issuer: issuer, Wildcard: wildcard}
+	caaDomains := IssuerCAADomains(issuer)
+	if len(caaDomains) > 0 {
+		check.IssuerCAADomain = caaDomains[0]
+	}
+
+	authorized, forbidden := p.Issue, p.IssuanceForbidden
+	if wildcard {
+		authorized, forbidden = p.IssueWild, p.WildcardIssuanceForbidden
+	}
+	switch {
+	case forbidden:
+		check.Permitted = boolPtr(false)
+	case p.AnyCAAllowed || (!wildcard && !p.hasTag("issue")): // Only issuewild restricts issuance
+		check.Permitted = boolPtr(true)
+	default:
+		for _, caaDomain := range caaDomains {
+			for _, allowed := range authorized {
+				if caaDomain == allowed {
+					check.MatchedCAA = allowed
+					check.Permitted = boolPtr(true)
+					return check
+				}
+			}
+		}
+		if len(caaDomains) > 0 {
+			check.Permitted = boolPtr(false)
+		}
+	}
+	return check
+}
+
+// hasTag reports whether the policy has a record with the given tag.
+func (p *Policy) hasTag(tag string) bool {
+	for _, record := range p.Records {
+		if record.Tag == tag {
+			return true
+		}
+	}
+	return false
+}
+
+// lookupCAA returns the CAA records at name. A name that does not exist has none.
+func lookupCAA(ctx context.Context, resolver utils.Resolver, name string) ([]Property, error) {
+	answers, err := resolver.Query(ctx, name, dnsTypeCAA)
+	if errors.Is(err, utils.ErrDNSNameNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var records []Property
+	for _, answer := range answers {
+		if answer.Header.Type != dnsTypeCAA { // Skip the CNAME chain leading to the answer
+			continue
+		}
+		body, ok := answer.Body.(*dnsmessage.UnknownResource)
+		if !ok {
+			continue
+		}
+		record, err := ParseProperty(body.Data)
+		if err != nil {
+			return nil, fmt.Errorf("invalid CAA record at %s: %w", name, err)
+		}
+		records = append(records, record)
+	}
+	return records, nil
+}
+
+// ParseProperty decodes the wire format of a CAA record: flags(1) tag-length(1) tag value.
+func ParseProperty(data []byte) (Property, error) {
+	if len(data) < 2 || data[1] == 0 || len(data) < 2+int(data[1]) {
+		return Property{}, fmt.Errorf("truncated record")
+	}
+	property := Property{
+		Flags:    data[0],
+		Critical: data[0]&0x80 != 0,
+		Tag:      strings.ToLower(string(data[2 : 2+int(data[1])])),
+		Value:    string(data[2+int(data[1]):]),
+	}
+	if property.Tag == "issue" || property.Tag == "issuewild" {
+		property.IssuerDomain, property.Parameters = parseIssueValue(property.Value)
+	}
+	return property, nil
+}
+
+// parseIssueValue splits an issue or issuewild value such as
+// "letsencrypt.org; accounturi=https://..." into the CA domain and its parameters.
+func parseIssueValue(value string) (string, map[string]string) {
+	issuerDomain, rest, _ := strings.Cut(value, ";")
+	var parameters map[string]string
+	for _, parameter := range strings.Split(rest, ";") {
+		key, val, ok := strings.Cut(strings.TrimSpace(parameter), "=")
+		if !ok {
+			continue
+		}
+		if parameters == nil {
+			parameters = map[string]string{}
+		}
+		parameters[strings.TrimSpace(key)] = strings.TrimSpace(val)
+	}
+	return strings.ToLower(strings.TrimSuffix(strings.TrimSpace(issuerDomain), ".")), parameters
+}
+
+// parentDomain drops the first label of name; the parent of a TLD is empty.
+func parentDomain(name string) string {
+	_, parent, ok := strings.Cut(name, ".")
+	if !ok {
+		return ""
+	}
+	return parent
+}
+
+func appendUnique(values []string, value string) []string {
+	for _, existing := range values {
+		if existing == value {
+			return values
+		}
+	}
+	return append(values, value)
+}
+
+func boolPtr(b bool) *bool {
+	return &b
+}
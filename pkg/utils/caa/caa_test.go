@@ -0,0 +1,104 @@
+package caa
+
+import "testing"
+
+// record builds the wire format of a CAA record.
+func record(flags byte, tag, value string) []byte {
+	return append(append([]byte{flags, byte(len(tag))}, tag...), value...)
+}
+
+func TestParseProperty(t *testing.T) {
+	property, err := ParseProperty(record(0, "issue", "LetsEncrypt.org; accounturi=https://acme-v02.api.letsencrypt.org/acme/acct/1; validationmethods=dns-01"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if property.Tag != "issue" || property.IssuerDomain != "letsencrypt.org" || property.Critical {
+		t.Errorf("property = %+v", property)
+	}
+	if property.Parameters["validationmethods"] != "dns-01" || property.Parameters["accounturi"] == "" {
+		t.Errorf("parameters = %v", property.Parameters)
+	}
+
+	property, err = ParseProperty(record(128, "tbs", "x"))
+	if err != nil || !property.Critical || property.IssuerDomain != "" {
+		t.Errorf("critical property = %+v, %v", property, err)
+	}
+	if _, err := ParseProperty([]byte{0, 9, 'i'}); err == nil {
+		t.Error("truncated record: no error")
+	}
+}
+
+func TestPolicy(t *testing.T) {
+	parse := func(raw ...[]byte) []Property {
+		var properties []Property
+		for _, data := range raw {
+			property, err := ParseProperty(data)
+			if err != nil {
+				t.Fatal(err)
+			}
+			properties = append(properties, property)
+		}
+		return properties
+	}
+	letsEncrypt := "CN=R11,O=Let's Encrypt,C=US"
+	digiCert := "CN=DigiCert Global G2 TLS RSA SHA256 2020 CA1,O=DigiCert Inc,C=US"
+	private := "CN=Internal CA,O=Example Corp"
+
+	for _, tt := range []struct {
+		name          string
+		records       []Property
+		issuer        string
+		wildcard      bool
+		wantAnyCA     bool
+		wantForbidden bool
+		wantPermitted *bool
+	}{
+		{"no records", nil, private, false, true, false, boolPtr(true)},
+		{"authorized", parse(record(0, "issue", "letsencrypt.org")), letsEncrypt, false, false, false, boolPtr(true)},
+		{"not authorized", parse(record(0, "issue", "letsencrypt.org")), digiCert, false, false, false, boolPtr(false)},
+		{"unknown issuer", parse(record(0, "issue", "letsencrypt.org")), private, false, false, false, nil},
+		{"issue forbidden", parse(record(0, "issue", ";")), letsEncrypt, false, false, true, boolPtr(false)},
+		{"issue covers wildcards", parse(record(0, "issue", "digicert.com")), digiCert, true, false, false, boolPtr(true)},
+		{"wildcard forbidden", parse(record(0, "issue", "letsencrypt.org"), record(0, "issuewild", ";")), letsEncrypt, true, false, false, boolPtr(false)},
+		{"only issuewild", parse(record(0, "issuewild", "digicert.com")), letsEncrypt, false, false, false, boolPtr(true)},
+		{"iodef only", parse(record(0, "iodef", "mailto:security@example.com")), digiCert, false, true, false, boolPtr(true)},
+		{"unknown critical", parse(record(0, "issue", "letsencrypt.org"), record(128, "tbs", "x")), letsEncrypt, false, false, true, boolPtr(false)},
+	} {
+		policy := &Policy{Records: tt.records, Issue: []string{}, IssueWild: []string{}}
+		policy.summarize()
+		if policy.AnyCAAllowed != tt.wantAnyCA || policy.IssuanceForbidden != tt.wantForbidden {
+			t.Errorf("%s: any CA %t, forbidden %t, want %t, %t", tt.name, policy.AnyCAAllowed, policy.IssuanceForbidden, tt.wantAnyCA, tt.wantForbidden)
+		}
+		check := policy.checkIssuer(tt.issuer, tt.wildcard)
+		if (check.Permitted == nil) != (tt.wantPermitted == nil) || (check.Permitted != nil && *check.Permitted != *tt.wantPermitted) {
+			t.Errorf("%s: permitted = %v, want %v", tt.name, check.Permitted, tt.wantPermitted)
+		}
+	}
+}
+
+func TestParentDomain(t *testing.T) {
+	var names []string
+	for name := "www.shop.example.co.uk"; name != ""; name = parentDomain(name) {
+		names = append(names, name)
+	}
+	if len(names) != 5 || names[4] != "uk" {
+		t.Errorf("climbed %v", names)
+	}
+}
+
+func TestIssuerCAADomains(t *testing.T) {
+	for issuer, want := range map[string]string{
+		"CN=WE1,O=Google Trust Services,C=US":                                 "pki.goog",
+		"CN=Sectigo RSA Domain Validation Secure Server CA,O=Sectigo Limited": "sectigo.com",
+		"CN=Amazon RSA 2048 M02,O=Amazon,C=US":                                "amazon.com",
+		"CN=Internal CA":                                                      "",
+	} {
+		got := ""
+		if domains := IssuerCAADomains(issuer); len(domains) > 0 {
+			got = domains[0]
+		}
+		if got != want {
+			t.Errorf("IssuerCAADomains(%q) starts with %q, want %q", issuer, got, want)
+		}
+	}
+}
@@ -0,0 +1,44 @@
+package caa
+
+import "strings"
+
+// knownIssuers maps the CAA domains of public CAs to words found in the issuer names of the
+// certificates they sign. A CA listed under several domains accepts any of them.
+var knownIssuers = []struct {
+	caaDomains  []string
+	issuerWords []string
+}{
+	{[]string{"letsencrypt.org"}, []string{"let's encrypt"}},
+	{[]string{"pki.goog", "google.com"}, []string{"google trust services"}},
+	{[]string{"digicert.com", "symantec.com", "thawte.com", "geotrust.com", "rapidssl.com", "digicert.ne.jp"}, []string{"digicert", "thawte", "geotrust", "rapidssl", "encryption everywhere"}},
+	{[]string{"sectigo.com", "comodoca.com", "comodo.com", "usertrust.com", "trust-provider.com"}, []string{"sectigo", "comodo", "usertrust"}},
+	{[]string{"zerossl.com"}, []string{"zerossl"}},
+	{[]string{"amazon.com", "amazontrust.com", "awstrust.com", "amazonaws.com"}, []string{"amazon"}},
+	{[]string{"globalsign.com"}, []string{"globalsign"}},
+	{[]string{"godaddy.com", "starfieldtech.com"}, []string{"godaddy", "go daddy", "starfield"}},
+	{[]string{"entrust.net", "affirmtrust.com"}, []string{"entrust", "affirmtrust"}},
+	{[]string{"ssl.com"}, []string{"ssl.com", "ssl corporation"}},
+	{[]string{"buypass.com", "buypass.no"}, []string{"buypass"}},
+	{[]string{"identrust.com"}, []string{"identrust"}},
+	{[]string{"microsoft.com"}, []string{"microsoft"}},
+	{[]string{"certum.pl", "certum.eu"}, []string{"certum", "asseco", "unizeto"}},
+	{[]string{"harica.gr"}, []string{"harica", "hellenic academic"}},
+	{[]string{"actalis.it"}, []string{"actalis"}},
+	{[]string{"telesec.de", "pki.dfn.de"}, []string{"telesec", "t-systems", "dfn-verein"}},
+	{[]string{"swisssign.com"}, []string{"swisssign"}},
+	{[]string{"cloudflare.com"}, []string{"cloudflare"}},
+}
+
+// IssuerCAADomains returns the CAA domains of the CA that signed a certificate with the given
+// issuer name, or nil when the CA is not known.
+func IssuerCAADomains(issuer string) []string {
+	issuer = strings.ToLower(issuer)
+	for _, known := range knownIssuers {
+		for _, word := range known.issuerWords {
+			if strings.Contains(issuer, word) {
+				return known.caaDomains
+			}
+		}
+	}
+	return nil
+}
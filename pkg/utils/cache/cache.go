@@ -0,0 +1,255 @@
+// Package cache keeps lookup results for a while so repeated queries are answered without
+// outbound work: in a bounded in-memory LRU by default, or in Redis when several replicas
+// should share it.
+package cache
+
+import (
+	"container/list"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"log"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/vit0-9/utils_api/pkg/utils/redis"
+)
+
+// defaultMaxEntries bounds the in-memory cache when no size is configured.
+const defaultMaxEntries = 10000
+
+// Backend stores cached values with a time to live, and the locks that keep two requests
+// from computing the same value at once.
+type Backend interface {
+	Get(ctx context.Context, key string) ([]byte, bool, error)
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration) error
+	// Lock takes the lock on key for at most ttl, unless another holder has it.
+	Lock(ctx context.Context, key, token string, ttl time.Duration) (bool, error)
+	// Unlock releases the lock on key if token still holds it.
+	Unlock(ctx context.Context, key, token string) error
+}
+
+var (
+	mu      sync.RWMutex
+	backend Backend = NewLRU(defaultMaxEntries)
+)
+
+// Configure selects Redis when a client is given, and the in-memory LRU holding up to
+// maxEntries values (10000 when 0) otherwise.
+func Configure(client *redis.Client, maxEntries int) {
+	mu.Lock()
+	defer mu.Unlock()
+	if client != nil {
+		backend = NewRedis(client)
+		log.Printf("Results cached in Redis")
+		return
+	}
+	if maxEntries <= 0 {
+		maxEntries = defaultMaxEntries
+	}
+	backend = NewLRU(maxEntries)
+}
+
+// Get returns a cached value. Backend errors are logged and reported as a miss, so an
+// unreachable Redis slows the API down but does not break it.
+func Get(ctx context.Context, key string) ([]byte, bool) {
+	mu.RLock()
+	b := backend
+	mu.RUnlock()
+	value, ok, err := b.Get(ctx, key)
+	if err != nil {
+		log.Printf("ERROR: Could not read cache entry %s: %v", key, err)
+		return nil, false
+	}
+	return value, ok
+}
+
+// Set caches a value for ttl, logging backend errors.
+func Set(ctx context.Context, key string, value []byte, ttl time.Duration) {
+	mu.RLock()
+	b := backend
+	mu.RUnlock()
+	if err := b.Set(ctx, key, value, ttl); err != nil {
+		log.Printf("ERROR: Could not write cache entry %s: %v", key, err)
+	}
+}
+
+// Backend names reported by Ping.
+const (
+	BackendMemory = "memory"
+	BackendRedis  = "redis"
+)
+
+// Ping checks that the backend is reachable and returns its name.
+func Ping(ctx context.Context) (string, error) {
+	mu.RLock()
+	b := backend
+	mu.RUnlock()
+	if r, ok := b.(*redisBackend); ok {
+		_, err := r.client.Do(ctx, "PING")
+		return BackendRedis, err
+	}
+	return BackendMemory, nil
+}
+
+// Acquire takes the lock on key for at most ttl, so only one request computes a value at a
+// time, across replicas when the cache is in Redis. ok is false while another request holds
+// it; release gives it up. When the backend fails the lock is granted, so requests fall back
+// to duplicate work rather than waiting.
+func Acquire(ctx context.Context, key string, ttl time.Duration) (release func(), ok bool) {
+	mu.RLock()
+	b := backend
+	mu.RUnlock()
+	token := newToken()
+	acquired, err := b.Lock(ctx, key, token, ttl)
+	if err != nil {
+		log.Printf("ERROR: Could not lock cache entry %s: %v", key, err)
+		return func() {}, true
+	}
+	if !acquired {
+		return nil, false
+	}
+	return func() {
+		if err := b.Unlock(context.Background(), key, token); err != nil {
+			log.Printf("ERROR: Could not unlock cache entry %s: %v", key, err)
+		}
+	}, true
+}
+
+func newToken() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// lockEntry is a lock held in memory.
+type lockEntry struct {
+	token   string
+	expires time.Time
+}
+
+// lruEntry is a cached value in the LRU list.
+type lruEntry struct {
+	key     string
+	value   []byte
+	expires time.Time
+}
+
+// lru evicts the least recently used value once full; expired values are dropped when read.
+type lru struct {
+	mu         sync.Mutex
+	maxEntries int
+	order      *list.List // Most recently used first
+	entries    map[string]*list.Element
+	locks      map[string]lockEntry
+}
+
+// NewLRU returns an in-memory backend holding up to maxEntries values.
+func NewLRU(maxEntries int) Backend {
+	return &lru{maxEntries: maxEntries, order: list.New(), entries: make(map[string]*list.Element), locks: make(map[string]lockEntry)}
+}
+
+func (c *lru) Get(_ context.Context, key string) ([]byte, bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	element, ok := c.entries[key]
+	if !ok {
+		return nil, false, nil
+	}
+	entry := element.Value.(*lruEntry)
+	if time.Now().After(entry.expires) {
+		c.order.Remove(element)
+		delete(c.entries, key)
+		return nil, false, nil
+	}
+	c.order.MoveToFront(element)
+	return entry.value, true, nil
+}
+
+func (c *lru) Set(_ context.Context, key string, value []byte, ttl time.Duration) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry := &lruEntry{key: key, value: value, expires: time.Now().Add(ttl)}
+	if element, ok := c.entries[key]; ok {
+		element.Value = entry
+		c.order.MoveToFront(element)
+		return nil
+	}
+	c.entries[key] = c.order.PushFront(entry)
+	for c.order.Len() > c.maxEntries {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*lruEntry).key)
+	}
+	return nil
+}
+
+func (c *lru) Lock(_ context.Context, key, token string, ttl time.Duration) (bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	now := time.Now()
+	if held, ok := c.locks[key]; ok && now.Before(held.expires) {
+		return false, nil
+	}
+	for lockKey, held := range c.locks { // Keeps abandoned locks from piling up
+		if now.After(held.expires) {
+			delete(c.locks, lockKey)
+		}
+	}
+	c.locks[key] = lockEntry{token: token, expires: now.Add(ttl)}
+	return true, nil
+}
+
+func (c *lru) Unlock(_ context.Context, key, token string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.locks[key].token == token {
+		delete(c.locks, key)
+	}
+	return nil
+}
+
+// Redis key prefixes, namespacing the cache in a shared Redis.
+const (
+	redisKeyPrefix  = "utils_api:cache:"
+	redisLockPrefix = "utils_api:lock:"
+)
+
+// unlockScript deletes a lock only while the token still holds it, so a request whose lock
+// expired cannot release the next holder's.
+const unlockScript = `if redis.call('GET', KEYS[1]) == ARGV[1] then return redis.call('DEL', KEYS[1]) end return 0`
+
+// redisBackend keeps values in Redis, which expires them itself.
+type redisBackend struct {
+	client *redis.Client
+}
+
+// NewRedis returns a backend storing values in Redis.
+func NewRedis(client *redis.Client) Backend {
+	return &redisBackend{client: client}
+}
+
+func (r *redisBackend) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	reply, err := r.client.Do(ctx, "GET", redisKeyPrefix+key)
+	if err != nil || reply == nil {
+		return nil, false, err
+	}
+	value, ok := reply.([]byte)
+	return value, ok, nil
+}
+
+func (r *redisBackend) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	_, err := r.client.Do(ctx, "SET", redisKeyPrefix+key, string(value), "PX", strconv.FormatInt(ttl.Milliseconds(), 10))
+	return err
+}
+
+func (r *redisBackend) Lock(ctx context.Context, key, token string, ttl time.Duration) (bool, error) {
+	reply, err := r.client.Do(ctx, "SET", redisLockPrefix+key, token, "NX", "PX", strconv.FormatInt(ttl.Milliseconds(), 10))
+	return reply == "OK", err
+}
+
+func (r *redisBackend) Unlock(ctx context.Context, key, token string) error {
+	_, err := r.client.Do(ctx, "EVAL", unlockScript, "1", redisLockPrefix+key, token)
+	return err
+}
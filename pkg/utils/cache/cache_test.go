@@ -0,0 +1,106 @@
+package cache
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/vit0-9/utils_api/pkg/utils/redis"
+	"github.com/vit0-9/utils_api/pkg/utils/redis/redistest"
+)
+
+func TestLRU(t *testing.T) {
+	ctx := context.Background()
+	c := NewLRU(2)
+	c.Set(ctx, "a", []byte("1"), time.Minute)
+	c.Set(ctx, "b", []byte("2"), time.Minute)
+	c.Get(ctx, "a") // b is now the least recently used
+	c.Set(ctx, "c", []byte("3"), time.Minute)
+
+	if _, ok, _ := c.Get(ctx, "b"); ok {
+		t.Error("the least recently used entry was not evicted")
+	}
+	for key, want := range map[string]string{"a": "1", "c": "3"} {
+		if value, ok, _ := c.Get(ctx, key); !ok || string(value) != want {
+			t.Errorf("Get(%s) = %q, %v; want %q", key, value, ok, want)
+		}
+	}
+
+	c.Set(ctx, "a", []byte("expired"), -time.Second)
+	if _, ok, _ := c.Get(ctx, "a"); ok {
+		t.Error("an expired entry was returned")
+	}
+}
+
+func TestConfigure(t *testing.T) {
+	t.Cleanup(func() { Configure(nil, 0) })
+	Configure(nil, 1)
+	ctx := context.Background()
+	Set(ctx, "x", []byte("1"), time.Minute)
+	Set(ctx, "y", []byte("2"), time.Minute)
+	if _, ok := Get(ctx, "x"); ok {
+		t.Error("Configure() did not bound the cache to one entry")
+	}
+	if backend, err := Ping(ctx); backend != BackendMemory || err != nil {
+		t.Errorf("Ping() = %q, %v; want the memory backend", backend, err)
+	}
+
+	// An unreachable Redis is a miss, not an error, and grants every lock.
+	Configure(redis.New("127.0.0.1:1", "", 0), 0)
+	Set(ctx, "x", []byte("1"), time.Minute)
+	if _, ok := Get(ctx, "x"); ok {
+		t.Error("Get() with an unreachable Redis reported a hit")
+	}
+	if _, ok := Acquire(ctx, "x", time.Minute); !ok {
+		t.Error("Acquire() with an unreachable Redis was refused")
+	}
+	if backend, err := Ping(ctx); backend != BackendRedis || err == nil {
+		t.Errorf("Ping() with an unreachable Redis = %q, %v; want an error", backend, err)
+	}
+	Configure(redis.New(redistest.NewServer(t).Addr, "", 0), 0)
+	if _, err := Ping(ctx); err != nil {
+		t.Errorf("Ping() = %v", err)
+	}
+}
+
+func TestAcquire(t *testing.T) {
+	t.Cleanup(func() { Configure(nil, 0) })
+	Configure(nil, 0)
+	ctx := context.Background()
+
+	release, ok := Acquire(ctx, "k", time.Minute)
+	if !ok {
+		t.Fatal("Acquire() of a free lock was refused")
+	}
+	if _, ok := Acquire(ctx, "k", time.Minute); ok {
+		t.Error("Acquire() of a held lock succeeded")
+	}
+	release()
+	if _, ok := Acquire(ctx, "k", -time.Second); !ok {
+		t.Error("Acquire() after release was refused")
+	}
+	// The lock above expired immediately, so it no longer blocks.
+	if _, ok := Acquire(ctx, "k", time.Minute); !ok {
+		t.Error("Acquire() of an expired lock was refused")
+	}
+}
+
+func TestRedisLock(t *testing.T) {
+	server := redistest.NewServer(t)
+	ctx := context.Background()
+	first := NewRedis(redis.New(server.Addr, "", 0))
+	second := NewRedis(redis.New(server.Addr, "", 0))
+
+	if ok, err := first.Lock(ctx, "k", "a", time.Minute); !ok || err != nil {
+		t.Fatalf("Lock() = %v, %v; want the free lock", ok, err)
+	}
+	if ok, err := second.Lock(ctx, "k", "b", time.Minute); ok || err != nil {
+		t.Errorf("Lock() from another replica = %v, %v; want it refused", ok, err)
+	}
+	if err := second.Set(ctx, "k", []byte("shared"), time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	if value, ok, err := first.Get(ctx, "k"); !ok || err != nil || string(value) != "shared" {
+		t.Errorf("Get() = %q, %v, %v; want the value set by the other replica", value, ok, err)
+	}
+}
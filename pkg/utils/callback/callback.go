@@ -0,0 +1,146 @@
+// Package callback POSTs the results of background jobs to a URL the client supplied when
+// starting them, so clients need not poll for completion.
+package callback
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/vit0-9/utils_api/pkg/utils"
+	"github.com/vit0-9/utils_api/pkg/utils/lifecycle"
+)
+
+// Callback request headers.
+const (
+	EventHeader     = "X-Callback-Event"
+	TimestampHeader = "X-Callback-Timestamp"
+	SignatureHeader = "X-Signature-256" // sha256=<hex HMAC-SHA256(secret, body)>, as on notification webhooks
+)
+
+const (
+	maxURLLength = 2048
+	sendTimeout  = 15 * time.Second
+)
+
+// retryDelays are the waits before each retry of a failed delivery.
+var retryDelays = []time.Duration{5 * time.Second, 30 * time.Second, 2 * time.Minute}
+
+var httpClient = &http.Client{Timeout: sendTimeout, Transport: utils.NewTargetTransport()}
+
+var (
+	pending  sync.WaitGroup // Deliveries in progress, finished before the server exits
+	stopOnce sync.Once
+)
+
+var (
+	mu     sync.RWMutex
+	secret []byte
+)
+
+// Configure sets the secret callbacks are signed with. Without one, callbacks are sent
+// unsigned.
+func Configure(signingSecret string) {
+	mu.Lock()
+	defer mu.Unlock()
+	secret = []byte(signingSecret)
+}
+
+// Validate checks that a callback URL is an http(s) URL the outbound policy allows.
+func Validate(rawURL string) error {
+	if len(rawURL) > maxURLLength {
+		return fmt.Errorf("callback_url is longer than %d characters", maxURLLength)
+	}
+	parsed, err := url.Parse(rawURL)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Hostname() == "" {
+		return fmt.Errorf("callback_url must be an http(s) URL")
+	}
+	if err := utils.CheckOutboundName(parsed.Hostname()); err != nil {
+		return fmt.Errorf("callback_url: %w", err)
+	}
+	if err := utils.CheckTargetURL(context.Background(), rawURL); err != nil {
+		return fmt.Errorf("callback_url: %w", err)
+	}
+	return nil
+}
+
+// Deliver POSTs payload as JSON to target in the background, retrying failed deliveries a
+// few times.
+func Deliver(target, event string, payload any) {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		log.Printf("ERROR: Could not encode the %s callback: %v", event, err)
+		return
+	}
+	stopOnce.Do(func() {
+		lifecycle.Register("callbacks", func(ctx context.Context) error { return lifecycle.Wait(ctx, &pending) })
+	})
+	pending.Add(1)
+	go func() {
+		defer pending.Done()
+		for attempt := 0; ; attempt++ {
+			err := send(context.Background(), target, event, body)
+			if err == nil {
+				return
+			}
+			if attempt == len(retryDelays) {
+				log.Printf("ERROR: Giving up on the %s callback to %s after %d attempts: %v", event, target, attempt+1, err)
+				return
+			}
+			log.Printf("WARN: The %s callback to %s failed, retrying in %s: %v", event, target, retryDelays[attempt], err)
+			select {
+			case <-time.After(retryDelays[attempt]):
+			case <-lifecycle.Context().Done():
+				log.Printf("ERROR: Giving up on the %s callback to %s: the server is shutting down", event, target)
+				return
+			}
+		}
+	}()
+}
+
+// send makes one delivery attempt; any non-2xx status is a failure.
+func send(ctx context.Context, target, event string, body []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, target, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(EventHeader, event)
+	req.Header.Set(TimestampHeader, strconv.FormatInt(time.Now().Unix(), 10))
+	if signature := Sign(body); signature != "" {
+		req.Header.Set(SignatureHeader, signature)
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// Sign returns the signature header value of a body, or "" without a secret.
+func Sign(body []byte) string {
+	mu.RLock()
+	defer mu.RUnlock()
+	if len(secret) == 0 {
+		return ""
+	}
+	mac := hmac.New(sha256.New, secret)
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
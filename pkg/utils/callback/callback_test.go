@@ -0,0 +1,59 @@
+package callback
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestDeliver(t *testing.T) {
+	Configure("s3cret")
+	defer Configure("")
+	defer func(saved []time.Duration) { retryDelays = saved }(retryDelays)
+	retryDelays = []time.Duration{10 * time.Millisecond}
+
+	received := make(chan *http.Request, 2)
+	bodies := make(chan []byte, 2)
+	attempts := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		if attempts == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		body, _ := io.ReadAll(r.Body)
+		received <- r
+		bodies <- body
+	}))
+	defer server.Close()
+
+	if err := Validate(server.URL + "/hook"); err != nil {
+		t.Fatalf("Validate() error = %v", err)
+	}
+	Deliver(server.URL+"/hook", "test.completed", map[string]any{"id": "abc"})
+
+	select {
+	case r := <-received:
+		body := <-bodies
+		var payload map[string]any
+		if err := json.Unmarshal(body, &payload); err != nil || payload["id"] != "abc" {
+			t.Errorf("body = %s, want the payload", body)
+		}
+		if r.Header.Get(EventHeader) != "test.completed" || r.Header.Get(SignatureHeader) != Sign(body) || Sign(body) == "" {
+			t.Errorf("headers = %v, want the event and the body's signature", r.Header)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("the callback was not retried after the failed attempt")
+	}
+}
+
+func TestValidate(t *testing.T) {
+	for _, bad := range []string{"", "ftp://example.com/hook", "https:///hook", "not a url"} {
+		if err := Validate(bad); err == nil {
+			t.Errorf("Validate(%q) error = nil", bad)
+		}
+	}
+}
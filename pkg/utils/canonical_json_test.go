@@ -0,0 +1,102 @@
+package utils
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"math"
+	"strconv"
+	"testing"
+)
+
+// TestCanonicalizeJSONNumbers uses the IEEE 754 test values of RFC 8785 appendix B.
+func TestCanonicalizeJSONNumbers(t *testing.T) {
+	tests := []struct {
+		bits string
+		want string
+	}{
+		{"0000000000000000", "0"},
+		{"8000000000000000", "0"},
+		{"0000000000000001", "5e-324"},
+		{"8000000000000001", "-5e-324"},
+		{"7fefffffffffffff", "1.7976931348623157e+308"},
+		{"ffefffffffffffff", "-1.7976931348623157e+308"},
+		{"4340000000000000", "9007199254740992"},
+		{"c340000000000000", "-9007199254740992"},
+		{"4430000000000000", "295147905179352830000"},
+		{"44b52d02c7e14af5", "9.999999999999997e+22"},
+		{"44b52d02c7e14af6", "1e+23"},
+		{"44b52d02c7e14af7", "1.0000000000000001e+23"},
+		{"444b1ae4d6e2ef4e", "999999999999999700000"},
+		{"444b1ae4d6e2ef4f", "999999999999999900000"},
+		{"444b1ae4d6e2ef50", "1e+21"},
+		{"3eb0c6f7a0b5ed8c", "9.999999999999997e-7"},
+		{"3eb0c6f7a0b5ed8d", "0.000001"},
+		{"41b3de4355555553", "333333333.3333332"},
+		{"41b3de4355555554", "333333333.33333325"},
+		{"41b3de4355555555", "333333333.3333333"},
+		{"41b3de4355555556", "333333333.3333334"},
+		{"41b3de4355555557", "333333333.33333343"},
+		{"becbf647612f3696", "-0.0000033333333333333333"},
+		{"43143ff3c1cb0959", "1424953923781206.2"},
+	}
+	for _, tt := range tests {
+		raw, _ := hex.DecodeString(tt.bits)
+		f := math.Float64frombits(binary.BigEndian.Uint64(raw))
+		input := strconv.FormatFloat(f, 'g', -1, 64)
+		got, err := CanonicalizeJSON([]byte(input))
+		if err != nil || string(got) != tt.want {
+			t.Errorf("CanonicalizeJSON(%s) [%s] = %s, %v; want %s", input, tt.bits, got, err, tt.want)
+		}
+	}
+}
+
+func TestCanonicalizeJSON(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{ // RFC 8785 section 3.2.2
+			name: "RFC 8785 example",
+			input: `{
+  "numbers": [333333333.33333329, 1E30, 4.50, 2e-3, 0.000000000000000000000000001],
+  "string": "\u20ac$\u000F\u000aA'\u0042\u0022\u005c\\\"\/",
+  "literals": [null, true, false]
+}`,
+			want: `{"literals":[null,true,false],"numbers":[333333333.3333333,1e+30,4.5,0.002,1e-27],"string":"€$\u000f\nA'B\"\\\\\"/"}`,
+		},
+		{ // RFC 8785 section 3.2.3: members sorted by UTF-16 code units, not code points
+			name: "RFC 8785 sorting",
+			input: `{
+  "\u20ac": "Euro Sign",
+  "\r": "Carriage Return",
+  "\ufb33": "Hebrew Letter Dalet With Dagesh",
+  "1": "One",
+  "\ud83d\ude00": "Emoji: Grinning Face",
+  "\u0080": "Control",
+  "\u00f6": "Latin Small Letter O With Diaeresis"
+}`,
+			want: "{\"\\r\":\"Carriage Return\",\"1\":\"One\",\"\u0080\":\"Control\",\"ö\":\"Latin Small Letter O With Diaeresis\"," +
+				"\"€\":\"Euro Sign\",\"😀\":\"Emoji: Grinning Face\",\"\ufb33\":\"Hebrew Letter Dalet With Dagesh\"}",
+		},
+		{
+			name:  "nested",
+			input: `{"b": [{"z": 1, "a": 2}], "a": {"y": "", "x": {}}}`,
+			want:  `{"a":{"x":{},"y":""},"b":[{"a":2,"z":1}]}`,
+		},
+	}
+	for _, tt := range tests {
+		got, err := CanonicalizeJSON([]byte(tt.input))
+		if err != nil || string(got) != tt.want {
+			t.Errorf("%s: CanonicalizeJSON() = %s, %v; want %s", tt.name, got, err, tt.want)
+		}
+	}
+}
+
+func TestCanonicalizeJSONErrors(t *testing.T) {
+	for _, input := range []string{`1e400`, `[-1e309]`, `{"a":1} {"b":2}`, `{"a":`, ``} {
+		if got, err := CanonicalizeJSON([]byte(input)); err == nil {
+			t.Errorf("CanonicalizeJSON(%q) = %s, want an error", input, got)
+		}
+	}
+}
@@ -0,0 +1,297 @@
+package utils
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/vit0-9/utils_api/pkg/utils/lifecycle"
+)
+
+// Capture is a stored page fetch that analyses can be re-run against without re-fetching,
+// e.g. after fingerprints or rules are updated.
+type Capture struct {
+	ID         string      `json:"id"`
+	RequestURL string      `json:"request_url"`
+	CapturedAt time.Time   `json:"captured_at"`
+	StatusCode int         `json:"status_code"`
+	Status     string      `json:"status"`
+	Headers    http.Header `json:"headers"`
+	Body       []byte      `json:"body"` // As received (possibly compressed)
+	FinalURL   string      `json:"final_url"`
+
+	CurlCommand string `json:"curl_command,omitempty"`
+}
+
+// captureIDRegex guards the file-backed store against path traversal.
+var captureIDRegex = regexp.MustCompile(`^[0-9a-f]{32}$`)
+
+// Capture store limits. Pages larger than captureMaxBodyBytes are analysed but not stored.
+const (
+	captureMaxBodyBytes     = 5 << 20
+	defaultCaptureMaxBytes  = 256 << 20
+	captureMaxMemoryEntries = 500
+	captureSweepInterval    = 10 * time.Minute
+)
+
+// storedCapture indexes one stored capture for expiry and eviction.
+type storedCapture struct {
+	id      string
+	size    int64
+	savedAt time.Time
+}
+
+// captureStore keeps captures in memory, or on disk when a directory is configured.
+// Both are bounded by a total size budget; the oldest captures are evicted first.
+type captureStore struct {
+	mu         sync.Mutex
+	dir        string
+	ttl        time.Duration
+	maxBytes   int64
+	totalBytes int64
+	captures   map[string]*Capture // In-memory store only
+	order      []storedCapture     // Oldest first
+	sweepOnce  sync.Once
+}
+
+var captures = &captureStore{
+	ttl:      24 * time.Hour,
+	maxBytes: defaultCaptureMaxBytes,
+	captures: make(map[string]*Capture),
+}
+
+// ConfigureCaptureStore sets where captures are kept, for how long and how much space they
+// may use. With an empty dir captures are kept in memory and lost on restart. A zero ttl or
+// maxBytes keeps the default. Expired captures are swept periodically.
+func ConfigureCaptureStore(dir string, ttl time.Duration, maxBytes int64) {
+	captures.mu.Lock()
+	if ttl > 0 {
+		captures.ttl = ttl
+	}
+	if maxBytes > 0 {
+		captures.maxBytes = maxBytes
+	}
+	if dir != "" {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			log.Printf("ERROR: Could not create capture directory %s: %v. Captures will be kept in memory.", dir, err)
+		} else {
+			captures.dir = dir
+			captures.scanDirLocked()
+			log.Printf("Captures will be stored in %s for %s (up to %d MB)", dir, captures.ttl, captures.maxBytes>>20)
+		}
+	}
+	captures.mu.Unlock()
+
+	captures.sweepOnce.Do(func() { go captures.sweepLoop() })
+}
+
+func newCaptureID() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return fmt.Sprintf("%032x", time.Now().UnixNano())
+	}
+	return hex.EncodeToString(b)
+}
+
+// SaveCapture stores a fetch result and returns its capture ID.
+func SaveCapture(requestURL string, fetchResult *FetchResult) (string, error) {
+	if len(fetchResult.Body) > captureMaxBodyBytes {
+		return "", fmt.Errorf("response body of %d bytes exceeds the %d byte capture limit", len(fetchResult.Body), captureMaxBodyBytes)
+	}
+	capture := &Capture{
+		ID:         newCaptureID(),
+		RequestURL: requestURL,
+		CapturedAt: time.Now().UTC(),
+		StatusCode: fetchResult.StatusCode,
+		Status:     fetchResult.Status,
+		Headers:    fetchResult.Headers,
+		Body:       fetchResult.Body,
+		FinalURL:   fetchResult.FinalURL,
+
+		CurlCommand: fetchResult.CurlCommand,
+	}
+
+	captures.mu.Lock()
+	defer captures.mu.Unlock()
+
+	size := int64(len(capture.Body))
+	if captures.dir != "" {
+		data, err := json.Marshal(capture)
+		if err != nil {
+			return "", fmt.Errorf("failed to encode capture: %w", err)
+		}
+		if err := os.WriteFile(captures.path(capture.ID), data, 0o644); err != nil {
+			return "", fmt.Errorf("failed to write capture: %w", err)
+		}
+		size = int64(len(data))
+	} else {
+		captures.captures[capture.ID] = capture
+	}
+	captures.order = append(captures.order, storedCapture{id: capture.ID, size: size, savedAt: capture.CapturedAt})
+	captures.totalBytes += size
+	captures.evictLocked()
+	return capture.ID, nil
+}
+
+// LoadCapture returns a stored capture by ID.
+func LoadCapture(id string) (*Capture, error) {
+	if !captureIDRegex.MatchString(id) {
+		return nil, fmt.Errorf("invalid capture ID: %s", id)
+	}
+
+	captures.mu.Lock()
+	defer captures.mu.Unlock()
+
+	var capture *Capture
+	if captures.dir != "" {
+		data, err := os.ReadFile(captures.path(id))
+		if err != nil {
+			return nil, fmt.Errorf("capture %s not found", id)
+		}
+		capture = &Capture{}
+		if err := json.Unmarshal(data, capture); err != nil {
+			return nil, fmt.Errorf("capture %s is corrupt: %w", id, err)
+		}
+	} else {
+		var ok bool
+		if capture, ok = captures.captures[id]; !ok {
+			return nil, fmt.Errorf("capture %s not found", id)
+		}
+	}
+	if time.Since(capture.CapturedAt) > captures.ttl {
+		captures.removeLocked(id)
+		return nil, fmt.Errorf("capture %s has expired", id)
+	}
+	return capture, nil
+}
+
+func (s *captureStore) path(id string) string {
+	return filepath.Join(s.dir, id+".json")
+}
+
+// removeLocked deletes one capture and drops it from the index.
+func (s *captureStore) removeLocked(id string) {
+	for i, entry := range s.order {
+		if entry.id == id {
+			s.totalBytes -= entry.size
+			s.order = append(s.order[:i], s.order[i+1:]...)
+			break
+		}
+	}
+	if s.dir != "" {
+		os.Remove(s.path(id))
+	} else {
+		delete(s.captures, id)
+	}
+}
+
+// evictLocked removes the oldest captures until the store is within its limits.
+func (s *captureStore) evictLocked() {
+	for len(s.order) > 0 && (s.totalBytes > s.maxBytes || (s.dir == "" && len(s.order) > captureMaxMemoryEntries)) {
+		s.removeLocked(s.order[0].id)
+	}
+}
+
+// expireLocked removes the captures older than the TTL.
+func (s *captureStore) expireLocked(now time.Time) {
+	for len(s.order) > 0 && now.Sub(s.order[0].savedAt) > s.ttl {
+		s.removeLocked(s.order[0].id)
+	}
+}
+
+// scanDirLocked rebuilds the index from the capture directory, which may hold captures from
+// earlier runs (or other instances sharing it), deleting the expired ones.
+func (s *captureStore) scanDirLocked() {
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		log.Printf("WARN: Could not read capture directory %s: %v", s.dir, err)
+		return
+	}
+	s.order, s.totalBytes = nil, 0
+	for _, entry := range entries {
+		id, ok := strings.CutSuffix(entry.Name(), ".json")
+		if !ok || !captureIDRegex.MatchString(id) {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		s.order = append(s.order, storedCapture{id: id, size: info.Size(), savedAt: info.ModTime()})
+		s.totalBytes += info.Size()
+	}
+	sort.Slice(s.order, func(i, j int) bool { return s.order[i].savedAt.Before(s.order[j].savedAt) })
+	s.expireLocked(time.Now())
+	s.evictLocked()
+}
+
+// sweepLoop periodically deletes expired captures, so the store does not rely on reads to
+// clean up after itself.
+func (s *captureStore) sweepLoop() {
+	for lifecycle.Sleep(captureSweepInterval) {
+		s.mu.Lock()
+		if s.dir != "" {
+			s.scanDirLocked()
+		} else {
+			s.expireLocked(time.Now())
+		}
+		s.mu.Unlock()
+	}
+}
+
+// FetchResult returns the capture as a fetch result, so analyses can run on it unchanged.
+func (c *Capture) FetchResult() *FetchResult {
+	return &FetchResult{
+		StatusCode: c.StatusCode,
+		Status:     c.Status,
+		Headers:    c.Headers,
+		Body:       c.Body,
+		FinalURL:   c.FinalURL,
+
+		CurlCommand: c.CurlCommand,
+	}
+}
+
+// FetchOrReplay returns the page to analyse: the stored capture when captureID is set,
+// otherwise a fresh fetch of targetURL that is stored as a new capture.
+// It returns the request URL (taken from the capture on replay) and the capture ID.
+func FetchOrReplay(ctx context.Context, targetURL, captureID string) (*FetchResult, string, string, error) {
+	return FetchOrReplayWithOptions(ctx, targetURL, captureID, FetchOptions{})
+}
+
+// FetchOrReplayWithOptions is FetchOrReplay with the fetch adjusted by options. Responses to
+// other methods than GET and truncated bodies are not stored as captures.
+func FetchOrReplayWithOptions(ctx context.Context, targetURL, captureID string, options FetchOptions) (*FetchResult, string, string, error) {
+	if captureID != "" {
+		capture, err := LoadCapture(captureID)
+		if err != nil {
+			return nil, targetURL, "", err
+		}
+		TimingRecorderFrom(ctx).MarkCacheHit()
+		return capture.FetchResult(), capture.RequestURL, capture.ID, nil
+	}
+
+	fetchResult, err := FetchURLWithOptions(ctx, targetURL, options)
+	if err != nil {
+		return fetchResult, targetURL, "", err
+	}
+	if (options.Method != "" && options.Method != http.MethodGet) || fetchResult.Truncated {
+		return fetchResult, targetURL, "", nil
+	}
+	id, err := SaveCapture(targetURL, fetchResult)
+	if err != nil {
+		log.Printf("Warning: could not store capture for %s: %v", targetURL, err)
+	}
+	return fetchResult, targetURL, id, nil
+}
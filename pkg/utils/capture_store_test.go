@@ -0,0 +1,127 @@
+package utils
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// useCaptureStore replaces the package store for the duration of the test.
+func useCaptureStore(t *testing.T, store *captureStore) {
+	t.Helper()
+	previous := captures
+	store.captures = make(map[string]*Capture)
+	captures = store
+	t.Cleanup(func() { captures = previous })
+}
+
+func fetchResultOfSize(n int) *FetchResult {
+	return &FetchResult{StatusCode: 200, Status: "200 OK", Body: bytes.Repeat([]byte("a"), n), FinalURL: "https://example.com/"}
+}
+
+func TestSaveCaptureRejectsOversizedBodies(t *testing.T) {
+	useCaptureStore(t, &captureStore{ttl: time.Hour, maxBytes: defaultCaptureMaxBytes})
+
+	if _, err := SaveCapture("https://example.com/", fetchResultOfSize(captureMaxBodyBytes+1)); err == nil {
+		t.Error("SaveCapture() stored a body over the per-capture limit")
+	}
+	if _, err := SaveCapture("https://example.com/", fetchResultOfSize(captureMaxBodyBytes)); err != nil {
+		t.Errorf("SaveCapture() error = %v for a body at the limit", err)
+	}
+}
+
+func TestMemoryCaptureStoreEvictsOldestOverBudget(t *testing.T) {
+	useCaptureStore(t, &captureStore{ttl: time.Hour, maxBytes: 250})
+
+	var ids []string
+	for i := 0; i < 3; i++ {
+		id, err := SaveCapture("https://example.com/", fetchResultOfSize(100))
+		if err != nil {
+			t.Fatalf("SaveCapture() error = %v", err)
+		}
+		ids = append(ids, id)
+	}
+	if _, err := LoadCapture(ids[0]); err == nil {
+		t.Error("oldest capture is still loadable after the budget was exceeded")
+	}
+	for _, id := range ids[1:] {
+		if _, err := LoadCapture(id); err != nil {
+			t.Errorf("LoadCapture(%s) error = %v", id, err)
+		}
+	}
+	if captures.totalBytes != 200 {
+		t.Errorf("totalBytes = %d, want 200", captures.totalBytes)
+	}
+}
+
+func TestMemoryCaptureStoreExpiry(t *testing.T) {
+	useCaptureStore(t, &captureStore{ttl: time.Hour, maxBytes: defaultCaptureMaxBytes})
+
+	id, err := SaveCapture("https://example.com/", fetchResultOfSize(10))
+	if err != nil {
+		t.Fatal(err)
+	}
+	captures.expireLocked(time.Now().Add(2 * time.Hour))
+	if len(captures.captures) != 0 || len(captures.order) != 0 || captures.totalBytes != 0 {
+		t.Errorf("expired capture %s was not swept: %d captures, %d indexed, %d bytes", id, len(captures.captures), len(captures.order), captures.totalBytes)
+	}
+}
+
+func TestDiskCaptureStoreSweepsDirectory(t *testing.T) {
+	dir := t.TempDir()
+	useCaptureStore(t, &captureStore{ttl: time.Hour, maxBytes: defaultCaptureMaxBytes, dir: dir})
+
+	fresh, err := SaveCapture("https://example.com/", fetchResultOfSize(10))
+	if err != nil {
+		t.Fatal(err)
+	}
+	stale, err := SaveCapture("https://example.com/", fetchResultOfSize(10))
+	if err != nil {
+		t.Fatal(err)
+	}
+	old := time.Now().Add(-2 * time.Hour)
+	if err := os.Chtimes(filepath.Join(dir, stale+".json"), old, old); err != nil {
+		t.Fatal(err)
+	}
+	// Files that are not captures are left alone.
+	if err := os.WriteFile(filepath.Join(dir, "notes.txt"), []byte("keep"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	captures.scanDirLocked()
+
+	if _, err := os.Stat(filepath.Join(dir, stale+".json")); !os.IsNotExist(err) {
+		t.Error("expired capture file was not deleted by the sweep")
+	}
+	if _, err := os.Stat(filepath.Join(dir, "notes.txt")); err != nil {
+		t.Error("sweep deleted a file that is not a capture")
+	}
+	if _, err := LoadCapture(fresh); err != nil {
+		t.Errorf("LoadCapture(%s) error = %v", fresh, err)
+	}
+	if len(captures.order) != 1 {
+		t.Errorf("index holds %d captures, want 1", len(captures.order))
+	}
+}
+
+func TestDiskCaptureStoreEvictsOverBudget(t *testing.T) {
+	dir := t.TempDir()
+	useCaptureStore(t, &captureStore{ttl: time.Hour, maxBytes: 1 << 20, dir: dir}) // Bodies are base64 encoded on disk
+
+	first, err := SaveCapture("https://example.com/", fetchResultOfSize(500<<10))
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := SaveCapture("https://example.com/", fetchResultOfSize(500<<10))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, first+".json")); !os.IsNotExist(err) {
+		t.Error("oldest capture file was not evicted when the budget was exceeded")
+	}
+	if _, err := LoadCapture(second); err != nil {
+		t.Errorf("LoadCapture(%s) error = %v", second, err)
+	}
+}
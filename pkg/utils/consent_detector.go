@@ -0,0 +1,229 @@
+package utils
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// consentSignature identifies a consent management platform or tracker by substrings
+// found in script sources, inline scripts or (for CMPs) anywhere in the page.
+type consentSignature struct {
+	Name     string
+	Category string
+	Patterns []string
+}
+
+// cmpSignatures lists well-known consent management platforms.
+var cmpSignatures = []consentSignature{
+	{Name: "OneTrust", Patterns: []string{"cdn.cookielaw.org", "optanon.blob.core.windows.net", "otsdkstub.js", "onetrust-banner-sdk"}},
+	{Name: "Cookiebot", Patterns: []string{"consent.cookiebot.com", "consentcdn.cookiebot.com"}},
+	{Name: "Didomi", Patterns: []string{"sdk.privacy-center.org", "didomi-host"}},
+	{Name: "Usercentrics", Patterns: []string{"app.usercentrics.eu", "web.cmp.usercentrics.eu"}},
+	{Name: "Quantcast Choice", Patterns: []string{"cmp.quantcast.com", "quantcast.mgr.consensu.org"}},
+	{Name: "TrustArc", Patterns: []string{"consent.trustarc.com", "consent-pref.trustarc.com"}},
+	{Name: "Osano", Patterns: []string{"cmp.osano.com"}},
+	{Name: "iubenda", Patterns: []string{"cdn.iubenda.com/cs/"}},
+	{Name: "CookieYes", Patterns: []string{"cdn-cookieyes.com"}},
+	{Name: "Complianz", Patterns: []string{"complianz-gdpr", "cmplz-cookiebanner"}},
+	{Name: "Termly", Patterns: []string{"app.termly.io"}},
+	{Name: "Sourcepoint", Patterns: []string{"cdn.privacy-mgmt.com", "wrapperMessagingWithoutDetection.js"}},
+	{Name: "Klaro", Patterns: []string{"klaro.js", "klaro-no-css.js"}},
+	{Name: "Cookie Information", Patterns: []string{"policy.app.cookieinformation.com"}},
+}
+
+// trackerSignatures lists common tracking scripts that normally require consent.
+var trackerSignatures = []consentSignature{
+	{Name: "Google Analytics", Category: "analytics", Patterns: []string{"google-analytics.com/analytics.js", "google-analytics.com/ga.js", "googletagmanager.com/gtag/js"}},
+	{Name: "Google Tag Manager", Category: "tag-manager", Patterns: []string{"googletagmanager.com/gtm.js"}},
+	{Name: "Google Ads / DoubleClick", Category: "advertising", Patterns: []string{"googleadservices.com", "doubleclick.net", "googlesyndication.com"}},
+	{Name: "Meta Pixel", Category: "advertising", Patterns: []string{"connect.facebook.net"}},
+	{Name: "LinkedIn Insight Tag", Category: "advertising", Patterns: []string{"snap.licdn.com"}},
+	{Name: "TikTok Pixel", Category: "advertising", Patterns: []string{"analytics.tiktok.com"}},
+	{Name: "X (Twitter) Pixel", Category: "advertising", Patterns: []string{"static.ads-twitter.com"}},
+	{Name: "Microsoft UET", Category: "advertising", Patterns: []string{"bat.bing.com"}},
+	{Name: "Microsoft Clarity", Category: "analytics", Patterns: []string{"clarity.ms/tag"}},
+	{Name: "Hotjar", Category: "analytics", Patterns: []string{"static.hotjar.com"}},
+	{Name: "Matomo", Category: "analytics", Patterns: []string{"matomo.js", "piwik.js"}},
+	{Name: "Segment", Category: "analytics", Patterns: []string{"cdn.segment.com"}},
+	{Name: "Mixpanel", Category: "analytics", Patterns: []string{"cdn.mxpnl.com", "mixpanel-2-latest"}},
+	{Name: "HubSpot", Category: "marketing", Patterns: []string{"js.hs-scripts.com", "js.hs-analytics.net"}},
+}
+
+// consentHeldSourceAttributes hold the source of a script that has no src until the CMP gets
+// consent: consentmanager's data-cmp-src and iubenda's data-suppressedsrc.
+var consentHeldSourceAttributes = []string{"data-cmp-src", "data-suppressedsrc"}
+
+// ConsentPlatform is a detected consent management platform.
+type ConsentPlatform struct {
+	Name     string `json:"name"`
+	Evidence string `json:"evidence"` // Script source or page marker that matched
+}
+
+// TrackerScript is a tracking script found in the page.
+type TrackerScript struct {
+	Name               string `json:"name"`
+	Category           string `json:"category"`
+	Source             string `json:"source"`               // Script src, or "inline"
+	Position           int    `json:"position"`             // Index of the script tag in document order
+	ConsentGated       bool   `json:"consent_gated"`        // Marked to wait for consent (type="text/plain", data-cookieconsent, ...)
+	LoadedBeforeCMP    bool   `json:"loaded_before_cmp"`    // Appears before the first CMP script
+	FiresBeforeConsent bool   `json:"fires_before_consent"` // Executable on page load without any consent gating
+}
+
+// ConsentAnalysis is the result of a consent banner / CMP check.
+type ConsentAnalysis struct {
+	FinalURL              string
+	DetectionMethod       string
+	Platforms             []ConsentPlatform
+	Trackers              []TrackerScript
+	TrackersBeforeConsent int
+	CurlCommand           string
+}
+
+func matchSignature(signatures []consentSignature, data string) (consentSignature, string, bool) {
+	lower := strings.ToLower(data)
+	for _, sig := range signatures {
+		for _, pattern := range sig.Patterns {
+			if strings.Contains(lower, strings.ToLower(pattern)) {
+				return sig, pattern, true
+			}
+		}
+	}
+	return consentSignature{}, "", false
+}
+
+// isConsentGated reports whether a script tag is prevented from executing until consent is
+// given. Only markers that block the script count: a type that is not JavaScript (the manual
+// mode of every CMP, e.g. type="text/plain" with OneTrust's optanon-category class), a source
+// held in a CMP attribute, or Cookiebot's data-cookieconsent, which its auto-blocking holds
+// back unless set to "ignore". Attributes that merely label scripts, such as data-category,
+// are left on scripts that run immediately and do not gate them.
+func isConsentGated(token html.Token) bool {
+	for _, attr := range token.Attr {
+		key := strings.ToLower(attr.Key)
+		val := strings.ToLower(strings.TrimSpace(attr.Val))
+		switch {
+		case key == "type" && val != "" && val != "text/javascript" && val != "application/javascript" && val != "module":
+			return true
+		case key == "data-cookieconsent" && val != "ignore":
+			return true
+		}
+		if slices.Contains(consentHeldSourceAttributes, key) {
+			return true
+		}
+	}
+	return false
+}
+
+// AnalyzeConsent fetches a page and detects consent management platforms and
+// tracking scripts that would execute before the visitor gives consent.
+// Detection is static: scripts injected at runtime (e.g. by a tag manager) are not seen.
+func AnalyzeConsent(ctx context.Context, targetURL string) (*ConsentAnalysis, error) {
+	fetchResult, err := FetchURL(ctx, targetURL)
+	if err != nil {
+		if fetchResult == nil { // The request could not even be built
+			return nil, err
+		}
+		return &ConsentAnalysis{CurlCommand: fetchResult.CurlCommand}, err
+	}
+	return AnalyzeFetchedConsent(targetURL, fetchResult)
+}
+
+// AnalyzeFetchedConsent runs the consent check on an already fetched (or captured) page.
+func AnalyzeFetchedConsent(targetURL string, fetchResult *FetchResult) (*ConsentAnalysis, error) {
+	analysis := &ConsentAnalysis{
+		CurlCommand:     fetchResult.CurlCommand,
+		FinalURL:        fetchResult.FinalURL,
+		DetectionMethod: "static",
+		Platforms:       []ConsentPlatform{},
+		Trackers:        []TrackerScript{},
+	}
+	if fetchResult.StatusCode != 200 {
+		return analysis, fmt.Errorf("failed to fetch %s: received status code %d (%s)", targetURL, fetchResult.StatusCode, fetchResult.Status)
+	}
+	body := DecodeResponseBody(fetchResult)
+
+	seenPlatforms := make(map[string]bool)
+	firstCMPPosition := -1
+	position := 0
+
+	tokenizer := html.NewTokenizer(bytes.NewReader(body))
+	for {
+		tt := tokenizer.Next()
+		if tt == html.ErrorToken {
+			break
+		}
+		if tt != html.StartTagToken {
+			continue
+		}
+		token := tokenizer.Token()
+		if token.Data != "script" {
+			continue
+		}
+
+		src := ""
+		for _, attr := range token.Attr {
+			if attr.Key == "src" || (src == "" && slices.Contains(consentHeldSourceAttributes, attr.Key)) {
+				src = attr.Val
+			}
+		}
+		content := src
+		if src == "" && tokenizer.Next() == html.TextToken {
+			content = string(tokenizer.Text())
+		}
+
+		if sig, pattern, ok := matchSignature(cmpSignatures, content); ok {
+			if firstCMPPosition < 0 {
+				firstCMPPosition = position
+			}
+			if !seenPlatforms[sig.Name] {
+				seenPlatforms[sig.Name] = true
+				evidence := src
+				if evidence == "" {
+					evidence = "inline script: " + pattern
+				}
+				analysis.Platforms = append(analysis.Platforms, ConsentPlatform{Name: sig.Name, Evidence: evidence})
+			}
+		} else if sig, _, ok := matchSignature(trackerSignatures, content); ok {
+			source := src
+			if source == "" {
+				source = "inline"
+			}
+			analysis.Trackers = append(analysis.Trackers, TrackerScript{
+				Name:         sig.Name,
+				Category:     sig.Category,
+				Source:       source,
+				Position:     position,
+				ConsentGated: isConsentGated(token),
+			})
+		}
+		position++
+	}
+
+	// CMPs are sometimes bootstrapped from markup rather than a script tag.
+	for _, sig := range cmpSignatures {
+		if seenPlatforms[sig.Name] {
+			continue
+		}
+		if _, pattern, ok := matchSignature([]consentSignature{sig}, string(body)); ok {
+			seenPlatforms[sig.Name] = true
+			analysis.Platforms = append(analysis.Platforms, ConsentPlatform{Name: sig.Name, Evidence: "page marker: " + pattern})
+		}
+	}
+
+	for i := range analysis.Trackers {
+		tracker := &analysis.Trackers[i]
+		tracker.LoadedBeforeCMP = firstCMPPosition < 0 || tracker.Position < firstCMPPosition
+		tracker.FiresBeforeConsent = !tracker.ConsentGated
+		if tracker.FiresBeforeConsent {
+			analysis.TrackersBeforeConsent++
+		}
+	}
+
+	return analysis, nil
+}
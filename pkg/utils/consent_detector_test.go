@@ -0,0 +1,98 @@
+package utils
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"reflect"
+	"testing"
+)
+
+func TestAnalyzeConsentInvalidURL(t *testing.T) {
+	analysis, err := AnalyzeConsent(context.Background(), "http://[::1")
+	if err == nil {
+		t.Fatal("AnalyzeConsent() error = nil, want an invalid URL error")
+	}
+	if analysis != nil {
+		t.Errorf("AnalyzeConsent() analysis = %+v, want nil", analysis)
+	}
+}
+
+func TestAnalyzeFetchedConsent(t *testing.T) {
+	// tracker is the part of a TrackerScript a fixture determines.
+	type tracker struct {
+		Name            string
+		Gated           bool
+		LoadedBeforeCMP bool
+	}
+	tests := []struct {
+		fixture       string
+		platforms     []string
+		trackers      []tracker
+		beforeConsent int
+	}{
+		{"onetrust", []string{"OneTrust"}, []tracker{
+			{"Google Analytics", true, false}, // type="text/plain" with the OneTrust category class
+			{"Meta Pixel", false, false},
+		}, 1},
+		{"cookiebot", []string{"Cookiebot"}, []tracker{
+			{"Google Analytics", false, true},
+			{"Hotjar", true, false},
+			{"LinkedIn Insight Tag", false, false}, // data-cookieconsent="ignore" opts out of blocking
+		}, 2},
+		{"labelled", []string{"Usercentrics"}, []tracker{
+			{"Google Analytics", false, false}, // data-category and data-service only label it
+			{"Microsoft UET", false, false},
+			{"Meta Pixel", true, false}, // Source held in data-cmp-src
+		}, 2},
+		{"no_cmp", []string{}, []tracker{
+			{"Google Tag Manager", false, true}, // Inline snippet
+			{"Hotjar", false, true},
+		}, 2},
+		{"markup_cmp", []string{"Didomi"}, []tracker{
+			{"Segment", false, true}, // No CMP script, so nothing is loaded after it
+		}, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.fixture, func(t *testing.T) {
+			page, err := os.ReadFile("testdata/consent/" + tt.fixture + ".html")
+			if err != nil {
+				t.Fatal(err)
+			}
+			analysis, err := AnalyzeFetchedConsent("https://www.example.test/", &FetchResult{
+				StatusCode: http.StatusOK,
+				Headers:    http.Header{"Content-Type": {"text/html; charset=utf-8"}},
+				Body:       page,
+				FinalURL:   "https://www.example.test/",
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			platforms := []string{}
+			for _, platform := range analysis.Platforms {
+				platforms = append(platforms, platform.Name)
+			}
+			if !reflect.DeepEqual(platforms, tt.platforms) {
+				t.Errorf("platforms = %v, want %v", platforms, tt.platforms)
+			}
+			var trackers []tracker
+			for _, script := range analysis.Trackers {
+				trackers = append(trackers, tracker{script.Name, script.ConsentGated, script.LoadedBeforeCMP})
+				if script.FiresBeforeConsent == script.ConsentGated {
+					t.Errorf("%s: fires before consent %v, gated %v", script.Name, script.FiresBeforeConsent, script.ConsentGated)
+				}
+			}
+			if !reflect.DeepEqual(trackers, tt.trackers) {
+				t.Errorf("trackers = %+v, want %+v", trackers, tt.trackers)
+			}
+			if analysis.TrackersBeforeConsent != tt.beforeConsent {
+				t.Errorf("trackers before consent = %d, want %d", analysis.TrackersBeforeConsent, tt.beforeConsent)
+			}
+		})
+	}
+
+	analysis, err := AnalyzeFetchedConsent("https://www.example.test/", &FetchResult{StatusCode: http.StatusNotFound, Status: "404 Not Found", Headers: http.Header{}})
+	if err == nil || analysis == nil {
+		t.Errorf("404 page: analysis %+v, error %v; want an analysis and an error", analysis, err)
+	}
+}
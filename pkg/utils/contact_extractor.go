@@ -0,0 +1,519 @@
+package utils
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"fmt"
+	"net/mail"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/net/html"
+)
+
+// SocialProfile is a normalized social media profile link found on a page.
+type SocialProfile struct {
+	Platform string `json:"platform"` // x, linkedin, facebook, instagram, github, youtube
+	Handle   string `json:"handle"`
+	URL      string `json:"url"` // Canonical profile URL
+}
+
+// ContactDetail is a validated email or phone number together with where it was found.
+type ContactDetail struct {
+	Type        string `json:"type"`                  // "email" or "phone"
+	Value       string `json:"value"`                 // Normalized value
+	Source      string `json:"source"`                // mailto, tel, text, script or cloudflare
+	Obfuscation string `json:"obfuscation,omitempty"` // html-entities, at-dot, js-concat or cloudflare-xor
+	Element     string `json:"element"`               // Tag the contact was found in (e.g. "a", "p", "script")
+	Context     string `json:"context,omitempty"`     // Surrounding text or attribute snippet
+}
+
+// ExtractedContacts holds everything the extraction module found on a page.
+type ExtractedContacts struct {
+	FinalURL       string
+	SocialProfiles []SocialProfile
+	Emails         []string
+	Phones         []string
+	Contacts       []ContactDetail // One entry per distinct email/phone, from its first occurrence
+	CurlCommand    string
+}
+
+// socialPlatform describes how to recognise and normalise profile links for one network.
+type socialPlatform struct {
+	Name          string
+	Hosts         []string
+	CanonicalHost string
+	// PathPrefixes are the path segments that introduce a handle (e.g. "in" for LinkedIn).
+	// An empty list means the first path segment is the handle.
+	PathPrefixes  []string
+	Reserved      map[string]bool // First path segments that are not profiles (share, intent, ...)
+	CaseSensitive bool
+}
+
+func reservedSet(words ...string) map[string]bool {
+	set := make(map[string]bool, len(words))
+	for _, w := range words {
+		set[w] = true
+	}
+	return set
+}
+
+var socialPlatforms = []socialPlatform{
+	{
+		Name: "x", Hosts: []string{"twitter.com", "x.com"}, CanonicalHost: "x.com",
+		Reserved: reservedSet("intent", "share", "home", "search", "hashtag", "i", "login", "signup", "explore", "settings", "privacy", "tos", "messages", "notifications"),
+	},
+	{
+		Name: "linkedin", Hosts: []string{"linkedin.com"}, CanonicalHost: "www.linkedin.com",
+		PathPrefixes: []string{"in", "company", "school", "showcase"},
+	},
+	{
+		Name: "facebook", Hosts: []string{"facebook.com", "fb.com"}, CanonicalHost: "www.facebook.com",
+		Reserved: reservedSet("sharer", "sharer.php", "share.php", "share", "dialog", "plugins", "tr", "login", "login.php", "events", "groups", "watch", "help", "policies", "privacy"),
+	},
+	{
+		Name: "instagram", Hosts: []string{"instagram.com"}, CanonicalHost: "www.instagram.com",
+		Reserved: reservedSet("p", "reel", "reels", "explore", "accounts", "stories", "about", "legal", "tv"),
+	},
+	{
+		Name: "github", Hosts: []string{"github.com"}, CanonicalHost: "github.com",
+		Reserved: reservedSet("features", "pricing", "login", "join", "about", "topics", "marketplace", "explore", "sponsors", "settings", "site", "security", "enterprise", "collections", "trending"),
+	},
+	{
+		Name: "youtube", Hosts: []string{"youtube.com"}, CanonicalHost: "www.youtube.com",
+		PathPrefixes: []string{"channel", "c", "user"}, CaseSensitive: true,
+	},
+}
+
+var (
+	emailTextRegex = regexp.MustCompile(`[A-Za-z0-9._%+\-]+@[A-Za-z0-9.\-]+\.[A-Za-z]{2,}`)
+	handleRegex    = regexp.MustCompile(`^@?[A-Za-z0-9._\-]{1,100}$`)
+
+	// Obfuscation patterns: "name [at] domain [dot] com", "name(at)domain(dot)com", "name at domain dot com".
+	bracketedAtRegex  = regexp.MustCompile(`(?i)\s*[\[\(\{<]\s*(?:at|@)\s*[\]\)\}>]\s*`)
+	bracketedDotRegex = regexp.MustCompile(`(?i)\s*[\[\(\{<]\s*(?:dot|\.)\s*[\]\)\}>]\s*`)
+	spelledEmailRegex = regexp.MustCompile(`(?i)\b([a-z0-9._%+\-]+)\s+at\s+([a-z0-9\-]+(?:\s+dot\s+[a-z0-9\-]+)+)\b`)
+	spelledDotRegex   = regexp.MustCompile(`(?i)\s+dot\s+`)
+	// JavaScript string concatenation ('info' + '@' + 'example.com') and escaped at-signs.
+	jsConcatRegex    = regexp.MustCompile(`["']\s*\+\s*["']`)
+	jsEscapedAtRegex = regexp.MustCompile(`\\x40|\\u0040`)
+)
+
+// contextRadius is how many bytes of surrounding text are reported with each contact,
+// widened to the nearest character boundary.
+const contextRadius = 40
+
+// voidElements never have an end tag, so they are not tracked as open elements.
+var voidElements = map[string]bool{
+	"area": true, "base": true, "br": true, "col": true, "embed": true, "hr": true, "img": true,
+	"input": true, "link": true, "meta": true, "param": true, "source": true, "track": true, "wbr": true,
+}
+
+// matchSocialPlatform returns the platform for a host, ignoring "www." and "m." prefixes.
+func matchSocialPlatform(host string) (socialPlatform, bool) {
+	host = strings.ToLower(host)
+	host = strings.TrimPrefix(host, "www.")
+	host = strings.TrimPrefix(host, "m.")
+	host = strings.TrimPrefix(host, "mobile.")
+	for _, platform := range socialPlatforms {
+		for _, h := range platform.Hosts {
+			if host == h {
+				return platform, true
+			}
+		}
+	}
+	return socialPlatform{}, false
+}
+
+// NormalizeSocialProfileURL turns a link into a canonical social profile, or returns false
+// if the link is not a profile (share buttons, posts, login pages, ...).
+func NormalizeSocialProfileURL(rawURL string) (SocialProfile, bool) {
+	parsed, err := url.Parse(strings.TrimSpace(rawURL))
+	if err != nil || parsed.Host == "" {
+		return SocialProfile{}, false
+	}
+	platform, ok := matchSocialPlatform(parsed.Hostname())
+	if !ok {
+		return SocialProfile{}, false
+	}
+
+	var segments []string
+	for _, seg := range strings.Split(parsed.Path, "/") {
+		if seg != "" {
+			segments = append(segments, seg)
+		}
+	}
+
+	// Facebook numeric profiles live at /profile.php?id=...
+	if platform.Name == "facebook" && len(segments) == 1 && segments[0] == "profile.php" {
+		id := parsed.Query().Get("id")
+		if id == "" {
+			return SocialProfile{}, false
+		}
+		return SocialProfile{Platform: platform.Name, Handle: id, URL: "https://" + platform.CanonicalHost + "/profile.php?id=" + id}, true
+	}
+
+	var handlePath string
+	switch {
+	case len(segments) == 0:
+		return SocialProfile{}, false
+	case platform.Name == "youtube" && strings.HasPrefix(segments[0], "@"):
+		handlePath = strings.ToLower(segments[0])
+	case len(platform.PathPrefixes) > 0:
+		if len(segments) < 2 {
+			return SocialProfile{}, false
+		}
+		prefix := strings.ToLower(segments[0])
+		found := false
+		for _, p := range platform.PathPrefixes {
+			if prefix == p {
+				found = true
+				break
+			}
+		}
+		if !found || !handleRegex.MatchString(segments[1]) {
+			return SocialProfile{}, false
+		}
+		handle := segments[1]
+		if !platform.CaseSensitive {
+			handle = strings.ToLower(handle)
+		}
+		handlePath = prefix + "/" + handle
+	default:
+		first := segments[0]
+		if platform.Name == "github" && strings.ToLower(first) == "orgs" && len(segments) > 1 {
+			first = segments[1]
+		}
+		if platform.Reserved[strings.ToLower(first)] || !handleRegex.MatchString(first) || strings.HasPrefix(first, "@") && platform.Name != "x" {
+			return SocialProfile{}, false
+		}
+		handlePath = strings.ToLower(strings.TrimPrefix(first, "@"))
+	}
+
+	return SocialProfile{
+		Platform: platform.Name,
+		Handle:   handlePath,
+		URL:      "https://" + platform.CanonicalHost + "/" + handlePath,
+	}, true
+}
+
+// NormalizeEmail validates an address and returns it lowercased.
+func NormalizeEmail(raw string) (string, bool) {
+	raw = strings.TrimSpace(strings.Trim(raw, ".,;:<>()[]\"'"))
+	addr, err := mail.ParseAddress(raw)
+	if err != nil || addr.Address != raw {
+		return "", false
+	}
+	at := strings.LastIndex(raw, "@")
+	domainPart := raw[at+1:]
+	if !strings.Contains(domainPart, ".") || strings.HasPrefix(domainPart, ".") || strings.HasSuffix(domainPart, ".") {
+		return "", false
+	}
+	// Asset names such as logo@2x.png look like addresses but are not.
+	for _, ext := range []string{".png", ".jpg", ".jpeg", ".gif", ".svg", ".webp", ".css", ".js"} {
+		if strings.HasSuffix(strings.ToLower(domainPart), ext) {
+			return "", false
+		}
+	}
+	return strings.ToLower(raw), true
+}
+
+// NormalizePhone keeps the leading "+" and digits, and validates the E.164 length (7-15 digits).
+func NormalizePhone(raw string) (string, bool) {
+	var b strings.Builder
+	raw = strings.TrimSpace(raw)
+	if strings.HasPrefix(raw, "+") {
+		b.WriteByte('+')
+	}
+	digits := 0
+	for _, r := range raw {
+		if r >= '0' && r <= '9' {
+			b.WriteRune(r)
+			digits++
+		}
+	}
+	if digits < 7 || digits > 15 {
+		return "", false
+	}
+	return b.String(), true
+}
+
+// contactCollector deduplicates extraction results while preserving discovery order.
+type contactCollector struct {
+	profiles     []SocialProfile
+	emails       []string
+	phones       []string
+	contacts     []ContactDetail
+	seenProfiles map[string]bool
+	seenEmails   map[string]bool
+	seenPhones   map[string]bool
+}
+
+func newContactCollector() *contactCollector {
+	return &contactCollector{
+		seenProfiles: make(map[string]bool),
+		seenEmails:   make(map[string]bool),
+		seenPhones:   make(map[string]bool),
+	}
+}
+
+func (cc *contactCollector) addProfile(p SocialProfile) {
+	if !cc.seenProfiles[p.URL] {
+		cc.seenProfiles[p.URL] = true
+		cc.profiles = append(cc.profiles, p)
+	}
+}
+
+func (cc *contactCollector) addEmail(raw string, detail ContactDetail) {
+	if email, ok := NormalizeEmail(raw); ok && !cc.seenEmails[email] {
+		cc.seenEmails[email] = true
+		cc.emails = append(cc.emails, email)
+		detail.Type = "email"
+		detail.Value = email
+		cc.contacts = append(cc.contacts, detail)
+	}
+}
+
+func (cc *contactCollector) addPhone(raw string, detail ContactDetail) {
+	if phone, ok := NormalizePhone(raw); ok && !cc.seenPhones[phone] {
+		cc.seenPhones[phone] = true
+		cc.phones = append(cc.phones, phone)
+		detail.Type = "phone"
+		detail.Value = phone
+		cc.contacts = append(cc.contacts, detail)
+	}
+}
+
+// snippet returns the text around match, collapsed to single spaces and trimmed to contextRadius on each side.
+func snippet(text, match string) string {
+	text = strings.Join(strings.Fields(text), " ")
+	i := strings.Index(text, match)
+	if i < 0 {
+		if len(text) > 2*contextRadius {
+			return text[:runeBoundaryAfter(text, 2*contextRadius)] + "..."
+		}
+		return text
+	}
+	start, end := i-contextRadius, i+len(match)+contextRadius
+	prefix, suffix := "...", "..."
+	if start <= 0 {
+		start, prefix = 0, ""
+	}
+	if end >= len(text) {
+		end, suffix = len(text), ""
+	}
+	return prefix + text[runeBoundaryBefore(text, start):runeBoundaryAfter(text, end)] + suffix
+}
+
+// runeBoundaryBefore moves i back to the start of the character it falls in.
+func runeBoundaryBefore(text string, i int) int {
+	for i > 0 && i < len(text) && !utf8.RuneStart(text[i]) {
+		i--
+	}
+	return i
+}
+
+// runeBoundaryAfter moves i forward past the end of the character it falls in.
+func runeBoundaryAfter(text string, i int) int {
+	for i < len(text) && !utf8.RuneStart(text[i]) {
+		i++
+	}
+	return i
+}
+
+// deobfuscateEmailText rewrites "[at]"/"(dot)" and spelled-out "at ... dot" forms into plain addresses.
+func deobfuscateEmailText(text string) string {
+	text = bracketedAtRegex.ReplaceAllString(text, "@")
+	text = bracketedDotRegex.ReplaceAllString(text, ".")
+	return spelledEmailRegex.ReplaceAllStringFunc(text, func(m string) string {
+		parts := spelledEmailRegex.FindStringSubmatch(m)
+		return parts[1] + "@" + spelledDotRegex.ReplaceAllString(parts[2], ".")
+	})
+}
+
+// deobfuscateScript joins concatenated string literals and decodes escaped at-signs in inline JavaScript.
+func deobfuscateScript(script string) string {
+	script = jsConcatRegex.ReplaceAllString(script, "")
+	return jsEscapedAtRegex.ReplaceAllString(script, "@")
+}
+
+// decodeCloudflareEmail decodes Cloudflare's email protection encoding: a hex string whose
+// first byte is an XOR key for the remaining bytes.
+func decodeCloudflareEmail(encoded string) (string, bool) {
+	encoded = strings.TrimSpace(encoded)
+	if len(encoded) < 4 || len(encoded)%2 != 0 {
+		return "", false
+	}
+	decoded, err := hex.DecodeString(encoded)
+	if err != nil {
+		return "", false
+	}
+	key := decoded[0]
+	out := make([]byte, len(decoded)-1)
+	for i, b := range decoded[1:] {
+		out[i] = b ^ key
+	}
+	return string(out), true
+}
+
+// scanTextForEmails finds plain and obfuscated addresses in a block of text.
+// raw is the undecoded source of the text, used to tell entity-encoded addresses apart.
+func (cc *contactCollector) scanTextForEmails(text, raw, element, source string) {
+	for _, match := range emailTextRegex.FindAllString(text, -1) {
+		obfuscation := ""
+		if !strings.Contains(raw, match) {
+			obfuscation = "html-entities"
+		}
+		cc.addEmail(match, ContactDetail{Source: source, Obfuscation: obfuscation, Element: element, Context: snippet(text, match)})
+	}
+	deobfuscated := deobfuscateEmailText(text)
+	if deobfuscated == text {
+		return
+	}
+	for _, match := range emailTextRegex.FindAllString(deobfuscated, -1) {
+		if strings.Contains(text, match) {
+			continue
+		}
+		cc.addEmail(match, ContactDetail{Source: source, Obfuscation: "at-dot", Element: element, Context: snippet(text, strings.SplitN(match, "@", 2)[0])})
+	}
+}
+
+// ExtractContactsFromHTML collects social profiles, emails and phone numbers from an HTML document.
+// Phone numbers are only taken from tel: links, since free-text digit runs are too ambiguous.
+// Obfuscated addresses are decoded where this is possible without executing JavaScript.
+func ExtractContactsFromHTML(body []byte, baseURL string) *ExtractedContacts {
+	base, _ := url.Parse(baseURL)
+	cc := newContactCollector()
+
+	tokenizer := html.NewTokenizer(bytes.NewReader(body))
+	var openTags []string
+	currentElement := func() string {
+		if len(openTags) == 0 {
+			return "body"
+		}
+		return openTags[len(openTags)-1]
+	}
+
+	for {
+		tt := tokenizer.Next()
+		switch tt {
+		case html.ErrorToken:
+			sort.SliceStable(cc.profiles, func(i, j int) bool { return cc.profiles[i].Platform < cc.profiles[j].Platform })
+			return &ExtractedContacts{
+				FinalURL:       baseURL,
+				SocialProfiles: cc.profiles,
+				Emails:         cc.emails,
+				Phones:         cc.phones,
+				Contacts:       cc.contacts,
+			}
+		case html.StartTagToken, html.SelfClosingTagToken:
+			rawTag := string(tokenizer.Raw())
+			token := tokenizer.Token()
+			if tt == html.StartTagToken && !voidElements[token.Data] {
+				openTags = append(openTags, token.Data)
+			}
+			for _, attr := range token.Attr {
+				if attr.Key == "data-cfemail" {
+					if decoded, ok := decodeCloudflareEmail(attr.Val); ok {
+						cc.addEmail(decoded, ContactDetail{Source: "cloudflare", Obfuscation: "cloudflare-xor", Element: token.Data, Context: snippet(rawTag, attr.Val)})
+					}
+				}
+				if attr.Key != "href" || (token.Data != "a" && token.Data != "link") {
+					continue
+				}
+				href := strings.TrimSpace(attr.Val)
+				lower := strings.ToLower(href)
+				obfuscation := ""
+				if !strings.Contains(rawTag, href) {
+					obfuscation = "html-entities"
+				}
+				switch {
+				case strings.HasPrefix(lower, "mailto:"):
+					addr := href[len("mailto:"):]
+					if i := strings.Index(addr, "?"); i >= 0 {
+						addr = addr[:i]
+					}
+					if decoded, err := url.PathUnescape(addr); err == nil {
+						addr = decoded
+					}
+					for _, a := range strings.Split(addr, ",") {
+						cc.addEmail(a, ContactDetail{Source: "mailto", Obfuscation: obfuscation, Element: token.Data, Context: snippet(href, a)})
+					}
+				case strings.HasPrefix(lower, "tel:"):
+					number := href[len("tel:"):]
+					if decoded, err := url.PathUnescape(number); err == nil {
+						number = decoded
+					}
+					cc.addPhone(number, ContactDetail{Source: "tel", Obfuscation: obfuscation, Element: token.Data, Context: href})
+				case strings.Contains(lower, "/cdn-cgi/l/email-protection#"):
+					encoded := href[strings.Index(href, "#")+1:]
+					if decoded, ok := decodeCloudflareEmail(encoded); ok {
+						cc.addEmail(decoded, ContactDetail{Source: "cloudflare", Obfuscation: "cloudflare-xor", Element: token.Data, Context: href})
+					}
+				default:
+					resolved := href
+					if base != nil {
+						if ref, err := url.Parse(href); err == nil {
+							resolved = base.ResolveReference(ref).String()
+						}
+					}
+					if profile, ok := NormalizeSocialProfileURL(resolved); ok {
+						cc.addProfile(profile)
+					}
+				}
+			}
+		case html.EndTagToken:
+			name, _ := tokenizer.TagName()
+			for i := len(openTags) - 1; i >= 0; i-- {
+				if openTags[i] == string(name) {
+					openTags = openTags[:i]
+					break
+				}
+			}
+		case html.TextToken:
+			raw := string(tokenizer.Raw())
+			text := string(tokenizer.Text())
+			switch currentElement() {
+			case "style":
+				continue
+			case "script":
+				script := deobfuscateScript(raw)
+				for _, match := range emailTextRegex.FindAllString(script, -1) {
+					obfuscation := ""
+					if !strings.Contains(raw, match) {
+						obfuscation = "js-concat"
+					}
+					cc.addEmail(match, ContactDetail{Source: "script", Obfuscation: obfuscation, Element: "script", Context: snippet(script, match)})
+				}
+			default:
+				cc.scanTextForEmails(text, raw, currentElement(), "text")
+			}
+		}
+	}
+}
+
+// ExtractSocialLinks fetches a page and extracts social profiles and contact details from it.
+func ExtractSocialLinks(ctx context.Context, targetURL string) (*ExtractedContacts, error) {
+	fetchResult, err := FetchURL(ctx, targetURL)
+	if err != nil {
+		if fetchResult == nil { // The request could not even be built
+			return nil, err
+		}
+		return &ExtractedContacts{CurlCommand: fetchResult.CurlCommand}, err
+	}
+	return ExtractFetchedSocialLinks(targetURL, fetchResult)
+}
+
+// ExtractFetchedSocialLinks extracts social profiles and contacts from an already fetched (or captured) page.
+func ExtractFetchedSocialLinks(targetURL string, fetchResult *FetchResult) (*ExtractedContacts, error) {
+	if fetchResult.StatusCode != 200 {
+		return &ExtractedContacts{FinalURL: fetchResult.FinalURL, CurlCommand: fetchResult.CurlCommand}, fmt.Errorf("failed to fetch %s: received status code %d (%s)", targetURL, fetchResult.StatusCode, fetchResult.Status)
+	}
+	contacts := ExtractContactsFromHTML(DecodeResponseBody(fetchResult), fetchResult.FinalURL)
+	contacts.CurlCommand = fetchResult.CurlCommand
+	return contacts, nil
+}
@@ -0,0 +1,115 @@
+package utils
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestDeobfuscateEmailText(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"info [at] example [dot] com", "info@example.com"},
+		{"info(at)example(dot)co(dot)uk", "info@example.co.uk"},
+		{"sales {@} example {.} org", "sales@example.org"},
+		{"write to info at example dot com today", "write to info@example.com today"},
+		{"meet at noon", "meet at noon"},
+		{"plain@example.com", "plain@example.com"},
+	}
+	for _, tt := range tests {
+		if got := deobfuscateEmailText(tt.in); got != tt.want {
+			t.Errorf("deobfuscateEmailText(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestDeobfuscateScript(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{`var e = 'info' + '@' + 'example.com';`, `var e = 'info@example.com';`},
+		{`var e = "info\x40example.com";`, `var e = "info@example.com";`},
+		{`var e = "info@example.com";`, `var e = "info@example.com";`},
+	}
+	for _, tt := range tests {
+		if got := deobfuscateScript(tt.in); got != tt.want {
+			t.Errorf("deobfuscateScript(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestDecodeCloudflareEmail(t *testing.T) {
+	tests := []struct {
+		in     string
+		want   string
+		wantOK bool
+	}{
+		{"422b2c242d02273a232f322e276c212d2f", "info@example.com", true},
+		{"42", "", false},
+		{"422b2", "", false},
+		{"zz2b2c24", "", false},
+	}
+	for _, tt := range tests {
+		got, ok := decodeCloudflareEmail(tt.in)
+		if got != tt.want || ok != tt.wantOK {
+			t.Errorf("decodeCloudflareEmail(%q) = %q, %v; want %q, %v", tt.in, got, ok, tt.want, tt.wantOK)
+		}
+	}
+}
+
+func TestExtractContactsFromHTML(t *testing.T) {
+	body := `<html><body>
+<p>Mail <a href="mailto:&#105;nfo@example.com">us</a><br>or sales [at] example [dot] com</p>
+<div><img src="x.png"><span data-cfemail="422b2c242d02273a232f322e276c212d2f">[email protected]</span></div>
+<script>var e = 'press' + '@' + 'example.com';</script>
+<footer>support@example.org <a href="tel:+1-555-0100">call</a></footer>
+</body></html>`
+
+	want := map[string]ContactDetail{
+		"info@example.com":    {Source: "mailto", Obfuscation: "html-entities", Element: "a"},
+		"sales@example.com":   {Source: "text", Obfuscation: "at-dot", Element: "p"},
+		"press@example.com":   {Source: "script", Obfuscation: "js-concat", Element: "script"},
+		"support@example.org": {Source: "text", Element: "footer"},
+		"+15550100":           {Source: "tel", Element: "a"},
+	}
+	contacts := ExtractContactsFromHTML([]byte(body), "https://example.com/")
+	got := make(map[string]ContactDetail)
+	for _, c := range contacts.Contacts {
+		got[c.Value] = ContactDetail{Source: c.Source, Obfuscation: c.Obfuscation, Element: c.Element}
+	}
+	for value, detail := range want {
+		if got[value] != detail {
+			t.Errorf("contact %s = %+v, want %+v", value, got[value], detail)
+		}
+	}
+}
+
+func TestSnippetKeepsCharactersWhole(t *testing.T) {
+	text := strings.Repeat("é", 60) + " info@example.com " + strings.Repeat("ü", 60)
+	got := snippet(text, "info@example.com")
+	if !utf8.ValidString(got) {
+		t.Fatalf("snippet() returned invalid UTF-8: %q", got)
+	}
+	if !strings.Contains(got, "info@example.com") || !strings.HasPrefix(got, "...") || !strings.HasSuffix(got, "...") {
+		t.Errorf("snippet() = %q", got)
+	}
+
+	got = snippet(strings.Repeat("日本", 50), "missing")
+	if !utf8.ValidString(got) {
+		t.Errorf("snippet() without a match returned invalid UTF-8: %q", got)
+	}
+}
+
+func TestExtractSocialLinksInvalidURL(t *testing.T) {
+	contacts, err := ExtractSocialLinks(context.Background(), "http://[::1")
+	if err == nil {
+		t.Fatal("ExtractSocialLinks() error = nil, want an invalid URL error")
+	}
+	if contacts != nil {
+		t.Errorf("ExtractSocialLinks() contacts = %+v, want nil", contacts)
+	}
+}
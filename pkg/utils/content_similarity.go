@@ -0,0 +1,274 @@
+package utils
+
+import (
+	"bytes"
+	"hash/fnv"
+	"math"
+	"math/bits"
+	"sort"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// Verdicts of a content comparison.
+const (
+	SimilarityIdentical = "identical" // Byte for byte the same
+	SimilaritySimilar   = "similar"   // Dynamic parts changed, such as dates or tokens
+	SimilarityModified  = "modified"  // Same template with notably different content, or the reverse
+	SimilarityDifferent = "different" // Another page altogether, e.g. a defacement or parked domain
+)
+
+const (
+	// shingleSize is the number of consecutive words or tags in one shingle.
+	shingleSize = 4
+
+	// maxTagChanges caps the tag count changes reported.
+	maxTagChanges = 20
+)
+
+// skippedTextTags hold no visible text.
+var skippedTextTags = map[string]bool{"script": true, "style": true, "noscript": true, "template": true}
+
+// ContentFingerprint is what a page is compared on: its words, element sequence and scripts.
+type ContentFingerprint struct {
+	Title   string
+	Words   []string
+	Tags    []string // Start tags in document order
+	Scripts []string // External script sources
+}
+
+// TagCountChange is an element whose number of occurrences changed.
+type TagCountChange struct {
+	Tag      string `json:"tag"`
+	Baseline int    `json:"baseline"`
+	Current  int    `json:"current"`
+}
+
+// StructureDiff summarizes how the DOM of a page changed.
+type StructureDiff struct {
+	BaselineTitle    string           `json:"baseline_title"`
+	CurrentTitle     string           `json:"current_title"`
+	TitleChanged     bool             `json:"title_changed"`
+	BaselineElements int              `json:"baseline_elements"`
+	CurrentElements  int              `json:"current_elements"`
+	TagChanges       []TagCountChange `json:"tag_changes"` // Largest changes first
+	AddedScripts     []string         `json:"added_scripts"`
+	RemovedScripts   []string         `json:"removed_scripts"`
+}
+
+// ContentSimilarity is the comparison of a page with a baseline.
+type ContentSimilarity struct {
+	Identical           bool          `json:"identical"`
+	TextSimilarity      float64       `json:"text_similarity"`      // Jaccard similarity of 4-word shingles, 0 to 1
+	SimHashDistance     int           `json:"simhash_distance"`     // Differing bits of the 64-bit SimHashes of the text
+	SimHashSimilarity   float64       `json:"simhash_similarity"`   // 1 - distance/64
+	StructureSimilarity float64       `json:"structure_similarity"` // Jaccard similarity of 4-tag shingles, 0 to 1
+	Verdict             string        `json:"verdict"`              // identical, similar, modified or different
+	Structure           StructureDiff `json:"structure"`
+}
+
+// FingerprintContent extracts the visible words, element sequence, title and external
+// scripts of an HTML document.
+func FingerprintContent(body []byte) *ContentFingerprint {
+	fingerprint := &ContentFingerprint{}
+	tokenizer := html.NewTokenizer(bytes.NewReader(body))
+	var skipping string
+	inTitle := false
+	for {
+		switch tokenizer.Next() {
+		case html.ErrorToken:
+			fingerprint.Title = strings.Join(strings.Fields(fingerprint.Title), " ")
+			return fingerprint
+		case html.StartTagToken, html.SelfClosingTagToken:
+			token := tokenizer.Token()
+			fingerprint.Tags = append(fingerprint.Tags, token.Data)
+			if token.Data == "script" {
+				for _, attr := range token.Attr {
+					if attr.Key == "src" && strings.TrimSpace(attr.Val) != "" {
+						fingerprint.Scripts = append(fingerprint.Scripts, strings.TrimSpace(attr.Val))
+					}
+				}
+			}
+			if skippedTextTags[token.Data] && skipping == "" {
+				skipping = token.Data
+			}
+			inTitle = token.Data == "title"
+		case html.EndTagToken:
+			name, _ := tokenizer.TagName()
+			if string(name) == skipping {
+				skipping = ""
+			}
+			inTitle = false
+		case html.TextToken:
+			if skipping != "" {
+				continue
+			}
+			text := string(tokenizer.Text())
+			if inTitle {
+				fingerprint.Title += text
+			}
+			fingerprint.Words = append(fingerprint.Words, strings.FieldsFunc(strings.ToLower(text), isWordSeparator)...)
+		}
+	}
+}
+
+// isWordSeparator splits text into words on whitespace and punctuation.
+func isWordSeparator(r rune) bool {
+	return !(r >= 'a' && r <= 'z' || r >= '0' && r <= '9' || r > 127)
+}
+
+// CompareContent scores how similar the current version of a page is to a baseline.
+func CompareContent(baseline, current []byte) *ContentSimilarity {
+	baselineFingerprint, currentFingerprint := FingerprintContent(baseline), FingerprintContent(current)
+	distance := bits.OnesCount64(simHash(baselineFingerprint.Words) ^ simHash(currentFingerprint.Words))
+	result := &ContentSimilarity{
+		Identical:           bytes.Equal(baseline, current),
+		TextSimilarity:      roundSimilarity(jaccard(shingles(baselineFingerprint.Words), shingles(currentFingerprint.Words))),
+		SimHashDistance:     distance,
+		SimHashSimilarity:   roundSimilarity(1 - float64(distance)/64),
+		StructureSimilarity: roundSimilarity(jaccard(shingles(baselineFingerprint.Tags), shingles(currentFingerprint.Tags))),
+		Structure:           diffStructure(baselineFingerprint, currentFingerprint),
+	}
+
+	switch lowest := min(result.TextSimilarity, result.StructureSimilarity); {
+	case result.Identical:
+		result.Verdict = SimilarityIdentical
+	case lowest >= 0.8: // One changed word alters up to four shingles of a short page
+		result.Verdict = SimilaritySimilar
+	case max(result.TextSimilarity, result.StructureSimilarity) >= 0.5:
+		result.Verdict = SimilarityModified
+	default:
+		result.Verdict = SimilarityDifferent
+	}
+	return result
+}
+
+// shingles returns the set of runs of shingleSize consecutive items. Shorter inputs form a
+// single shingle.
+func shingles(items []string) map[string]bool {
+	set := map[string]bool{}
+	if len(items) < shingleSize {
+		if len(items) > 0 {
+			set[strings.Join(items, " ")] = true
+		}
+		return set
+	}
+	for i := 0; i+shingleSize <= len(items); i++ {
+		set[strings.Join(items[i:i+shingleSize], " ")] = true
+	}
+	return set
+}
+
+// jaccard is the size of the intersection of two sets over the size of their union. Two
+// empty sets are identical.
+func jaccard(a, b map[string]bool) float64 {
+	if len(a) == 0 && len(b) == 0 {
+		return 1
+	}
+	shared := 0
+	for item := range a {
+		if b[item] {
+			shared++
+		}
+	}
+	return float64(shared) / float64(len(a)+len(b)-shared)
+}
+
+// simHash is Charikar's 64-bit SimHash of words, weighted by their frequency.
+func simHash(words []string) uint64 {
+	var weights [64]int
+	for _, word := range words {
+		h := fnv.New64a()
+		h.Write([]byte(word))
+		sum := h.Sum64()
+		for bit := range 64 {
+			if sum&(1<<bit) != 0 {
+				weights[bit]++
+			} else {
+				weights[bit]--
+			}
+		}
+	}
+	var hash uint64
+	for bit, weight := range weights {
+		if weight > 0 {
+			hash |= 1 << bit
+		}
+	}
+	return hash
+}
+
+// roundSimilarity rounds a score to four decimals.
+func roundSimilarity(score float64) float64 {
+	return math.Round(score*10000) / 10000
+}
+
+// diffStructure compares the titles, element counts and scripts of two pages.
+func diffStructure(baseline, current *ContentFingerprint) StructureDiff {
+	diff := StructureDiff{
+		BaselineTitle:    baseline.Title,
+		CurrentTitle:     current.Title,
+		TitleChanged:     baseline.Title != current.Title,
+		BaselineElements: len(baseline.Tags),
+		CurrentElements:  len(current.Tags),
+		TagChanges:       []TagCountChange{},
+		AddedScripts:     setDifference(current.Scripts, baseline.Scripts),
+		RemovedScripts:   setDifference(baseline.Scripts, current.Scripts),
+	}
+
+	counts := map[string]*TagCountChange{}
+	for _, tag := range baseline.Tags {
+		if counts[tag] == nil {
+			counts[tag] = &TagCountChange{Tag: tag}
+		}
+		counts[tag].Baseline++
+	}
+	for _, tag := range current.Tags {
+		if counts[tag] == nil {
+			counts[tag] = &TagCountChange{Tag: tag}
+		}
+		counts[tag].Current++
+	}
+	for _, change := range counts {
+		if change.Baseline != change.Current {
+			diff.TagChanges = append(diff.TagChanges, *change)
+		}
+	}
+	sort.Slice(diff.TagChanges, func(i, j int) bool {
+		a, b := diff.TagChanges[i], diff.TagChanges[j]
+		deltaA, deltaB := abs(a.Current-a.Baseline), abs(b.Current-b.Baseline)
+		if deltaA != deltaB {
+			return deltaA > deltaB
+		}
+		return a.Tag < b.Tag
+	})
+	if len(diff.TagChanges) > maxTagChanges {
+		diff.TagChanges = diff.TagChanges[:maxTagChanges]
+	}
+	return diff
+}
+
+// setDifference returns the distinct items of a that are not in b, in order.
+func setDifference(a, b []string) []string {
+	exclude := map[string]bool{}
+	for _, item := range b {
+		exclude[item] = true
+	}
+	difference := []string{}
+	for _, item := range a {
+		if !exclude[item] {
+			difference = append(difference, item)
+			exclude[item] = true
+		}
+	}
+	return difference
+}
+
+// abs returns the absolute value of n.
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
@@ -0,0 +1,59 @@
+package utils
+
+import (
+	"strings"
+	"testing"
+)
+
+const similarityBaseline = `<html><head><title>Example Shop</title><script src="/app.js"></script></head>
+<body><nav><a href="/">Home</a><a href="/about">About</a></nav>
+<main><h1>Welcome to the Example Shop</h1>
+<p>We sell hand made furniture, lamps and rugs from small workshops across the country.</p>
+<p>Orders over fifty pounds ship for free and arrive within three working days.</p>
+<p>Last updated on 1 May 2024.</p></main>
+<footer><p>Example Shop Ltd, registered in England.</p></footer></body></html>`
+
+func TestCompareContent(t *testing.T) {
+	dateChanged := strings.Replace(similarityBaseline, "1 May 2024", "2 May 2024", 1)
+	defaced := `<html><head><title>Hacked</title><script src="https://evil.example/x.js"></script></head>
+<body><center><h1>Hacked by nobody</h1><marquee>greetings to everyone</marquee></center></body></html>`
+
+	for _, tt := range []struct {
+		name        string
+		current     string
+		wantVerdict string
+	}{
+		{"same page", similarityBaseline, SimilarityIdentical},
+		{"date changed", dateChanged, SimilaritySimilar},
+		{"defaced", defaced, SimilarityDifferent},
+	} {
+		result := CompareContent([]byte(similarityBaseline), []byte(tt.current))
+		if result.Verdict != tt.wantVerdict {
+			t.Errorf("%s: verdict %q, want %q (%+v)", tt.name, result.Verdict, tt.wantVerdict, result)
+		}
+	}
+
+	result := CompareContent([]byte(similarityBaseline), []byte(defaced))
+	if !result.Structure.TitleChanged || result.Structure.CurrentTitle != "Hacked" {
+		t.Errorf("title change not reported: %+v", result.Structure)
+	}
+	if len(result.Structure.AddedScripts) != 1 || result.Structure.AddedScripts[0] != "https://evil.example/x.js" {
+		t.Errorf("added scripts = %v", result.Structure.AddedScripts)
+	}
+	if len(result.Structure.RemovedScripts) != 1 || result.Structure.RemovedScripts[0] != "/app.js" {
+		t.Errorf("removed scripts = %v", result.Structure.RemovedScripts)
+	}
+	if result.TextSimilarity > 0.1 || result.SimHashDistance == 0 {
+		t.Errorf("defaced page text similarity %v, SimHash distance %d", result.TextSimilarity, result.SimHashDistance)
+	}
+}
+
+func TestFingerprintContentSkipsScripts(t *testing.T) {
+	fingerprint := FingerprintContent([]byte(`<title> A  Title </title><style>p { color: red }</style><script>var hidden = 1;</script><p>Visible text</p>`))
+	if fingerprint.Title != "A Title" {
+		t.Errorf("title = %q", fingerprint.Title)
+	}
+	if got := strings.Join(fingerprint.Words, " "); got != "a title visible text" {
+		t.Errorf("words = %q", got)
+	}
+}
@@ -0,0 +1,424 @@
+// Package crawler follows the same-origin links of a site from a start page, breadth first
+// up to a depth and page limit, respecting robots.txt. Each crawl runs in the background and
+// reports the pages found with their status codes and titles, plus the technologies detected
+// across them.
+package crawler
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/net/html"
+
+	"github.com/vit0-9/utils_api/pkg/utils"
+	"github.com/vit0-9/utils_api/pkg/utils/callback"
+	"github.com/vit0-9/utils_api/pkg/utils/lifecycle"
+	"github.com/vit0-9/utils_api/pkg/utils/notifications"
+)
+
+// Crawl statuses.
+const (
+	StatusRunning     = "running"
+	StatusCompleted   = "completed"
+	StatusInterrupted = "interrupted" // Stopped by a shutdown
+)
+
+const (
+	// DefaultDepth and DefaultMaxPages apply when a crawl does not set them.
+	DefaultDepth    = 2
+	DefaultMaxPages = 50
+	// MaxDepth and MaxPages cap what a crawl may ask for.
+	MaxDepth = 5
+	MaxPages = 500
+
+	workers          = 4 // Pages fetched at once within a crawl
+	maxRunning       = 5 // Crawls running at once
+	maxStoredCrawls  = 50
+	crawlTimeout     = 10 * time.Minute
+	maxLinksPerPage  = 500
+	maxStoredLinks   = 100 // Links listed per page in the result
+	robotsFetchLimit = 15 * time.Second
+)
+
+// ErrTooManyCrawls is returned by Start while maxRunning crawls are in progress.
+var ErrTooManyCrawls = errors.New("too many crawls are running, retry later")
+
+// Page is one fetched page of a crawl.
+type Page struct {
+	URL        string   `json:"url"`
+	FinalURL   string   `json:"final_url,omitempty"` // Set when the page redirected
+	Depth      int      `json:"depth"`               // Links followed from the start page
+	StatusCode int      `json:"status_code,omitempty"`
+	Title      string   `json:"title,omitempty"`
+	Links      []string `json:"links,omitempty"` // Same-origin links found on the page
+	Error      string   `json:"error,omitempty"`
+}
+
+// Technology is a technology detected on the crawled pages.
+type Technology struct {
+	Name       string   `json:"name"`
+	Versions   []string `json:"versions,omitempty"`
+	Categories []string `json:"categories,omitempty"`
+	Pages      int      `json:"pages"` // Number of pages it was detected on
+}
+
+// Crawl is a crawl and, once completed, its results.
+type Crawl struct {
+	ID                 string       `json:"id"`
+	StartURL           string       `json:"start_url"`
+	Status             string       `json:"status"`
+	MaxDepth           int          `json:"max_depth"`
+	MaxPages           int          `json:"max_pages"`
+	CallbackURL        string       `json:"callback_url,omitempty"`
+	Pages              []Page       `json:"pages"`
+	DisallowedByRobots []string     `json:"disallowed_by_robots,omitempty"` // Links robots.txt kept the crawl from
+	Truncated          bool         `json:"truncated"`                      // The page limit stopped the crawl before the depth did
+	Technologies       []Technology `json:"technologies"`
+	Error              string       `json:"error,omitempty"`
+	StartedAt          time.Time    `json:"started_at"`
+	FinishedAt         *time.Time   `json:"finished_at,omitempty"`
+}
+
+var (
+	mu      sync.Mutex
+	crawls  = map[string]*Crawl{}
+	order   []string // Crawl IDs, oldest first
+	running int
+
+	runningCrawls sync.WaitGroup
+	stopOnce      sync.Once
+)
+
+// Start validates a crawl request and runs the crawl in the background. Zero depth or
+// maxPages select the defaults. The completed crawl is POSTed to callbackURL, if set.
+func Start(startURL string, depth, maxPages int, callbackURL string) (Crawl, error) {
+	parsed, err := url.Parse(strings.TrimSpace(startURL))
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		return Crawl{}, fmt.Errorf("url must be an absolute http(s) URL")
+	}
+	if err := utils.CheckTargetURL(context.Background(), parsed.String()); err != nil {
+		return Crawl{}, err
+	}
+	parsed.Fragment = ""
+	if parsed.Path == "" {
+		parsed.Path = "/" // As links to the home page are normalized
+	}
+	if depth == 0 {
+		depth = DefaultDepth
+	}
+	if maxPages == 0 {
+		maxPages = DefaultMaxPages
+	}
+	if depth < 0 || depth > MaxDepth {
+		return Crawl{}, fmt.Errorf("max_depth must be between 1 and %d", MaxDepth)
+	}
+	if maxPages < 0 || maxPages > MaxPages {
+		return Crawl{}, fmt.Errorf("max_pages must be between 1 and %d", MaxPages)
+	}
+	if callbackURL != "" {
+		if err := callback.Validate(callbackURL); err != nil {
+			return Crawl{}, err
+		}
+	}
+
+	crawl := &Crawl{
+		ID:           newCrawlID(),
+		StartURL:     parsed.String(),
+		Status:       StatusRunning,
+		MaxDepth:     depth,
+		MaxPages:     maxPages,
+		CallbackURL:  callbackURL,
+		Pages:        []Page{},
+		Technologies: []Technology{},
+		StartedAt:    time.Now().UTC(),
+	}
+	mu.Lock()
+	if running >= maxRunning {
+		mu.Unlock()
+		return Crawl{}, ErrTooManyCrawls
+	}
+	running++
+	crawls[crawl.ID] = crawl
+	order = append(order, crawl.ID)
+	for len(order) > maxStoredCrawls {
+		if stored := crawls[order[0]]; stored.Status == StatusRunning {
+			break
+		}
+		delete(crawls, order[0])
+		order = order[1:]
+	}
+	snapshot := *crawl
+	mu.Unlock()
+
+	stopOnce.Do(func() {
+		lifecycle.Register("crawls", func(ctx context.Context) error { return lifecycle.Wait(ctx, &runningCrawls) })
+	})
+	runningCrawls.Add(1)
+	go run(crawl)
+	return snapshot, nil
+}
+
+// Get returns a crawl by ID.
+func Get(id string) (Crawl, bool) {
+	mu.Lock()
+	defer mu.Unlock()
+	crawl, ok := crawls[id]
+	if !ok {
+		return Crawl{}, false
+	}
+	snapshot := *crawl
+	snapshot.Pages = append([]Page(nil), crawl.Pages...)
+	return snapshot, true
+}
+
+func newCrawlID() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// run crawls level by level, so every page is reached over the fewest links.
+func run(crawl *Crawl) {
+	defer runningCrawls.Done()
+	ctx, cancel := context.WithTimeout(lifecycle.Context(), crawlTimeout)
+	defer cancel()
+	start, _ := url.Parse(crawl.StartURL)
+	robots := fetchRobots(ctx, start)
+
+	pages := []Page{}
+	var disallowed []string
+	technologies := map[string]*Technology{}
+	var techMu sync.Mutex
+	seen := map[string]bool{crawl.StartURL: true}
+	level := []string{crawl.StartURL}
+	truncated := false
+
+	for depth := 0; len(level) > 0 && depth <= crawl.MaxDepth; depth++ {
+		var allowed []string
+		for _, link := range level {
+			linkURL, _ := url.Parse(link)
+			if !robots.allowed(linkURL.RequestURI()) {
+				disallowed = append(disallowed, link)
+				continue
+			}
+			allowed = append(allowed, link)
+		}
+		if room := crawl.MaxPages - len(pages); len(allowed) > room {
+			allowed, truncated = allowed[:room], true
+		}
+
+		results := make([]Page, len(allowed))
+		next := make([][]string, len(allowed))
+		semaphore := make(chan struct{}, workers)
+		var wg sync.WaitGroup
+		for i, link := range allowed {
+			wg.Add(1)
+			semaphore <- struct{}{}
+			go func() {
+				defer func() { <-semaphore; wg.Done() }()
+				var detected []utils.DetectedTechnologyInfo
+				results[i], next[i], detected = crawlPage(ctx, start, link, depth)
+				techMu.Lock()
+				addTechnologies(technologies, detected)
+				techMu.Unlock()
+			}()
+		}
+		wg.Wait()
+		pages = append(pages, results...)
+
+		level = nil
+		for i := range results {
+			for _, link := range next[i] {
+				if !seen[link] {
+					seen[link] = true
+					level = append(level, link)
+				}
+			}
+		}
+		if len(pages) >= crawl.MaxPages {
+			truncated = truncated || (len(level) > 0 && depth < crawl.MaxDepth)
+			break
+		}
+	}
+
+	finished := time.Now().UTC()
+	mu.Lock()
+	crawl.Pages = pages
+	crawl.DisallowedByRobots = disallowed
+	crawl.Truncated = truncated
+	crawl.Technologies = sortedTechnologies(technologies)
+	crawl.Status = StatusCompleted
+	if lifecycle.Stopping() {
+		crawl.Status = StatusInterrupted
+	} else if ctx.Err() != nil {
+		crawl.Error = "the crawl timed out after " + crawlTimeout.String()
+	}
+	crawl.FinishedAt = &finished
+	running--
+	completed := *crawl
+	mu.Unlock()
+	if completed.Status == StatusInterrupted {
+		return // Crawls are kept in memory, so it cannot be resumed after the restart
+	}
+	notifyCompleted(completed)
+}
+
+// fetchRobots reads the robots.txt of the start URL's origin. A missing or unreadable file
+// allows everything.
+func fetchRobots(ctx context.Context, start *url.URL) robotsRules {
+	ctx, cancel := context.WithTimeout(ctx, robotsFetchLimit)
+	defer cancel()
+	robotsURL := url.URL{Scheme: start.Scheme, Host: start.Host, Path: "/robots.txt"}
+	fetchResult, err := utils.FetchURL(ctx, robotsURL.String())
+	if err != nil || fetchResult.StatusCode != 200 {
+		return nil
+	}
+	return parseRobots(string(utils.DecodeResponseBody(fetchResult)))
+}
+
+// crawlPage fetches one page, returning it, the same-origin links to follow and the
+// technologies detected on it.
+func crawlPage(ctx context.Context, start *url.URL, link string, depth int) (Page, []string, []utils.DetectedTechnologyInfo) {
+	page := Page{URL: link, Depth: depth}
+	fetchResult, err := utils.FetchURL(ctx, link)
+	if err != nil {
+		page.Error = err.Error()
+		return page, nil, nil
+	}
+	page.StatusCode = fetchResult.StatusCode
+	if fetchResult.FinalURL != link {
+		page.FinalURL = fetchResult.FinalURL
+	}
+	final, err := url.Parse(fetchResult.FinalURL)
+	if err != nil || !sameOrigin(start, final) {
+		return page, nil, nil // Redirected off the site
+	}
+	if fetchResult.StatusCode != 200 || !strings.Contains(strings.ToLower(fetchResult.Headers.Get("Content-Type")), "html") {
+		return page, nil, nil
+	}
+
+	body := utils.DecodeResponseBody(fetchResult)
+	page.Title = utils.ExtractMetadataFromHTML(body, fetchResult.FinalURL).Title
+	links := extractLinks(body, final, start)
+	if len(links) > maxStoredLinks {
+		page.Links = links[:maxStoredLinks]
+	} else {
+		page.Links = links
+	}
+	detected, _ := utils.AnalyzeFetchedStack(link, fetchResult)
+	return page, links, detected
+}
+
+// extractLinks returns the distinct same-origin http(s) links of a page, without fragments.
+func extractLinks(body []byte, base, start *url.URL) []string {
+	var links []string
+	seen := map[string]bool{}
+	tokenizer := html.NewTokenizer(bytes.NewReader(body))
+	for len(links) < maxLinksPerPage {
+		tt := tokenizer.Next()
+		if tt == html.ErrorToken {
+			break
+		}
+		if tt != html.StartTagToken && tt != html.SelfClosingTagToken {
+			continue
+		}
+		token := tokenizer.Token()
+		if token.Data == "base" {
+			for _, attr := range token.Attr {
+				if attr.Key == "href" {
+					if ref, err := base.Parse(strings.TrimSpace(attr.Val)); err == nil {
+						base = ref
+					}
+				}
+			}
+			continue
+		}
+		if token.Data != "a" && token.Data != "area" {
+			continue
+		}
+		for _, attr := range token.Attr {
+			if attr.Key != "href" {
+				continue
+			}
+			ref, err := base.Parse(strings.TrimSpace(attr.Val))
+			if err != nil || !sameOrigin(start, ref) {
+				continue
+			}
+			ref.Fragment = ""
+			if ref.Path == "" {
+				ref.Path = "/"
+			}
+			if link := ref.String(); !seen[link] {
+				seen[link] = true
+				links = append(links, link)
+			}
+		}
+	}
+	return links
+}
+
+func sameOrigin(a, b *url.URL) bool {
+	return a.Scheme == b.Scheme && strings.EqualFold(a.Host, b.Host)
+}
+
+func addTechnologies(technologies map[string]*Technology, detected []utils.DetectedTechnologyInfo) {
+	for _, tech := range detected {
+		aggregate, ok := technologies[tech.Name]
+		if !ok {
+			aggregate = &Technology{Name: tech.Name, Categories: tech.Categories}
+			technologies[tech.Name] = aggregate
+		}
+		aggregate.Pages++
+		if tech.Version != "" && !contains(aggregate.Versions, tech.Version) {
+			aggregate.Versions = append(aggregate.Versions, tech.Version)
+		}
+	}
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// sortedTechnologies lists the technologies found on the most pages first.
+func sortedTechnologies(technologies map[string]*Technology) []Technology {
+	list := make([]Technology, 0, len(technologies))
+	for _, tech := range technologies {
+		sort.Strings(tech.Versions)
+		list = append(list, *tech)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].Pages != list[j].Pages {
+			return list[i].Pages > list[j].Pages
+		}
+		return list[i].Name < list[j].Name
+	})
+	return list
+}
+
+// notifyCompleted sends the job.completed notification of a crawl and its callback.
+func notifyCompleted(crawl Crawl) {
+	if crawl.CallbackURL != "" {
+		callback.Deliver(crawl.CallbackURL, "crawl.completed", crawl)
+	}
+	duration := crawl.FinishedAt.Sub(crawl.StartedAt)
+	notifications.Notify(notifications.Notification{
+		Event:   notifications.EventJobCompleted,
+		Title:   "Site crawl completed",
+		Message: fmt.Sprintf("%d pages of %s crawled in %s", len(crawl.Pages), crawl.StartURL, duration.Round(time.Millisecond)),
+		Data:    map[string]any{"job": "crawl", "id": crawl.ID, "url": crawl.StartURL, "count": len(crawl.Pages), "duration_ms": duration.Milliseconds()},
+	})
+}
@@ -0,0 +1,125 @@
+package crawler
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"slices"
+	"testing"
+	"time"
+)
+
+func newTestSite(t *testing.T) *httptest.Server {
+	t.Helper()
+	pages := map[string]string{
+		"/":       `<title>Home</title><a href="/a">A</a> <a href="b#top">B</a> <a href="/private/x">X</a> <a href="https://other.example/">Elsewhere</a>`,
+		"/a":      `<title>Page A</title><a href="/">Home</a> <a href="/deep">Deep</a> <a href="/missing">Missing</a>`,
+		"/b":      `<title>Page B</title><a href="/a">A</a>`,
+		"/deep":   `<title>Deep</title><a href="/deeper">Deeper</a>`,
+		"/deeper": `<title>Too deep</title>`,
+	}
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/robots.txt" {
+			fmt.Fprint(w, "User-agent: *\nDisallow: /private/\n")
+			return
+		}
+		page, ok := pages[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		fmt.Fprint(w, page)
+	}))
+}
+
+func waitForCrawl(t *testing.T, id string) Crawl {
+	t.Helper()
+	deadline := time.Now().Add(10 * time.Second)
+	for {
+		crawl, ok := Get(id)
+		if !ok {
+			t.Fatalf("crawl %s not found", id)
+		}
+		if crawl.Status == StatusCompleted {
+			return crawl
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("crawl did not complete: %+v", crawl)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestCrawl(t *testing.T) {
+	server := newTestSite(t)
+	defer server.Close()
+
+	started, err := Start(server.URL+"/", 2, 0, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if started.Status != StatusRunning || started.MaxPages != DefaultMaxPages {
+		t.Errorf("started crawl = %+v", started)
+	}
+	crawl := waitForCrawl(t, started.ID)
+
+	got := map[string]Page{}
+	for _, page := range crawl.Pages {
+		path, _ := url.Parse(page.URL)
+		got[path.Path] = page
+	}
+	for path, want := range map[string]Page{
+		"/":        {Depth: 0, StatusCode: 200, Title: "Home"},
+		"/a":       {Depth: 1, StatusCode: 200, Title: "Page A"},
+		"/b":       {Depth: 1, StatusCode: 200, Title: "Page B"},
+		"/deep":    {Depth: 2, StatusCode: 200, Title: "Deep"},
+		"/missing": {Depth: 2, StatusCode: 404},
+	} {
+		page, ok := got[path]
+		if !ok || page.Depth != want.Depth || page.StatusCode != want.StatusCode || page.Title != want.Title {
+			t.Errorf("page %s = %+v, want %+v", path, page, want)
+		}
+	}
+	if len(crawl.Pages) != 5 {
+		t.Errorf("crawled %d pages, want 5 within depth 2: %+v", len(crawl.Pages), crawl.Pages)
+	}
+	if !slices.Equal(crawl.DisallowedByRobots, []string{server.URL + "/private/x"}) {
+		t.Errorf("DisallowedByRobots = %v", crawl.DisallowedByRobots)
+	}
+	if crawl.Truncated || crawl.FinishedAt == nil {
+		t.Errorf("crawl truncated %t, finished %v", crawl.Truncated, crawl.FinishedAt)
+	}
+}
+
+func TestCrawlPageLimit(t *testing.T) {
+	server := newTestSite(t)
+	defer server.Close()
+
+	started, err := Start(server.URL, 5, 2, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	crawl := waitForCrawl(t, started.ID)
+	if len(crawl.Pages) != 2 || !crawl.Truncated {
+		t.Errorf("crawl with max_pages 2 = %d pages, truncated %t", len(crawl.Pages), crawl.Truncated)
+	}
+}
+
+func TestStartRejectsInvalidCrawls(t *testing.T) {
+	for _, tt := range []struct {
+		url             string
+		depth, maxPages int
+	}{
+		{"ftp://example.com/", 0, 0},
+		{"example.com", 0, 0},
+		{"https://example.com/", MaxDepth + 1, 0},
+		{"https://example.com/", 0, MaxPages + 1},
+		{"https://example.com/", -1, 0},
+	} {
+		if _, err := Start(tt.url, tt.depth, tt.maxPages, ""); err == nil {
+			t.Errorf("Start(%q, %d, %d) succeeded, want an error", tt.url, tt.depth, tt.maxPages)
+		}
+	}
+}
@@ -0,0 +1,83 @@
+package crawler
+
+import (
+	"bufio"
+	"regexp"
+	"strings"
+
+	"github.com/vit0-9/utils_api/pkg/utils"
+)
+
+// robotsRule is one Allow or Disallow line of the group that applies to the crawler.
+type robotsRule struct {
+	allow   bool
+	length  int // Length of the path pattern; the longest matching rule wins
+	pattern *regexp.Regexp
+}
+
+// robotsRules are the rules robots.txt sets for user agent "*".
+type robotsRules []robotsRule
+
+// parseRobots reads the groups for user agent "*" from a robots.txt file (RFC 9309). The
+// API fetches with browser User-Agents, so no other group applies to it.
+func parseRobots(body string) robotsRules {
+	var rules robotsRules
+	applies, inRules := false, false
+	scanner := bufio.NewScanner(strings.NewReader(body))
+	for scanner.Scan() {
+		line, _, _ := strings.Cut(scanner.Text(), "#")
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		key = strings.ToLower(strings.TrimSpace(key))
+		value = strings.TrimSpace(value)
+		switch key {
+		case "user-agent":
+			if inRules { // A user-agent line after rules starts a new group
+				applies, inRules = false, false
+			}
+			if value == "*" {
+				applies = true
+			}
+		case "allow", "disallow":
+			inRules = true
+			if applies && value != "" {
+				rules = append(rules, robotsRule{allow: key == "allow", length: len(value), pattern: robotsPattern(value)})
+			}
+		}
+	}
+	return rules
+}
+
+// robotsPattern compiles a path pattern, where * matches any characters and a trailing $
+// anchors the end of the path.
+func robotsPattern(path string) *regexp.Regexp {
+	anchored := strings.HasSuffix(path, "$")
+	path = strings.TrimSuffix(path, "$")
+	parts := strings.Split(path, "*")
+	for i, part := range parts {
+		parts[i] = regexp.QuoteMeta(part)
+	}
+	expr := "^" + strings.Join(parts, ".*")
+	if anchored {
+		expr += "$"
+	}
+	return utils.MustCompileRegexp(expr) // Shared by every crawl of the site
+}
+
+// allowed reports whether the rules allow fetching path (with its query).
+func (rules robotsRules) allowed(path string) bool {
+	if path == "/robots.txt" {
+		return true
+	}
+	best, allow := -1, true
+	for _, rule := range rules {
+		if rule.length > best || (rule.length == best && rule.allow) {
+			if rule.pattern.MatchString(path) {
+				best, allow = rule.length, rule.allow
+			}
+		}
+	}
+	return allow
+}
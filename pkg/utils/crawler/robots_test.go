@@ -0,0 +1,40 @@
+package crawler
+
+import "testing"
+
+func TestRobotsRules(t *testing.T) {
+	rules := parseRobots(`
+User-agent: Googlebot
+Disallow: /
+
+User-agent: *
+Disallow: /private/
+Allow: /private/press   # More specific, so it wins
+Disallow: /*.pdf$
+Disallow: /search?
+
+User-agent: Bingbot
+Disallow: /bing-only
+`)
+	tests := map[string]bool{
+		"/":                      true,
+		"/about":                 true,
+		"/private/":              false,
+		"/private/team":          false,
+		"/private/press/2026":    true,
+		"/files/report.pdf":      false,
+		"/files/report.pdf?dl=1": true,
+		"/search?q=x":            false,
+		"/search":                true,
+		"/bing-only":             true,
+		"/robots.txt":            true,
+	}
+	for path, want := range tests {
+		if got := rules.allowed(path); got != want {
+			t.Errorf("allowed(%q) = %t, want %t", path, got, want)
+		}
+	}
+	if !parseRobots("").allowed("/anything") {
+		t.Error("an empty robots.txt disallowed a path")
+	}
+}
@@ -0,0 +1,55 @@
+package utils
+
+import (
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// maxFollowedRedirects matches the net/http client default redirect limit.
+const maxFollowedRedirects = 10
+
+// shellQuote quotes s for POSIX shells.
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// BuildCurlCommand returns a curl command reproducing a request made by the API, so users
+// can debug differences locally. An empty proxy means the request was sent directly, and
+// curl is told to ignore any proxy from the environment too.
+func BuildCurlCommand(method, targetURL string, headers http.Header, proxy string, followRedirects bool) string {
+	args := []string{"curl", "-sS"}
+	switch method {
+	case "", http.MethodGet:
+	case http.MethodHead:
+		args = append(args, "-I") // -X HEAD would wait for a body
+	default:
+		args = append(args, "-X", method)
+	}
+	if followRedirects {
+		args = append(args, "-L", "--max-redirs", strconv.Itoa(maxFollowedRedirects))
+	}
+
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		for _, value := range headers[name] {
+			args = append(args, "-H", shellQuote(name+": "+value))
+		}
+	}
+	if headers.Get("Accept-Encoding") != "" {
+		args = append(args, "--compressed") // Let curl decode the body we asked to be compressed
+	}
+
+	if proxy != "" {
+		args = append(args, "-x", shellQuote(proxy))
+	} else {
+		args = append(args, "--noproxy", shellQuote("*"))
+	}
+	args = append(args, shellQuote(targetURL))
+	return strings.Join(args, " ")
+}
@@ -1,84 +1,196 @@
 package utils
 
 import (
+	"context"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
 	"fmt"
 	"net"
+	"strconv"
 	"strings"
+
+	"golang.org/x/net/dns/dnsmessage"
 )
 
 type DNSRecord struct {
 	Type     string `json:"type"`
 	Value    string `json:"value"`
-	Priority uint16 `json:"priority,omitempty"` // For MX records
-	TTL      uint32 `json:"ttl,omitempty"`      // Often not directly available from simple lookups
+	Priority uint16 `json:"priority,omitempty"` // For MX and SRV records
+	Weight   uint16 `json:"weight,omitempty"`   // For SRV records
+	Port     uint16 `json:"port,omitempty"`     // For SRV records
+	TTL      uint32 `json:"ttl,omitempty"`
+}
+
+// Record types without a named constant in dnsmessage.
+const (
+	dnsTypeDS     dnsmessage.Type = 43
+	dnsTypeRRSIG  dnsmessage.Type = 46
+	dnsTypeDNSKEY dnsmessage.Type = 48
+	dnsTypeCAA    dnsmessage.Type = 257
+)
+
+// SupportedDNSRecordTypes maps the record type names accepted by the lookup to their wire types.
+var SupportedDNSRecordTypes = map[string]dnsmessage.Type{
+	"A":      dnsmessage.TypeA,
+	"AAAA":   dnsmessage.TypeAAAA,
+	"MX":     dnsmessage.TypeMX,
+	"TXT":    dnsmessage.TypeTXT,
+	"CNAME":  dnsmessage.TypeCNAME,
+	"NS":     dnsmessage.TypeNS,
+	"SOA":    dnsmessage.TypeSOA,
+	"SRV":    dnsmessage.TypeSRV,
+	"PTR":    dnsmessage.TypePTR,
+	"CAA":    dnsTypeCAA,
+	"DS":     dnsTypeDS,
+	"DNSKEY": dnsTypeDNSKEY,
+}
+
+// LookupDNSRecords performs DNS lookups for various record types using the default resolver.
+func LookupDNSRecords(ctx context.Context, domain string, recordTypes []string) (map[string][]DNSRecord, map[string]string) {
+	return LookupDNSRecordsWithResolver(ctx, DefaultResolver(), domain, recordTypes)
 }
 
-// LookupDNSRecords performs DNS lookups for various record types.
-func LookupDNSRecords(domain string, recordTypes []string) (map[string][]DNSRecord, map[string]string) {
+// LookupDNSRecordsWithResolver performs DNS lookups for various record types using the given resolver.
+// PTR lookups accept an IP address and query its reverse (in-addr.arpa / ip6.arpa) name, or
+// take a reverse name as is.
+func LookupDNSRecordsWithResolver(ctx context.Context, resolver Resolver, domain string, recordTypes []string) (map[string][]DNSRecord, map[string]string) {
 	results := make(map[string][]DNSRecord)
 	errors := make(map[string]string)
 
-	for _, recordType := range recordTypes {
-		var records []DNSRecord
-		var err error
+	if err := CheckOutboundName(domain); err != nil {
+		for _, recordType := range recordTypes {
+			errors[recordType] = err.Error()
+		}
+		return results, errors
+	}
 
+	for _, recordType := range recordTypes {
 		normalizedType := strings.ToUpper(strings.TrimSpace(recordType))
-
-		switch normalizedType {
-		case "A":
-			ips, e := net.LookupIP(domain)
-			err = e
-			for _, ip := range ips {
-				if ip.To4() != nil { // Ensure it's an IPv4 address
-					records = append(records, DNSRecord{Type: "A", Value: ip.String()})
-				}
-			}
-		case "AAAA":
-			ips, e := net.LookupIP(domain)
-			err = e
-			for _, ip := range ips {
-				if ip.To16() != nil && ip.To4() == nil { // Ensure it's an IPv6 address and not an IPv4-mapped IPv6
-					records = append(records, DNSRecord{Type: "AAAA", Value: ip.String()})
-				}
-			}
-		case "MX":
-			mxs, e := net.LookupMX(domain)
-			err = e
-			for _, mx := range mxs {
-				records = append(records, DNSRecord{Type: "MX", Value: mx.Host, Priority: mx.Pref})
-			}
-		case "TXT":
-			txts, e := net.LookupTXT(domain)
-			err = e
-			for _, txt := range txts {
-				records = append(records, DNSRecord{Type: "TXT", Value: txt})
-			}
-		case "CNAME":
-			cname, e := net.LookupCNAME(domain)
-			err = e
-			if cname != "" { // LookupCNAME returns empty string if no CNAME or multiple CNAMEs (which is invalid)
-				records = append(records, DNSRecord{Type: "CNAME", Value: cname})
-			}
-		case "NS":
-			nss, e := net.LookupNS(domain)
-			err = e
-			for _, ns := range nss {
-				records = append(records, DNSRecord{Type: "NS", Value: ns.Host})
-			}
-		default:
+		qtype, ok := SupportedDNSRecordTypes[normalizedType]
+		if !ok {
 			errors[recordType] = fmt.Sprintf("Unsupported record type: %s", recordType)
 			continue
 		}
 
+		name := domain
+		if qtype == dnsmessage.TypePTR && !isReverseDNSName(domain) {
+			reverse, err := reverseDNSName(domain)
+			if err != nil {
+				errors[recordType] = err.Error()
+				continue
+			}
+			name = reverse
+		}
+
+		lookupCtx, cancel := context.WithTimeout(ctx, dnsLookupTimeout)
+		answers, err := resolver.Query(lookupCtx, name, qtype)
+		cancel()
 		if err != nil {
 			errors[recordType] = err.Error()
+			continue
+		}
+
+		var records []DNSRecord
+		for _, answer := range answers {
+			if answer.Header.Type != qtype { // Skip the CNAME chain leading to the answer
+				continue
+			}
+			if record, ok := dnsRecordFromResource(normalizedType, answer); ok {
+				records = append(records, record)
+			}
 		}
 		if len(records) > 0 {
 			results[normalizedType] = records
-		} else if err == nil { // No error but no records (e.g. for specific IP versions in A/AAAA)
-			// results[normalizedType] = []DNSRecord{} // Optionally indicate no records found vs. error
 		}
-
 	}
 	return results, errors
 }
+
+// isReverseDNSName reports whether name is already an in-addr.arpa or ip6.arpa name.
+func isReverseDNSName(name string) bool {
+	name = strings.ToLower(strings.TrimSuffix(name, "."))
+	return strings.HasSuffix(name, ".in-addr.arpa") || strings.HasSuffix(name, ".ip6.arpa")
+}
+
+// reverseDNSName returns the PTR query name for an IP address.
+func reverseDNSName(ipStr string) (string, error) {
+	ip := net.ParseIP(ipStr)
+	if ip == nil {
+		return "", fmt.Errorf("not an IP address: %s", ipStr)
+	}
+	if ip4 := ip.To4(); ip4 != nil {
+		return fmt.Sprintf("%d.%d.%d.%d.in-addr.arpa.", ip4[3], ip4[2], ip4[1], ip4[0]), nil
+	}
+	hexDigits := hex.EncodeToString(ip.To16())
+	var b strings.Builder
+	for i := len(hexDigits) - 1; i >= 0; i-- {
+		b.WriteByte(hexDigits[i])
+		b.WriteByte('.')
+	}
+	b.WriteString("ip6.arpa.")
+	return b.String(), nil
+}
+
+// dnsRecordFromResource converts an answer into a DNSRecord, formatting values like dig does.
+func dnsRecordFromResource(recordType string, resource dnsmessage.Resource) (DNSRecord, bool) {
+	record := DNSRecord{Type: recordType, TTL: resource.Header.TTL}
+
+	switch body := resource.Body.(type) {
+	case *dnsmessage.AResource:
+		record.Value = net.IP(body.A[:]).String()
+	case *dnsmessage.AAAAResource:
+		record.Value = net.IP(body.AAAA[:]).String()
+	case *dnsmessage.MXResource:
+		record.Value = body.MX.String()
+		record.Priority = body.Pref
+	case *dnsmessage.TXTResource:
+		record.Value = strings.Join(body.TXT, "")
+	case *dnsmessage.CNAMEResource:
+		record.Value = body.CNAME.String()
+	case *dnsmessage.NSResource:
+		record.Value = body.NS.String()
+	case *dnsmessage.PTRResource:
+		record.Value = body.PTR.String()
+	case *dnsmessage.SOAResource:
+		record.Value = fmt.Sprintf("%s %s %d %d %d %d %d", body.NS.String(), body.MBox.String(), body.Serial, body.Refresh, body.Retry, body.Expire, body.MinTTL)
+	case *dnsmessage.SRVResource:
+		record.Value = body.Target.String()
+		record.Priority = body.Priority
+		record.Weight = body.Weight
+		record.Port = body.Port
+	case *dnsmessage.UnknownResource:
+		value, ok := formatUnknownRecord(resource.Header.Type, body.Data)
+		if !ok {
+			return record, false
+		}
+		record.Value = value
+	default:
+		return record, false
+	}
+	return record, true
+}
+
+// formatUnknownRecord formats the record types dnsmessage leaves unparsed.
+func formatUnknownRecord(rtype dnsmessage.Type, data []byte) (string, bool) {
+	switch rtype {
+	case dnsTypeCAA: // flags(1) tag-length(1) tag value
+		if len(data) < 2 || len(data) < 2+int(data[1]) {
+			return "", false
+		}
+		tag := string(data[2 : 2+int(data[1])])
+		value := string(data[2+int(data[1]):])
+		return fmt.Sprintf("%d %s %s", data[0], tag, strconv.Quote(value)), true
+	case dnsTypeDS: // key-tag(2) algorithm(1) digest-type(1) digest
+		if len(data) < 4 {
+			return "", false
+		}
+		return fmt.Sprintf("%d %d %d %s", binary.BigEndian.Uint16(data[:2]), data[2], data[3], strings.ToUpper(hex.EncodeToString(data[4:]))), true
+	case dnsTypeDNSKEY: // flags(2) protocol(1) algorithm(1) public-key
+		if len(data) < 4 {
+			return "", false
+		}
+		return fmt.Sprintf("%d %d %d %s", binary.BigEndian.Uint16(data[:2]), data[2], data[3], base64.StdEncoding.EncodeToString(data[4:])), true
+	}
+	return "", false
+}
@@ -0,0 +1,469 @@
+package utils
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+// dnsLookupTimeout bounds each individual record lookup.
+const dnsLookupTimeout = 10 * time.Second
+
+// dnsUDPPayloadSize is the EDNS0 buffer size advertised to servers.
+const dnsUDPPayloadSize = 4096
+
+// ErrDNSNameNotFound is returned (wrapped) by Query when the name does not exist (NXDOMAIN).
+var ErrDNSNameNotFound = errors.New("no such host")
+
+// DNSResolver answers DNS queries through the system resolver, or by sending them to a
+// set of servers over UDP (falling back to TCP for truncated answers) or to a DNS over
+// HTTPS endpoint.
+type DNSResolver struct {
+	name string // "system", the server address, or the DoH URL
+
+	system    *net.Resolver // Set for the system resolver, which answers the types it supports
+	observed  bool          // Answers go into the passive DNS history: the system resolver's only
+	servers   []string      // host:port, tried in order
+	dial      func(ctx context.Context, network, addr string) (net.Conn, error)
+	dohURL    string
+	dohClient *http.Client
+}
+
+// systemNameservers reads the nameservers configured in /etc/resolv.conf. They are only
+// queried directly for record types the system resolver cannot look up (SOA, CAA, DS, ...).
+func systemNameservers() []string {
+	var servers []string
+	file, err := os.Open("/etc/resolv.conf")
+	if err == nil {
+		defer file.Close()
+		scanner := bufio.NewScanner(file)
+		for scanner.Scan() {
+			fields := strings.Fields(scanner.Text())
+			if len(fields) >= 2 && fields[0] == "nameserver" {
+				servers = append(servers, net.JoinHostPort(fields[1], "53"))
+			}
+		}
+	}
+	if len(servers) == 0 {
+		servers = []string{"127.0.0.1:53"} // Same default as the Go resolver
+	}
+	return servers
+}
+
+// NewDNSResolver builds a resolver from a user supplied spec:
+//   - "" or "system": the system resolver (so /etc/hosts and the search list apply)
+//   - an IP or host, optionally with a port (e.g. "1.1.1.1", "8.8.8.8:53"): plain DNS to that server
+//   - an https:// URL (e.g. "https://cloudflare-dns.com/dns-query"): DNS over HTTPS (RFC 8484)
+func NewDNSResolver(spec string) (*DNSResolver, error) {
+	spec = strings.TrimSpace(spec)
+	if spec == "" || strings.EqualFold(spec, "system") {
+		dialer := &net.Dialer{Timeout: 5 * time.Second}
+		return &DNSResolver{name: "system", system: net.DefaultResolver, observed: true, servers: systemNameservers(), dial: dialer.DialContext}, nil
+	}
+
+	if strings.HasPrefix(strings.ToLower(spec), "https://") {
+		dohURL, err := url.Parse(spec)
+		if err != nil || dohURL.Host == "" {
+			return nil, fmt.Errorf("invalid DoH resolver URL: %s", spec)
+		}
+		if err := CheckOutboundName(dohURL.Hostname()); err != nil {
+			return nil, err
+		}
+		// The endpoint is chosen by the caller, so it gets the SSRF protection of other
+		// caller-supplied URLs: queries are POSTed to it
+		ctx, cancel := context.WithTimeout(context.Background(), dnsLookupTimeout)
+		defer cancel()
+		if err := CheckTargetURL(ctx, dohURL.String()); err != nil {
+			return nil, err
+		}
+		return &DNSResolver{
+			name:      dohURL.String(),
+			dohURL:    dohURL.String(),
+			dohClient: &http.Client{Timeout: dnsLookupTimeout, Transport: NewTargetTransport()},
+		}, nil
+	}
+
+	host, port, err := net.SplitHostPort(spec)
+	if err != nil {
+		host, port = strings.Trim(spec, "[]"), "53"
+	}
+	if host == "" {
+		return nil, fmt.Errorf("invalid resolver address: %s", spec)
+	}
+	if err := CheckOutboundName(host); err != nil {
+		return nil, err
+	}
+	server := net.JoinHostPort(host, port)
+	return &DNSResolver{
+		name:    server,
+		servers: []string{server},
+		dial:    PolicyDialContext(&net.Dialer{Timeout: 5 * time.Second}),
+	}, nil
+}
+
+// Name identifies the resolver in results and errors: "system", the server address or the
+// DoH URL.
+func (r *DNSResolver) Name() string {
+	return r.name
+}
+
+// Query sends a single question and returns the answer section. A non-success response
+// code (NXDOMAIN, SERVFAIL, ...) is returned as an error. Answers of the system resolver
+// are added to the local passive DNS history; a resolver named by the caller could feed it
+// anything.
+func (r *DNSResolver) Query(ctx context.Context, name string, qtype dnsmessage.Type) ([]dnsmessage.Resource, error) {
+	answers, err := r.query(ctx, name, qtype, false)
+	if err == nil && r.observed {
+		recordPassiveDNS(answers)
+	}
+	return answers, err
+}
+
+// QueryDNSSEC is Query with the DO and CD bits set: RRSIG records are returned with the
+// answer, and a validating resolver hands back the data even when its own validation fails
+// so the caller can check the signatures itself.
+func (r *DNSResolver) QueryDNSSEC(ctx context.Context, name string, qtype dnsmessage.Type) ([]dnsmessage.Resource, error) {
+	answers, err := r.query(ctx, name, qtype, true)
+	if err == nil && r.observed {
+		recordPassiveDNS(answers)
+	}
+	return answers, err
+}
+
+func (r *DNSResolver) query(ctx context.Context, name string, qtype dnsmessage.Type, dnssec bool) (answers []dnsmessage.Resource, err error) {
+	start := time.Now()
+	defer func() {
+		TimingRecorderFrom(ctx).AddDNS(time.Since(start))
+		TimingRecorderFrom(ctx).AddFailure(err)
+	}()
+	if OfflineMode() {
+		return offlineDNSAnswers(name, qtype)
+	}
+	if r.system != nil && !dnssec && systemLookupTypes[qtype] {
+		return r.lookupSystem(ctx, name, qtype)
+	}
+	if !strings.HasSuffix(name, ".") {
+		name += "."
+	}
+	qname, err := dnsmessage.NewName(name)
+	if err != nil {
+		return nil, fmt.Errorf("invalid name %q: %w", name, err)
+	}
+
+	var idBytes [2]byte
+	rand.Read(idBytes[:])
+	query := dnsmessage.Message{
+		Header:    dnsmessage.Header{ID: binary.BigEndian.Uint16(idBytes[:]), RecursionDesired: true, CheckingDisabled: dnssec},
+		Questions: []dnsmessage.Question{{Name: qname, Type: qtype, Class: dnsmessage.ClassINET}},
+	}
+	var opt dnsmessage.ResourceHeader
+	if err := opt.SetEDNS0(dnsUDPPayloadSize, dnsmessage.RCodeSuccess, dnssec); err != nil {
+		return nil, err
+	}
+	query.Additionals = []dnsmessage.Resource{{Header: opt, Body: &dnsmessage.OPTResource{}}}
+	packed, err := query.Pack()
+	if err != nil {
+		return nil, fmt.Errorf("failed to build query: %w", err)
+	}
+
+	raw, err := r.exchange(ctx, packed)
+	if err != nil {
+		return nil, err
+	}
+	var response dnsmessage.Message
+	if err := response.Unpack(raw); err != nil {
+		return nil, fmt.Errorf("invalid response from %s: %w", r.name, err)
+	}
+	if response.ID != query.ID {
+		return nil, fmt.Errorf("mismatched response ID from %s", r.name)
+	}
+	switch response.RCode {
+	case dnsmessage.RCodeSuccess:
+		return response.Answers, nil
+	case dnsmessage.RCodeNameError:
+		return nil, fmt.Errorf("lookup %s via %s resolver: %w", strings.TrimSuffix(name, "."), r.name, ErrDNSNameNotFound)
+	default:
+		return nil, fmt.Errorf("lookup %s via %s resolver: server returned %s", strings.TrimSuffix(name, "."), r.name, strings.TrimPrefix(response.RCode.String(), "RCode"))
+	}
+}
+
+// exchange sends a packed query and returns the packed response.
+func (r *DNSResolver) exchange(ctx context.Context, query []byte) ([]byte, error) {
+	if r.dohURL != "" {
+		return r.exchangeDoH(ctx, query)
+	}
+	var lastErr error
+	for i, server := range r.servers {
+		if i > 0 {
+			TimingRecorderFrom(ctx).AddRetry()
+		}
+		response, err := r.exchangeUDP(ctx, server, query)
+		if err == nil && len(response) > 2 && response[2]&0x02 != 0 { // TC bit: retry over TCP
+			TimingRecorderFrom(ctx).AddRetry()
+			response, err = r.exchangeTCP(ctx, server, query)
+		}
+		if err == nil {
+			return response, nil
+		}
+		lastErr = err
+	}
+	return nil, lastErr
+}
+
+func connDeadline(ctx context.Context) time.Time {
+	if deadline, ok := ctx.Deadline(); ok {
+		return deadline
+	}
+	return time.Now().Add(dnsLookupTimeout)
+}
+
+func (r *DNSResolver) exchangeUDP(ctx context.Context, server string, query []byte) ([]byte, error) {
+	conn, err := r.dial(ctx, "udp", server)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	conn.SetDeadline(connDeadline(ctx))
+
+	if _, err := conn.Write(query); err != nil {
+		return nil, err
+	}
+	buf := make([]byte, dnsUDPPayloadSize)
+	for {
+		n, err := conn.Read(buf)
+		if err != nil {
+			return nil, fmt.Errorf("no response from %s: %w", server, err)
+		}
+		// Ignore stray or spoofed packets until the answer to this question arrives.
+		if responseMatchesQuery(query, buf[:n]) {
+			return buf[:n], nil
+		}
+	}
+}
+
+// responseMatchesQuery reports whether a packed response answers the packed query: same
+// ID and the same question (names compared case-insensitively).
+func responseMatchesQuery(query, response []byte) bool {
+	var queryParser, responseParser dnsmessage.Parser
+	queryHeader, err := queryParser.Start(query)
+	if err != nil {
+		return false
+	}
+	responseHeader, err := responseParser.Start(response)
+	if err != nil || !responseHeader.Response || responseHeader.ID != queryHeader.ID {
+		return false
+	}
+	question, err := queryParser.Question()
+	if err != nil {
+		return false
+	}
+	answered, err := responseParser.Question()
+	if err != nil {
+		return false
+	}
+	return answered.Type == question.Type && answered.Class == question.Class &&
+		strings.EqualFold(answered.Name.String(), question.Name.String())
+}
+
+func (r *DNSResolver) exchangeTCP(ctx context.Context, server string, query []byte) ([]byte, error) {
+	conn, err := r.dial(ctx, "tcp", server)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	conn.SetDeadline(connDeadline(ctx))
+
+	framed := make([]byte, 2+len(query))
+	binary.BigEndian.PutUint16(framed, uint16(len(query)))
+	copy(framed[2:], query)
+	if _, err := conn.Write(framed); err != nil {
+		return nil, err
+	}
+	var length [2]byte
+	if _, err := io.ReadFull(conn, length[:]); err != nil {
+		return nil, fmt.Errorf("no response from %s: %w", server, err)
+	}
+	response := make([]byte, binary.BigEndian.Uint16(length[:]))
+	if _, err := io.ReadFull(conn, response); err != nil {
+		return nil, fmt.Errorf("truncated response from %s: %w", server, err)
+	}
+	return response, nil
+}
+
+func (r *DNSResolver) exchangeDoH(ctx context.Context, query []byte) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, "POST", r.dohURL, bytes.NewReader(query))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/dns-message")
+	req.Header.Set("Accept", "application/dns-message")
+
+	resp, err := r.dohClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("DoH request to %s failed: %w", r.dohURL, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("DoH server %s returned status %d", r.dohURL, resp.StatusCode)
+	}
+	response, err := io.ReadAll(io.LimitReader(resp.Body, 65535))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read DoH response from %s: %w", r.dohURL, err)
+	}
+	return response, nil
+}
+
+// systemLookupTypes are the record types the system resolver can look up. Its answers carry
+// no TTLs.
+var systemLookupTypes = map[dnsmessage.Type]bool{
+	dnsmessage.TypeA:     true,
+	dnsmessage.TypeAAAA:  true,
+	dnsmessage.TypeMX:    true,
+	dnsmessage.TypeTXT:   true,
+	dnsmessage.TypeCNAME: true,
+	dnsmessage.TypeNS:    true,
+	dnsmessage.TypeSRV:   true,
+	dnsmessage.TypePTR:   true,
+}
+
+// lookupSystem answers a query with the system resolver, returning the results as answer
+// records so callers handle both paths alike.
+func (r *DNSResolver) lookupSystem(ctx context.Context, name string, qtype dnsmessage.Type) ([]dnsmessage.Resource, error) {
+	name = strings.TrimSuffix(name, ".")
+	var answers []dnsmessage.Resource
+	add := func(body dnsmessage.ResourceBody) {
+		answers = append(answers, dnsmessage.Resource{
+			Header: dnsmessage.ResourceHeader{Name: dnsName(name), Type: qtype, Class: dnsmessage.ClassINET},
+			Body:   body,
+		})
+	}
+
+	var err error
+	switch qtype {
+	case dnsmessage.TypeA, dnsmessage.TypeAAAA:
+		network := "ip4"
+		if qtype == dnsmessage.TypeAAAA {
+			network = "ip6"
+		}
+		var ips []net.IP
+		ips, err = r.system.LookupIP(ctx, network, name)
+		for _, ip := range ips {
+			if ip4 := ip.To4(); qtype == dnsmessage.TypeA && ip4 != nil {
+				add(&dnsmessage.AResource{A: [4]byte(ip4)})
+			} else if qtype == dnsmessage.TypeAAAA && ip4 == nil {
+				add(&dnsmessage.AAAAResource{AAAA: [16]byte(ip.To16())})
+			}
+		}
+	case dnsmessage.TypeMX:
+		var mxs []*net.MX
+		mxs, err = r.system.LookupMX(ctx, name)
+		for _, mx := range mxs {
+			add(&dnsmessage.MXResource{Pref: mx.Pref, MX: dnsName(mx.Host)})
+		}
+	case dnsmessage.TypeTXT:
+		var txts []string
+		txts, err = r.system.LookupTXT(ctx, name)
+		for _, txt := range txts {
+			add(&dnsmessage.TXTResource{TXT: []string{txt}})
+		}
+	case dnsmessage.TypeCNAME:
+		var cname string
+		cname, err = r.system.LookupCNAME(ctx, name)
+		if err == nil && !strings.EqualFold(strings.TrimSuffix(cname, "."), name) { // The name itself when there is no CNAME
+			add(&dnsmessage.CNAMEResource{CNAME: dnsName(cname)})
+		}
+	case dnsmessage.TypeNS:
+		var nss []*net.NS
+		nss, err = r.system.LookupNS(ctx, name)
+		for _, ns := range nss {
+			add(&dnsmessage.NSResource{NS: dnsName(ns.Host)})
+		}
+	case dnsmessage.TypeSRV:
+		var srvs []*net.SRV
+		_, srvs, err = r.system.LookupSRV(ctx, "", "", name)
+		for _, srv := range srvs {
+			add(&dnsmessage.SRVResource{Priority: srv.Priority, Weight: srv.Weight, Port: srv.Port, Target: dnsName(srv.Target)})
+		}
+	case dnsmessage.TypePTR:
+		ip, ok := ipFromReverseName(name)
+		if !ok {
+			return nil, fmt.Errorf("lookup %s via %s resolver: PTR queries need an in-addr.arpa or ip6.arpa name", name, r.name)
+		}
+		var hosts []string
+		hosts, err = r.system.LookupAddr(ctx, ip.String())
+		for _, host := range hosts {
+			add(&dnsmessage.PTRResource{PTR: dnsName(host)})
+		}
+	}
+
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) && dnsErr.IsNotFound {
+		return nil, fmt.Errorf("lookup %s via %s resolver: %w", name, r.name, ErrDNSNameNotFound)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("lookup %s via %s resolver: %w", name, r.name, err)
+	}
+	return answers, nil
+}
+
+// dnsName converts a host name to a dnsmessage name, falling back to the root for names
+// that cannot be represented (only used for display).
+func dnsName(host string) dnsmessage.Name {
+	if !strings.HasSuffix(host, ".") {
+		host += "."
+	}
+	name, err := dnsmessage.NewName(host)
+	if err != nil {
+		return dnsmessage.MustNewName(".")
+	}
+	return name
+}
+
+// ipFromReverseName parses an in-addr.arpa or ip6.arpa name back into the IP address.
+func ipFromReverseName(name string) (net.IP, bool) {
+	name = strings.ToLower(strings.TrimSuffix(name, "."))
+	if rest, ok := strings.CutSuffix(name, ".in-addr.arpa"); ok {
+		labels := strings.Split(rest, ".")
+		if len(labels) != 4 {
+			return nil, false
+		}
+		for i, j := 0, len(labels)-1; i < j; i, j = i+1, j-1 {
+			labels[i], labels[j] = labels[j], labels[i]
+		}
+		ip := net.ParseIP(strings.Join(labels, "."))
+		return ip, ip != nil && ip.To4() != nil
+	}
+	if rest, ok := strings.CutSuffix(name, ".ip6.arpa"); ok {
+		nibbles := strings.Split(rest, ".")
+		if len(nibbles) != 32 {
+			return nil, false
+		}
+		var hexDigits strings.Builder
+		for i := len(nibbles) - 1; i >= 0; i-- {
+			if len(nibbles[i]) != 1 {
+				return nil, false
+			}
+			hexDigits.WriteString(nibbles[i])
+		}
+		raw, err := hex.DecodeString(hexDigits.String())
+		if err != nil {
+			return nil, false
+		}
+		return net.IP(raw), true
+	}
+	return nil, false
+}
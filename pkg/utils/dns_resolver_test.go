@@ -0,0 +1,254 @@
+package utils
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+func packMessage(t *testing.T, msg dnsmessage.Message) []byte {
+	t.Helper()
+	packed, err := msg.Pack()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return packed
+}
+
+func testQuestion(name string, qtype dnsmessage.Type) dnsmessage.Question {
+	return dnsmessage.Question{Name: dnsmessage.MustNewName(name), Type: qtype, Class: dnsmessage.ClassINET}
+}
+
+func TestResponseMatchesQuery(t *testing.T) {
+	query := packMessage(t, dnsmessage.Message{
+		Header:    dnsmessage.Header{ID: 0x1234, RecursionDesired: true},
+		Questions: []dnsmessage.Question{testQuestion("example.com.", dnsmessage.TypeA)},
+	})
+	tests := []struct {
+		name     string
+		response dnsmessage.Message
+		want     bool
+	}{
+		{"same question", dnsmessage.Message{
+			Header:    dnsmessage.Header{ID: 0x1234, Response: true},
+			Questions: []dnsmessage.Question{testQuestion("example.com.", dnsmessage.TypeA)},
+		}, true},
+		{"name case differs", dnsmessage.Message{
+			Header:    dnsmessage.Header{ID: 0x1234, Response: true},
+			Questions: []dnsmessage.Question{testQuestion("ExAmPle.CoM.", dnsmessage.TypeA)},
+		}, true},
+		{"other ID", dnsmessage.Message{
+			Header:    dnsmessage.Header{ID: 0x4321, Response: true},
+			Questions: []dnsmessage.Question{testQuestion("example.com.", dnsmessage.TypeA)},
+		}, false},
+		{"other name", dnsmessage.Message{
+			Header:    dnsmessage.Header{ID: 0x1234, Response: true},
+			Questions: []dnsmessage.Question{testQuestion("attacker.test.", dnsmessage.TypeA)},
+		}, false},
+		{"other type", dnsmessage.Message{
+			Header:    dnsmessage.Header{ID: 0x1234, Response: true},
+			Questions: []dnsmessage.Question{testQuestion("example.com.", dnsmessage.TypeAAAA)},
+		}, false},
+		{"no question", dnsmessage.Message{Header: dnsmessage.Header{ID: 0x1234, Response: true}}, false},
+		{"not a response", dnsmessage.Message{
+			Header:    dnsmessage.Header{ID: 0x1234},
+			Questions: []dnsmessage.Question{testQuestion("example.com.", dnsmessage.TypeA)},
+		}, false},
+	}
+	for _, tt := range tests {
+		if got := responseMatchesQuery(query, packMessage(t, tt.response)); got != tt.want {
+			t.Errorf("%s: responseMatchesQuery() = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+	if responseMatchesQuery(query, []byte{0x12, 0x34}) {
+		t.Error("responseMatchesQuery() accepted a truncated packet")
+	}
+}
+
+// TestQuerySkipsMismatchedUDPReplies answers each query with a forged reply for another
+// question before the real one; the resolver must wait for the real answer.
+func TestQuerySkipsMismatchedUDPReplies(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	go func() {
+		buf := make([]byte, 4096)
+		for {
+			n, addr, err := conn.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+			var query dnsmessage.Message
+			if query.Unpack(buf[:n]) != nil {
+				continue
+			}
+			forged := dnsmessage.Message{
+				Header:    dnsmessage.Header{ID: query.ID, Response: true},
+				Questions: []dnsmessage.Question{testQuestion("attacker.test.", dnsmessage.TypeA)},
+				Answers: []dnsmessage.Resource{{
+					Header: dnsmessage.ResourceHeader{Name: query.Questions[0].Name, Type: dnsmessage.TypeA, Class: dnsmessage.ClassINET, TTL: 1},
+					Body:   &dnsmessage.AResource{A: [4]byte{6, 6, 6, 6}},
+				}},
+			}
+			real := dnsmessage.Message{
+				Header:    dnsmessage.Header{ID: query.ID, Response: true},
+				Questions: query.Questions,
+				Answers: []dnsmessage.Resource{{
+					Header: dnsmessage.ResourceHeader{Name: query.Questions[0].Name, Type: dnsmessage.TypeA, Class: dnsmessage.ClassINET, TTL: 300},
+					Body:   &dnsmessage.AResource{A: [4]byte{192, 0, 2, 1}},
+				}},
+			}
+			for _, msg := range []dnsmessage.Message{forged, real} {
+				packed, _ := msg.Pack()
+				conn.WriteTo(packed, addr)
+			}
+		}
+	}()
+
+	resolver, err := NewDNSResolver(conn.LocalAddr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	answers, err := resolver.Query(ctx, "example.com", dnsmessage.TypeA)
+	if err != nil {
+		t.Fatalf("Query() error = %v", err)
+	}
+	if len(answers) != 1 || answers[0].Header.TTL != 300 {
+		t.Fatalf("Query() answers = %+v, want the single real answer", answers)
+	}
+	if a := answers[0].Body.(*dnsmessage.AResource).A; a != [4]byte{192, 0, 2, 1} {
+		t.Errorf("Query() answer = %v, want 192.0.2.1", a)
+	}
+}
+
+func TestReverseDNSNameRoundTrip(t *testing.T) {
+	tests := []struct {
+		ip   string
+		name string
+	}{
+		{"192.0.2.1", "1.2.0.192.in-addr.arpa."},
+		{"2001:db8::1", "1.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.8.b.d.0.1.0.0.2.ip6.arpa."},
+	}
+	for _, tt := range tests {
+		name, err := reverseDNSName(tt.ip)
+		if err != nil || name != tt.name {
+			t.Errorf("reverseDNSName(%q) = %q, %v; want %q", tt.ip, name, err, tt.name)
+		}
+		ip, ok := ipFromReverseName(tt.name)
+		if !ok || !ip.Equal(net.ParseIP(tt.ip)) {
+			t.Errorf("ipFromReverseName(%q) = %v, %v; want %s", tt.name, ip, ok, tt.ip)
+		}
+	}
+
+	if _, err := reverseDNSName("example.com"); err == nil {
+		t.Error("reverseDNSName() accepted a host name")
+	}
+	for _, name := range []string{"example.com", "2.0.192.in-addr.arpa", "x.2.0.192.in-addr.arpa", strings.Repeat("0.", 31) + "ip6.arpa"} {
+		if ip, ok := ipFromReverseName(name); ok {
+			t.Errorf("ipFromReverseName(%q) = %v, want failure", name, ip)
+		}
+	}
+}
+
+func TestLookupDNSRecordsReportsBadPTRInput(t *testing.T) {
+	resolver, err := NewDNSResolver("192.0.2.53")
+	if err != nil {
+		t.Fatal(err)
+	}
+	records, errs := LookupDNSRecordsWithResolver(context.Background(), resolver, "example.com", []string{"PTR"})
+	if len(records) != 0 || !strings.Contains(errs["PTR"], "not an IP address") {
+		t.Errorf("LookupDNSRecordsWithResolver() = %v, %v; want a PTR input error", records, errs)
+	}
+}
+
+func TestDNSRecordFromResource(t *testing.T) {
+	dnskey, _ := hex.DecodeString("0101030803010001")
+	tests := []struct {
+		name       string
+		recordType string
+		resource   dnsmessage.Resource
+		want       DNSRecord
+	}{
+		{"A", "A", dnsmessage.Resource{
+			Header: dnsmessage.ResourceHeader{Type: dnsmessage.TypeA, TTL: 60},
+			Body:   &dnsmessage.AResource{A: [4]byte{192, 0, 2, 1}},
+		}, DNSRecord{Type: "A", Value: "192.0.2.1", TTL: 60}},
+		{"MX", "MX", dnsmessage.Resource{
+			Header: dnsmessage.ResourceHeader{Type: dnsmessage.TypeMX, TTL: 300},
+			Body:   &dnsmessage.MXResource{Pref: 10, MX: dnsmessage.MustNewName("mail.example.com.")},
+		}, DNSRecord{Type: "MX", Value: "mail.example.com.", Priority: 10, TTL: 300}},
+		{"TXT joins strings", "TXT", dnsmessage.Resource{
+			Header: dnsmessage.ResourceHeader{Type: dnsmessage.TypeTXT},
+			Body:   &dnsmessage.TXTResource{TXT: []string{"v=spf1 ", "-all"}},
+		}, DNSRecord{Type: "TXT", Value: "v=spf1 -all"}},
+		{"SOA", "SOA", dnsmessage.Resource{
+			Header: dnsmessage.ResourceHeader{Type: dnsmessage.TypeSOA, TTL: 3600},
+			Body: &dnsmessage.SOAResource{NS: dnsmessage.MustNewName("ns1.example.com."), MBox: dnsmessage.MustNewName("hostmaster.example.com."),
+				Serial: 2024010101, Refresh: 7200, Retry: 900, Expire: 1209600, MinTTL: 300},
+		}, DNSRecord{Type: "SOA", Value: "ns1.example.com. hostmaster.example.com. 2024010101 7200 900 1209600 300", TTL: 3600}},
+		{"SRV", "SRV", dnsmessage.Resource{
+			Header: dnsmessage.ResourceHeader{Type: dnsmessage.TypeSRV},
+			Body:   &dnsmessage.SRVResource{Priority: 10, Weight: 5, Port: 5060, Target: dnsmessage.MustNewName("sip.example.com.")},
+		}, DNSRecord{Type: "SRV", Value: "sip.example.com.", Priority: 10, Weight: 5, Port: 5060}},
+		{"CAA", "CAA", dnsmessage.Resource{
+			Header: dnsmessage.ResourceHeader{Type: dnsTypeCAA},
+			Body:   &dnsmessage.UnknownResource{Type: dnsTypeCAA, Data: append([]byte{0, 5}, "issueletsencrypt.org"...)},
+		}, DNSRecord{Type: "CAA", Value: `0 issue "letsencrypt.org"`}},
+		{"DS", "DS", dnsmessage.Resource{
+			Header: dnsmessage.ResourceHeader{Type: dnsTypeDS},
+			Body:   &dnsmessage.UnknownResource{Type: dnsTypeDS, Data: []byte{0x4f, 0x66, 8, 2, 0xab, 0xcd}},
+		}, DNSRecord{Type: "DS", Value: "20326 8 2 ABCD"}},
+		{"DNSKEY", "DNSKEY", dnsmessage.Resource{
+			Header: dnsmessage.ResourceHeader{Type: dnsTypeDNSKEY},
+			Body:   &dnsmessage.UnknownResource{Type: dnsTypeDNSKEY, Data: dnskey},
+		}, DNSRecord{Type: "DNSKEY", Value: "257 3 8 AwEAAQ=="}},
+	}
+	for _, tt := range tests {
+		got, ok := dnsRecordFromResource(tt.recordType, tt.resource)
+		if !ok || got != tt.want {
+			t.Errorf("%s: dnsRecordFromResource() = %+v, %v; want %+v", tt.name, got, ok, tt.want)
+		}
+	}
+
+	for _, data := range [][]byte{{0}, {0, 9, 'i'}} { // Truncated CAA records
+		resource := dnsmessage.Resource{Header: dnsmessage.ResourceHeader{Type: dnsTypeCAA}, Body: &dnsmessage.UnknownResource{Type: dnsTypeCAA, Data: data}}
+		if record, ok := dnsRecordFromResource("CAA", resource); ok {
+			t.Errorf("dnsRecordFromResource() decoded malformed CAA %v as %+v", data, record)
+		}
+	}
+}
+
+func TestDoHResolverRefusesInternalEndpoints(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+	defer ConfigureSSRFProtection(false, "")
+
+	// A resolver built while the endpoint was allowed still dials through the SSRF check
+	resolver, err := NewDNSResolver(server.URL + "/dns-query")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ConfigureSSRFProtection(true, "")
+	var blocked *SSRFBlockedError
+	if _, err := resolver.Query(context.Background(), "example.com", dnsmessage.TypeA); !errors.As(err, &blocked) {
+		t.Errorf("query to a loopback DoH endpoint: err = %v", err)
+	}
+
+	for _, endpoint := range []string{"https://169.254.169.254/dns-query", server.URL + "/dns-query"} {
+		if _, err := NewDNSResolver(endpoint); !errors.As(err, &blocked) {
+			t.Errorf("NewDNSResolver(%q) error = %v, want the SSRF refusal", endpoint, err)
+		}
+	}
+}
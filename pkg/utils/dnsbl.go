@@ -0,0 +1,135 @@
+package utils
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"strings"
+	"sync"
+
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+// Default DNS blacklists. IP lists are queried with the reversed address, domain lists with
+// the domain itself.
+var (
+	dnsblIPLists     = []string{"zen.spamhaus.org", "b.barracudacentral.org", "dnsbl.sorbs.net"}
+	dnsblDomainLists = []string{"dbl.spamhaus.org", "multi.surbl.org"}
+)
+
+// ConfigureDNSBL replaces the default blacklists with comma-separated zone lists. An empty
+// list keeps the defaults.
+func ConfigureDNSBL(ipLists, domainLists string) {
+	if lists := splitDNSBLZones(ipLists); len(lists) > 0 {
+		dnsblIPLists = lists
+	}
+	if lists := splitDNSBLZones(domainLists); len(lists) > 0 {
+		dnsblDomainLists = lists
+	}
+	if ipLists != "" || domainLists != "" {
+		log.Printf("DNS blacklists: IP %v, domain %v", dnsblIPLists, dnsblDomainLists)
+	}
+}
+
+func splitDNSBLZones(value string) []string {
+	var zones []string
+	for _, zone := range strings.Split(value, ",") {
+		if zone = strings.ToLower(strings.Trim(strings.TrimSpace(zone), ".")); zone != "" {
+			zones = append(zones, zone)
+		}
+	}
+	return zones
+}
+
+// BlacklistResult is the answer of one DNS blacklist.
+type BlacklistResult struct {
+	Blacklist   string   `json:"blacklist"`
+	Listed      bool     `json:"listed"`
+	ReturnCodes []string `json:"return_codes,omitempty"` // The 127.0.0.x answers, which encode the listing reason
+	Reasons     []string `json:"reasons,omitempty"`      // TXT records published for the listing
+	Error       string   `json:"error,omitempty"`
+}
+
+// BlacklistReport lists a target's status on each queried blacklist.
+type BlacklistReport struct {
+	TargetType  string            `json:"target_type"` // "ip" or "domain"
+	Blacklists  []BlacklistResult `json:"blacklists"`
+	ListedCount int               `json:"listed_count"`
+}
+
+// CheckBlacklists queries the configured DNS blacklists for an IP address or a domain
+// concurrently through the resolver.
+func CheckBlacklists(ctx context.Context, resolver Resolver, target string) (*BlacklistReport, error) {
+	target = strings.ToLower(strings.TrimSuffix(strings.TrimSpace(target), "."))
+	report := &BlacklistReport{TargetType: "domain"}
+	prefix, lists := target, dnsblDomainLists
+	if ip := net.ParseIP(target); ip != nil {
+		reverse, err := reverseDNSName(target)
+		if err != nil {
+			return nil, err
+		}
+		prefix = strings.TrimSuffix(strings.TrimSuffix(reverse, ".in-addr.arpa."), ".ip6.arpa.")
+		report.TargetType, lists = "ip", dnsblIPLists
+	} else if _, err := dnsmessage.NewName(target + "."); err != nil || !strings.Contains(target, ".") {
+		return nil, fmt.Errorf("not an IP address or domain name: %s", target)
+	}
+
+	report.Blacklists = make([]BlacklistResult, len(lists))
+	var wg sync.WaitGroup
+	for i, list := range lists {
+		wg.Add(1)
+		go func(i int, list string) {
+			defer wg.Done()
+			report.Blacklists[i] = queryBlacklist(ctx, resolver, prefix+"."+list, list)
+		}(i, list)
+	}
+	wg.Wait()
+
+	for _, result := range report.Blacklists {
+		if result.Listed {
+			report.ListedCount++
+		}
+	}
+	return report, nil
+}
+
+// queryBlacklist looks up name in one blacklist: NXDOMAIN means not listed, a 127.0.0.0/8
+// answer means listed.
+func queryBlacklist(ctx context.Context, resolver Resolver, name, list string) BlacklistResult {
+	result := BlacklistResult{Blacklist: list}
+	answers, err := resolver.Query(ctx, name, dnsmessage.TypeA)
+	if errors.Is(err, ErrDNSNameNotFound) {
+		return result
+	}
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	for _, answer := range answers {
+		if a, ok := answer.Body.(*dnsmessage.AResource); ok {
+			result.ReturnCodes = append(result.ReturnCodes, net.IP(a.A[:]).String())
+		}
+	}
+	if len(result.ReturnCodes) == 0 {
+		return result
+	}
+	for _, code := range result.ReturnCodes {
+		// Spamhaus answers 127.255.255.x when it refuses the query (e.g. from a public resolver).
+		if !strings.HasPrefix(code, "127.") || strings.HasPrefix(code, "127.255.255.") {
+			result.Error = fmt.Sprintf("the blacklist returned %s, which is not a listing; the query was probably refused", code)
+			return result
+		}
+	}
+	result.Listed = true
+
+	if txts, err := resolver.Query(ctx, name, dnsmessage.TypeTXT); err == nil {
+		for _, txt := range txts {
+			if body, ok := txt.Body.(*dnsmessage.TXTResource); ok {
+				result.Reasons = append(result.Reasons, strings.Join(body.TXT, ""))
+			}
+		}
+	}
+	return result
+}
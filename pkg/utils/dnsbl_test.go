@@ -0,0 +1,113 @@
+package utils
+
+import (
+	"context"
+	"net"
+	"strings"
+	"testing"
+
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+// serveZone answers UDP queries with the records of the queried name and type, keyed by
+// lowercase name without the trailing dot, and NXDOMAIN for names it does not hold.
+func serveZone(t *testing.T, records map[string][]dnsmessage.Resource) *DNSResolver {
+	t.Helper()
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	go func() {
+		buf := make([]byte, 4096)
+		for {
+			n, addr, err := conn.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+			var query dnsmessage.Message
+			if query.Unpack(buf[:n]) != nil || len(query.Questions) != 1 {
+				continue
+			}
+			question := query.Questions[0]
+			response := dnsmessage.Message{
+				Header:    dnsmessage.Header{ID: query.ID, Response: true, RCode: dnsmessage.RCodeNameError},
+				Questions: query.Questions,
+			}
+			if resources, ok := records[strings.TrimSuffix(strings.ToLower(question.Name.String()), ".")]; ok {
+				response.RCode = dnsmessage.RCodeSuccess
+				for _, resource := range resources {
+					if resource.Header.Type == question.Type {
+						resource.Header.Name, resource.Header.Class = question.Name, dnsmessage.ClassINET
+						response.Answers = append(response.Answers, resource)
+					}
+				}
+			}
+			packed, err := response.Pack()
+			if err == nil {
+				conn.WriteTo(packed, addr)
+			}
+		}
+	}()
+
+	resolver, err := NewDNSResolver(conn.LocalAddr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return resolver
+}
+
+func aRecord(ip string) dnsmessage.Resource {
+	return dnsmessage.Resource{
+		Header: dnsmessage.ResourceHeader{Type: dnsmessage.TypeA, TTL: 300},
+		Body:   &dnsmessage.AResource{A: [4]byte(net.ParseIP(ip).To4())},
+	}
+}
+
+func txtRecord(value string) dnsmessage.Resource {
+	return dnsmessage.Resource{
+		Header: dnsmessage.ResourceHeader{Type: dnsmessage.TypeTXT, TTL: 300},
+		Body:   &dnsmessage.TXTResource{TXT: []string{value}},
+	}
+}
+
+func TestCheckBlacklists(t *testing.T) {
+	defer func(ipLists, domainLists []string) { dnsblIPLists, dnsblDomainLists = ipLists, domainLists }(dnsblIPLists, dnsblDomainLists)
+	ConfigureDNSBL("listed.test, clean.test,refusing.test", "dbl.test")
+
+	resolver := serveZone(t, map[string][]dnsmessage.Resource{
+		"2.0.0.127.listed.test":   {aRecord("127.0.0.2"), aRecord("127.0.0.4"), txtRecord("Listed for spam")},
+		"2.0.0.127.refusing.test": {aRecord("127.255.255.254")},
+		"bad.example.dbl.test":    {aRecord("127.0.1.2")},
+	})
+
+	report, err := CheckBlacklists(context.Background(), resolver, "127.0.0.2")
+	if err != nil {
+		t.Fatalf("CheckBlacklists() error = %v", err)
+	}
+	if report.TargetType != "ip" || report.ListedCount != 1 || len(report.Blacklists) != 3 {
+		t.Fatalf("CheckBlacklists() = %+v, want one listing among three IP lists", report)
+	}
+	listed, clean, refusing := report.Blacklists[0], report.Blacklists[1], report.Blacklists[2]
+	if !listed.Listed || strings.Join(listed.ReturnCodes, ",") != "127.0.0.2,127.0.0.4" || len(listed.Reasons) != 1 || listed.Reasons[0] != "Listed for spam" {
+		t.Errorf("listed.test result = %+v, want listed with return codes and a reason", listed)
+	}
+	if clean.Listed || clean.Error != "" {
+		t.Errorf("clean.test result = %+v, want not listed", clean)
+	}
+	if refusing.Listed || refusing.Error == "" {
+		t.Errorf("refusing.test result = %+v, want an error for the 127.255.255.x answer", refusing)
+	}
+
+	report, err = CheckBlacklists(context.Background(), resolver, "Bad.Example.")
+	if err != nil {
+		t.Fatalf("CheckBlacklists() error = %v", err)
+	}
+	if report.TargetType != "domain" || report.ListedCount != 1 || report.Blacklists[0].Blacklist != "dbl.test" {
+		t.Errorf("CheckBlacklists(domain) = %+v, want a listing on dbl.test", report)
+	}
+
+	if _, err := CheckBlacklists(context.Background(), resolver, "localhost"); err == nil {
+		t.Error("CheckBlacklists(localhost) error = nil, want an invalid target error")
+	}
+}
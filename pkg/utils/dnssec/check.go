@@ -0,0 +1,365 @@
+// Package dnssec walks the DNSSEC chain of trust from the root zone to a domain,
+// verifying the DS, DNSKEY and RRSIG records of every zone along the way.
+package dnssec
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/vit0-9/utils_api/pkg/utils"
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+// Chain of trust states (RFC 4033 section 5).
+const (
+	StatusSecure   = "secure"   // Every link from the root trust anchor validates
+	StatusInsecure = "insecure" // An unsigned delegation (no DS record) ends the chain
+	StatusBogus    = "bogus"    // A link is broken: a missing, mismatched or invalid record
+)
+
+// rootTrustAnchors are the IANA root zone KSK digests (KSK-2017 and KSK-2024).
+var rootTrustAnchors = []dsRecord{
+	mustDS(20326, 8, 2, "e06d44b80b8f1d39a95c0b0d7c65d08458e880409bbc683457104237c7f8ec8d"),
+	mustDS(38696, 8, 2, "683d2d0acb8c9b712a1948b27f741219298d0a450d612c483af444a4c0fb2b16"),
+}
+
+// DSRecord is a delegation signer record published in the parent zone.
+type DSRecord struct {
+	KeyTag     uint16 `json:"key_tag"`
+	Algorithm  string `json:"algorithm"`
+	DigestType string `json:"digest_type"`
+	Digest     string `json:"digest"`
+	Matched    bool   `json:"matched"` // A DNSKEY of the zone hashes to this digest
+}
+
+// DNSKEY is a public key published by a zone.
+type DNSKEY struct {
+	KeyTag    uint16 `json:"key_tag"`
+	Flags     uint16 `json:"flags"`
+	Role      string `json:"role"` // KSK (secure entry point flag set) or ZSK
+	Algorithm string `json:"algorithm"`
+	KeyBits   int    `json:"key_bits,omitempty"`
+	Revoked   bool   `json:"revoked,omitempty"`
+}
+
+// Signature is an RRSIG over one of the zone's RRsets, and whether it verifies.
+type Signature struct {
+	Covers     string    `json:"covers"` // DS, DNSKEY or SOA
+	KeyTag     uint16    `json:"key_tag"`
+	Algorithm  string    `json:"algorithm"`
+	Signer     string    `json:"signer"`
+	Inception  time.Time `json:"inception"`
+	Expiration time.Time `json:"expiration"`
+	Valid      bool      `json:"valid"`
+	Error      string    `json:"error,omitempty"`
+}
+
+// Zone is one link in the chain of trust.
+type Zone struct {
+	Name       string      `json:"name"`
+	Status     string      `json:"status"`
+	DS         []DSRecord  `json:"ds"` // For the root zone, the IANA trust anchors
+	DNSKEYs    []DNSKEY    `json:"dnskeys"`
+	Signatures []Signature `json:"signatures"`
+	Problems   []string    `json:"problems,omitempty"`
+}
+
+// Report is the result of a DNSSEC chain of trust check.
+type Report struct {
+	Domain      string    `json:"domain"`
+	Status      string    `json:"status"`
+	Signed      bool      `json:"signed"`     // The domain's zone publishes DNSKEY records
+	Algorithms  []string  `json:"algorithms"` // Key algorithms used along the chain
+	Zones       []Zone    `json:"zones"`      // From the root down to the zone containing the domain
+	BrokenLinks []string  `json:"broken_links"`
+	Error       string    `json:"-"` // Why the walk stopped early; surfaced by the handler
+	QueryTime   time.Time `json:"query_time"`
+}
+
+// zoneState is what the walk carries from a zone to its child.
+type zoneState struct {
+	name   string
+	status string
+	keys   []dnskeyRecord
+}
+
+type checker struct {
+	ctx      context.Context
+	resolver utils.Resolver
+	now      time.Time
+}
+
+// Check walks the delegation chain from the root to the zone containing domain. Each zone
+// cut is found by querying DS and SOA records through the resolver, which must pass DNSSEC
+// records through. The absence of a DS record is taken from the resolver's answer; NSEC
+// and NSEC3 denial proofs are not verified.
+func Check(ctx context.Context, resolver utils.Resolver, domain string) *Report {
+	domain = strings.ToLower(strings.TrimSuffix(strings.TrimSpace(domain), "."))
+	report := &Report{Domain: domain, Algorithms: []string{}, Zones: []Zone{}, BrokenLinks: []string{}, QueryTime: time.Now()}
+	c := &checker{ctx: ctx, resolver: resolver, now: time.Now()}
+
+	root := Zone{Name: "."}
+	rootState, err := c.checkZone(&root, zoneState{status: StatusSecure}, rootTrustAnchors, nil)
+	report.addZone(root)
+	if err != nil {
+		report.Error = err.Error()
+		report.Status = StatusBogus
+		return report
+	}
+
+	parent := rootState
+	labels := strings.Split(domain, ".")
+	for i := len(labels) - 1; i >= 0; i-- {
+		name := strings.Join(labels[i:], ".") + "."
+		dsAnswers, err := c.resolver.QueryDNSSEC(ctx, name, typeDS)
+		if err != nil {
+			if errors.Is(err, utils.ErrDNSNameNotFound) {
+				report.Error = fmt.Sprintf("%s does not exist", strings.TrimSuffix(name, "."))
+			} else {
+				report.Error = err.Error()
+			}
+			break
+		}
+		dsRecords, dsSigs := recordsOf(dsAnswers, name, typeDS)
+		if len(dsRecords) == 0 && !c.isZoneApex(name) {
+			continue // Not a zone cut: the name belongs to the parent zone
+		}
+
+		zone := Zone{Name: name}
+		var parsedDS []dsRecord
+		for _, record := range dsRecords {
+			if ds, err := parseDS(record); err == nil {
+				parsedDS = append(parsedDS, ds)
+			}
+		}
+		zone.Signatures = append(zone.Signatures, c.verifySignatures(name, typeDS, dsRecords, dsSigs, parent.keys)...)
+		if len(parsedDS) > 0 && parent.status == StatusSecure && !anyValid(zone.Signatures) {
+			zone.Problems = append(zone.Problems, fmt.Sprintf("DS records are not validly signed by the %s zone", parent.name))
+		}
+
+		state, err := c.checkZone(&zone, parent, parsedDS, zone.Problems)
+		report.addZone(zone)
+		if err != nil {
+			report.Error = err.Error()
+			break
+		}
+		parent = state
+	}
+
+	// The deepest zone's own data must verify with its keys too, not just its DNSKEY set.
+	if report.Error == "" && len(parent.keys) > 0 {
+		last := &report.Zones[len(report.Zones)-1]
+		soaAnswers, err := c.resolver.QueryDNSSEC(ctx, parent.name, dnsmessage.TypeSOA)
+		if err == nil {
+			soaRecords, soaSigs := recordsOf(soaAnswers, parent.name, dnsmessage.TypeSOA)
+			signatures := c.verifySignatures(parent.name, dnsmessage.TypeSOA, soaRecords, soaSigs, parent.keys)
+			last.Signatures = append(last.Signatures, signatures...)
+			if len(soaRecords) > 0 && !anyValid(signatures) && last.Status == StatusSecure {
+				last.Status = StatusBogus
+				last.Problems = append(last.Problems, "SOA record is not validly signed by the zone's keys")
+				report.BrokenLinks = append(report.BrokenLinks, last.Name+": SOA record is not validly signed by the zone's keys")
+			}
+			parent.status = last.Status
+		}
+	}
+
+	report.Status = parent.status
+	if report.Error != "" {
+		report.Status = StatusBogus
+	}
+	report.Signed = len(parent.keys) > 0
+	sort.Strings(report.Algorithms)
+	return report
+}
+
+// checkZone fetches and verifies a zone's DNSKEY set against the DS records that vouch for
+// it, fills in the zone and returns the state passed on to its children.
+func (c *checker) checkZone(zone *Zone, parent zoneState, dsRecords []dsRecord, problems []string) (zoneState, error) {
+	state := zoneState{name: zone.Name}
+	zone.DS, zone.DNSKEYs = []DSRecord{}, []DNSKEY{}
+	answers, err := c.resolver.QueryDNSSEC(c.ctx, zone.Name, typeDNSKEY)
+	if err != nil {
+		zone.Status = StatusBogus
+		return state, fmt.Errorf("could not query DNSKEY records of %s: %w", zone.Name, err)
+	}
+	keyRecords, keySigs := recordsOf(answers, zone.Name, typeDNSKEY)
+	for _, record := range keyRecords {
+		if key, err := parseDNSKEY(record); err == nil {
+			state.keys = append(state.keys, key)
+		}
+	}
+
+	anchored := make(map[uint16]bool) // Tags of keys matching a DS record
+	for _, ds := range dsRecords {
+		record := DSRecord{
+			KeyTag:     ds.keyTag,
+			Algorithm:  algorithmName(ds.algorithm),
+			DigestType: digestTypeNames[ds.digestType],
+			Digest:     strings.ToUpper(hex.EncodeToString(ds.digest)),
+		}
+		for _, key := range state.keys {
+			if key.matchesDS(zone.Name, ds) {
+				record.Matched = true
+				anchored[key.keyTag] = true
+			}
+		}
+		if record.DigestType == "" {
+			record.DigestType = fmt.Sprintf("%d", ds.digestType)
+		}
+		zone.DS = append(zone.DS, record)
+	}
+
+	for _, key := range state.keys {
+		role := "ZSK"
+		if key.flags&flagSEP != 0 {
+			role = "KSK"
+		}
+		zone.DNSKEYs = append(zone.DNSKEYs, DNSKEY{
+			KeyTag:    key.keyTag,
+			Flags:     key.flags,
+			Role:      role,
+			Algorithm: algorithmName(key.algorithm),
+			KeyBits:   key.keyBits(),
+			Revoked:   key.flags&flagRevoke != 0,
+		})
+	}
+
+	keySignatures := c.verifySignatures(zone.Name, typeDNSKEY, keyRecords, keySigs, state.keys)
+	zone.Signatures = append(keySignatures, zone.Signatures...)
+	signedByAnchor := false
+	for _, signature := range keySignatures {
+		if signature.Valid && anchored[signature.KeyTag] {
+			signedByAnchor = true
+		}
+	}
+
+	switch {
+	case len(dsRecords) > 0 && len(state.keys) == 0:
+		problems = append(problems, "the parent publishes DS records but the zone has no DNSKEY records")
+	case len(dsRecords) > 0 && len(anchored) == 0:
+		problems = append(problems, "no DNSKEY matches the DS records in the parent zone")
+	case len(dsRecords) > 0 && !signedByAnchor:
+		problems = append(problems, "the DNSKEY set is not validly signed by a key matching a DS record")
+	case len(dsRecords) == 0 && len(state.keys) > 0 && parent.status == StatusSecure:
+		problems = append(problems, "the zone is signed but the parent publishes no DS record")
+	}
+	if len(state.keys) > 0 && len(keySigs) == 0 {
+		problems = append(problems, "no RRSIG records were returned; the resolver may strip DNSSEC data")
+	}
+	zone.Problems = problems
+
+	switch {
+	case parent.status != StatusSecure:
+		state.status = parent.status
+	case len(dsRecords) == 0:
+		state.status = StatusInsecure
+	case len(problems) > 0:
+		state.status = StatusBogus
+	default:
+		state.status = StatusSecure
+	}
+	zone.Status = state.status
+	return state, nil
+}
+
+// isZoneApex reports whether a name has its own SOA record, i.e. is the apex of a zone.
+func (c *checker) isZoneApex(name string) bool {
+	answers, err := c.resolver.QueryDNSSEC(c.ctx, name, dnsmessage.TypeSOA)
+	if err != nil {
+		return false
+	}
+	records, _ := recordsOf(answers, name, dnsmessage.TypeSOA)
+	return len(records) > 0
+}
+
+// verifySignatures checks every RRSIG over an RRset.
+func (c *checker) verifySignatures(owner string, rrtype dnsmessage.Type, rdatas [][]byte, signatures []rrsigRecord, keys []dnskeyRecord) []Signature {
+	if len(rdatas) == 0 {
+		return nil
+	}
+	var results []Signature
+	for _, sig := range signatures {
+		result := Signature{
+			Covers:     typeName(rrtype),
+			KeyTag:     sig.keyTag,
+			Algorithm:  algorithmName(sig.algorithm),
+			Signer:     sig.signerName,
+			Inception:  time.Unix(int64(sig.inception), 0).UTC(),
+			Expiration: time.Unix(int64(sig.expiration), 0).UTC(),
+		}
+		if err := verifyRRSIG(sig, owner, rrtype, rdatas, keys, c.now); err != nil {
+			result.Error = err.Error()
+		} else {
+			result.Valid = true
+		}
+		results = append(results, result)
+	}
+	return results
+}
+
+// recordsOf picks the canonical RDATAs of one RRset, and the RRSIGs covering it, out of an
+// answer.
+func recordsOf(answers []dnsmessage.Resource, owner string, rrtype dnsmessage.Type) ([][]byte, []rrsigRecord) {
+	var records [][]byte
+	var signatures []rrsigRecord
+	for _, answer := range answers {
+		if !strings.EqualFold(answer.Header.Name.String(), owner) {
+			continue
+		}
+		switch answer.Header.Type {
+		case rrtype:
+			if data, err := canonicalRData(answer); err == nil {
+				records = append(records, data)
+			}
+		case typeRRSIG:
+			body, ok := answer.Body.(*dnsmessage.UnknownResource)
+			if !ok {
+				continue
+			}
+			if sig, err := parseRRSIG(body.Data); err == nil && sig.typeCovered == rrtype {
+				signatures = append(signatures, sig)
+			}
+		}
+	}
+	return records, signatures
+}
+
+func (r *Report) addZone(zone Zone) {
+	r.Zones = append(r.Zones, zone)
+	for _, problem := range zone.Problems {
+		r.BrokenLinks = append(r.BrokenLinks, zone.Name+": "+problem)
+	}
+	for _, key := range zone.DNSKEYs {
+		found := false
+		for _, algorithm := range r.Algorithms {
+			found = found || algorithm == key.Algorithm
+		}
+		if !found {
+			r.Algorithms = append(r.Algorithms, key.Algorithm)
+		}
+	}
+}
+
+func anyValid(signatures []Signature) bool {
+	for _, signature := range signatures {
+		if signature.Valid {
+			return true
+		}
+	}
+	return false
+}
+
+func typeName(rrtype dnsmessage.Type) string {
+	switch rrtype {
+	case typeDS:
+		return "DS"
+	case typeDNSKEY:
+		return "DNSKEY"
+	}
+	return strings.TrimPrefix(rrtype.String(), "Type")
+}
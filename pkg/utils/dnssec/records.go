@@ -0,0 +1,364 @@
+package dnssec
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math/big"
+	"sort"
+	"strings"
+	"time"
+
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+// DNSSEC record types, which dnsmessage does not parse.
+const (
+	typeDS     = dnsmessage.Type(43)
+	typeRRSIG  = dnsmessage.Type(46)
+	typeDNSKEY = dnsmessage.Type(48)
+)
+
+// DNSKEY flags (RFC 4034 section 2.1.1, RFC 5011).
+const (
+	flagZoneKey = 0x0100
+	flagRevoke  = 0x0080
+	flagSEP     = 0x0001
+)
+
+var algorithmNames = map[uint8]string{
+	1:  "RSAMD5",
+	3:  "DSA",
+	5:  "RSASHA1",
+	6:  "DSA-NSEC3-SHA1",
+	7:  "RSASHA1-NSEC3-SHA1",
+	8:  "RSASHA256",
+	10: "RSASHA512",
+	12: "ECC-GOST",
+	13: "ECDSAP256SHA256",
+	14: "ECDSAP384SHA384",
+	15: "ED25519",
+	16: "ED448",
+}
+
+var digestTypeNames = map[uint8]string{1: "SHA-1", 2: "SHA-256", 3: "GOST R 34.11-94", 4: "SHA-384"}
+
+func algorithmName(algorithm uint8) string {
+	if name, ok := algorithmNames[algorithm]; ok {
+		return name
+	}
+	return fmt.Sprintf("ALG%d", algorithm)
+}
+
+// dsRecord is a parsed DS record.
+type dsRecord struct {
+	keyTag     uint16
+	algorithm  uint8
+	digestType uint8
+	digest     []byte
+}
+
+func parseDS(data []byte) (dsRecord, error) {
+	if len(data) < 5 {
+		return dsRecord{}, errors.New("short DS record")
+	}
+	return dsRecord{
+		keyTag:     binary.BigEndian.Uint16(data),
+		algorithm:  data[2],
+		digestType: data[3],
+		digest:     data[4:],
+	}, nil
+}
+
+func mustDS(keyTag uint16, algorithm, digestType uint8, digest string) dsRecord {
+	raw, err := hex.DecodeString(digest)
+	if err != nil {
+		panic(err)
+	}
+	return dsRecord{keyTag: keyTag, algorithm: algorithm, digestType: digestType, digest: raw}
+}
+
+// dnskeyRecord is a parsed DNSKEY record.
+type dnskeyRecord struct {
+	rdata     []byte
+	flags     uint16
+	algorithm uint8
+	publicKey []byte
+	keyTag    uint16
+}
+
+func parseDNSKEY(data []byte) (dnskeyRecord, error) {
+	if len(data) < 5 {
+		return dnskeyRecord{}, errors.New("short DNSKEY record")
+	}
+	return dnskeyRecord{
+		rdata:     data,
+		flags:     binary.BigEndian.Uint16(data),
+		algorithm: data[3],
+		publicKey: data[4:],
+		keyTag:    keyTag(data),
+	}, nil
+}
+
+// keyTag computes the key tag of a DNSKEY (RFC 4034 appendix B).
+func keyTag(rdata []byte) uint16 {
+	var ac uint32
+	for i, b := range rdata {
+		if i&1 == 0 {
+			ac += uint32(b) << 8
+		} else {
+			ac += uint32(b)
+		}
+	}
+	ac += ac >> 16 & 0xFFFF
+	return uint16(ac & 0xFFFF)
+}
+
+// matchesDS reports whether the DS record is a digest of this key under the given owner.
+func (k dnskeyRecord) matchesDS(owner string, ds dsRecord) bool {
+	if ds.keyTag != k.keyTag || ds.algorithm != k.algorithm {
+		return false
+	}
+	data := append(nameWire(owner), k.rdata...)
+	var digest []byte
+	switch ds.digestType {
+	case 1:
+		sum := sha1.Sum(data)
+		digest = sum[:]
+	case 2:
+		sum := sha256.Sum256(data)
+		digest = sum[:]
+	case 4:
+		sum := sha512.Sum384(data)
+		digest = sum[:]
+	default:
+		return false
+	}
+	return bytes.Equal(digest, ds.digest)
+}
+
+// keyBits returns the size of the key, or 0 when the algorithm is not supported.
+func (k dnskeyRecord) keyBits() int {
+	switch key := k.parsePublicKey().(type) {
+	case *rsa.PublicKey:
+		return key.N.BitLen()
+	case *ecdsa.PublicKey:
+		return key.Curve.Params().BitSize
+	case ed25519.PublicKey:
+		return 256
+	}
+	return 0
+}
+
+func (k dnskeyRecord) parsePublicKey() crypto.PublicKey {
+	key := k.publicKey
+	switch k.algorithm {
+	case 5, 7, 8, 10:
+		if len(key) < 3 {
+			return nil
+		}
+		exponentLength, offset := int(key[0]), 1
+		if exponentLength == 0 {
+			exponentLength, offset = int(binary.BigEndian.Uint16(key[1:])), 3
+		}
+		if exponentLength == 0 || len(key) <= offset+exponentLength {
+			return nil
+		}
+		exponent := new(big.Int).SetBytes(key[offset : offset+exponentLength])
+		if !exponent.IsInt64() || exponent.Int64() > 1<<31-1 {
+			return nil
+		}
+		return &rsa.PublicKey{N: new(big.Int).SetBytes(key[offset+exponentLength:]), E: int(exponent.Int64())}
+	case 13, 14:
+		curve := elliptic.P256()
+		if k.algorithm == 14 {
+			curve = elliptic.P384()
+		}
+		size := curve.Params().BitSize / 8
+		if len(key) != 2*size {
+			return nil
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: new(big.Int).SetBytes(key[:size]), Y: new(big.Int).SetBytes(key[size:])}
+	case 15:
+		if len(key) != ed25519.PublicKeySize {
+			return nil
+		}
+		return ed25519.PublicKey(key)
+	}
+	return nil
+}
+
+// verify checks a signature made with this key over data.
+func (k dnskeyRecord) verify(data, signature []byte) error {
+	publicKey := k.parsePublicKey()
+	if publicKey == nil {
+		return fmt.Errorf("unsupported or malformed %s key", algorithmName(k.algorithm))
+	}
+	var hash crypto.Hash
+	switch k.algorithm {
+	case 5, 7:
+		hash = crypto.SHA1
+	case 8, 13:
+		hash = crypto.SHA256
+	case 14:
+		hash = crypto.SHA384
+	case 10:
+		hash = crypto.SHA512
+	}
+
+	switch key := publicKey.(type) {
+	case *rsa.PublicKey:
+		h := hash.New()
+		h.Write(data)
+		if err := rsa.VerifyPKCS1v15(key, hash, h.Sum(nil), signature); err != nil {
+			return errors.New("signature does not verify")
+		}
+	case *ecdsa.PublicKey:
+		h := hash.New()
+		h.Write(data)
+		half := len(signature) / 2
+		if len(signature) != 2*(key.Curve.Params().BitSize/8) ||
+			!ecdsa.Verify(key, h.Sum(nil), new(big.Int).SetBytes(signature[:half]), new(big.Int).SetBytes(signature[half:])) {
+			return errors.New("signature does not verify")
+		}
+	case ed25519.PublicKey:
+		if !ed25519.Verify(key, data, signature) {
+			return errors.New("signature does not verify")
+		}
+	}
+	return nil
+}
+
+// rrsigRecord is a parsed RRSIG record.
+type rrsigRecord struct {
+	typeCovered dnsmessage.Type
+	algorithm   uint8
+	labels      uint8
+	originalTTL uint32
+	expiration  uint32
+	inception   uint32
+	keyTag      uint16
+	signerName  string
+	signature   []byte
+	header      []byte // RDATA up to the signature, with the signer name in canonical form
+}
+
+func parseRRSIG(data []byte) (rrsigRecord, error) {
+	if len(data) < 19 {
+		return rrsigRecord{}, errors.New("short RRSIG record")
+	}
+	sig := rrsigRecord{
+		typeCovered: dnsmessage.Type(binary.BigEndian.Uint16(data)),
+		algorithm:   data[2],
+		labels:      data[3],
+		originalTTL: binary.BigEndian.Uint32(data[4:]),
+		expiration:  binary.BigEndian.Uint32(data[8:]),
+		inception:   binary.BigEndian.Uint32(data[12:]),
+		keyTag:      binary.BigEndian.Uint16(data[16:]),
+	}
+	var labels []string
+	offset := 18
+	for {
+		if offset >= len(data) {
+			return rrsigRecord{}, errors.New("malformed RRSIG signer name")
+		}
+		length := int(data[offset])
+		offset++
+		if length == 0 {
+			break
+		}
+		if length > 63 || offset+length > len(data) {
+			return rrsigRecord{}, errors.New("malformed RRSIG signer name")
+		}
+		labels = append(labels, string(data[offset:offset+length]))
+		offset += length
+	}
+	sig.signerName = strings.ToLower(strings.Join(labels, ".")) + "."
+	sig.signature = data[offset:]
+	sig.header = append(append([]byte{}, data[:18]...), nameWire(sig.signerName)...)
+	return sig, nil
+}
+
+// validityError reports whether the signature is outside its validity period.
+func (s rrsigRecord) validityError(now time.Time) error {
+	switch {
+	case now.Before(time.Unix(int64(s.inception), 0)):
+		return errors.New("signature is not yet valid")
+	case now.After(time.Unix(int64(s.expiration), 0)):
+		return errors.New("signature has expired")
+	}
+	return nil
+}
+
+// verifyRRSIG checks an RRSIG over an RRset (given as canonical RDATAs) with the zone's
+// keys, returning nil when a key with the signature's tag and algorithm verifies it.
+func verifyRRSIG(sig rrsigRecord, owner string, rrtype dnsmessage.Type, rdatas [][]byte, keys []dnskeyRecord, now time.Time) error {
+	if err := sig.validityError(now); err != nil {
+		return err
+	}
+
+	sorted := append([][]byte{}, rdatas...)
+	sort.Slice(sorted, func(i, j int) bool { return bytes.Compare(sorted[i], sorted[j]) < 0 })
+	data := append([]byte{}, sig.header...)
+	ownerWire := nameWire(owner)
+	for i, rdata := range sorted {
+		if i > 0 && bytes.Equal(rdata, sorted[i-1]) {
+			continue
+		}
+		data = append(data, ownerWire...)
+		data = binary.BigEndian.AppendUint16(data, uint16(rrtype))
+		data = binary.BigEndian.AppendUint16(data, uint16(dnsmessage.ClassINET))
+		data = binary.BigEndian.AppendUint32(data, sig.originalTTL)
+		data = binary.BigEndian.AppendUint16(data, uint16(len(rdata)))
+		data = append(data, rdata...)
+	}
+
+	lastErr := fmt.Errorf("no DNSKEY with key tag %d and algorithm %s", sig.keyTag, algorithmName(sig.algorithm))
+	for _, key := range keys {
+		if key.keyTag != sig.keyTag || key.algorithm != sig.algorithm || key.flags&flagZoneKey == 0 {
+			continue
+		}
+		if lastErr = key.verify(data, sig.signature); lastErr == nil {
+			return nil
+		}
+	}
+	return lastErr
+}
+
+// canonicalRData returns a record's RDATA in canonical form (RFC 4034 section 6.2).
+func canonicalRData(resource dnsmessage.Resource) ([]byte, error) {
+	switch body := resource.Body.(type) {
+	case *dnsmessage.UnknownResource:
+		return body.Data, nil
+	case *dnsmessage.SOAResource:
+		data := append(nameWire(body.NS.String()), nameWire(body.MBox.String())...)
+		for _, value := range []uint32{body.Serial, body.Refresh, body.Retry, body.Expire, body.MinTTL} {
+			data = binary.BigEndian.AppendUint32(data, value)
+		}
+		return data, nil
+	}
+	return nil, fmt.Errorf("cannot canonicalize %s records", resource.Header.Type)
+}
+
+// nameWire encodes a domain name in lowercase, uncompressed wire format.
+func nameWire(name string) []byte {
+	var wire []byte
+	for _, label := range strings.Split(strings.ToLower(strings.TrimSuffix(name, ".")), ".") {
+		if label == "" {
+			continue
+		}
+		wire = append(wire, byte(len(label)))
+		wire = append(wire, label...)
+	}
+	return append(wire, 0)
+}
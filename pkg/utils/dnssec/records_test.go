@@ -0,0 +1,109 @@
+package dnssec
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"testing"
+)
+
+// dnskeyRData builds DNSKEY RDATA from its presentation form.
+func dnskeyRData(t *testing.T, flags uint16, algorithm uint8, publicKey string) []byte {
+	t.Helper()
+	key, err := base64.StdEncoding.DecodeString(publicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rdata := binary.BigEndian.AppendUint16(nil, flags)
+	return append(append(rdata, 3, algorithm), key...)
+}
+
+const (
+	// Root zone KSK-2017, the IANA trust anchor with key tag 20326.
+	rootKSK2017 = "AwEAAaz/tAm8yTn4Mfeh5eyI96WSVexTBAvkMgJzkKTOiW1vkIbzxeF3+/4RgWOq7HrxRixHlFlExOLAJr5emLvN7SWXgnLh4+B5xQlNVz8Og8kvArMtNROxVQuCaSnIDdD5LKyWbRd2n9WGe2R8PzgCmr3EgVLrjyBxWezF0jLHwVN8efS3rCj/EWgvIWgb9tarpVUDK/b58Da+sqqls3eNbuv7pr+eoZG+SrDK6nWeL3c6H5Apxz7LjVc1uTIdsIXxuOLYA4/ilBmSVIzuDWfdRUfhHdY6+cn8HFRm+2hM8AnXGXws9555KrUB5qihylGa8subX2Nn6UwNR1AkUTV74bU="
+	// The dskey.example.com key from RFC 4034 section 5.4 and RFC 4509 section 2.2.1.
+	rfcExampleKey = "AQOeiiR0GOMYkDshWoSKz9XzfwJr1AYtsmx3TGkJaNXVbfi/2pHm822aJ5iI9BMzNXxeYCmZDRD99WYwYqUSdjMmmAphXdvxegXd/M5+X7OrzKBaMbCVdFLUUh6DhweJBjEVv5f2wwjM9XzcnOf+EPbtG9DMBmADjFDc2w/rljwvFw=="
+)
+
+func TestDNSKEYMatchesDS(t *testing.T) {
+	rootKey, err := parseDNSKEY(dnskeyRData(t, 257, 8, rootKSK2017))
+	if err != nil {
+		t.Fatal(err)
+	}
+	exampleKey, err := parseDNSKEY(dnskeyRData(t, 256, 5, rfcExampleKey))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name  string
+		key   dnskeyRecord
+		owner string
+		ds    dsRecord
+		want  bool
+	}{
+		{"root trust anchor", rootKey, ".", rootTrustAnchors[0], true},
+		{"root key under another owner", rootKey, "com.", rootTrustAnchors[0], false},
+		{"root key against the other anchor", rootKey, ".", rootTrustAnchors[1], false},
+		{"RFC 4034 SHA-1", exampleKey, "dskey.example.com.", mustDS(60485, 5, 1, "2bb183af5f22588179a53b0a98631fad1a292118"), true},
+		{"RFC 4509 SHA-256", exampleKey, "DSKEY.example.com", mustDS(60485, 5, 2, "d4b7d520e7bb5f0f67674a0cceb1e3e0614b93c4f9e99b8383f6a1e4469da50a"), true},
+		{"wrong digest", exampleKey, "dskey.example.com.", mustDS(60485, 5, 1, "2bb183af5f22588179a53b0a98631fad1a292119"), false},
+		{"wrong algorithm", exampleKey, "dskey.example.com.", mustDS(60485, 8, 1, "2bb183af5f22588179a53b0a98631fad1a292118"), false},
+		{"unsupported digest type", exampleKey, "dskey.example.com.", mustDS(60485, 5, 3, "2bb183af5f22588179a53b0a98631fad1a292118"), false},
+	}
+	for _, tt := range tests {
+		if got := tt.key.matchesDS(tt.owner, tt.ds); got != tt.want {
+			t.Errorf("%s: matchesDS() = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestKeyTag(t *testing.T) {
+	tests := []struct {
+		name      string
+		rdata     []byte
+		wantTag   uint16
+		wantBits  int
+		wantFlags uint16
+	}{
+		{"root KSK-2017", dnskeyRData(t, 257, 8, rootKSK2017), 20326, 2048, flagZoneKey | flagSEP},
+		{"RFC 4034 example", dnskeyRData(t, 256, 5, rfcExampleKey), 60485, 1024, flagZoneKey},
+		{"revoked root KSK", dnskeyRData(t, 257|flagRevoke, 8, rootKSK2017), 20454, 2048, flagZoneKey | flagSEP | flagRevoke},
+	}
+	for _, tt := range tests {
+		key, err := parseDNSKEY(tt.rdata)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if key.keyTag != tt.wantTag || key.keyBits() != tt.wantBits || key.flags != tt.wantFlags {
+			t.Errorf("%s: key tag %d, %d bits, flags %#x; want %d, %d, %#x", tt.name, key.keyTag, key.keyBits(), key.flags, tt.wantTag, tt.wantBits, tt.wantFlags)
+		}
+	}
+}
+
+func TestParseDS(t *testing.T) {
+	ds, err := parseDS([]byte{0x4f, 0x66, 8, 2, 0xe0, 0x6d})
+	if err != nil || ds.keyTag != 20326 || ds.algorithm != 8 || ds.digestType != 2 || string(ds.digest) != "\xe0\x6d" {
+		t.Errorf("parseDS() = %+v, %v", ds, err)
+	}
+	if _, err := parseDS([]byte{0x4f, 0x66, 8, 2}); err == nil {
+		t.Error("parseDS() accepted a record without a digest")
+	}
+	if _, err := parseDNSKEY([]byte{1, 1, 3, 8}); err == nil {
+		t.Error("parseDNSKEY() accepted a record without a key")
+	}
+}
+
+func TestNameWire(t *testing.T) {
+	tests := map[string]string{
+		".":                  "\x00",
+		"":                   "\x00",
+		"com.":               "\x03com\x00",
+		"DSKEY.Example.COM":  "\x05dskey\x07example\x03com\x00",
+		"dskey.example.com.": "\x05dskey\x07example\x03com\x00",
+	}
+	for name, want := range tests {
+		if got := string(nameWire(name)); got != want {
+			t.Errorf("nameWire(%q) = %q, want %q", name, got, want)
+		}
+	}
+}
@@ -0,0 +1,232 @@
+package domain
+
+import (
+	"bytes"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"net"
+)
+
+// maxDecodedCertificates caps how many PEM certificates one decode reads.
+const maxDecodedCertificates = 10
+
+// Kinds of decoded input.
+const (
+	DecodedTypeCertificate = "certificate"
+	DecodedTypeCSR         = "csr"
+)
+
+// extensionNames names the common X.509 extensions by OID.
+var extensionNames = map[string]string{
+	"2.5.29.14":               "Subject Key Identifier",
+	"2.5.29.15":               "Key Usage",
+	"2.5.29.17":               "Subject Alternative Name",
+	"2.5.29.19":               "Basic Constraints",
+	"2.5.29.30":               "Name Constraints",
+	"2.5.29.31":               "CRL Distribution Points",
+	"2.5.29.32":               "Certificate Policies",
+	"2.5.29.35":               "Authority Key Identifier",
+	"2.5.29.37":               "Extended Key Usage",
+	"1.3.6.1.5.5.7.1.1":       "Authority Information Access",
+	"1.3.6.1.5.5.7.1.24":      "TLS Feature (OCSP Must-Staple)",
+	"1.3.6.1.4.1.11129.2.4.2": "Signed Certificate Timestamps",
+	"1.3.6.1.4.1.11129.2.4.3": "Precertificate Poison",
+}
+
+// extKeyUsageNames names the extended key usages crypto/x509 knows.
+var extKeyUsageNames = map[x509.ExtKeyUsage]string{
+	x509.ExtKeyUsageAny:             "Any",
+	x509.ExtKeyUsageServerAuth:      "Server Authentication",
+	x509.ExtKeyUsageClientAuth:      "Client Authentication",
+	x509.ExtKeyUsageCodeSigning:     "Code Signing",
+	x509.ExtKeyUsageEmailProtection: "Email Protection",
+	x509.ExtKeyUsageTimeStamping:    "Time Stamping",
+	x509.ExtKeyUsageOCSPSigning:     "OCSP Signing",
+}
+
+// CertificateExtension is one X.509 extension of a certificate or CSR.
+type CertificateExtension struct {
+	OID      string `json:"oid"`
+	Name     string `json:"name,omitempty"`
+	Critical bool   `json:"critical"`
+}
+
+// CertificateDetails are the parts of a certificate an SSL check does not report.
+type CertificateDetails struct {
+	Extensions            []CertificateExtension `json:"extensions"`
+	ExtKeyUsage           []string               `json:"ext_key_usage,omitempty"`
+	IsCA                  bool                   `json:"is_ca"`
+	MaxPathLen            *int                   `json:"max_path_len,omitempty"` // Only for CAs that set one
+	IPAddresses           []string               `json:"ip_addresses,omitempty"`
+	EmailAddresses        []string               `json:"email_addresses,omitempty"`
+	OCSPServers           []string               `json:"ocsp_servers,omitempty"`
+	IssuingCertificateURL []string               `json:"issuing_certificate_url,omitempty"`
+	CRLDistribution       []string               `json:"crl_distribution_points,omitempty"`
+}
+
+// CSRInfo is the analysis of a certificate signing request.
+type CSRInfo struct {
+	Subject            string                 `json:"subject"`
+	DNSNames           []string               `json:"dns_names,omitempty"`
+	IPAddresses        []string               `json:"ip_addresses,omitempty"`
+	EmailAddresses     []string               `json:"email_addresses,omitempty"`
+	PublicKeyAlgorithm string                 `json:"public_key_algorithm"`
+	KeySize            int                    `json:"key_size"`
+	WeakKey            bool                   `json:"weak_key"`
+	PublicKeyPin       string                 `json:"public_key_pin_sha256"`
+	SignatureAlgorithm string                 `json:"signature_algorithm"`
+	SignatureValid     bool                   `json:"signature_valid"` // Signed by the key it requests a certificate for
+	Extensions         []CertificateExtension `json:"extensions"`
+}
+
+// DecodedCertificate is the analysis of an uploaded certificate or CSR.
+type DecodedCertificate struct {
+	Type        string              // certificate or csr
+	Certificate *SSLInfo            // The SSL check's analysis of the certificate and any chain after it
+	Details     *CertificateDetails // Extensions and other fields of the leaf
+	CSR         *CSRInfo
+}
+
+// DecodeCertificate analyzes a PEM or DER certificate, with any chain following it in PEM,
+// or a certificate signing request, without connecting anywhere. host, when set, is verified
+// against the certificate like the host of an SSL check.
+func DecodeCertificate(data []byte, host string) (*DecodedCertificate, error) {
+	// Only PEM is trimmed: DER is binary and may end in a byte that reads as whitespace
+	trimmed := bytes.TrimSpace(data)
+	if len(trimmed) == 0 {
+		return nil, fmt.Errorf("no certificate given")
+	}
+
+	var certs []*x509.Certificate
+	if bytes.HasPrefix(trimmed, []byte("-----BEGIN")) {
+		for block, rest := pem.Decode(trimmed); block != nil; block, rest = pem.Decode(rest) {
+			switch block.Type {
+			case "CERTIFICATE REQUEST", "NEW CERTIFICATE REQUEST":
+				if len(certs) == 0 {
+					csr, err := decodeCSR(block.Bytes)
+					if err != nil {
+						return nil, err
+					}
+					return &DecodedCertificate{Type: DecodedTypeCSR, CSR: csr}, nil
+				}
+			case "CERTIFICATE":
+				cert, err := x509.ParseCertificate(block.Bytes)
+				if err != nil {
+					return nil, fmt.Errorf("invalid certificate %d: %w", len(certs)+1, err)
+				}
+				if len(certs) < maxDecodedCertificates {
+					certs = append(certs, cert)
+				}
+			}
+		}
+		if len(certs) == 0 {
+			return nil, fmt.Errorf("no CERTIFICATE or CERTIFICATE REQUEST block found in the PEM input")
+		}
+	} else {
+		cert, err := x509.ParseCertificate(data)
+		if err != nil {
+			if csr, csrErr := decodeCSR(data); csrErr == nil {
+				return &DecodedCertificate{Type: DecodedTypeCSR, CSR: csr}, nil
+			}
+			return nil, fmt.Errorf("input is neither PEM nor a DER certificate or CSR: %w", err)
+		}
+		certs = append(certs, cert)
+	}
+
+	sslInfo, _ := analyzeCertificates(host, certs, "")
+	sslInfo.Grade, sslInfo.Findings = gradeSSL(sslInfo, certs)
+	return &DecodedCertificate{
+		Type:        DecodedTypeCertificate,
+		Certificate: sslInfo,
+		Details:     newCertificateDetails(certs[0]),
+	}, nil
+}
+
+// DecodeCSR analyzes a PEM or DER certificate signing request.
+func DecodeCSR(data []byte) (*CSRInfo, error) {
+	data = bytes.TrimSpace(data)
+	if len(data) == 0 {
+		return nil, fmt.Errorf("no certificate request given")
+	}
+	if !bytes.HasPrefix(data, []byte("-----BEGIN")) {
+		return decodeCSR(data)
+	}
+	for block, rest := pem.Decode(data); block != nil; block, rest = pem.Decode(rest) {
+		if block.Type == "CERTIFICATE REQUEST" || block.Type == "NEW CERTIFICATE REQUEST" {
+			return decodeCSR(block.Bytes)
+		}
+	}
+	return nil, fmt.Errorf("no CERTIFICATE REQUEST block found in the PEM input")
+}
+
+// decodeCSR analyzes a DER certificate signing request.
+func decodeCSR(der []byte) (*CSRInfo, error) {
+	csr, err := x509.ParseCertificateRequest(der)
+	if err != nil {
+		return nil, fmt.Errorf("invalid certificate request: %w", err)
+	}
+	keySize := KeySize(&x509.Certificate{PublicKey: csr.PublicKey})
+	info := &CSRInfo{
+		Subject:            csr.Subject.String(),
+		DNSNames:           csr.DNSNames,
+		EmailAddresses:     csr.EmailAddresses,
+		PublicKeyAlgorithm: csr.PublicKeyAlgorithm.String(),
+		KeySize:            keySize,
+		WeakKey:            isWeakKey(csr.PublicKeyAlgorithm, keySize),
+		PublicKeyPin:       PublicKeyPin(&x509.Certificate{RawSubjectPublicKeyInfo: csr.RawSubjectPublicKeyInfo}),
+		SignatureAlgorithm: csr.SignatureAlgorithm.String(),
+		SignatureValid:     csr.CheckSignature() == nil,
+		Extensions:         describeExtensions(csr.Extensions),
+	}
+	info.IPAddresses = ipStrings(csr.IPAddresses)
+	return info, nil
+}
+
+// newCertificateDetails lists the extensions and the fields they set.
+func newCertificateDetails(cert *x509.Certificate) *CertificateDetails {
+	details := &CertificateDetails{
+		Extensions:            describeExtensions(cert.Extensions),
+		IsCA:                  cert.IsCA,
+		IPAddresses:           ipStrings(cert.IPAddresses),
+		EmailAddresses:        cert.EmailAddresses,
+		OCSPServers:           cert.OCSPServer,
+		IssuingCertificateURL: cert.IssuingCertificateURL,
+		CRLDistribution:       cert.CRLDistributionPoints,
+	}
+	for _, usage := range cert.ExtKeyUsage {
+		name, ok := extKeyUsageNames[usage]
+		if !ok {
+			name = fmt.Sprintf("Unknown (%d)", usage)
+		}
+		details.ExtKeyUsage = append(details.ExtKeyUsage, name)
+	}
+	for _, oid := range cert.UnknownExtKeyUsage {
+		details.ExtKeyUsage = append(details.ExtKeyUsage, oid.String())
+	}
+	if cert.IsCA && (cert.MaxPathLen > 0 || cert.MaxPathLenZero) {
+		maxPathLen := cert.MaxPathLen
+		details.MaxPathLen = &maxPathLen
+	}
+	return details
+}
+
+// describeExtensions names the extensions it knows.
+func describeExtensions(extensions []pkix.Extension) []CertificateExtension {
+	described := []CertificateExtension{}
+	for _, extension := range extensions {
+		oid := extension.Id.String()
+		described = append(described, CertificateExtension{OID: oid, Name: extensionNames[oid], Critical: extension.Critical})
+	}
+	return described
+}
+
+// ipStrings formats IP addresses.
+func ipStrings(ips []net.IP) []string {
+	var formatted []string
+	for _, ip := range ips {
+		formatted = append(formatted, ip.String())
+	}
+	return formatted
+}
@@ -0,0 +1,120 @@
+package domain
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"testing"
+)
+
+func TestDecodeCertificate(t *testing.T) {
+	root, rootKey := issueTestCertificate(t, "Private Root", true, nil, nil)
+	leaf, _ := issueTestCertificate(t, "example.com", false, root, rootKey)
+	var chain bytes.Buffer
+	for _, cert := range []*x509.Certificate{leaf, root} {
+		pem.Encode(&chain, &pem.Block{Type: "CERTIFICATE", Bytes: cert.Raw})
+	}
+
+	decoded, err := DecodeCertificate(chain.Bytes(), "example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if decoded.Type != DecodedTypeCertificate || decoded.Certificate == nil || decoded.Details == nil {
+		t.Fatalf("decoded = %+v, want a certificate with details", decoded)
+	}
+	info := decoded.Certificate
+	if info.Subject != "CN=example.com" || len(info.CertificateChain) != 2 {
+		t.Errorf("subject %q with %d chain certificates, want CN=example.com with 2", info.Subject, len(info.CertificateChain))
+	}
+	if !info.Hostname.Matches || info.TrustIssue != TrustIssueUntrustedRoot || info.Grade == "" {
+		t.Errorf("hostname %+v, trust issue %q, grade %q", info.Hostname, info.TrustIssue, info.Grade)
+	}
+	if len(decoded.Details.Extensions) == 0 || decoded.Details.IsCA {
+		t.Errorf("details = %+v, want the leaf's extensions", decoded.Details)
+	}
+	for _, extension := range decoded.Details.Extensions {
+		if extension.OID == "2.5.29.17" && extension.Name != "Subject Alternative Name" {
+			t.Errorf("SAN extension named %q", extension.Name)
+		}
+	}
+
+	decoded, err = DecodeCertificate(leaf.Raw, "other.example.org")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if decoded.Certificate.Hostname.Matches {
+		t.Error("DER certificate matches other.example.org")
+	}
+
+	decoded, err = DecodeCertificate(leaf.Raw, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if decoded.Certificate.Hostname.Host != "" {
+		t.Errorf("hostname checked without a host: %+v", decoded.Certificate.Hostname)
+	}
+}
+
+// A DER certificate's signature can end in a byte that reads as whitespace.
+func TestDecodeCertificateDERTrailingWhitespaceByte(t *testing.T) {
+	root, rootKey := issueTestCertificate(t, "Private Root", true, nil, nil)
+	for range 1000 {
+		leaf, _ := issueTestCertificate(t, "example.com", false, root, rootKey)
+		if len(bytes.TrimSpace(leaf.Raw)) == len(leaf.Raw) {
+			continue
+		}
+		if _, err := DecodeCertificate(leaf.Raw, ""); err != nil {
+			t.Fatalf("DecodeCertificate(DER ending in %#x) error = %v", leaf.Raw[len(leaf.Raw)-1], err)
+		}
+		return
+	}
+	t.Skip("no certificate ending in a whitespace byte was issued")
+}
+
+func TestDecodeCertificateCSR(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := x509.CreateCertificateRequest(rand.Reader, &x509.CertificateRequest{
+		Subject:  pkix.Name{CommonName: "example.com"},
+		DNSNames: []string{"example.com", "www.example.com"},
+	}, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for name, data := range map[string][]byte{
+		"pem": pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE REQUEST", Bytes: der}),
+		"der": der,
+	} {
+		decoded, err := DecodeCertificate(data, "")
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if decoded.Type != DecodedTypeCSR || decoded.CSR == nil {
+			t.Fatalf("%s: decoded = %+v, want a CSR", name, decoded)
+		}
+		csr := decoded.CSR
+		if !csr.SignatureValid || csr.KeySize != 256 || csr.WeakKey || len(csr.DNSNames) != 2 || csr.PublicKeyPin == "" {
+			t.Errorf("%s: CSR = %+v", name, csr)
+		}
+	}
+}
+
+func TestDecodeCertificateInvalid(t *testing.T) {
+	for name, data := range map[string][]byte{
+		"empty":     []byte("  \n"),
+		"garbage":   []byte("not a certificate"),
+		"wrong PEM": pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: []byte{1, 2, 3}}),
+		"bad PEM":   pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: []byte{1, 2, 3}}),
+	} {
+		if _, err := DecodeCertificate(data, ""); err == nil {
+			t.Errorf("%s: no error", name)
+		}
+	}
+}
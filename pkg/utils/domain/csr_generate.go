@@ -0,0 +1,127 @@
+package domain
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"net"
+	"strings"
+)
+
+// Key types a CSR can be generated for.
+const (
+	KeyTypeRSA   = "rsa"
+	KeyTypeECDSA = "ecdsa"
+)
+
+// CSRSubject is the distinguished name requested in a CSR.
+type CSRSubject struct {
+	CommonName         string   `json:"common_name" example:"www.example.com"`
+	Organization       []string `json:"organization,omitempty" example:"Example Ltd"`
+	OrganizationalUnit []string `json:"organizational_unit,omitempty"`
+	Country            []string `json:"country,omitempty" example:"GB"`
+	Province           []string `json:"province,omitempty"`
+	Locality           []string `json:"locality,omitempty" example:"London"`
+}
+
+// CSROptions describe the key pair and CSR to generate.
+type CSROptions struct {
+	KeyType        string // rsa (default) or ecdsa
+	KeySize        int    // 2048 (default), 3072 or 4096 bits for RSA; 256 (default), 384 or 521 for ECDSA
+	Subject        CSRSubject
+	DNSNames       []string
+	IPAddresses    []string
+	EmailAddresses []string
+}
+
+// GeneratedCSR is a new private key and the CSR signed with it.
+type GeneratedCSR struct {
+	PrivateKeyPEM string // PKCS #8
+	CSRPEM        string
+	CSR           *CSRInfo // The analysis of the generated CSR
+}
+
+// GenerateCSR creates a key pair and a certificate signing request for it. Nothing is kept:
+// the private key is only returned.
+func GenerateCSR(options CSROptions) (*GeneratedCSR, error) {
+	if options.Subject.CommonName == "" && len(options.DNSNames) == 0 && len(options.IPAddresses) == 0 {
+		return nil, fmt.Errorf("a common name or at least one subject alternative name is required")
+	}
+	template := &x509.CertificateRequest{
+		Subject: pkix.Name{
+			CommonName:         options.Subject.CommonName,
+			Organization:       options.Subject.Organization,
+			OrganizationalUnit: options.Subject.OrganizationalUnit,
+			Country:            options.Subject.Country,
+			Province:           options.Subject.Province,
+			Locality:           options.Subject.Locality,
+		},
+		DNSNames:       options.DNSNames,
+		EmailAddresses: options.EmailAddresses,
+	}
+	for _, address := range options.IPAddresses {
+		ip := net.ParseIP(strings.TrimSpace(address))
+		if ip == nil {
+			return nil, fmt.Errorf("invalid IP address %q", address)
+		}
+		template.IPAddresses = append(template.IPAddresses, ip)
+	}
+
+	key, err := generateKey(options.KeyType, options.KeySize)
+	if err != nil {
+		return nil, err
+	}
+	csrDER, err := x509.CreateCertificateRequest(rand.Reader, template, key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create certificate request: %w", err)
+	}
+	keyDER, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode private key: %w", err)
+	}
+	info, err := decodeCSR(csrDER)
+	if err != nil {
+		return nil, err
+	}
+	return &GeneratedCSR{
+		PrivateKeyPEM: string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: keyDER})),
+		CSRPEM:        string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE REQUEST", Bytes: csrDER})),
+		CSR:           info,
+	}, nil
+}
+
+// generateKey creates an RSA or ECDSA private key, rejecting sizes considered weak.
+func generateKey(keyType string, size int) (crypto.Signer, error) {
+	switch strings.ToLower(keyType) {
+	case "", KeyTypeRSA:
+		switch size {
+		case 0:
+			size = 2048
+		case 2048, 3072, 4096:
+		default:
+			return nil, fmt.Errorf("unsupported RSA key size %d: use 2048, 3072 or 4096", size)
+		}
+		return rsa.GenerateKey(rand.Reader, size)
+	case KeyTypeECDSA:
+		var curve elliptic.Curve
+		switch size {
+		case 0, 256:
+			curve = elliptic.P256()
+		case 384:
+			curve = elliptic.P384()
+		case 521:
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("unsupported ECDSA key size %d: use 256, 384 or 521", size)
+		}
+		return ecdsa.GenerateKey(curve, rand.Reader)
+	default:
+		return nil, fmt.Errorf("unsupported key type %q: use rsa or ecdsa", keyType)
+	}
+}
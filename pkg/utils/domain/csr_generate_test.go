@@ -0,0 +1,68 @@
+package domain
+
+import (
+	"crypto/x509"
+	"encoding/pem"
+	"testing"
+)
+
+func TestGenerateCSR(t *testing.T) {
+	for _, tt := range []struct {
+		keyType   string
+		keySize   int
+		wantAlgo  string
+		wantSize  int
+		wantError bool
+	}{
+		{"", 0, "RSA", 2048, false},
+		{"ecdsa", 0, "ECDSA", 256, false},
+		{"ecdsa", 384, "ECDSA", 384, false},
+		{"rsa", 1024, "", 0, true},
+		{"ecdsa", 224, "", 0, true},
+		{"dsa", 0, "", 0, true},
+	} {
+		generated, err := GenerateCSR(CSROptions{
+			KeyType:     tt.keyType,
+			KeySize:     tt.keySize,
+			Subject:     CSRSubject{CommonName: "www.example.com", Organization: []string{"Example Ltd"}},
+			DNSNames:    []string{"www.example.com", "example.com"},
+			IPAddresses: []string{"192.0.2.1"},
+		})
+		if tt.wantError {
+			if err == nil {
+				t.Errorf("%s/%d: no error", tt.keyType, tt.keySize)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("%s/%d: %v", tt.keyType, tt.keySize, err)
+		}
+		if generated.CSR.PublicKeyAlgorithm != tt.wantAlgo || generated.CSR.KeySize != tt.wantSize || !generated.CSR.SignatureValid {
+			t.Errorf("%s/%d: CSR = %+v", tt.keyType, tt.keySize, generated.CSR)
+		}
+		if generated.CSR.Subject != "CN=www.example.com,O=Example Ltd" || len(generated.CSR.DNSNames) != 2 || len(generated.CSR.IPAddresses) != 1 {
+			t.Errorf("%s/%d: names = %q, %v, %v", tt.keyType, tt.keySize, generated.CSR.Subject, generated.CSR.DNSNames, generated.CSR.IPAddresses)
+		}
+
+		block, _ := pem.Decode([]byte(generated.PrivateKeyPEM))
+		if block == nil || block.Type != "PRIVATE KEY" {
+			t.Fatalf("%s/%d: private key is not a PKCS #8 PEM block", tt.keyType, tt.keySize)
+		}
+		if _, err := x509.ParsePKCS8PrivateKey(block.Bytes); err != nil {
+			t.Errorf("%s/%d: %v", tt.keyType, tt.keySize, err)
+		}
+		decoded, err := DecodeCSR([]byte(generated.CSRPEM))
+		if err != nil || decoded.PublicKeyPin != generated.CSR.PublicKeyPin {
+			t.Errorf("%s/%d: DecodeCSR = %+v, %v", tt.keyType, tt.keySize, decoded, err)
+		}
+	}
+}
+
+func TestGenerateCSRInvalid(t *testing.T) {
+	if _, err := GenerateCSR(CSROptions{KeyType: "ecdsa"}); err == nil {
+		t.Error("CSR without names: no error")
+	}
+	if _, err := GenerateCSR(CSROptions{KeyType: "ecdsa", Subject: CSRSubject{CommonName: "example.com"}, IPAddresses: []string{"not-an-ip"}}); err == nil {
+		t.Error("CSR with an invalid IP: no error")
+	}
+}
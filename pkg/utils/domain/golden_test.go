@@ -0,0 +1,52 @@
+package domain
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/vit0-9/utils_api/pkg/utils/golden"
+)
+
+// The golden tests run the parsers over recorded responses in testdata and compare the
+// results with the .golden.json files next to them. After an intended parser change, rewrite
+// them with: go test ./pkg/utils/domain -run Golden -update
+
+// TestWhoisGolden parses recorded WHOIS responses, one per registry format, named after the
+// domain they answer for.
+func TestWhoisGolden(t *testing.T) {
+	for _, fixture := range golden.Fixtures(t, "testdata/whois/*.txt") {
+		domain := golden.Name(fixture)
+		t.Run(domain, func(t *testing.T) {
+			raw, err := os.ReadFile(fixture)
+			if err != nil {
+				t.Fatal(err)
+			}
+			info := parseWhoisResponse(domain, string(raw), "whois.test")
+			info.RawData = "" // The fixture itself
+			golden.Assert(t, fixture, info)
+		})
+	}
+}
+
+// TestCertificateGolden decodes recorded certificate chains, verifying each against the host
+// it is named after.
+func TestCertificateGolden(t *testing.T) {
+	for _, fixture := range golden.Fixtures(t, "testdata/certs/*.pem") {
+		host := golden.Name(fixture)
+		t.Run(host, func(t *testing.T) {
+			data, err := os.ReadFile(fixture)
+			if err != nil {
+				t.Fatal(err)
+			}
+			decoded, err := DecodeCertificate(data, host)
+			if err != nil {
+				t.Fatalf("DecodeCertificate() error = %v", err)
+			}
+			// Only the fields counting down to the expiry change from run to run
+			decoded.Certificate.DaysUntilExpiry, decoded.Certificate.ExpiresInSeconds = 0, 0
+			decoded.Certificate.QueryTime = time.Time{}
+			golden.Assert(t, fixture, decoded)
+		})
+	}
+}
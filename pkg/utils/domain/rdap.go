@@ -0,0 +1,438 @@
+package domain
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/vit0-9/utils_api/pkg/utils"
+)
+
+// rdapBootstrapURL is the IANA registry mapping TLDs to RDAP servers (RFC 9224).
+const rdapBootstrapURL = "https://data.iana.org/rdap/dns.json"
+
+// rdapBootstrapTTL is how long the bootstrap registry is cached before being refreshed.
+const rdapBootstrapTTL = 24 * time.Hour
+
+// rdapMaxReferrals limits how many "related" links are followed (registry -> registrar).
+const rdapMaxReferrals = 2
+
+// RDAPEvent is a dated lifecycle event (registration, expiration, last changed, ...).
+type RDAPEvent struct {
+	Action string    `json:"action"`
+	Date   time.Time `json:"date"`
+	Actor  string    `json:"actor,omitempty"`
+}
+
+// RDAPEntity is a contact or organization linked to the domain (registrar, registrant, abuse, ...).
+type RDAPEntity struct {
+	Handle       string   `json:"handle,omitempty"`
+	Roles        []string `json:"roles"`
+	Name         string   `json:"name,omitempty"`
+	Organization string   `json:"organization,omitempty"`
+	Email        string   `json:"email,omitempty"`
+	Phone        string   `json:"phone,omitempty"`
+}
+
+// RDAPInfo is the structured registration data for a domain.
+type RDAPInfo struct {
+	Domain           string       `json:"domain"`
+	Handle           string       `json:"handle,omitempty"`
+	Registrar        string       `json:"registrar,omitempty"`
+	Status           []string     `json:"status"`
+	Events           []RDAPEvent  `json:"events"`
+	Entities         []RDAPEntity `json:"entities"`
+	NameServers      []string     `json:"name_servers"`
+	DelegationSigned bool         `json:"delegation_signed"` // DNSSEC
+	Servers          []string     `json:"servers"`           // RDAP URLs queried, registry first, then referrals
+	Source           string       `json:"source"`            // "rdap", or "whois" when RDAP was unavailable
+	FallbackReason   string       `json:"fallback_reason,omitempty"`
+	QueryTime        time.Time    `json:"query_time"`
+}
+
+// rdapDomainResponse is the subset of an RDAP domain object (RFC 9083) that is used.
+type rdapDomainResponse struct {
+	LDHName     string           `json:"ldhName"`
+	Handle      string           `json:"handle"`
+	Status      []string         `json:"status"`
+	Events      []rdapEvent      `json:"events"`
+	Entities    []rdapEntity     `json:"entities"`
+	Nameservers []rdapNameserver `json:"nameservers"`
+	SecureDNS   *struct {
+		DelegationSigned bool `json:"delegationSigned"`
+	} `json:"secureDNS"`
+	Links []rdapLink `json:"links"`
+}
+
+type rdapEvent struct {
+	EventAction string `json:"eventAction"`
+	EventDate   string `json:"eventDate"`
+	EventActor  string `json:"eventActor"`
+}
+
+type rdapEntity struct {
+	Handle     string          `json:"handle"`
+	Roles      []string        `json:"roles"`
+	VCardArray json.RawMessage `json:"vcardArray"`
+	Entities   []rdapEntity    `json:"entities"`
+}
+
+type rdapNameserver struct {
+	LDHName string `json:"ldhName"`
+}
+
+type rdapLink struct {
+	Rel  string `json:"rel"`
+	Href string `json:"href"`
+	Type string `json:"type"`
+}
+
+var (
+	rdapClient = &http.Client{Timeout: 15 * time.Second, Transport: utils.NewOutboundTransport()}
+
+	rdapBootstrapMu      sync.Mutex
+	rdapBootstrap        map[string][]string // TLD -> RDAP base URLs
+	rdapBootstrapFetched time.Time
+)
+
+// rdapGet fetches an RDAP URL and decodes the JSON response into v.
+func rdapGet(ctx context.Context, rawURL string, v interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, "GET", rawURL, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/rdap+json, application/json")
+
+	resp, err := rdapClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s returned status %d", rawURL, resp.StatusCode)
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 5<<20))
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(body, v)
+}
+
+// rdapServersForTLD returns the RDAP base URLs for a TLD from the (cached) IANA bootstrap registry.
+func rdapServersForTLD(ctx context.Context, tld string) ([]string, error) {
+	rdapBootstrapMu.Lock()
+	defer rdapBootstrapMu.Unlock()
+
+	if rdapBootstrap == nil || time.Since(rdapBootstrapFetched) > rdapBootstrapTTL {
+		var registry struct {
+			Services [][][]string `json:"services"`
+		}
+		if err := rdapGet(ctx, rdapBootstrapURL, &registry); err != nil {
+			if rdapBootstrap == nil {
+				return nil, fmt.Errorf("failed to load RDAP bootstrap registry: %w", err)
+			}
+			// Keep serving the stale registry rather than failing every lookup.
+		} else {
+			bootstrap := make(map[string][]string)
+			for _, service := range registry.Services {
+				if len(service) != 2 {
+					continue
+				}
+				for _, entry := range service[0] {
+					bootstrap[strings.ToLower(entry)] = service[1]
+				}
+			}
+			rdapBootstrap = bootstrap
+			rdapBootstrapFetched = time.Now()
+		}
+	}
+
+	servers := rdapBootstrap[tld]
+	if len(servers) == 0 {
+		return nil, fmt.Errorf("no RDAP service registered for .%s", tld)
+	}
+	return servers, nil
+}
+
+// GetRDAPInfo looks up registration data over RDAP, following referrals from the registry
+// to the registrar. When RDAP is unavailable for the domain it falls back to WHOIS.
+func GetRDAPInfo(ctx context.Context, domain string) (*RDAPInfo, error) {
+	domain = strings.ToLower(strings.TrimSuffix(strings.TrimSpace(domain), "."))
+	if domain == "" {
+		return nil, fmt.Errorf("domain cannot be empty")
+	}
+	parts := strings.Split(domain, ".")
+	if len(parts) < 2 {
+		return nil, fmt.Errorf("invalid domain format: %s", domain)
+	}
+	if err := utils.CheckOutboundName(domain); err != nil {
+		return nil, err
+	}
+
+	info, err := queryRDAP(ctx, domain, parts[len(parts)-1])
+	if err == nil {
+		return info, nil
+	}
+
+	whoisInfo, whoisErr := GetWhoisInfo(ctx, domain)
+	if whoisErr != nil {
+		return nil, fmt.Errorf("RDAP lookup failed (%v) and WHOIS fallback failed: %w", err, whoisErr)
+	}
+	return rdapInfoFromWhois(whoisInfo, err.Error()), nil
+}
+
+// queryRDAP queries the registry RDAP server for the TLD and then any registrar referrals.
+func queryRDAP(ctx context.Context, domain, tld string) (*RDAPInfo, error) {
+	servers, err := rdapServersForTLD(ctx, tld)
+	if err != nil {
+		return nil, err
+	}
+
+	var registryResp rdapDomainResponse
+	var lastErr error
+	var queried string
+	for _, base := range servers {
+		queried = strings.TrimSuffix(base, "/") + "/domain/" + url.PathEscape(domain)
+		if lastErr = rdapGet(ctx, queried, &registryResp); lastErr == nil {
+			break
+		}
+	}
+	if lastErr != nil {
+		return nil, lastErr
+	}
+
+	info := &RDAPInfo{
+		Domain:      domain,
+		Status:      []string{},
+		Events:      []RDAPEvent{},
+		Entities:    []RDAPEntity{},
+		NameServers: []string{},
+		Servers:     []string{queried},
+		Source:      "rdap",
+		QueryTime:   time.Now(),
+	}
+	mergeRDAPResponse(info, &registryResp)
+
+	// Thin registries (e.g. .com) only hold a pointer to the registrar's RDAP server,
+	// which has the contact entities.
+	current := &registryResp
+	for i := 0; i < rdapMaxReferrals; i++ {
+		referral := rdapReferral(current.Links, info.Servers)
+		if referral == "" {
+			break
+		}
+		var referralResp rdapDomainResponse
+		if err := rdapGet(ctx, referral, &referralResp); err != nil {
+			break // The registry data is still useful on its own
+		}
+		info.Servers = append(info.Servers, referral)
+		mergeRDAPResponse(info, &referralResp)
+		current = &referralResp
+	}
+
+	info.Status = removeDuplicates(info.Status)
+	info.NameServers = removeDuplicates(info.NameServers)
+	return info, nil
+}
+
+// rdapReferral returns the first RDAP "related" link that has not been queried yet.
+func rdapReferral(links []rdapLink, queried []string) string {
+	for _, link := range links {
+		if link.Rel != "related" || link.Href == "" {
+			continue
+		}
+		if link.Type != "" && !strings.Contains(link.Type, "rdap+json") {
+			continue
+		}
+		if !strings.Contains(link.Href, "/domain/") || !strings.HasPrefix(link.Href, "https://") {
+			continue
+		}
+		alreadyQueried := false
+		for _, q := range queried {
+			if strings.EqualFold(q, link.Href) {
+				alreadyQueried = true
+			}
+		}
+		if !alreadyQueried {
+			return link.Href
+		}
+	}
+	return ""
+}
+
+// mergeRDAPResponse adds the data of one RDAP response to info. Data already present
+// (from the registry) is kept; referrals only add what is missing.
+func mergeRDAPResponse(info *RDAPInfo, resp *rdapDomainResponse) {
+	if info.Handle == "" {
+		info.Handle = resp.Handle
+	}
+	for _, status := range resp.Status {
+		info.Status = append(info.Status, strings.ToLower(status))
+	}
+
+	for _, event := range resp.Events {
+		date, err := time.Parse(time.RFC3339, event.EventDate)
+		if err != nil {
+			continue
+		}
+		duplicate := false
+		for _, existing := range info.Events {
+			if existing.Action == event.EventAction {
+				duplicate = true
+			}
+		}
+		if !duplicate {
+			info.Events = append(info.Events, RDAPEvent{Action: event.EventAction, Date: date, Actor: event.EventActor})
+		}
+	}
+
+	for _, ns := range resp.Nameservers {
+		if ns.LDHName != "" {
+			info.NameServers = append(info.NameServers, strings.ToLower(strings.TrimSuffix(ns.LDHName, ".")))
+		}
+	}
+	if resp.SecureDNS != nil && resp.SecureDNS.DelegationSigned {
+		info.DelegationSigned = true
+	}
+
+	for _, entity := range flattenRDAPEntities(resp.Entities) {
+		if info.Registrar == "" && containsRole(entity.Roles, "registrar") {
+			info.Registrar = entity.Name
+			if info.Registrar == "" {
+				info.Registrar = entity.Organization
+			}
+		}
+		duplicate := false
+		for _, existing := range info.Entities {
+			if existing.Handle != "" && existing.Handle == entity.Handle && strings.Join(existing.Roles, ",") == strings.Join(entity.Roles, ",") {
+				duplicate = true
+			}
+		}
+		if !duplicate {
+			info.Entities = append(info.Entities, entity)
+		}
+	}
+}
+
+// flattenRDAPEntities returns entities and their nested entities (e.g. the registrar's abuse contact).
+func flattenRDAPEntities(entities []rdapEntity) []RDAPEntity {
+	var result []RDAPEntity
+	for _, entity := range entities {
+		parsed := RDAPEntity{Handle: entity.Handle, Roles: entity.Roles}
+		if parsed.Roles == nil {
+			parsed.Roles = []string{}
+		}
+		parseVCard(entity.VCardArray, &parsed)
+		result = append(result, parsed)
+		result = append(result, flattenRDAPEntities(entity.Entities)...)
+	}
+	return result
+}
+
+// parseVCard extracts name, organization, email and phone from a jCard (RFC 7095):
+// ["vcard", [["fn", {}, "text", "Example Inc."], ["email", {}, "text", "a@b.c"], ...]]
+func parseVCard(raw json.RawMessage, entity *RDAPEntity) {
+	if len(raw) == 0 {
+		return
+	}
+	var vcard []json.RawMessage
+	if err := json.Unmarshal(raw, &vcard); err != nil || len(vcard) < 2 {
+		return
+	}
+	var properties [][]interface{}
+	if err := json.Unmarshal(vcard[1], &properties); err != nil {
+		return
+	}
+	for _, property := range properties {
+		if len(property) < 4 {
+			continue
+		}
+		name, _ := property[0].(string)
+		value, ok := property[3].(string)
+		if !ok {
+			// "org" and "adr" may be structured arrays; use the first component.
+			if values, isList := property[3].([]interface{}); isList && len(values) > 0 {
+				value, _ = values[0].(string)
+			}
+		}
+		if value == "" {
+			continue
+		}
+		switch name {
+		case "fn":
+			entity.Name = value
+		case "org":
+			entity.Organization = value
+		case "email":
+			if entity.Email == "" {
+				entity.Email = value
+			}
+		case "tel":
+			if entity.Phone == "" {
+				entity.Phone = strings.TrimPrefix(value, "tel:")
+			}
+		}
+	}
+}
+
+func containsRole(roles []string, role string) bool {
+	for _, r := range roles {
+		if strings.EqualFold(r, role) {
+			return true
+		}
+	}
+	return false
+}
+
+// rdapInfoFromWhois maps a WHOIS result into the RDAP structure for the fallback path.
+func rdapInfoFromWhois(whoisInfo *WhoisInfo, reason string) *RDAPInfo {
+	info := &RDAPInfo{
+		Domain:         whoisInfo.Domain,
+		Registrar:      whoisInfo.Registrar,
+		Status:         whoisInfo.Status,
+		Events:         []RDAPEvent{},
+		Entities:       []RDAPEntity{},
+		NameServers:    whoisInfo.NameServers,
+		Servers:        []string{},
+		Source:         "whois",
+		FallbackReason: reason,
+		QueryTime:      whoisInfo.QueryTime,
+	}
+	for _, event := range []RDAPEvent{
+		{Action: "registration", Date: whoisInfo.CreationDate},
+		{Action: "expiration", Date: whoisInfo.ExpirationDate},
+		{Action: "last changed", Date: whoisInfo.UpdatedDate},
+	} {
+		if !event.Date.IsZero() {
+			info.Events = append(info.Events, event)
+		}
+	}
+	if whoisInfo.Registrar != "" {
+		info.Entities = append(info.Entities, RDAPEntity{Roles: []string{"registrar"}, Name: whoisInfo.Registrar})
+	}
+	if whoisInfo.RegistrantOrg != "" || whoisInfo.RegistrantEmail != "" {
+		info.Entities = append(info.Entities, RDAPEntity{Roles: []string{"registrant"}, Organization: whoisInfo.RegistrantOrg, Email: whoisInfo.RegistrantEmail})
+	}
+	if whoisInfo.AdminEmail != "" {
+		info.Entities = append(info.Entities, RDAPEntity{Roles: []string{"administrative"}, Email: whoisInfo.AdminEmail})
+	}
+	if whoisInfo.TechEmail != "" {
+		info.Entities = append(info.Entities, RDAPEntity{Roles: []string{"technical"}, Email: whoisInfo.TechEmail})
+	}
+	if whoisInfo.WhoisServer != "" {
+		info.Servers = append(info.Servers, "whois://"+whoisInfo.WhoisServer)
+	}
+	if info.Status == nil {
+		info.Status = []string{}
+	}
+	if info.NameServers == nil {
+		info.NameServers = []string{}
+	}
+	return info
+}
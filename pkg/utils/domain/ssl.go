@@ -2,16 +2,27 @@
 package domain
 
 import (
+	"bytes"
 	"context"
 	"crypto/ecdsa"
 	"crypto/ed25519"
 	"crypto/rsa"
+	"crypto/sha256"
 	"crypto/tls"
 	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
 	"fmt"
+	"log"
 	"net"
+	"os"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
+
+	"github.com/vit0-9/utils_api/pkg/utils"
 )
 
 type SSLInfo struct {
@@ -20,9 +31,12 @@ type SSLInfo struct {
 	Issuer             string            `json:"issuer"`
 	Subject            string            `json:"subject"`
 	SerialNumber       string            `json:"serial_number"`
+	FingerprintSHA256  string            `json:"fingerprint_sha256"`    // Hex SHA-256 of the leaf certificate's DER encoding
+	PublicKeyPin       string            `json:"public_key_pin_sha256"` // Base64 SHA-256 of the leaf's SubjectPublicKeyInfo (RFC 7469)
 	NotBefore          time.Time         `json:"not_before"`
 	NotAfter           time.Time         `json:"not_after"`
 	DaysUntilExpiry    int               `json:"days_until_expiry"`
+	ExpiresInSeconds   int64             `json:"expires_in_seconds"` // Negative once the certificate has expired
 	SubjectAltNames    []string          `json:"subject_alt_names"`
 	SignatureAlgorithm string            `json:"signature_algorithm"`
 	PublicKeyAlgorithm string            `json:"public_key_algorithm"`
@@ -30,10 +44,25 @@ type SSLInfo struct {
 	Version            int               `json:"version"`
 	IsSelfSigned       bool              `json:"is_self_signed"`
 	IsWildcard         bool              `json:"is_wildcard"`
+	Hostname           HostnameCheck     `json:"hostname"`                     // Whether the certificate covers the checked host
+	AlternateHostname  *HostnameCheck    `json:"alternate_hostname,omitempty"` // Only when another hostname was verified
 	CertificateChain   []CertificateInfo `json:"certificate_chain"`
 	TLSVersion         string            `json:"tls_version"`
 	CipherSuite        string            `json:"cipher_suite"`
 	ValidationErrors   []string          `json:"validation_errors,omitempty"`
+	Grade              string            `json:"grade"`    // A (no findings above low) to F (a critical finding)
+	Findings           []SSLFinding      `json:"findings"` // Weak algorithms, expiry, trust and protocol problems by severity
+	ChainTrusted       bool              `json:"chain_trusted"`
+	VerificationError  string            `json:"verification_error,omitempty"`
+	TrustIssue         string            `json:"trust_issue,omitempty"`    // self_signed_leaf, untrusted_root or unknown_issuer when no trusted root was found
+	UntrustedRoot      string            `json:"untrusted_root,omitempty"` // The root the chain ends in (untrusted_root) or the issuer that was not found
+	VerifiedChain      []CertificateInfo `json:"verified_chain,omitempty"` // Leaf to trusted root, as built by x509.Verify
+	Revocation         *RevocationInfo   `json:"revocation,omitempty"`
+	ClientAuth         *ClientAuthInfo   `json:"client_auth"`
+	Protocols          []ProtocolSupport `json:"protocols,omitempty"`           // Only when the protocol versions were probed
+	Session            *SessionInfo      `json:"session,omitempty"`             // Only when the session features were probed
+	Endpoints          []SSLEndpoint     `json:"endpoints,omitempty"`           // Per-IP results when every resolved address was checked
+	EndpointMismatches []string          `json:"endpoint_mismatches,omitempty"` // Differences between those endpoints
 	QueryTime          time.Time         `json:"query_time"`
 }
 
@@ -55,8 +84,118 @@ func (e *SSLError) Error() string {
 	return fmt.Sprintf("SSL check failed for %s: %v", e.Domain, e.Err)
 }
 
+var (
+	trustedRootsMu sync.RWMutex
+	trustedRoots   *x509.CertPool // nil means the system roots
+)
+
+// ConfigureSSLCABundle adds the PEM certificates in path to the system roots used for chain
+// verification, e.g. for internal CAs. An empty path keeps the system roots only.
+func ConfigureSSLCABundle(path string) {
+	if path == "" {
+		return
+	}
+	pem, err := os.ReadFile(path)
+	if err != nil {
+		log.Printf("ERROR: Could not read CA bundle %s: %v. Using system roots only.", path, err)
+		return
+	}
+	pool, err := x509.SystemCertPool()
+	if err != nil || pool == nil {
+		pool = x509.NewCertPool()
+	}
+	if !pool.AppendCertsFromPEM(pem) {
+		log.Printf("ERROR: No certificates found in CA bundle %s. Using system roots only.", path)
+		return
+	}
+	trustedRootsMu.Lock()
+	trustedRoots = pool
+	trustedRootsMu.Unlock()
+	log.Printf("Loaded custom CA bundle from %s", path)
+}
+
+// verifyChain builds and verifies the chain presented by the server against the trusted roots.
+func verifyChain(peerCerts []*x509.Certificate, domain string) ([]*x509.Certificate, error) {
+	intermediates := x509.NewCertPool()
+	for _, cert := range peerCerts[1:] {
+		intermediates.AddCert(cert)
+	}
+
+	trustedRootsMu.RLock()
+	roots := trustedRoots
+	trustedRootsMu.RUnlock()
+
+	chains, err := peerCerts[0].Verify(x509.VerifyOptions{
+		DNSName:       domain,
+		Roots:         roots,
+		Intermediates: intermediates,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return chains[0], nil
+}
+
+// Reasons a chain has no trusted root.
+const (
+	TrustIssueSelfSignedLeaf = "self_signed_leaf" // The leaf signs itself
+	TrustIssueUntrustedRoot  = "untrusted_root"   // The served chain ends in a root that is not trusted, e.g. a private CA
+	TrustIssueUnknownIssuer  = "unknown_issuer"   // The last served certificate's issuer was neither served nor trusted
+)
+
+// classifyTrustIssue explains a verification failure caused by a missing trusted root, and
+// names the root or issuer concerned. Other failures, such as expiry, are not classified.
+func classifyTrustIssue(peerCerts []*x509.Certificate, err error) (string, string) {
+	var unknownAuthority x509.UnknownAuthorityError
+	if !errors.As(err, &unknownAuthority) {
+		return "", ""
+	}
+	leaf, last := peerCerts[0], peerCerts[len(peerCerts)-1]
+	switch {
+	case isSelfSigned(leaf):
+		return TrustIssueSelfSignedLeaf, leaf.Subject.String()
+	case len(peerCerts) > 1 && isSelfSigned(last):
+		return TrustIssueUntrustedRoot, last.Subject.String()
+	default:
+		return TrustIssueUnknownIssuer, last.Issuer.String()
+	}
+}
+
+// isSelfSigned reports whether a certificate's signature verifies with its own public key.
+// Matching issuer and subject names are not enough: a CA can issue a leaf with its own name.
+func isSelfSigned(cert *x509.Certificate) bool {
+	err := cert.CheckSignature(cert.SignatureAlgorithm, cert.RawTBSCertificate, cert.Signature)
+	var insecure x509.InsecureAlgorithmError
+	if errors.As(err, &insecure) || errors.Is(err, x509.ErrUnsupportedAlgorithm) {
+		// MD5 and MD2 signatures are no longer verified, so fall back to the names
+		return bytes.Equal(cert.RawIssuer, cert.RawSubject)
+	}
+	return err == nil
+}
+
+// SSLCheckOptions controls the optional parts of an SSL check.
+type SSLCheckOptions struct {
+	Port            int    // Defaults to 443
+	CheckRevocation bool   // Query OCSP (or the CRL) for the leaf certificate
+	AllIPs          bool   // Check every A/AAAA address of the host instead of the first that connects
+	VerifyHost      string // Another hostname to verify against the presented certificate
+
+	ClientCertificate *tls.Certificate // Presented if the server requests a client certificate
+	ProbeProtocols    bool             // Probe each TLS version on its own connection, including deprecated TLS 1.0/1.1
+	ProbeSession      bool             // Probe session resumption, secure renegotiation, OCSP stapling and ALPN
+}
+
 // GetSSLInfo retrieves SSL certificate information for a domain
 func GetSSLInfo(ctx context.Context, domain string, port ...int) (*SSLInfo, error) {
+	var options SSLCheckOptions
+	if len(port) > 0 {
+		options.Port = port[0]
+	}
+	return GetSSLInfoWithOptions(ctx, domain, options)
+}
+
+// GetSSLInfoWithOptions retrieves SSL certificate information for a domain
+func GetSSLInfoWithOptions(ctx context.Context, domain string, options SSLCheckOptions) (*SSLInfo, error) {
 	domain = strings.ToLower(strings.TrimSpace(domain))
 	if domain == "" {
 		return nil, fmt.Errorf("domain cannot be empty")
@@ -64,25 +203,44 @@ func GetSSLInfo(ctx context.Context, domain string, port ...int) (*SSLInfo, erro
 
 	// Default to HTTPS port
 	targetPort := 443
-	if len(port) > 0 && port[0] > 0 {
-		targetPort = port[0]
+	if options.Port > 0 {
+		targetPort = options.Port
 	}
 
-	address := fmt.Sprintf("%s:%d", domain, targetPort)
-
-	// Create TLS connection with timeout
-	dialer := &net.Dialer{
-		Timeout: 10 * time.Second,
+	if options.AllIPs && net.ParseIP(domain) == nil {
+		return getSSLInfoForAllIPs(ctx, domain, targetPort, options)
 	}
+	return getSSLInfo(ctx, domain, targetPort, nil, options)
+}
 
-	conn, err := tls.DialWithDialer(dialer, "tcp", address, &tls.Config{
-		ServerName:         domain,
-		InsecureSkipVerify: true, // We want to analyze even invalid certs
-	})
+// getSSLInfo runs the check against the host, or against ip when it is set. Callers passing
+// ip have already applied the outbound policy to it.
+func getSSLInfo(ctx context.Context, domain string, targetPort int, ip net.IP, options SSLCheckOptions) (*SSLInfo, error) {
+	recorder := utils.TimingRecorderFrom(ctx)
+	dialStart := time.Now()
+	rawConn, err := dialSSL(ctx, domain, targetPort, ip)
+	recorder.AddConnect(time.Since(dialStart))
 	if err != nil {
 		return nil, &SSLError{Domain: domain, Err: err}
 	}
+	clientAuth := &ClientAuthInfo{}
+	conn := tls.Client(rawConn, &tls.Config{
+		ServerName:           domain,
+		InsecureSkipVerify:   true, // We want to analyze even invalid certs
+		MinVersion:           tls.VersionTLS10,
+		CipherSuites:         legacyCipherSuites(), // So legacy-only servers are analyzed rather than failing the handshake
+		GetClientCertificate: clientAuthRecorder(options.ClientCertificate, clientAuth),
+	})
 	defer conn.Close()
+	handshakeStart := time.Now()
+	err = conn.HandshakeContext(ctx)
+	recorder.AddTLS(time.Since(handshakeStart))
+	if err != nil {
+		if clientAuth.Requested && !clientAuth.CertificateSent {
+			err = fmt.Errorf("server requires a client certificate: %w", err)
+		}
+		return nil, &SSLError{Domain: domain, Err: err}
+	}
 
 	// Get connection state
 	state := conn.ConnectionState()
@@ -91,7 +249,46 @@ func GetSSLInfo(ctx context.Context, domain string, port ...int) (*SSLInfo, erro
 		return nil, &SSLError{Domain: domain, Err: fmt.Errorf("no certificates found")}
 	}
 
-	cert := state.PeerCertificates[0]
+	sslInfo, verifiedChain := analyzeCertificates(domain, state.PeerCertificates, options.VerifyHost)
+	sslInfo.TLSVersion = getTLSVersion(state.Version)
+	sslInfo.CipherSuite = tls.CipherSuiteName(state.CipherSuite)
+	sslInfo.ClientAuth = clientAuth
+
+	// Probe the protocol versions on separate connections
+	if options.ProbeProtocols {
+		sslInfo.Protocols = probeProtocols(ctx, domain, targetPort, ip)
+		for _, protocol := range sslInfo.Protocols {
+			if protocol.Supported && protocol.Deprecated {
+				sslInfo.ValidationErrors = append(sslInfo.ValidationErrors, "deprecated protocol "+protocol.Version+" is enabled")
+			}
+		}
+	}
+
+	// Probe resumption and the other session features on separate connections
+	if options.ProbeSession {
+		sslInfo.Session = probeSession(ctx, domain, targetPort, ip)
+	}
+
+	// Check revocation of the leaf certificate
+	if options.CheckRevocation {
+		var issuer *x509.Certificate
+		if len(verifiedChain) > 1 {
+			issuer = verifiedChain[1]
+		} else if len(state.PeerCertificates) > 1 {
+			issuer = state.PeerCertificates[1]
+		}
+		sslInfo.Revocation = checkRevocation(ctx, state.PeerCertificates[0], issuer, state.OCSPResponse)
+	}
+
+	sslInfo.Grade, sslInfo.Findings = gradeSSL(sslInfo, state.PeerCertificates)
+	return sslInfo, nil
+}
+
+// analyzeCertificates runs the checks that need only the certificates a server presented,
+// leaf first: validity, key, hostname and chain verification. An empty domain skips the
+// hostname checks. It returns the verified chain too, which is nil when verification failed.
+func analyzeCertificates(domain string, peerCerts []*x509.Certificate, verifyHost string) (*SSLInfo, []*x509.Certificate) {
+	cert := peerCerts[0]
 
 	// Build SSL info
 	sslInfo := &SSLInfo{
@@ -99,29 +296,31 @@ func GetSSLInfo(ctx context.Context, domain string, port ...int) (*SSLInfo, erro
 		Issuer:             cert.Issuer.String(),
 		Subject:            cert.Subject.String(),
 		SerialNumber:       cert.SerialNumber.String(),
+		FingerprintSHA256:  Fingerprint(cert),
+		PublicKeyPin:       PublicKeyPin(cert),
 		NotBefore:          cert.NotBefore,
 		NotAfter:           cert.NotAfter,
 		SubjectAltNames:    cert.DNSNames,
 		SignatureAlgorithm: cert.SignatureAlgorithm.String(),
 		PublicKeyAlgorithm: cert.PublicKeyAlgorithm.String(),
 		Version:            cert.Version,
-		TLSVersion:         getTLSVersion(state.Version),
-		CipherSuite:        tls.CipherSuiteName(state.CipherSuite),
 		QueryTime:          time.Now(),
 	}
 
 	// Calculate days until expiry
-	daysUntilExpiry := int(time.Until(cert.NotAfter).Hours() / 24)
+	expiresIn := time.Until(cert.NotAfter)
+	daysUntilExpiry := int(expiresIn.Hours() / 24)
 	sslInfo.DaysUntilExpiry = daysUntilExpiry
+	sslInfo.ExpiresInSeconds = int64(expiresIn.Seconds())
 
 	// Check if certificate is valid
 	sslInfo.IsValid = daysUntilExpiry > 0 && time.Now().After(cert.NotBefore)
 
 	// Determine key size
-	sslInfo.KeySize = getKeySize(cert)
+	sslInfo.KeySize = KeySize(cert)
 
 	// Check if self-signed
-	sslInfo.IsSelfSigned = cert.Issuer.String() == cert.Subject.String()
+	sslInfo.IsSelfSigned = isSelfSigned(cert)
 
 	// Check if wildcard
 	for _, name := range cert.DNSNames {
@@ -131,23 +330,64 @@ func GetSSLInfo(ctx context.Context, domain string, port ...int) (*SSLInfo, erro
 		}
 	}
 
+	// Verify the hostnames against the certificate
+	if domain != "" {
+		sslInfo.Hostname = checkHostname(cert, domain)
+	}
+	if verifyHost != "" {
+		alternate := checkHostname(cert, strings.ToLower(strings.TrimSpace(verifyHost)))
+		sslInfo.AlternateHostname = &alternate
+	}
+
 	// Validate certificate chain
 	sslInfo.ValidationErrors = validateCertificate(cert, domain)
 
 	// Process certificate chain
-	for _, peerCert := range state.PeerCertificates {
-		certInfo := CertificateInfo{
-			Subject:   peerCert.Subject.String(),
-			Issuer:    peerCert.Issuer.String(),
-			NotBefore: peerCert.NotBefore,
-			NotAfter:  peerCert.NotAfter,
-			IsCA:      peerCert.IsCA,
-			KeyUsage:  getKeyUsage(peerCert),
+	for _, peerCert := range peerCerts {
+		sslInfo.CertificateChain = append(sslInfo.CertificateChain, newCertificateInfo(peerCert))
+	}
+
+	// Verify the chain against the trusted roots
+	verifiedChain, err := verifyChain(peerCerts, domain)
+	if err != nil {
+		sslInfo.VerificationError = err.Error()
+		sslInfo.TrustIssue, sslInfo.UntrustedRoot = classifyTrustIssue(peerCerts, err)
+	} else {
+		sslInfo.ChainTrusted = true
+		for _, chainCert := range verifiedChain {
+			sslInfo.VerifiedChain = append(sslInfo.VerifiedChain, newCertificateInfo(chainCert))
 		}
-		sslInfo.CertificateChain = append(sslInfo.CertificateChain, certInfo)
 	}
 
-	return sslInfo, nil
+	return sslInfo, verifiedChain
+}
+
+// dialSSL opens the TCP connection for a check, to ip when it is set.
+func dialSSL(ctx context.Context, domain string, targetPort int, ip net.IP) (net.Conn, error) {
+	// Create TLS connection with timeout
+	dialer := &net.Dialer{
+		Timeout: 10 * time.Second,
+	}
+	if ip != nil {
+		conn, err := dialer.DialContext(ctx, "tcp", net.JoinHostPort(ip.String(), strconv.Itoa(targetPort)))
+		if err != nil {
+			utils.TimingRecorderFrom(ctx).AddFailure(err)
+		}
+		return conn, err
+	}
+	return utils.PolicyDialContext(dialer)(ctx, "tcp", net.JoinHostPort(domain, strconv.Itoa(targetPort)))
+}
+
+// newCertificateInfo summarizes a certificate for the chain listings
+func newCertificateInfo(cert *x509.Certificate) CertificateInfo {
+	return CertificateInfo{
+		Subject:   cert.Subject.String(),
+		Issuer:    cert.Issuer.String(),
+		NotBefore: cert.NotBefore,
+		NotAfter:  cert.NotAfter,
+		IsCA:      cert.IsCA,
+		KeyUsage:  getKeyUsage(cert),
+	}
 }
 
 // validateCertificate performs basic certificate validation
@@ -165,7 +405,7 @@ func validateCertificate(cert *x509.Certificate, domain string) []string {
 	}
 
 	// Check domain match
-	if !matchesDomain(cert, domain) {
+	if domain != "" && cert.VerifyHostname(domain) != nil {
 		errors = append(errors, "certificate does not match domain")
 	}
 
@@ -177,32 +417,54 @@ func validateCertificate(cert *x509.Certificate, domain string) []string {
 	return errors
 }
 
-// matchesDomain checks if certificate matches the domain
-func matchesDomain(cert *x509.Certificate, domain string) bool {
-	// Check subject common name
-	if strings.EqualFold(cert.Subject.CommonName, domain) {
-		return true
-	}
+// HostnameCheck reports whether a certificate is valid for a hostname, following the rules
+// of crypto/x509: only SANs count, and a wildcard covers exactly one leftmost label.
+type HostnameCheck struct {
+	Host        string `json:"host"`
+	Matches     bool   `json:"matches"`
+	MatchedName string `json:"matched_name,omitempty"` // The SAN that covers the host
+	Error       string `json:"error,omitempty"`
+}
 
-	// Check subject alternative names
+// checkHostname verifies host against the certificate and finds the SAN that matched.
+func checkHostname(cert *x509.Certificate, host string) HostnameCheck {
+	check := HostnameCheck{Host: host}
+	if err := cert.VerifyHostname(host); err != nil {
+		check.Error = err.Error()
+		return check
+	}
+	check.Matches = true
+	// Verify against each SAN on its own, so the reported name follows the same rules
 	for _, name := range cert.DNSNames {
-		if strings.EqualFold(name, domain) {
-			return true
+		if (&x509.Certificate{DNSNames: []string{name}}).VerifyHostname(host) == nil {
+			check.MatchedName = name
+			return check
 		}
-		// Check wildcard match
-		if strings.HasPrefix(name, "*.") {
-			wildcard := name[2:]
-			if strings.HasSuffix(domain, "."+wildcard) || strings.EqualFold(domain, wildcard) {
-				return true
-			}
+	}
+	for _, ip := range cert.IPAddresses {
+		if (&x509.Certificate{IPAddresses: []net.IP{ip}}).VerifyHostname(host) == nil {
+			check.MatchedName = ip.String()
+			return check
 		}
 	}
+	return check
+}
+
+// Fingerprint returns the hex SHA-256 fingerprint of a certificate's DER encoding.
+func Fingerprint(cert *x509.Certificate) string {
+	sum := sha256.Sum256(cert.Raw)
+	return hex.EncodeToString(sum[:])
+}
 
-	return false
+// PublicKeyPin returns the base64 SHA-256 of a certificate's SubjectPublicKeyInfo, the pin
+// format of RFC 7469. Unlike the fingerprint it survives renewals that keep the key.
+func PublicKeyPin(cert *x509.Certificate) string {
+	sum := sha256.Sum256(cert.RawSubjectPublicKeyInfo)
+	return base64.StdEncoding.EncodeToString(sum[:])
 }
 
-// getKeySize determines the key size based on public key type
-func getKeySize(cert *x509.Certificate) int {
+// KeySize determines the key size based on public key type
+func KeySize(cert *x509.Certificate) int {
 	switch pub := cert.PublicKey.(type) {
 	case *rsa.PublicKey:
 		return pub.N.BitLen()
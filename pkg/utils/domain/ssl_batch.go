@@ -0,0 +1,162 @@
+package domain
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// MaxBatchSSLTargets caps the number of host:port pairs in one batch SSL check.
+	MaxBatchSSLTargets = 500
+
+	// batchSSLWorkers bounds how many SSL checks a batch runs at once.
+	batchSSLWorkers = 16
+
+	// DefaultBatchSSLTimeout and MaxBatchSSLTimeout bound the time spent on one target.
+	DefaultBatchSSLTimeout = 15 * time.Second
+	MaxBatchSSLTimeout     = 60 * time.Second
+
+	// batchExpiringDays is the window for the expiring_within_30_days count.
+	batchExpiringDays = 30
+)
+
+// SSLBatchResult is the certificate of one target in a batch check.
+type SSLBatchResult struct {
+	Target             string    `json:"target"` // As given in the request
+	Host               string    `json:"host"`
+	Port               int       `json:"port"`
+	Subject            string    `json:"subject,omitempty"`
+	Issuer             string    `json:"issuer,omitempty"`
+	NotAfter           time.Time `json:"not_after,omitempty"`
+	DaysUntilExpiry    int       `json:"days_until_expiry"`
+	FingerprintSHA256  string    `json:"fingerprint_sha256,omitempty"`
+	PublicKeyAlgorithm string    `json:"public_key_algorithm,omitempty"`
+	KeySize            int       `json:"key_size,omitempty"`
+	SignatureAlgorithm string    `json:"signature_algorithm,omitempty"`
+	TLSVersion         string    `json:"tls_version,omitempty"`
+	Grade              string    `json:"grade,omitempty"`
+	Valid              bool      `json:"valid"` // In its validity period, trusted and covering the host
+	Weak               bool      `json:"weak"`  // A weak key or signature algorithm somewhere in the served chain
+	Error              string    `json:"error,omitempty"`
+}
+
+// SSLBatchSummary counts the results of a batch check.
+type SSLBatchSummary struct {
+	Total             int            `json:"total"`
+	Succeeded         int            `json:"succeeded"`
+	Failed            int            `json:"failed"`
+	Invalid           int            `json:"invalid"`
+	Expired           int            `json:"expired"`
+	ExpiringWithin30  int            `json:"expiring_within_30_days"` // Not yet expired
+	Weak              int            `json:"weak"`
+	GradeDistribution map[string]int `json:"grade_distribution"`
+}
+
+// CheckSSLBatch checks many host or host:port targets with a bounded worker pool, giving
+// each target at most timeout. Results are returned in the same order as the input.
+func CheckSSLBatch(ctx context.Context, targets []string, timeout time.Duration) ([]SSLBatchResult, SSLBatchSummary) {
+	results := make([]SSLBatchResult, len(targets))
+	workers := min(batchSSLWorkers, len(targets))
+
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				results[i] = checkSSLBatchTarget(ctx, targets[i], timeout)
+			}
+		}()
+	}
+	for i := range targets {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+
+	return results, summarizeSSLBatch(results)
+}
+
+// checkSSLBatchTarget runs the SSL check for one target of a batch.
+func checkSSLBatchTarget(ctx context.Context, target string, timeout time.Duration) SSLBatchResult {
+	result := SSLBatchResult{Target: target}
+	host, port, err := parseSSLTarget(target)
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	result.Host, result.Port = host, port
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	info, err := GetSSLInfoWithOptions(ctx, host, SSLCheckOptions{Port: port})
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	result.Subject = info.Subject
+	result.Issuer = info.Issuer
+	result.NotAfter = info.NotAfter
+	result.DaysUntilExpiry = info.DaysUntilExpiry
+	result.FingerprintSHA256 = info.FingerprintSHA256
+	result.PublicKeyAlgorithm = info.PublicKeyAlgorithm
+	result.KeySize = info.KeySize
+	result.SignatureAlgorithm = info.SignatureAlgorithm
+	result.TLSVersion = info.TLSVersion
+	result.Grade = info.Grade
+	result.Valid = info.IsValid && info.ChainTrusted && info.Hostname.Matches
+	result.Weak = slices.ContainsFunc(info.Findings, func(finding SSLFinding) bool {
+		return finding.Check == "key_size" || finding.Check == "signature"
+	})
+	return result
+}
+
+// parseSSLTarget splits a host or host:port target, defaulting the port to 443.
+func parseSSLTarget(target string) (string, int, error) {
+	target = strings.TrimSpace(target)
+	if target == "" {
+		return "", 0, fmt.Errorf("empty target")
+	}
+	host, portStr, err := net.SplitHostPort(target)
+	if err != nil {
+		// No port, or a bare IPv6 address
+		return strings.Trim(target, "[]"), 443, nil
+	}
+	port, err := strconv.Atoi(portStr)
+	if err != nil || port <= 0 || port > 65535 {
+		return "", 0, fmt.Errorf("invalid port %q", portStr)
+	}
+	return host, port, nil
+}
+
+// summarizeSSLBatch counts the expiring, invalid and weak certificates of a batch.
+func summarizeSSLBatch(results []SSLBatchResult) SSLBatchSummary {
+	summary := SSLBatchSummary{Total: len(results), GradeDistribution: map[string]int{}}
+	for _, result := range results {
+		if result.Error != "" {
+			summary.Failed++
+			continue
+		}
+		summary.Succeeded++
+		summary.GradeDistribution[result.Grade]++
+		if !result.Valid {
+			summary.Invalid++
+		}
+		if result.DaysUntilExpiry <= 0 {
+			summary.Expired++
+		} else if result.DaysUntilExpiry <= batchExpiringDays {
+			summary.ExpiringWithin30++
+		}
+		if result.Weak {
+			summary.Weak++
+		}
+	}
+	return summary
+}
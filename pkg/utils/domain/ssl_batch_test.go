@@ -0,0 +1,73 @@
+package domain
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseSSLTarget(t *testing.T) {
+	for _, tt := range []struct {
+		target   string
+		wantHost string
+		wantPort int
+		wantErr  bool
+	}{
+		{"example.com", "example.com", 443, false},
+		{" example.com:8443 ", "example.com", 8443, false},
+		{"[2001:db8::1]:443", "2001:db8::1", 443, false},
+		{"2001:db8::1", "2001:db8::1", 443, false},
+		{"example.com:0", "", 0, true},
+		{"example.com:https", "", 0, true},
+		{"", "", 0, true},
+	} {
+		host, port, err := parseSSLTarget(tt.target)
+		if host != tt.wantHost || port != tt.wantPort || (err != nil) != tt.wantErr {
+			t.Errorf("parseSSLTarget(%q) = %q, %d, %v, want %q, %d", tt.target, host, port, err, tt.wantHost, tt.wantPort)
+		}
+	}
+}
+
+func TestCheckSSLBatch(t *testing.T) {
+	server := httptest.NewTLSServer(http.NotFoundHandler())
+	defer server.Close()
+	target := strings.TrimPrefix(server.URL, "https://")
+
+	results, summary := CheckSSLBatch(context.Background(), []string{target, "example.com:99999", target}, 5*time.Second)
+	if len(results) != 3 || results[0].Target != target || results[1].Target != "example.com:99999" {
+		t.Fatalf("results = %+v, want them in request order", results)
+	}
+	if results[0].Error != "" || results[0].Port == 0 || results[0].FingerprintSHA256 == "" {
+		t.Errorf("result = %+v, want the test server's certificate", results[0])
+	}
+	if results[0].Valid {
+		t.Error("the test server's untrusted certificate was reported valid")
+	}
+	if results[1].Error == "" {
+		t.Error("an invalid port was checked")
+	}
+	if summary.Total != 3 || summary.Succeeded != 2 || summary.Failed != 1 || summary.Invalid != 2 || summary.GradeDistribution[results[0].Grade] != 2 {
+		t.Errorf("summary = %+v, want 2 invalid results and 1 failure", summary)
+	}
+}
+
+func TestSummarizeSSLBatch(t *testing.T) {
+	summary := summarizeSSLBatch([]SSLBatchResult{
+		{Grade: "A", Valid: true, DaysUntilExpiry: 90},
+		{Grade: "B", Valid: true, DaysUntilExpiry: 20},
+		{Grade: "F", DaysUntilExpiry: -3},
+		{Grade: "C", Valid: true, DaysUntilExpiry: 200, Weak: true},
+		{Error: "connection refused"},
+	})
+	want := SSLBatchSummary{Total: 5, Succeeded: 4, Failed: 1, Invalid: 1, Expired: 1, ExpiringWithin30: 1, Weak: 1}
+	if summary.Total != want.Total || summary.Succeeded != want.Succeeded || summary.Failed != want.Failed || summary.Invalid != want.Invalid ||
+		summary.Expired != want.Expired || summary.ExpiringWithin30 != want.ExpiringWithin30 || summary.Weak != want.Weak {
+		t.Errorf("summary = %+v, want %+v", summary, want)
+	}
+	if len(summary.GradeDistribution) != 4 || summary.GradeDistribution["A"] != 1 {
+		t.Errorf("grade distribution = %v, want one of each", summary.GradeDistribution)
+	}
+}
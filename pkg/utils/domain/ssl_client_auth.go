@@ -0,0 +1,114 @@
+package domain
+
+import (
+	"crypto/tls"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"sync"
+)
+
+// ClientAuthInfo reports whether the server asked for a client certificate (mutual TLS).
+type ClientAuthInfo struct {
+	Requested       bool     `json:"requested"`
+	AcceptableCAs   []string `json:"acceptable_cas,omitempty"` // Distinguished names the server advertised, if any
+	CertificateSent bool     `json:"certificate_sent"`
+}
+
+// ClientProfileConfig names a client certificate and key kept on the server.
+type ClientProfileConfig struct {
+	CertFile string `json:"cert_file"`
+	KeyFile  string `json:"key_file"`
+}
+
+var (
+	clientProfilesMu sync.RWMutex
+	clientProfiles   = map[string]*tls.Certificate{}
+)
+
+// LoadSSLClientProfiles reads the client certificate profiles (a JSON object of name to
+// ClientProfileConfig) from a file. An empty path configures no profiles.
+func LoadSSLClientProfiles(path string) {
+	if path == "" {
+		return
+	}
+	fileData, err := os.ReadFile(path)
+	if err != nil {
+		log.Fatalf("Could not read SSL client profiles at %s: %v", path, err)
+	}
+	var configs map[string]ClientProfileConfig
+	if err := json.Unmarshal(fileData, &configs); err != nil {
+		log.Fatalf("Could not parse SSL client profiles at %s: %v", path, err)
+	}
+	if err := ConfigureSSLClientProfiles(configs); err != nil {
+		log.Fatalf("Invalid SSL client profiles at %s: %v", path, err)
+	}
+	log.Printf("SSL client profiles loaded from %s (%d profiles)", path, len(configs))
+}
+
+// ConfigureSSLClientProfiles replaces the client certificate profiles.
+func ConfigureSSLClientProfiles(configs map[string]ClientProfileConfig) error {
+	profiles := make(map[string]*tls.Certificate, len(configs))
+	for name, config := range configs {
+		cert, err := tls.LoadX509KeyPair(config.CertFile, config.KeyFile)
+		if err != nil {
+			return fmt.Errorf("profile %q: %w", name, err)
+		}
+		profiles[name] = &cert
+	}
+	clientProfilesMu.Lock()
+	clientProfiles = profiles
+	clientProfilesMu.Unlock()
+	return nil
+}
+
+// SSLClientProfile returns the client certificate of a configured profile.
+func SSLClientProfile(name string) (*tls.Certificate, error) {
+	clientProfilesMu.RLock()
+	defer clientProfilesMu.RUnlock()
+	cert, ok := clientProfiles[name]
+	if !ok {
+		names := make([]string, 0, len(clientProfiles))
+		for known := range clientProfiles {
+			names = append(names, known)
+		}
+		sort.Strings(names)
+		return nil, fmt.Errorf("unknown client profile %q (configured: %v)", name, names)
+	}
+	return cert, nil
+}
+
+// ParseClientCertificate builds a client certificate from PEM encoded certificate and key.
+func ParseClientCertificate(certPEM, keyPEM string) (*tls.Certificate, error) {
+	cert, err := tls.X509KeyPair([]byte(certPEM), []byte(keyPEM))
+	if err != nil {
+		return nil, fmt.Errorf("invalid client certificate or key: %w", err)
+	}
+	return &cert, nil
+}
+
+// clientAuthRecorder answers the server's certificate request with the configured client
+// certificate, or none, and records what was asked for.
+func clientAuthRecorder(cert *tls.Certificate, info *ClientAuthInfo) func(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+	return func(request *tls.CertificateRequestInfo) (*tls.Certificate, error) {
+		info.Requested = true
+		for _, der := range request.AcceptableCAs {
+			var rdn pkix.RDNSequence
+			if _, err := asn1.Unmarshal(der, &rdn); err != nil {
+				continue
+			}
+			var name pkix.Name
+			name.FillFromRDNSequence(&rdn)
+			info.AcceptableCAs = append(info.AcceptableCAs, name.String())
+		}
+		if cert == nil {
+			return &tls.Certificate{}, nil
+		}
+		info.CertificateSent = true
+		return cert, nil
+	}
+}
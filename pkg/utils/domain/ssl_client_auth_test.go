@@ -0,0 +1,90 @@
+package domain
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newClientCertificate returns a self-signed client certificate and its PEM encoding.
+func newClientCertificate(t *testing.T) (*x509.Certificate, string, string) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "Test Client CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, _ := x509.ParseCertificate(der)
+	keyDER, _ := x509.MarshalECPrivateKey(key)
+	return cert, string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})),
+		string(pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}))
+}
+
+func TestGetSSLInfoClientCertificate(t *testing.T) {
+	clientCert, certPEM, keyPEM := newClientCertificate(t)
+	pool := x509.NewCertPool()
+	pool.AddCert(clientCert)
+	server := httptest.NewUnstartedServer(http.NotFoundHandler())
+	server.TLS = &tls.Config{ClientAuth: tls.RequireAndVerifyClientCert, ClientCAs: pool, MaxVersion: tls.VersionTLS12}
+	server.StartTLS()
+	defer server.Close()
+	_, portStr, _ := net.SplitHostPort(server.Listener.Addr().String())
+	port, _ := strconv.Atoi(portStr)
+
+	_, err := getSSLInfo(context.Background(), "example.com", port, net.ParseIP("127.0.0.1"), SSLCheckOptions{})
+	if err == nil || !strings.Contains(err.Error(), "requires a client certificate") {
+		t.Errorf("check without a client certificate = %v, want it to report the requirement", err)
+	}
+
+	certificate, err := ParseClientCertificate(certPEM, keyPEM)
+	if err != nil {
+		t.Fatal(err)
+	}
+	info, err := getSSLInfo(context.Background(), "example.com", port, net.ParseIP("127.0.0.1"), SSLCheckOptions{ClientCertificate: certificate})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if auth := info.ClientAuth; !auth.Requested || !auth.CertificateSent || len(auth.AcceptableCAs) != 1 || auth.AcceptableCAs[0] != "CN=Test Client CA" {
+		t.Errorf("client auth = %+v, want the request, the sent certificate and the advertised CA", auth)
+	}
+
+	if _, err := ParseClientCertificate(certPEM, ""); err == nil {
+		t.Error("ParseClientCertificate accepted a certificate without its key")
+	}
+}
+
+func TestSSLClientProfile(t *testing.T) {
+	t.Cleanup(func() { ConfigureSSLClientProfiles(nil) })
+	if err := ConfigureSSLClientProfiles(map[string]ClientProfileConfig{"missing": {CertFile: "/nonexistent.pem", KeyFile: "/nonexistent.key"}}); err == nil {
+		t.Error("ConfigureSSLClientProfiles accepted unreadable files")
+	}
+	if _, err := SSLClientProfile("partner"); err == nil {
+		t.Error("SSLClientProfile found an unconfigured profile")
+	}
+}
@@ -0,0 +1,171 @@
+package domain
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/vit0-9/utils_api/pkg/utils"
+)
+
+// SSLTarget is one side of a certificate comparison: a host, optionally on a given port or
+// reached at a given address instead of the one it resolves to.
+type SSLTarget struct {
+	Host string `json:"host"`
+	Port int    `json:"port,omitempty"`
+	IP   string `json:"ip,omitempty"`
+}
+
+func (t SSLTarget) String() string {
+	target := t.Host
+	if t.Port > 0 {
+		target = net.JoinHostPort(t.Host, strconv.Itoa(t.Port))
+	}
+	if t.IP != "" {
+		target += " at " + t.IP
+	}
+	return target
+}
+
+// SSLCertificateSummary is the certificate one side of a comparison served.
+type SSLCertificateSummary struct {
+	Target            SSLTarget `json:"target"`
+	Subject           string    `json:"subject,omitempty"`
+	Issuer            string    `json:"issuer,omitempty"`
+	SerialNumber      string    `json:"serial_number,omitempty"`
+	SubjectAltNames   []string  `json:"subject_alt_names,omitempty"`
+	NotBefore         time.Time `json:"not_before,omitempty"`
+	NotAfter          time.Time `json:"not_after,omitempty"`
+	FingerprintSHA256 string    `json:"fingerprint_sha256,omitempty"`
+	PublicKeyPin      string    `json:"public_key_pin_sha256,omitempty"`
+	ChainTrusted      bool      `json:"chain_trusted"`
+	TLSVersion        string    `json:"tls_version,omitempty"`
+	Error             string    `json:"error,omitempty"`
+}
+
+// SSLDifference is one field that differs between the two certificates.
+type SSLDifference struct {
+	Field string `json:"field"`
+	A     string `json:"a"`
+	B     string `json:"b"`
+}
+
+// SSLComparison is the result of comparing the certificates served by two targets.
+type SSLComparison struct {
+	A                SSLCertificateSummary `json:"a"`
+	B                SSLCertificateSummary `json:"b"`
+	SameCertificate  bool                  `json:"same_certificate"` // Identical fingerprints
+	SamePublicKey    bool                  `json:"same_public_key"`  // Identical pins, e.g. a renewal that kept the key
+	Differences      []SSLDifference       `json:"differences"`
+	ComparedAt       time.Time             `json:"compared_at"`
+	ComparisonFailed bool                  `json:"comparison_failed,omitempty"` // Either check failed, see the side's error
+}
+
+// CompareSSL checks both targets concurrently and lists where their certificates differ.
+func CompareSSL(ctx context.Context, a, b SSLTarget) *SSLComparison {
+	comparison := &SSLComparison{Differences: []SSLDifference{}}
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		comparison.A = checkSSLTarget(ctx, a)
+	}()
+	go func() {
+		defer wg.Done()
+		comparison.B = checkSSLTarget(ctx, b)
+	}()
+	wg.Wait()
+	comparison.ComparedAt = time.Now()
+
+	if comparison.A.Error != "" || comparison.B.Error != "" {
+		comparison.ComparisonFailed = true
+		return comparison
+	}
+	comparison.SameCertificate = comparison.A.FingerprintSHA256 == comparison.B.FingerprintSHA256
+	comparison.SamePublicKey = comparison.A.PublicKeyPin == comparison.B.PublicKeyPin
+	comparison.Differences = diffSSLSummaries(comparison.A, comparison.B)
+	return comparison
+}
+
+// checkSSLTarget runs the SSL check for one side of a comparison.
+func checkSSLTarget(ctx context.Context, target SSLTarget) SSLCertificateSummary {
+	summary := SSLCertificateSummary{Target: target}
+	host := strings.ToLower(strings.TrimSpace(target.Host))
+	port := 443
+	if target.Port > 0 {
+		port = target.Port
+	}
+
+	var ip net.IP
+	if target.IP != "" {
+		if ip = net.ParseIP(target.IP); ip == nil {
+			summary.Error = fmt.Sprintf("invalid IP address %q", target.IP)
+			return summary
+		}
+		if utils.OfflineMode() {
+			summary.Error = utils.ErrOffline.Error()
+			return summary
+		}
+		if err := utils.CheckOutboundAddress(host, []net.IP{ip}); err != nil {
+			summary.Error = err.Error()
+			return summary
+		}
+	}
+
+	var info *SSLInfo
+	var err error
+	if ip != nil {
+		info, err = getSSLInfo(ctx, host, port, ip, SSLCheckOptions{})
+	} else {
+		info, err = GetSSLInfoWithOptions(ctx, host, SSLCheckOptions{Port: port})
+	}
+	if err != nil {
+		summary.Error = err.Error()
+		return summary
+	}
+	summary.Subject = info.Subject
+	summary.Issuer = info.Issuer
+	summary.SerialNumber = info.SerialNumber
+	summary.SubjectAltNames = info.SubjectAltNames
+	summary.NotBefore = info.NotBefore
+	summary.NotAfter = info.NotAfter
+	summary.FingerprintSHA256 = info.FingerprintSHA256
+	summary.PublicKeyPin = info.PublicKeyPin
+	summary.ChainTrusted = info.ChainTrusted
+	summary.TLSVersion = info.TLSVersion
+	return summary
+}
+
+// diffSSLSummaries lists the fields that differ between two successful checks.
+func diffSSLSummaries(a, b SSLCertificateSummary) []SSLDifference {
+	differences := []SSLDifference{}
+	add := func(field, valueA, valueB string) {
+		if valueA != valueB {
+			differences = append(differences, SSLDifference{Field: field, A: valueA, B: valueB})
+		}
+	}
+	add("subject", a.Subject, b.Subject)
+	add("issuer", a.Issuer, b.Issuer)
+	add("serial_number", a.SerialNumber, b.SerialNumber)
+	add("subject_alt_names", sortedNames(a.SubjectAltNames), sortedNames(b.SubjectAltNames))
+	add("not_before", a.NotBefore.UTC().Format(time.RFC3339), b.NotBefore.UTC().Format(time.RFC3339))
+	add("not_after", a.NotAfter.UTC().Format(time.RFC3339), b.NotAfter.UTC().Format(time.RFC3339))
+	add("fingerprint_sha256", a.FingerprintSHA256, b.FingerprintSHA256)
+	add("public_key_pin_sha256", a.PublicKeyPin, b.PublicKeyPin)
+	add("chain_trusted", strconv.FormatBool(a.ChainTrusted), strconv.FormatBool(b.ChainTrusted))
+	add("tls_version", a.TLSVersion, b.TLSVersion)
+	return differences
+}
+
+// sortedNames joins SANs in a stable order, so the same names served in a different order
+// are not reported as a difference.
+func sortedNames(names []string) string {
+	names = slices.Clone(names)
+	slices.Sort(names)
+	return strings.Join(names, ", ")
+}
@@ -0,0 +1,62 @@
+package domain
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+)
+
+func TestCompareSSL(t *testing.T) {
+	first := httptest.NewTLSServer(http.NotFoundHandler())
+	defer first.Close()
+	_, certPEM, keyPEM := newClientCertificate(t)
+	certificate, err := ParseClientCertificate(certPEM, keyPEM)
+	if err != nil {
+		t.Fatal(err)
+	}
+	second := httptest.NewUnstartedServer(http.NotFoundHandler())
+	second.TLS = &tls.Config{Certificates: []tls.Certificate{*certificate}}
+	second.StartTLS()
+	defer second.Close()
+	target := func(server *httptest.Server) SSLTarget {
+		_, portStr, _ := net.SplitHostPort(server.Listener.Addr().String())
+		port, _ := strconv.Atoi(portStr)
+		return SSLTarget{Host: "example.com", Port: port, IP: "127.0.0.1"}
+	}
+
+	same := CompareSSL(context.Background(), target(first), target(first))
+	if same.ComparisonFailed || !same.SameCertificate || !same.SamePublicKey || len(same.Differences) != 0 {
+		t.Errorf("comparing a server with itself = %+v, want the same certificate", same)
+	}
+
+	different := CompareSSL(context.Background(), target(first), target(second))
+	if different.ComparisonFailed || different.SameCertificate || different.SamePublicKey {
+		t.Fatalf("comparing two certificates = %+v, want a difference", different)
+	}
+	fields := map[string]bool{}
+	for _, difference := range different.Differences {
+		fields[difference.Field] = true
+	}
+	for _, field := range []string{"issuer", "serial_number", "fingerprint_sha256", "public_key_pin_sha256"} {
+		if !fields[field] {
+			t.Errorf("differences %+v do not include %s", different.Differences, field)
+		}
+	}
+
+	failed := CompareSSL(context.Background(), target(first), SSLTarget{Host: "example.com", IP: "not-an-ip"})
+	if !failed.ComparisonFailed || failed.B.Error == "" || failed.A.Error != "" {
+		t.Errorf("comparison with an invalid address = %+v, want only side b to fail", failed)
+	}
+}
+
+func TestDiffSSLSummariesIgnoresSANOrder(t *testing.T) {
+	a := SSLCertificateSummary{SubjectAltNames: []string{"a.example", "b.example"}}
+	b := SSLCertificateSummary{SubjectAltNames: []string{"b.example", "a.example"}}
+	if differences := diffSSLSummaries(a, b); len(differences) != 0 {
+		t.Errorf("differences = %+v, want none for reordered SANs", differences)
+	}
+}
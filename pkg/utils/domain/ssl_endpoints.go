@@ -0,0 +1,127 @@
+package domain
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/vit0-9/utils_api/pkg/utils"
+)
+
+// maxSSLEndpoints caps how many resolved addresses one check connects to.
+const maxSSLEndpoints = 16
+
+// SSLEndpoint is the certificate served by one address of a host.
+type SSLEndpoint struct {
+	IP                string    `json:"ip"`
+	FingerprintSHA256 string    `json:"fingerprint_sha256,omitempty"`
+	Subject           string    `json:"subject,omitempty"`
+	Issuer            string    `json:"issuer,omitempty"`
+	NotAfter          time.Time `json:"not_after,omitempty"`
+	DaysUntilExpiry   int       `json:"days_until_expiry"`
+	IsValid           bool      `json:"is_valid"`
+	ChainTrusted      bool      `json:"chain_trusted"`
+	TLSVersion        string    `json:"tls_version,omitempty"`
+	Error             string    `json:"error,omitempty"`
+}
+
+// getSSLInfoForAllIPs checks the certificate on every address the host resolves to. The
+// result describes the first address that answered, with all of them listed in Endpoints.
+func getSSLInfoForAllIPs(ctx context.Context, domain string, targetPort int, options SSLCheckOptions) (*SSLInfo, error) {
+	if utils.OfflineMode() {
+		return nil, &SSLError{Domain: domain, Err: utils.ErrOffline}
+	}
+	ipAddrs, err := net.DefaultResolver.LookupIPAddr(ctx, domain)
+	if err != nil {
+		return nil, &SSLError{Domain: domain, Err: err}
+	}
+	var ips []net.IP
+	for _, ipAddr := range ipAddrs {
+		ips = append(ips, ipAddr.IP)
+	}
+	if len(ips) == 0 {
+		return nil, &SSLError{Domain: domain, Err: fmt.Errorf("no addresses found")}
+	}
+	if err := utils.CheckOutboundAddress(domain, ips); err != nil {
+		return nil, &SSLError{Domain: domain, Err: err}
+	}
+	sort.SliceStable(ips, func(i, j int) bool { return ips[i].To4() != nil && ips[j].To4() == nil })
+	if len(ips) > maxSSLEndpoints {
+		ips = ips[:maxSSLEndpoints]
+	}
+
+	infos := make([]*SSLInfo, len(ips))
+	errs := make([]error, len(ips))
+	var wg sync.WaitGroup
+	for i, ip := range ips {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			infos[i], errs[i] = getSSLInfo(ctx, domain, targetPort, ip, options)
+		}()
+	}
+	wg.Wait()
+
+	var primary *SSLInfo
+	endpoints := make([]SSLEndpoint, len(ips))
+	for i, ip := range ips {
+		endpoints[i] = newSSLEndpoint(ip, infos[i], errs[i])
+		if primary == nil && infos[i] != nil {
+			primary = infos[i]
+		}
+	}
+	if primary == nil {
+		return nil, errs[0]
+	}
+	primary.Endpoints = endpoints
+	primary.EndpointMismatches = compareSSLEndpoints(endpoints)
+	return primary, nil
+}
+
+func newSSLEndpoint(ip net.IP, info *SSLInfo, err error) SSLEndpoint {
+	endpoint := SSLEndpoint{IP: ip.String()}
+	if err != nil {
+		endpoint.Error = err.Error()
+		return endpoint
+	}
+	endpoint.FingerprintSHA256 = info.FingerprintSHA256
+	endpoint.Subject = info.Subject
+	endpoint.Issuer = info.Issuer
+	endpoint.NotAfter = info.NotAfter
+	endpoint.DaysUntilExpiry = info.DaysUntilExpiry
+	endpoint.IsValid = info.IsValid
+	endpoint.ChainTrusted = info.ChainTrusted
+	endpoint.TLSVersion = info.TLSVersion
+	return endpoint
+}
+
+// compareSSLEndpoints describes how the endpoints differ from the first one that answered:
+// failed connections, a different certificate, chain trust or TLS version.
+func compareSSLEndpoints(endpoints []SSLEndpoint) []string {
+	var mismatches []string
+	var reference *SSLEndpoint
+	for i := range endpoints {
+		if endpoints[i].Error == "" {
+			reference = &endpoints[i]
+			break
+		}
+	}
+	for _, endpoint := range endpoints {
+		switch {
+		case endpoint.Error != "":
+			mismatches = append(mismatches, fmt.Sprintf("%s: check failed: %s", endpoint.IP, endpoint.Error))
+		case reference == nil || endpoint.IP == reference.IP:
+		case endpoint.FingerprintSHA256 != reference.FingerprintSHA256:
+			mismatches = append(mismatches, fmt.Sprintf("%s serves a different certificate than %s (expires %s vs %s)",
+				endpoint.IP, reference.IP, endpoint.NotAfter.Format("2006-01-02"), reference.NotAfter.Format("2006-01-02")))
+		case endpoint.ChainTrusted != reference.ChainTrusted:
+			mismatches = append(mismatches, fmt.Sprintf("%s chain trusted is %t but %t on %s", endpoint.IP, endpoint.ChainTrusted, reference.ChainTrusted, reference.IP))
+		case endpoint.TLSVersion != reference.TLSVersion:
+			mismatches = append(mismatches, fmt.Sprintf("%s negotiated %s but %s negotiated %s", endpoint.IP, endpoint.TLSVersion, reference.IP, reference.TLSVersion))
+		}
+	}
+	return mismatches
+}
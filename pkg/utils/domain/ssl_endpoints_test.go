@@ -0,0 +1,51 @@
+package domain
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestGetSSLInfoPinnedIP(t *testing.T) {
+	server := httptest.NewTLSServer(http.NotFoundHandler())
+	defer server.Close()
+	_, portStr, _ := net.SplitHostPort(server.Listener.Addr().String())
+	port, _ := strconv.Atoi(portStr)
+
+	info, err := getSSLInfo(context.Background(), "example.com", port, net.ParseIP("127.0.0.1"), SSLCheckOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := Fingerprint(server.Certificate()); info.FingerprintSHA256 != want {
+		t.Errorf("fingerprint = %s, want the test server's %s", info.FingerprintSHA256, want)
+	}
+	if endpoint := newSSLEndpoint(net.ParseIP("127.0.0.1"), info, nil); endpoint.IP != "127.0.0.1" || endpoint.FingerprintSHA256 != info.FingerprintSHA256 {
+		t.Errorf("endpoint = %+v", endpoint)
+	}
+}
+
+func TestCompareSSLEndpoints(t *testing.T) {
+	endpoints := []SSLEndpoint{
+		{IP: "192.0.2.1", Error: "connection refused"},
+		{IP: "192.0.2.2", FingerprintSHA256: "aa", ChainTrusted: true, TLSVersion: "TLS 1.3"},
+		{IP: "192.0.2.3", FingerprintSHA256: "aa", ChainTrusted: true, TLSVersion: "TLS 1.3"},
+		{IP: "2001:db8::1", FingerprintSHA256: "bb", ChainTrusted: true, TLSVersion: "TLS 1.3"},
+		{IP: "2001:db8::2", FingerprintSHA256: "aa", ChainTrusted: true, TLSVersion: "TLS 1.2"},
+	}
+	mismatches := compareSSLEndpoints(endpoints)
+	if len(mismatches) != 3 {
+		t.Fatalf("mismatches = %q, want the failure, the certificate and the TLS version", mismatches)
+	}
+	for i, want := range []string{"192.0.2.1: check failed", "2001:db8::1 serves a different certificate than 192.0.2.2", "2001:db8::2 negotiated TLS 1.2"} {
+		if !strings.HasPrefix(mismatches[i], want) {
+			t.Errorf("mismatch %d = %q, want prefix %q", i, mismatches[i], want)
+		}
+	}
+	if mismatches := compareSSLEndpoints(endpoints[1:3]); len(mismatches) != 0 {
+		t.Errorf("identical endpoints reported %q", mismatches)
+	}
+}
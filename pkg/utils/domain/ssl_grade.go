@@ -0,0 +1,128 @@
+package domain
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"time"
+)
+
+// Finding severities, from worst to least bad.
+const (
+	SeverityCritical = "critical"
+	SeverityHigh     = "high"
+	SeverityMedium   = "medium"
+	SeverityLow      = "low"
+)
+
+// maxCertificateLifetime is the longest validity browsers accept for certificates issued
+// since September 2020.
+const maxCertificateLifetime = 398 * 24 * time.Hour
+
+var lifetimeLimitSince = time.Date(2020, time.September, 1, 0, 0, 0, 0, time.UTC)
+
+// SSLFinding is one problem found in an SSL check.
+type SSLFinding struct {
+	Check    string `json:"check"` // e.g. "expiry", "signature", "key_size"
+	Severity string `json:"severity"`
+	Message  string `json:"message"`
+}
+
+// gradeSSL lists the findings of a check and grades it: F with any critical finding, C with
+// a high one, B with a medium one and A otherwise; low findings do not lower the grade.
+func gradeSSL(info *SSLInfo, peerCerts []*x509.Certificate) (string, []SSLFinding) {
+	findings := []SSLFinding{}
+	add := func(check, severity, format string, args ...any) {
+		findings = append(findings, SSLFinding{Check: check, Severity: severity, Message: fmt.Sprintf(format, args...)})
+	}
+	leaf := peerCerts[0]
+	now := time.Now()
+
+	switch remaining := leaf.NotAfter.Sub(now); {
+	case remaining <= 0:
+		add("expiry", SeverityCritical, "certificate expired on %s", leaf.NotAfter.Format("2006-01-02"))
+	case remaining <= 14*24*time.Hour:
+		add("expiry", SeverityHigh, "certificate expires in %d days", int(remaining.Hours()/24))
+	case remaining <= 30*24*time.Hour:
+		add("expiry", SeverityMedium, "certificate expires in %d days", int(remaining.Hours()/24))
+	}
+	if now.Before(leaf.NotBefore) {
+		add("expiry", SeverityCritical, "certificate is not valid until %s", leaf.NotBefore.Format("2006-01-02"))
+	}
+	if info.Domain != "" && leaf.VerifyHostname(info.Domain) != nil {
+		add("hostname", SeverityCritical, "certificate does not cover %s", info.Domain)
+	}
+	if len(leaf.DNSNames) == 0 && len(leaf.IPAddresses) == 0 {
+		add("san", SeverityHigh, "certificate has no subject alternative names; browsers ignore the common name")
+	}
+	if lifetime := leaf.NotAfter.Sub(leaf.NotBefore); leaf.NotBefore.After(lifetimeLimitSince) && lifetime > maxCertificateLifetime+24*time.Hour {
+		add("validity_period", SeverityMedium, "certificate is valid for %d days, more than the 398 browsers accept", int(lifetime.Hours()/24))
+	}
+
+	for i, cert := range peerCerts {
+		name := "leaf certificate"
+		if i > 0 {
+			name = "chain certificate " + cert.Subject.CommonName
+		}
+		selfSigned := i > 0 && isSelfSigned(cert)
+		if !selfSigned { // A root's own signature is never checked
+			switch cert.SignatureAlgorithm {
+			case x509.MD2WithRSA, x509.MD5WithRSA:
+				add("signature", SeverityCritical, "%s is signed with %s", name, cert.SignatureAlgorithm)
+			case x509.SHA1WithRSA, x509.DSAWithSHA1, x509.ECDSAWithSHA1:
+				add("signature", SeverityHigh, "%s is signed with %s", name, cert.SignatureAlgorithm)
+			}
+		}
+		if size := KeySize(cert); isWeakKey(cert.PublicKeyAlgorithm, size) {
+			add("key_size", SeverityHigh, "%s has a weak %s key (%d bits)", name, cert.PublicKeyAlgorithm, size)
+		}
+		if selfSigned {
+			add("chain", SeverityLow, "served chain includes the self-signed root %s, which clients ignore", cert.Subject.CommonName)
+		}
+	}
+	if info.IsSelfSigned {
+		add("trust", SeverityHigh, "certificate is self-signed")
+	} else if info.TrustIssue == TrustIssueUntrustedRoot {
+		add("trust", SeverityHigh, "chain ends in the untrusted root %s", info.UntrustedRoot)
+	} else if !info.ChainTrusted {
+		add("trust", SeverityHigh, "chain is not trusted: %s", info.VerificationError)
+	}
+
+	if info.TLSVersion == getTLSVersion(tls.VersionTLS10) || info.TLSVersion == getTLSVersion(tls.VersionTLS11) {
+		add("protocol", SeverityHigh, "connection negotiated the deprecated %s", info.TLSVersion)
+	}
+	for _, protocol := range info.Protocols {
+		if protocol.Supported && protocol.Deprecated {
+			add("protocol", SeverityMedium, "deprecated protocol %s is enabled", protocol.Version)
+		}
+	}
+	if info.Revocation != nil && info.Revocation.Status == "revoked" {
+		add("revocation", SeverityCritical, "certificate is revoked")
+	}
+
+	grade := "A"
+	for _, finding := range findings {
+		switch {
+		case finding.Severity == SeverityCritical:
+			grade = "F"
+		case finding.Severity == SeverityHigh && grade < "C":
+			grade = "C"
+		case finding.Severity == SeverityMedium && grade < "B":
+			grade = "B"
+		}
+	}
+	return grade, findings
+}
+
+// isWeakKey flags keys below current CA/Browser Forum minimums. An unknown size is not weak.
+func isWeakKey(algorithm x509.PublicKeyAlgorithm, size int) bool {
+	switch algorithm {
+	case x509.RSA:
+		return size > 0 && size < 2048
+	case x509.ECDSA:
+		return size > 0 && size < 256
+	case x509.DSA:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,74 @@
+package domain
+
+import (
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"slices"
+	"testing"
+	"time"
+)
+
+// gradeTestCertificate returns a certificate that grades A for example.com; tests adjust it.
+func gradeTestCertificate() *x509.Certificate {
+	return &x509.Certificate{
+		Subject:            pkix.Name{CommonName: "example.com"},
+		Issuer:             pkix.Name{CommonName: "Test CA"},
+		NotBefore:          time.Now().Add(-24 * time.Hour),
+		NotAfter:           time.Now().Add(90 * 24 * time.Hour),
+		DNSNames:           []string{"example.com"},
+		SignatureAlgorithm: x509.SHA256WithRSA,
+		PublicKeyAlgorithm: x509.RSA,
+		PublicKey:          &rsa.PublicKey{N: new(big.Int).Lsh(big.NewInt(1), 2047), E: 65537},
+	}
+}
+
+func TestGradeSSL(t *testing.T) {
+	tests := []struct {
+		name   string
+		adjust func(*x509.Certificate, *SSLInfo)
+		grade  string
+		check  string
+	}{
+		{"clean", func(*x509.Certificate, *SSLInfo) {}, "A", ""},
+		{"expiring soon", func(cert *x509.Certificate, _ *SSLInfo) { cert.NotAfter = time.Now().Add(20 * 24 * time.Hour) }, "B", "expiry"},
+		{"expired", func(cert *x509.Certificate, _ *SSLInfo) { cert.NotAfter = time.Now().Add(-time.Hour) }, "F", "expiry"},
+		{"long validity", func(cert *x509.Certificate, _ *SSLInfo) { cert.NotAfter = cert.NotBefore.Add(800 * 24 * time.Hour) }, "B", "validity_period"},
+		{"no SANs", func(cert *x509.Certificate, _ *SSLInfo) { cert.DNSNames = nil }, "F", "san"}, // The common name no longer covers the host
+		{"SHA-1", func(cert *x509.Certificate, _ *SSLInfo) { cert.SignatureAlgorithm = x509.SHA1WithRSA }, "C", "signature"},
+		{"weak key", func(cert *x509.Certificate, _ *SSLInfo) {
+			cert.PublicKey = &rsa.PublicKey{N: new(big.Int).Lsh(big.NewInt(1), 1023), E: 65537}
+		}, "C", "key_size"},
+		{"self-signed", func(_ *x509.Certificate, info *SSLInfo) { info.IsSelfSigned = true }, "C", "trust"},
+		{"hostname", func(_ *x509.Certificate, info *SSLInfo) { info.Domain = "other.example" }, "F", "hostname"},
+		{"TLS 1.0", func(_ *x509.Certificate, info *SSLInfo) { info.TLSVersion = "TLS 1.0" }, "C", "protocol"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cert := gradeTestCertificate()
+			info := &SSLInfo{Domain: "example.com", ChainTrusted: true, TLSVersion: "TLS 1.3"}
+			tt.adjust(cert, info)
+			grade, findings := gradeSSL(info, []*x509.Certificate{cert})
+			if grade != tt.grade {
+				t.Errorf("grade = %s, want %s (findings %+v)", grade, tt.grade, findings)
+			}
+			if tt.check == "" && len(findings) > 0 {
+				t.Errorf("findings = %+v, want none", findings)
+			}
+			if tt.check != "" && !slices.ContainsFunc(findings, func(finding SSLFinding) bool { return finding.Check == tt.check }) {
+				t.Errorf("findings = %+v, want a %s finding", findings, tt.check)
+			}
+		})
+	}
+}
+
+func TestGradeSSLChain(t *testing.T) {
+	leaf := gradeTestCertificate()
+	root, _ := issueTestCertificate(t, "Test CA", true, nil, nil)
+
+	grade, findings := gradeSSL(&SSLInfo{Domain: "example.com", ChainTrusted: true}, []*x509.Certificate{leaf, root})
+	if grade != "A" || len(findings) != 1 || findings[0].Check != "chain" || findings[0].Severity != SeverityLow {
+		t.Errorf("grade %s, findings %+v, want A with a low finding for the served root", grade, findings)
+	}
+}
@@ -0,0 +1,80 @@
+package domain
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+	"sync"
+	"time"
+)
+
+// ProtocolSupport is the result of probing one TLS version on its own connection.
+type ProtocolSupport struct {
+	Version     string `json:"version"`
+	Supported   bool   `json:"supported"`
+	Deprecated  bool   `json:"deprecated"` // TLS 1.0 and 1.1 (RFC 8996)
+	CipherSuite string `json:"cipher_suite,omitempty"`
+	Error       string `json:"error,omitempty"` // Why the handshake failed; usually the server refusing the version
+}
+
+// probedProtocols are the versions crypto/tls can speak. SSLv3 and older are not supported by
+// the library, so they cannot be probed.
+var probedProtocols = []uint16{tls.VersionTLS10, tls.VersionTLS11, tls.VersionTLS12, tls.VersionTLS13}
+
+// legacyCipherSuites lists every suite crypto/tls implements, including the RSA key exchange
+// and 3DES suites it leaves out by default, so servers offering only those can be checked.
+func legacyCipherSuites() []uint16 {
+	var ids []uint16
+	for _, suite := range tls.CipherSuites() {
+		ids = append(ids, suite.ID)
+	}
+	for _, suite := range tls.InsecureCipherSuites() {
+		ids = append(ids, suite.ID)
+	}
+	return ids
+}
+
+// probeProtocols tries a handshake limited to each TLS version, in parallel, on separate
+// connections from the main check.
+func probeProtocols(ctx context.Context, domain string, targetPort int, ip net.IP) []ProtocolSupport {
+	results := make([]ProtocolSupport, len(probedProtocols))
+	var wg sync.WaitGroup
+	for i, version := range probedProtocols {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i] = probeProtocol(ctx, domain, targetPort, ip, version)
+		}()
+	}
+	wg.Wait()
+	return results
+}
+
+func probeProtocol(ctx context.Context, domain string, targetPort int, ip net.IP, version uint16) ProtocolSupport {
+	result := ProtocolSupport{
+		Version:    getTLSVersion(version),
+		Deprecated: version < tls.VersionTLS12,
+	}
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+	rawConn, err := dialSSL(ctx, domain, targetPort, ip)
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	conn := tls.Client(rawConn, &tls.Config{
+		ServerName:         domain,
+		InsecureSkipVerify: true, // Only the protocol version is of interest
+		MinVersion:         version,
+		MaxVersion:         version,
+		CipherSuites:       legacyCipherSuites(),
+	})
+	defer conn.Close()
+	if err := conn.HandshakeContext(ctx); err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	result.Supported = true
+	result.CipherSuite = tls.CipherSuiteName(conn.ConnectionState().CipherSuite)
+	return result
+}
@@ -0,0 +1,49 @@
+package domain
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+)
+
+func TestGetSSLInfoLegacyProtocols(t *testing.T) {
+	server := httptest.NewUnstartedServer(http.NotFoundHandler())
+	server.TLS = &tls.Config{MinVersion: tls.VersionTLS10, MaxVersion: tls.VersionTLS11}
+	server.StartTLS()
+	defer server.Close()
+	_, portStr, _ := net.SplitHostPort(server.Listener.Addr().String())
+	port, _ := strconv.Atoi(portStr)
+
+	info, err := getSSLInfo(context.Background(), "example.com", port, net.ParseIP("127.0.0.1"), SSLCheckOptions{ProbeProtocols: true})
+	if err != nil {
+		t.Fatalf("check of a TLS 1.1 server failed: %v", err)
+	}
+	if info.TLSVersion != "TLS 1.1" {
+		t.Errorf("TLSVersion = %s, want TLS 1.1", info.TLSVersion)
+	}
+	want := map[string]bool{"TLS 1.0": true, "TLS 1.1": true, "TLS 1.2": false, "TLS 1.3": false}
+	if len(info.Protocols) != len(want) {
+		t.Fatalf("Protocols = %+v", info.Protocols)
+	}
+	for _, protocol := range info.Protocols {
+		if protocol.Supported != want[protocol.Version] {
+			t.Errorf("%s supported = %t, want %t (%s)", protocol.Version, protocol.Supported, want[protocol.Version], protocol.Error)
+		}
+		if protocol.Deprecated != (protocol.Version == "TLS 1.0" || protocol.Version == "TLS 1.1") {
+			t.Errorf("%s deprecated = %t", protocol.Version, protocol.Deprecated)
+		}
+	}
+	deprecated := 0
+	for _, message := range info.ValidationErrors {
+		if message == "deprecated protocol TLS 1.0 is enabled" || message == "deprecated protocol TLS 1.1 is enabled" {
+			deprecated++
+		}
+	}
+	if deprecated != 2 {
+		t.Errorf("ValidationErrors = %q, want both deprecated protocols flagged", info.ValidationErrors)
+	}
+}
@@ -0,0 +1,242 @@
+package domain
+
+import (
+	"bytes"
+	"context"
+	"crypto/x509"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/vit0-9/utils_api/pkg/utils"
+	"golang.org/x/crypto/ocsp"
+)
+
+// maxCRLSize bounds CRL downloads; some public CAs publish CRLs of several megabytes.
+const maxCRLSize = 20 << 20
+
+// revocationClient fetches the OCSP, CRL and issuer URLs named in the certificate the target
+// presents, which are as untrusted as a caller-supplied URL.
+var revocationClient = &http.Client{Timeout: 10 * time.Second, Transport: utils.NewTargetTransport()}
+
+// RevocationInfo reports the revocation status of the leaf certificate.
+type RevocationInfo struct {
+	Method             string     `json:"method,omitempty"`    // "ocsp" or "crl"
+	Status             string     `json:"status"`              // "good", "revoked" or "unknown"
+	Responder          string     `json:"responder,omitempty"` // OCSP responder or CRL URL queried
+	ResponderLatencyMs int64      `json:"responder_latency_ms,omitempty"`
+	RevokedAt          *time.Time `json:"revoked_at,omitempty"`
+	RevocationReason   string     `json:"revocation_reason,omitempty"`
+	ThisUpdate         *time.Time `json:"this_update,omitempty"`
+	NextUpdate         *time.Time `json:"next_update,omitempty"`
+	OCSPStapled        bool       `json:"ocsp_stapled"`
+	StapledStatus      string     `json:"stapled_status,omitempty"`
+	Error              string     `json:"error,omitempty"`
+}
+
+// revocationReasons maps RFC 5280 CRLReason codes to their names.
+var revocationReasons = map[int]string{
+	ocsp.Unspecified:          "unspecified",
+	ocsp.KeyCompromise:        "keyCompromise",
+	ocsp.CACompromise:         "cACompromise",
+	ocsp.AffiliationChanged:   "affiliationChanged",
+	ocsp.Superseded:           "superseded",
+	ocsp.CessationOfOperation: "cessationOfOperation",
+	ocsp.CertificateHold:      "certificateHold",
+	ocsp.RemoveFromCRL:        "removeFromCRL",
+	ocsp.PrivilegeWithdrawn:   "privilegeWithdrawn",
+	ocsp.AACompromise:         "aACompromise",
+}
+
+// ocspStatusName converts an OCSP status to the names used in RevocationInfo.
+func ocspStatusName(status int) string {
+	switch status {
+	case ocsp.Good:
+		return "good"
+	case ocsp.Revoked:
+		return "revoked"
+	default:
+		return "unknown"
+	}
+}
+
+// checkRevocation queries the leaf's OCSP responder, falling back to its CRL when it has no
+// OCSP responder or the responder fails. stapled is the OCSP response sent in the handshake.
+func checkRevocation(ctx context.Context, leaf, issuer *x509.Certificate, stapled []byte) *RevocationInfo {
+	info := &RevocationInfo{Status: "unknown", OCSPStapled: len(stapled) > 0}
+
+	if issuer == nil {
+		var err error
+		if issuer, err = fetchIssuer(ctx, leaf); err != nil {
+			info.Error = err.Error()
+			return info
+		}
+	}
+
+	if info.OCSPStapled {
+		if response, err := ocsp.ParseResponseForCert(stapled, leaf, issuer); err == nil {
+			info.StapledStatus = ocspStatusName(response.Status)
+		} else {
+			info.StapledStatus = "invalid"
+		}
+	}
+
+	var ocspErr error
+	if len(leaf.OCSPServer) > 0 {
+		if ocspErr = queryOCSP(ctx, leaf, issuer, info); ocspErr == nil {
+			return info
+		}
+	}
+	if len(leaf.CRLDistributionPoints) > 0 {
+		if err := checkCRL(ctx, leaf, issuer, info); err != nil {
+			info.Error = err.Error()
+		}
+		return info
+	}
+
+	if ocspErr != nil {
+		info.Error = ocspErr.Error()
+	} else {
+		info.Error = "certificate has no OCSP responder or CRL distribution point"
+	}
+	return info
+}
+
+// queryOCSP asks the leaf's first OCSP responder for its status.
+func queryOCSP(ctx context.Context, leaf, issuer *x509.Certificate, info *RevocationInfo) error {
+	responder := leaf.OCSPServer[0]
+	request, err := ocsp.CreateRequest(leaf, issuer, nil)
+	if err != nil {
+		return fmt.Errorf("failed to build OCSP request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", responder, bytes.NewReader(request))
+	if err != nil {
+		return fmt.Errorf("invalid OCSP responder %s: %w", responder, err)
+	}
+	req.Header.Set("Content-Type", "application/ocsp-request")
+	req.Header.Set("Accept", "application/ocsp-response")
+
+	start := time.Now()
+	resp, err := revocationClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("OCSP request to %s failed: %w", responder, err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	latency := time.Since(start).Milliseconds()
+	if err != nil {
+		return fmt.Errorf("failed to read OCSP response from %s: %w", responder, err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("OCSP responder %s returned status %d", responder, resp.StatusCode)
+	}
+
+	response, err := ocsp.ParseResponseForCert(body, leaf, issuer)
+	if err != nil {
+		return fmt.Errorf("invalid OCSP response from %s: %w", responder, err)
+	}
+
+	info.Method = "ocsp"
+	info.Responder = responder
+	info.ResponderLatencyMs = latency
+	info.Status = ocspStatusName(response.Status)
+	info.ThisUpdate = timePtr(response.ThisUpdate)
+	info.NextUpdate = timePtr(response.NextUpdate)
+	if response.Status == ocsp.Revoked {
+		info.RevokedAt = timePtr(response.RevokedAt)
+		info.RevocationReason = revocationReasons[response.RevocationReason]
+	}
+	return nil
+}
+
+// checkCRL downloads the leaf's first HTTP CRL and looks up its serial number.
+func checkCRL(ctx context.Context, leaf, issuer *x509.Certificate, info *RevocationInfo) error {
+	var crlURL string
+	for _, point := range leaf.CRLDistributionPoints {
+		if strings.HasPrefix(point, "http://") || strings.HasPrefix(point, "https://") {
+			crlURL = point
+			break
+		}
+	}
+	if crlURL == "" {
+		return fmt.Errorf("no HTTP CRL distribution point")
+	}
+
+	start := time.Now()
+	der, err := downloadRevocationData(ctx, crlURL, maxCRLSize)
+	latency := time.Since(start).Milliseconds()
+	if err != nil {
+		return err
+	}
+	crl, err := x509.ParseRevocationList(der)
+	if err != nil {
+		return fmt.Errorf("invalid CRL from %s: %w", crlURL, err)
+	}
+	if err := crl.CheckSignatureFrom(issuer); err != nil {
+		return fmt.Errorf("CRL from %s is not signed by the issuer: %w", crlURL, err)
+	}
+
+	info.Method = "crl"
+	info.Responder = crlURL
+	info.ResponderLatencyMs = latency
+	info.Status = "good"
+	info.ThisUpdate = timePtr(crl.ThisUpdate)
+	info.NextUpdate = timePtr(crl.NextUpdate)
+	for _, entry := range crl.RevokedCertificateEntries {
+		if entry.SerialNumber.Cmp(leaf.SerialNumber) == 0 {
+			info.Status = "revoked"
+			info.RevokedAt = timePtr(entry.RevocationTime)
+			info.RevocationReason = revocationReasons[entry.ReasonCode]
+			break
+		}
+	}
+	return nil
+}
+
+// fetchIssuer downloads the issuing certificate named in the leaf's AIA extension, for
+// servers that only send the leaf.
+func fetchIssuer(ctx context.Context, leaf *x509.Certificate) (*x509.Certificate, error) {
+	for _, issuerURL := range leaf.IssuingCertificateURL {
+		der, err := downloadRevocationData(ctx, issuerURL, 1<<20)
+		if err != nil {
+			continue
+		}
+		if issuer, err := x509.ParseCertificate(der); err == nil {
+			return issuer, nil
+		}
+	}
+	return nil, fmt.Errorf("issuer certificate not available to check revocation")
+}
+
+func downloadRevocationData(ctx context.Context, target string, limit int64) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", target, nil)
+	if err != nil {
+		return nil, fmt.Errorf("invalid URL %s: %w", target, err)
+	}
+	resp, err := revocationClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("download of %s failed: %w", target, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s returned status %d", target, resp.StatusCode)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, limit+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", target, err)
+	}
+	if int64(len(data)) > limit {
+		return nil, fmt.Errorf("%s exceeds %d bytes", target, limit)
+	}
+	return data, nil
+}
+
+func timePtr(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+	return &t
+}
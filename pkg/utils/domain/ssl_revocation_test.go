@@ -0,0 +1,244 @@
+package domain
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"errors"
+	"io"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/vit0-9/utils_api/pkg/utils"
+	"golang.org/x/crypto/ocsp"
+)
+
+// revocationPKI is a CA with an HTTP server answering for its OCSP responder, CRL and issuer
+// certificate URLs.
+type revocationPKI struct {
+	server    *httptest.Server
+	ca        *x509.Certificate
+	caKey     *ecdsa.PrivateKey
+	ocspReply func(leaf *ocsp.Request) (status int, revocation *ocsp.Response) // nil answers 500
+	revoked   []x509.RevocationListEntry
+	crlSigner *ecdsa.PrivateKey // The CA's key unless set
+	requests  []string
+}
+
+func newRevocationPKI(t *testing.T) *revocationPKI {
+	t.Helper()
+	pki := &revocationPKI{}
+	caKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "Revocation Test CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(24 * time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageCRLSign | x509.KeyUsageDigitalSignature,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &caKey.PublicKey, caKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pki.ca, err = x509.ParseCertificate(der); err != nil {
+		t.Fatal(err)
+	}
+	pki.caKey = caKey
+
+	pki.server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		pki.requests = append(pki.requests, r.Method+" "+r.URL.Path)
+		switch r.URL.Path {
+		case "/issuer.cer":
+			w.Write(pki.ca.Raw)
+		case "/ocsp":
+			body, _ := io.ReadAll(r.Body)
+			request, err := ocsp.ParseRequest(body)
+			if err != nil || pki.ocspReply == nil {
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			status, revocation := pki.ocspReply(request)
+			reply := ocsp.Response{Status: status, SerialNumber: request.SerialNumber, ThisUpdate: time.Now().Add(-time.Minute), NextUpdate: time.Now().Add(time.Hour)}
+			if revocation != nil {
+				reply.RevokedAt, reply.RevocationReason = revocation.RevokedAt, revocation.RevocationReason
+			}
+			response, err := ocsp.CreateResponse(pki.ca, pki.ca, reply, pki.caKey)
+			if err != nil {
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			w.Header().Set("Content-Type", "application/ocsp-response")
+			w.Write(response)
+		case "/crl":
+			signer := pki.caKey
+			if pki.crlSigner != nil {
+				signer = pki.crlSigner
+			}
+			crl, err := x509.CreateRevocationList(rand.Reader, &x509.RevocationList{
+				Number:                    big.NewInt(1),
+				ThisUpdate:                time.Now().Add(-time.Minute),
+				NextUpdate:                time.Now().Add(time.Hour),
+				RevokedCertificateEntries: pki.revoked,
+			}, pki.ca, signer)
+			if err != nil {
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			w.Write(crl)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(pki.server.Close)
+	return pki
+}
+
+// leaf issues a certificate naming the given revocation endpoints of the PKI's server, e.g.
+// "/ocsp" and "/crl".
+func (pki *revocationPKI) leaf(t *testing.T, serial int64, ocspPath, crlPath string) *x509.Certificate {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(serial),
+		Subject:               pkix.Name{CommonName: "www.example.test"},
+		DNSNames:              []string{"www.example.test"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(24 * time.Hour),
+		IssuingCertificateURL: []string{pki.server.URL + "/issuer.cer"},
+	}
+	if ocspPath != "" {
+		template.OCSPServer = []string{pki.server.URL + ocspPath}
+	}
+	if crlPath != "" {
+		template.CRLDistributionPoints = []string{pki.server.URL + crlPath}
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, pki.ca, &key.PublicKey, pki.caKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	leaf, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return leaf
+}
+
+func TestCheckRevocationOCSP(t *testing.T) {
+	pki := newRevocationPKI(t)
+	revokedAt := time.Now().Add(-24 * time.Hour).Truncate(time.Second).UTC()
+	pki.ocspReply = func(request *ocsp.Request) (int, *ocsp.Response) {
+		if request.SerialNumber.Int64() == 666 {
+			return ocsp.Revoked, &ocsp.Response{RevokedAt: revokedAt, RevocationReason: ocsp.KeyCompromise}
+		}
+		return ocsp.Good, nil
+	}
+
+	info := checkRevocation(context.Background(), pki.leaf(t, 100, "/ocsp", "/crl"), pki.ca, nil)
+	if info.Method != "ocsp" || info.Status != "good" || info.Responder != pki.server.URL+"/ocsp" || info.ThisUpdate == nil || info.NextUpdate == nil || info.Error != "" {
+		t.Errorf("good certificate = %+v", info)
+	}
+
+	info = checkRevocation(context.Background(), pki.leaf(t, 666, "/ocsp", ""), pki.ca, nil)
+	if info.Status != "revoked" || info.RevokedAt == nil || !info.RevokedAt.Equal(revokedAt) || info.RevocationReason != "keyCompromise" {
+		t.Errorf("revoked certificate = %+v", info)
+	}
+}
+
+func TestCheckRevocationStapled(t *testing.T) {
+	pki := newRevocationPKI(t)
+	pki.ocspReply = func(*ocsp.Request) (int, *ocsp.Response) { return ocsp.Good, nil }
+	leaf := pki.leaf(t, 101, "/ocsp", "")
+	stapled, err := ocsp.CreateResponse(pki.ca, pki.ca, ocsp.Response{Status: ocsp.Good, SerialNumber: leaf.SerialNumber, ThisUpdate: time.Now()}, pki.caKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	info := checkRevocation(context.Background(), leaf, pki.ca, stapled)
+	if !info.OCSPStapled || info.StapledStatus != "good" || info.Status != "good" {
+		t.Errorf("stapled response = %+v", info)
+	}
+	info = checkRevocation(context.Background(), leaf, pki.ca, []byte("not an OCSP response"))
+	if !info.OCSPStapled || info.StapledStatus != "invalid" {
+		t.Errorf("invalid stapled response = %+v", info)
+	}
+}
+
+func TestCheckRevocationCRL(t *testing.T) {
+	pki := newRevocationPKI(t)
+	revokedAt := time.Now().Add(-2 * time.Hour).Truncate(time.Second).UTC()
+	pki.revoked = []x509.RevocationListEntry{{SerialNumber: big.NewInt(667), RevocationTime: revokedAt, ReasonCode: ocsp.Superseded}}
+
+	// The OCSP responder fails (ocspReply is nil), so the CRL is used
+	info := checkRevocation(context.Background(), pki.leaf(t, 667, "/ocsp", "/crl"), pki.ca, nil)
+	if info.Method != "crl" || info.Status != "revoked" || info.RevokedAt == nil || !info.RevokedAt.Equal(revokedAt) || info.RevocationReason != "superseded" || info.Responder != pki.server.URL+"/crl" {
+		t.Errorf("revoked certificate = %+v", info)
+	}
+	info = checkRevocation(context.Background(), pki.leaf(t, 102, "", "/crl"), pki.ca, nil)
+	if info.Method != "crl" || info.Status != "good" || info.Error != "" {
+		t.Errorf("good certificate = %+v", info)
+	}
+
+	otherKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pki.crlSigner = otherKey
+	info = checkRevocation(context.Background(), pki.leaf(t, 103, "", "/crl"), pki.ca, nil)
+	if info.Status != "unknown" || !strings.Contains(info.Error, "not signed by the issuer") {
+		t.Errorf("CRL signed by another key = %+v", info)
+	}
+}
+
+func TestCheckRevocationFetchesIssuer(t *testing.T) {
+	pki := newRevocationPKI(t)
+	pki.ocspReply = func(*ocsp.Request) (int, *ocsp.Response) { return ocsp.Good, nil }
+
+	info := checkRevocation(context.Background(), pki.leaf(t, 104, "/ocsp", ""), nil, nil)
+	if info.Status != "good" || pki.requests[0] != "GET /issuer.cer" {
+		t.Errorf("revocation = %+v after requests %v, want the issuer downloaded from the AIA URL", info, pki.requests)
+	}
+
+	info = checkRevocation(context.Background(), pki.leaf(t, 105, "", ""), pki.ca, nil)
+	if info.Status != "unknown" || info.Error != "certificate has no OCSP responder or CRL distribution point" {
+		t.Errorf("no revocation endpoints = %+v", info)
+	}
+}
+
+// The revocation URLs come from the certificate the target presents, so they must not reach
+// internal addresses.
+func TestCheckRevocationRefusesInternalAddresses(t *testing.T) {
+	pki := newRevocationPKI(t)
+	pki.ocspReply = func(*ocsp.Request) (int, *ocsp.Response) { return ocsp.Good, nil }
+	defer utils.ConfigureSSRFProtection(false, "")
+	utils.ConfigureSSRFProtection(true, "")
+
+	for _, issuer := range []*x509.Certificate{pki.ca, nil} {
+		info := checkRevocation(context.Background(), pki.leaf(t, 106, "/ocsp", "/crl"), issuer, nil)
+		if info.Status != "unknown" || info.Error == "" {
+			t.Errorf("issuer %v: revocation = %+v, want the loopback responder refused", issuer != nil, info)
+		}
+	}
+	if len(pki.requests) != 0 {
+		t.Errorf("requests reached the loopback server: %v", pki.requests)
+	}
+
+	var blocked *utils.SSRFBlockedError
+	if err := queryOCSP(context.Background(), pki.leaf(t, 107, "/ocsp", ""), pki.ca, &RevocationInfo{}); !errors.As(err, &blocked) {
+		t.Errorf("queryOCSP() error = %v, want an SSRF refusal", err)
+	}
+}
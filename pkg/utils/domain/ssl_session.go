@@ -0,0 +1,204 @@
+package domain
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/binary"
+	"net"
+	"slices"
+	"sync"
+	"time"
+)
+
+// alpnProtocols are the application protocols offered when probing ALPN. h3 runs over QUIC,
+// so it cannot be negotiated on a TCP connection.
+var alpnProtocols = []string{"h2", "http/1.1"}
+
+// ticketWait is how long a probe reads after the handshake for TLS 1.3 session tickets,
+// which servers send once the handshake is done.
+const ticketWait = 500 * time.Millisecond
+
+// SessionInfo reports the session and handshake features of a server, probed on separate
+// connections from the certificate check.
+type SessionInfo struct {
+	ALPNSelected        string   `json:"alpn_selected,omitempty"`        // Chosen when h2 and http/1.1 were both offered
+	ALPNSupported       []string `json:"alpn_supported"`                 // Protocols accepted when offered on their own
+	OCSPStapled         bool     `json:"ocsp_stapled"`                   // The server sent an OCSP response in the handshake
+	SecureRenegotiation *bool    `json:"secure_renegotiation,omitempty"` // RFC 5746 support; TLS 1.3 has no renegotiation
+	SessionIDIssued     bool     `json:"session_id_issued"`              // A TLS 1.2 server assigned a session ID for resumption
+	TicketIssued        bool     `json:"ticket_issued"`                  // The server sent a session ticket (or TLS 1.3 PSK)
+	Resumed             bool     `json:"resumed"`                        // A second connection resumed the first one's session
+	Error               string   `json:"error,omitempty"`
+}
+
+// recordingConn keeps the first bytes read from the server, which hold its ServerHello.
+type recordingConn struct {
+	net.Conn
+	received []byte
+}
+
+func (c *recordingConn) Read(p []byte) (int, error) {
+	n, err := c.Conn.Read(p)
+	if room := 16<<10 - len(c.received); room > 0 {
+		c.received = append(c.received, p[:min(n, room)]...)
+	}
+	return n, err
+}
+
+// recordingSessionCache notes whether the server handed out anything to resume with.
+type recordingSessionCache struct {
+	tls.ClientSessionCache
+	mu  sync.Mutex
+	put bool
+}
+
+func (c *recordingSessionCache) Put(key string, state *tls.ClientSessionState) {
+	if state != nil {
+		c.mu.Lock()
+		c.put = true
+		c.mu.Unlock()
+	}
+	c.ClientSessionCache.Put(key, state)
+}
+
+func (c *recordingSessionCache) issued() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.put
+}
+
+// probeSession connects twice with a shared session cache to test resumption, and once per
+// ALPN protocol to list the ones the server accepts.
+func probeSession(ctx context.Context, domain string, targetPort int, ip net.IP) *SessionInfo {
+	info := &SessionInfo{ALPNSupported: []string{}}
+	cache := &recordingSessionCache{ClientSessionCache: tls.NewLRUClientSessionCache(1)}
+	config := &tls.Config{
+		ServerName:         domain,
+		InsecureSkipVerify: true, // Only the session features are of interest
+		MinVersion:         tls.VersionTLS10,
+		CipherSuites:       legacyCipherSuites(),
+		NextProtos:         alpnProtocols,
+		ClientSessionCache: cache,
+	}
+
+	state, serverHello, err := probeHandshake(ctx, domain, targetPort, ip, config, true)
+	if err != nil {
+		info.Error = err.Error()
+		return info
+	}
+	info.ALPNSelected = state.NegotiatedProtocol
+	info.OCSPStapled = len(state.OCSPResponse) > 0
+	if state.Version < tls.VersionTLS13 {
+		if sessionID, extensions, ok := parseServerHello(serverHello); ok {
+			renegotiation := slices.Contains(extensions, extensionRenegotiationInfo)
+			info.SecureRenegotiation = &renegotiation
+			info.SessionIDIssued = len(sessionID) > 0
+		}
+	}
+	info.TicketIssued = cache.issued()
+
+	var wg sync.WaitGroup
+	wg.Add(1 + len(alpnProtocols))
+	go func() {
+		defer wg.Done()
+		if info.TicketIssued || info.SessionIDIssued {
+			if resumed, _, err := probeHandshake(ctx, domain, targetPort, ip, config, false); err == nil {
+				info.Resumed = resumed.DidResume
+			}
+		}
+	}()
+	accepted := make([]bool, len(alpnProtocols))
+	for i, protocol := range alpnProtocols {
+		go func() {
+			defer wg.Done()
+			alpnConfig := config.Clone()
+			alpnConfig.NextProtos = []string{protocol}
+			alpnConfig.ClientSessionCache = nil
+			if state, _, err := probeHandshake(ctx, domain, targetPort, ip, alpnConfig, false); err == nil {
+				accepted[i] = state.NegotiatedProtocol == protocol
+			}
+		}()
+	}
+	wg.Wait()
+	for i, protocol := range alpnProtocols {
+		if accepted[i] {
+			info.ALPNSupported = append(info.ALPNSupported, protocol)
+		}
+	}
+	return info
+}
+
+// probeHandshake completes one handshake and returns its state and the raw bytes the server
+// sent first. With waitForTickets it then reads briefly so TLS 1.3 tickets are processed.
+func probeHandshake(ctx context.Context, domain string, targetPort int, ip net.IP, config *tls.Config, waitForTickets bool) (tls.ConnectionState, []byte, error) {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+	rawConn, err := dialSSL(ctx, domain, targetPort, ip)
+	if err != nil {
+		return tls.ConnectionState{}, nil, err
+	}
+	recorder := &recordingConn{Conn: rawConn}
+	conn := tls.Client(recorder, config)
+	defer conn.Close()
+	if err := conn.HandshakeContext(ctx); err != nil {
+		return tls.ConnectionState{}, nil, err
+	}
+	serverHello := recorder.received
+	if waitForTickets && conn.ConnectionState().Version == tls.VersionTLS13 {
+		conn.SetReadDeadline(time.Now().Add(ticketWait))
+		conn.Read(make([]byte, 1))
+	}
+	return conn.ConnectionState(), serverHello, nil
+}
+
+// extensionRenegotiationInfo is the RFC 5746 renegotiation_info extension.
+const extensionRenegotiationInfo uint16 = 0xff01
+
+// parseServerHello reads the session ID and extension types from the first handshake record
+// a server sent. crypto/tls does not expose either.
+func parseServerHello(data []byte) (sessionID []byte, extensions []uint16, ok bool) {
+	// Record header: content type 22 (handshake), version, length
+	if len(data) < 5 || data[0] != 22 {
+		return nil, nil, false
+	}
+	data = data[5:]
+	// Handshake header: type 2 (server_hello), 24-bit length
+	if len(data) < 4 || data[0] != 2 {
+		return nil, nil, false
+	}
+	length := int(data[1])<<16 | int(data[2])<<8 | int(data[3])
+	data = data[4:]
+	if len(data) < length {
+		return nil, nil, false
+	}
+	data = data[:length]
+	// Version and random
+	if len(data) < 35 {
+		return nil, nil, false
+	}
+	data = data[34:]
+	idLength := int(data[0])
+	if len(data) < 1+idLength+3 {
+		return nil, nil, false
+	}
+	sessionID = data[1 : 1+idLength]
+	data = data[1+idLength+3:] // Cipher suite and compression method
+	if len(data) < 2 {
+		return sessionID, nil, true // No extensions
+	}
+	extensionsLength := int(binary.BigEndian.Uint16(data))
+	data = data[2:]
+	if len(data) < extensionsLength {
+		return nil, nil, false
+	}
+	data = data[:extensionsLength]
+	for len(data) >= 4 {
+		extensionLength := int(binary.BigEndian.Uint16(data[2:]))
+		if len(data) < 4+extensionLength {
+			return nil, nil, false
+		}
+		extensions = append(extensions, binary.BigEndian.Uint16(data))
+		data = data[4+extensionLength:]
+	}
+	return sessionID, extensions, true
+}
@@ -0,0 +1,72 @@
+package domain
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strconv"
+	"testing"
+)
+
+func TestProbeSession(t *testing.T) {
+	for _, maxVersion := range []uint16{tls.VersionTLS12, tls.VersionTLS13} {
+		t.Run(getTLSVersion(maxVersion), func(t *testing.T) {
+			server := httptest.NewUnstartedServer(http.NotFoundHandler())
+			server.EnableHTTP2 = true
+			server.TLS = &tls.Config{MaxVersion: maxVersion, NextProtos: []string{"h2", "http/1.1"}}
+			server.StartTLS()
+			defer server.Close()
+			_, portStr, _ := net.SplitHostPort(server.Listener.Addr().String())
+			port, _ := strconv.Atoi(portStr)
+
+			info := probeSession(context.Background(), "example.com", port, net.ParseIP("127.0.0.1"))
+			if info.Error != "" {
+				t.Fatal(info.Error)
+			}
+			if info.ALPNSelected != "h2" || !slices.Equal(info.ALPNSupported, []string{"h2", "http/1.1"}) {
+				t.Errorf("ALPN selected %q, supported %v; want h2 of h2 and http/1.1", info.ALPNSelected, info.ALPNSupported)
+			}
+			if !info.TicketIssued || !info.Resumed {
+				t.Errorf("ticket issued %t, resumed %t; want both", info.TicketIssued, info.Resumed)
+			}
+			if info.OCSPStapled {
+				t.Error("OCSPStapled without a stapled response")
+			}
+			if maxVersion == tls.VersionTLS13 && info.SecureRenegotiation != nil {
+				t.Errorf("SecureRenegotiation = %v for TLS 1.3, want it left out", *info.SecureRenegotiation)
+			}
+			if maxVersion == tls.VersionTLS12 && (info.SecureRenegotiation == nil || !*info.SecureRenegotiation) {
+				t.Error("SecureRenegotiation not reported for a TLS 1.2 server supporting it")
+			}
+		})
+	}
+}
+
+func TestParseServerHello(t *testing.T) {
+	hello := []byte{
+		2, 0, 0, 0, // Handshake header, length filled in below
+		3, 3, // Version
+	}
+	hello = append(hello, make([]byte, 32)...) // Random
+	hello = append(hello, 2, 0xab, 0xcd)       // Session ID
+	hello = append(hello, 0xc0, 0x2f, 0)       // Cipher suite, compression
+	hello = append(hello, 0, 9)                // Extensions length
+	hello = append(hello, 0xff, 0x01, 0, 1, 0) // renegotiation_info
+	hello = append(hello, 0x00, 0x17, 0, 0)    // extended_master_secret
+	hello[3] = byte(len(hello) - 4)
+	record := append([]byte{22, 3, 3, 0, byte(len(hello))}, hello...)
+
+	sessionID, extensions, ok := parseServerHello(record)
+	if !ok || len(sessionID) != 2 || !slices.Equal(extensions, []uint16{0xff01, 0x0017}) {
+		t.Errorf("parseServerHello = %x, %x, %t", sessionID, extensions, ok)
+	}
+	if _, _, ok := parseServerHello(record[:20]); ok {
+		t.Error("parseServerHello accepted a truncated record")
+	}
+	if _, _, ok := parseServerHello([]byte{21, 3, 3, 0, 2, 2, 40}); ok {
+		t.Error("parseServerHello accepted an alert")
+	}
+}
@@ -0,0 +1,123 @@
+package domain
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"net"
+	"testing"
+	"time"
+)
+
+// issueTestCertificate signs a certificate named commonName with parent's key, or with its
+// own key when parent is nil.
+func issueTestCertificate(t *testing.T, commonName string, isCA bool, parent *x509.Certificate, parentKey *ecdsa.PrivateKey) (*x509.Certificate, *ecdsa.PrivateKey) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(time.Now().UnixNano()),
+		Subject:               pkix.Name{CommonName: commonName},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(24 * time.Hour),
+		DNSNames:              []string{"example.com"},
+		BasicConstraintsValid: true,
+		IsCA:                  isCA,
+	}
+	if parent == nil {
+		parent, parentKey = template, key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, parent, &key.PublicKey, parentKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cert, key
+}
+
+func TestIsSelfSigned(t *testing.T) {
+	root, rootKey := issueTestCertificate(t, "Private Root", true, nil, nil)
+	leaf, _ := issueTestCertificate(t, "example.com", false, root, rootKey)
+	sameName, _ := issueTestCertificate(t, "Private Root", false, root, rootKey)
+	selfSignedLeaf, _ := issueTestCertificate(t, "example.com", false, nil, nil)
+
+	for _, tt := range []struct {
+		name string
+		cert *x509.Certificate
+		want bool
+	}{
+		{"root", root, true},
+		{"issued leaf", leaf, false},
+		{"issued with the issuer's name", sameName, false},
+		{"self-signed leaf", selfSignedLeaf, true},
+	} {
+		if got := isSelfSigned(tt.cert); got != tt.want {
+			t.Errorf("isSelfSigned(%s) = %t, want %t", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestClassifyTrustIssue(t *testing.T) {
+	root, rootKey := issueTestCertificate(t, "Private Root", true, nil, nil)
+	intermediate, intermediateKey := issueTestCertificate(t, "Private Intermediate", true, root, rootKey)
+	leaf, _ := issueTestCertificate(t, "example.com", false, intermediate, intermediateKey)
+	selfSignedLeaf, _ := issueTestCertificate(t, "example.com", false, nil, nil)
+
+	for _, tt := range []struct {
+		name      string
+		chain     []*x509.Certificate
+		wantIssue string
+		wantRoot  string
+	}{
+		{"self-signed leaf", []*x509.Certificate{selfSignedLeaf}, TrustIssueSelfSignedLeaf, "CN=example.com"},
+		{"private root served", []*x509.Certificate{leaf, intermediate, root}, TrustIssueUntrustedRoot, "CN=Private Root"},
+		{"root not served", []*x509.Certificate{leaf, intermediate}, TrustIssueUnknownIssuer, "CN=Private Root"},
+		{"intermediate missing", []*x509.Certificate{leaf}, TrustIssueUnknownIssuer, "CN=Private Intermediate"},
+	} {
+		_, err := verifyChain(tt.chain, "example.com")
+		if err == nil {
+			t.Fatalf("%s: chain verified against the system roots", tt.name)
+		}
+		issue, root := classifyTrustIssue(tt.chain, err)
+		if issue != tt.wantIssue || root != tt.wantRoot {
+			t.Errorf("%s: classifyTrustIssue = %q, %q, want %q, %q", tt.name, issue, root, tt.wantIssue, tt.wantRoot)
+		}
+	}
+}
+
+func TestCheckHostname(t *testing.T) {
+	cert := &x509.Certificate{
+		Subject:     pkix.Name{CommonName: "legacy.example.com"},
+		DNSNames:    []string{"example.com", "*.example.com", "*.*.example.net"},
+		IPAddresses: []net.IP{net.ParseIP("192.0.2.1")},
+	}
+	for _, tt := range []struct {
+		host        string
+		wantMatch   bool
+		wantMatched string
+	}{
+		{"example.com", true, "example.com"},
+		{"WWW.example.com", true, "*.example.com"},
+		{"a.b.example.com", false, ""}, // A wildcard covers one label
+		{"a.b.example.net", false, ""}, // Multi-level wildcards are invalid
+		{"legacy.example.com", true, "*.example.com"},
+		{"legacy.example.org", false, ""}, // The common name is ignored
+		{"192.0.2.1", true, "192.0.2.1"},
+	} {
+		check := checkHostname(cert, tt.host)
+		if check.Matches != tt.wantMatch || check.MatchedName != tt.wantMatched {
+			t.Errorf("checkHostname(%s) = %+v, want match %t via %q", tt.host, check, tt.wantMatch, tt.wantMatched)
+		}
+		if !check.Matches && check.Error == "" {
+			t.Errorf("checkHostname(%s) has no error", tt.host)
+		}
+	}
+}
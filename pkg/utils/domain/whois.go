@@ -4,28 +4,39 @@ package domain
 import (
 	"bufio"
 	"context"
+	"errors"
 	"fmt"
+	"io"
+	"log"
 	"net"
 	"regexp"
 	"strings"
+	"sync/atomic"
 	"time"
+
+	"github.com/vit0-9/utils_api/pkg/utils"
 )
 
 type WhoisInfo struct {
-	Domain          string    `json:"domain"`
-	Registrar       string    `json:"registrar"`
-	CreationDate    time.Time `json:"creation_date"`
-	ExpirationDate  time.Time `json:"expiration_date"`
-	UpdatedDate     time.Time `json:"updated_date"`
-	NameServers     []string  `json:"name_servers"`
-	Status          []string  `json:"status"`
-	RegistrantOrg   string    `json:"registrant_org,omitempty"`
-	RegistrantEmail string    `json:"registrant_email,omitempty"`
-	AdminEmail      string    `json:"admin_email,omitempty"`
-	TechEmail       string    `json:"tech_email,omitempty"`
-	RawData         string    `json:"raw_data,omitempty"`
-	WhoisServer     string    `json:"whois_server"`
-	QueryTime       time.Time `json:"query_time"`
+	Domain         string    `json:"domain"`
+	Registrar      string    `json:"registrar"`
+	CreationDate   time.Time `json:"creation_date"`
+	ExpirationDate time.Time `json:"expiration_date"`
+	UpdatedDate    time.Time `json:"updated_date"`
+	// The date values as the server wrote them, by field, also when they could not be parsed
+	RawDates        map[string]string `json:"raw_dates,omitempty"`
+	NameServers     []string          `json:"name_servers"`
+	Status          []string          `json:"status"`
+	RegistrantOrg   string            `json:"registrant_org,omitempty"`
+	RegistrantEmail string            `json:"registrant_email,omitempty"`
+	AdminEmail      string            `json:"admin_email,omitempty"`
+	TechEmail       string            `json:"tech_email,omitempty"`
+	RawData         string            `json:"raw_data,omitempty"`
+	WhoisServer     string            `json:"whois_server"`
+	// Set when a thin registry referred the lookup to the registrar's WHOIS server
+	RegistrarWhoisServer string    `json:"registrar_whois_server,omitempty"`
+	ReferralError        string    `json:"referral_error,omitempty"`
+	QueryTime            time.Time `json:"query_time"`
 }
 
 type WhoisError struct {
@@ -38,17 +49,44 @@ func (e *WhoisError) Error() string {
 	return fmt.Sprintf("whois lookup failed for %s via %s: %v", e.Domain, e.Server, e.Err)
 }
 
-// WhoisServers defines fallback servers for different TLDs
-var WhoisServers = map[string][]string{
-	"com":     {"whois.verisign-grs.com", "whois.markmonitor.com"},
-	"net":     {"whois.verisign-grs.com"},
-	"org":     {"whois.pir.org"},
-	"info":    {"whois.afilias.net"},
-	"biz":     {"whois.neulevel.biz"},
-	"default": {"whois.iana.org", "whois.internic.net"},
+func (e *WhoisError) Unwrap() error {
+	return e.Err
+}
+
+// whoisPort is the port WHOIS queries go to; tests point it at a fake server.
+var whoisPort = "43"
+
+const (
+	// whoisQueryTimeout bounds a whole query, from sending it to the end of the response.
+	whoisQueryTimeout = 15 * time.Second
+	// defaultWhoisMaxResponseSize is far above any genuine record.
+	defaultWhoisMaxResponseSize = 1 << 20
+)
+
+// ErrWhoisResponseTooLarge is returned (wrapped) for responses over the size limit.
+var ErrWhoisResponseTooLarge = errors.New("WHOIS response too large")
+
+// whoisMaxResponseSize caps the bytes read from a WHOIS server.
+var whoisMaxResponseSize atomic.Int64
+
+func init() {
+	whoisMaxResponseSize.Store(defaultWhoisMaxResponseSize)
+}
+
+// ConfigureWhoisMaxResponseSize sets the largest WHOIS response read, in KiB; larger
+// responses fail the lookup. Zero keeps the default of 1 MiB.
+func ConfigureWhoisMaxResponseSize(kib int) {
+	if kib <= 0 {
+		return
+	}
+	whoisMaxResponseSize.Store(int64(kib) << 10)
+	log.Printf("WHOIS responses limited to %d KiB", kib)
 }
 
-// GetWhoisInfo performs WHOIS lookup with fallback servers
+// referralHostRegex matches the host names accepted from a registry's referral.
+var referralHostRegex = regexp.MustCompile(`^[a-z0-9]([a-z0-9-]*[a-z0-9])?(\.[a-z0-9]([a-z0-9-]*[a-z0-9])?)+$`)
+
+// GetWhoisInfo performs a WHOIS lookup against the TLD's server, discovered from IANA
 func GetWhoisInfo(ctx context.Context, domain string) (*WhoisInfo, error) {
 	domain = strings.ToLower(strings.TrimSpace(domain))
 	if domain == "" {
@@ -62,65 +100,188 @@ func GetWhoisInfo(ctx context.Context, domain string) (*WhoisInfo, error) {
 	}
 	tld := parts[len(parts)-1]
 
-	// Get servers for this TLD
-	servers := WhoisServers[tld]
-	if len(servers) == 0 {
-		servers = WhoisServers["default"]
+	if err := utils.CheckOutboundName(domain); err != nil {
+		return nil, err
 	}
 
-	var lastErr error
-	for _, server := range servers {
-		result, err := queryWhoisServer(ctx, domain, server)
-		if err != nil {
-			lastErr = &WhoisError{Domain: domain, Err: err, Server: server}
-			continue
-		}
-		return result, nil
+	server, err := whoisServerForTLD(ctx, tld)
+	if err != nil {
+		return nil, err
 	}
-
-	return nil, lastErr
+	result, err := queryWhoisServer(ctx, domain, server)
+	if err != nil {
+		return nil, &WhoisError{Domain: domain, Err: err, Server: server}
+	}
+	return followReferral(ctx, domain, result), nil
 }
 
-// queryWhoisServer performs the actual WHOIS query
+// queryWhoisServer queries a WHOIS server for a domain and parses the response
 func queryWhoisServer(ctx context.Context, domain, server string) (*WhoisInfo, error) {
-	conn, err := net.DialTimeout("tcp", server+":43", 10*time.Second)
+	rawData, err := queryWhoisRaw(ctx, whoisQuery(domain), server)
 	if err != nil {
-		return nil, fmt.Errorf("connection failed: %w", err)
+		return nil, err
+	}
+
+	// Parse the response
+	whoisInfo := parseWhoisResponse(domain, rawData, server)
+	whoisInfo.QueryTime = time.Now()
+
+	return whoisInfo, nil
+}
+
+// readWhoisRaw sends a query to a WHOIS server and returns the raw response
+func readWhoisRaw(ctx context.Context, query, server string) (string, error) {
+	if utils.OfflineMode() {
+		return utils.OfflineWhois(query)
+	}
+	if err := whoisThrottle.Wait(ctx, server); err != nil {
+		return "", err
+	}
+	dialStart := time.Now()
+	conn, err := utils.PolicyDialContext(&net.Dialer{Timeout: 10 * time.Second})(ctx, "tcp", net.JoinHostPort(server, whoisPort))
+	utils.TimingRecorderFrom(ctx).AddConnect(time.Since(dialStart))
+	if err != nil {
+		return "", fmt.Errorf("connection failed: %w", err)
 	}
 	defer conn.Close()
 
-	// Set deadline for the entire operation
-	conn.SetDeadline(time.Now().Add(15 * time.Second))
+	// Set deadline for the entire operation, and abort it when the caller gives up
+	deadline := time.Now().Add(whoisQueryTimeout)
+	if ctxDeadline, ok := ctx.Deadline(); ok && ctxDeadline.Before(deadline) {
+		deadline = ctxDeadline
+	}
+	conn.SetDeadline(deadline)
+	stop := context.AfterFunc(ctx, func() { conn.SetDeadline(time.Unix(1, 0)) })
+	defer stop()
 
 	// Send query
-	query := domain + "\r\n"
-	if _, err := conn.Write([]byte(query)); err != nil {
-		return nil, fmt.Errorf("write failed: %w", err)
+	if _, err := conn.Write([]byte(query + "\r\n")); err != nil {
+		return "", fmt.Errorf("write failed: %w", err)
 	}
 
-	// Read response
+	// Read response, up to the size limit; a single line may be as long as the limit
+	limit := whoisMaxResponseSize.Load()
+	limited := &io.LimitedReader{R: conn, N: limit + 1}
 	var response strings.Builder
-	scanner := bufio.NewScanner(conn)
+	scanner := bufio.NewScanner(limited)
+	scanner.Buffer(make([]byte, 0, 64<<10), int(limit)+1)
 	for scanner.Scan() {
 		response.WriteString(scanner.Text() + "\n")
 	}
 
+	if limited.N <= 0 {
+		return "", fmt.Errorf("response exceeds %d bytes: %w", limit, ErrWhoisResponseTooLarge)
+	}
 	if err := scanner.Err(); err != nil {
-		return nil, fmt.Errorf("read failed: %w", err)
+		utils.TimingRecorderFrom(ctx).AddFailure(err)
+		if ctx.Err() != nil {
+			return "", fmt.Errorf("read failed: %w", ctx.Err())
+		}
+		return "", fmt.Errorf("read failed: %w", err)
 	}
 
 	rawData := response.String()
 	if rawData == "" {
-		return nil, fmt.Errorf("empty response from server")
+		return "", fmt.Errorf("empty response from server")
 	}
+	return rawData, nil
+}
 
-	// Parse the response
-	whoisInfo := parseWhoisResponse(domain, rawData, server)
-	whoisInfo.QueryTime = time.Now()
+// followReferral re-queries the registrar's WHOIS server named by a thin registry (such as
+// Verisign for .com and .net), whose own response lacks registrant details, and merges the
+// registrar's answer in. Only one referral is followed. A failed referral keeps the registry
+// data and records the error.
+func followReferral(ctx context.Context, domain string, registry *WhoisInfo) *WhoisInfo {
+	server := referralHost(registry.RegistrarWhoisServer)
+	registry.RegistrarWhoisServer = server
+	if server == "" || strings.EqualFold(server, registry.WhoisServer) {
+		return registry
+	}
+	registrar, err := queryWhoisServer(ctx, domain, server)
+	if err != nil {
+		registry.ReferralError = (&WhoisError{Domain: domain, Err: err, Server: server}).Error()
+		return registry
+	}
+	mergeWhois(registry, registrar)
+	return registry
+}
 
-	return whoisInfo, nil
+// referralHost extracts the host from a referral, which registries write as a bare host,
+// "whois://host" or "host:port". Web URLs are not WHOIS servers and are ignored.
+func referralHost(referral string) string {
+	host := strings.ToLower(strings.TrimSpace(referral))
+	host = strings.TrimPrefix(strings.TrimPrefix(host, "whois://"), "rwhois://")
+	if strings.Contains(host, "://") {
+		return ""
+	}
+	host = strings.TrimSuffix(host, "/")
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	if net.ParseIP(host) == nil && !referralHostRegex.MatchString(host) {
+		return ""
+	}
+	return host
 }
 
+// mergeWhois folds the registrar's answer into the registry's. The registry stays
+// authoritative for dates, status and name servers; the registrar supplies the contacts and
+// fills in anything the registry left out.
+func mergeWhois(registry, registrar *WhoisInfo) {
+	fill := func(field *string, value string) {
+		if *field == "" {
+			*field = value
+		}
+	}
+	override := func(field *string, value string) {
+		if value != "" {
+			*field = value
+		}
+	}
+	fill(&registry.Registrar, registrar.Registrar)
+	fillDate := func(field string, date *time.Time, value time.Time) {
+		if !date.IsZero() {
+			return
+		}
+		*date = value
+		if raw, ok := registrar.RawDates[field]; ok {
+			if registry.RawDates == nil {
+				registry.RawDates = make(map[string]string)
+			}
+			registry.RawDates[field] = raw
+		}
+	}
+	fillDate(dateFieldCreation, &registry.CreationDate, registrar.CreationDate)
+	fillDate(dateFieldExpiration, &registry.ExpirationDate, registrar.ExpirationDate)
+	fillDate(dateFieldUpdated, &registry.UpdatedDate, registrar.UpdatedDate)
+	if len(registry.NameServers) == 0 {
+		registry.NameServers = registrar.NameServers
+	}
+	if len(registry.Status) == 0 {
+		registry.Status = registrar.Status
+	}
+	override(&registry.RegistrantOrg, registrar.RegistrantOrg)
+	override(&registry.RegistrantEmail, registrar.RegistrantEmail)
+	override(&registry.AdminEmail, registrar.AdminEmail)
+	override(&registry.TechEmail, registrar.TechEmail)
+	registry.RawData += "\n# Referral to " + registrar.WhoisServer + "\n" + registrar.RawData
+}
+
+// Common patterns for different WHOIS formats, matched against each line of a response
+var (
+	whoisRegistrarRegex       = regexp.MustCompile(`(?i)registrar:\s*(.+)`)
+	whoisReferralRegex        = regexp.MustCompile(`(?i)^(registrar whois server|whois server|referralserver):\s*(.+)`)
+	whoisCreationDateRegex    = regexp.MustCompile(`(?i)(creation date|created|registered):\s*(.+)`)
+	whoisExpirationDateRegex  = regexp.MustCompile(`(?i)(expir|expires)[^:]*:\s*(.+)`)
+	whoisUpdatedDateRegex     = regexp.MustCompile(`(?i)(updated|last updated|modified)[^:]*:\s*(.+)`)
+	whoisNameServerRegex      = regexp.MustCompile(`(?i)name server:\s*(.+)`)
+	whoisStatusRegex          = regexp.MustCompile(`(?i)(domain )?status:\s*(.+)`)
+	whoisRegistrantOrgRegex   = regexp.MustCompile(`(?i)registrant.*organization:\s*(.+)`)
+	whoisRegistrantEmailRegex = regexp.MustCompile(`(?i)registrant.*email:\s*(.+)`)
+	whoisAdminEmailRegex      = regexp.MustCompile(`(?i)admin.*email:\s*(.+)`)
+	whoisTechEmailRegex       = regexp.MustCompile(`(?i)tech.*email:\s*(.+)`)
+)
+
 // parseWhoisResponse extracts structured data from raw WHOIS response
 func parseWhoisResponse(domain, rawData, server string) *WhoisInfo {
 	info := &WhoisInfo{
@@ -131,20 +292,6 @@ func parseWhoisResponse(domain, rawData, server string) *WhoisInfo {
 
 	lines := strings.Split(rawData, "\n")
 
-	// Common patterns for different WHOIS formats
-	patterns := map[string]*regexp.Regexp{
-		"registrar":        regexp.MustCompile(`(?i)registrar:\s*(.+)`),
-		"creation_date":    regexp.MustCompile(`(?i)(creation date|created|registered):\s*(.+)`),
-		"expiration_date":  regexp.MustCompile(`(?i)(expir|expires).*:\s*(.+)`),
-		"updated_date":     regexp.MustCompile(`(?i)(updated|last updated|modified).*:\s*(.+)`),
-		"name_server":      regexp.MustCompile(`(?i)name server:\s*(.+)`),
-		"status":           regexp.MustCompile(`(?i)(domain )?status:\s*(.+)`),
-		"registrant_org":   regexp.MustCompile(`(?i)registrant.*organization:\s*(.+)`),
-		"registrant_email": regexp.MustCompile(`(?i)registrant.*email:\s*(.+)`),
-		"admin_email":      regexp.MustCompile(`(?i)admin.*email:\s*(.+)`),
-		"tech_email":       regexp.MustCompile(`(?i)tech.*email:\s*(.+)`),
-	}
-
 	for _, line := range lines {
 		line = strings.TrimSpace(line)
 		if line == "" || strings.HasPrefix(line, "%") || strings.HasPrefix(line, "#") {
@@ -152,55 +299,58 @@ func parseWhoisResponse(domain, rawData, server string) *WhoisInfo {
 		}
 
 		// Parse different fields
-		if match := patterns["registrar"].FindStringSubmatch(line); len(match) > 1 {
+		if match := whoisRegistrarRegex.FindStringSubmatch(line); len(match) > 1 {
 			info.Registrar = strings.TrimSpace(match[1])
 		}
 
-		if match := patterns["creation_date"].FindStringSubmatch(line); len(match) > 2 {
-			if date := parseDate(match[2]); !date.IsZero() {
-				info.CreationDate = date
-			}
+		if match := whoisReferralRegex.FindStringSubmatch(line); len(match) > 2 && info.RegistrarWhoisServer == "" {
+			info.RegistrarWhoisServer = strings.TrimSpace(match[2])
 		}
 
-		if match := patterns["expiration_date"].FindStringSubmatch(line); len(match) > 2 {
-			if date := parseDate(match[2]); !date.IsZero() {
-				info.ExpirationDate = date
-			}
+		if match := whoisCreationDateRegex.FindStringSubmatch(line); len(match) > 2 {
+			info.setDate(dateFieldCreation, match[2])
 		}
 
-		if match := patterns["updated_date"].FindStringSubmatch(line); len(match) > 2 {
-			if date := parseDate(match[2]); !date.IsZero() {
-				info.UpdatedDate = date
-			}
+		if match := whoisExpirationDateRegex.FindStringSubmatch(line); len(match) > 2 {
+			info.setDate(dateFieldExpiration, match[2])
+		}
+
+		if match := whoisUpdatedDateRegex.FindStringSubmatch(line); len(match) > 2 {
+			info.setDate(dateFieldUpdated, match[2])
 		}
 
-		if match := patterns["name_server"].FindStringSubmatch(line); len(match) > 1 {
+		if match := whoisNameServerRegex.FindStringSubmatch(line); len(match) > 1 {
 			ns := strings.ToLower(strings.TrimSpace(match[1]))
 			info.NameServers = append(info.NameServers, ns)
 		}
 
-		if match := patterns["status"].FindStringSubmatch(line); len(match) > 2 {
+		if match := whoisStatusRegex.FindStringSubmatch(line); len(match) > 2 {
 			status := strings.TrimSpace(match[2])
 			info.Status = append(info.Status, status)
 		}
 
-		if match := patterns["registrant_org"].FindStringSubmatch(line); len(match) > 1 {
+		if match := whoisRegistrantOrgRegex.FindStringSubmatch(line); len(match) > 1 {
 			info.RegistrantOrg = strings.TrimSpace(match[1])
 		}
 
-		if match := patterns["registrant_email"].FindStringSubmatch(line); len(match) > 1 {
+		if match := whoisRegistrantEmailRegex.FindStringSubmatch(line); len(match) > 1 {
 			info.RegistrantEmail = strings.TrimSpace(match[1])
 		}
 
-		if match := patterns["admin_email"].FindStringSubmatch(line); len(match) > 1 {
+		if match := whoisAdminEmailRegex.FindStringSubmatch(line); len(match) > 1 {
 			info.AdminEmail = strings.TrimSpace(match[1])
 		}
 
-		if match := patterns["tech_email"].FindStringSubmatch(line); len(match) > 1 {
+		if match := whoisTechEmailRegex.FindStringSubmatch(line); len(match) > 1 {
 			info.TechEmail = strings.TrimSpace(match[1])
 		}
 	}
 
+	// Registries with their own format override what the generic patterns found
+	if profile, ok := profileForDomain(domain); ok {
+		profile.parse(info, rawData)
+	}
+
 	// Remove duplicates from slices
 	info.NameServers = removeDuplicates(info.NameServers)
 	info.Status = removeDuplicates(info.Status)
@@ -208,31 +358,6 @@ func parseWhoisResponse(domain, rawData, server string) *WhoisInfo {
 	return info
 }
 
-// parseDate attempts to parse various date formats found in WHOIS data
-func parseDate(dateStr string) time.Time {
-	dateStr = strings.TrimSpace(dateStr)
-
-	// Common WHOIS date formats
-	formats := []string{
-		"2006-01-02T15:04:05Z07:00", // RFC3339
-		"2006-01-02T15:04:05Z",      // RFC3339 UTC
-		"2006-01-02 15:04:05",       // MySQL datetime
-		"2006-01-02",                // Date only
-		"02-Jan-2006",               // Some registrars
-		"January 02 2006",           // Some registrars
-		"2-Jan-2006",                // Some registrars
-		"2006/01/02",                // Some registrars
-	}
-
-	for _, format := range formats {
-		if date, err := time.Parse(format, dateStr); err == nil {
-			return date
-		}
-	}
-
-	return time.Time{}
-}
-
 // removeDuplicates removes duplicate strings from slice
 func removeDuplicates(slice []string) []string {
 	seen := make(map[string]bool)
@@ -246,4 +371,4 @@ func removeDuplicates(slice []string) []string {
 	}
 
 	return result
-}
\ No newline at end of file
+}
@@ -0,0 +1,105 @@
+package domain
+
+import (
+	"context"
+	"log"
+	"math"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/vit0-9/utils_api/pkg/utils"
+)
+
+const (
+	// MaxBulkWhoisDomains caps the number of domains in one bulk WHOIS request.
+	MaxBulkWhoisDomains = 100
+
+	// bulkWhoisWorkers bounds how many WHOIS queries a bulk request runs at once.
+	bulkWhoisWorkers = 8
+
+	// defaultWhoisServerRateLimit is the number of queries per minute sent to one WHOIS
+	// server. Registries ban clients that query faster than a few per second.
+	defaultWhoisServerRateLimit = 60
+)
+
+// whoisThrottle spaces out queries to each WHOIS server, across all requests.
+var whoisThrottle = utils.NewHostThrottle("whois", defaultWhoisServerRateLimit)
+
+// ConfigureWhoisRateLimit sets the per-WHOIS-server query rate. Zero keeps the default.
+func ConfigureWhoisRateLimit(queriesPerMinute int) {
+	if queriesPerMinute <= 0 {
+		return
+	}
+	whoisThrottle = utils.NewHostThrottle("whois", queriesPerMinute)
+	log.Printf("WHOIS queries limited to %d per server per minute", queriesPerMinute)
+}
+
+// WhoisExpiry is the expiry information of one domain in a bulk lookup.
+type WhoisExpiry struct {
+	Domain          string     `json:"domain"`
+	Registrar       string     `json:"registrar,omitempty"`
+	ExpirationDate  *time.Time `json:"expiration_date,omitempty"`
+	DaysUntilExpiry *int       `json:"days_until_expiry,omitempty"` // Negative once the domain has expired
+	WhoisServer     string     `json:"whois_server,omitempty"`
+	Error           string     `json:"error,omitempty"`
+	ErrorCode       string     `json:"error_code,omitempty"`          // "throttled" when the WHOIS server refused the query for now
+	RetryAfter      int        `json:"retry_after_seconds,omitempty"` // Set for throttled lookups
+}
+
+// GetBulkWhoisExpiry looks up many domains with a bounded worker pool. Queries to each WHOIS
+// server are rate limited, so large batches against one registry take a while rather than
+// getting the service banned. Results are returned in the same order as the input.
+func GetBulkWhoisExpiry(ctx context.Context, domains []string) []WhoisExpiry {
+	results := make([]WhoisExpiry, len(domains))
+	workers := bulkWhoisWorkers
+	if len(domains) < workers {
+		workers = len(domains)
+	}
+
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				results[i] = GetWhoisExpiry(ctx, strings.TrimSpace(domains[i]))
+			}
+		}()
+	}
+	for i := range domains {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+
+	return results
+}
+
+// GetWhoisExpiry looks up the expiry information of one domain.
+func GetWhoisExpiry(ctx context.Context, domain string) WhoisExpiry {
+	info, err := GetWhoisInfo(ctx, domain)
+	if err != nil {
+		result := WhoisExpiry{Domain: domain, Error: err.Error()}
+		if code, retryAfter := WhoisErrorCode(err); code != "" {
+			result.ErrorCode, result.RetryAfter = code, int(math.Ceil(retryAfter.Seconds()))
+		}
+		return result
+	}
+	return expiryFromWhois(info, time.Now())
+}
+
+// expiryFromWhois extracts the expiry fields from a WHOIS result.
+func expiryFromWhois(info *WhoisInfo, now time.Time) WhoisExpiry {
+	result := WhoisExpiry{Domain: info.Domain, Registrar: info.Registrar, WhoisServer: info.WhoisServer}
+	if info.ExpirationDate.IsZero() {
+		result.Error = "the WHOIS response has no expiration date"
+		return result
+	}
+	expiration := info.ExpirationDate
+	days := int(math.Floor(expiration.Sub(now).Hours() / 24))
+	result.ExpirationDate = &expiration
+	result.DaysUntilExpiry = &days
+	return result
+}
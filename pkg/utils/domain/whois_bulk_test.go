@@ -0,0 +1,61 @@
+package domain
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/vit0-9/utils_api/pkg/utils"
+)
+
+func TestExpiryFromWhois(t *testing.T) {
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name       string
+		expiration time.Time
+		wantDays   int
+		wantError  bool
+	}{
+		{"in a month", time.Date(2026, 3, 31, 12, 0, 0, 0, time.UTC), 30, false},
+		{"later today", time.Date(2026, 3, 1, 18, 0, 0, 0, time.UTC), 0, false},
+		{"expired yesterday", time.Date(2026, 2, 28, 18, 0, 0, 0, time.UTC), -1, false},
+		{"no expiration date", time.Time{}, 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := expiryFromWhois(&WhoisInfo{Domain: "example.com", ExpirationDate: tt.expiration}, now)
+			if tt.wantError {
+				if result.Error == "" || result.DaysUntilExpiry != nil {
+					t.Errorf("expiryFromWhois() = %+v, want an error and no days", result)
+				}
+				return
+			}
+			if result.DaysUntilExpiry == nil || *result.DaysUntilExpiry != tt.wantDays {
+				t.Errorf("expiryFromWhois() days = %v, want %d", result.DaysUntilExpiry, tt.wantDays)
+			}
+		})
+	}
+}
+
+func TestGetBulkWhoisExpiryKeepsOrder(t *testing.T) {
+	results := GetBulkWhoisExpiry(context.Background(), []string{" nodot ", "", "x"})
+	for i, want := range []string{"nodot", "", "x"} {
+		if results[i].Domain != want || results[i].Error == "" {
+			t.Errorf("results[%d] = %+v, want an error for %q", i, results[i], want)
+		}
+	}
+}
+
+func TestWhoisThrottleRejectsQueriesPastTheDeadline(t *testing.T) {
+	defer func(throttle *utils.HostThrottle) { whoisThrottle = throttle }(whoisThrottle)
+	whoisThrottle = utils.NewHostThrottle("whois", 1)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := whoisThrottle.Wait(ctx, "whois.example"); err != nil {
+		t.Fatalf("first Wait() error = %v, want the burst slot", err)
+	}
+	if _, err := queryWhoisServer(ctx, "example.com", "whois.example"); err == nil {
+		t.Error("queryWhoisServer() error = nil, want the server's rate limit to be exceeded")
+	}
+}
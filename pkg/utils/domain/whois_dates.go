@@ -0,0 +1,196 @@
+package domain
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Date fields of a WHOIS response, as keyed in WhoisInfo.RawDates.
+const (
+	dateFieldCreation   = "creation_date"
+	dateFieldExpiration = "expiration_date"
+	dateFieldUpdated    = "updated_date"
+)
+
+// whoisDateFormat is how a registry writes dates: the layouts it uses, tried before the
+// generic ones, and the time zone of dates that carry none.
+type whoisDateFormat struct {
+	layouts  []string
+	location *time.Location // nil is UTC
+}
+
+// whoisDateFormats are selected by TLD. Add a registry here when its dates come back
+// unparsed in raw_dates.
+var whoisDateFormats = map[string]whoisDateFormat{
+	"br": {layouts: []string{"20060102"}},
+	"cz": {layouts: []string{"02.01.2006 15:04:05", "02.01.2006"}},
+	"fi": {layouts: []string{"2.1.2006 15:04:05", "2.1.2006"}},
+	"jp": {layouts: []string{"2006/01/02 15:04:05", "2006/01/02"}, location: jst},
+	"kr": {layouts: []string{"2006. 01. 02."}, location: time.FixedZone("KST", 9*60*60)},
+	"pl": {layouts: []string{"2006.01.02 15:04:05"}},
+	"tw": {layouts: []string{"2006-01-02 15:04:05"}, location: time.FixedZone("CST", 8*60*60)},
+	"uk": {layouts: []string{"02-Jan-2006"}},
+}
+
+// whoisDateLayouts are the generic layouts, most specific first.
+var whoisDateLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02T15:04:05Z0700",
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05Z07:00",
+	"2006-01-02 15:04:05Z0700",
+	"2006-01-02 15:04:05.999999999",
+	"2006-01-02 15:04:05",
+	"2006-01-02",
+	"2006.01.02 15:04:05",
+	"2006.01.02",
+	"2006/01/02 15:04:05",
+	"2006/01/02",
+	"02.01.2006 15:04:05",
+	"02.01.2006",
+	"02-Jan-2006 15:04:05",
+	"02-Jan-2006",
+	"2-Jan-2006",
+	"Jan-2006", // "before Aug-1996"
+	"02 Jan 2006",
+	"January 02 2006",
+	"January 2 2006",
+	"Jan 02 2006",
+	"Jan 2 2006",
+	"Mon Jan 2 15:04:05 2006",
+	"Mon Jan _2 15:04:05 2006",
+	"20060102",
+}
+
+// whoisZoneOffsets are the zone abbreviations registries append to dates, in hours.
+var whoisZoneOffsets = map[string]int{
+	"UTC": 0, "GMT": 0, "Z": 0,
+	"CET": 1, "CEST": 2, "MSK": 3, "CST": 8, "JST": 9, "KST": 9,
+	"EST": -5, "EDT": -4, "PST": -8, "PDT": -7,
+}
+
+var (
+	// whoisZoneSuffixRegex matches a trailing zone abbreviation, optionally in parentheses.
+	whoisZoneSuffixRegex = regexp.MustCompile(`\s*\(?\b([A-Z]{1,4})\)?$`)
+	// whoisOffsetSuffixRegex matches a trailing "(UTC+8)" or "UTC+08:00" offset.
+	whoisOffsetSuffixRegex = regexp.MustCompile(`\s*\(?(?:UTC|GMT)([+-])(\d{1,2})(?::?(\d{2}))?\)?$`)
+	whoisSpaceRegex        = regexp.MustCompile(`\s+`)
+	whoisDigitsRegex       = regexp.MustCompile(`^\d+$`)
+)
+
+// Plausible WHOIS dates; anything outside is a misparse.
+var (
+	earliestWhoisDate = time.Date(1980, 1, 1, 0, 0, 0, 0, time.UTC)
+	latestWhoisDate   = time.Date(2200, 1, 1, 0, 0, 0, 0, time.UTC)
+)
+
+// parseWhoisDate parses a date as written by the registry of tld. It understands the
+// registry's own layouts, the generic ones, Unix timestamps in seconds or milliseconds,
+// trailing zone abbreviations and offsets, and a "before" qualifier (which yields the date
+// the qualifier is relative to). It reports false when nothing fits.
+func parseWhoisDate(tld, value string) (time.Time, bool) {
+	value = whoisSpaceRegex.ReplaceAllString(strings.TrimSpace(value), " ")
+	if lower := strings.ToLower(value); strings.HasPrefix(lower, "before ") {
+		value = strings.TrimSpace(value[len("before "):])
+	}
+	if value == "" || len(value) > 64 {
+		return time.Time{}, false
+	}
+
+	format := whoisDateFormats[strings.ToLower(tld)]
+	location := format.location
+	if location == nil {
+		location = time.UTC
+	}
+	if match := whoisOffsetSuffixRegex.FindStringSubmatch(value); match != nil {
+		hours, _ := strconv.Atoi(match[2])
+		minutes, _ := strconv.Atoi(match[3])
+		offset := hours*3600 + minutes*60
+		if match[1] == "-" {
+			offset = -offset
+		}
+		location = time.FixedZone(strings.TrimSpace(strings.Trim(match[0], " ()")), offset)
+		value = strings.TrimSpace(value[:len(value)-len(match[0])])
+	} else if match := whoisZoneSuffixRegex.FindStringSubmatch(value); match != nil && len(value) > len(match[0]) {
+		if hours, ok := whoisZoneOffsets[match[1]]; ok && (match[1] != "Z" || strings.HasSuffix(value, " Z")) {
+			location = time.FixedZone(match[1], hours*60*60)
+			value = strings.TrimSpace(value[:len(value)-len(match[0])])
+		}
+	}
+	value = strings.TrimSuffix(value, ",")
+
+	if whoisDigitsRegex.MatchString(value) {
+		if date, ok := parseWhoisTimestamp(value); ok {
+			return date, true
+		}
+	}
+	for _, layouts := range [][]string{format.layouts, whoisDateLayouts} {
+		for _, layout := range layouts {
+			date, err := time.ParseInLocation(layout, value, location)
+			if err == nil && plausibleWhoisDate(date) {
+				return date, true
+			}
+		}
+	}
+	return time.Time{}, false
+}
+
+// parseWhoisTimestamp parses Unix timestamps in seconds (10 digits) or milliseconds (13).
+func parseWhoisTimestamp(value string) (time.Time, bool) {
+	number, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return time.Time{}, false
+	}
+	var date time.Time
+	switch len(value) {
+	case 9, 10:
+		date = time.Unix(number, 0).UTC()
+	case 12, 13:
+		date = time.UnixMilli(number).UTC()
+	default:
+		return time.Time{}, false
+	}
+	return date, plausibleWhoisDate(date)
+}
+
+func plausibleWhoisDate(date time.Time) bool {
+	return !date.Before(earliestWhoisDate) && date.Before(latestWhoisDate)
+}
+
+// parseDate parses a date in the generic WHOIS layouts, returning the zero time when none
+// fits.
+func parseDate(dateStr string) time.Time {
+	date, _ := parseWhoisDate("", dateStr)
+	return date
+}
+
+// setDate records the raw value of a date field and, when it parses, the date. An
+// unparsed value never replaces a date parsed from an earlier line.
+func (info *WhoisInfo) setDate(field, raw string) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return
+	}
+	var target *time.Time
+	switch field {
+	case dateFieldCreation:
+		target = &info.CreationDate
+	case dateFieldExpiration:
+		target = &info.ExpirationDate
+	case dateFieldUpdated:
+		target = &info.UpdatedDate
+	default:
+		return
+	}
+	date, ok := parseWhoisDate(info.Domain[strings.LastIndex(info.Domain, ".")+1:], raw)
+	if !ok && !target.IsZero() {
+		return
+	}
+	if info.RawDates == nil {
+		info.RawDates = make(map[string]string)
+	}
+	info.RawDates[field] = raw
+	*target = date
+}
@@ -0,0 +1,78 @@
+package domain
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseWhoisDate(t *testing.T) {
+	jst := time.FixedZone("JST", 9*60*60)
+	tests := []struct {
+		tld, value string
+		want       time.Time
+	}{
+		{"com", "2024-05-01T12:00:00Z", time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)},
+		{"com", "2024-05-01T12:00:00.123Z", time.Date(2024, 5, 1, 12, 0, 0, 123000000, time.UTC)},
+		{"com", "2024-05-01T14:00:00+0200", time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)},
+		{"ru", "2024-05-01T12:00:00", time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)},
+		{"pl", "2024.05.01 12:00:00", time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)},
+		{"org", "2024.05.01", time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)},
+		{"uk", "01-May-2024", time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)},
+		{"info", "01-May-2024 12:00:00 UTC", time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)},
+		{"net", "2024-05-01 21:00:00 (JST)", time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)},
+		{"tw", "2024-05-01 20:00:00 (UTC+8)", time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)},
+		{"tw", "2024-05-01 20:00:00", time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)},
+		{"jp", "2024/05/01", time.Date(2024, 5, 1, 0, 0, 0, 0, jst)},
+		{"br", "20240501", time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)},
+		{"cz", "01.05.2024 12:00:00", time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)},
+		{"kr", "2024. 05. 01.", time.Date(2024, 4, 30, 15, 0, 0, 0, time.UTC)},
+		{"com", "1714564800", time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)},
+		{"com", "1714564800000", time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)},
+		{"uk", "before Aug-1996", time.Date(1996, 8, 1, 0, 0, 0, 0, time.UTC)},
+		{"com", "May 1 2024", time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)},
+	}
+	for _, tc := range tests {
+		got, ok := parseWhoisDate(tc.tld, tc.value)
+		if !ok || !got.Equal(tc.want) {
+			t.Errorf("parseWhoisDate(%s, %q) = %s, %v; want %s", tc.tld, tc.value, got, ok, tc.want)
+		}
+	}
+
+	for _, value := range []string{"", "not a date", "0", "1999999999999999", "9999-12-31", "12345", "2024-13-45"} {
+		if got, ok := parseWhoisDate("com", value); ok {
+			t.Errorf("parseWhoisDate(%q) = %s, want no date", value, got)
+		}
+	}
+}
+
+func TestParseWhoisResponseKeepsRawDates(t *testing.T) {
+	raw := "Domain Name: EXAMPLE.COM\n" +
+		"Registry Expiry Date: 2025-08-13T04:00:00Z\n" +
+		"Creation Date: sometime in 1995\n" +
+		"Updated Date: 2024-08-14T07:01:34Z\n"
+	info := parseWhoisResponse("example.com", raw, "whois.example")
+	if want := time.Date(2025, 8, 13, 4, 0, 0, 0, time.UTC); !info.ExpirationDate.Equal(want) {
+		t.Errorf("ExpirationDate = %s, want %s", info.ExpirationDate, want)
+	}
+	if !info.CreationDate.IsZero() || info.RawDates[dateFieldCreation] != "sometime in 1995" {
+		t.Errorf("CreationDate = %s with raw %q, want no date and the raw value", info.CreationDate, info.RawDates[dateFieldCreation])
+	}
+	if info.RawDates[dateFieldExpiration] != "2025-08-13T04:00:00Z" {
+		t.Errorf("RawDates = %v", info.RawDates)
+	}
+}
+
+func FuzzParseWhoisDate(f *testing.F) {
+	for _, seed := range []string{"2024-05-01T12:00:00Z", "01-May-2024 12:00:00 UTC", "before Aug-1996", "1714564800", "2024. 05. 01.", "(UTC+8)", " Z", "before "} {
+		f.Add("com", seed)
+	}
+	f.Fuzz(func(t *testing.T, tld, value string) {
+		date, ok := parseWhoisDate(tld, value)
+		if ok && !plausibleWhoisDate(date) {
+			t.Errorf("parseWhoisDate(%q, %q) = %s, outside the plausible range", tld, value, date)
+		}
+		if !ok && !date.IsZero() {
+			t.Errorf("parseWhoisDate(%q, %q) = %s without success", tld, value, date)
+		}
+	})
+}
@@ -0,0 +1,172 @@
+package domain
+
+import (
+	"regexp"
+	"strings"
+	"time"
+)
+
+// whoisProfile adapts the query and the parsing to a registry whose response format the
+// generic "key: value" patterns of parseWhoisResponse miss.
+type whoisProfile struct {
+	query func(domain string) string            // nil sends the domain as is
+	parse func(info *WhoisInfo, rawData string) // Runs after the generic patterns and overrides them
+}
+
+// whoisProfiles are selected by TLD.
+var whoisProfiles = map[string]whoisProfile{
+	"uk": {parse: parseNominet},
+	"de": {query: func(domain string) string { return "-T dn,ace " + domain }, parse: parseDENIC},
+	"jp": {query: func(domain string) string { return domain + "/e" }, parse: parseJPRS}, // /e asks for English labels
+}
+
+// profileForDomain returns the parser profile for a domain's TLD.
+func profileForDomain(domain string) (whoisProfile, bool) {
+	tld := domain[strings.LastIndex(domain, ".")+1:]
+	profile, ok := whoisProfiles[strings.ToLower(tld)]
+	return profile, ok
+}
+
+// whoisQuery returns the query string to send for a domain.
+func whoisQuery(domain string) string {
+	if profile, ok := profileForDomain(domain); ok && profile.query != nil {
+		return profile.query(domain)
+	}
+	return domain
+}
+
+// whoisSections splits a block-structured response, such as Nominet's, into its sections:
+// a line ending with ":" starts a section whose values are the lines up to the next blank
+// line. Section names are lowercased.
+func whoisSections(rawData string) map[string][]string {
+	sections := make(map[string][]string)
+	current := ""
+	for _, line := range strings.Split(rawData, "\n") {
+		line = strings.TrimSpace(line)
+		switch {
+		case line == "":
+			current = ""
+		case strings.HasSuffix(line, ":"):
+			current = strings.ToLower(strings.TrimSuffix(line, ":"))
+		case current != "":
+			sections[current] = append(sections[current], line)
+		}
+	}
+	return sections
+}
+
+// nominetTagRegex matches the registrar tag Nominet appends to the registrar name.
+var nominetTagRegex = regexp.MustCompile(`\s*\[Tag = [^\]]*\]$`)
+
+// parseNominet parses the .uk format, where each field is a heading followed by indented
+// values:
+//
+//	Registrar:
+//	    Example Ltd [Tag = EXAMPLE]
+//	Relevant dates:
+//	    Registered on: 26-Aug-1996
+func parseNominet(info *WhoisInfo, rawData string) {
+	sections := whoisSections(rawData)
+	if values := sections["registrar"]; len(values) > 0 {
+		info.Registrar = nominetTagRegex.ReplaceAllString(values[0], "")
+	}
+	if values := sections["registrant"]; len(values) > 0 {
+		info.RegistrantOrg = values[0]
+	}
+	for _, line := range sections["relevant dates"] {
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		switch strings.ToLower(strings.TrimSpace(key)) {
+		case "registered on":
+			info.setDate(dateFieldCreation, value)
+		case "expiry date":
+			info.setDate(dateFieldExpiration, value)
+		case "last updated":
+			info.setDate(dateFieldUpdated, value)
+		}
+	}
+	if values := sections["name servers"]; len(values) > 0 {
+		info.NameServers = nil
+		for _, value := range values {
+			info.NameServers = append(info.NameServers, strings.ToLower(strings.Fields(value)[0]))
+		}
+	}
+	if values := sections["registration status"]; len(values) > 0 {
+		info.Status = values
+	}
+}
+
+// parseDENIC parses the .de format. DENIC publishes no creation or expiry dates, only when
+// the domain last changed.
+func parseDENIC(info *WhoisInfo, rawData string) {
+	var nameServers []string
+	for _, line := range strings.Split(rawData, "\n") {
+		key, value, ok := strings.Cut(strings.TrimSpace(line), ":")
+		if !ok {
+			continue
+		}
+		value = strings.TrimSpace(value)
+		switch strings.ToLower(key) {
+		case "nserver":
+			if fields := strings.Fields(value); len(fields) > 0 {
+				nameServers = append(nameServers, strings.ToLower(fields[0]))
+			}
+		case "changed":
+			info.setDate(dateFieldUpdated, value)
+		}
+	}
+	if len(nameServers) > 0 {
+		info.NameServers = removeDuplicates(nameServers)
+	}
+}
+
+var (
+	// jprsFieldRegex matches "[Field]  value" lines, optionally prefixed with a letter as in
+	// the .co.jp format ("a. [Domain Name]").
+	jprsFieldRegex = regexp.MustCompile(`^(?:[a-z]\.\s*)?\[([^\]]+)\]\s*(.*)$`)
+	// jprsStateDateRegex extracts the expiry from a .co.jp state such as "Connected (2025/01/31)".
+	jprsStateDateRegex = regexp.MustCompile(`\((\d{4}/\d{2}/\d{2})\)`)
+	jst                = time.FixedZone("JST", 9*60*60)
+)
+
+// parseJPRS parses the .jp format in English ("[Created on]  2001/01/01"), with dates in
+// Japan time.
+func parseJPRS(info *WhoisInfo, rawData string) {
+	var nameServers []string
+	for _, line := range strings.Split(rawData, "\n") {
+		match := jprsFieldRegex.FindStringSubmatch(strings.TrimSpace(line))
+		if match == nil {
+			continue
+		}
+		value := strings.TrimSpace(match[2])
+		switch strings.ToLower(match[1]) {
+		case "name server":
+			if value != "" {
+				nameServers = append(nameServers, strings.ToLower(strings.Fields(value)[0]))
+			}
+		case "created on", "registered date":
+			info.setDate(dateFieldCreation, value)
+		case "expires on":
+			info.setDate(dateFieldExpiration, value)
+		case "last updated", "last update":
+			info.setDate(dateFieldUpdated, value)
+		case "registrant", "organization":
+			if value != "" && info.RegistrantOrg == "" {
+				info.RegistrantOrg = value
+			}
+		case "status", "state":
+			if value != "" {
+				info.Status = append(info.Status, value)
+			}
+			if dateMatch := jprsStateDateRegex.FindStringSubmatch(value); dateMatch != nil && info.ExpirationDate.IsZero() {
+				info.setDate(dateFieldExpiration, dateMatch[1])
+			}
+		}
+	}
+	if len(nameServers) > 0 {
+		info.NameServers = removeDuplicates(nameServers)
+	}
+	info.Status = removeDuplicates(info.Status)
+}
@@ -0,0 +1,111 @@
+package domain
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+const nominetResponse = `
+    Domain name:
+        example.co.uk
+
+    Data validation:
+        Nominet was able to match the registrant's name and address against a 3rd party data source on 10-Dec-2012
+
+    Registrar:
+        Example Registrar Ltd [Tag = EXAMPLE]
+        URL: https://registrar.example
+
+    Relevant dates:
+        Registered on: 26-Aug-1996
+        Expiry date:  26-Aug-2030
+        Last updated:  20-Aug-2024
+
+    Registration status:
+        Registered until expiry date.
+
+    Name servers:
+        NS1.EXAMPLE.NET           192.0.2.1
+        ns2.example.net
+
+    WHOIS lookup made at 10:00:00 01-Jan-2026
+`
+
+const denicResponse = `Domain: example.de
+Nserver: ns1.example.net
+Nserver: ns2.example.net 192.0.2.2
+Status: connect
+Changed: 2024-03-05T10:11:12+01:00
+`
+
+const jprsResponse = `[ JPRS database provides information on network administration. ]
+
+Domain Information:
+[Domain Name]                   EXAMPLE.JP
+
+[Registrant]                    Example Co., Ltd.
+
+[Name Server]                   ns1.example.jp
+[Name Server]                   ns2.example.jp
+
+[Created on]                    2001/02/03
+[Expires on]                    2030/02/28
+[Status]                        Active
+[Last Updated]                  2025/03/01 01:05:05 (JST)
+`
+
+func TestParseWhoisProfiles(t *testing.T) {
+	day := func(year int, month time.Month, d int) time.Time {
+		return time.Date(year, month, d, 0, 0, 0, 0, time.UTC)
+	}
+	jst := time.FixedZone("JST", 9*60*60)
+	tests := []struct {
+		name        string
+		domain      string
+		raw         string
+		registrar   string
+		registrant  string
+		created     time.Time
+		expires     time.Time
+		updated     time.Time
+		nameServers []string
+		status      []string
+	}{
+		{"nominet", "example.co.uk", nominetResponse, "Example Registrar Ltd", "", day(1996, 8, 26), day(2030, 8, 26), day(2024, 8, 20),
+			[]string{"ns1.example.net", "ns2.example.net"}, []string{"Registered until expiry date."}},
+		{"denic", "example.de", denicResponse, "", "", time.Time{}, time.Time{}, time.Date(2024, 3, 5, 10, 11, 12, 0, time.FixedZone("", 3600)),
+			[]string{"ns1.example.net", "ns2.example.net"}, []string{"connect"}},
+		{"jprs", "example.jp", jprsResponse, "", "Example Co., Ltd.", time.Date(2001, 2, 3, 0, 0, 0, 0, jst), time.Date(2030, 2, 28, 0, 0, 0, 0, jst), time.Date(2025, 3, 1, 1, 5, 5, 0, jst),
+			[]string{"ns1.example.jp", "ns2.example.jp"}, []string{"Active"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			info := parseWhoisResponse(tt.domain, tt.raw, "whois.test")
+			if info.Registrar != tt.registrar || info.RegistrantOrg != tt.registrant {
+				t.Errorf("registrar, registrant = %q, %q, want %q, %q", info.Registrar, info.RegistrantOrg, tt.registrar, tt.registrant)
+			}
+			if !info.CreationDate.Equal(tt.created) || !info.ExpirationDate.Equal(tt.expires) || !info.UpdatedDate.Equal(tt.updated) {
+				t.Errorf("dates = %v, %v, %v, want %v, %v, %v", info.CreationDate, info.ExpirationDate, info.UpdatedDate, tt.created, tt.expires, tt.updated)
+			}
+			if !reflect.DeepEqual(info.NameServers, tt.nameServers) {
+				t.Errorf("NameServers = %v, want %v", info.NameServers, tt.nameServers)
+			}
+			if !reflect.DeepEqual(info.Status, tt.status) {
+				t.Errorf("Status = %v, want %v", info.Status, tt.status)
+			}
+		})
+	}
+}
+
+func TestWhoisQuery(t *testing.T) {
+	for domain, want := range map[string]string{
+		"example.com": "example.com",
+		"example.de":  "-T dn,ace example.de",
+		"example.jp":  "example.jp/e",
+	} {
+		if got := whoisQuery(domain); got != want {
+			t.Errorf("whoisQuery(%q) = %q, want %q", domain, got, want)
+		}
+	}
+}
@@ -0,0 +1,54 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestReadWhoisRawLimits(t *testing.T) {
+	defer func(port string) { whoisPort = port }(whoisPort)
+	longLine := "Remarks: " + strings.Repeat("x", 200<<10)
+	whoisPort = serveWhois(t, "Domain Name: EXAMPLE.TEST\r\n"+longLine+"\r\n", strings.Repeat("Domain Name: EXAMPLE.TEST\n", 100))
+
+	raw, err := readWhoisRaw(context.Background(), "example.test", "127.0.0.1")
+	if err != nil || !strings.HasSuffix(raw, longLine+"\n") {
+		t.Fatalf("readWhoisRaw() with a 200 KiB line = %d bytes, %v; want it read whole", len(raw), err)
+	}
+
+	defer whoisMaxResponseSize.Store(whoisMaxResponseSize.Load())
+	ConfigureWhoisMaxResponseSize(1)
+	if _, err := readWhoisRaw(context.Background(), "example.test", "127.0.0.1"); !errors.Is(err, ErrWhoisResponseTooLarge) {
+		t.Errorf("readWhoisRaw() over the limit error = %v, want ErrWhoisResponseTooLarge", err)
+	}
+}
+
+func TestReadWhoisRawCancellation(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		conn.Write([]byte("Domain Name: EXAMPLE.TEST\n")) // Then never finishes the response
+		time.Sleep(5 * time.Second)
+	}()
+	defer func(port string) { whoisPort = port }(whoisPort)
+	_, whoisPort, _ = net.SplitHostPort(listener.Addr().String())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(100*time.Millisecond, cancel)
+	start := time.Now()
+	_, err = readWhoisRaw(ctx, "example.test", "127.0.0.1")
+	if !errors.Is(err, context.Canceled) || time.Since(start) > 2*time.Second {
+		t.Errorf("readWhoisRaw() = %v after %s, want it canceled promptly", err, time.Since(start))
+	}
+}
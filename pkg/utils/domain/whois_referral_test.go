@@ -0,0 +1,109 @@
+package domain
+
+import (
+	"bufio"
+	"context"
+	"net"
+	"strings"
+	"testing"
+)
+
+// serveWhois starts a fake WHOIS server on loopback answering each connection with the
+// response for the connection's position, and returns its port.
+func serveWhois(t *testing.T, responses ...string) string {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { listener.Close() })
+	go func() {
+		for i := 0; ; i++ {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			bufio.NewReader(conn).ReadString('\n')
+			if i < len(responses) {
+				conn.Write([]byte(responses[i]))
+			}
+			conn.Close()
+		}
+	}()
+	_, port, _ := net.SplitHostPort(listener.Addr().String())
+	return port
+}
+
+func TestReferralHost(t *testing.T) {
+	tests := map[string]string{
+		"whois.markmonitor.com":         "whois.markmonitor.com",
+		" WHOIS.Example-Registrar.COM ": "whois.example-registrar.com",
+		"whois://whois.example.net":     "whois.example.net",
+		"rwhois.example.net:4321":       "rwhois.example.net",
+		"192.0.2.43":                    "192.0.2.43",
+		"https://www.example.com/whois": "",
+		"localhost":                     "",
+		"":                              "",
+	}
+	for referral, want := range tests {
+		if got := referralHost(referral); got != want {
+			t.Errorf("referralHost(%q) = %q, want %q", referral, got, want)
+		}
+	}
+}
+
+func TestGetWhoisInfoFollowsRegistrarReferral(t *testing.T) {
+	registry := strings.Join([]string{
+		"Domain Name: EXAMPLE.COM",
+		"Registrar WHOIS Server: 127.0.0.1",
+		"Registrar: Example Registrar, Inc.",
+		"Creation Date: 1995-08-14T04:00:00Z",
+		"Registry Expiry Date: 2030-08-13",
+		"Name Server: A.IANA-SERVERS.NET",
+		"",
+	}, "\n")
+	registrar := strings.Join([]string{
+		"Domain Name: example.com",
+		"Registrar WHOIS Server: 127.0.0.1",
+		"Registrar Registration Expiration Date: 2030-08-12",
+		"Registrant Organization: Example Org",
+		"Registrant Email: owner@example.com",
+		"Admin Email: admin@example.com",
+		"",
+	}, "\n")
+
+	defer func(port string) { whoisPort = port }(whoisPort)
+	whoisPort = serveWhois(t, registry, registrar)
+	setWhoisServer(t, "test", "localhost")
+
+	info, err := GetWhoisInfo(context.Background(), "example.test")
+	if err != nil {
+		t.Fatalf("GetWhoisInfo() error = %v", err)
+	}
+	if info.RegistrarWhoisServer != "127.0.0.1" || info.ReferralError != "" {
+		t.Fatalf("referral = %q (error %q), want 127.0.0.1 followed", info.RegistrarWhoisServer, info.ReferralError)
+	}
+	// The registrar's contacts are merged in and the registry's dates kept.
+	if info.RegistrantOrg != "Example Org" || info.AdminEmail != "admin@example.com" {
+		t.Errorf("contacts = %q %q, want the registrar's", info.RegistrantOrg, info.AdminEmail)
+	}
+	if info.ExpirationDate.Day() != 13 || info.Registrar != "Example Registrar, Inc." {
+		t.Errorf("ExpirationDate = %v, Registrar = %q, want the registry's", info.ExpirationDate, info.Registrar)
+	}
+}
+
+func TestMergeWhois(t *testing.T) {
+	registry := parseWhoisResponse("example.com", "Registrar: Registry Side\nCreation Date: 2000-01-02\n", "whois.registry.test")
+	registrar := parseWhoisResponse("example.com", "Registrar: Registrar Side\nCreation Date: 2000-01-03\nRegistrant Organization: Example Org\nTech Email: tech@example.com\n", "whois.registrar.test")
+	mergeWhois(registry, registrar)
+
+	if registry.Registrar != "Registry Side" || registry.CreationDate.Day() != 2 {
+		t.Errorf("registry fields = %q %v, want the registry's kept", registry.Registrar, registry.CreationDate)
+	}
+	if registry.RegistrantOrg != "Example Org" || registry.TechEmail != "tech@example.com" {
+		t.Errorf("contacts = %q %q, want the registrar's", registry.RegistrantOrg, registry.TechEmail)
+	}
+	if !strings.Contains(registry.RawData, "# Referral to whois.registrar.test\nRegistrar: Registrar Side") {
+		t.Errorf("RawData = %q, want both responses", registry.RawData)
+	}
+}
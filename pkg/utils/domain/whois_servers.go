@@ -0,0 +1,114 @@
+package domain
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/vit0-9/utils_api/pkg/utils"
+)
+
+// whoisServerCacheTTL is how long a TLD's WHOIS server discovered from IANA is trusted.
+const whoisServerCacheTTL = 30 * 24 * time.Hour
+
+// ianaWhoisServer is asked for the WHOIS server of each TLD; tests point it at a fake server.
+var ianaWhoisServer = "whois.iana.org"
+
+// ianaWhoisRegex matches the "whois:" line of IANA's TLD records.
+var ianaWhoisRegex = regexp.MustCompile(`(?im)^whois:[ \t]*(\S+)`)
+
+// whoisServerEntry is a TLD's WHOIS server as discovered from IANA.
+type whoisServerEntry struct {
+	Server       string    `json:"server"`
+	DiscoveredAt time.Time `json:"discovered_at"`
+}
+
+// whoisServerCache remembers the WHOIS server of each TLD, saved to a JSON file when a
+// path is configured.
+type whoisServerCache struct {
+	mu      sync.Mutex
+	path    string
+	servers map[string]whoisServerEntry // By TLD
+}
+
+var whoisServers = &whoisServerCache{servers: make(map[string]whoisServerEntry)}
+
+// ConfigureWhoisServerCache loads the TLD to WHOIS server cache from path and saves newly
+// discovered servers back to it. With an empty path servers are rediscovered after a restart.
+func ConfigureWhoisServerCache(path string) {
+	if path == "" {
+		return
+	}
+	whoisServers.mu.Lock()
+	defer whoisServers.mu.Unlock()
+
+	data, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		log.Printf("ERROR: Could not read WHOIS server cache %s: %v. Servers will be cached in memory.", path, err)
+		return
+	}
+	if err == nil {
+		if err := json.Unmarshal(data, &whoisServers.servers); err != nil {
+			log.Printf("ERROR: Could not parse WHOIS server cache %s: %v. Servers will be cached in memory.", path, err)
+			whoisServers.servers = make(map[string]whoisServerEntry)
+			return
+		}
+		if whoisServers.servers == nil { // The file held null
+			whoisServers.servers = make(map[string]whoisServerEntry)
+		}
+	}
+	whoisServers.path = path
+	log.Printf("WHOIS servers cached in %s (%d TLDs)", path, len(whoisServers.servers))
+}
+
+// whoisServerForTLD returns the WHOIS server of a TLD, asking IANA when it is not cached.
+func whoisServerForTLD(ctx context.Context, tld string) (string, error) {
+	if utils.OfflineMode() { // Fixtures answer whichever server is asked
+		return ianaWhoisServer, nil
+	}
+	whoisServers.mu.Lock()
+	entry, ok := whoisServers.servers[tld]
+	whoisServers.mu.Unlock()
+	if ok && time.Since(entry.DiscoveredAt) < whoisServerCacheTTL {
+		return entry.Server, nil
+	}
+
+	raw, err := queryWhoisRaw(ctx, tld, ianaWhoisServer)
+	if err != nil {
+		if ok { // A stale server beats none while IANA is unreachable
+			return entry.Server, nil
+		}
+		return "", fmt.Errorf("could not find the WHOIS server for .%s via %s: %w", tld, ianaWhoisServer, err)
+	}
+	match := ianaWhoisRegex.FindStringSubmatch(raw)
+	if match == nil {
+		return "", fmt.Errorf(".%s has no WHOIS server registered with IANA; try the RDAP lookup", tld)
+	}
+	server := strings.ToLower(match[1])
+
+	whoisServers.mu.Lock()
+	defer whoisServers.mu.Unlock()
+	whoisServers.servers[tld] = whoisServerEntry{Server: server, DiscoveredAt: time.Now().UTC()}
+	whoisServers.saveLocked()
+	return server, nil
+}
+
+func (c *whoisServerCache) saveLocked() {
+	if c.path == "" {
+		return
+	}
+	data, err := json.MarshalIndent(c.servers, "", "  ")
+	if err != nil {
+		log.Printf("ERROR: Could not encode WHOIS server cache: %v", err)
+		return
+	}
+	if err := utils.WriteFileAtomic(c.path, data); err != nil {
+		log.Printf("ERROR: Could not save WHOIS server cache to %s: %v", c.path, err)
+	}
+}
@@ -0,0 +1,57 @@
+package domain
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// setWhoisServer caches the WHOIS server of a TLD for the duration of a test.
+func setWhoisServer(t *testing.T, tld, server string) {
+	t.Helper()
+	whoisServers.mu.Lock()
+	saved, ok := whoisServers.servers[tld]
+	whoisServers.servers[tld] = whoisServerEntry{Server: server, DiscoveredAt: time.Now()}
+	whoisServers.mu.Unlock()
+	t.Cleanup(func() {
+		whoisServers.mu.Lock()
+		defer whoisServers.mu.Unlock()
+		if ok {
+			whoisServers.servers[tld] = saved
+		} else {
+			delete(whoisServers.servers, tld)
+		}
+	})
+}
+
+func TestWhoisServerDiscoveredFromIANA(t *testing.T) {
+	defer func(saved *whoisServerCache) { whoisServers = saved }(whoisServers)
+	whoisServers = &whoisServerCache{servers: make(map[string]whoisServerEntry)}
+	path := filepath.Join(t.TempDir(), "whois-servers.json")
+	ConfigureWhoisServerCache(path)
+
+	iana := "% IANA WHOIS server\n\ndomain:       EXAMPLE\n\nwhois:        WHOIS.NIC.Example\n\nstatus:       ACTIVE\n"
+	noWhois := "domain:       NOWHOIS\n\nwhois:\n\nstatus:       ACTIVE\n"
+	defer func(port string) { whoisPort = port }(whoisPort)
+	whoisPort = serveWhois(t, iana, noWhois)
+	defer func(server string) { ianaWhoisServer = server }(ianaWhoisServer)
+	ianaWhoisServer = "127.0.0.1"
+
+	ctx := context.Background()
+	server, err := whoisServerForTLD(ctx, "example")
+	if err != nil || server != "whois.nic.example" {
+		t.Fatalf("whoisServerForTLD(example) = %q, %v, want whois.nic.example", server, err)
+	}
+	if _, err := whoisServerForTLD(ctx, "nowhois"); err == nil {
+		t.Error("whoisServerForTLD(nowhois) error = nil, want no WHOIS server")
+	}
+
+	// The discovered server is served from the persisted cache without asking IANA again.
+	whoisServers = &whoisServerCache{servers: make(map[string]whoisServerEntry)}
+	ConfigureWhoisServerCache(path)
+	ianaWhoisServer = "192.0.2.1"
+	if server, err := whoisServerForTLD(ctx, "example"); err != nil || server != "whois.nic.example" {
+		t.Errorf("whoisServerForTLD(example) after reload = %q, %v, want the cached server", server, err)
+	}
+}
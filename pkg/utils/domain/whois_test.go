@@ -0,0 +1,33 @@
+package domain
+
+import (
+	"os"
+	"testing"
+)
+
+func BenchmarkParseWhoisResponse(b *testing.B) {
+	raw, err := os.ReadFile("testdata/whois/example.org.txt")
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.ReportAllocs()
+	for b.Loop() {
+		parseWhoisResponse("example.org", string(raw), "whois.test")
+	}
+}
+
+// TestParseWhoisResponseAllocations keeps parsing a response within an allocation budget,
+// like the hot paths in package utils.
+func TestParseWhoisResponseAllocations(t *testing.T) {
+	if testing.Short() {
+		t.Skip("allocation budgets are not checked in short mode")
+	}
+	raw, err := os.ReadFile("testdata/whois/example.org.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	const budget = 60
+	if allocs := testing.AllocsPerRun(20, func() { parseWhoisResponse("example.org", string(raw), "whois.test") }); allocs > budget {
+		t.Errorf("parseWhoisResponse allocates %.0f times per call, over its budget of %d", allocs, budget)
+	}
+}
@@ -0,0 +1,133 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrorCodeThrottled is the error code of lookups refused because a WHOIS server is
+// throttling this client.
+const ErrorCodeThrottled = "throttled"
+
+const (
+	// defaultWhoisCooldown is how long a throttling server is left alone when it does not
+	// say when to come back.
+	defaultWhoisCooldown = 5 * time.Minute
+	minWhoisCooldown     = time.Minute
+	maxWhoisCooldown     = time.Hour
+)
+
+// WhoisThrottledError is returned when a WHOIS server refuses queries because of a rate
+// limit or a block, or is cooling down after having done so.
+type WhoisThrottledError struct {
+	Server     string
+	RetryAfter time.Duration
+	Message    string // The server's refusal
+}
+
+func (e *WhoisThrottledError) Error() string {
+	return fmt.Sprintf("%s is throttling queries (%q); retry in %s", e.Server, e.Message, e.RetryAfter.Round(time.Second))
+}
+
+var (
+	// whoisThrottledRegex matches the refusals registries send instead of a record.
+	whoisThrottledRegex = regexp.MustCompile(`(?i)(exceeded (the |your )?(maximum |allowed |daily |hourly )?(query|queries|request|requests|connection)? ?(limit|quota|rate)|limit (exceeded|reached)|(query|request) rate (limit )?(exceeded|reached)|quota exceeded|too many (queries|requests|connections)|(you|your ip|your address|ip address|access|client|host) (has been |have been |is |are )?(temporarily )?(blocked|denied|banned|blacklisted)|try again later)`)
+	// whoisRecordRegex matches the domain line that a genuine record, which may mention
+	// query limits in its terms of use, always has.
+	whoisRecordRegex = regexp.MustCompile(`(?im)^\s*(domain( name)?|\[domain name\])\s*:`)
+	// whoisRetryInRegex extracts the wait from refusals such as "try again in 10 minutes".
+	whoisRetryInRegex = regexp.MustCompile(`(?i)\b(?:in|after|for)\s+(\d{1,5})\s*(seconds?|secs?|minutes?|mins?|hours?)\b`)
+)
+
+// detectWhoisThrottling returns the error for a raw response that is a throttling refusal
+// rather than a record, or nil.
+func detectWhoisThrottling(server, raw string) *WhoisThrottledError {
+	if whoisRecordRegex.MatchString(raw) {
+		return nil
+	}
+	match := whoisThrottledRegex.FindStringIndex(raw)
+	if match == nil {
+		return nil
+	}
+	message := raw[match[0]:match[1]]
+	// Quote the whole line of the refusal, which usually says more.
+	lineStart := strings.LastIndex(raw[:match[0]], "\n") + 1
+	if lineEnd := strings.Index(raw[match[0]:], "\n"); lineEnd >= 0 {
+		message = strings.TrimSpace(raw[lineStart : match[0]+lineEnd])
+	} else {
+		message = strings.TrimSpace(raw[lineStart:])
+	}
+	if len(message) > 200 {
+		message = message[:200]
+	}
+
+	retryAfter := defaultWhoisCooldown
+	if wait := whoisRetryInRegex.FindStringSubmatch(raw); wait != nil {
+		amount, _ := strconv.Atoi(wait[1])
+		unit := time.Second
+		switch strings.ToLower(wait[2])[0] {
+		case 'm':
+			unit = time.Minute
+		case 'h':
+			unit = time.Hour
+		}
+		retryAfter = min(max(time.Duration(amount)*unit, minWhoisCooldown), maxWhoisCooldown)
+	}
+	return &WhoisThrottledError{Server: server, RetryAfter: retryAfter, Message: message}
+}
+
+// whoisCooldowns holds the servers that throttled this client, until when they are left
+// alone.
+var whoisCooldowns = struct {
+	mu    sync.Mutex
+	until map[string]whoisCooldown
+}{until: make(map[string]whoisCooldown)}
+
+type whoisCooldown struct {
+	until   time.Time
+	message string
+}
+
+// queryWhoisRaw sends a query to a WHOIS server and returns the raw response. A server
+// that answers with a throttling refusal is not queried again until its cooldown passes;
+// both cases return a WhoisThrottledError.
+func queryWhoisRaw(ctx context.Context, query, server string) (string, error) {
+	whoisCooldowns.mu.Lock()
+	cooldown, coolingDown := whoisCooldowns.until[server]
+	if coolingDown && time.Now().After(cooldown.until) {
+		delete(whoisCooldowns.until, server)
+		coolingDown = false
+	}
+	whoisCooldowns.mu.Unlock()
+	if coolingDown {
+		return "", &WhoisThrottledError{Server: server, RetryAfter: time.Until(cooldown.until), Message: cooldown.message}
+	}
+
+	raw, err := readWhoisRaw(ctx, query, server)
+	if err != nil {
+		return "", err
+	}
+	if throttled := detectWhoisThrottling(server, raw); throttled != nil {
+		whoisCooldowns.mu.Lock()
+		whoisCooldowns.until[server] = whoisCooldown{until: time.Now().Add(throttled.RetryAfter), message: throttled.Message}
+		whoisCooldowns.mu.Unlock()
+		return "", throttled
+	}
+	return raw, nil
+}
+
+// WhoisErrorCode returns the error code of a failed WHOIS lookup and, for throttled
+// lookups, when to retry; the code is empty for other errors.
+func WhoisErrorCode(err error) (string, time.Duration) {
+	var throttled *WhoisThrottledError
+	if errors.As(err, &throttled) {
+		return ErrorCodeThrottled, throttled.RetryAfter
+	}
+	return "", 0
+}
@@ -0,0 +1,55 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestDetectWhoisThrottling(t *testing.T) {
+	tests := []struct {
+		raw       string
+		throttled bool
+		wait      time.Duration
+	}{
+		{"%% Maximum query rate reached\nWHOIS LIMIT EXCEEDED - SEE WWW.EXAMPLE/WHOIS FOR DETAILS\n", true, defaultWhoisCooldown},
+		{"Your connection limit exceeded. Please slow down and try again later.\n", true, defaultWhoisCooldown},
+		{"% Query rate limit exceeded. Try again in 90 seconds.\n", true, 90 * time.Second},
+		{"Your IP has been blocked for 3 hours due to excessive querying\n", true, maxWhoisCooldown},
+		{"You have exceeded your query limit; retry after 10 s\n", true, defaultWhoisCooldown},
+		{"Domain Name: EXAMPLE.COM\nTerms: you may be blocked if you exceed the query limit.\n", false, 0},
+		{"No match for \"EXAMPLE.TEST\".\n", false, 0},
+	}
+	for _, tc := range tests {
+		got := detectWhoisThrottling("whois.example", tc.raw)
+		if (got != nil) != tc.throttled {
+			t.Errorf("detectWhoisThrottling(%q) = %v, want throttled %v", tc.raw, got, tc.throttled)
+			continue
+		}
+		if got != nil && got.RetryAfter != tc.wait {
+			t.Errorf("detectWhoisThrottling(%q) RetryAfter = %s, want %s", tc.raw, got.RetryAfter, tc.wait)
+		}
+	}
+}
+
+func TestQueryWhoisRawBacksOffThrottlingServer(t *testing.T) {
+	defer func(port string) { whoisPort = port }(whoisPort)
+	whoisPort = serveWhois(t, "Too many queries from your IP, try again in 2 minutes\n", "Domain Name: EXAMPLE.TEST\n")
+	t.Cleanup(func() {
+		whoisCooldowns.mu.Lock()
+		delete(whoisCooldowns.until, "127.0.0.1")
+		whoisCooldowns.mu.Unlock()
+	})
+
+	_, err := queryWhoisRaw(context.Background(), "example.test", "127.0.0.1")
+	var throttled *WhoisThrottledError
+	if !errors.As(err, &throttled) || throttled.RetryAfter != 2*time.Minute {
+		t.Fatalf("first query error = %v, want a throttled error with a 2 minute wait", err)
+	}
+	// The server is left alone during the cooldown, even though it would answer now.
+	_, err = queryWhoisRaw(context.Background(), "example.test", "127.0.0.1")
+	if code, retryAfter := WhoisErrorCode(&WhoisError{Err: err}); code != ErrorCodeThrottled || retryAfter <= 0 || retryAfter > 2*time.Minute {
+		t.Errorf("query during the cooldown = %v, code %q, retry after %s", err, code, retryAfter)
+	}
+}
@@ -0,0 +1,159 @@
+// Package domainreport builds an aggregate report on a domain from the individual checks
+// (DNS, WHOIS, SSL, email security, DNSSEC and blacklists). A failing check degrades its
+// own section instead of failing the whole report.
+package domainreport
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/vit0-9/utils_api/pkg/utils"
+	"github.com/vit0-9/utils_api/pkg/utils/dnssec"
+	"github.com/vit0-9/utils_api/pkg/utils/domain"
+	"github.com/vit0-9/utils_api/pkg/utils/emailauth"
+)
+
+// Section statuses.
+const (
+	StatusOK     = "ok"
+	StatusFailed = "failed"
+)
+
+// sectionTimeout bounds each check, so one slow server cannot hold up the report.
+const sectionTimeout = 30 * time.Second
+
+// Section is the outcome of one check. A failed section carries the error and, when the
+// check got part way, whatever result it produced; Partial is set for failed sections and
+// for sections where only some lookups succeeded.
+type Section struct {
+	Status     string `json:"status"`
+	Partial    bool   `json:"partial,omitempty"`
+	Error      string `json:"error,omitempty"`
+	DurationMs int64  `json:"duration_ms"`
+	Result     any    `json:"result,omitempty"`
+}
+
+// Report is the aggregate report on a domain.
+type Report struct {
+	Domain       string             `json:"domain"`
+	Sections     map[string]Section `json:"sections"`
+	Completeness float64            `json:"completeness"` // Share of sections that succeeded, from 0 to 1
+	Partial      bool               `json:"partial"`      // At least one section failed or is incomplete
+	QueryTime    time.Time          `json:"query_time"`
+}
+
+// check runs one section, returning its result and whether that result is incomplete. A
+// non-nil error fails the section; a result returned alongside it is kept.
+type check func(ctx context.Context) (any, bool, error)
+
+// Build runs every check concurrently and assembles the report.
+func Build(ctx context.Context, resolver utils.Resolver, domainName string) *Report {
+	domainName = strings.TrimSuffix(strings.ToLower(strings.TrimSpace(domainName)), ".")
+	checks := map[string]check{
+		"dns": func(ctx context.Context) (any, bool, error) {
+			records, lookupErrors := utils.LookupDNSRecordsWithResolver(ctx, resolver, domainName, []string{"A", "AAAA", "MX", "NS", "TXT", "CAA"})
+			result := map[string]any{"records": records}
+			if len(lookupErrors) > 0 {
+				result["errors"] = lookupErrors
+			}
+			if len(records) == 0 && len(lookupErrors) > 0 {
+				return result, true, fmt.Errorf("all %d DNS lookups failed", len(lookupErrors))
+			}
+			return result, len(lookupErrors) > 0, nil
+		},
+		"whois": func(ctx context.Context) (any, bool, error) {
+			info, err := domain.GetWhoisInfo(ctx, domainName)
+			return info, false, err
+		},
+		"ssl": func(ctx context.Context) (any, bool, error) {
+			info, err := domain.GetSSLInfo(ctx, domainName)
+			return info, false, err
+		},
+		"email_security": func(ctx context.Context) (any, bool, error) {
+			return emailauth.Analyze(ctx, resolver, domainName, nil), false, nil
+		},
+		"dnssec": func(ctx context.Context) (any, bool, error) {
+			report := dnssec.Check(ctx, resolver, domainName)
+			if report.Error != "" {
+				return report, true, fmt.Errorf("%s", report.Error)
+			}
+			return report, false, nil
+		},
+		"blacklists": func(ctx context.Context) (any, bool, error) {
+			report, err := utils.CheckBlacklists(ctx, resolver, domainName)
+			return report, false, err
+		},
+	}
+	return run(ctx, domainName, checks)
+}
+
+// run executes the checks and scores the report.
+func run(ctx context.Context, domainName string, checks map[string]check) *Report {
+	report := &Report{Domain: domainName, Sections: make(map[string]Section, len(checks)), QueryTime: time.Now()}
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for name, fn := range checks {
+		wg.Add(1)
+		go func(name string, fn check) {
+			defer wg.Done()
+			section := runSection(ctx, fn)
+			mu.Lock()
+			report.Sections[name] = section
+			mu.Unlock()
+		}(name, fn)
+	}
+	wg.Wait()
+
+	succeeded := 0
+	for _, section := range report.Sections {
+		if section.Status == StatusOK {
+			succeeded++
+		}
+		if section.Partial {
+			report.Partial = true
+		}
+	}
+	if len(report.Sections) > 0 {
+		report.Completeness = math.Round(float64(succeeded)/float64(len(report.Sections))*100) / 100
+	}
+	return report
+}
+
+// runSection runs one check with its own timeout, turning errors and panics into a failed
+// section.
+func runSection(ctx context.Context, fn check) (section Section) {
+	ctx, cancel := context.WithTimeout(ctx, sectionTimeout)
+	defer cancel()
+	start := time.Now()
+	defer func() {
+		if r := recover(); r != nil {
+			section = Section{Status: StatusFailed, Partial: true, Error: fmt.Sprintf("check crashed: %v", r)}
+		}
+		section.DurationMs = time.Since(start).Milliseconds()
+	}()
+
+	result, partial, err := fn(ctx)
+	if err != nil {
+		section = Section{Status: StatusFailed, Partial: true, Error: err.Error()}
+		if !isNil(result) {
+			section.Result = result
+		}
+		return section
+	}
+	return Section{Status: StatusOK, Partial: partial, Result: result}
+}
+
+// isNil reports whether a result is nil or a typed nil pointer, so failed sections do not
+// render "result": null.
+func isNil(result any) bool {
+	if result == nil {
+		return true
+	}
+	value := reflect.ValueOf(result)
+	return value.Kind() == reflect.Pointer && value.IsNil()
+}
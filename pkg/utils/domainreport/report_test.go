@@ -0,0 +1,54 @@
+package domainreport
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestRunDegradesFailedSections(t *testing.T) {
+	var nilResult *struct{}
+	report := run(context.Background(), "example.com", map[string]check{
+		"ok": func(context.Context) (any, bool, error) {
+			return "fine", false, nil
+		},
+		"incomplete": func(context.Context) (any, bool, error) {
+			return "some records", true, nil
+		},
+		"failed": func(context.Context) (any, bool, error) {
+			return nilResult, false, errors.New("connection refused")
+		},
+		"crashed": func(context.Context) (any, bool, error) {
+			panic("boom")
+		},
+	})
+
+	if len(report.Sections) != 4 {
+		t.Fatalf("run() returned %d sections, want 4", len(report.Sections))
+	}
+	if report.Completeness != 0.5 || !report.Partial {
+		t.Errorf("run() completeness = %v, partial = %v; want 0.5 and true", report.Completeness, report.Partial)
+	}
+	if section := report.Sections["ok"]; section.Status != StatusOK || section.Partial || section.Result != "fine" {
+		t.Errorf("ok section = %+v", section)
+	}
+	if section := report.Sections["incomplete"]; section.Status != StatusOK || !section.Partial {
+		t.Errorf("incomplete section = %+v, want ok and partial", section)
+	}
+	if section := report.Sections["failed"]; section.Status != StatusFailed || !section.Partial || section.Error != "connection refused" || section.Result != nil {
+		t.Errorf("failed section = %+v, want failed with the error and no result", section)
+	}
+	if section := report.Sections["crashed"]; section.Status != StatusFailed || section.Error != "check crashed: boom" {
+		t.Errorf("crashed section = %+v, want failed with the panic", section)
+	}
+}
+
+func TestRunComplete(t *testing.T) {
+	report := run(context.Background(), "example.com", map[string]check{
+		"a": func(context.Context) (any, bool, error) { return 1, false, nil },
+		"b": func(context.Context) (any, bool, error) { return 2, false, nil },
+	})
+	if report.Completeness != 1 || report.Partial {
+		t.Errorf("run() completeness = %v, partial = %v; want 1 and false", report.Completeness, report.Partial)
+	}
+}
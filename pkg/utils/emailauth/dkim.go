@@ -0,0 +1,125 @@
+package emailauth
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"fmt"
+	"strings"
+
+	"github.com/vit0-9/utils_api/pkg/utils"
+)
+
+// DKIMResult describes the key published under one DKIM selector.
+type DKIMResult struct {
+	Selector string `json:"selector"`
+	Name     string `json:"name"` // e.g. "google._domainkey.example.com"
+	Found    bool   `json:"found"`
+	Record   string `json:"record,omitempty"`
+	KeyType  string `json:"key_type,omitempty"` // k, defaults to rsa
+	KeyBits  int    `json:"key_bits,omitempty"`
+	Revoked  bool   `json:"revoked"`         // Empty p= tag
+	Testing  bool   `json:"testing"`         // t=y
+	Error    string `json:"error,omitempty"` // Lookup or key parsing error
+}
+
+// checkDKIM fetches and evaluates the DKIM key record for a selector.
+func checkDKIM(ctx context.Context, resolver utils.Resolver, domain, selector string) (DKIMResult, []Finding) {
+	var findings []Finding
+	addFinding := func(status, format string, args ...any) {
+		findings = append(findings, Finding{Check: "dkim", Status: status, Message: fmt.Sprintf(format, args...)})
+	}
+
+	result := DKIMResult{Selector: selector, Name: selector + "._domainkey." + domain}
+	records, err := lookupTXT(ctx, resolver, result.Name)
+	if err != nil {
+		result.Error = err.Error()
+		addFinding(StatusFail, "Could not look up DKIM selector %s: %v", selector, err)
+		return result, findings
+	}
+	for _, record := range records {
+		tags := parseTagList(record)
+		if _, hasKey := tags["p"]; hasKey || strings.EqualFold(tags["v"], "DKIM1") {
+			result.Record = record
+			break
+		}
+	}
+	if result.Record == "" {
+		addFinding(StatusWarn, "No DKIM key found for selector %s", selector)
+		return result, findings
+	}
+	result.Found = true
+
+	tags := parseTagList(result.Record)
+	result.KeyType = strings.ToLower(tags["k"])
+	if result.KeyType == "" {
+		result.KeyType = "rsa"
+	}
+	for _, flag := range strings.Split(tags["t"], ":") {
+		if strings.TrimSpace(flag) == "y" {
+			result.Testing = true
+		}
+	}
+
+	publicKey := strings.Join(strings.Fields(tags["p"]), "")
+	if publicKey == "" {
+		result.Revoked = true
+		addFinding(StatusWarn, "DKIM key for selector %s is revoked (empty p=)", selector)
+		return result, findings
+	}
+	result.KeyBits, err = dkimKeyBits(result.KeyType, publicKey)
+	if err != nil {
+		result.Error = err.Error()
+		addFinding(StatusFail, "DKIM key for selector %s is invalid: %v", selector, err)
+		return result, findings
+	}
+
+	switch {
+	case result.KeyType == "ed25519":
+		addFinding(StatusPass, "DKIM selector %s publishes an Ed25519 key", selector)
+	case result.KeyBits < 1024:
+		addFinding(StatusFail, "DKIM key for selector %s is only %d bits; receivers may ignore it", selector, result.KeyBits)
+	case result.KeyBits < 2048:
+		addFinding(StatusWarn, "DKIM key for selector %s is %d bits; 2048 is recommended", selector, result.KeyBits)
+	default:
+		addFinding(StatusPass, "DKIM selector %s publishes a %d-bit RSA key", selector, result.KeyBits)
+	}
+	if result.Testing {
+		addFinding(StatusWarn, "DKIM selector %s is in testing mode (t=y)", selector)
+	}
+	return result, findings
+}
+
+// dkimKeyBits decodes a p= tag and returns the key size.
+func dkimKeyBits(keyType, encoded string) (int, error) {
+	der, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return 0, fmt.Errorf("p= is not valid base64")
+	}
+	switch keyType {
+	case "ed25519":
+		if len(der) != ed25519.PublicKeySize {
+			return 0, fmt.Errorf("Ed25519 key has %d bytes, expected %d", len(der), ed25519.PublicKeySize)
+		}
+		return 256, nil
+	case "rsa":
+		key, err := x509.ParsePKIXPublicKey(der)
+		if err != nil {
+			// Some publishers use a bare PKCS#1 key instead of SubjectPublicKeyInfo.
+			rsaKey, pkcs1Err := x509.ParsePKCS1PublicKey(der)
+			if pkcs1Err != nil {
+				return 0, fmt.Errorf("could not parse RSA key: %v", err)
+			}
+			return rsaKey.N.BitLen(), nil
+		}
+		rsaKey, ok := key.(*rsa.PublicKey)
+		if !ok {
+			return 0, fmt.Errorf("k=rsa but the key is not an RSA key")
+		}
+		return rsaKey.N.BitLen(), nil
+	default:
+		return 0, fmt.Errorf("unsupported key type %q", keyType)
+	}
+}
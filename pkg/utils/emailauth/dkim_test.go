@@ -0,0 +1,121 @@
+package emailauth
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"testing"
+)
+
+func TestCheckDKIM(t *testing.T) {
+	key1024, err := rsa.GenerateKey(rand.Reader, 1024)
+	if err != nil {
+		t.Fatal(err)
+	}
+	key2048, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	spki := func(key *rsa.PrivateKey) string {
+		der, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return base64.StdEncoding.EncodeToString(der)
+	}
+	pkcs1 := base64.StdEncoding.EncodeToString(x509.MarshalPKCS1PublicKey(&key2048.PublicKey))
+	edKey, _, _ := ed25519.GenerateKey(rand.Reader)
+	ed := base64.StdEncoding.EncodeToString(edKey)
+
+	tests := []struct {
+		name        string
+		record      string
+		want        DKIMResult
+		wantFinding string
+		wantStatus  string
+	}{
+		{
+			name:        "2048-bit RSA",
+			record:      "v=DKIM1; k=rsa; p=" + spki(key2048),
+			want:        DKIMResult{Found: true, KeyType: "rsa", KeyBits: 2048},
+			wantFinding: "DKIM selector sel publishes a 2048-bit RSA key",
+			wantStatus:  StatusPass,
+		},
+		{
+			name:        "1024-bit RSA with default key type",
+			record:      "v=DKIM1; p=" + spki(key1024),
+			want:        DKIMResult{Found: true, KeyType: "rsa", KeyBits: 1024},
+			wantFinding: "DKIM key for selector sel is 1024 bits; 2048 is recommended",
+			wantStatus:  StatusWarn,
+		},
+		{
+			name:        "PKCS#1 key split by whitespace in testing mode",
+			record:      "v=DKIM1; t=y:s; p=" + pkcs1[:100] + " " + pkcs1[100:],
+			want:        DKIMResult{Found: true, KeyType: "rsa", KeyBits: 2048, Testing: true},
+			wantFinding: "DKIM selector sel is in testing mode (t=y)",
+			wantStatus:  StatusWarn,
+		},
+		{
+			name:        "Ed25519",
+			record:      "v=DKIM1; k=ed25519; p=" + ed,
+			want:        DKIMResult{Found: true, KeyType: "ed25519", KeyBits: 256},
+			wantFinding: "DKIM selector sel publishes an Ed25519 key",
+			wantStatus:  StatusPass,
+		},
+		{
+			name:        "revoked",
+			record:      "v=DKIM1; p=",
+			want:        DKIMResult{Found: true, KeyType: "rsa", Revoked: true},
+			wantFinding: "DKIM key for selector sel is revoked (empty p=)",
+			wantStatus:  StatusWarn,
+		},
+		{
+			name:        "bad base64",
+			record:      "v=DKIM1; p=not*base64",
+			want:        DKIMResult{Found: true, KeyType: "rsa", Error: "p= is not valid base64"},
+			wantFinding: "DKIM key for selector sel is invalid: p= is not valid base64",
+			wantStatus:  StatusFail,
+		},
+		{
+			name:        "short Ed25519 key",
+			record:      "v=DKIM1; k=ed25519; p=AAAA",
+			want:        DKIMResult{Found: true, KeyType: "ed25519", Error: "Ed25519 key has 3 bytes, expected 32"},
+			wantFinding: "DKIM key for selector sel is invalid: Ed25519 key has 3 bytes, expected 32",
+			wantStatus:  StatusFail,
+		},
+		{
+			name:        "unsupported key type",
+			record:      "v=DKIM1; k=dsa; p=AAAA",
+			want:        DKIMResult{Found: true, KeyType: "dsa", Error: `unsupported key type "dsa"`},
+			wantFinding: `DKIM key for selector sel is invalid: unsupported key type "dsa"`,
+			wantStatus:  StatusFail,
+		},
+		{
+			name:        "not a DKIM record",
+			record:      "v=spf1 -all",
+			want:        DKIMResult{},
+			wantFinding: "No DKIM key found for selector sel",
+			wantStatus:  StatusWarn,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resolver := txtZone(t, map[string][]string{"sel._domainkey.example.com": {tt.record}})
+			result, findings := checkDKIM(context.Background(), resolver, "example.com", "sel")
+			tt.want.Selector, tt.want.Name = "sel", "sel._domainkey.example.com"
+			if tt.want.Found {
+				tt.want.Record = tt.record
+			}
+			if result != tt.want {
+				t.Errorf("checkDKIM() = %+v, want %+v", result, tt.want)
+			}
+			if got, ok := findingStatuses(findings)[tt.wantFinding]; !ok || got != tt.wantStatus {
+				t.Errorf("finding %q has status %q (present %v), want %q; findings: %+v", tt.wantFinding, got, ok, tt.wantStatus, findings)
+			}
+		})
+	}
+}
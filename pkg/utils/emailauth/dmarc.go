@@ -0,0 +1,133 @@
+package emailauth
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/vit0-9/utils_api/pkg/utils"
+	"golang.org/x/net/publicsuffix"
+)
+
+// DMARCResult is a parsed DMARC policy record.
+type DMARCResult struct {
+	Name            string   `json:"name"` // Where the record was found, e.g. "_dmarc.example.com"
+	Record          string   `json:"record,omitempty"`
+	Policy          string   `json:"policy,omitempty"`           // p
+	SubdomainPolicy string   `json:"subdomain_policy,omitempty"` // sp, defaults to p
+	Percentage      int      `json:"percentage"`                 // pct, defaults to 100
+	AggregateReport []string `json:"aggregate_report_uris,omitempty"`
+	ForensicReport  []string `json:"forensic_report_uris,omitempty"`
+	DKIMAlignment   string   `json:"dkim_alignment,omitempty"` // "relaxed" or "strict"
+	SPFAlignment    string   `json:"spf_alignment,omitempty"`
+	Inherited       bool     `json:"inherited"` // Taken from the organizational domain
+	Error           string   `json:"error,omitempty"`
+}
+
+// checkDMARC fetches the DMARC record for domain, falling back to the organizational
+// domain's record as receivers do (RFC 7489 section 6.6.3).
+func checkDMARC(ctx context.Context, resolver utils.Resolver, domain string) (*DMARCResult, []Finding) {
+	var findings []Finding
+	addFinding := func(status, format string, args ...any) {
+		findings = append(findings, Finding{Check: "dmarc", Status: status, Message: fmt.Sprintf(format, args...)})
+	}
+
+	result := &DMARCResult{Name: "_dmarc." + domain, Percentage: 100}
+	records, err := lookupDMARC(ctx, resolver, result.Name)
+	if err == nil && len(records) == 0 {
+		if orgDomain, orgErr := publicsuffix.EffectiveTLDPlusOne(domain); orgErr == nil && orgDomain != domain {
+			result.Name = "_dmarc." + orgDomain
+			result.Inherited = true
+			records, err = lookupDMARC(ctx, resolver, result.Name)
+		}
+	}
+	if err != nil {
+		result.Error = err.Error()
+		addFinding(StatusFail, "Could not look up the DMARC record: %v", err)
+		return result, findings
+	}
+	switch {
+	case len(records) == 0:
+		result.Name = "_dmarc." + domain
+		result.Inherited = false
+		result.Error = "no DMARC record"
+		addFinding(StatusFail, "No DMARC record published at _dmarc.%s", domain)
+		return result, findings
+	case len(records) > 1:
+		result.Error = "multiple DMARC records"
+		addFinding(StatusFail, "%s has %d DMARC records; receivers ignore all of them", result.Name, len(records))
+		return result, findings
+	}
+
+	result.Record = records[0]
+	tags := parseTagList(result.Record)
+	result.Policy = strings.ToLower(tags["p"])
+	result.SubdomainPolicy = strings.ToLower(tags["sp"])
+	if result.SubdomainPolicy == "" {
+		result.SubdomainPolicy = result.Policy
+	}
+	if pct, err := strconv.Atoi(tags["pct"]); err == nil {
+		result.Percentage = pct
+	}
+	result.AggregateReport = splitURIList(tags["rua"])
+	result.ForensicReport = splitURIList(tags["ruf"])
+	result.DKIMAlignment = alignmentMode(tags["adkim"])
+	result.SPFAlignment = alignmentMode(tags["aspf"])
+
+	if result.Inherited {
+		addFinding(StatusPass, "DMARC policy inherited from the organizational domain record at %s", result.Name)
+	}
+	switch result.Policy {
+	case "reject":
+		addFinding(StatusPass, "DMARC policy is reject")
+	case "quarantine":
+		addFinding(StatusPass, "DMARC policy is quarantine")
+	case "none":
+		addFinding(StatusWarn, "DMARC policy is none (monitoring only); failing mail is still delivered")
+	default:
+		addFinding(StatusFail, "DMARC record has a missing or invalid p= policy %q", tags["p"])
+	}
+	if result.SubdomainPolicy == "none" && result.Policy != "none" {
+		addFinding(StatusWarn, "DMARC subdomain policy is none while the domain policy is %s", result.Policy)
+	}
+	if result.Percentage < 100 {
+		addFinding(StatusWarn, "DMARC policy applies to only %d%% of failing mail", result.Percentage)
+	}
+	if len(result.AggregateReport) == 0 {
+		addFinding(StatusWarn, "DMARC record has no rua= address, so no aggregate reports are received")
+	}
+	return result, findings
+}
+
+// lookupDMARC returns the DMARC records (those starting with v=DMARC1) at name.
+func lookupDMARC(ctx context.Context, resolver utils.Resolver, name string) ([]string, error) {
+	records, err := lookupTXT(ctx, resolver, name)
+	if err != nil {
+		return nil, err
+	}
+	var dmarcRecords []string
+	for _, record := range records {
+		if strings.HasPrefix(strings.ToUpper(strings.TrimSpace(record)), "V=DMARC1") {
+			dmarcRecords = append(dmarcRecords, record)
+		}
+	}
+	return dmarcRecords, nil
+}
+
+func splitURIList(value string) []string {
+	var uris []string
+	for _, uri := range strings.Split(value, ",") {
+		if uri = strings.TrimSpace(uri); uri != "" {
+			uris = append(uris, uri)
+		}
+	}
+	return uris
+}
+
+func alignmentMode(value string) string {
+	if strings.EqualFold(value, "s") {
+		return "strict"
+	}
+	return "relaxed"
+}
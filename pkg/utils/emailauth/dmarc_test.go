@@ -0,0 +1,95 @@
+package emailauth
+
+import (
+	"context"
+	"reflect"
+	"testing"
+)
+
+func TestCheckDMARC(t *testing.T) {
+	tests := []struct {
+		name        string
+		domain      string
+		records     map[string][]string
+		want        DMARCResult
+		wantFinding string
+		wantStatus  string
+	}{
+		{
+			name:   "full record",
+			domain: "example.com",
+			records: map[string][]string{"_dmarc.example.com": {
+				"v=DMARC1; p=Reject; sp=none; pct=50; rua=mailto:a@example.com, mailto:b@example.net; ruf=mailto:f@example.com; adkim=s; aspf=r",
+			}},
+			want: DMARCResult{
+				Name: "_dmarc.example.com", Policy: "reject", SubdomainPolicy: "none", Percentage: 50,
+				AggregateReport: []string{"mailto:a@example.com", "mailto:b@example.net"}, ForensicReport: []string{"mailto:f@example.com"},
+				DKIMAlignment: "strict", SPFAlignment: "relaxed",
+			},
+			wantFinding: "DMARC subdomain policy is none while the domain policy is reject",
+			wantStatus:  StatusWarn,
+		},
+		{
+			name:    "defaults",
+			domain:  "example.com",
+			records: map[string][]string{"_dmarc.example.com": {"v=DMARC1; p=none"}},
+			want: DMARCResult{
+				Name: "_dmarc.example.com", Policy: "none", SubdomainPolicy: "none", Percentage: 100,
+				DKIMAlignment: "relaxed", SPFAlignment: "relaxed",
+			},
+			wantFinding: "DMARC record has no rua= address, so no aggregate reports are received",
+			wantStatus:  StatusWarn,
+		},
+		{
+			name:    "inherited from the organizational domain",
+			domain:  "mail.example.co.uk",
+			records: map[string][]string{"_dmarc.example.co.uk": {"v=DMARC1; p=quarantine; rua=mailto:d@example.co.uk"}},
+			want: DMARCResult{
+				Name: "_dmarc.example.co.uk", Policy: "quarantine", SubdomainPolicy: "quarantine", Percentage: 100,
+				AggregateReport: []string{"mailto:d@example.co.uk"}, DKIMAlignment: "relaxed", SPFAlignment: "relaxed", Inherited: true,
+			},
+			wantFinding: "DMARC policy inherited from the organizational domain record at _dmarc.example.co.uk",
+			wantStatus:  StatusPass,
+		},
+		{
+			name:        "missing",
+			domain:      "mail.example.com",
+			records:     map[string][]string{"_dmarc.example.com": {"v=spf1 -all"}},
+			want:        DMARCResult{Name: "_dmarc.mail.example.com", Percentage: 100, Error: "no DMARC record"},
+			wantFinding: "No DMARC record published at _dmarc.mail.example.com",
+			wantStatus:  StatusFail,
+		},
+		{
+			name:        "multiple",
+			domain:      "example.com",
+			records:     map[string][]string{"_dmarc.example.com": {"v=DMARC1; p=none", "v=DMARC1; p=reject"}},
+			want:        DMARCResult{Name: "_dmarc.example.com", Percentage: 100, Error: "multiple DMARC records"},
+			wantFinding: "_dmarc.example.com has 2 DMARC records; receivers ignore all of them",
+			wantStatus:  StatusFail,
+		},
+		{
+			name:    "invalid policy",
+			domain:  "example.com",
+			records: map[string][]string{"_dmarc.example.com": {"v=DMARC1; p=block; rua=mailto:a@example.com"}},
+			want: DMARCResult{
+				Name: "_dmarc.example.com", Policy: "block", SubdomainPolicy: "block", Percentage: 100,
+				AggregateReport: []string{"mailto:a@example.com"}, DKIMAlignment: "relaxed", SPFAlignment: "relaxed",
+			},
+			wantFinding: `DMARC record has a missing or invalid p= policy "block"`,
+			wantStatus:  StatusFail,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, findings := checkDMARC(context.Background(), txtZone(t, tt.records), tt.domain)
+			result.Record = ""
+			if !reflect.DeepEqual(*result, tt.want) {
+				t.Errorf("checkDMARC() = %+v, want %+v", *result, tt.want)
+			}
+			if got, ok := findingStatuses(findings)[tt.wantFinding]; !ok || got != tt.wantStatus {
+				t.Errorf("finding %q has status %q (present %v), want %q; findings: %+v", tt.wantFinding, got, ok, tt.wantStatus, findings)
+			}
+		})
+	}
+}
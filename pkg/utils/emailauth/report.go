@@ -0,0 +1,117 @@
+// Package emailauth checks a domain's email authentication setup: SPF, DMARC and DKIM.
+package emailauth
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/vit0-9/utils_api/pkg/utils"
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+// Finding severities, from best to worst.
+const (
+	StatusPass = "pass"
+	StatusWarn = "warn"
+	StatusFail = "fail"
+)
+
+// Finding is a single pass/warn/fail observation about the domain's setup.
+type Finding struct {
+	Check   string `json:"check"` // "spf", "dmarc" or "dkim"
+	Status  string `json:"status"`
+	Message string `json:"message"`
+}
+
+// Report is the combined SPF, DMARC and DKIM analysis of a domain.
+type Report struct {
+	Domain   string        `json:"domain"`
+	Status   string        `json:"status"` // Worst status among the findings
+	SPF      *SPFResult    `json:"spf"`
+	DMARC    *DMARCResult  `json:"dmarc"`
+	DKIM     []DKIMResult  `json:"dkim,omitempty"`
+	Findings []Finding     `json:"findings"`
+	Summary  FindingCounts `json:"summary"`
+}
+
+// FindingCounts counts findings by status.
+type FindingCounts struct {
+	Pass int `json:"pass"`
+	Warn int `json:"warn"`
+	Fail int `json:"fail"`
+}
+
+// Analyze fetches and evaluates the SPF and DMARC records of domain, and the DKIM keys
+// published under each of the given selectors.
+func Analyze(ctx context.Context, resolver utils.Resolver, domain string, dkimSelectors []string) *Report {
+	domain = strings.TrimSuffix(strings.ToLower(strings.TrimSpace(domain)), ".")
+	report := &Report{Domain: domain}
+
+	var findings []Finding
+	report.SPF, findings = checkSPF(ctx, resolver, domain)
+	report.Findings = append(report.Findings, findings...)
+
+	report.DMARC, findings = checkDMARC(ctx, resolver, domain)
+	report.Findings = append(report.Findings, findings...)
+
+	for _, selector := range dkimSelectors {
+		selector = strings.TrimSpace(selector)
+		if selector == "" {
+			continue
+		}
+		var result DKIMResult
+		result, findings = checkDKIM(ctx, resolver, domain, selector)
+		report.DKIM = append(report.DKIM, result)
+		report.Findings = append(report.Findings, findings...)
+	}
+
+	report.Status = StatusPass
+	for _, finding := range report.Findings {
+		switch finding.Status {
+		case StatusPass:
+			report.Summary.Pass++
+		case StatusWarn:
+			report.Summary.Warn++
+			if report.Status == StatusPass {
+				report.Status = StatusWarn
+			}
+		case StatusFail:
+			report.Summary.Fail++
+			report.Status = StatusFail
+		}
+	}
+	return report
+}
+
+// lookupTXT returns the TXT records at name, each record's strings joined. A name that
+// does not exist yields no records and no error.
+func lookupTXT(ctx context.Context, resolver utils.Resolver, name string) ([]string, error) {
+	answers, err := resolver.Query(ctx, name, dnsmessage.TypeTXT)
+	if errors.Is(err, utils.ErrDNSNameNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var records []string
+	for _, answer := range answers {
+		if txt, ok := answer.Body.(*dnsmessage.TXTResource); ok {
+			records = append(records, strings.Join(txt.TXT, ""))
+		}
+	}
+	return records, nil
+}
+
+// parseTagList parses a "tag=value; tag=value" record as used by DMARC and DKIM.
+func parseTagList(record string) map[string]string {
+	tags := make(map[string]string)
+	for _, part := range strings.Split(record, ";") {
+		name, value, ok := strings.Cut(part, "=")
+		if !ok {
+			continue
+		}
+		tags[strings.ToLower(strings.TrimSpace(name))] = strings.TrimSpace(value)
+	}
+	return tags
+}
@@ -0,0 +1,80 @@
+package emailauth
+
+import (
+	"context"
+	"testing"
+
+	"github.com/vit0-9/utils_api/pkg/utils"
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+// txtZone answers with the given TXT records and NXDOMAIN for other names.
+func txtZone(t *testing.T, records map[string][]string) utils.Resolver {
+	t.Helper()
+	resolver := utils.NewFakeResolver()
+	for name, values := range records {
+		for _, value := range values {
+			resolver.Add(name, dnsmessage.TypeTXT, &dnsmessage.TXTResource{TXT: txtStrings(value)})
+		}
+	}
+	return resolver
+}
+
+// txtStrings splits a record into the 255-byte character strings TXT records carry.
+func txtStrings(value string) []string {
+	var parts []string
+	for len(value) > 255 {
+		parts = append(parts, value[:255])
+		value = value[255:]
+	}
+	return append(parts, value)
+}
+
+func findingStatuses(findings []Finding) map[string]string {
+	statuses := make(map[string]string)
+	for _, finding := range findings {
+		statuses[finding.Message] = finding.Status
+	}
+	return statuses
+}
+
+func TestParseTagList(t *testing.T) {
+	tests := []struct {
+		record string
+		want   map[string]string
+	}{
+		{"v=DMARC1; p=reject; rua=mailto:a@example.com", map[string]string{"v": "DMARC1", "p": "reject", "rua": "mailto:a@example.com"}},
+		{" V = DKIM1 ;K=rsa; p= ; ", map[string]string{"v": "DKIM1", "k": "rsa", "p": ""}},
+		{"p=abc=def", map[string]string{"p": "abc=def"}},
+		{"no tags here", map[string]string{}},
+	}
+	for _, tt := range tests {
+		got := parseTagList(tt.record)
+		if len(got) != len(tt.want) {
+			t.Errorf("parseTagList(%q) = %v, want %v", tt.record, got, tt.want)
+			continue
+		}
+		for name, value := range tt.want {
+			if got[name] != value {
+				t.Errorf("parseTagList(%q)[%q] = %q, want %q", tt.record, name, got[name], value)
+			}
+		}
+	}
+}
+
+func TestAnalyzeStatus(t *testing.T) {
+	resolver := txtZone(t, map[string][]string{
+		"example.com":        {"v=spf1 -all"},
+		"_dmarc.example.com": {"v=DMARC1; p=reject; rua=mailto:dmarc@example.com"},
+	})
+	report := Analyze(context.Background(), resolver, " Example.COM. ", []string{"", "missing"})
+	if report.Domain != "example.com" {
+		t.Errorf("Domain = %q, want example.com", report.Domain)
+	}
+	if report.Status != StatusWarn || report.Summary.Fail != 0 || report.Summary.Warn != 1 {
+		t.Errorf("Status = %q, summary %+v; want warn from the missing DKIM selector only", report.Status, report.Summary)
+	}
+	if len(report.DKIM) != 1 || report.DKIM[0].Selector != "missing" {
+		t.Errorf("DKIM = %+v, want only the non-empty selector", report.DKIM)
+	}
+}
@@ -0,0 +1,203 @@
+package emailauth
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/vit0-9/utils_api/pkg/utils"
+)
+
+// spfMaxDNSLookups is the RFC 7208 limit on DNS-querying terms across an SPF evaluation.
+const spfMaxDNSLookups = 10
+
+// SPFMechanism is one term of an SPF record, e.g. "~all" or "include:_spf.google.com".
+type SPFMechanism struct {
+	Qualifier string `json:"qualifier"` // "+", "-", "~" or "?"
+	Type      string `json:"type"`      // all, include, a, mx, ptr, ip4, ip6, exists
+	Value     string `json:"value,omitempty"`
+}
+
+// SPFResult is a parsed SPF record with its includes and redirect resolved recursively.
+type SPFResult struct {
+	Domain     string         `json:"domain"`
+	Record     string         `json:"record,omitempty"`
+	Mechanisms []SPFMechanism `json:"mechanisms,omitempty"`
+	Redirect   string         `json:"redirect,omitempty"`
+	Includes   []*SPFResult   `json:"includes,omitempty"` // Included records, then the redirect target
+	DNSLookups int            `json:"dns_lookups"`        // Lookups counted against the limit by this record and its includes
+	Error      string         `json:"error,omitempty"`
+}
+
+// spfEvaluation carries the state shared across a recursive SPF resolution.
+type spfEvaluation struct {
+	resolver utils.Resolver
+	lookups  int
+	visited  map[string]bool
+	findings []Finding
+}
+
+func (e *spfEvaluation) addFinding(status, format string, args ...any) {
+	e.findings = append(e.findings, Finding{Check: "spf", Status: status, Message: fmt.Sprintf(format, args...)})
+}
+
+// checkSPF fetches the domain's SPF record, resolves its includes and evaluates it.
+func checkSPF(ctx context.Context, resolver utils.Resolver, domain string) (*SPFResult, []Finding) {
+	evaluation := &spfEvaluation{resolver: resolver, visited: make(map[string]bool)}
+	result := evaluation.resolve(ctx, domain, true)
+	if result.Record == "" {
+		return result, evaluation.findings
+	}
+
+	switch allQualifier(result) {
+	case "+":
+		evaluation.addFinding(StatusFail, "SPF record ends with +all, allowing any server to send mail for %s", domain)
+	case "?":
+		evaluation.addFinding(StatusWarn, "SPF record ends with ?all (neutral), which gives receivers no guidance")
+	case "~":
+		evaluation.addFinding(StatusPass, "SPF record ends with ~all (soft fail)")
+	case "-":
+		evaluation.addFinding(StatusPass, "SPF record ends with -all (hard fail)")
+	default:
+		evaluation.addFinding(StatusWarn, "SPF record has no all mechanism or redirect, so unlisted senders get a neutral result")
+	}
+
+	if evaluation.lookups > spfMaxDNSLookups {
+		evaluation.addFinding(StatusFail, "SPF evaluation needs %d DNS lookups, more than the limit of %d (permerror)", evaluation.lookups, spfMaxDNSLookups)
+	} else {
+		evaluation.addFinding(StatusPass, "SPF evaluation needs %d of at most %d DNS lookups", evaluation.lookups, spfMaxDNSLookups)
+	}
+	return result, evaluation.findings
+}
+
+// allQualifier returns the qualifier of the "all" mechanism that ends the evaluation,
+// following a redirect when the record has no all of its own.
+func allQualifier(result *SPFResult) string {
+	for _, mechanism := range result.Mechanisms {
+		if mechanism.Type == "all" {
+			return mechanism.Qualifier
+		}
+	}
+	if result.Redirect != "" && len(result.Includes) > 0 {
+		return allQualifier(result.Includes[len(result.Includes)-1])
+	}
+	return ""
+}
+
+// resolve fetches and parses the SPF record at domain, recursing into includes and redirects.
+func (e *spfEvaluation) resolve(ctx context.Context, domain string, root bool) *SPFResult {
+	result := &SPFResult{Domain: domain}
+	lookupsBefore := e.lookups
+	defer func() { result.DNSLookups = e.lookups - lookupsBefore }()
+
+	if e.visited[domain] {
+		result.Error = "include loop"
+		e.addFinding(StatusFail, "SPF include loop through %s", domain)
+		return result
+	}
+	e.visited[domain] = true
+	defer delete(e.visited, domain)
+
+	records, err := lookupTXT(ctx, e.resolver, domain)
+	if err != nil {
+		result.Error = err.Error()
+		e.addFinding(StatusFail, "Could not look up the SPF record of %s: %v", domain, err)
+		return result
+	}
+	var spfRecords []string
+	for _, record := range records {
+		if isSPFRecord(record) {
+			spfRecords = append(spfRecords, record)
+		}
+	}
+	switch {
+	case len(spfRecords) == 0 && root:
+		result.Error = "no SPF record"
+		e.addFinding(StatusFail, "No SPF record published for %s", domain)
+		return result
+	case len(spfRecords) == 0:
+		result.Error = "no SPF record"
+		e.addFinding(StatusFail, "Included domain %s has no SPF record (permerror)", domain)
+		return result
+	case len(spfRecords) > 1:
+		result.Error = "multiple SPF records"
+		e.addFinding(StatusFail, "%s publishes %d SPF records; exactly one is allowed (permerror)", domain, len(spfRecords))
+		return result
+	}
+	result.Record = spfRecords[0]
+
+	for _, term := range strings.Fields(result.Record)[1:] {
+		if name, value, ok := strings.Cut(term, "="); ok && !strings.Contains(name, ":") {
+			if strings.EqualFold(name, "redirect") {
+				result.Redirect = value
+			}
+			continue // exp and unknown modifiers
+		}
+
+		mechanism := parseSPFMechanism(term)
+		result.Mechanisms = append(result.Mechanisms, mechanism)
+		switch mechanism.Type {
+		case "a", "mx", "exists":
+			e.lookups++
+		case "ptr":
+			e.lookups++
+			e.addFinding(StatusWarn, "%s uses the ptr mechanism, which is deprecated and slow", domain)
+		case "include":
+			e.lookups++
+			result.Includes = append(result.Includes, e.resolveTarget(ctx, mechanism.Value))
+		case "all", "ip4", "ip6":
+		default:
+			e.addFinding(StatusFail, "%s has an unknown SPF mechanism %q (permerror)", domain, term)
+		}
+	}
+
+	if result.Redirect != "" && !hasAllMechanism(result) {
+		e.lookups++
+		result.Includes = append(result.Includes, e.resolveTarget(ctx, result.Redirect))
+	}
+	return result
+}
+
+// resolveTarget resolves an include or redirect target, unless the lookup limit is
+// already exceeded or the target uses macros that can only be expanded for a real message.
+func (e *spfEvaluation) resolveTarget(ctx context.Context, target string) *SPFResult {
+	if strings.Contains(target, "%") {
+		return &SPFResult{Domain: target, Error: "contains macros; not resolved"}
+	}
+	if e.lookups > spfMaxDNSLookups {
+		return &SPFResult{Domain: target, Error: "not resolved: DNS lookup limit exceeded"}
+	}
+	return e.resolve(ctx, strings.ToLower(strings.TrimSuffix(target, ".")), false)
+}
+
+func isSPFRecord(record string) bool {
+	lower := strings.ToLower(strings.TrimSpace(record))
+	return lower == "v=spf1" || strings.HasPrefix(lower, "v=spf1 ")
+}
+
+func hasAllMechanism(result *SPFResult) bool {
+	for _, mechanism := range result.Mechanisms {
+		if mechanism.Type == "all" {
+			return true
+		}
+	}
+	return false
+}
+
+// parseSPFMechanism splits a term like "~include:example.com" or "a/24".
+func parseSPFMechanism(term string) SPFMechanism {
+	mechanism := SPFMechanism{Qualifier: "+"}
+	if strings.ContainsAny(term[:1], "+-~?") {
+		mechanism.Qualifier, term = term[:1], term[1:]
+	}
+	name, value, found := strings.Cut(term, ":")
+	if !found {
+		name, value, _ = strings.Cut(term, "/")
+		if value != "" {
+			value = "/" + value
+		}
+	}
+	mechanism.Type = strings.ToLower(name)
+	mechanism.Value = value
+	return mechanism
+}
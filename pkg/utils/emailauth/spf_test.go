@@ -0,0 +1,169 @@
+package emailauth
+
+import (
+	"context"
+	"fmt"
+	"testing"
+)
+
+func TestParseSPFMechanism(t *testing.T) {
+	tests := []struct {
+		term string
+		want SPFMechanism
+	}{
+		{"-all", SPFMechanism{Qualifier: "-", Type: "all"}},
+		{"all", SPFMechanism{Qualifier: "+", Type: "all"}},
+		{"~include:_spf.google.com", SPFMechanism{Qualifier: "~", Type: "include", Value: "_spf.google.com"}},
+		{"ip4:192.0.2.0/24", SPFMechanism{Qualifier: "+", Type: "ip4", Value: "192.0.2.0/24"}},
+		{"ip6:2001:db8::/32", SPFMechanism{Qualifier: "+", Type: "ip6", Value: "2001:db8::/32"}},
+		{"?a/24", SPFMechanism{Qualifier: "?", Type: "a", Value: "/24"}},
+		{"MX:mail.example.com/28", SPFMechanism{Qualifier: "+", Type: "mx", Value: "mail.example.com/28"}},
+	}
+	for _, tt := range tests {
+		if got := parseSPFMechanism(tt.term); got != tt.want {
+			t.Errorf("parseSPFMechanism(%q) = %+v, want %+v", tt.term, got, tt.want)
+		}
+	}
+}
+
+func TestIsSPFRecord(t *testing.T) {
+	tests := map[string]bool{
+		"v=spf1 -all":    true,
+		"V=SPF1 mx -all": true,
+		"v=spf1":         true,
+		" v=spf1 -all ":  true,
+		"v=spf10 -all":   false,
+		"v=DMARC1; p=no": false,
+		"spf1 -all":      false,
+	}
+	for record, want := range tests {
+		if got := isSPFRecord(record); got != want {
+			t.Errorf("isSPFRecord(%q) = %v, want %v", record, got, want)
+		}
+	}
+}
+
+func TestCheckSPF(t *testing.T) {
+	tests := []struct {
+		name        string
+		records     map[string][]string
+		wantLookups int
+		wantAll     string
+		wantError   string
+		wantFinding string
+		wantStatus  string
+	}{
+		{
+			name:        "hard fail",
+			records:     map[string][]string{"example.com": {"v=spf1 ip4:192.0.2.0/24 -all", "google-site-verification=abc"}},
+			wantAll:     "-",
+			wantFinding: "SPF record ends with -all (hard fail)",
+			wantStatus:  StatusPass,
+		},
+		{
+			name:        "pass all",
+			records:     map[string][]string{"example.com": {"v=spf1 +all"}},
+			wantAll:     "+",
+			wantFinding: "SPF record ends with +all, allowing any server to send mail for example.com",
+			wantStatus:  StatusFail,
+		},
+		{
+			name: "includes and redirect count lookups",
+			records: map[string][]string{
+				"example.com":        {"v=spf1 a mx include:_spf.example.net redirect=_spf.example.org"},
+				"_spf.example.net":   {"v=spf1 ip4:198.51.100.1 include:nested.example.net ~all"},
+				"nested.example.net": {"v=spf1 exists:%{i}.rbl.example.net ?all"},
+				"_spf.example.org":   {"v=spf1 ~all"},
+			},
+			wantLookups: 6, // a, mx, include, nested include, exists, redirect
+			wantAll:     "~",
+			wantFinding: "SPF evaluation needs 6 of at most 10 DNS lookups",
+			wantStatus:  StatusPass,
+		},
+		{
+			name:        "no record",
+			records:     map[string][]string{"example.com": {"unrelated"}},
+			wantError:   "no SPF record",
+			wantFinding: "No SPF record published for example.com",
+			wantStatus:  StatusFail,
+		},
+		{
+			name:        "multiple records",
+			records:     map[string][]string{"example.com": {"v=spf1 -all", "v=spf1 ~all"}},
+			wantError:   "multiple SPF records",
+			wantFinding: "example.com publishes 2 SPF records; exactly one is allowed (permerror)",
+			wantStatus:  StatusFail,
+		},
+		{
+			name: "include loop",
+			records: map[string][]string{
+				"example.com":   {"v=spf1 include:a.example.com -all"},
+				"a.example.com": {"v=spf1 include:example.com -all"},
+			},
+			wantLookups: 2,
+			wantAll:     "-",
+			wantFinding: "SPF include loop through example.com",
+			wantStatus:  StatusFail,
+		},
+		{
+			name:        "missing include",
+			records:     map[string][]string{"example.com": {"v=spf1 include:gone.example.com -all"}},
+			wantLookups: 1,
+			wantAll:     "-",
+			wantFinding: "Included domain gone.example.com has no SPF record (permerror)",
+			wantStatus:  StatusFail,
+		},
+		{
+			name:        "no all",
+			records:     map[string][]string{"example.com": {"v=spf1 mx"}},
+			wantLookups: 1,
+			wantFinding: "SPF record has no all mechanism or redirect, so unlisted senders get a neutral result",
+			wantStatus:  StatusWarn,
+		},
+		{
+			name:        "ptr is deprecated",
+			records:     map[string][]string{"example.com": {"v=spf1 ptr -all"}},
+			wantLookups: 1,
+			wantAll:     "-",
+			wantFinding: "example.com uses the ptr mechanism, which is deprecated and slow",
+			wantStatus:  StatusWarn,
+		},
+		{
+			name:        "unknown mechanism",
+			records:     map[string][]string{"example.com": {"v=spf1 ip5:1.2.3.4 -all"}},
+			wantAll:     "-",
+			wantFinding: `example.com has an unknown SPF mechanism "ip5:1.2.3.4" (permerror)`,
+			wantStatus:  StatusFail,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, findings := checkSPF(context.Background(), txtZone(t, tt.records), "example.com")
+			if result.Error != tt.wantError {
+				t.Errorf("Error = %q, want %q", result.Error, tt.wantError)
+			}
+			if result.DNSLookups != tt.wantLookups {
+				t.Errorf("DNSLookups = %d, want %d", result.DNSLookups, tt.wantLookups)
+			}
+			if got := allQualifier(result); got != tt.wantAll {
+				t.Errorf("allQualifier() = %q, want %q", got, tt.wantAll)
+			}
+			if got, ok := findingStatuses(findings)[tt.wantFinding]; !ok || got != tt.wantStatus {
+				t.Errorf("finding %q has status %q (present %v), want %q; findings: %+v", tt.wantFinding, got, ok, tt.wantStatus, findings)
+			}
+		})
+	}
+}
+
+func TestCheckSPFLookupLimit(t *testing.T) {
+	records := map[string][]string{"example.com": {"v=spf1 include:i0.example.com include:i1.example.com -all"}}
+	for i := 0; i < 2; i++ {
+		records[fmt.Sprintf("i%d.example.com", i)] = []string{"v=spf1 a mx a:x.example.com mx:y.example.com exists:z.example.com ~all"}
+	}
+	_, findings := checkSPF(context.Background(), txtZone(t, records), "example.com")
+	want := "SPF evaluation needs 12 DNS lookups, more than the limit of 10 (permerror)"
+	if findingStatuses(findings)[want] != StatusFail {
+		t.Errorf("missing lookup limit failure %q; findings: %+v", want, findings)
+	}
+}
@@ -0,0 +1,80 @@
+// Package eventlog keeps a bounded, in-memory log of what the API observed (lookups served,
+// monitor events and new passive DNS answers) for export to SIEM systems.
+package eventlog
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/vit0-9/utils_api/pkg/utils/redact"
+)
+
+// Event types.
+const (
+	TypeLookup     = "lookup"      // An API request was served
+	TypeMonitor    = "monitor"     // A notification was emitted, e.g. job.completed or monitor.alert
+	TypePassiveDNS = "passive_dns" // A DNS answer was seen for the first time
+)
+
+// maxEvents bounds the log; the oldest events are dropped first.
+const maxEvents = 50000
+
+// Event is one entry in the log. IDs increase strictly, also across restarts, and serve as
+// the export cursor.
+type Event struct {
+	ID       uint64         `json:"id"`
+	Time     time.Time      `json:"time"`
+	Type     string         `json:"type"`
+	Name     string         `json:"name"`     // Request path, notification event or DNS name
+	Severity int            `json:"severity"` // 0 (lowest) to 10, as in CEF
+	Data     map[string]any `json:"data,omitempty"`
+}
+
+var (
+	mu     sync.Mutex
+	events []Event // Oldest first
+	// lastID starts at the boot time in microseconds, so IDs issued after a restart are
+	// still greater than any cursor a client holds.
+	lastID = uint64(time.Now().UnixMicro())
+)
+
+// Append adds an event to the log, with the email addresses in its data masked.
+func Append(eventType, name string, severity int, data map[string]any) {
+	if data != nil {
+		data = redact.Value(data).(map[string]any)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	lastID++
+	events = append(events, Event{ID: lastID, Time: time.Now().UTC(), Type: eventType, Name: name, Severity: severity, Data: data})
+	if len(events) > maxEvents {
+		events = append(events[:0:0], events[len(events)-maxEvents:]...)
+	}
+}
+
+// Query selects events from the log.
+type Query struct {
+	After uint64          // Only events with a greater ID (the cursor)
+	Since time.Time       // Only events at or after this time
+	Types map[string]bool // Only these types; empty means all
+	Limit int
+}
+
+// Read returns up to query.Limit matching events, oldest first, and whether more follow.
+func Read(query Query) ([]Event, bool) {
+	mu.Lock()
+	defer mu.Unlock()
+	start := sort.Search(len(events), func(i int) bool { return events[i].ID > query.After })
+	matched := []Event{}
+	for _, event := range events[start:] {
+		if event.Time.Before(query.Since) || (len(query.Types) > 0 && !query.Types[event.Type]) {
+			continue
+		}
+		if len(matched) == query.Limit {
+			return matched, true
+		}
+		matched = append(matched, event)
+	}
+	return matched, false
+}
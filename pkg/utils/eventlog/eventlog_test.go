@@ -0,0 +1,61 @@
+package eventlog
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestReadPagesWithCursor(t *testing.T) {
+	mu.Lock()
+	events = nil
+	mu.Unlock()
+	start := time.Now().UTC()
+	for i := 0; i < 5; i++ {
+		Append(TypeLookup, "/api/v1/net/dns-lookup", 1, nil)
+	}
+	Append(TypeMonitor, "monitor.alert", 7, nil)
+
+	page, more := Read(Query{Limit: 4})
+	if len(page) != 4 || !more {
+		t.Fatalf("Read(limit 4) = %d events, more %v, want 4 and more", len(page), more)
+	}
+	rest, more := Read(Query{After: page[3].ID, Limit: 4})
+	if len(rest) != 2 || more || rest[0].ID != page[3].ID+1 {
+		t.Errorf("Read(after cursor) = %+v, more %v, want the last 2 events", rest, more)
+	}
+	if monitor, _ := Read(Query{Types: map[string]bool{TypeMonitor: true}, Limit: 10}); len(monitor) != 1 || monitor[0].Name != "monitor.alert" {
+		t.Errorf("Read(monitor) = %+v, want the alert", monitor)
+	}
+	if future, _ := Read(Query{Since: start.Add(time.Hour), Limit: 10}); len(future) != 0 {
+		t.Errorf("Read(since an hour ahead) = %+v, want none", future)
+	}
+}
+
+func TestFormats(t *testing.T) {
+	event := Event{
+		ID:       42,
+		Time:     time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC),
+		Type:     TypeLookup,
+		Name:     "/api/v1/net|odd",
+		Severity: 1,
+		Data:     map[string]any{"query": "a=b\nc", "status": 200, "client ip": "192.0.2.1"},
+	}
+	cef := FormatCEF(event)
+	want := `CEF:0|utils_api|Utility API|1.0|lookup|/api/v1/net\|odd|1|rt=1767323045000 externalId=42 cat=lookup client_ip=192.0.2.1 query=a\=b\nc status=200`
+	if cef != want {
+		t.Errorf("FormatCEF() =\n%s\nwant\n%s", cef, want)
+	}
+	leef := FormatLEEF(event)
+	if !strings.HasPrefix(leef, "LEEF:1.0|utils_api|Utility API|1.0|lookup|devTime=Jan 02 2026 03:04:05\t") || !strings.Contains(leef, "\tquery=a=b c\t") {
+		t.Errorf("FormatLEEF() = %q", leef)
+	}
+}
+
+func TestAppendMasksEmails(t *testing.T) {
+	Append(TypeMonitor, "monitor.alert", 5, map[string]any{"message": "owner jane@example.com notified"})
+	page, _ := Read(Query{Types: map[string]bool{TypeMonitor: true}, Limit: maxEvents})
+	if got := page[len(page)-1].Data["message"]; got != "owner j***@example.com notified" {
+		t.Errorf("stored message = %q, want the email masked", got)
+	}
+}
@@ -0,0 +1,88 @@
+package eventlog
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Header fields shared by the CEF and LEEF formats.
+const (
+	vendor  = "utils_api"
+	product = "Utility API"
+	version = "1.0"
+)
+
+var (
+	cefHeaderEscaper    = strings.NewReplacer(`\`, `\\`, `|`, `\|`, "\n", " ", "\r", " ")
+	cefExtensionEscaper = strings.NewReplacer(`\`, `\\`, `=`, `\=`, "\n", `\n`, "\r", `\r`)
+	leefHeaderEscaper   = strings.NewReplacer(`|`, ` `, "\n", " ", "\r", " ")
+	leefValueEscaper    = strings.NewReplacer("\t", " ", "\n", " ", "\r", " ")
+)
+
+// FormatCEF renders an event as an ArcSight Common Event Format line.
+func FormatCEF(event Event) string {
+	extension := []string{
+		"rt=" + strconv.FormatInt(event.Time.UnixMilli(), 10),
+		"externalId=" + strconv.FormatUint(event.ID, 10),
+		"cat=" + cefExtensionEscaper.Replace(event.Type),
+	}
+	for _, key := range sortedKeys(event.Data) {
+		extension = append(extension, fieldName(key)+"="+cefExtensionEscaper.Replace(formatValue(event.Data[key])))
+	}
+	return fmt.Sprintf("CEF:0|%s|%s|%s|%s|%s|%d|%s", vendor, product, version,
+		cefHeaderEscaper.Replace(event.Type), cefHeaderEscaper.Replace(event.Name), event.Severity, strings.Join(extension, " "))
+}
+
+// FormatLEEF renders an event as an IBM QRadar Log Event Extended Format 1.0 line, with
+// tab-separated attributes.
+func FormatLEEF(event Event) string {
+	attributes := []string{
+		"devTime=" + event.Time.Format("Jan 02 2006 15:04:05"),
+		"externalId=" + strconv.FormatUint(event.ID, 10),
+		"cat=" + leefValueEscaper.Replace(event.Type),
+		"sev=" + strconv.Itoa(event.Severity),
+		"name=" + leefValueEscaper.Replace(event.Name),
+	}
+	for _, key := range sortedKeys(event.Data) {
+		attributes = append(attributes, fieldName(key)+"="+leefValueEscaper.Replace(formatValue(event.Data[key])))
+	}
+	return fmt.Sprintf("LEEF:1.0|%s|%s|%s|%s|%s", vendor, product, version, leefHeaderEscaper.Replace(event.Type), strings.Join(attributes, "\t"))
+}
+
+func sortedKeys(data map[string]any) []string {
+	keys := make([]string, 0, len(data))
+	for key := range data {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// fieldName reduces a data key to the letters, digits and underscores CEF and LEEF keys
+// allow.
+func fieldName(key string) string {
+	return strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '_' {
+			return r
+		}
+		return '_'
+	}, key)
+}
+
+// formatValue renders scalars as text and anything else as JSON.
+func formatValue(value any) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case int, int64, uint64, float64, bool:
+		return fmt.Sprint(v)
+	}
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprint(value)
+	}
+	return string(encoded)
+}
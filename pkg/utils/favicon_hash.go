@@ -0,0 +1,160 @@
+package utils
+
+import (
+	"context"
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"math/bits"
+	"mime"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// Where the hashed favicon came from.
+const (
+	FaviconSourceGiven    = "given"    // The favicon URL was passed in
+	FaviconSourceDeclared = "declared" // A <link rel="icon"> of the page
+	FaviconSourceDefault  = "default"  // /favicon.ico of the site
+)
+
+// FaviconHash is a favicon and the hashes used to pivot on it in threat-intel tools.
+type FaviconHash struct {
+	FaviconURL  string
+	Source      string
+	ContentType string
+	Size        int
+	MMH3        int32 // Shodan's http.favicon.hash
+	MD5         string
+	SHA256      string
+	CurlCommand string
+}
+
+// HashFavicon downloads a favicon and hashes it. Without faviconURL the icon declared by the
+// page at pageURL is used, falling back to /favicon.ico of its site.
+func HashFavicon(ctx context.Context, pageURL, faviconURL string) (*FaviconHash, error) {
+	source := FaviconSourceGiven
+	if faviconURL == "" {
+		var err error
+		if faviconURL, source, err = discoverFavicon(ctx, pageURL); err != nil {
+			return nil, err
+		}
+	}
+
+	fetchResult, err := FetchURL(ctx, faviconURL)
+	if err != nil {
+		if fetchResult != nil {
+			return &FaviconHash{FaviconURL: faviconURL, Source: source, CurlCommand: fetchResult.CurlCommand}, err
+		}
+		return nil, err
+	}
+	result := &FaviconHash{FaviconURL: faviconURL, Source: source, CurlCommand: fetchResult.CurlCommand}
+	if fetchResult.StatusCode != 200 {
+		return result, fmt.Errorf("failed to fetch favicon %s: received status code %d (%s)", faviconURL, fetchResult.StatusCode, fetchResult.Status)
+	}
+	if len(fetchResult.Body) == 0 {
+		return result, fmt.Errorf("favicon %s is empty", faviconURL)
+	}
+
+	md5Sum := md5.Sum(fetchResult.Body)
+	sha256Sum := sha256.Sum256(fetchResult.Body)
+	result.Size = len(fetchResult.Body)
+	result.MMH3 = FaviconMMH3(fetchResult.Body)
+	result.MD5 = hex.EncodeToString(md5Sum[:])
+	result.SHA256 = hex.EncodeToString(sha256Sum[:])
+	result.ContentType = http.DetectContentType(fetchResult.Body)
+	if mediaType, _, err := mime.ParseMediaType(fetchResult.Headers.Get("Content-Type")); err == nil {
+		result.ContentType = mediaType
+	}
+	return result, nil
+}
+
+// discoverFavicon finds the icon a page declares, or the site's /favicon.ico.
+func discoverFavicon(ctx context.Context, pageURL string) (string, string, error) {
+	parsed, err := url.Parse(pageURL)
+	if err != nil || parsed.Host == "" || (parsed.Scheme != "http" && parsed.Scheme != "https") {
+		return "", "", fmt.Errorf("invalid URL %q", pageURL)
+	}
+	defaultURL := (&url.URL{Scheme: parsed.Scheme, Host: parsed.Host, Path: "/favicon.ico"}).String()
+
+	fetchResult, err := FetchURL(ctx, pageURL)
+	if err != nil || fetchResult.StatusCode != 200 {
+		return defaultURL, FaviconSourceDefault, nil
+	}
+	metadata := ExtractMetadataFromHTML(DecodeResponseBody(fetchResult), fetchResult.FinalURL)
+	for _, favicon := range metadata.Favicons {
+		rels := strings.Fields(strings.ToLower(favicon.Rel))
+		for _, rel := range rels {
+			if rel == "icon" && favicon.URL != "" {
+				return favicon.URL, FaviconSourceDeclared, nil
+			}
+		}
+	}
+	return defaultURL, FaviconSourceDefault, nil
+}
+
+// FaviconMMH3 computes the favicon hash Shodan indexes: the signed 32-bit MurmurHash3 of
+// the favicon's base64 encoding as Python's base64.encodebytes writes it.
+func FaviconMMH3(data []byte) int32 {
+	return int32(murmur3x86_32([]byte(encodeBase64Lines(data)), 0))
+}
+
+// encodeBase64Lines encodes data in base64 with a newline after every 76 characters and at
+// the end.
+func encodeBase64Lines(data []byte) string {
+	encoded := base64.StdEncoding.EncodeToString(data)
+	var b strings.Builder
+	for len(encoded) > 76 {
+		b.WriteString(encoded[:76])
+		b.WriteByte('\n')
+		encoded = encoded[76:]
+	}
+	b.WriteString(encoded)
+	b.WriteByte('\n')
+	return b.String()
+}
+
+// murmur3x86_32 is MurmurHash3's x86 32-bit variant.
+func murmur3x86_32(data []byte, seed uint32) uint32 {
+	const c1, c2 = 0xcc9e2d51, 0x1b873593
+	h := seed
+	blocks := len(data) / 4
+	for i := range blocks {
+		k := binary.LittleEndian.Uint32(data[i*4:])
+		k *= c1
+		k = bits.RotateLeft32(k, 15)
+		k *= c2
+		h ^= k
+		h = bits.RotateLeft32(h, 13)
+		h = h*5 + 0xe6546b64
+	}
+
+	tail := data[blocks*4:]
+	var k uint32
+	switch len(tail) {
+	case 3:
+		k ^= uint32(tail[2]) << 16
+		fallthrough
+	case 2:
+		k ^= uint32(tail[1]) << 8
+		fallthrough
+	case 1:
+		k ^= uint32(tail[0])
+		k *= c1
+		k = bits.RotateLeft32(k, 15)
+		k *= c2
+		h ^= k
+	}
+
+	h ^= uint32(len(data))
+	h ^= h >> 16
+	h *= 0x85ebca6b
+	h ^= h >> 13
+	h *= 0xc2b2ae35
+	h ^= h >> 16
+	return h
+}
@@ -0,0 +1,64 @@
+package utils
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestMurmur3(t *testing.T) {
+	for _, tt := range []struct {
+		input string
+		want  uint32
+	}{
+		{"", 0},
+		{"hello", 0x248bfa47},
+		{"The quick brown fox jumps over the lazy dog", 0x2e4ff723},
+	} {
+		if got := murmur3x86_32([]byte(tt.input), 0); got != tt.want {
+			t.Errorf("murmur3x86_32(%q) = %#x, want %#x", tt.input, got, tt.want)
+		}
+	}
+}
+
+func TestEncodeBase64Lines(t *testing.T) {
+	data := make([]byte, 100)
+	for i := range data {
+		data[i] = byte(i)
+	}
+	// Python: base64.encodebytes(bytes(range(100)))
+	want := "AAECAwQFBgcICQoLDA0ODxAREhMUFRYXGBkaGxwdHh8gISIjJCUmJygpKissLS4vMDEyMzQ1Njc4\nOTo7PD0+P0BBQkNERUZHSElKS0xNTk9QUVJTVFVWV1hZWltcXV5fYGFiYw==\n"
+	if got := encodeBase64Lines(data); got != want {
+		t.Errorf("encodeBase64Lines = %q, want %q", got, want)
+	}
+}
+
+func TestHashFavicon(t *testing.T) {
+	icon := []byte("\x89PNG\r\n\x1a\nnot really a png")
+	mux := http.NewServeMux()
+	mux.HandleFunc("/{$}", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`<html><head><link rel="apple-touch-icon" href="/touch.png"><link rel="icon" href="/static/icon.png"></head></html>`))
+	})
+	mux.HandleFunc("/static/icon.png", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "image/png; charset=binary")
+		w.Write(icon)
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	result, err := HashFavicon(context.Background(), server.URL+"/", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.FaviconURL != server.URL+"/static/icon.png" || result.Source != FaviconSourceDeclared {
+		t.Errorf("favicon = %s (%s), want the declared icon", result.FaviconURL, result.Source)
+	}
+	if result.ContentType != "image/png" || result.Size != len(icon) || result.MMH3 != FaviconMMH3(icon) || len(result.SHA256) != 64 {
+		t.Errorf("result = %+v, want the icon's type, size and hashes", result)
+	}
+
+	if _, err := HashFavicon(context.Background(), server.URL+"/missing", server.URL+"/favicon.ico"); err == nil {
+		t.Error("a favicon returning 404 was hashed")
+	}
+}
@@ -0,0 +1,104 @@
+// Package features switches endpoint groups, or single endpoints, off by configuration, e.g.
+// the outbound scanners in shared environments.
+package features
+
+import (
+	"encoding/json"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// apiPrefix is stripped from request paths before matching; entries are relative to it.
+const apiPrefix = "/api/v1/"
+
+var (
+	mu       sync.RWMutex
+	disabled []string // Sorted entries such as "web" or "net/zone-transfer"
+)
+
+// Configure disables the comma-separated entries: a group such as "web" switches off every
+// endpoint under /api/v1/web, and "net/zone-transfer" a single endpoint with its
+// sub-paths. The health check cannot be disabled.
+func Configure(entries string) {
+	mu.Lock()
+	defer mu.Unlock()
+	disabled = nil
+	for _, entry := range strings.Split(entries, ",") {
+		entry = strings.Trim(strings.ToLower(strings.TrimSpace(entry)), "/")
+		entry = strings.TrimPrefix(entry, strings.Trim(apiPrefix, "/")+"/")
+		if entry == "" || entry == "health" {
+			continue
+		}
+		disabled = append(disabled, entry)
+	}
+	sort.Strings(disabled)
+	if len(disabled) > 0 {
+		log.Printf("Endpoints disabled: %s", strings.Join(disabled, ", "))
+	}
+}
+
+// Disabled returns the disabled entries.
+func Disabled() []string {
+	mu.RLock()
+	defer mu.RUnlock()
+	return append([]string(nil), disabled...)
+}
+
+// Enabled reports whether the endpoint at path, such as /api/v1/net/zone-transfer, is
+// available. Paths outside /api/v1 are always enabled.
+func Enabled(path string) bool {
+	if !strings.HasPrefix(path, apiPrefix) {
+		return true
+	}
+	relative := strings.TrimSuffix(strings.ToLower(strings.TrimPrefix(path, apiPrefix)), "/")
+	mu.RLock()
+	defer mu.RUnlock()
+	for _, entry := range disabled {
+		if relative == entry || strings.HasPrefix(relative, entry+"/") {
+			return false
+		}
+	}
+	return true
+}
+
+// Unmatched returns the disabled entries that match none of the route paths, which are
+// most likely typos.
+func Unmatched(paths []string) []string {
+	var unmatched []string
+	for _, entry := range Disabled() {
+		matched := false
+		for _, path := range paths {
+			relative := strings.TrimPrefix(path, apiPrefix)
+			if relative == entry || strings.HasPrefix(relative, entry+"/") {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			unmatched = append(unmatched, entry)
+		}
+	}
+	return unmatched
+}
+
+// FilterOpenAPI removes the disabled endpoints from a Swagger 2.0 document whose paths are
+// relative to /api/v1.
+func FilterOpenAPI(doc []byte) ([]byte, error) {
+	if len(Disabled()) == 0 {
+		return doc, nil
+	}
+	var spec map[string]any
+	if err := json.Unmarshal(doc, &spec); err != nil {
+		return nil, err
+	}
+	if paths, ok := spec["paths"].(map[string]any); ok {
+		for path := range paths {
+			if !Enabled(strings.TrimSuffix(apiPrefix, "/") + path) {
+				delete(paths, path)
+			}
+		}
+	}
+	return json.Marshal(spec)
+}
@@ -0,0 +1,55 @@
+package features
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestEnabled(t *testing.T) {
+	t.Cleanup(func() { Configure("") })
+	Configure(" Web , /api/v1/net/zone-transfer/, health,, net/whois-lookup/bulk")
+
+	if got, want := Disabled(), []string{"net/whois-lookup/bulk", "net/zone-transfer", "web"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Disabled() = %v, want %v", got, want)
+	}
+	for path, want := range map[string]bool{
+		"/api/v1/web/stack-analyzer":      false,
+		"/api/v1/web":                     false,
+		"/api/v1/net/zone-transfer":       false,
+		"/api/v1/net/zone-transfer-check": true,
+		"/api/v1/net/whois-lookup":        true,
+		"/api/v1/net/whois-lookup/bulk":   false,
+		"/api/v1/health":                  true,
+		"/api/v1/webhooks":                true,
+		"/swagger/index.html":             true,
+	} {
+		if got := Enabled(path); got != want {
+			t.Errorf("Enabled(%s) = %v, want %v", path, got, want)
+		}
+	}
+	if got := Unmatched([]string{"/api/v1/web/har", "/api/v1/net/zone-transfer"}); !reflect.DeepEqual(got, []string{"net/whois-lookup/bulk"}) {
+		t.Errorf("Unmatched() = %v", got)
+	}
+}
+
+func TestFilterOpenAPI(t *testing.T) {
+	t.Cleanup(func() { Configure("") })
+	doc := []byte(`{"basePath": "/api/v1", "paths": {"/health": {}, "/web/har": {}, "/net/ping": {}}}`)
+	if got, err := FilterOpenAPI(doc); err != nil || string(got) != string(doc) {
+		t.Errorf("FilterOpenAPI() with nothing disabled = %s, %v; want the document unchanged", got, err)
+	}
+
+	Configure("web")
+	filtered, err := FilterOpenAPI(doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var spec struct {
+		Paths map[string]any `json:"paths"`
+	}
+	json.Unmarshal(filtered, &spec)
+	if _, ok := spec.Paths["/web/har"]; ok || len(spec.Paths) != 2 {
+		t.Errorf("filtered paths = %v, want /health and /net/ping", spec.Paths)
+	}
+}
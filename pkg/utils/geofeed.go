@@ -0,0 +1,251 @@
+package utils
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Geofeed fetch limits.
+const (
+	geofeedMaxBytes  = 20 << 20
+	geofeedMaxIssues = 500 // Issues and conflicts beyond this are counted but not listed
+)
+
+// Geofeed issue severities.
+const (
+	GeofeedSeverityError   = "error"
+	GeofeedSeverityWarning = "warning"
+)
+
+// geofeedClient fetches caller-supplied feed URLs, so it refuses internal addresses.
+var geofeedClient = &http.Client{Timeout: 30 * time.Second, Transport: NewTargetTransport()}
+
+// GeofeedIssue is a syntax or semantic problem on one line of a geofeed.
+type GeofeedIssue struct {
+	Line     int    `json:"line,omitempty"` // Zero for feed-wide issues
+	Prefix   string `json:"prefix,omitempty"`
+	Severity string `json:"severity"` // error or warning
+	Message  string `json:"message"`
+}
+
+// GeofeedConflict is a geofeed entry whose location disagrees with the loaded GeoIP database.
+type GeofeedConflict struct {
+	Line    int    `json:"line"`
+	Prefix  string `json:"prefix"`
+	Field   string `json:"field"` // country, region or city
+	Geofeed string `json:"geofeed"`
+	MMDB    string `json:"mmdb"`
+}
+
+// GeofeedReport is the result of validating an RFC 8805 geofeed.
+type GeofeedReport struct {
+	URL            string            `json:"url"`
+	Valid          bool              `json:"valid"` // No error-level issues
+	Entries        int               `json:"entries"`
+	IPv4Entries    int               `json:"ipv4_entries"`
+	IPv6Entries    int               `json:"ipv6_entries"`
+	ErrorCount     int               `json:"error_count"`
+	WarningCount   int               `json:"warning_count"`
+	Issues         []GeofeedIssue    `json:"issues"`
+	MMDBChecked    bool              `json:"mmdb_checked"` // False when no City database is loaded
+	MatchedEntries int               `json:"matched_entries"`
+	ConflictCount  int               `json:"conflict_count"`
+	Conflicts      []GeofeedConflict `json:"conflicts"`
+	Truncated      bool              `json:"truncated,omitempty"` // Issues or conflicts were not all listed
+}
+
+// CheckGeofeed fetches a published geofeed CSV, validates it against RFC 8805 and
+// cross-checks each entry's location against the loaded GeoLite2-City database.
+func CheckGeofeed(ctx context.Context, feedURL string) (*GeofeedReport, error) {
+	parsedURL, err := url.Parse(feedURL)
+	if err != nil || (parsedURL.Scheme != "http" && parsedURL.Scheme != "https") || parsedURL.Host == "" {
+		return nil, fmt.Errorf("invalid geofeed URL: %s", feedURL)
+	}
+	if err := CheckTargetURL(ctx, feedURL); err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "GET", feedURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", GetRandomUserAgent())
+	resp, err := geofeedClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch geofeed: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("geofeed URL returned status %d", resp.StatusCode)
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, geofeedMaxBytes+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read geofeed: %w", err)
+	}
+	if len(body) > geofeedMaxBytes {
+		return nil, fmt.Errorf("geofeed is larger than %d bytes", geofeedMaxBytes)
+	}
+
+	report := ValidateGeofeed(body)
+	report.URL = feedURL
+	if parsedURL.Scheme != "https" {
+		report.addIssue(GeofeedIssue{Severity: GeofeedSeverityWarning, Message: "geofeed should be published over HTTPS"})
+	}
+	return report, nil
+}
+
+// ValidateGeofeed parses geofeed CSV data ("ip_prefix,alpha2code,region,city,postal_code"
+// lines, '#' comments) and reports syntax issues and GeoIP database conflicts.
+func ValidateGeofeed(data []byte) *GeofeedReport {
+	report := &GeofeedReport{Issues: []GeofeedIssue{}, Conflicts: []GeofeedConflict{}, MMDBChecked: cityDB.loaded()}
+	seen := make(map[string]int)
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	lineNumber := 0
+	for scanner.Scan() {
+		lineNumber++
+		line := strings.TrimSpace(scanner.Text())
+		if lineNumber == 1 {
+			line = strings.TrimPrefix(line, "\ufeff")
+		}
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		fields := strings.Split(line, ",")
+		for i := range fields {
+			fields[i] = strings.TrimSpace(fields[i])
+		}
+		issue := func(severity, format string, args ...any) {
+			report.addIssue(GeofeedIssue{Line: lineNumber, Prefix: fields[0], Severity: severity, Message: fmt.Sprintf(format, args...)})
+		}
+		if len(fields) > 5 {
+			issue(GeofeedSeverityError, "expected at most 5 fields, found %d", len(fields))
+			continue
+		}
+		for len(fields) < 5 {
+			fields = append(fields, "")
+		}
+		prefixField, country, region, city, postal := fields[0], fields[1], fields[2], fields[3], fields[4]
+
+		ip, network, err := net.ParseCIDR(prefixField)
+		if err != nil {
+			issue(GeofeedSeverityError, "invalid IP prefix")
+			continue
+		}
+		if !ip.Equal(network.IP) {
+			issue(GeofeedSeverityError, "prefix has host bits set (network is %s)", network)
+		}
+		if first, ok := seen[network.String()]; ok {
+			issue(GeofeedSeverityError, "duplicate prefix (first listed on line %d)", first)
+			continue
+		}
+		seen[network.String()] = lineNumber
+		report.Entries++
+		if network.IP.To4() != nil {
+			report.IPv4Entries++
+		} else {
+			report.IPv6Entries++
+		}
+
+		if country != "" && !isAlpha(country, 2) {
+			issue(GeofeedSeverityError, "country %q is not an ISO 3166-1 alpha-2 code", country)
+			country = ""
+		}
+		if region != "" {
+			countryPart, subdivision, ok := strings.Cut(region, "-")
+			switch {
+			case !ok || !isAlpha(countryPart, 2) || subdivision == "" || len(subdivision) > 3:
+				issue(GeofeedSeverityError, "region %q is not an ISO 3166-2 code (e.g., US-CA)", region)
+				region = ""
+			case country == "":
+				issue(GeofeedSeverityWarning, "region is set without a country")
+			case !strings.EqualFold(countryPart, country):
+				issue(GeofeedSeverityError, "region %q does not belong to country %s", region, country)
+			}
+		}
+		if postal != "" {
+			issue(GeofeedSeverityWarning, "postal codes are deprecated and should be left empty (RFC 8805 section 2.1.1.5)")
+		}
+
+		if cityDB.loaded() {
+			report.crossCheck(lineNumber, network, country, region, city)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		report.addIssue(GeofeedIssue{Line: lineNumber + 1, Severity: GeofeedSeverityError, Message: "could not read line: " + err.Error()})
+	}
+	if report.Entries == 0 && report.ErrorCount == 0 {
+		report.addIssue(GeofeedIssue{Severity: GeofeedSeverityWarning, Message: "geofeed has no entries"})
+	}
+	report.Valid = report.ErrorCount == 0
+	return report
+}
+
+// crossCheck compares an entry with the City database record of its network address.
+func (r *GeofeedReport) crossCheck(line int, network *net.IPNet, country, region, city string) {
+	record, err := cityDB.city(network.IP)
+	if err != nil || record.Country.IsoCode == "" {
+		return
+	}
+	conflict := func(field, geofeed, mmdb string) {
+		r.ConflictCount++
+		if len(r.Conflicts) >= geofeedMaxIssues {
+			r.Truncated = true
+			return
+		}
+		r.Conflicts = append(r.Conflicts, GeofeedConflict{Line: line, Prefix: network.String(), Field: field, Geofeed: geofeed, MMDB: mmdb})
+	}
+
+	before := r.ConflictCount
+	if country != "" && !strings.EqualFold(country, record.Country.IsoCode) {
+		conflict("country", strings.ToUpper(country), record.Country.IsoCode)
+	} else {
+		if _, subdivision, ok := strings.Cut(region, "-"); ok && len(record.Subdivisions) > 0 && record.Subdivisions[0].IsoCode != "" &&
+			!strings.EqualFold(subdivision, record.Subdivisions[0].IsoCode) {
+			conflict("region", strings.ToUpper(region), record.Country.IsoCode+"-"+record.Subdivisions[0].IsoCode)
+		}
+		if mmdbCity := record.City.Names["en"]; city != "" && mmdbCity != "" && !strings.EqualFold(city, mmdbCity) {
+			conflict("city", city, mmdbCity)
+		}
+	}
+	if r.ConflictCount == before {
+		r.MatchedEntries++
+	}
+}
+
+func (r *GeofeedReport) addIssue(issue GeofeedIssue) {
+	if issue.Severity == GeofeedSeverityError {
+		r.ErrorCount++
+		r.Valid = false
+	} else {
+		r.WarningCount++
+	}
+	if len(r.Issues) >= geofeedMaxIssues {
+		r.Truncated = true
+		return
+	}
+	r.Issues = append(r.Issues, issue)
+}
+
+// isAlpha reports whether s is exactly n ASCII letters.
+func isAlpha(s string, n int) bool {
+	if len(s) != n {
+		return false
+	}
+	for _, c := range s {
+		if (c < 'A' || c > 'Z') && (c < 'a' || c > 'z') {
+			return false
+		}
+	}
+	return true
+}
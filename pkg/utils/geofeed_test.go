@@ -0,0 +1,155 @@
+package utils
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestValidateGeofeed(t *testing.T) {
+	tests := []struct {
+		name        string
+		feed        string
+		wantEntries [2]int // IPv4, IPv6
+		wantIssues  []GeofeedIssue
+	}{
+		{
+			name:        "valid feed with comments, BOM and short lines",
+			feed:        "\ufeff# prefix,country,region,city,postal\n192.0.2.0/24,US,US-CA,San Francisco,\n\n2001:db8::/32,gb,GB-ENG,London\n198.51.100.0/24\n",
+			wantEntries: [2]int{2, 1},
+			wantIssues:  []GeofeedIssue{},
+		},
+		{
+			name:        "invalid prefix and too many fields",
+			feed:        "192.0.2.0/33,US\nexample.com,US\n192.0.2.0/24,US,US-CA,City,,extra\n",
+			wantEntries: [2]int{0, 0},
+			wantIssues: []GeofeedIssue{
+				{Line: 1, Prefix: "192.0.2.0/33", Severity: GeofeedSeverityError, Message: "invalid IP prefix"},
+				{Line: 2, Prefix: "example.com", Severity: GeofeedSeverityError, Message: "invalid IP prefix"},
+				{Line: 3, Prefix: "192.0.2.0/24", Severity: GeofeedSeverityError, Message: "expected at most 5 fields, found 6"},
+			},
+		},
+		{
+			name:        "host bits and duplicates",
+			feed:        "192.0.2.1/24,US\n192.0.2.0/24,US\n2001:DB8::/32,US\n2001:db8::/32,US\n",
+			wantEntries: [2]int{1, 1},
+			wantIssues: []GeofeedIssue{
+				{Line: 1, Prefix: "192.0.2.1/24", Severity: GeofeedSeverityError, Message: "prefix has host bits set (network is 192.0.2.0/24)"},
+				{Line: 2, Prefix: "192.0.2.0/24", Severity: GeofeedSeverityError, Message: "duplicate prefix (first listed on line 1)"},
+				{Line: 4, Prefix: "2001:db8::/32", Severity: GeofeedSeverityError, Message: "duplicate prefix (first listed on line 3)"},
+			},
+		},
+		{
+			name:        "country and region codes",
+			feed:        "192.0.2.0/25,USA\n192.0.2.128/25,US,CA\n198.51.100.0/25,US,DE-BY\n198.51.100.128/25,,US-CA\n203.0.113.0/25,US,US-ABCD\n203.0.113.128/25,U1\n",
+			wantEntries: [2]int{6, 0},
+			wantIssues: []GeofeedIssue{
+				{Line: 1, Prefix: "192.0.2.0/25", Severity: GeofeedSeverityError, Message: `country "USA" is not an ISO 3166-1 alpha-2 code`},
+				{Line: 2, Prefix: "192.0.2.128/25", Severity: GeofeedSeverityError, Message: `region "CA" is not an ISO 3166-2 code (e.g., US-CA)`},
+				{Line: 3, Prefix: "198.51.100.0/25", Severity: GeofeedSeverityError, Message: `region "DE-BY" does not belong to country US`},
+				{Line: 4, Prefix: "198.51.100.128/25", Severity: GeofeedSeverityWarning, Message: "region is set without a country"},
+				{Line: 5, Prefix: "203.0.113.0/25", Severity: GeofeedSeverityError, Message: `region "US-ABCD" is not an ISO 3166-2 code (e.g., US-CA)`},
+				{Line: 6, Prefix: "203.0.113.128/25", Severity: GeofeedSeverityError, Message: `country "U1" is not an ISO 3166-1 alpha-2 code`},
+			},
+		},
+		{
+			name:        "deprecated postal code",
+			feed:        "192.0.2.0/24,us,us-ca,San Francisco,94107\n",
+			wantEntries: [2]int{1, 0},
+			wantIssues: []GeofeedIssue{
+				{Line: 1, Prefix: "192.0.2.0/24", Severity: GeofeedSeverityWarning, Message: "postal codes are deprecated and should be left empty (RFC 8805 section 2.1.1.5)"},
+			},
+		},
+		{
+			name:        "empty feed",
+			feed:        "# nothing here\n",
+			wantEntries: [2]int{0, 0},
+			wantIssues:  []GeofeedIssue{{Severity: GeofeedSeverityWarning, Message: "geofeed has no entries"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			report := ValidateGeofeed([]byte(tt.feed))
+			if !reflect.DeepEqual(report.Issues, tt.wantIssues) {
+				t.Errorf("Issues = %+v, want %+v", report.Issues, tt.wantIssues)
+			}
+			if got := [2]int{report.IPv4Entries, report.IPv6Entries}; got != tt.wantEntries || report.Entries != got[0]+got[1] {
+				t.Errorf("entries = %d (IPv4, IPv6 %v), want %v", report.Entries, got, tt.wantEntries)
+			}
+			errors, warnings := 0, 0
+			for _, issue := range tt.wantIssues {
+				if issue.Severity == GeofeedSeverityError {
+					errors++
+				} else {
+					warnings++
+				}
+			}
+			if report.ErrorCount != errors || report.WarningCount != warnings || report.Valid != (errors == 0) {
+				t.Errorf("errors %d, warnings %d, valid %v; want %d, %d, %v", report.ErrorCount, report.WarningCount, report.Valid, errors, warnings, errors == 0)
+			}
+		})
+	}
+}
+
+func TestValidateGeofeedTruncatesIssues(t *testing.T) {
+	var feed strings.Builder
+	for i := 0; i < geofeedMaxIssues+10; i++ {
+		fmt.Fprintf(&feed, "10.%d.%d.0/24,XXX\n", i/256, i%256)
+	}
+	report := ValidateGeofeed([]byte(feed.String()))
+	if len(report.Issues) != geofeedMaxIssues || report.ErrorCount != geofeedMaxIssues+10 || !report.Truncated {
+		t.Errorf("listed %d issues of %d (truncated %v), want %d listed of %d", len(report.Issues), report.ErrorCount, report.Truncated, geofeedMaxIssues, geofeedMaxIssues+10)
+	}
+}
+
+func TestCheckGeofeed(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/geofeed.csv" {
+			http.NotFound(w, r)
+			return
+		}
+		fmt.Fprint(w, "192.0.2.0/24,US,US-CA,,\n")
+	}))
+	defer server.Close()
+
+	report, err := CheckGeofeed(context.Background(), server.URL+"/geofeed.csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []GeofeedIssue{{Severity: GeofeedSeverityWarning, Message: "geofeed should be published over HTTPS"}}
+	if report.URL != server.URL+"/geofeed.csv" || report.Entries != 1 || !report.Valid || !reflect.DeepEqual(report.Issues, want) {
+		t.Errorf("CheckGeofeed() = %+v, want one valid entry and an HTTPS warning", report)
+	}
+
+	for _, feedURL := range []string{server.URL + "/missing.csv", "ftp://example.com/geofeed.csv", "not a url"} {
+		if _, err := CheckGeofeed(context.Background(), feedURL); err == nil {
+			t.Errorf("CheckGeofeed(%q) succeeded, want an error", feedURL)
+		}
+	}
+}
+
+func TestCheckGeofeedRefusesInternalAddresses(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+	}))
+	defer server.Close()
+	defer ConfigureSSRFProtection(false, "")
+	ConfigureSSRFProtection(true, "")
+
+	var blocked *SSRFBlockedError
+	for _, feedURL := range []string{server.URL + "/geofeed.csv", "http://169.254.169.254/geofeed.csv"} {
+		if _, err := CheckGeofeed(context.Background(), feedURL); !errors.As(err, &blocked) {
+			t.Errorf("CheckGeofeed(%q) error = %v, want the SSRF refusal", feedURL, err)
+		}
+	}
+	if requests != 0 {
+		t.Errorf("%d requests reached the loopback server", requests)
+	}
+}
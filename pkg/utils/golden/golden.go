@@ -0,0 +1,89 @@
+// Package golden compares parser output in tests against golden JSON files kept next to the
+// fixtures they were produced from. Run the tests with -update to rewrite the golden files
+// after an intended change, and review the diff like any other change.
+package golden
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "rewrite golden files with the current output")
+
+// Fixtures returns the fixture files matching pattern (e.g. testdata/whois/*.txt), failing
+// the test when there are none.
+func Fixtures(t *testing.T, pattern string) []string {
+	t.Helper()
+	files, err := filepath.Glob(pattern)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) == 0 {
+		t.Fatalf("no fixtures match %s", pattern)
+	}
+	return files
+}
+
+// Name returns a fixture's file name without its directory and extension, such as
+// example.co.uk for testdata/whois/example.co.uk.txt.
+func Name(fixture string) string {
+	base := filepath.Base(fixture)
+	return strings.TrimSuffix(base, filepath.Ext(base))
+}
+
+// Assert compares got, encoded as indented JSON, with the golden file of a fixture: the
+// fixture's path with its extension replaced by .golden.json.
+func Assert(t *testing.T, fixture string, got any) {
+	t.Helper()
+	path := strings.TrimSuffix(fixture, filepath.Ext(fixture)) + ".golden.json"
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false) // Keep markup in the golden files readable
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(got); err != nil {
+		t.Fatalf("encoding the output for %s: %v", path, err)
+	}
+	data := buf.Bytes()
+
+	if *update {
+		if err := os.WriteFile(path, data, 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("%v (run the tests with -update to create it)", err)
+	}
+	if !bytes.Equal(want, data) {
+		t.Errorf("output differs from %s (run the tests with -update after an intended change):\n%s", path, diff(string(want), string(data)))
+	}
+}
+
+// diff lists the lines that differ between want and got, with their line numbers.
+func diff(want, got string) string {
+	wantLines, gotLines := strings.Split(want, "\n"), strings.Split(got, "\n")
+	var out strings.Builder
+	shown := 0
+	for i := 0; i < max(len(wantLines), len(gotLines)) && shown < 20; i++ {
+		var w, g string
+		if i < len(wantLines) {
+			w = wantLines[i]
+		}
+		if i < len(gotLines) {
+			g = gotLines[i]
+		}
+		if w == g {
+			continue
+		}
+		out.WriteString("line " + strconv.Itoa(i+1) + ":\n- " + w + "\n+ " + g + "\n")
+		shown++
+	}
+	return out.String()
+}
@@ -0,0 +1,35 @@
+package utils
+
+import (
+	"os"
+	"testing"
+
+	"github.com/vit0-9/utils_api/pkg/utils/golden"
+)
+
+// htmlGoldenOutput is what the HTML parsers find in one page.
+type htmlGoldenOutput struct {
+	Metadata     *PageMetadata
+	Contacts     *ExtractedContacts
+	MixedContent []MixedContentResource
+}
+
+// TestHTMLGolden runs the meta, contact and mixed content extractors over recorded pages in
+// testdata/html and compares the results with the .golden.json files next to them. After an
+// intended parser change, rewrite them with: go test ./pkg/utils -run Golden -update
+func TestHTMLGolden(t *testing.T) {
+	for _, fixture := range golden.Fixtures(t, "testdata/html/*.html") {
+		t.Run(golden.Name(fixture), func(t *testing.T) {
+			body, err := os.ReadFile(fixture)
+			if err != nil {
+				t.Fatal(err)
+			}
+			baseURL := "https://www.example.test/pages/" + golden.Name(fixture)
+			golden.Assert(t, fixture, htmlGoldenOutput{
+				Metadata:     ExtractMetadataFromHTML(body, baseURL),
+				Contacts:     ExtractContactsFromHTML(body, baseURL),
+				MixedContent: ScanMixedContent(body),
+			})
+		})
+	}
+}
@@ -0,0 +1,360 @@
+package utils
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"net/http/cookiejar"
+	"net/http/httptrace"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"golang.org/x/net/publicsuffix"
+)
+
+// HAR types follow the HTTP Archive 1.2 format, so exports open in browser devtools and other HAR tooling.
+
+// HARLog is the root "log" object of a HAR document.
+type HARLog struct {
+	Version string     `json:"version"`
+	Creator HARCreator `json:"creator"`
+	Pages   []HARPage  `json:"pages"`
+	Entries []HAREntry `json:"entries"`
+	Comment string     `json:"comment,omitempty"` // Set when the capture failed part way
+}
+
+// HARCreator identifies the application that produced the HAR.
+type HARCreator struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+}
+
+// HARPage is a page (navigation) the entries belong to.
+type HARPage struct {
+	StartedDateTime time.Time      `json:"startedDateTime"`
+	ID              string         `json:"id"`
+	Title           string         `json:"title"`
+	PageTimings     HARPageTimings `json:"pageTimings"`
+}
+
+// HARPageTimings holds page level timings in milliseconds.
+type HARPageTimings struct {
+	OnContentLoad float64 `json:"onContentLoad"`
+	OnLoad        float64 `json:"onLoad"`
+}
+
+// HAREntry is one request/response exchange.
+type HAREntry struct {
+	Pageref         string      `json:"pageref"`
+	StartedDateTime time.Time   `json:"startedDateTime"`
+	Time            float64     `json:"time"` // Total time in milliseconds
+	Request         HARRequest  `json:"request"`
+	Response        HARResponse `json:"response"`
+	Cache           struct{}    `json:"cache"`
+	Timings         HARTimings  `json:"timings"`
+	ServerIPAddress string      `json:"serverIPAddress,omitempty"`
+	Connection      string      `json:"connection,omitempty"`
+	Comment         string      `json:"comment,omitempty"`
+}
+
+// HARNameValue is a header, cookie or query string pair.
+type HARNameValue struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// HARRequest describes the request sent.
+type HARRequest struct {
+	Method      string         `json:"method"`
+	URL         string         `json:"url"`
+	HTTPVersion string         `json:"httpVersion"`
+	Cookies     []HARNameValue `json:"cookies"`
+	Headers     []HARNameValue `json:"headers"`
+	QueryString []HARNameValue `json:"queryString"`
+	HeadersSize int            `json:"headersSize"`
+	BodySize    int            `json:"bodySize"`
+}
+
+// HARResponse describes the response received.
+type HARResponse struct {
+	Status      int            `json:"status"`
+	StatusText  string         `json:"statusText"`
+	HTTPVersion string         `json:"httpVersion"`
+	Cookies     []HARNameValue `json:"cookies"`
+	Headers     []HARNameValue `json:"headers"`
+	Content     HARContent     `json:"content"`
+	RedirectURL string         `json:"redirectURL"`
+	HeadersSize int            `json:"headersSize"`
+	BodySize    int            `json:"bodySize"`
+}
+
+// HARContent is the decoded response body.
+type HARContent struct {
+	Size        int    `json:"size"`
+	Compression int    `json:"compression,omitempty"`
+	MimeType    string `json:"mimeType"`
+	Text        string `json:"text,omitempty"`
+	Encoding    string `json:"encoding,omitempty"` // "base64" for binary bodies
+}
+
+// HARTimings breaks the entry time down into phases, in milliseconds. -1 means not applicable.
+type HARTimings struct {
+	Blocked float64 `json:"blocked"`
+	DNS     float64 `json:"dns"`
+	Connect float64 `json:"connect"` // Includes SSL, as required by the HAR spec
+	SSL     float64 `json:"ssl"`
+	Send    float64 `json:"send"`
+	Wait    float64 `json:"wait"`
+	Receive float64 `json:"receive"`
+}
+
+const harPageID = "page_1"
+
+// harRecorder is a RoundTripper that records every exchange, including redirect hops, as HAR entries.
+type harRecorder struct {
+	base    http.RoundTripper
+	mu      sync.Mutex
+	entries []HAREntry
+}
+
+// exchangeTrace collects httptrace timestamps for one request.
+type exchangeTrace struct {
+	start, getConn, gotConn   time.Time
+	dnsStart, dnsDone         time.Time
+	connectStart, connectDone time.Time
+	tlsStart, tlsDone         time.Time
+	wroteRequest, firstByte   time.Time
+	reused                    bool
+	remoteAddr                string
+}
+
+func millis(from, to time.Time) float64 {
+	if from.IsZero() || to.IsZero() || to.Before(from) {
+		return -1
+	}
+	return float64(to.Sub(from).Microseconds()) / 1000
+}
+
+func (t *exchangeTrace) clientTrace() *httptrace.ClientTrace {
+	return &httptrace.ClientTrace{
+		GetConn: func(string) { t.getConn = time.Now() },
+		GotConn: func(info httptrace.GotConnInfo) {
+			t.gotConn = time.Now()
+			t.reused = info.Reused
+			if info.Conn != nil {
+				t.remoteAddr = info.Conn.RemoteAddr().String()
+			}
+		},
+		DNSStart: func(httptrace.DNSStartInfo) {
+			if t.dnsStart.IsZero() {
+				t.dnsStart = time.Now()
+			}
+		},
+		DNSDone: func(httptrace.DNSDoneInfo) { t.dnsDone = time.Now() },
+		ConnectStart: func(string, string) {
+			if t.connectStart.IsZero() {
+				t.connectStart = time.Now()
+			}
+		},
+		ConnectDone:          func(string, string, error) { t.connectDone = time.Now() },
+		TLSHandshakeStart:    func() { t.tlsStart = time.Now() },
+		TLSHandshakeDone:     func(tls.ConnectionState, error) { t.tlsDone = time.Now() },
+		WroteRequest:         func(httptrace.WroteRequestInfo) { t.wroteRequest = time.Now() },
+		GotFirstResponseByte: func() { t.firstByte = time.Now() },
+	}
+}
+
+func (r *harRecorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	trace := &exchangeTrace{start: time.Now()}
+	req = req.WithContext(httptrace.WithClientTrace(req.Context(), trace.clientTrace()))
+
+	resp, err := r.base.RoundTrip(req)
+	if err != nil {
+		r.record(harEntry(req, nil, nil, trace, time.Now(), err))
+		return nil, err
+	}
+
+	body, readErr := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	end := time.Now()
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+	r.record(harEntry(req, resp, body, trace, end, readErr))
+	return resp, readErr
+}
+
+func (r *harRecorder) record(entry HAREntry) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.entries = append(r.entries, entry)
+}
+
+func harHeaders(header http.Header) []HARNameValue {
+	pairs := []HARNameValue{}
+	for name, values := range header {
+		for _, value := range values {
+			pairs = append(pairs, HARNameValue{Name: name, Value: value})
+		}
+	}
+	return pairs
+}
+
+func harEntry(req *http.Request, resp *http.Response, body []byte, trace *exchangeTrace, end time.Time, err error) HAREntry {
+	entry := HAREntry{
+		Pageref:         harPageID,
+		StartedDateTime: trace.start,
+		Time:            millis(trace.start, end),
+		Request: HARRequest{
+			Method:      req.Method,
+			URL:         req.URL.String(),
+			HTTPVersion: req.Proto,
+			Cookies:     []HARNameValue{},
+			Headers:     harHeaders(req.Header),
+			QueryString: []HARNameValue{},
+			HeadersSize: -1,
+			BodySize:    0,
+		},
+		Response: HARResponse{
+			Cookies:     []HARNameValue{},
+			Headers:     []HARNameValue{},
+			HeadersSize: -1,
+			BodySize:    -1,
+		},
+	}
+	for _, cookie := range req.Cookies() {
+		entry.Request.Cookies = append(entry.Request.Cookies, HARNameValue{Name: cookie.Name, Value: cookie.Value})
+	}
+	for name, values := range req.URL.Query() {
+		for _, value := range values {
+			entry.Request.QueryString = append(entry.Request.QueryString, HARNameValue{Name: name, Value: value})
+		}
+	}
+	if err != nil {
+		entry.Comment = err.Error()
+	}
+
+	timings := HARTimings{Blocked: -1, DNS: -1, Connect: -1, SSL: -1, Send: -1, Wait: -1, Receive: -1}
+	if !trace.reused {
+		timings.DNS = millis(trace.dnsStart, trace.dnsDone)
+		timings.Connect = millis(trace.connectStart, trace.tlsDone)
+		if timings.Connect < 0 {
+			timings.Connect = millis(trace.connectStart, trace.connectDone)
+		}
+		timings.SSL = millis(trace.tlsStart, trace.tlsDone)
+	}
+	if blocked := millis(trace.start, trace.gotConn); blocked >= 0 {
+		timings.Blocked = blocked - max(timings.DNS, 0) - max(timings.Connect, 0)
+		if timings.Blocked < 0 {
+			timings.Blocked = 0
+		}
+	}
+	timings.Send = millis(trace.gotConn, trace.wroteRequest)
+	timings.Wait = millis(trace.wroteRequest, trace.firstByte)
+	timings.Receive = millis(trace.firstByte, end)
+	entry.Timings = timings
+	if trace.remoteAddr != "" {
+		if host, _, splitErr := net.SplitHostPort(trace.remoteAddr); splitErr == nil {
+			entry.ServerIPAddress = host
+		}
+	}
+
+	if resp == nil {
+		return entry
+	}
+	entry.Request.HTTPVersion = resp.Proto
+	entry.Response.Status = resp.StatusCode
+	entry.Response.StatusText = strings.TrimSpace(strings.TrimPrefix(resp.Status, fmt.Sprint(resp.StatusCode)))
+	entry.Response.HTTPVersion = resp.Proto
+	entry.Response.Headers = harHeaders(resp.Header)
+	for _, cookie := range resp.Cookies() {
+		entry.Response.Cookies = append(entry.Response.Cookies, HARNameValue{Name: cookie.Name, Value: cookie.Value})
+	}
+	entry.Response.RedirectURL = resp.Header.Get("Location")
+	entry.Response.BodySize = len(body)
+
+	decoded := DecodeResponseBody(&FetchResult{Headers: resp.Header, Body: body, FinalURL: req.URL.String()})
+	content := HARContent{
+		Size:        len(decoded),
+		Compression: len(decoded) - len(body),
+		MimeType:    resp.Header.Get("Content-Type"),
+	}
+	if isTextualContent(content.MimeType) && utf8.Valid(decoded) {
+		content.Text = string(decoded)
+	} else if len(decoded) > 0 {
+		content.Text = base64.StdEncoding.EncodeToString(decoded)
+		content.Encoding = "base64"
+	}
+	if content.Compression < 0 {
+		content.Compression = 0
+	}
+	entry.Response.Content = content
+	return entry
+}
+
+func isTextualContent(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return contentType == ""
+	}
+	return strings.HasPrefix(mediaType, "text/") || strings.HasSuffix(mediaType, "json") ||
+		strings.HasSuffix(mediaType, "xml") || strings.HasSuffix(mediaType, "javascript")
+}
+
+// CaptureHAR fetches targetURL with browser-like headers, following redirects,
+// and returns the full request/response log as a HAR document.
+// A partial log is returned alongside the error when the fetch fails part way.
+func CaptureHAR(ctx context.Context, targetURL string) (*HARLog, error) {
+	initializeHTTPClient()
+
+	// Each capture gets its own cookie jar, so one capture never replays another's cookies.
+	jar, err := cookiejar.New(&cookiejar.Options{PublicSuffixList: publicsuffix.List})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cookie jar: %w", err)
+	}
+	recorder := &harRecorder{base: httpClient.Transport}
+	client := &http.Client{
+		Timeout:   httpClient.Timeout,
+		Jar:       jar,
+		Transport: recorder,
+	}
+
+	harLog := &HARLog{
+		Version: "1.2",
+		Creator: HARCreator{Name: "utils_api", Version: "1.0"},
+		Entries: []HAREntry{},
+	}
+	started := time.Now()
+
+	req, err := http.NewRequestWithContext(ctx, "GET", targetURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request for %s: %w", targetURL, err)
+	}
+	setBrowserHeaders(req)
+
+	resp, fetchErr := client.Do(req)
+	if fetchErr == nil {
+		resp.Body.Close()
+	}
+
+	harLog.Pages = []HARPage{{
+		StartedDateTime: started,
+		ID:              harPageID,
+		Title:           targetURL,
+		PageTimings:     HARPageTimings{OnContentLoad: -1, OnLoad: millis(started, time.Now())},
+	}}
+	recorder.mu.Lock()
+	harLog.Entries = append(harLog.Entries, recorder.entries...)
+	recorder.mu.Unlock()
+
+	if fetchErr != nil {
+		return harLog, fmt.Errorf("failed to fetch %s: %w", targetURL, fetchErr)
+	}
+	return harLog, nil
+}
@@ -0,0 +1,56 @@
+package utils
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestCaptureHARDoesNotShareCookies(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, err := r.Cookie("session"); err == nil {
+			w.Header().Set("X-Saw-Cookie", "yes")
+		}
+		http.SetCookie(w, &http.Cookie{Name: "session", Value: "secret", Path: "/"})
+		w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+
+	for i := 0; i < 2; i++ {
+		harLog, err := CaptureHAR(context.Background(), server.URL)
+		if err != nil {
+			t.Fatalf("CaptureHAR() error = %v", err)
+		}
+		if len(harLog.Entries) != 1 {
+			t.Fatalf("CaptureHAR() recorded %d entries, want 1", len(harLog.Entries))
+		}
+		entry := harLog.Entries[0]
+		if len(entry.Request.Cookies) != 0 {
+			t.Errorf("capture %d sent cookies %v from an earlier capture", i+1, entry.Request.Cookies)
+		}
+		for _, header := range entry.Response.Headers {
+			if header.Name == "X-Saw-Cookie" {
+				t.Errorf("capture %d: server received a cookie from an earlier capture", i+1)
+			}
+		}
+	}
+}
+
+func TestCaptureHARFailedRequestKeepsProtocol(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	url := server.URL
+	server.Close() // Connection refused
+
+	harLog, err := CaptureHAR(context.Background(), url)
+	if err == nil {
+		t.Fatal("CaptureHAR() error = nil, want a connection error")
+	}
+	if len(harLog.Entries) != 1 {
+		t.Fatalf("CaptureHAR() recorded %d entries, want 1", len(harLog.Entries))
+	}
+	entry := harLog.Entries[0]
+	if entry.Request.HTTPVersion != "HTTP/1.1" || entry.Comment == "" {
+		t.Errorf("failed entry has HTTPVersion %q and comment %q", entry.Request.HTTPVersion, entry.Comment)
+	}
+}
@@ -0,0 +1,91 @@
+package utils
+
+import (
+	"context"
+	"net"
+	"runtime/debug"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Version and Commit identify the build. Set them with -ldflags
+// "-X github.com/vit0-9/utils_api/pkg/utils.Version=v1.2.3 -X github.com/vit0-9/utils_api/pkg/utils.Commit=abc123";
+// otherwise they come from the module version and VCS stamp Go embeds in the binary.
+var (
+	Version string
+	Commit  string
+)
+
+// BuildInfo identifies the running binary.
+type BuildInfo struct {
+	Version    string `json:"version"`
+	Commit     string `json:"commit,omitempty"`
+	CommitTime string `json:"commit_time,omitempty"`
+	Modified   bool   `json:"modified,omitempty"` // Built from a tree with uncommitted changes
+	GoVersion  string `json:"go_version"`
+}
+
+var (
+	buildInfoOnce sync.Once
+	buildInfo     BuildInfo
+)
+
+// GetBuildInfo returns the version and commit of the binary.
+func GetBuildInfo() BuildInfo {
+	buildInfoOnce.Do(func() {
+		buildInfo = BuildInfo{Version: "(devel)"}
+		if info, ok := debug.ReadBuildInfo(); ok {
+			buildInfo.GoVersion = info.GoVersion
+			if info.Main.Version != "" {
+				buildInfo.Version = info.Main.Version
+			}
+			for _, setting := range info.Settings {
+				switch setting.Key {
+				case "vcs.revision":
+					buildInfo.Commit = setting.Value
+				case "vcs.time":
+					buildInfo.CommitTime = setting.Value
+				case "vcs.modified":
+					buildInfo.Modified = setting.Value == "true"
+				}
+			}
+		}
+		if Version != "" {
+			buildInfo.Version = Version
+		}
+		if Commit != "" {
+			buildInfo.Commit, buildInfo.CommitTime, buildInfo.Modified = Commit, "", false
+		}
+	})
+	return buildInfo
+}
+
+// DefaultOutboundProbeTarget is the host:port dialed to check internet access.
+const DefaultOutboundProbeTarget = "1.1.1.1:443"
+
+var outboundProbeTarget = DefaultOutboundProbeTarget
+
+// ConfigureOutboundProbe sets the comma-separated host:port targets dialed to check outbound
+// internet access; the check passes when any of them answers.
+func ConfigureOutboundProbe(targets string) {
+	if strings.TrimSpace(targets) != "" {
+		outboundProbeTarget = targets
+	}
+}
+
+// ProbeOutbound opens a TCP connection to the probe targets in turn and returns the one that
+// answered and how long it took.
+func ProbeOutbound(ctx context.Context) (target string, latency time.Duration, err error) {
+	dialer := &net.Dialer{Timeout: 3 * time.Second}
+	for _, target = range strings.Split(outboundProbeTarget, ",") {
+		target = strings.TrimSpace(target)
+		start := time.Now()
+		var conn net.Conn
+		if conn, err = dialer.DialContext(ctx, "tcp", target); err == nil {
+			conn.Close()
+			return target, time.Since(start), nil
+		}
+	}
+	return target, 0, err
+}
@@ -0,0 +1,198 @@
+package utils
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// hostBucket is a token bucket holding the remaining request budget for one host.
+type hostBucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// HostThrottle limits outbound requests per target host, shared by every endpoint,
+// so crawling-style features don't hammer a site and get the service blocked.
+type HostThrottle struct {
+	name         string // Namespaces the throttle's buckets in Redis
+	mu           sync.Mutex
+	buckets      map[string]*hostBucket
+	perMinute    float64
+	maxIdleHosts int
+}
+
+// hostThrottle is nil when per-host throttling is disabled.
+var hostThrottle *HostThrottle
+
+// ConfigureHostThrottle enables per-host throttling at requestsPerMinute. Zero or less disables it.
+func ConfigureHostThrottle(requestsPerMinute int) {
+	if requestsPerMinute <= 0 {
+		hostThrottle = nil
+		return
+	}
+	hostThrottle = NewHostThrottle("outbound", requestsPerMinute)
+	log.Printf("Outbound per-host throttling enabled: %d requests per host per minute", requestsPerMinute)
+}
+
+// NewHostThrottle returns a throttle allowing requestsPerMinute per host, for protocols
+// that do not go through the shared HTTP transports. With shared state in Redis, the budget
+// is shared by every replica using the same name.
+func NewHostThrottle(name string, requestsPerMinute int) *HostThrottle {
+	return &HostThrottle{
+		name:         name,
+		buckets:      make(map[string]*hostBucket),
+		perMinute:    float64(requestsPerMinute),
+		maxIdleHosts: 10000,
+	}
+}
+
+// reserve takes a token for host and returns how long the caller must wait before using it.
+// An unreachable Redis falls back to the process's own buckets.
+func (t *HostThrottle) reserve(host string, now time.Time) time.Duration {
+	if sharedRedis != nil {
+		wait, err := t.reserveShared(host, now)
+		if err == nil {
+			return wait
+		}
+		log.Printf("ERROR: Could not reserve a shared rate limit slot for %s: %v. Using the local limit.", host, err)
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	bucket, ok := t.buckets[host]
+	if !ok {
+		if len(t.buckets) >= t.maxIdleHosts {
+			t.pruneLocked(now)
+		}
+		bucket = &hostBucket{tokens: t.perMinute, last: now}
+		t.buckets[host] = bucket
+	}
+
+	refillPerSecond := t.perMinute / 60
+	bucket.tokens += now.Sub(bucket.last).Seconds() * refillPerSecond
+	if bucket.tokens > t.perMinute {
+		bucket.tokens = t.perMinute
+	}
+	bucket.last = now
+
+	// Tokens may go negative: each waiting caller queues behind the previous ones.
+	bucket.tokens--
+	if bucket.tokens >= 0 {
+		return 0
+	}
+	return time.Duration(-bucket.tokens / refillPerSecond * float64(time.Second))
+}
+
+// pruneLocked drops buckets that have fully refilled, since they carry no state.
+func (t *HostThrottle) pruneLocked(now time.Time) {
+	for host, bucket := range t.buckets {
+		if bucket.tokens+now.Sub(bucket.last).Minutes()*t.perMinute >= t.perMinute {
+			delete(t.buckets, host)
+		}
+	}
+}
+
+// release returns an unused token, e.g. when the caller gave up waiting.
+func (t *HostThrottle) release(host string) {
+	if sharedRedis != nil {
+		if _, err := sharedRedis.Do(context.Background(), "HINCRBYFLOAT", t.sharedKey(host), "tokens", "1"); err == nil {
+			return
+		}
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if bucket, ok := t.buckets[host]; ok {
+		bucket.tokens++
+	}
+}
+
+// reserveScript runs the token bucket of reserve atomically in Redis. The bucket expires
+// once it would have refilled, like the local buckets pruned by pruneLocked.
+const reserveScript = `
+local rate = tonumber(ARGV[1])
+local now = tonumber(ARGV[2])
+local bucket = redis.call('HMGET', KEYS[1], 'tokens', 'last')
+local tokens = tonumber(bucket[1]) or rate
+local last = tonumber(bucket[2]) or now
+tokens = math.min(rate, tokens + (now - last) * rate / 60000) - 1
+redis.call('HSET', KEYS[1], 'tokens', tostring(tokens), 'last', tostring(now))
+redis.call('PEXPIRE', KEYS[1], 60000 + math.ceil(math.max(0, -tokens) * 60000 / rate))
+if tokens >= 0 then return 0 end
+return math.ceil(-tokens * 60000 / rate)`
+
+// reserveShared takes a token from the host's bucket in Redis.
+func (t *HostThrottle) reserveShared(host string, now time.Time) (time.Duration, error) {
+	reply, err := sharedRedis.Do(context.Background(), "EVAL", reserveScript, "1", t.sharedKey(host),
+		strconv.FormatFloat(t.perMinute, 'f', -1, 64), strconv.FormatInt(now.UnixMilli(), 10))
+	if err != nil {
+		return 0, err
+	}
+	waitMillis, ok := reply.(int64)
+	if !ok {
+		return 0, fmt.Errorf("unexpected reply %v", reply)
+	}
+	return time.Duration(waitMillis) * time.Millisecond, nil
+}
+
+func (t *HostThrottle) sharedKey(host string) string {
+	return "utils_api:throttle:" + t.name + ":" + host
+}
+
+// WaitForHostSlot blocks until a request to host is allowed by the per-host throttle,
+// or returns an error if ctx ends first.
+func WaitForHostSlot(ctx context.Context, host string) error {
+	return hostThrottle.Wait(ctx, host)
+}
+
+// Wait blocks until a request to host is allowed, or returns an error if ctx ends first
+// or would end before the slot comes up. A nil throttle never waits.
+func (t *HostThrottle) Wait(ctx context.Context, host string) error {
+	if t == nil {
+		return nil
+	}
+	host = strings.ToLower(host)
+	wait := t.reserve(host, time.Now())
+	if wait <= 0 {
+		return nil
+	}
+	if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < wait {
+		t.release(host)
+		return fmt.Errorf("outbound rate limit for %s exceeded: next slot in %s", host, wait.Round(time.Second))
+	}
+
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		t.release(host)
+		return fmt.Errorf("outbound rate limit for %s: %w", host, ctx.Err())
+	}
+}
+
+// throttledTransport applies the per-host throttle to every request, including redirect hops,
+// and reports each request's connection timing to the caller's TimingRecorder.
+type throttledTransport struct {
+	base http.RoundTripper
+}
+
+func (t *throttledTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if OfflineMode() {
+		return offlineRoundTrip(req)
+	}
+	if err := WaitForHostSlot(req.Context(), req.URL.Hostname()); err != nil {
+		return nil, err
+	}
+	resp, err := t.base.RoundTrip(traceTiming(req))
+	if err != nil {
+		TimingRecorderFrom(req.Context()).AddFailure(err)
+	}
+	return resp, err
+}
@@ -0,0 +1,140 @@
+package utils
+
+import (
+	"bytes"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"strings"
+	"time"
+)
+
+//go:embed html_report.tmpl
+var htmlReportTemplateText string
+
+var htmlReportTemplate = template.Must(template.New("report").Parse(htmlReportTemplateText))
+
+// reportNode is a JSON value prepared for the report renderers. Objects keep the field
+// order of the JSON response so reports read in the same order as the API output.
+type reportNode struct {
+	Kind   string // "object", "list", "text", "bool" or "empty"
+	Text   string
+	Fields []reportField
+	Items  []reportNode
+}
+
+type reportField struct {
+	Label string
+	Value reportNode
+}
+
+// RenderHTMLReport renders a report response as a self-contained HTML page (inline CSS,
+// no external assets), so it can be opened in a browser or saved and shared as one file.
+func RenderHTMLReport(title string, report any) ([]byte, error) {
+	root, err := prepareReport(report)
+	if err != nil {
+		return nil, err
+	}
+
+	var page bytes.Buffer
+	err = htmlReportTemplate.Execute(&page, struct {
+		Title       string
+		GeneratedAt string
+		Root        reportNode
+	}{
+		Title:       title,
+		GeneratedAt: time.Now().UTC().Format(time.RFC1123),
+		Root:        root,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to render report: %w", err)
+	}
+	return page.Bytes(), nil
+}
+
+// prepareReport converts a report response into the node tree shared by the HTML and PDF
+// renderers.
+func prepareReport(report any) (reportNode, error) {
+	data, err := json.Marshal(report)
+	if err != nil {
+		return reportNode{}, fmt.Errorf("failed to encode report: %w", err)
+	}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	root, err := decodeReportNode(decoder)
+	if err != nil {
+		return reportNode{}, fmt.Errorf("failed to prepare report: %w", err)
+	}
+	return root, nil
+}
+
+// decodeReportNode reads the next JSON value from the decoder, preserving object key order.
+func decodeReportNode(decoder *json.Decoder) (reportNode, error) {
+	token, err := decoder.Token()
+	if err != nil {
+		return reportNode{}, err
+	}
+
+	switch value := token.(type) {
+	case json.Delim:
+		if value == '{' {
+			node := reportNode{Kind: "object"}
+			for decoder.More() {
+				keyToken, err := decoder.Token()
+				if err != nil {
+					return node, err
+				}
+				child, err := decodeReportNode(decoder)
+				if err != nil {
+					return node, err
+				}
+				if child.Kind == "empty" {
+					continue
+				}
+				node.Fields = append(node.Fields, reportField{Label: reportLabel(keyToken.(string)), Value: child})
+			}
+			_, err := decoder.Token() // Closing brace
+			if len(node.Fields) == 0 {
+				node.Kind = "empty"
+			}
+			return node, err
+		}
+		node := reportNode{Kind: "list"}
+		for decoder.More() {
+			child, err := decodeReportNode(decoder)
+			if err != nil {
+				return node, err
+			}
+			node.Items = append(node.Items, child)
+		}
+		_, err := decoder.Token() // Closing bracket
+		if len(node.Items) == 0 {
+			node.Kind = "empty"
+		}
+		return node, err
+	case string:
+		if value == "" {
+			return reportNode{Kind: "empty"}, nil
+		}
+		return reportNode{Kind: "text", Text: value}, nil
+	case json.Number:
+		return reportNode{Kind: "text", Text: value.String()}, nil
+	case bool:
+		if value {
+			return reportNode{Kind: "bool", Text: "Yes"}, nil
+		}
+		return reportNode{Kind: "bool", Text: "No"}, nil
+	default: // null
+		return reportNode{Kind: "empty"}, nil
+	}
+}
+
+// reportLabel turns a JSON key such as "days_until_expiry" into "Days until expiry".
+func reportLabel(key string) string {
+	label := strings.ReplaceAll(key, "_", " ")
+	if label == "" {
+		return label
+	}
+	return strings.ToUpper(label[:1]) + label[1:]
+}
@@ -1,13 +1,20 @@
 package utils
 
 import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"context"
 	"crypto/tls"
+	"errors"
 	"fmt"
 	"io"
+	"log"
 	"math/rand"
 	"net"
 	"net/http"
 	"net/http/cookiejar"
+	"strings"
 	"sync"
 	"time"
 
@@ -31,14 +38,8 @@ var defaultUserAgents = []string{
 var (
 	httpClient     *http.Client
 	httpClientOnce sync.Once
-	randSource     rand.Source
 )
 
-func init() {
-	// Initialize random source for user agent selection
-	randSource = rand.NewSource(time.Now().UnixNano())
-}
-
 // initializeHTTPClient creates a shared HTTP client with good defaults.
 func initializeHTTPClient() {
 	httpClientOnce.Do(func() {
@@ -51,14 +52,15 @@ func initializeHTTPClient() {
 
 		// Custom transport with more browser-like settings
 		transport := &http.Transport{
+			Proxy: proxyForTargetRequest, // The configured egress proxy, if any
 			TLSClientConfig: &tls.Config{
 				MinVersion: tls.VersionTLS12, // Enforce modern TLS
 				// CipherSuites: you can specify a list of cipher suites if needed for very specific targets
 			},
-			DialContext: (&net.Dialer{
+			DialContext: TargetDialContext(&net.Dialer{
 				Timeout:   15 * time.Second, // Connection timeout
 				KeepAlive: 30 * time.Second,
-			}).DialContext,
+			}), // Enforces the outbound allow/deny policy and the SSRF protection
 			MaxIdleConns:          100,
 			MaxIdleConnsPerHost:   10, // More realistic than default 2 for browsers
 			IdleConnTimeout:       90 * time.Second,
@@ -70,7 +72,7 @@ func initializeHTTPClient() {
 		httpClient = &http.Client{
 			Timeout:   30 * time.Second, // Overall request timeout
 			Jar:       jar,
-			Transport: transport,
+			Transport: &throttledTransport{base: transport}, // Per-host politeness throttling
 			// Default redirect policy: follow up to 10 redirects.
 			// If you need to prevent redirects for specific utilities,
 			// you'd create a request and use client.Do(req) with a client
@@ -80,10 +82,59 @@ func initializeHTTPClient() {
 	})
 }
 
+// NewOutboundTransport returns a transport for outbound API calls made outside the shared
+// page-fetching client. It applies the outbound policy and per-host throttling, and uses the
+// proxy set with ConfigureOutboundProxy rather than the proxies from the environment, whose
+// targets the policy could not check.
+func NewOutboundTransport() http.RoundTripper {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = proxyForRequest
+	transport.DialContext = PolicyDialContext(&net.Dialer{Timeout: 15 * time.Second, KeepAlive: 30 * time.Second})
+	return &throttledTransport{base: transport}
+}
+
+// NewTargetTransport returns an outbound transport for requests to caller-supplied URLs,
+// which also applies the SSRF protection to every connection.
+func NewTargetTransport() http.RoundTripper {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = proxyForTargetRequest
+	transport.DialContext = TargetDialContext(&net.Dialer{Timeout: 15 * time.Second, KeepAlive: 30 * time.Second})
+	return &throttledTransport{base: transport}
+}
+
 // GetRandomUserAgent selects a User-Agent string randomly from the predefined list.
 func GetRandomUserAgent() string {
-	r := rand.New(randSource) // Create a new rand.Rand for thread-safety if this func is called concurrently often
-	return defaultUserAgents[r.Intn(len(defaultUserAgents))]
+	return defaultUserAgents[rand.Intn(len(defaultUserAgents))] // The top-level functions are safe for concurrent use
+}
+
+// setBrowserHeaders sets the common headers a browser sends for a page navigation.
+func setBrowserHeaders(req *http.Request) {
+	req.Header.Set("User-Agent", GetRandomUserAgent())
+	req.Header.Set("Accept", "text/html,application/xhtml+xml,application/xml;q=0.9,image/avif,image/webp,image/apng,*/*;q=0.8,application/signed-exchange;v=b3;q=0.9")
+	req.Header.Set("Accept-Language", "en-US,en;q=0.9")
+	req.Header.Set("Accept-Encoding", "gzip, deflate, br")
+	req.Header.Set("Connection", "keep-alive")
+	req.Header.Set("DNT", "1") // Do Not Track
+	req.Header.Set("Upgrade-Insecure-Requests", "1")
+}
+
+// Defaults of FetchOptions.
+const (
+	DefaultFetchTimeout  = 30 * time.Second
+	DefaultFetchMaxBytes = 10 << 20
+	DefaultFetchBackoff  = 500 * time.Millisecond
+)
+
+// FetchOptions adjusts a fetch; the zero value fetches as FetchURL does.
+type FetchOptions struct {
+	Method   string        // GET when empty
+	Timeout  time.Duration // Per attempt, redirects included; DefaultFetchTimeout when zero
+	MaxBytes int64         // Body bytes read before it is truncated; DefaultFetchMaxBytes when zero
+	Retries  int           // Further attempts after a connection error or a 429, 502, 503 or 504
+	Backoff  time.Duration // Wait before the first retry, doubled for each next one; DefaultFetchBackoff when zero
+	Headers  http.Header   // Added to the browser headers, replacing those with the same name
+
+	NoRedirects bool // Return the first response instead of following redirects
 }
 
 // FetchResult encapsulates the results of an HTTP fetch operation.
@@ -92,46 +143,202 @@ type FetchResult struct {
 	Status     string
 	Headers    http.Header
 	Body       []byte
+	Truncated  bool   // The body was cut at the fetch's MaxBytes, or decoding it at DefaultFetchMaxBytes
 	FinalURL   string // URL after all redirects
+
+	CurlCommand string // Equivalent curl command for the request that was sent
 }
 
 // FetchURL performs an HTTP GET request to the targetURL with browser-like headers
 // and returns the response details.
-func FetchURL(targetURL string) (*FetchResult, error) {
+func FetchURL(ctx context.Context, targetURL string) (*FetchResult, error) {
+	return FetchURLWithOptions(ctx, targetURL, FetchOptions{})
+}
+
+// FetchURLWithOptions fetches targetURL with browser-like headers like FetchURL, with the
+// method, timeout, body size limit, retries and extra headers of options.
+func FetchURLWithOptions(ctx context.Context, targetURL string, options FetchOptions) (*FetchResult, error) {
 	initializeHTTPClient() // Ensure our shared client is initialized
+	if options.Method == "" {
+		options.Method = http.MethodGet
+	}
+	if options.Timeout <= 0 {
+		options.Timeout = DefaultFetchTimeout
+	}
+	if options.MaxBytes <= 0 {
+		options.MaxBytes = DefaultFetchMaxBytes
+	}
+	if options.Backoff <= 0 {
+		options.Backoff = DefaultFetchBackoff
+	}
+	client := *httpClient // Shares the transport and cookie jar
+	client.Timeout = options.Timeout
+	if options.NoRedirects {
+		client.CheckRedirect = func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }
+	}
 
-	req, err := http.NewRequest("GET", targetURL, nil)
+	req, err := http.NewRequestWithContext(ctx, options.Method, targetURL, nil)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create request for %s: %w", targetURL, err)
 	}
+	setBrowserHeaders(req)
+	for name, values := range options.Headers {
+		req.Header[http.CanonicalHeaderKey(name)] = values
+	}
+	proxy := ""
+	if proxyURL := outboundProxyFor(ctx); proxyURL != nil {
+		proxy = proxyURL.Redacted()
+	}
+	curlCommand := BuildCurlCommand(req.Method, targetURL, req.Header, proxy, !options.NoRedirects)
 
-	// Set common browser headers
-	req.Header.Set("User-Agent", GetRandomUserAgent())
-	req.Header.Set("Accept", "text/html,application/xhtml+xml,application/xml;q=0.9,image/avif,image/webp,image/apng,*/*;q=0.8,application/signed-exchange;v=b3;q=0.9")
-	req.Header.Set("Accept-Language", "en-US,en;q=0.9")
-	req.Header.Set("Accept-Encoding", "gzip, deflate, br")
-	req.Header.Set("Connection", "keep-alive")
-	req.Header.Set("DNT", "1") // Do Not Track
-	req.Header.Set("Upgrade-Insecure-Requests", "1")
+	backoff := options.Backoff
+	for attempt := 0; ; attempt++ {
+		resp, err := client.Do(req)
+		if attempt < options.Retries && retryableFetch(resp, err) {
+			if resp != nil {
+				resp.Body.Close()
+			}
+			TimingRecorderFrom(ctx).AddRetry()
+			select {
+			case <-time.After(backoff):
+			case <-ctx.Done():
+				return &FetchResult{CurlCommand: curlCommand}, fmt.Errorf("failed to fetch %s: %w", targetURL, ctx.Err())
+			}
+			backoff *= 2
+			continue
+		}
+		if err != nil {
+			return &FetchResult{CurlCommand: curlCommand}, fmt.Errorf("failed to fetch %s: %w", targetURL, err)
+		}
+		defer resp.Body.Close()
 
-	resp, err := httpClient.Do(req)
-	if err != nil {
-		return nil, fmt.Errorf("failed to fetch %s: %w", targetURL, err)
+		bodyBytes, err := io.ReadAll(io.LimitReader(resp.Body, options.MaxBytes+1))
+		if err != nil {
+			return &FetchResult{CurlCommand: curlCommand}, fmt.Errorf("failed to read response body from %s: %w", targetURL, err)
+		}
+		result := &FetchResult{
+			StatusCode:  resp.StatusCode,
+			Status:      resp.Status,
+			Headers:     resp.Header,
+			Body:        bodyBytes,
+			FinalURL:    resp.Request.URL.String(), // URL after redirects
+			CurlCommand: curlCommand,
+		}
+		if int64(len(bodyBytes)) > options.MaxBytes {
+			result.Body, result.Truncated = bodyBytes[:options.MaxBytes], true
+		}
+		return result, nil
 	}
-	defer resp.Body.Close()
+}
 
-	bodyBytes, err := io.ReadAll(resp.Body)
+// retryableFetch reports whether a fetch attempt failed in a way another attempt may not:
+// a connection error other than a refusal by the outbound policy or SSRF protection, or a
+// rate-limited or unavailable response.
+func retryableFetch(resp *http.Response, err error) bool {
 	if err != nil {
-		return nil, fmt.Errorf("failed to read response body from %s: %w", targetURL, err)
+		var denied *PolicyDeniedError
+		var blocked *SSRFBlockedError
+		return !errors.As(err, &denied) && !errors.As(err, &blocked) && !errors.Is(err, ErrOffline) && !errors.Is(err, context.Canceled)
+	}
+	switch resp.StatusCode {
+	case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+// Decompressors are pooled: each holds tens of kilobytes of window and Huffman tables, which
+// batch endpoints would otherwise allocate for every body.
+var (
+	gzipReaders sync.Pool // *gzip.Reader
+	zlibReaders sync.Pool // io.ReadCloser implementing zlib.Resetter
+)
+
+// maxDecompressPrealloc caps the buffer sized up front from the compressed length.
+const maxDecompressPrealloc = 1 << 20
+
+// decompressBody decompresses a gzip or deflate (zlib) body with a pooled reader.
+func decompressBody(encoding string, body []byte, limit int64) ([]byte, bool, error) {
+	source := bytes.NewReader(body)
+	var reader io.Reader
+	switch encoding {
+	case "gzip":
+		gzReader, _ := gzipReaders.Get().(*gzip.Reader)
+		if gzReader == nil {
+			var err error
+			if gzReader, err = gzip.NewReader(source); err != nil {
+				return nil, false, fmt.Errorf("failed to create gzip reader: %w", err)
+			}
+		} else if err := gzReader.Reset(source); err != nil {
+			gzipReaders.Put(gzReader)
+			return nil, false, fmt.Errorf("failed to create gzip reader: %w", err)
+		}
+		defer gzipReaders.Put(gzReader)
+		reader = gzReader
+	default:
+		zlibReader, _ := zlibReaders.Get().(io.ReadCloser)
+		if zlibReader == nil {
+			var err error
+			if zlibReader, err = zlib.NewReader(source); err != nil {
+				return nil, false, fmt.Errorf("failed to create deflate reader: %w", err)
+			}
+		} else if err := zlibReader.(zlib.Resetter).Reset(source, nil); err != nil {
+			zlibReaders.Put(zlibReader)
+			return nil, false, fmt.Errorf("failed to create deflate reader: %w", err)
+		}
+		defer zlibReaders.Put(zlibReader)
+		reader = zlibReader
+	}
+
+	var decompressed bytes.Buffer
+	decompressed.Grow(min(4*len(body), maxDecompressPrealloc)) // Text compresses about 4:1
+	// One byte over the limit tells a body of exactly limit bytes from a longer one
+	if _, err := decompressed.ReadFrom(io.LimitReader(reader, limit+1)); err != nil {
+		return nil, false, fmt.Errorf("failed to read %s decompressed body: %w", encoding, err)
+	}
+	if int64(decompressed.Len()) > limit {
+		return decompressed.Bytes()[:limit], true, nil
+	}
+	return decompressed.Bytes(), false, nil
+}
+
+// DecodeResponseBody returns the fetched body decompressed according to its Content-Encoding.
+// FetchURL sets Accept-Encoding itself, so the transport does not decompress transparently.
+// On unsupported encodings or decompression errors the original body is returned. The
+// decompressed body is cut at DefaultFetchMaxBytes, setting Truncated, so a small compressed
+// response cannot expand without bound.
+func DecodeResponseBody(fetchResult *FetchResult) []byte {
+	bodyToProcess := fetchResult.Body
+	finalURL := fetchResult.FinalURL
+	var errDecompress error
+
+	contentEncoding := ""
+	if fetchResult.Headers != nil {
+		contentEncoding = strings.ToLower(strings.TrimSpace(fetchResult.Headers.Get("Content-Encoding")))
 	}
 
-	result := &FetchResult{
-		StatusCode: resp.StatusCode,
-		Status:     resp.Status,
-		Headers:    resp.Header,
-		Body:       bodyBytes,
-		FinalURL:   resp.Request.URL.String(), // URL after redirects
+	switch contentEncoding {
+	case "gzip", "deflate":
+		decompressedBody, truncated, err := decompressBody(contentEncoding, fetchResult.Body, DefaultFetchMaxBytes)
+		if err == nil {
+			bodyToProcess = decompressedBody
+			if truncated {
+				fetchResult.Truncated = true
+			}
+		} else {
+			errDecompress = err
+		}
+	case "br":
+		log.Printf("Warning: Brotli (br) Content-Encoding detected for %s. Standard library does not support Brotli. Body might remain compressed.", finalURL)
+		// Brotli decompression would require an external library, e.g., github.com/andybalholm/brotli
+	case "":
+		// No Content-Encoding or it's an identity encoding. Body is likely plain.
+	default:
+		log.Printf("Warning: Unsupported Content-Encoding '%s' for %s. Using original body.", contentEncoding, finalURL)
 	}
 
-	return result, nil
+	if errDecompress != nil {
+		log.Printf("Warning: Decompression error for %s (encoding: %s): %v. Using original body.", finalURL, contentEncoding, errDecompress)
+	}
+	return bodyToProcess
 }
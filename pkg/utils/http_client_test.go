@@ -0,0 +1,134 @@
+package utils
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestFetchURLWithOptions(t *testing.T) {
+	var attempts atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/flaky":
+			if attempts.Add(1) < 3 {
+				w.WriteHeader(http.StatusServiceUnavailable)
+				return
+			}
+		case "/redirect":
+			http.Redirect(w, r, "/echo", http.StatusFound)
+			return
+		case "/echo":
+			w.Header().Set("X-Method", r.Method)
+			w.Header().Set("X-Custom", r.Header.Get("X-Custom"))
+		}
+		w.Write([]byte(strings.Repeat("a", 100)))
+	}))
+	defer server.Close()
+
+	result, err := FetchURLWithOptions(context.Background(), server.URL+"/echo", FetchOptions{
+		Method:   http.MethodHead,
+		Headers:  http.Header{"x-custom": {"value"}},
+		MaxBytes: 10,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Headers.Get("X-Method") != http.MethodHead || result.Headers.Get("X-Custom") != "value" || len(result.Body) != 0 {
+		t.Errorf("HEAD fetch: headers %v, body %q", result.Headers, result.Body)
+	}
+	if !strings.Contains(result.CurlCommand, "curl -sS -I ") {
+		t.Errorf("curl command = %q", result.CurlCommand)
+	}
+
+	result, err = FetchURLWithOptions(context.Background(), server.URL+"/echo", FetchOptions{MaxBytes: 10})
+	if err != nil || len(result.Body) != 10 || !result.Truncated {
+		t.Errorf("truncated fetch: body %q, truncated %v, err %v", result.Body, result.Truncated, err)
+	}
+
+	ctx, recorder := WithTimingRecorder(context.Background())
+	result, err = FetchURLWithOptions(ctx, server.URL+"/flaky", FetchOptions{Retries: 3, Backoff: time.Millisecond})
+	if err != nil || result.StatusCode != http.StatusOK || recorder.Timing(0).Retries != 2 {
+		t.Errorf("retried fetch: status %d, retries %d, err %v", result.StatusCode, recorder.Timing(0).Retries, err)
+	}
+
+	result, err = FetchURLWithOptions(context.Background(), server.URL+"/redirect", FetchOptions{NoRedirects: true})
+	if err != nil || result.StatusCode != http.StatusFound || result.Headers.Get("Location") != "/echo" || result.FinalURL != server.URL+"/redirect" {
+		t.Errorf("fetch without redirects: status %d, final URL %q, err %v", result.StatusCode, result.FinalURL, err)
+	}
+	if strings.Contains(result.CurlCommand, " -L ") {
+		t.Errorf("curl command follows redirects: %q", result.CurlCommand)
+	}
+
+	attempts.Store(0)
+	result, err = FetchURLWithOptions(context.Background(), server.URL+"/flaky", FetchOptions{})
+	if err != nil || result.StatusCode != http.StatusServiceUnavailable {
+		t.Errorf("fetch without retries: status %d, err %v", result.StatusCode, err)
+	}
+}
+
+// compressedPage returns a page of HTML compressed with an encoding.
+func compressedPage(t testing.TB, encoding string) (page, compressed []byte) {
+	t.Helper()
+	page = bytes.Repeat([]byte("<div class=\"product\"><a href=\"/p/42\">Boots</a></div>\n"), 500)
+	var buf bytes.Buffer
+	var writer io.WriteCloser = gzip.NewWriter(&buf)
+	if encoding == "deflate" {
+		writer = zlib.NewWriter(&buf)
+	}
+	writer.Write(page)
+	writer.Close()
+	return page, buf.Bytes()
+}
+
+func TestDecodeResponseBody(t *testing.T) {
+	for _, encoding := range []string{"gzip", "deflate"} {
+		page, compressed := compressedPage(t, encoding)
+		headers := http.Header{"Content-Encoding": {encoding}}
+		// Twice, so the second decode uses a pooled reader
+		for range 2 {
+			if got := DecodeResponseBody(&FetchResult{Body: compressed, Headers: headers}); !bytes.Equal(got, page) {
+				t.Errorf("%s: DecodeResponseBody() returned %d bytes, want the %d byte page", encoding, len(got), len(page))
+			}
+		}
+		corrupt := append([]byte(nil), compressed[:len(compressed)/2]...)
+		if got := DecodeResponseBody(&FetchResult{Body: corrupt, Headers: headers}); !bytes.Equal(got, corrupt) {
+			t.Errorf("%s: DecodeResponseBody() of a truncated body did not return it unchanged", encoding)
+		}
+	}
+}
+
+func TestDecodeResponseBodyLimitsDecompression(t *testing.T) {
+	var bomb bytes.Buffer
+	writer := gzip.NewWriter(&bomb)
+	writer.Write(make([]byte, DefaultFetchMaxBytes+1<<20))
+	writer.Close()
+
+	result := &FetchResult{Body: bomb.Bytes(), Headers: http.Header{"Content-Encoding": {"gzip"}}}
+	if got := DecodeResponseBody(result); len(got) != DefaultFetchMaxBytes || !result.Truncated {
+		t.Errorf("DecodeResponseBody() of a %d byte bomb returned %d bytes, truncated %v; want %d and truncated", bomb.Len(), len(got), result.Truncated, DefaultFetchMaxBytes)
+	}
+
+	_, compressed := compressedPage(t, "gzip")
+	result = &FetchResult{Body: compressed, Headers: http.Header{"Content-Encoding": {"gzip"}}}
+	if DecodeResponseBody(result); result.Truncated {
+		t.Error("DecodeResponseBody() marked a small page truncated")
+	}
+}
+
+func BenchmarkDecodeResponseBody(b *testing.B) {
+	_, compressed := compressedPage(b, "gzip")
+	result := &FetchResult{Body: compressed, Headers: http.Header{"Content-Encoding": {"gzip"}}}
+	b.ReportAllocs()
+	for b.Loop() {
+		DecodeResponseBody(result)
+	}
+}
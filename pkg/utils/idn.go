@@ -0,0 +1,262 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"unicode"
+
+	"golang.org/x/net/idna"
+	"golang.org/x/text/unicode/norm"
+)
+
+// Homograph risk levels of a host.
+const (
+	HomographRiskNone   = "none"   // ASCII only
+	HomographRiskLow    = "low"    // Internationalized, in one script, without lookalikes of ASCII
+	HomographRiskMedium = "medium" // Mixed scripts, or characters that look like ASCII letters
+	HomographRiskHigh   = "high"   // Spoofs an ASCII name: lookalikes mixed with Latin, or a whole label of them
+)
+
+// confusables maps characters of other scripts, and Latin letters that are not ASCII, to the
+// ASCII letter they are mistaken for, after the IDNA mapping has lowercased and NFKC
+// normalized the host. It covers the lookalikes used in homograph attacks rather than the
+// whole Unicode confusables list.
+var confusables = map[rune]rune{
+	// Cyrillic
+	'а': 'a', 'в': 'b', 'е': 'e', 'ё': 'e', 'һ': 'h', 'і': 'i', 'ї': 'i', 'ј': 'j', 'к': 'k',
+	'ӏ': 'l', 'м': 'm', 'п': 'n', 'о': 'o', 'р': 'p', 'ԛ': 'q', 'г': 'r', 'ѕ': 's', 'т': 't',
+	'у': 'y', 'ү': 'y', 'х': 'x', 'ԁ': 'd', 'ԝ': 'w', 'ь': 'b', 'ѵ': 'v', 'ԍ': 'g',
+	// Greek
+	'α': 'a', 'β': 'b', 'ε': 'e', 'η': 'n', 'ι': 'i', 'κ': 'k', 'μ': 'u', 'ν': 'v', 'ο': 'o',
+	'ρ': 'p', 'τ': 't', 'υ': 'u', 'χ': 'x', 'γ': 'y', 'ω': 'w',
+	// Armenian
+	'օ': 'o', 'ս': 'u', 'հ': 'h', 'ո': 'n', 'զ': 'q', 'ց': 'g', 'ք': 'p', 'ա': 'w',
+	// Latin letters that are not ASCII
+	'ı': 'i', 'ɩ': 'i', 'ɪ': 'i', 'ɑ': 'a', 'ɡ': 'g', 'ĸ': 'k', 'ɴ': 'n', 'ʀ': 'r', 'ʏ': 'y',
+	'ᴄ': 'c', 'ᴅ': 'd', 'ᴇ': 'e', 'ᴊ': 'j', 'ᴋ': 'k', 'ᴍ': 'm', 'ᴏ': 'o', 'ᴘ': 'p', 'ᴛ': 't',
+	'ᴜ': 'u', 'ᴠ': 'v', 'ᴡ': 'w', 'ᴢ': 'z', 'ƅ': 'b', 'ɢ': 'g', 'ʜ': 'h', 'ʟ': 'l', 'ꜱ': 's',
+	'ł': 'l', 'đ': 'd', 'ħ': 'h', 'ŧ': 't', 'ø': 'o',
+}
+
+// homoglyphs maps ASCII letters to the characters of the confusables table that look like them.
+var homoglyphs = func() map[rune][]rune {
+	lookalikes := make(map[rune][]rune)
+	for r, ascii := range confusables {
+		lookalikes[ascii] = append(lookalikes[ascii], r)
+	}
+	for _, runes := range lookalikes {
+		slices.Sort(runes)
+	}
+	return lookalikes
+}()
+
+// Homoglyphs returns the characters of other scripts, and Latin letters that are not ASCII,
+// that look like the ASCII letter r, such as Cyrillic а and Greek α for a.
+func Homoglyphs(r rune) []rune {
+	return homoglyphs[r]
+}
+
+// allowedScriptSets are the script combinations that are normal within one label (UTS #39
+// "highly restrictive"): Latin with the scripts of Chinese, Japanese or Korean.
+var allowedScriptSets = [][]string{
+	{"Han", "Hiragana", "Katakana", "Latin"},
+	{"Bopomofo", "Han", "Latin"},
+	{"Han", "Hangul", "Latin"},
+}
+
+// ConfusableChar is a character that looks like an ASCII letter.
+type ConfusableChar struct {
+	Char      string `json:"char" example:"а"`
+	CodePoint string `json:"code_point" example:"U+0430"`
+	Script    string `json:"script" example:"Cyrillic"`
+	LooksLike string `json:"looks_like" example:"a"`
+	Label     int    `json:"label"`  // Index of the label, from the left
+	Offset    int    `json:"offset"` // Character offset within the label
+}
+
+// IDNLabel is one label of a host.
+type IDNLabel struct {
+	ASCII       string   `json:"ascii" example:"xn--pypal-4ve"`
+	Unicode     string   `json:"unicode" example:"pаypal"`
+	Scripts     []string `json:"scripts"` // Scripts of its letters, without Common and Inherited
+	MixedScript bool     `json:"mixed_script"`
+}
+
+// IDNAnalysis is a host in its ASCII (punycode) and Unicode forms with its homograph risk.
+type IDNAnalysis struct {
+	Input       string           `json:"input" example:"pаypal.com"`
+	ASCII       string           `json:"ascii" example:"xn--pypal-4ve.com"`
+	Unicode     string           `json:"unicode" example:"pаypal.com"`
+	IDN         bool             `json:"idn"` // Some label is internationalized
+	Labels      []IDNLabel       `json:"labels"`
+	Scripts     []string         `json:"scripts"`
+	MixedScript bool             `json:"mixed_script"` // A label mixes scripts that are not normally combined
+	Confusables []ConfusableChar `json:"confusables,omitempty"`
+	// The host with every confusable and accented letter replaced by the ASCII letter it
+	// looks like: the name a homograph imitates
+	Skeleton      string   `json:"skeleton" example:"paypal.com"`
+	HomographRisk string   `json:"homograph_risk" example:"high"`
+	Reasons       []string `json:"reasons,omitempty"`
+}
+
+// AnalyzeIDN converts a host between its punycode and Unicode forms, in either direction, and
+// flags homograph risks: labels mixing scripts and characters that look like ASCII letters.
+func AnalyzeIDN(host string) (*IDNAnalysis, error) {
+	input := host
+	host = strings.TrimSuffix(strings.TrimSpace(host), ".")
+	if host == "" {
+		return nil, errors.New("host is empty")
+	}
+	ascii, err := idna.Lookup.ToASCII(host)
+	if err != nil {
+		return nil, fmt.Errorf("invalid internationalized host: %w", err)
+	}
+	unicodeHost, err := idna.Lookup.ToUnicode(ascii)
+	if err != nil {
+		return nil, fmt.Errorf("invalid internationalized host: %w", err)
+	}
+
+	analysis := &IDNAnalysis{Input: input, ASCII: ascii, Unicode: unicodeHost, Scripts: []string{}}
+	asciiLabels, unicodeLabels := strings.Split(ascii, "."), strings.Split(unicodeHost, ".")
+	hostScripts := map[string]bool{}
+	skeletonLabels := make([]string, len(unicodeLabels))
+	wholeScriptSpoof := false
+	for i, label := range unicodeLabels {
+		idnLabel := IDNLabel{ASCII: asciiLabels[i], Unicode: label, Scripts: []string{}}
+		analysis.IDN = analysis.IDN || strings.HasPrefix(idnLabel.ASCII, "xn--")
+		scripts := map[string]bool{}
+		var skeleton strings.Builder
+		confusablesInLabel := 0
+		offset := 0
+		for _, r := range norm.NFD.String(label) {
+			if unicode.Is(unicode.Mn, r) { // Accents: é is read as e
+				continue
+			}
+			script := runeScript(r)
+			if script != "" {
+				scripts[script] = true
+			}
+			lookalike, confusable := confusables[r]
+			switch {
+			case confusable:
+				confusablesInLabel++
+				skeleton.WriteRune(lookalike)
+				analysis.Confusables = append(analysis.Confusables, ConfusableChar{
+					Char:      string(r),
+					CodePoint: fmt.Sprintf("U+%04X", r),
+					Script:    script,
+					LooksLike: string(lookalike),
+					Label:     i,
+					Offset:    offset,
+				})
+			default:
+				skeleton.WriteRune(r)
+			}
+			offset++
+		}
+		skeletonLabels[i] = skeleton.String()
+
+		for script := range scripts {
+			idnLabel.Scripts = append(idnLabel.Scripts, script)
+			hostScripts[script] = true
+		}
+		sort.Strings(idnLabel.Scripts)
+		idnLabel.MixedScript = !allowedScripts(idnLabel.Scripts)
+		analysis.MixedScript = analysis.MixedScript || idnLabel.MixedScript
+		// A label written wholly in lookalikes, such as Cyrillic "аррӏе"
+		if confusablesInLabel > 0 && !scripts["Latin"] && isASCIIName(skeletonLabels[i]) {
+			wholeScriptSpoof = true
+		}
+		analysis.Labels = append(analysis.Labels, idnLabel)
+	}
+	for script := range hostScripts {
+		analysis.Scripts = append(analysis.Scripts, script)
+	}
+	sort.Strings(analysis.Scripts)
+	analysis.Skeleton = strings.Join(skeletonLabels, ".")
+
+	mixedConfusables := false
+	for _, label := range analysis.Labels {
+		if label.MixedScript && len(analysis.Confusables) > 0 {
+			mixedConfusables = true
+		}
+	}
+	switch {
+	case !analysis.IDN:
+		analysis.HomographRisk = HomographRiskNone
+	case mixedConfusables:
+		analysis.HomographRisk = HomographRiskHigh
+		analysis.Reasons = append(analysis.Reasons, "mixes scripts with characters that look like ASCII letters")
+	case wholeScriptSpoof:
+		analysis.HomographRisk = HomographRiskHigh
+		analysis.Reasons = append(analysis.Reasons, "a label is written entirely in characters that look like ASCII letters")
+	case analysis.MixedScript:
+		analysis.HomographRisk = HomographRiskMedium
+		analysis.Reasons = append(analysis.Reasons, "mixes scripts that are not normally combined")
+	case isASCIIName(analysis.Skeleton):
+		analysis.HomographRisk = HomographRiskMedium
+		analysis.Reasons = append(analysis.Reasons, "reads as the ASCII name "+analysis.Skeleton)
+	default:
+		analysis.HomographRisk = HomographRiskLow
+	}
+	return analysis, nil
+}
+
+// runeScript returns the script of a letter, or "" for characters of the Common and
+// Inherited scripts such as digits and the hyphen.
+func runeScript(r rune) string {
+	if r < 0x80 {
+		if unicode.IsLetter(r) {
+			return "Latin"
+		}
+		return ""
+	}
+	for _, name := range scriptNames {
+		if unicode.Is(unicode.Scripts[name], r) {
+			return name
+		}
+	}
+	return ""
+}
+
+// scriptNames lists the scripts runeScript reports, in a fixed order.
+var scriptNames = func() []string {
+	var names []string
+	for name := range unicode.Scripts {
+		if name != "Common" && name != "Inherited" {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}()
+
+// allowedScripts reports whether a label's scripts may appear together.
+func allowedScripts(scripts []string) bool {
+	if len(scripts) <= 1 {
+		return true
+	}
+	for _, allowed := range allowedScriptSets {
+		subset := true
+		for _, script := range scripts {
+			subset = subset && slices.Contains(allowed, script)
+		}
+		if subset {
+			return true
+		}
+	}
+	return false
+}
+
+// isASCIIName reports whether a host or label only has ASCII letters, digits, hyphens and dots.
+func isASCIIName(name string) bool {
+	for _, r := range name {
+		if !(r >= 'a' && r <= 'z' || r >= '0' && r <= '9' || r == '-' || r == '.') {
+			return false
+		}
+	}
+	return name != ""
+}
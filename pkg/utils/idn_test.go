@@ -0,0 +1,48 @@
+package utils
+
+import "testing"
+
+func TestAnalyzeIDN(t *testing.T) {
+	tests := []struct {
+		host, ascii, unicode, skeleton, risk string
+		mixed                                bool
+		confusables                          int
+	}{
+		{"Example.com.", "example.com", "example.com", "example.com", HomographRiskNone, false, 0},
+		{"bücher.example", "xn--bcher-kva.example", "bücher.example", "bucher.example", HomographRiskMedium, false, 0},
+		{"xn--80ak6aa92e.com", "xn--80ak6aa92e.com", "аррӏе.com", "apple.com", HomographRiskHigh, false, 5}, // Cyrillic only
+		{"pаypal.com", "xn--pypal-4ve.com", "pаypal.com", "paypal.com", HomographRiskHigh, true, 1},         // Cyrillic а
+		{"münchen-日本.jp", "xn--mnchen--n2a7163upxc.jp", "münchen-日本.jp", "munchen-日本.jp", HomographRiskLow, false, 0},
+		{"пример.рф", "xn--e1afmkfd.xn--p1ai", "пример.рф", "npиmep.pф", HomographRiskLow, false, 6},
+		{"σπίτι.gr", "xn--kxautlh.gr", "σπίτι.gr", "σπiti.gr", HomographRiskLow, false, 3},
+		{"abcδ.com", "xn--abc-2xc.com", "abcδ.com", "abcδ.com", HomographRiskMedium, true, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.host, func(t *testing.T) {
+			got, err := AnalyzeIDN(tt.host)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got.ASCII != tt.ascii || got.Unicode != tt.unicode || got.Skeleton != tt.skeleton {
+				t.Errorf("ascii %q, unicode %q, skeleton %q; want %q, %q, %q", got.ASCII, got.Unicode, got.Skeleton, tt.ascii, tt.unicode, tt.skeleton)
+			}
+			if got.HomographRisk != tt.risk || got.MixedScript != tt.mixed || len(got.Confusables) != tt.confusables {
+				t.Errorf("risk %s, mixed %v, confusables %+v; want %s, %v, %d", got.HomographRisk, got.MixedScript, got.Confusables, tt.risk, tt.mixed, tt.confusables)
+			}
+		})
+	}
+
+	got, _ := AnalyzeIDN("pаypal.com")
+	if c := got.Confusables[0]; c.Char != "а" || c.CodePoint != "U+0430" || c.Script != "Cyrillic" || c.LooksLike != "a" || c.Label != 0 || c.Offset != 1 {
+		t.Errorf("confusable = %+v", c)
+	}
+	if want := []string{"Cyrillic", "Latin"}; len(got.Labels[0].Scripts) != 2 || got.Labels[0].Scripts[0] != want[0] || got.Labels[0].Scripts[1] != want[1] {
+		t.Errorf("label scripts = %v, want %v", got.Labels[0].Scripts, want)
+	}
+
+	for _, host := range []string{"", "xn--a.com", "a_b.com", "-ab.com"} {
+		if _, err := AnalyzeIDN(host); err == nil {
+			t.Errorf("AnalyzeIDN(%q) error = nil", host)
+		}
+	}
+}
@@ -0,0 +1,396 @@
+// Package ingest accepts batches of URLs, domains and IP addresses pushed by external
+// systems and runs the configured analyses on each in the background, tagging the results
+// with the caller's correlation ID, so the API can act as an enrichment worker in SOAR
+// pipelines.
+package ingest
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/vit0-9/utils_api/pkg/utils"
+	"github.com/vit0-9/utils_api/pkg/utils/callback"
+	"github.com/vit0-9/utils_api/pkg/utils/domain"
+	"github.com/vit0-9/utils_api/pkg/utils/lifecycle"
+	"github.com/vit0-9/utils_api/pkg/utils/notifications"
+)
+
+// Item kinds.
+const (
+	KindURL    = "url"
+	KindDomain = "domain"
+	KindIP     = "ip"
+)
+
+// Batch statuses.
+const (
+	StatusQueued    = "queued"
+	StatusCompleted = "completed"
+)
+
+const (
+	// MaxItems caps the items in one batch.
+	MaxItems = 100
+
+	workers          = 4
+	queueSize        = 1000 // Items waiting across all batches
+	maxStoredBatches = 100
+	itemTimeout      = 2 * time.Minute
+)
+
+// APIKeyHeader carries the API key on ingestion requests.
+const APIKeyHeader = "X-API-Key"
+
+// Errors returned by Submit and CheckAPIKey.
+var (
+	ErrQueueFull     = errors.New("the ingestion queue is full, retry later")
+	ErrDisabled      = errors.New("ingestion is disabled: no API keys are configured")
+	ErrInvalidAPIKey = errors.New("missing or invalid API key")
+)
+
+// domainNameRegex accepts host names with at least two labels.
+var domainNameRegex = regexp.MustCompile(`^([a-z0-9]([a-z0-9_-]{0,61}[a-z0-9])?\.)+[a-z0-9-]{2,63}$`)
+
+// analysis is one enrichment that applies to some item kinds.
+type analysis struct {
+	kinds map[string]bool
+	run   func(ctx context.Context, item Item) (any, error)
+}
+
+// analyses are the enrichments an item can get. URLs run the domain analyses on their host.
+var analyses = map[string]analysis{
+	"dns": {
+		kinds: map[string]bool{KindDomain: true, KindURL: true},
+		run: func(ctx context.Context, item Item) (any, error) {
+			records, lookupErrors := utils.LookupDNSRecords(ctx, item.host(), []string{"A", "AAAA", "MX", "NS", "TXT"})
+			if len(records) == 0 && len(lookupErrors) > 0 {
+				return nil, fmt.Errorf("all %d DNS lookups failed", len(lookupErrors))
+			}
+			return records, nil
+		},
+	},
+	"whois": {
+		kinds: map[string]bool{KindDomain: true},
+		run: func(ctx context.Context, item Item) (any, error) {
+			return domain.GetWhoisInfo(ctx, item.Value)
+		},
+	},
+	"ssl": {
+		kinds: map[string]bool{KindDomain: true, KindURL: true},
+		run: func(ctx context.Context, item Item) (any, error) {
+			return domain.GetSSLInfo(ctx, item.host())
+		},
+	},
+	"blacklist": {
+		kinds: map[string]bool{KindDomain: true, KindIP: true},
+		run: func(ctx context.Context, item Item) (any, error) {
+			return utils.CheckBlacklists(ctx, utils.DefaultResolver(), item.Value)
+		},
+	},
+	"ip-info": {
+		kinds: map[string]bool{KindIP: true},
+		run: func(ctx context.Context, item Item) (any, error) {
+			return utils.GetBasicIPInfo(item.Value), nil
+		},
+	},
+	"stack": {
+		kinds: map[string]bool{KindURL: true},
+		run: func(ctx context.Context, item Item) (any, error) {
+			fetchResult, err := utils.FetchURL(ctx, item.Value)
+			if err != nil {
+				return nil, err
+			}
+			return utils.AnalyzeFetchedStack(item.Value, fetchResult)
+		},
+	},
+	"redirects": {
+		kinds: map[string]bool{KindURL: true},
+		run: func(ctx context.Context, item Item) (any, error) {
+			return utils.ResolveRedirect(ctx, item.Value)
+		},
+	},
+}
+
+// Analyses lists the supported analysis names.
+func Analyses() []string {
+	names := make([]string, 0, len(analyses))
+	for name := range analyses {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+var (
+	configMu        sync.RWMutex
+	apiKeys         [][]byte
+	defaultAnalyses []string // Run when a batch names none; all analyses when empty
+)
+
+// Configure sets the API keys accepted by the ingestion endpoints and the analyses run by
+// default, both comma-separated. Without keys, ingestion is disabled.
+func Configure(keys, defaults string) {
+	configMu.Lock()
+	defer configMu.Unlock()
+	apiKeys = nil
+	for _, key := range strings.Split(keys, ",") {
+		if key = strings.TrimSpace(key); key != "" {
+			apiKeys = append(apiKeys, []byte(key))
+		}
+	}
+	defaultAnalyses = nil
+	for _, name := range strings.Split(defaults, ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+		if _, ok := analyses[name]; !ok {
+			log.Printf("ERROR: Unknown ingestion analysis %q ignored. Use one of %s.", name, strings.Join(Analyses(), ", "))
+			continue
+		}
+		defaultAnalyses = append(defaultAnalyses, name)
+	}
+	if len(apiKeys) > 0 {
+		log.Printf("URL ingestion enabled with %d API keys", len(apiKeys))
+	}
+}
+
+// CheckAPIKey reports whether key is one of the configured API keys.
+func CheckAPIKey(key string) error {
+	configMu.RLock()
+	defer configMu.RUnlock()
+	if len(apiKeys) == 0 {
+		return ErrDisabled
+	}
+	for _, valid := range apiKeys {
+		if subtle.ConstantTimeCompare([]byte(key), valid) == 1 {
+			return nil
+		}
+	}
+	return ErrInvalidAPIKey
+}
+
+// Item is one URL, domain or IP address to analyse.
+type Item struct {
+	Value string `json:"value"`
+	Kind  string `json:"kind"`
+}
+
+// host returns the domain to run domain analyses on.
+func (item Item) host() string {
+	if item.Kind == KindURL {
+		if parsed, err := url.Parse(item.Value); err == nil {
+			return parsed.Hostname()
+		}
+	}
+	return item.Value
+}
+
+// classify detects the kind of an item and normalizes it.
+func classify(value string) (Item, error) {
+	value = strings.TrimSpace(value)
+	if ip := net.ParseIP(value); ip != nil {
+		return Item{Value: ip.String(), Kind: KindIP}, nil
+	}
+	if strings.Contains(value, "://") {
+		parsed, err := url.Parse(value)
+		if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Hostname() == "" {
+			return Item{}, fmt.Errorf("invalid URL %q: only http and https URLs are supported", value)
+		}
+		return Item{Value: value, Kind: KindURL}, nil
+	}
+	name := strings.TrimSuffix(strings.ToLower(value), ".")
+	if len(name) > 253 || !domainNameRegex.MatchString(name) {
+		return Item{}, fmt.Errorf("%q is not a URL, domain name or IP address", value)
+	}
+	return Item{Value: name, Kind: KindDomain}, nil
+}
+
+// ItemResult is the outcome of the analyses of one item.
+type ItemResult struct {
+	Item
+	Results map[string]any    `json:"results,omitempty"` // By analysis name
+	Errors  map[string]string `json:"errors,omitempty"`  // By analysis name
+}
+
+// Batch is a set of items submitted together.
+type Batch struct {
+	ID            string       `json:"id"`
+	CorrelationID string       `json:"correlation_id,omitempty"`
+	CallbackURL   string       `json:"callback_url,omitempty"` // Receives the completed batch
+	Analyses      []string     `json:"analyses"`
+	Status        string       `json:"status"`
+	ReceivedAt    time.Time    `json:"received_at"`
+	FinishedAt    *time.Time   `json:"finished_at,omitempty"`
+	Total         int          `json:"total"`
+	Done          int          `json:"done"`
+	Items         []ItemResult `json:"items"`
+}
+
+// Submit validates a batch and queues its items. Analyses not applicable to an item's kind
+// are skipped for that item. The completed batch is also POSTed to callbackURL, if set.
+func Submit(correlationID string, values []string, analysisNames []string, callbackURL string) (Batch, error) {
+	if len(values) == 0 {
+		return Batch{}, fmt.Errorf("at least one item is required")
+	}
+	if len(values) > MaxItems {
+		return Batch{}, fmt.Errorf("too many items: maximum is %d", MaxItems)
+	}
+	names, err := selectAnalyses(analysisNames)
+	if err != nil {
+		return Batch{}, err
+	}
+	if callbackURL != "" {
+		if err := callback.Validate(callbackURL); err != nil {
+			return Batch{}, err
+		}
+	}
+	batch := &Batch{
+		ID:            newBatchID(),
+		CorrelationID: correlationID,
+		CallbackURL:   callbackURL,
+		Analyses:      names,
+		Status:        StatusQueued,
+		ReceivedAt:    time.Now().UTC(),
+		Total:         len(values),
+		Items:         make([]ItemResult, len(values)),
+	}
+	for i, value := range values {
+		item, err := classify(value)
+		if err != nil {
+			return Batch{}, err
+		}
+		batch.Items[i] = ItemResult{Item: item}
+	}
+
+	if err := currentQueue().submit(batch); err != nil {
+		return Batch{}, err
+	}
+	workersOnce.Do(startWorkers)
+	return *batch, nil
+}
+
+func selectAnalyses(requested []string) ([]string, error) {
+	if len(requested) == 0 {
+		configMu.RLock()
+		requested = defaultAnalyses
+		configMu.RUnlock()
+		if len(requested) == 0 {
+			return Analyses(), nil
+		}
+	}
+	seen := make(map[string]bool)
+	var names []string
+	for _, name := range requested {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if _, ok := analyses[name]; !ok {
+			return nil, fmt.Errorf("unknown analysis %q: use one of %s", name, strings.Join(Analyses(), ", "))
+		}
+		if !seen[name] {
+			seen[name] = true
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+func newBatchID() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// startWorkers starts the workers, which stop taking items when the server shuts down.
+func startWorkers() {
+	lifecycle.Register("ingestion workers", func(ctx context.Context) error { return lifecycle.Wait(ctx, &runningWorkers) })
+	for w := 0; w < workers; w++ {
+		runningWorkers.Add(1)
+		go func() {
+			defer runningWorkers.Done()
+			for !lifecycle.Stopping() {
+				q := currentQueue()
+				next, ok := q.next()
+				if !ok {
+					continue
+				}
+				result := analyse(next)
+				if lifecycle.Stopping() && len(result.Errors) > 0 {
+					// Possibly cut short: put the item back for a worker of the next process or another replica
+					if err := q.requeue(next); err != nil {
+						log.Printf("ERROR: Could not requeue item %d of batch %s: %v", next.Index, next.BatchID, err)
+					}
+					return
+				}
+				completed, err := q.complete(next, result)
+				if err != nil {
+					log.Printf("ERROR: Could not save the result of item %d of batch %s: %v", next.Index, next.BatchID, err)
+					continue
+				}
+				if completed != nil {
+					notifyCompleted(*completed)
+				}
+			}
+		}()
+	}
+}
+
+// analyse runs the batch's analyses that apply to one item.
+func analyse(next work) ItemResult {
+	ctx, cancel := context.WithTimeout(lifecycle.Context(), itemTimeout)
+	defer cancel()
+	result := ItemResult{Item: next.Item, Results: make(map[string]any)}
+	for _, name := range next.Analyses {
+		analysis, ok := analyses[name]
+		if !ok || !analysis.kinds[next.Item.Kind] {
+			continue
+		}
+		value, err := analysis.run(ctx, next.Item)
+		if err != nil {
+			if result.Errors == nil {
+				result.Errors = make(map[string]string)
+			}
+			result.Errors[name] = err.Error()
+			continue
+		}
+		result.Results[name] = value
+	}
+	return result
+}
+
+// notifyCompleted sends the job.completed notification of a batch and its callback.
+func notifyCompleted(batch Batch) {
+	if batch.CallbackURL != "" {
+		callback.Deliver(batch.CallbackURL, "ingest.batch.completed", batch)
+	}
+	failed := 0
+	for _, result := range batch.Items {
+		if len(result.Errors) > 0 {
+			failed++
+		}
+	}
+	duration := batch.FinishedAt.Sub(batch.ReceivedAt)
+	notifications.Notify(notifications.Notification{
+		Event:   notifications.EventJobCompleted,
+		Title:   "Ingested batch analysed",
+		Message: fmt.Sprintf("%d items analysed in %s, %d with errors", batch.Total, duration.Round(time.Millisecond), failed),
+		Data:    map[string]any{"job": "ingest", "id": batch.ID, "correlation_id": batch.CorrelationID, "count": batch.Total, "failed": failed, "duration_ms": duration.Milliseconds()},
+	})
+}
+
+// Get returns a batch by ID.
+func Get(id string) (Batch, bool) {
+	return currentQueue().get(id)
+}
@@ -0,0 +1,124 @@
+package ingest
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestClassify(t *testing.T) {
+	tests := []struct {
+		value, kind, normalized string
+	}{
+		{"192.0.2.1", KindIP, "192.0.2.1"},
+		{"2001:DB8::1", KindIP, "2001:db8::1"},
+		{"https://example.com/login", KindURL, "https://example.com/login"},
+		{" Example.COM. ", KindDomain, "example.com"},
+	}
+	for _, tt := range tests {
+		item, err := classify(tt.value)
+		if err != nil || item.Kind != tt.kind || item.Value != tt.normalized {
+			t.Errorf("classify(%q) = %+v, %v; want %s %s", tt.value, item, err, tt.kind, tt.normalized)
+		}
+	}
+	for _, value := range []string{"", "localhost", "ftp://example.com", "https://", "not a domain.com"} {
+		if _, err := classify(value); err == nil {
+			t.Errorf("classify(%q) succeeded, want an error", value)
+		}
+	}
+}
+
+func TestCheckAPIKey(t *testing.T) {
+	t.Cleanup(func() { Configure("", "") })
+	Configure("", "")
+	if err := CheckAPIKey("anything"); !errors.Is(err, ErrDisabled) {
+		t.Errorf("without keys CheckAPIKey = %v, want ErrDisabled", err)
+	}
+	Configure(" key-one , key-two", "")
+	if err := CheckAPIKey("key-two"); err != nil {
+		t.Errorf("CheckAPIKey(key-two) = %v, want nil", err)
+	}
+	for _, key := range []string{"", "key-on", "key-one "} {
+		if err := CheckAPIKey(key); !errors.Is(err, ErrInvalidAPIKey) {
+			t.Errorf("CheckAPIKey(%q) = %v, want ErrInvalidAPIKey", key, err)
+		}
+	}
+}
+
+func TestSelectAnalyses(t *testing.T) {
+	t.Cleanup(func() { Configure("", "") })
+	Configure("", "whois, bogus,dns")
+	names, err := selectAnalyses(nil)
+	if err != nil || strings.Join(names, ",") != "dns,whois" {
+		t.Errorf("default analyses = %v, %v; want the configured dns,whois", names, err)
+	}
+	names, err = selectAnalyses([]string{"SSL", "ssl", "dns"})
+	if err != nil || strings.Join(names, ",") != "dns,ssl" {
+		t.Errorf("selectAnalyses = %v, %v; want dns,ssl", names, err)
+	}
+	if _, err := selectAnalyses([]string{"nope"}); err == nil {
+		t.Error("selectAnalyses accepted an unknown analysis")
+	}
+}
+
+func TestSubmitRejectsInvalidBatches(t *testing.T) {
+	if _, err := Submit("", nil, nil, ""); err == nil {
+		t.Error("Submit accepted an empty batch")
+	}
+	if _, err := Submit("", make([]string, MaxItems+1), nil, ""); err == nil {
+		t.Error("Submit accepted too many items")
+	}
+	if _, err := Submit("", []string{"example.com", "not valid"}, nil, ""); err == nil {
+		t.Error("Submit accepted an invalid item")
+	}
+	if _, err := Submit("", []string{"example.com"}, nil, "ftp://example.com/hook"); err == nil {
+		t.Error("Submit accepted an invalid callback URL")
+	}
+}
+
+func TestSubmitRunsApplicableAnalyses(t *testing.T) {
+	analyses["test-echo"] = analysis{
+		kinds: map[string]bool{KindDomain: true, KindURL: true},
+		run: func(ctx context.Context, item Item) (any, error) {
+			if item.host() == "fail.example" {
+				return nil, errors.New("lookup failed")
+			}
+			return item.host(), nil
+		},
+	}
+	t.Cleanup(func() { delete(analyses, "test-echo") })
+
+	batch, err := Submit("case-1", []string{"https://www.example.com/path", "fail.example", "192.0.2.1"}, []string{"test-echo"}, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if batch.Status != StatusQueued || batch.CorrelationID != "case-1" || batch.Total != 3 {
+		t.Errorf("submitted batch = %+v", batch)
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		batch, _ = Get(batch.ID)
+		if batch.Status == StatusCompleted {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("batch did not complete: %+v", batch)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if got := batch.Items[0].Results["test-echo"]; got != "www.example.com" {
+		t.Errorf("URL result = %v, want its host", got)
+	}
+	if got := batch.Items[1].Errors["test-echo"]; got != "lookup failed" {
+		t.Errorf("failing item errors = %v", batch.Items[1].Errors)
+	}
+	if len(batch.Items[2].Results) != 0 || len(batch.Items[2].Errors) != 0 {
+		t.Errorf("IP item ran a domain analysis: %+v", batch.Items[2])
+	}
+	if batch.FinishedAt == nil {
+		t.Error("completed batch has no finish time")
+	}
+}
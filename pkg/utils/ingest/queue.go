@@ -0,0 +1,161 @@
+package ingest
+
+import (
+	"log"
+	"sync"
+	"time"
+
+	"github.com/vit0-9/utils_api/pkg/utils/redis"
+)
+
+// idleWait is how long a worker waits for an item before polling again.
+const idleWait = 2 * time.Second
+
+// work is one item of a batch waiting in the queue, with what the worker needs to analyse it.
+type work struct {
+	BatchID  string   `json:"batch_id"`
+	Index    int      `json:"index"`
+	Total    int      `json:"total"` // Items in the batch
+	Item     Item     `json:"item"`
+	Analyses []string `json:"analyses"`
+}
+
+// queue keeps the batches and the items waiting to be analysed.
+type queue interface {
+	// submit stores a batch and queues its items, or returns ErrQueueFull.
+	submit(batch *Batch) error
+	// next returns the next item, or false when none arrived within idleWait.
+	next() (work, bool)
+	// complete stores an item's result and returns the batch once its last item completed.
+	complete(next work, result ItemResult) (*Batch, error)
+	// requeue puts back an item whose analysis a shutdown cut short, first in line.
+	requeue(next work) error
+	get(id string) (Batch, bool)
+}
+
+var (
+	queueMu        sync.RWMutex
+	activeQueue    queue = newLocalQueue()
+	workersOnce    sync.Once
+	runningWorkers sync.WaitGroup
+)
+
+func currentQueue() queue {
+	queueMu.RLock()
+	defer queueMu.RUnlock()
+	return activeQueue
+}
+
+// ConfigureSharedQueue keeps the queue and the batches in Redis, so every replica behind a
+// load balancer takes items from one queue and answers for every batch, and starts this
+// replica's workers.
+func ConfigureSharedQueue(client *redis.Client) {
+	if client == nil {
+		return
+	}
+	queueMu.Lock()
+	activeQueue = newRedisQueue(client)
+	queueMu.Unlock()
+	workersOnce.Do(startWorkers)
+	log.Printf("Ingestion queue shared through Redis")
+}
+
+// workItems splits a batch into its queue items.
+func workItems(batch *Batch) []work {
+	items := make([]work, len(batch.Items))
+	for i, item := range batch.Items {
+		items[i] = work{BatchID: batch.ID, Index: i, Total: batch.Total, Item: item.Item, Analyses: batch.Analyses}
+	}
+	return items
+}
+
+// localQueue keeps the queue in a channel and the last batches in memory.
+type localQueue struct {
+	mu      sync.Mutex
+	batches []*Batch // Oldest first
+	pending chan work
+}
+
+func newLocalQueue() *localQueue {
+	return &localQueue{pending: make(chan work, queueSize)}
+}
+
+func (q *localQueue) submit(batch *Batch) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if len(q.pending)+len(batch.Items) > cap(q.pending) {
+		return ErrQueueFull
+	}
+	stored := *batch
+	stored.Items = append([]ItemResult(nil), batch.Items...)
+	q.batches = append(q.batches, &stored)
+	if len(q.batches) > maxStoredBatches {
+		q.batches = q.batches[len(q.batches)-maxStoredBatches:]
+	}
+	for _, item := range workItems(batch) {
+		q.pending <- item // Cannot block: only workers take items while the lock is held
+	}
+	return nil
+}
+
+func (q *localQueue) next() (work, bool) {
+	select {
+	case item := <-q.pending:
+		return item, true
+	case <-time.After(idleWait):
+		return work{}, false
+	}
+}
+
+func (q *localQueue) complete(next work, result ItemResult) (*Batch, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	batch := q.findLocked(next.BatchID)
+	if batch == nil { // Dropped from the stored batches while its items were queued
+		return nil, nil
+	}
+	batch.Items[next.Index] = result
+	batch.Done++
+	if batch.Done < batch.Total {
+		return nil, nil
+	}
+	finished := time.Now().UTC()
+	batch.Status = StatusCompleted
+	batch.FinishedAt = &finished
+	completed := snapshot(batch)
+	return &completed, nil
+}
+
+func (q *localQueue) requeue(next work) error {
+	select {
+	case q.pending <- next: // The order matters little for a queue that dies with the process
+		return nil
+	default:
+		return ErrQueueFull
+	}
+}
+
+func (q *localQueue) get(id string) (Batch, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if batch := q.findLocked(id); batch != nil {
+		return snapshot(batch), true
+	}
+	return Batch{}, false
+}
+
+func (q *localQueue) findLocked(id string) *Batch {
+	for _, batch := range q.batches {
+		if batch.ID == id {
+			return batch
+		}
+	}
+	return nil
+}
+
+// snapshot copies a batch so it can be encoded while workers update it.
+func snapshot(batch *Batch) Batch {
+	copied := *batch
+	copied.Items = append([]ItemResult(nil), batch.Items...)
+	return copied
+}
@@ -0,0 +1,151 @@
+package ingest
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/vit0-9/utils_api/pkg/utils/redis"
+)
+
+// Redis keys of the shared queue. Each batch is a hash holding the submitted batch under
+// "batch", the number of items done under "done", each item's result under "item:<index>"
+// and the completion time under "finished_at".
+const (
+	redisQueueKey       = "utils_api:ingest:queue"
+	redisBatchKeyPrefix = "utils_api:ingest:batch:"
+	redisBatchTTL       = 24 * time.Hour
+)
+
+// redisQueue keeps the queue in a Redis list and the batches in hashes that expire a day
+// after they were submitted.
+type redisQueue struct {
+	client *redis.Client
+}
+
+func newRedisQueue(client *redis.Client) *redisQueue {
+	return &redisQueue{client: client}
+}
+
+func (q *redisQueue) submit(batch *Batch) error {
+	ctx := context.Background()
+	reply, err := q.client.Do(ctx, "LLEN", redisQueueKey)
+	if err != nil {
+		return err
+	}
+	if queued, _ := reply.(int64); int(queued)+len(batch.Items) > queueSize {
+		return ErrQueueFull
+	}
+	data, err := json.Marshal(batch)
+	if err != nil {
+		return err
+	}
+	key := redisBatchKeyPrefix + batch.ID
+	if _, err := q.client.Do(ctx, "HSET", key, "batch", string(data), "done", "0"); err != nil {
+		return err
+	}
+	if _, err := q.client.Do(ctx, "PEXPIRE", key, strconv.FormatInt(redisBatchTTL.Milliseconds(), 10)); err != nil {
+		return err
+	}
+	args := []string{"RPUSH", redisQueueKey}
+	for _, item := range workItems(batch) {
+		encoded, err := json.Marshal(item)
+		if err != nil {
+			return err
+		}
+		args = append(args, string(encoded))
+	}
+	_, err = q.client.Do(ctx, args...)
+	return err
+}
+
+func (q *redisQueue) next() (work, bool) {
+	reply, err := q.client.Do(context.Background(), "BLPOP", redisQueueKey, strconv.Itoa(int(idleWait.Seconds())))
+	if err != nil {
+		time.Sleep(idleWait) // Redis is unreachable; do not spin
+		return work{}, false
+	}
+	popped, ok := reply.([]any)
+	if !ok || len(popped) != 2 {
+		return work{}, false
+	}
+	data, _ := popped[1].([]byte)
+	var item work
+	if err := json.Unmarshal(data, &item); err != nil {
+		return work{}, false
+	}
+	return item, true
+}
+
+func (q *redisQueue) complete(next work, result ItemResult) (*Batch, error) {
+	ctx := context.Background()
+	key := redisBatchKeyPrefix + next.BatchID
+	data, err := json.Marshal(result)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := q.client.Do(ctx, "HSET", key, "item:"+strconv.Itoa(next.Index), string(data)); err != nil {
+		return nil, err
+	}
+	reply, err := q.client.Do(ctx, "HINCRBY", key, "done", "1")
+	if err != nil {
+		return nil, err
+	}
+	if done, _ := reply.(int64); int(done) < next.Total {
+		return nil, nil
+	}
+	if _, err := q.client.Do(ctx, "HSET", key, "finished_at", time.Now().UTC().Format(time.RFC3339Nano)); err != nil {
+		return nil, err
+	}
+	batch, ok := q.get(next.BatchID)
+	if !ok {
+		return nil, fmt.Errorf("batch %s expired before it completed", next.BatchID)
+	}
+	return &batch, nil
+}
+
+func (q *redisQueue) requeue(next work) error {
+	encoded, err := json.Marshal(next)
+	if err != nil {
+		return err
+	}
+	_, err = q.client.Do(context.Background(), "LPUSH", redisQueueKey, string(encoded))
+	return err
+}
+
+func (q *redisQueue) get(id string) (Batch, bool) {
+	reply, err := q.client.Do(context.Background(), "HGETALL", redisBatchKeyPrefix+id)
+	if err != nil {
+		return Batch{}, false
+	}
+	pairs, _ := reply.([]any)
+	fields := make(map[string][]byte, len(pairs)/2)
+	for i := 0; i+1 < len(pairs); i += 2 {
+		name, _ := pairs[i].([]byte)
+		value, _ := pairs[i+1].([]byte)
+		fields[string(name)] = value
+	}
+	var batch Batch
+	if err := json.Unmarshal(fields["batch"], &batch); err != nil {
+		return Batch{}, false
+	}
+	batch.Done, _ = strconv.Atoi(string(fields["done"]))
+	for name, value := range fields {
+		index, err := strconv.Atoi(strings.TrimPrefix(name, "item:"))
+		if !strings.HasPrefix(name, "item:") || err != nil || index < 0 || index >= len(batch.Items) {
+			continue
+		}
+		var result ItemResult
+		if json.Unmarshal(value, &result) == nil {
+			batch.Items[index] = result
+		}
+	}
+	if finished, err := time.Parse(time.RFC3339Nano, string(fields["finished_at"])); err == nil {
+		batch.Status = StatusCompleted
+		batch.FinishedAt = &finished
+	}
+	return batch, true
+}
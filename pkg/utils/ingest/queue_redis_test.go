@@ -0,0 +1,61 @@
+package ingest
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/vit0-9/utils_api/pkg/utils/redis"
+	"github.com/vit0-9/utils_api/pkg/utils/redis/redistest"
+)
+
+func TestRedisQueue(t *testing.T) {
+	server := redistest.NewServer(t)
+	q := newRedisQueue(redis.New(server.Addr, "", 0))
+	batch := &Batch{
+		ID:         "b1",
+		Analyses:   []string{"dns"},
+		Status:     StatusQueued,
+		ReceivedAt: time.Now().UTC(),
+		Total:      2,
+		Items:      []ItemResult{{Item: Item{Value: "example.com", Kind: KindDomain}}, {Item: Item{Value: "192.0.2.1", Kind: KindIP}}},
+	}
+	if err := q.submit(batch); err != nil {
+		t.Fatalf("submit() error = %v", err)
+	}
+
+	// Another replica sees the queued batch and takes its items in order.
+	other := newRedisQueue(redis.New(server.Addr, "", 0))
+	if got, ok := other.get("b1"); !ok || got.Status != StatusQueued || got.Done != 0 || len(got.Items) != 2 {
+		t.Fatalf("get() = %+v, %v; want the queued batch", got, ok)
+	}
+	first, ok := other.next()
+	if !ok || first.BatchID != "b1" || first.Index != 0 || first.Item.Value != "example.com" || first.Total != 2 {
+		t.Fatalf("next() = %+v, %v", first, ok)
+	}
+	second, _ := q.next()
+
+	if completed, err := q.complete(second, ItemResult{Item: second.Item, Errors: map[string]string{"ip-info": "failed"}}); err != nil || completed != nil {
+		t.Errorf("complete() of the first finished item = %+v, %v; want the batch still running", completed, err)
+	}
+	completed, err := other.complete(first, ItemResult{Item: first.Item, Results: map[string]any{"dns": "ok"}})
+	if err != nil || completed == nil {
+		t.Fatalf("complete() of the last item = %+v, %v; want the completed batch", completed, err)
+	}
+	if completed.Status != StatusCompleted || completed.Done != 2 || completed.FinishedAt == nil ||
+		completed.Items[0].Results["dns"] != "ok" || completed.Items[1].Errors["ip-info"] != "failed" {
+		t.Errorf("completed batch = %+v", completed)
+	}
+	if _, ok := q.get("missing"); ok {
+		t.Error("get() of an unknown batch succeeded")
+	}
+}
+
+func TestRedisQueueFull(t *testing.T) {
+	server := redistest.NewServer(t)
+	q := newRedisQueue(redis.New(server.Addr, "", 0))
+	batch := &Batch{ID: "big", Total: queueSize + 1, Items: make([]ItemResult, queueSize+1)}
+	if err := q.submit(batch); !errors.Is(err, ErrQueueFull) {
+		t.Errorf("submit() of more items than the queue holds error = %v, want ErrQueueFull", err)
+	}
+}
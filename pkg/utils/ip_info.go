@@ -4,8 +4,10 @@ import (
 	"fmt"
 	"log"
 	"net"
+	"os"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/oschwald/geoip2-golang"
 )
@@ -35,65 +37,142 @@ type IPInfoData struct {
 	GeoError       string  `json:"geo_error,omitempty"`
 }
 
+// geoIPDatabase is a MaxMind database whose reader can be swapped while lookups run: they hold
+// mu for reading, so a reader is only closed once no lookup uses it.
+type geoIPDatabase struct {
+	edition string // e.g. GeoLite2-City
+
+	mu        sync.RWMutex
+	path      string
+	reader    *geoip2.Reader
+	loadErr   error
+	updatedAt time.Time // When the file in use was written
+	builtAt   time.Time // When MaxMind built the database in use
+	lastCheck time.Time // The last download attempt, when updates are enabled
+	lastError string
+}
+
 var (
-	cityDB       *geoip2.Reader
-	asnDB        *geoip2.Reader // Reader for the ASN database
-	cityLoadOnce sync.Once
-	asnLoadOnce  sync.Once
-	cityLoadErr  error
-	asnLoadErr   error
+	cityDB = &geoIPDatabase{edition: "GeoLite2-City"}
+	asnDB  = &geoIPDatabase{edition: "GeoLite2-ASN"} // Reader for the ASN database
 )
 
-// LoadMaxMindDBs initializes the GeoIP2 readers.
-// dbPaths should be a map like: {"city": "/path/to/city.mmdb", "asn": "/path/to/asn.mmdb"}
+// loaded reports whether the database can be queried.
+func (d *geoIPDatabase) loaded() bool {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	return d.reader != nil
+}
+
+// loadError returns why the database is not loaded.
+func (d *geoIPDatabase) loadError() error {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	return d.loadErr
+}
+
+// city looks ip up in a City (or Country) database.
+func (d *geoIPDatabase) city(ip net.IP) (*geoip2.City, error) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	if d.reader == nil {
+		return nil, fmt.Errorf("%s database not loaded", d.edition)
+	}
+	return d.reader.City(ip)
+}
+
+// asn looks ip up in an ASN database.
+func (d *geoIPDatabase) asn(ip net.IP) (*geoip2.ASN, error) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	if d.reader == nil {
+		return nil, fmt.Errorf("%s database not loaded", d.edition)
+	}
+	return d.reader.ASN(ip)
+}
+
+// open loads the database at path, replacing the reader in use.
+func (d *geoIPDatabase) open(path string) error {
+	reader, err := geoip2.Open(path)
+	if err != nil {
+		d.mu.Lock()
+		if d.reader == nil {
+			d.path, d.loadErr = path, err
+		}
+		d.mu.Unlock()
+		return err
+	}
+	var updatedAt time.Time
+	if info, err := os.Stat(path); err == nil {
+		updatedAt = info.ModTime().UTC()
+	}
+
+	d.mu.Lock()
+	previous := d.reader
+	d.path, d.reader, d.loadErr, d.updatedAt = path, reader, nil, updatedAt
+	d.builtAt = time.Unix(int64(reader.Metadata().BuildEpoch), 0).UTC()
+	d.mu.Unlock()
+	if previous != nil { // No lookup holds it any more
+		previous.Close()
+	}
+	if builtAt := time.Unix(int64(reader.Metadata().BuildEpoch), 0).UTC(); geoIPStale(builtAt) {
+		log.Printf("WARN: The %s database at %s was built on %s and is stale; GeoIP results may be outdated", d.edition, path, builtAt.Format(time.DateOnly))
+	}
+	return nil
+}
+
+// close closes the reader; later lookups fail.
+func (d *geoIPDatabase) close() error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.reader == nil {
+		return nil
+	}
+	err := d.reader.Close()
+	d.reader, d.loadErr = nil, fmt.Errorf("%s database closed", d.edition)
+	return err
+}
+
+// LoadMaxMindDBs initializes the GeoIP2 readers from the GeoLite2-City and GeoLite2-ASN
+// databases at the given paths.
 func LoadMaxMindDBs(cityDBPath string, asnDBPath string) {
 	if cityDBPath != "" {
-		cityLoadOnce.Do(func() {
-			db, err := geoip2.Open(cityDBPath)
-			if err != nil {
-				log.Printf("ERROR: Could not open GeoLite2-City database at %s: %v. City GeoIP lookups will be disabled.", cityDBPath, err)
-				cityLoadErr = err
-				return
-			}
-			cityDB = db
+		if err := cityDB.open(cityDBPath); err != nil {
+			log.Printf("ERROR: Could not open GeoLite2-City database at %s: %v. City GeoIP lookups will be disabled.", cityDBPath, err)
+		} else {
 			log.Printf("Successfully loaded GeoLite2-City database from %s", cityDBPath)
-		})
+		}
 	} else {
 		log.Println("WARN: City MMDB path not provided. City GeoIP lookups will be disabled.")
-		cityLoadErr = fmt.Errorf("city MMDB path not provided")
+		cityDB.mu.Lock()
+		cityDB.loadErr = fmt.Errorf("city MMDB path not provided")
+		cityDB.mu.Unlock()
 	}
 
 	if asnDBPath != "" {
-		asnLoadOnce.Do(func() {
-			db, err := geoip2.Open(asnDBPath)
-			if err != nil {
-				log.Printf("ERROR: Could not open GeoLite2-ASN database at %s: %v. ASN GeoIP lookups will be disabled.", asnDBPath, err)
-				asnLoadErr = err
-				return
-			}
-			asnDB = db
+		if err := asnDB.open(asnDBPath); err != nil {
+			log.Printf("ERROR: Could not open GeoLite2-ASN database at %s: %v. ASN GeoIP lookups will be disabled.", asnDBPath, err)
+		} else {
 			log.Printf("Successfully loaded GeoLite2-ASN database from %s", asnDBPath)
-		})
+		}
 	} else {
 		log.Println("WARN: ASN MMDB path not provided. ASN GeoIP lookups will be disabled.")
-		asnLoadErr = fmt.Errorf("ASN MMDB path not provided")
+		asnDB.mu.Lock()
+		asnDB.loadErr = fmt.Errorf("ASN MMDB path not provided")
+		asnDB.mu.Unlock()
 	}
 }
 
 // CloseMaxMindDBs closes all GeoIP2 readers.
 func CloseMaxMindDBs() {
-	if cityDB != nil {
-		if err := cityDB.Close(); err != nil {
-			log.Printf("Error closing GeoLite2-City database: %v", err)
-		} else {
-			log.Println("GeoLite2-City database closed.")
+	for _, db := range []*geoIPDatabase{cityDB, asnDB} {
+		if !db.loaded() {
+			continue
 		}
-	}
-	if asnDB != nil {
-		if err := asnDB.Close(); err != nil {
-			log.Printf("Error closing GeoLite2-ASN database: %v", err)
+		if err := db.close(); err != nil {
+			log.Printf("Error closing %s database: %v", db.edition, err)
 		} else {
-			log.Println("GeoLite2-ASN database closed.")
+			log.Printf("%s database closed.", db.edition)
 		}
 	}
 }
@@ -127,7 +206,10 @@ func GetBasicIPInfo(ipStr string) IPInfoData {
 	data.IsLinkLocalUnicast = parsedIP.IsLinkLocalUnicast()
 	data.IsGlobalUnicast = parsedIP.IsGlobalUnicast()
 
-	names, _ := net.LookupAddr(ipStr)
+	var names []string
+	if !OfflineMode() && CheckOutboundAddress(ipStr, []net.IP{parsedIP}) == nil { // Reverse DNS is skipped offline and for targets outside the outbound policy
+		names, _ = net.LookupAddr(ipStr)
+	}
 	if len(names) > 0 {
 		cleanedNames := make([]string, len(names))
 		for i, name := range names {
@@ -143,8 +225,8 @@ func GetBasicIPInfo(ipStr string) IPInfoData {
 	var geoErrs []string
 
 	// City/Country/Location Lookup
-	if cityDB != nil {
-		cityRecord, err := cityDB.City(parsedIP) // .City() method can also be used on Country DBs
+	if cityDB.loaded() {
+		cityRecord, err := cityDB.city(parsedIP) // .City() method can also be used on Country DBs
 		if err == nil && cityRecord != nil {
 			if cityRecord.Country.IsoCode != "" {
 				data.CountryCode = cityRecord.Country.IsoCode
@@ -171,13 +253,13 @@ func GetBasicIPInfo(ipStr string) IPInfoData {
 		} else if err != nil {
 			geoErrs = append(geoErrs, fmt.Sprintf("City/Country lookup error: %v", err))
 		}
-	} else if cityLoadErr != nil {
-		geoErrs = append(geoErrs, fmt.Sprintf("City/Country DB not loaded: %v", cityLoadErr))
+	} else if loadErr := cityDB.loadError(); loadErr != nil {
+		geoErrs = append(geoErrs, fmt.Sprintf("City/Country DB not loaded: %v", loadErr))
 	}
 
 	// ASN Lookup
-	if asnDB != nil {
-		asnRecord, err := asnDB.ASN(parsedIP) // Use the .ASN() method with the ASN database reader
+	if asnDB.loaded() {
+		asnRecord, err := asnDB.asn(parsedIP) // Use the .ASN() method with the ASN database reader
 		if err == nil && asnRecord != nil {
 			if asnRecord.AutonomousSystemNumber != 0 {
 				data.ASN = asnRecord.AutonomousSystemNumber
@@ -188,8 +270,8 @@ func GetBasicIPInfo(ipStr string) IPInfoData {
 		} else if err != nil {
 			geoErrs = append(geoErrs, fmt.Sprintf("ASN lookup error: %v", err))
 		}
-	} else if asnLoadErr != nil {
-		geoErrs = append(geoErrs, fmt.Sprintf("ASN DB not loaded: %v", asnLoadErr))
+	} else if loadErr := asnDB.loadError(); loadErr != nil {
+		geoErrs = append(geoErrs, fmt.Sprintf("ASN DB not loaded: %v", loadErr))
 	}
 
 	if len(geoErrs) > 0 {
@@ -198,3 +280,38 @@ func GetBasicIPInfo(ipStr string) IPInfoData {
 
 	return data
 }
+
+// MaxBulkIPInfoAddresses caps how many addresses a single bulk lookup may contain.
+const MaxBulkIPInfoAddresses = 1000
+
+// bulkIPInfoWorkers bounds how many lookups (mostly reverse DNS) run at once.
+const bulkIPInfoWorkers = 32
+
+// GetBulkIPInfo looks up many IP addresses concurrently with a bounded worker pool.
+// Results are returned in the same order as the input.
+func GetBulkIPInfo(ipStrs []string) []IPInfoData {
+	results := make([]IPInfoData, len(ipStrs))
+	workers := bulkIPInfoWorkers
+	if len(ipStrs) < workers {
+		workers = len(ipStrs)
+	}
+
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				results[i] = GetBasicIPInfo(strings.TrimSpace(ipStrs[i]))
+			}
+		}()
+	}
+	for i := range ipStrs {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+
+	return results
+}
@@ -0,0 +1,21 @@
+package utils
+
+import "testing"
+
+func BenchmarkGetBasicIPInfo(b *testing.B) {
+	// Offline, no reverse DNS lookup is made and only the parsing and GeoIP lookups are
+	// measured.
+	offlineMu.Lock()
+	offlineEnabled = true
+	offlineMu.Unlock()
+	defer func() {
+		offlineMu.Lock()
+		offlineEnabled = false
+		offlineMu.Unlock()
+	}()
+	b.ReportAllocs()
+	for b.Loop() {
+		GetBasicIPInfo("192.0.2.1")
+		GetBasicIPInfo("2001:db8::1")
+	}
+}
@@ -0,0 +1,98 @@
+// Package lifecycle coordinates shutdown. Background subsystems (job runners, queue workers,
+// schedulers, stores) register how they stop; Shutdown first cancels the shared context their
+// loops watch, then stops them in reverse order of registration, so a subsystem stops before
+// the ones it was started on top of, e.g. the job runners before the storage they save to.
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+)
+
+// subsystem is a registered stop function.
+type subsystem struct {
+	name string
+	stop func(ctx context.Context) error
+}
+
+var (
+	mu         sync.Mutex
+	subsystems []subsystem
+	ctx, stop  = context.WithCancel(context.Background())
+)
+
+// Register adds a subsystem to stop on shutdown. stop should return once the subsystem has
+// finished or checkpointed its work, or when ctx, which ends at the shutdown deadline, is done.
+func Register(name string, stop func(ctx context.Context) error) {
+	mu.Lock()
+	defer mu.Unlock()
+	subsystems = append(subsystems, subsystem{name, stop})
+}
+
+// Context is cancelled when shutdown begins. Background work derives its contexts from it
+// and schedulers return when it is done.
+func Context() context.Context {
+	return ctx
+}
+
+// Stopping reports whether shutdown has begun, for workers deciding whether to take more work.
+func Stopping() bool {
+	return ctx.Err() != nil
+}
+
+// Shutdown cancels Context and stops the registered subsystems, last registered first. Each is
+// given what remains of ctx; the errors of those that failed or ran out of time are returned
+// together.
+func Shutdown(shutdownCtx context.Context) error {
+	stop()
+	mu.Lock()
+	registered := append([]subsystem(nil), subsystems...)
+	subsystems = nil
+	mu.Unlock()
+
+	var errs []error
+	for i := len(registered) - 1; i >= 0; i-- {
+		subsystem := registered[i]
+		started := time.Now()
+		if err := subsystem.stop(shutdownCtx); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", subsystem.name, err))
+			log.Printf("WARN: Stopping %s failed after %s: %v", subsystem.name, time.Since(started).Round(time.Millisecond), err)
+			continue
+		}
+		log.Printf("Stopped %s in %s", subsystem.name, time.Since(started).Round(time.Millisecond))
+	}
+	return errors.Join(errs...)
+}
+
+// Sleep pauses a scheduler for d and reports whether it should go on: false as soon as
+// shutdown begins.
+func Sleep(d time.Duration) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// Wait waits for wg, or returns ctx's error when ctx is done first. Subsystems use it to
+// wait for their goroutines within the shutdown deadline.
+func Wait(ctx context.Context, wg *sync.WaitGroup) error {
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
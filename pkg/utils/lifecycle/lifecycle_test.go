@@ -0,0 +1,44 @@
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestShutdown(t *testing.T) {
+	var order []string
+	record := func(name string) func(context.Context) error {
+		return func(context.Context) error {
+			if !Stopping() {
+				t.Errorf("%s stopped before Context was cancelled", name)
+			}
+			order = append(order, name)
+			return nil
+		}
+	}
+	Register("storage", record("storage"))
+	Register("jobs", record("jobs"))
+	var running sync.WaitGroup
+	running.Add(1)
+	Register("stuck", func(ctx context.Context) error { return Wait(ctx, &running) })
+	Register("http", record("http"))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	err := Shutdown(ctx)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Shutdown = %v, want the stuck subsystem's deadline error", err)
+	}
+	if want := []string{"http", "jobs", "storage"}; !reflect.DeepEqual(order, want) {
+		t.Errorf("stop order = %v, want %v", order, want)
+	}
+	select {
+	case <-Context().Done():
+	default:
+		t.Error("Context not cancelled")
+	}
+}
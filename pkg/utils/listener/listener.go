@@ -0,0 +1,89 @@
+// Package listener opens the socket the API server accepts connections on: a TCP port, a Unix
+// domain socket for deployments behind a local proxy, or the socket systemd passes a
+// socket-activated service, which keeps accepting (queueing) connections across restarts.
+package listener
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"time"
+)
+
+// DefaultSocketMode lets the socket's owner and group, e.g. the proxy's, connect.
+const DefaultSocketMode os.FileMode = 0o660
+
+// listenFDsStart is the first file descriptor systemd passes (SD_LISTEN_FDS_START).
+var listenFDsStart = 3
+
+// Listen returns the listener to serve on: the socket systemd passed when the process was
+// socket-activated, else a Unix domain socket at socketPath when set, else TCP on addr.
+func Listen(addr, socketPath string, socketMode os.FileMode) (net.Listener, error) {
+	listener, err := Systemd()
+	if err != nil || listener != nil {
+		return listener, err
+	}
+	if socketPath != "" {
+		return Unix(socketPath, socketMode)
+	}
+	return net.Listen("tcp", addr)
+}
+
+// Systemd returns the listening socket systemd passed through LISTEN_FDS, or nil when the
+// process was not socket-activated. The variables are unset so child processes don't
+// mistake the socket for theirs.
+func Systemd() (net.Listener, error) {
+	pid, fds := os.Getenv("LISTEN_PID"), os.Getenv("LISTEN_FDS")
+	if fds == "" || pid != strconv.Itoa(os.Getpid()) {
+		return nil, nil
+	}
+	os.Unsetenv("LISTEN_PID")
+	os.Unsetenv("LISTEN_FDS")
+	os.Unsetenv("LISTEN_FDNAMES")
+
+	count, err := strconv.Atoi(fds)
+	if err != nil || count < 1 {
+		return nil, fmt.Errorf("invalid LISTEN_FDS %q", fds)
+	}
+	if count > 1 {
+		return nil, fmt.Errorf("systemd passed %d sockets, the server listens on exactly one", count)
+	}
+	file := os.NewFile(uintptr(listenFDsStart), "systemd-socket")
+	defer file.Close() // FileListener works on a duplicate
+	listener, err := net.FileListener(file)
+	if err != nil {
+		return nil, fmt.Errorf("systemd socket: %w", err)
+	}
+	return listener, nil
+}
+
+// Unix listens on a Unix domain socket at path with the given permissions. A socket file left
+// behind by a server that has stopped is replaced, one that still accepts connections is not.
+func Unix(path string, mode os.FileMode) (net.Listener, error) {
+	if info, err := os.Lstat(path); err == nil {
+		if info.Mode()&os.ModeSocket == 0 {
+			return nil, fmt.Errorf("%s exists and is not a socket", path)
+		}
+		if conn, err := net.DialTimeout("unix", path, time.Second); err == nil {
+			conn.Close()
+			return nil, fmt.Errorf("%s is in use by another server", path)
+		}
+		if err := os.Remove(path); err != nil {
+			return nil, fmt.Errorf("removing stale socket: %w", err)
+		}
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+
+	listener, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.Chmod(path, mode); err != nil {
+		listener.Close()
+		return nil, fmt.Errorf("setting socket permissions: %w", err)
+	}
+	return listener, nil
+}
@@ -0,0 +1,78 @@
+package listener
+
+import (
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+)
+
+func TestUnix(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "api.sock")
+	listener, err := Unix(path, 0o600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info, err := os.Stat(path); err != nil || info.Mode().Perm() != 0o600 {
+		t.Fatalf("socket mode = %v, %v", info.Mode(), err)
+	}
+	if _, err := Unix(path, 0o600); err == nil {
+		t.Error("listening on a socket in use succeeded")
+	}
+
+	// A socket left behind by a stopped server is replaced
+	listener.(*net.UnixListener).SetUnlinkOnClose(false)
+	listener.Close()
+	listener, err = Unix(path, 0o600)
+	if err != nil {
+		t.Fatalf("replacing stale socket: %v", err)
+	}
+	listener.Close()
+
+	file := filepath.Join(t.TempDir(), "file")
+	os.WriteFile(file, nil, 0o600)
+	if _, err := Unix(file, 0o600); err == nil {
+		t.Error("listening over a regular file succeeded")
+	}
+}
+
+func TestSystemd(t *testing.T) {
+	if listener, err := Systemd(); listener != nil || err != nil {
+		t.Fatalf("without LISTEN_FDS = %v, %v", listener, err)
+	}
+
+	tcp, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tcp.Close()
+	file, err := tcp.(*net.TCPListener).File()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Systemd takes over the descriptor and closes it
+	original := listenFDsStart
+	defer func() { listenFDsStart = original }()
+	listenFDsStart = int(file.Fd())
+
+	t.Setenv("LISTEN_PID", strconv.Itoa(os.Getpid()))
+	t.Setenv("LISTEN_FDS", "1")
+	listener, err := Systemd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	if listener.Addr().String() != tcp.Addr().String() {
+		t.Errorf("systemd listener on %s, want %s", listener.Addr(), tcp.Addr())
+	}
+	if os.Getenv("LISTEN_FDS") != "" {
+		t.Error("LISTEN_FDS still set")
+	}
+
+	t.Setenv("LISTEN_PID", "1")
+	t.Setenv("LISTEN_FDS", "1")
+	if listener, _ := Systemd(); listener != nil {
+		t.Error("used sockets passed to another process")
+	}
+}
@@ -0,0 +1,241 @@
+package utils
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/oschwald/geoip2-golang"
+
+	"github.com/vit0-9/utils_api/pkg/utils/lifecycle"
+)
+
+const (
+	// DefaultMaxMindUpdateInterval is how often the GeoLite2 databases are downloaded. MaxMind
+	// publishes them twice a week.
+	DefaultMaxMindUpdateInterval = 24 * time.Hour
+
+	// maxmindCheckInterval is how often the databases are checked for being due an update.
+	maxmindCheckInterval = time.Hour
+
+	// maxMaxMindDatabaseSize caps the size of a downloaded database.
+	maxMaxMindDatabaseSize = 512 << 20
+
+	// DefaultGeoIPStaleAfter is the age of a database build after which it is reported stale.
+	DefaultGeoIPStaleAfter = 60 * 24 * time.Hour
+)
+
+// geoIPStaleAfter is set by ConfigureGeoIPStaleness.
+var geoIPStaleAfter = DefaultGeoIPStaleAfter
+
+// maxmindDownloadURL serves each edition as a tar.gz archive holding the .mmdb file.
+var maxmindDownloadURL = "https://download.maxmind.com/app/geoip_download"
+
+// maxmindUpdate holds the download settings, set by ConfigureMaxMindUpdates.
+var maxmindUpdate struct {
+	sync.Mutex // Serializes downloads
+	licenseKey string
+	interval   time.Duration
+}
+
+// GeoIPDatabaseStatus describes a MaxMind database and its last update.
+type GeoIPDatabaseStatus struct {
+	Edition   string     `json:"edition"`
+	Path      string     `json:"path"`
+	Loaded    bool       `json:"loaded"`
+	UpdatedAt *time.Time `json:"updated_at,omitempty"` // When the file in use was written
+	BuiltAt   *time.Time `json:"built_at,omitempty"`   // When MaxMind built the database in use
+	Stale     bool       `json:"stale,omitempty"`      // The build is older than the staleness threshold
+	LastCheck *time.Time `json:"last_check,omitempty"` // The last download attempt
+	LastError string     `json:"last_error,omitempty"`
+}
+
+// ConfigureGeoIPStaleness sets the age in days after which a database build is reported stale,
+// as lookups then return outdated locations and networks.
+func ConfigureGeoIPStaleness(days int) {
+	if days > 0 {
+		geoIPStaleAfter = time.Duration(days) * 24 * time.Hour
+	}
+}
+
+// geoIPStale reports whether a database built at builtAt is stale.
+func geoIPStale(builtAt time.Time) bool {
+	return !builtAt.IsZero() && time.Since(builtAt) > geoIPStaleAfter
+}
+
+// ConfigureMaxMindUpdates enables downloading the GeoLite2 City and ASN databases with a
+// MaxMind license key, on startup when they are missing or older than interval and every
+// interval after. They are written to the paths passed to LoadMaxMindDBs, or under data/ when
+// none was set, and swapped in without a restart.
+func ConfigureMaxMindUpdates(licenseKey string, interval time.Duration) {
+	if licenseKey == "" {
+		return
+	}
+	if interval <= 0 {
+		interval = DefaultMaxMindUpdateInterval
+	}
+	maxmindUpdate.Lock()
+	maxmindUpdate.licenseKey, maxmindUpdate.interval = licenseKey, interval
+	maxmindUpdate.Unlock()
+
+	for _, db := range []*geoIPDatabase{cityDB, asnDB} {
+		db.mu.Lock()
+		if db.path == "" {
+			db.path = filepath.Join("data", db.edition+".mmdb")
+		}
+		db.mu.Unlock()
+	}
+	log.Printf("MaxMind database updates enabled every %s", interval)
+	go func() {
+		for {
+			updateDueGeoIPDatabases(lifecycle.Context())
+			if !lifecycle.Sleep(maxmindCheckInterval) {
+				return
+			}
+		}
+	}()
+}
+
+// updateDueGeoIPDatabases downloads the databases that are missing or older than the update
+// interval, unless a download was attempted within the interval.
+func updateDueGeoIPDatabases(ctx context.Context) {
+	maxmindUpdate.Lock()
+	defer maxmindUpdate.Unlock()
+	for _, db := range []*geoIPDatabase{cityDB, asnDB} {
+		db.mu.RLock()
+		current := db.reader != nil && time.Since(db.updatedAt) < maxmindUpdate.interval
+		checked := time.Since(db.lastCheck) < maxmindUpdate.interval
+		db.mu.RUnlock()
+		if current || checked {
+			continue
+		}
+
+		err := downloadGeoIPDatabase(ctx, maxmindUpdate.licenseKey, db)
+		db.mu.Lock()
+		db.lastCheck, db.lastError = time.Now().UTC(), ""
+		if err != nil {
+			db.lastError = err.Error()
+		}
+		db.mu.Unlock()
+		if err != nil {
+			log.Printf("ERROR: %s database update failed: %v", db.edition, err)
+			continue
+		}
+		log.Printf("Updated %s database", db.edition)
+	}
+}
+
+// downloadGeoIPDatabase fetches the latest release of the database's edition, checks that it
+// is a database of that edition and swaps it in. On failure the database in use is kept.
+func downloadGeoIPDatabase(ctx context.Context, licenseKey string, db *geoIPDatabase) error {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Minute)
+	defer cancel()
+	query := url.Values{"edition_id": {db.edition}, "license_key": {licenseKey}, "suffix": {"tar.gz"}}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, maxmindDownloadURL+"?"+query.Encode(), nil)
+	if err != nil {
+		return err
+	}
+	resp, err := (&http.Client{Transport: NewOutboundTransport()}).Do(req)
+	if err != nil {
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) { // Keep the license key in the URL out of the message
+			err = urlErr.Err
+		}
+		return fmt.Errorf("failed to download %s: %w", db.edition, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("failed to download %s: received status %s", db.edition, resp.Status)
+	}
+
+	data, err := extractMMDB(resp.Body, db.edition)
+	if err != nil {
+		return fmt.Errorf("failed to extract %s: %w", db.edition, err)
+	}
+	reader, err := geoip2.FromBytes(data)
+	if err != nil {
+		return fmt.Errorf("invalid %s database: %w", db.edition, err)
+	}
+	databaseType := reader.Metadata().DatabaseType
+	reader.Close()
+	if databaseType != db.edition {
+		return fmt.Errorf("invalid %s database: it is a %s database", db.edition, databaseType)
+	}
+
+	db.mu.RLock()
+	dbPath := db.path
+	db.mu.RUnlock()
+	if err := os.MkdirAll(filepath.Dir(dbPath), 0o755); err != nil {
+		return fmt.Errorf("failed to store %s: %w", db.edition, err)
+	}
+	// The reader in use maps the replaced file, which stays readable until it is closed
+	if err := WriteFileAtomic(dbPath, data); err != nil {
+		return fmt.Errorf("failed to store %s: %w", db.edition, err)
+	}
+	return db.open(dbPath)
+}
+
+// extractMMDB returns the <edition>.mmdb file of a GeoLite2 tar.gz archive, which keeps it in
+// a dated directory such as GeoLite2-City_20240102/.
+func extractMMDB(r io.Reader, edition string) ([]byte, error) {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, err
+	}
+	defer gz.Close()
+	archive := tar.NewReader(gz)
+	for {
+		header, err := archive.Next()
+		if err == io.EOF {
+			return nil, fmt.Errorf("archive has no %s.mmdb", edition)
+		}
+		if err != nil {
+			return nil, err
+		}
+		if header.Typeflag != tar.TypeReg || path.Base(header.Name) != edition+".mmdb" {
+			continue
+		}
+		if header.Size > maxMaxMindDatabaseSize {
+			return nil, fmt.Errorf("database exceeds %d MB", maxMaxMindDatabaseSize>>20)
+		}
+		return io.ReadAll(archive)
+	}
+}
+
+// GeoIPDatabaseStatuses returns the state of the configured MaxMind databases.
+func GeoIPDatabaseStatuses() []GeoIPDatabaseStatus {
+	statuses := []GeoIPDatabaseStatus{}
+	for _, db := range []*geoIPDatabase{cityDB, asnDB} {
+		db.mu.RLock()
+		status := GeoIPDatabaseStatus{Edition: db.edition, Path: db.path, Loaded: db.reader != nil, LastError: db.lastError}
+		if !db.updatedAt.IsZero() {
+			updatedAt := db.updatedAt
+			status.UpdatedAt = &updatedAt
+		}
+		if !db.builtAt.IsZero() {
+			builtAt := db.builtAt
+			status.BuiltAt, status.Stale = &builtAt, geoIPStale(builtAt)
+		}
+		if !db.lastCheck.IsZero() {
+			lastCheck := db.lastCheck
+			status.LastCheck = &lastCheck
+		}
+		db.mu.RUnlock()
+		if status.Path == "" {
+			continue
+		}
+		statuses = append(statuses, status)
+	}
+	return statuses
+}
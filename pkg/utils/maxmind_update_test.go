@@ -0,0 +1,94 @@
+package utils
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// geoLiteArchive packs files into a tar.gz the way MaxMind serves databases.
+func geoLiteArchive(t *testing.T, files map[string]string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	archive := tar.NewWriter(gz)
+	for name, content := range files {
+		if err := archive.WriteHeader(&tar.Header{Name: name, Mode: 0o644, Size: int64(len(content)), Typeflag: tar.TypeReg}); err != nil {
+			t.Fatal(err)
+		}
+		archive.Write([]byte(content))
+	}
+	archive.Close()
+	gz.Close()
+	return buf.Bytes()
+}
+
+func TestExtractMMDB(t *testing.T) {
+	data := geoLiteArchive(t, map[string]string{
+		"GeoLite2-City_20261013/COPYRIGHT.txt":      "copyright",
+		"GeoLite2-City_20261013/GeoLite2-City.mmdb": "database",
+	})
+	mmdb, err := extractMMDB(bytes.NewReader(data), "GeoLite2-City")
+	if err != nil || string(mmdb) != "database" {
+		t.Errorf("extractMMDB = %q, %v", mmdb, err)
+	}
+	if _, err := extractMMDB(bytes.NewReader(data), "GeoLite2-ASN"); err == nil {
+		t.Error("missing edition: no error")
+	}
+	if _, err := extractMMDB(strings.NewReader("not gzip"), "GeoLite2-City"); err == nil {
+		t.Error("not an archive: no error")
+	}
+}
+
+func TestDownloadGeoIPDatabase(t *testing.T) {
+	body := geoLiteArchive(t, map[string]string{"GeoLite2-City_20261013/GeoLite2-City.mmdb": "not a database"})
+	var query string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query = r.URL.RawQuery
+		w.Write(body)
+	}))
+	defer server.Close()
+	defer func(original string) { maxmindDownloadURL = original }(maxmindDownloadURL)
+	maxmindDownloadURL = server.URL
+
+	dbPath := filepath.Join(t.TempDir(), "GeoLite2-City.mmdb")
+	os.WriteFile(dbPath, []byte("current"), 0o644)
+	db := &geoIPDatabase{edition: "GeoLite2-City", path: dbPath}
+
+	// An invalid database is rejected and the file in use is kept
+	if err := downloadGeoIPDatabase(context.Background(), "secret-key", db); err == nil || !strings.Contains(err.Error(), "invalid GeoLite2-City database") {
+		t.Errorf("invalid database: err = %v", err)
+	}
+	if !strings.Contains(query, "edition_id=GeoLite2-City") || !strings.Contains(query, "license_key=secret-key") {
+		t.Errorf("query = %q", query)
+	}
+	if data, _ := os.ReadFile(dbPath); string(data) != "current" {
+		t.Errorf("database file replaced with %q", data)
+	}
+
+	// Errors do not reveal the license key carried in the URL
+	server.Close()
+	err := downloadGeoIPDatabase(context.Background(), "secret-key", db)
+	if err == nil || strings.Contains(err.Error(), "secret-key") {
+		t.Errorf("unreachable server: err = %v", err)
+	}
+}
+
+func TestGeoIPStale(t *testing.T) {
+	defer func(original time.Duration) { geoIPStaleAfter = original }(geoIPStaleAfter)
+	ConfigureGeoIPStaleness(30)
+	if !geoIPStale(time.Now().Add(-31 * 24 * time.Hour)) {
+		t.Error("31-day-old build not stale")
+	}
+	if geoIPStale(time.Now().Add(-29*24*time.Hour)) || geoIPStale(time.Time{}) {
+		t.Error("recent or unknown build reported stale")
+	}
+}
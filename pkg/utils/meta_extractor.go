@@ -0,0 +1,184 @@
+package utils
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// MetaTag is one Open Graph or Twitter Card property, in document order.
+type MetaTag struct {
+	Property string `json:"property"` // e.g. "og:image" or "twitter:card"
+	Content  string `json:"content"`
+}
+
+// Favicon is an icon declared with a <link rel="icon">-style element.
+type Favicon struct {
+	URL   string `json:"url"`
+	Rel   string `json:"rel"` // icon, shortcut icon, apple-touch-icon, mask-icon, ...
+	Sizes string `json:"sizes,omitempty"`
+	Type  string `json:"type,omitempty"`
+}
+
+// HreflangLink is an alternate language version of the page.
+type HreflangLink struct {
+	Lang string `json:"lang"`
+	URL  string `json:"url"`
+}
+
+// PageMetadata is the metadata declared in a page's markup.
+type PageMetadata struct {
+	FinalURL     string
+	Title        string
+	Description  string
+	CanonicalURL string
+	OpenGraph    []MetaTag
+	TwitterCard  []MetaTag
+	Favicons     []Favicon
+	Hreflang     []HreflangLink
+	JSONLD       []any    // Parsed application/ld+json blocks
+	JSONLDErrors []string // Blocks that are not valid JSON
+	CurlCommand  string
+}
+
+// ExtractMetadataFromHTML reads the title, description, canonical URL, Open Graph and Twitter
+// Card tags, icons, hreflang alternates and JSON-LD blocks from an HTML document. Link URLs
+// are resolved against baseURL.
+func ExtractMetadataFromHTML(body []byte, baseURL string) *PageMetadata {
+	metadata := &PageMetadata{FinalURL: baseURL}
+	doc, err := html.Parse(bytes.NewReader(body))
+	if err != nil { // The parser recovers from malformed markup, so this means a read error
+		return metadata
+	}
+	base, _ := url.Parse(baseURL)
+	resolve := func(href string) string {
+		href = strings.TrimSpace(href)
+		if base == nil {
+			return href
+		}
+		ref, err := url.Parse(href)
+		if err != nil {
+			return href
+		}
+		return base.ResolveReference(ref).String()
+	}
+
+	var walk func(*html.Node, bool)
+	walk = func(n *html.Node, inSVG bool) {
+		if n.Type == html.ElementNode {
+			switch n.Data {
+			case "svg":
+				inSVG = true // Its <title> elements describe graphics, not the page
+			case "title":
+				if metadata.Title == "" && !inSVG {
+					metadata.Title = strings.Join(strings.Fields(htmlText(n)), " ")
+				}
+			case "meta":
+				addMetaTag(metadata, n)
+			case "link":
+				addLinkTag(metadata, n, resolve)
+			case "script":
+				if strings.EqualFold(strings.TrimSpace(strings.Split(htmlAttr(n, "type"), ";")[0]), "application/ld+json") {
+					var block any
+					if err := json.Unmarshal([]byte(htmlText(n)), &block); err != nil {
+						metadata.JSONLDErrors = append(metadata.JSONLDErrors, fmt.Sprintf("block %d: %v", len(metadata.JSONLD)+len(metadata.JSONLDErrors)+1, err))
+					} else {
+						metadata.JSONLD = append(metadata.JSONLD, block)
+					}
+				}
+				return
+			}
+		}
+		for child := n.FirstChild; child != nil; child = child.NextSibling {
+			walk(child, inSVG)
+		}
+	}
+	walk(doc, false)
+	return metadata
+}
+
+func addMetaTag(metadata *PageMetadata, n *html.Node) {
+	content := strings.TrimSpace(htmlAttr(n, "content"))
+	name := strings.ToLower(strings.TrimSpace(htmlAttr(n, "name")))
+	property := strings.ToLower(strings.TrimSpace(htmlAttr(n, "property")))
+	if property == "" {
+		property = name // Twitter Cards use name=, but many sites write property= for both
+	}
+	switch {
+	case name == "description":
+		if metadata.Description == "" {
+			metadata.Description = content
+		}
+	case strings.HasPrefix(property, "og:"):
+		metadata.OpenGraph = append(metadata.OpenGraph, MetaTag{Property: property, Content: content})
+	case strings.HasPrefix(property, "twitter:"):
+		metadata.TwitterCard = append(metadata.TwitterCard, MetaTag{Property: property, Content: content})
+	}
+}
+
+func addLinkTag(metadata *PageMetadata, n *html.Node, resolve func(string) string) {
+	href := htmlAttr(n, "href")
+	if strings.TrimSpace(href) == "" {
+		return
+	}
+	rel := strings.ToLower(strings.Join(strings.Fields(htmlAttr(n, "rel")), " "))
+	relTypes := strings.Fields(rel)
+	hasRel := func(want string) bool {
+		for _, r := range relTypes {
+			if r == want {
+				return true
+			}
+		}
+		return false
+	}
+	switch {
+	case hasRel("canonical"):
+		if metadata.CanonicalURL == "" {
+			metadata.CanonicalURL = resolve(href)
+		}
+	case hasRel("alternate") && htmlAttr(n, "hreflang") != "":
+		metadata.Hreflang = append(metadata.Hreflang, HreflangLink{Lang: strings.TrimSpace(htmlAttr(n, "hreflang")), URL: resolve(href)})
+	case hasRel("icon") || hasRel("apple-touch-icon") || hasRel("apple-touch-icon-precomposed") || hasRel("mask-icon"):
+		metadata.Favicons = append(metadata.Favicons, Favicon{URL: resolve(href), Rel: rel, Sizes: htmlAttr(n, "sizes"), Type: htmlAttr(n, "type")})
+	}
+}
+
+// htmlAttr returns the value of an element's attribute, or "" if it is not set.
+func htmlAttr(n *html.Node, key string) string {
+	for _, attr := range n.Attr {
+		if attr.Key == key {
+			return attr.Val
+		}
+	}
+	return ""
+}
+
+// htmlText concatenates the text inside an element.
+func htmlText(n *html.Node) string {
+	var text strings.Builder
+	var collect func(*html.Node)
+	collect = func(n *html.Node) {
+		if n.Type == html.TextNode {
+			text.WriteString(n.Data)
+		}
+		for child := n.FirstChild; child != nil; child = child.NextSibling {
+			collect(child)
+		}
+	}
+	collect(n)
+	return text.String()
+}
+
+// ExtractFetchedMetadata extracts the page metadata from an already fetched (or captured) page.
+func ExtractFetchedMetadata(targetURL string, fetchResult *FetchResult) (*PageMetadata, error) {
+	if fetchResult.StatusCode != 200 {
+		return &PageMetadata{FinalURL: fetchResult.FinalURL, CurlCommand: fetchResult.CurlCommand}, fmt.Errorf("failed to fetch %s: received status code %d (%s)", targetURL, fetchResult.StatusCode, fetchResult.Status)
+	}
+	metadata := ExtractMetadataFromHTML(DecodeResponseBody(fetchResult), fetchResult.FinalURL)
+	metadata.CurlCommand = fetchResult.CurlCommand
+	return metadata, nil
+}
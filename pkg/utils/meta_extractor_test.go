@@ -0,0 +1,78 @@
+package utils
+
+import (
+	"strings"
+	"testing"
+)
+
+const metadataPage = `<!doctype html>
+<html lang="en"><head>
+<title>
+  Example   Shop
+</title>
+<meta name="description" content=" Everything for the example household. ">
+<meta name="DESCRIPTION" content="ignored second description">
+<link rel="canonical" href="/products">
+<meta property="og:title" content="Example Shop">
+<meta property="og:image" content="https://cdn.example.com/a.png">
+<meta property="og:image" content="https://cdn.example.com/b.png">
+<meta name="twitter:card" content="summary_large_image">
+<meta property="twitter:site" content="@example">
+<link rel="icon" type="image/png" sizes="32x32" href="/favicon-32.png">
+<link rel="Shortcut  Icon" href="favicon.ico">
+<link rel="apple-touch-icon" href="https://static.example.com/touch.png">
+<link rel="alternate" hreflang="de" href="https://example.com/de/products">
+<link rel="alternate" type="application/rss+xml" href="/feed.xml">
+<link rel="stylesheet" href="/site.css">
+<script type="application/ld+json">{"@context": "https://schema.org", "@type": "Organization", "name": "Example"}</script>
+<script type="application/ld+json">{"@type": "broken",}</script>
+</head><body>
+<svg><title>Cart icon</title></svg>
+<script>var title = "<title>Not the title</title>";</script>
+</body></html>`
+
+func TestExtractMetadataFromHTML(t *testing.T) {
+	metadata := ExtractMetadataFromHTML([]byte(metadataPage), "https://example.com/shop/index.html")
+
+	if metadata.Title != "Example Shop" {
+		t.Errorf("Title = %q", metadata.Title)
+	}
+	if metadata.Description != "Everything for the example household." {
+		t.Errorf("Description = %q", metadata.Description)
+	}
+	if metadata.CanonicalURL != "https://example.com/products" {
+		t.Errorf("CanonicalURL = %q", metadata.CanonicalURL)
+	}
+	if len(metadata.OpenGraph) != 3 || metadata.OpenGraph[2] != (MetaTag{"og:image", "https://cdn.example.com/b.png"}) {
+		t.Errorf("OpenGraph = %+v, want all three tags with repeats kept", metadata.OpenGraph)
+	}
+	if len(metadata.TwitterCard) != 2 || metadata.TwitterCard[1].Property != "twitter:site" {
+		t.Errorf("TwitterCard = %+v, want the name= and property= tags", metadata.TwitterCard)
+	}
+	var icons []string
+	for _, icon := range metadata.Favicons {
+		icons = append(icons, icon.Rel+" "+icon.URL)
+	}
+	if got := strings.Join(icons, ", "); got != "icon https://example.com/favicon-32.png, shortcut icon https://example.com/shop/favicon.ico, apple-touch-icon https://static.example.com/touch.png" {
+		t.Errorf("Favicons = %s", got)
+	}
+	if metadata.Favicons[0].Sizes != "32x32" || metadata.Favicons[0].Type != "image/png" {
+		t.Errorf("first favicon = %+v", metadata.Favicons[0])
+	}
+	if len(metadata.Hreflang) != 1 || metadata.Hreflang[0] != (HreflangLink{"de", "https://example.com/de/products"}) {
+		t.Errorf("Hreflang = %+v", metadata.Hreflang)
+	}
+	if len(metadata.JSONLD) != 1 || metadata.JSONLD[0].(map[string]any)["@type"] != "Organization" {
+		t.Errorf("JSONLD = %+v", metadata.JSONLD)
+	}
+	if len(metadata.JSONLDErrors) != 1 || !strings.HasPrefix(metadata.JSONLDErrors[0], "block 2:") {
+		t.Errorf("JSONLDErrors = %q", metadata.JSONLDErrors)
+	}
+}
+
+func TestExtractMetadataFromHTMLEmpty(t *testing.T) {
+	metadata := ExtractMetadataFromHTML([]byte("plain text"), "https://example.com/")
+	if metadata.Title != "" || metadata.CanonicalURL != "" || len(metadata.Favicons) != 0 || len(metadata.JSONLD) != 0 {
+		t.Errorf("metadata of a page without markup = %+v", metadata)
+	}
+}
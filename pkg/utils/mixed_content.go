@@ -0,0 +1,155 @@
+package utils
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// Kinds of mixed content, by how browsers treat them on an HTTPS page.
+const (
+	MixedContentActive  = "active"  // Scripts, stylesheets, frames and plugins: blocked
+	MixedContentPassive = "passive" // Images, audio and video: loaded or upgraded with a warning
+	MixedContentForm    = "form"    // A form submitting over http: warned about before sending
+)
+
+// maxMixedContentContext caps the markup quoted for each resource.
+const maxMixedContentContext = 200
+
+// MixedContentResource is an http:// resource referenced by a page.
+type MixedContentResource struct {
+	Tag       string `json:"tag"`
+	Attribute string `json:"attribute"`
+	URL       string `json:"url"`
+	Kind      string `json:"kind"` // active, passive or form
+	Line      int    `json:"line"`
+	Context   string `json:"context"` // The tag as written, shortened
+}
+
+// MixedContentReport is the result of scanning a page for insecure resources.
+type MixedContentReport struct {
+	FinalURL    string
+	PageIsHTTPS bool
+	Resources   []MixedContentResource
+	CurlCommand string
+}
+
+// mixedContentAttributes lists the URL attributes checked per tag and the kind of content
+// they load. link elements are handled separately as their kind depends on rel.
+var mixedContentAttributes = map[string]map[string]string{
+	"script": {"src": MixedContentActive},
+	"iframe": {"src": MixedContentActive},
+	"frame":  {"src": MixedContentActive},
+	"object": {"data": MixedContentActive},
+	"embed":  {"src": MixedContentActive},
+	"img":    {"src": MixedContentPassive, "srcset": MixedContentPassive},
+	"source": {"src": MixedContentPassive, "srcset": MixedContentPassive},
+	"audio":  {"src": MixedContentPassive},
+	"video":  {"src": MixedContentPassive, "poster": MixedContentPassive},
+	"input":  {"src": MixedContentPassive}, // type=image
+	"form":   {"action": MixedContentForm},
+	"button": {"formaction": MixedContentForm},
+}
+
+// ScanMixedContent lists the http:// scripts, stylesheets, images, frames and form actions
+// of an HTML document with the line each appears on.
+func ScanMixedContent(body []byte) []MixedContentResource {
+	resources := []MixedContentResource{}
+	tokenizer := html.NewTokenizer(bytes.NewReader(body))
+	line := 1
+	for {
+		tokenType := tokenizer.Next()
+		if tokenType == html.ErrorToken {
+			return resources
+		}
+		raw := tokenizer.Raw()
+		tokenLine := line
+		line += bytes.Count(raw, []byte("\n"))
+		if tokenType != html.StartTagToken && tokenType != html.SelfClosingTagToken {
+			continue
+		}
+		context := mixedContentContext(string(raw)) // Before Token, which lowercases the buffer
+
+		token := tokenizer.Token()
+		attributes := mixedContentAttributes[token.Data]
+		if token.Data == "link" {
+			for _, attr := range token.Attr {
+				if attr.Key == "rel" {
+					attributes = linkMixedContentAttributes(attr.Val)
+				}
+			}
+		}
+		for _, attr := range token.Attr {
+			kind, ok := attributes[attr.Key]
+			if !ok {
+				continue
+			}
+			for _, resourceURL := range attributeURLs(attr) {
+				if !strings.HasPrefix(strings.ToLower(resourceURL), "http://") {
+					continue
+				}
+				resources = append(resources, MixedContentResource{
+					Tag:       token.Data,
+					Attribute: attr.Key,
+					URL:       resourceURL,
+					Kind:      kind,
+					Line:      tokenLine,
+					Context:   context,
+				})
+			}
+		}
+	}
+}
+
+// linkMixedContentAttributes returns the kind of content a <link> with the given rel loads.
+// Links that are not fetched while rendering, such as canonical or alternate, are ignored.
+func linkMixedContentAttributes(rel string) map[string]string {
+	for _, value := range strings.Fields(strings.ToLower(rel)) {
+		switch value {
+		case "stylesheet", "preload", "modulepreload", "import":
+			return map[string]string{"href": MixedContentActive}
+		case "icon", "apple-touch-icon", "mask-icon":
+			return map[string]string{"href": MixedContentPassive}
+		}
+	}
+	return nil
+}
+
+// attributeURLs returns the URLs of an attribute; srcset holds several, each with a descriptor.
+func attributeURLs(attr html.Attribute) []string {
+	if attr.Key != "srcset" {
+		return []string{strings.TrimSpace(attr.Val)}
+	}
+	var urls []string
+	for _, candidate := range strings.Split(attr.Val, ",") {
+		if fields := strings.Fields(candidate); len(fields) > 0 {
+			urls = append(urls, fields[0])
+		}
+	}
+	return urls
+}
+
+// mixedContentContext collapses the whitespace of a tag and shortens it.
+func mixedContentContext(raw string) string {
+	context := strings.Join(strings.Fields(raw), " ")
+	if len(context) > maxMixedContentContext {
+		context = strings.ToValidUTF8(context[:maxMixedContentContext], "") + "…"
+	}
+	return context
+}
+
+// ScanFetchedMixedContent scans a fetched page for insecure resources.
+func ScanFetchedMixedContent(targetURL string, fetchResult *FetchResult) (*MixedContentReport, error) {
+	report := &MixedContentReport{
+		FinalURL:    fetchResult.FinalURL,
+		PageIsHTTPS: strings.HasPrefix(strings.ToLower(fetchResult.FinalURL), "https://"),
+		CurlCommand: fetchResult.CurlCommand,
+	}
+	if fetchResult.StatusCode != 200 {
+		return report, fmt.Errorf("failed to fetch %s: received status code %d (%s)", targetURL, fetchResult.StatusCode, fetchResult.Status)
+	}
+	report.Resources = ScanMixedContent(DecodeResponseBody(fetchResult))
+	return report, nil
+}
@@ -0,0 +1,47 @@
+package utils
+
+import "testing"
+
+func TestScanMixedContent(t *testing.T) {
+	body := []byte(`<!DOCTYPE html>
+<html>
+<head>
+  <link rel="stylesheet" href="http://cdn.example.com/site.css">
+  <link rel="canonical" href="http://example.com/">
+  <script src="//cdn.example.com/app.js"></script>
+  <script src="HTTP://ads.example.com/ads.js"></script>
+</head>
+<body>
+  <img src="/logo.png" srcset="http://img.example.com/a.png 1x, https://img.example.com/b.png 2x">
+  <iframe
+    src="http://widgets.example.com/embed"></iframe>
+  <form action="http://example.com/login" method="post"></form>
+  <a href="http://example.org/">A link is not loaded</a>
+</body>
+</html>`)
+
+	resources := ScanMixedContent(body)
+	want := []MixedContentResource{
+		{Tag: "link", Attribute: "href", URL: "http://cdn.example.com/site.css", Kind: MixedContentActive, Line: 4},
+		{Tag: "script", Attribute: "src", URL: "HTTP://ads.example.com/ads.js", Kind: MixedContentActive, Line: 7},
+		{Tag: "img", Attribute: "srcset", URL: "http://img.example.com/a.png", Kind: MixedContentPassive, Line: 10},
+		{Tag: "iframe", Attribute: "src", URL: "http://widgets.example.com/embed", Kind: MixedContentActive, Line: 11},
+		{Tag: "form", Attribute: "action", URL: "http://example.com/login", Kind: MixedContentForm, Line: 13},
+	}
+	if len(resources) != len(want) {
+		t.Fatalf("resources = %+v, want %d", resources, len(want))
+	}
+	for i, resource := range resources {
+		context := resource.Context
+		resource.Context = ""
+		if resource != want[i] {
+			t.Errorf("resource %d = %+v, want %+v", i, resource, want[i])
+		}
+		if context == "" {
+			t.Errorf("resource %d has no context", i)
+		}
+	}
+	if resources[3].Context != `<iframe src="http://widgets.example.com/embed">` {
+		t.Errorf("context = %q, want the tag on one line", resources[3].Context)
+	}
+}
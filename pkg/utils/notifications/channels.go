@@ -0,0 +1,202 @@
+package notifications
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/smtp"
+	"net/url"
+	"strconv"
+	"strings"
+	"text/template"
+
+	"github.com/vit0-9/utils_api/pkg/utils"
+)
+
+var httpClient = &http.Client{Timeout: sendTimeout, Transport: utils.NewOutboundTransport()}
+
+// postJSON sends a JSON body and treats any non-2xx status as a failure.
+func postJSON(ctx context.Context, target string, body []byte, headers map[string]string) error {
+	req, err := http.NewRequestWithContext(ctx, "POST", target, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for name, value := range headers {
+		req.Header.Set(name, value)
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("%s returned status %d", target, resp.StatusCode)
+	}
+	return nil
+}
+
+func validateURL(config ChannelConfig) error {
+	parsed, err := url.Parse(config.URL)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		return fmt.Errorf("url must be an http(s) URL")
+	}
+	return nil
+}
+
+// webhookChannel posts the notification as JSON (or the rendered template as the body).
+// With a secret, the body is signed: X-Signature-256: sha256=<hex HMAC-SHA256(secret, body)>.
+type webhookChannel struct {
+	url    string
+	secret string
+	tmpl   *template.Template
+}
+
+func newWebhookChannel(config ChannelConfig, tmpl *template.Template) (Channel, error) {
+	if err := validateURL(config); err != nil {
+		return nil, err
+	}
+	return &webhookChannel{url: config.URL, secret: config.Secret, tmpl: tmpl}, nil
+}
+
+func (c *webhookChannel) Send(ctx context.Context, notification Notification) error {
+	var body []byte
+	if c.tmpl != nil {
+		text, err := renderText(c.tmpl, notification)
+		if err != nil {
+			return err
+		}
+		body = []byte(text)
+	} else {
+		var err error
+		if body, err = json.Marshal(notification); err != nil {
+			return err
+		}
+	}
+
+	headers := map[string]string{
+		"X-Notification-Event":     notification.Event,
+		"X-Notification-Timestamp": strconv.FormatInt(notification.Time.Unix(), 10),
+	}
+	if c.secret != "" {
+		mac := hmac.New(sha256.New, []byte(c.secret))
+		mac.Write(body)
+		headers["X-Signature-256"] = "sha256=" + hex.EncodeToString(mac.Sum(nil))
+	}
+	return postJSON(ctx, c.url, body, headers)
+}
+
+// chatChannel posts the rendered text to a chat service's incoming webhook.
+type chatChannel struct {
+	url     string
+	tmpl    *template.Template
+	payload func(notification Notification, text string) any
+}
+
+func (c *chatChannel) Send(ctx context.Context, notification Notification) error {
+	text, err := renderText(c.tmpl, notification)
+	if err != nil {
+		return err
+	}
+	body, err := json.Marshal(c.payload(notification, text))
+	if err != nil {
+		return err
+	}
+	return postJSON(ctx, c.url, body, nil)
+}
+
+func newSlackChannel(config ChannelConfig, tmpl *template.Template) (Channel, error) {
+	if err := validateURL(config); err != nil {
+		return nil, err
+	}
+	return &chatChannel{url: config.URL, tmpl: tmpl, payload: func(_ Notification, text string) any {
+		return map[string]string{"text": text}
+	}}, nil
+}
+
+func newDiscordChannel(config ChannelConfig, tmpl *template.Template) (Channel, error) {
+	if err := validateURL(config); err != nil {
+		return nil, err
+	}
+	return &chatChannel{url: config.URL, tmpl: tmpl, payload: func(_ Notification, text string) any {
+		if len(text) > 2000 { // Discord's message length limit
+			text = text[:1997] + "..."
+		}
+		return map[string]string{"content": text}
+	}}, nil
+}
+
+func newTeamsChannel(config ChannelConfig, tmpl *template.Template) (Channel, error) {
+	if err := validateURL(config); err != nil {
+		return nil, err
+	}
+	return &chatChannel{url: config.URL, tmpl: tmpl, payload: func(notification Notification, text string) any {
+		return map[string]string{
+			"@type":    "MessageCard",
+			"@context": "https://schema.org/extensions",
+			"summary":  notification.Title,
+			"title":    notification.Title,
+			"text":     text,
+		}
+	}}, nil
+}
+
+// smtpChannel emails the rendered text, with the title as the subject.
+type smtpChannel struct {
+	address string
+	auth    smtp.Auth
+	from    string
+	to      []string
+	tmpl    *template.Template
+}
+
+func newSMTPChannel(config ChannelConfig, tmpl *template.Template) (Channel, error) {
+	if config.Host == "" || config.From == "" || len(config.To) == 0 {
+		return nil, fmt.Errorf("smtp channels need host, from and to")
+	}
+	port := config.Port
+	if port == 0 {
+		port = 587
+	}
+	channel := &smtpChannel{
+		address: config.Host + ":" + strconv.Itoa(port),
+		from:    config.From,
+		to:      config.To,
+		tmpl:    tmpl,
+	}
+	if config.Username != "" {
+		channel.auth = smtp.PlainAuth("", config.Username, config.Password, config.Host)
+	}
+	return channel, nil
+}
+
+func (c *smtpChannel) Send(ctx context.Context, notification Notification) error {
+	text, err := renderText(c.tmpl, notification)
+	if err != nil {
+		return err
+	}
+	subject := strings.NewReplacer("\r", " ", "\n", " ").Replace(notification.Title)
+
+	var message bytes.Buffer
+	fmt.Fprintf(&message, "From: %s\r\n", c.from)
+	fmt.Fprintf(&message, "To: %s\r\n", strings.Join(c.to, ", "))
+	fmt.Fprintf(&message, "Subject: %s\r\n", subject)
+	message.WriteString("MIME-Version: 1.0\r\nContent-Type: text/plain; charset=utf-8\r\n\r\n")
+	message.WriteString(strings.ReplaceAll(text, "\n", "\r\n"))
+
+	done := make(chan error, 1)
+	go func() { done <- smtp.SendMail(c.address, c.auth, c.from, c.to, message.Bytes()) }()
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,201 @@
+// Package notifications delivers event notifications, such as batch job completion, to
+// outgoing channels such as webhooks, chat services and email.
+package notifications
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+
+	"github.com/vit0-9/utils_api/pkg/utils/eventlog"
+)
+
+// Event names emitted by the API.
+const (
+	EventJobCompleted = "job.completed" // A batch lookup finished, e.g. a bulk IP info request
+	EventMonitorAlert = "monitor.alert" // A portfolio job found something needing attention, e.g. an expiring certificate
+)
+
+// Notification is a single event to deliver.
+type Notification struct {
+	Event   string         `json:"event"`
+	Title   string         `json:"title"`
+	Message string         `json:"message"`
+	Data    map[string]any `json:"data,omitempty"` // Event specific details, e.g. the job result
+	Time    time.Time      `json:"time"`
+}
+
+// ChannelConfig configures one delivery channel. Fields that do not apply to a channel type
+// are ignored.
+type ChannelConfig struct {
+	Name     string   `json:"name"`
+	Type     string   `json:"type"`             // webhook, slack, discord, teams or smtp
+	Events   []string `json:"events,omitempty"` // Events delivered to this channel; empty means all
+	Template string   `json:"template,omitempty"`
+
+	// Webhook, Slack, Discord and Teams
+	URL    string `json:"url,omitempty"`
+	Secret string `json:"secret,omitempty"` // Webhook HMAC-SHA256 signing key
+
+	// SMTP
+	Host     string   `json:"host,omitempty"`
+	Port     int      `json:"port,omitempty"`
+	Username string   `json:"username,omitempty"`
+	Password string   `json:"password,omitempty"`
+	From     string   `json:"from,omitempty"`
+	To       []string `json:"to,omitempty"`
+}
+
+// Channel delivers notifications to one destination.
+type Channel interface {
+	Send(ctx context.Context, notification Notification) error
+}
+
+// ChannelFactory builds a channel from its configuration and parsed template (nil when the
+// configuration has none).
+type ChannelFactory func(config ChannelConfig, tmpl *template.Template) (Channel, error)
+
+var (
+	factoriesMu sync.RWMutex
+	factories   = map[string]ChannelFactory{
+		"webhook": newWebhookChannel,
+		"slack":   newSlackChannel,
+		"discord": newDiscordChannel,
+		"teams":   newTeamsChannel,
+		"smtp":    newSMTPChannel,
+	}
+)
+
+// RegisterChannelType makes a custom channel type available to the configuration.
+func RegisterChannelType(channelType string, factory ChannelFactory) {
+	factoriesMu.Lock()
+	defer factoriesMu.Unlock()
+	factories[strings.ToLower(channelType)] = factory
+}
+
+// configuredChannel is a channel with the settings the dispatcher needs.
+type configuredChannel struct {
+	name    string
+	events  map[string]bool
+	channel Channel
+}
+
+var (
+	channelsMu sync.RWMutex
+	channels   []configuredChannel
+)
+
+// sendTimeout bounds a single delivery.
+const sendTimeout = 15 * time.Second
+
+// LoadConfig reads the channel configuration (a JSON array of ChannelConfig) from a file.
+// An empty path leaves notifications disabled.
+func LoadConfig(path string) {
+	if path == "" {
+		return
+	}
+	fileData, err := os.ReadFile(path)
+	if err != nil {
+		log.Fatalf("Could not read notification config at %s: %v", path, err)
+	}
+	var configs []ChannelConfig
+	if err := json.Unmarshal(fileData, &configs); err != nil {
+		log.Fatalf("Could not parse notification config at %s: %v", path, err)
+	}
+	if err := Configure(configs); err != nil {
+		log.Fatalf("Invalid notification config at %s: %v", path, err)
+	}
+	log.Printf("Notification channels loaded from %s (%d channels)", path, len(configs))
+}
+
+// Configure replaces the configured channels.
+func Configure(configs []ChannelConfig) error {
+	built := make([]configuredChannel, 0, len(configs))
+	for i, config := range configs {
+		if config.Name == "" {
+			config.Name = fmt.Sprintf("%s-%d", config.Type, i+1)
+		}
+		factoriesMu.RLock()
+		factory, ok := factories[strings.ToLower(config.Type)]
+		factoriesMu.RUnlock()
+		if !ok {
+			return fmt.Errorf("channel %s: unknown type %q", config.Name, config.Type)
+		}
+
+		var tmpl *template.Template
+		if config.Template != "" {
+			var err error
+			if tmpl, err = template.New(config.Name).Parse(config.Template); err != nil {
+				return fmt.Errorf("channel %s: invalid template: %w", config.Name, err)
+			}
+		}
+		channel, err := factory(config, tmpl)
+		if err != nil {
+			return fmt.Errorf("channel %s: %w", config.Name, err)
+		}
+
+		configured := configuredChannel{name: config.Name, channel: channel}
+		if len(config.Events) > 0 {
+			configured.events = make(map[string]bool)
+			for _, event := range config.Events {
+				configured.events[event] = true
+			}
+		}
+		built = append(built, configured)
+	}
+
+	channelsMu.Lock()
+	channels = built
+	channelsMu.Unlock()
+	return nil
+}
+
+// Notify delivers a notification to every channel subscribed to its event, in the
+// background, and adds it to the event log. Delivery failures are logged.
+func Notify(notification Notification) {
+	if notification.Time.IsZero() {
+		notification.Time = time.Now().UTC()
+	}
+	severity := 3
+	if notification.Event == EventMonitorAlert {
+		severity = 7
+	}
+	eventlog.Append(eventlog.TypeMonitor, notification.Event, severity, map[string]any{"title": notification.Title, "message": notification.Message, "details": notification.Data})
+
+	channelsMu.RLock()
+	defer channelsMu.RUnlock()
+	for _, configured := range channels {
+		if configured.events != nil && !configured.events[notification.Event] {
+			continue
+		}
+		go func(configured configuredChannel) {
+			ctx, cancel := context.WithTimeout(context.Background(), sendTimeout)
+			defer cancel()
+			if err := configured.channel.Send(ctx, notification); err != nil {
+				log.Printf("ERROR: Notification %s to channel %s failed: %v", notification.Event, configured.name, err)
+			}
+		}(configured)
+	}
+}
+
+// renderText renders the channel template, or "Title: Message" when there is none.
+func renderText(tmpl *template.Template, notification Notification) (string, error) {
+	if tmpl == nil {
+		if notification.Message == "" {
+			return notification.Title, nil
+		}
+		return notification.Title + ": " + notification.Message, nil
+	}
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, notification); err != nil {
+		return "", fmt.Errorf("failed to render template: %w", err)
+	}
+	return buf.String(), nil
+}
@@ -0,0 +1,133 @@
+package notifications
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"text/template"
+	"time"
+)
+
+// recordingChannel passes every notification it is sent to a Go channel.
+type recordingChannel struct {
+	sent chan Notification
+}
+
+func (c *recordingChannel) Send(_ context.Context, notification Notification) error {
+	c.sent <- notification
+	return nil
+}
+
+func TestNotifyFiltersByEvent(t *testing.T) {
+	recorders := map[string]*recordingChannel{}
+	RegisterChannelType("recording", func(config ChannelConfig, _ *template.Template) (Channel, error) {
+		recorder := &recordingChannel{sent: make(chan Notification, 4)}
+		recorders[config.Name] = recorder
+		return recorder, nil
+	})
+	err := Configure([]ChannelConfig{
+		{Name: "all", Type: "recording"},
+		{Name: "jobs", Type: "Recording", Events: []string{EventJobCompleted}},
+		{Name: "other", Type: "recording", Events: []string{"other.event"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { Configure(nil) })
+
+	Notify(Notification{Event: EventJobCompleted, Title: "Done"})
+	for _, name := range []string{"all", "jobs"} {
+		select {
+		case got := <-recorders[name].sent:
+			if got.Title != "Done" || got.Time.IsZero() {
+				t.Errorf("channel %s received %+v, want the notification with a time set", name, got)
+			}
+		case <-time.After(2 * time.Second):
+			t.Errorf("channel %s received nothing", name)
+		}
+	}
+	select {
+	case got := <-recorders["other"].sent:
+		t.Errorf("channel subscribed to other events received %+v", got)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+func TestConfigureErrors(t *testing.T) {
+	tests := []struct {
+		config ChannelConfig
+		want   string
+	}{
+		{ChannelConfig{Type: "pager"}, `channel pager-1: unknown type "pager"`},
+		{ChannelConfig{Name: "hook", Type: "webhook", URL: "ftp://example.com"}, "channel hook: url must be an http(s) URL"},
+		{ChannelConfig{Name: "hook", Type: "webhook", URL: "https://example.com", Template: "{{.Title"}, "channel hook: invalid template"},
+	}
+	for _, tt := range tests {
+		if err := Configure([]ChannelConfig{tt.config}); err == nil || !strings.HasPrefix(err.Error(), tt.want) {
+			t.Errorf("Configure(%+v) error = %v, want %q", tt.config, err, tt.want)
+		}
+	}
+}
+
+func TestWebhookSignsBody(t *testing.T) {
+	type request struct {
+		header http.Header
+		body   []byte
+	}
+	received := make(chan request, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		received <- request{r.Header, body}
+	}))
+	defer server.Close()
+
+	channel, err := newWebhookChannel(ChannelConfig{URL: server.URL, Secret: "s3cret"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	notification := Notification{Event: EventJobCompleted, Title: "Done", Data: map[string]any{"count": 2}, Time: time.Unix(1700000000, 0).UTC()}
+	if err := channel.Send(context.Background(), notification); err != nil {
+		t.Fatal(err)
+	}
+
+	got := <-received
+	mac := hmac.New(sha256.New, []byte("s3cret"))
+	mac.Write(got.body)
+	if want := "sha256=" + hex.EncodeToString(mac.Sum(nil)); got.header.Get("X-Signature-256") != want {
+		t.Errorf("X-Signature-256 = %q, want %q", got.header.Get("X-Signature-256"), want)
+	}
+	if got.header.Get("X-Notification-Event") != EventJobCompleted || got.header.Get("X-Notification-Timestamp") != "1700000000" {
+		t.Errorf("notification headers = %v", got.header)
+	}
+	var decoded Notification
+	if err := json.Unmarshal(got.body, &decoded); err != nil || decoded.Title != "Done" || decoded.Data["count"] != float64(2) {
+		t.Errorf("body = %s (%v), want the notification as JSON", got.body, err)
+	}
+}
+
+func TestRenderText(t *testing.T) {
+	notification := Notification{Event: EventJobCompleted, Title: "Done", Message: "3 addresses", Data: map[string]any{"job": "ip-info-bulk"}}
+	tests := []struct {
+		template string
+		want     string
+	}{
+		{"", "Done: 3 addresses"},
+		{"[{{.Event}}] {{.Data.job}}", "[job.completed] ip-info-bulk"},
+	}
+	for _, tt := range tests {
+		var tmpl *template.Template
+		if tt.template != "" {
+			tmpl = template.Must(template.New("test").Parse(tt.template))
+		}
+		if got, err := renderText(tmpl, notification); err != nil || got != tt.want {
+			t.Errorf("renderText(%q) = %q, %v; want %q", tt.template, got, err, tt.want)
+		}
+	}
+}
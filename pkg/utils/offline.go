@@ -0,0 +1,255 @@
+package utils
+
+import (
+	"bytes"
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+// offlineFixturesJSON is the built-in cassette, so a bare offline instance answers for
+// example.com and example.org.
+//
+//go:embed offline_fixtures.json
+var offlineFixturesJSON []byte
+
+// ErrOffline is returned (wrapped) for outbound calls that have no recorded fixture while
+// the API runs in offline mode.
+var ErrOffline = errors.New("offline mode: no recorded fixture")
+
+// OfflineCassette holds the recorded DNS answers, WHOIS responses and HTTP responses
+// served in offline mode.
+type OfflineCassette struct {
+	DNS   []OfflineDNSFixture   `json:"dns"`
+	Whois []OfflineWhoisFixture `json:"whois"`
+	HTTP  []OfflineHTTPFixture  `json:"http"`
+}
+
+// OfflineDNSFixture is the answer to one name and record type. MX values are "pref host",
+// the other types one record value each.
+type OfflineDNSFixture struct {
+	Name   string   `json:"name"`
+	Type   string   `json:"type"`
+	Values []string `json:"values"`
+}
+
+// OfflineWhoisFixture is the raw response to a WHOIS query, whichever server it is sent to.
+type OfflineWhoisFixture struct {
+	Query    string `json:"query"`
+	Response string `json:"response"`
+}
+
+// OfflineHTTPFixture is the response to a request for a URL.
+type OfflineHTTPFixture struct {
+	Method  string            `json:"method,omitempty"` // Default GET; HEAD requests get the GET fixture without its body
+	URL     string            `json:"url"`
+	Status  int               `json:"status"`
+	Headers map[string]string `json:"headers,omitempty"`
+	Body    string            `json:"body,omitempty"`
+}
+
+var (
+	offlineMu       sync.RWMutex
+	offlineEnabled  bool
+	offlineDNS      map[string][]string // By "name TYPE"
+	offlineWhois    map[string]string   // By lowercased query
+	offlineHTTP     map[string]OfflineHTTPFixture
+	offlineDNSNames map[string]bool // Names with any fixture; others are NXDOMAIN
+)
+
+// ConfigureOfflineMode switches outbound-dependent utilities to recorded fixtures: the
+// built-in cassette, extended or overridden by the JSON cassette at cassettePath, if any.
+// Every other outbound connection fails, so nothing reaches the network.
+func ConfigureOfflineMode(enabled bool, cassettePath string) {
+	if !enabled {
+		return
+	}
+	var cassettes []OfflineCassette
+	var builtIn OfflineCassette
+	if err := json.Unmarshal(offlineFixturesJSON, &builtIn); err != nil {
+		log.Fatalf("Could not parse the built-in offline fixtures: %v", err)
+	}
+	cassettes = append(cassettes, builtIn)
+	if cassettePath != "" {
+		fileData, err := os.ReadFile(cassettePath)
+		if err != nil {
+			log.Fatalf("Could not read offline cassette at %s: %v", cassettePath, err)
+		}
+		var cassette OfflineCassette
+		if err := json.Unmarshal(fileData, &cassette); err != nil {
+			log.Fatalf("Could not parse offline cassette at %s: %v", cassettePath, err)
+		}
+		cassettes = append(cassettes, cassette)
+	}
+	if err := loadOfflineCassettes(cassettes...); err != nil {
+		log.Fatalf("Invalid offline cassette: %v", err)
+	}
+	log.Printf("Offline mode: outbound lookups are answered from fixtures (%d DNS names, %d WHOIS queries, %d URLs)", len(offlineDNSNames), len(offlineWhois), len(offlineHTTP))
+}
+
+// loadOfflineCassettes enables offline mode with the fixtures of the cassettes; later
+// cassettes override earlier ones.
+func loadOfflineCassettes(cassettes ...OfflineCassette) error {
+	dnsFixtures := make(map[string][]string)
+	names := make(map[string]bool)
+	whoisFixtures := make(map[string]string)
+	httpFixtures := make(map[string]OfflineHTTPFixture)
+	for _, cassette := range cassettes {
+		for _, fixture := range cassette.DNS {
+			name := strings.TrimSuffix(strings.ToLower(fixture.Name), ".")
+			recordType := strings.ToUpper(fixture.Type)
+			if _, ok := dnsRecordTypes[recordType]; !ok {
+				return fmt.Errorf("DNS fixture for %s: unsupported type %q", fixture.Name, fixture.Type)
+			}
+			dnsFixtures[name+" "+recordType] = fixture.Values
+			names[name] = true
+		}
+		for _, fixture := range cassette.Whois {
+			whoisFixtures[strings.ToLower(strings.TrimSpace(fixture.Query))] = fixture.Response
+		}
+		for _, fixture := range cassette.HTTP {
+			if fixture.Method == "" {
+				fixture.Method = http.MethodGet
+			}
+			if fixture.Status == 0 {
+				fixture.Status = http.StatusOK
+			}
+			httpFixtures[strings.ToUpper(fixture.Method)+" "+fixture.URL] = fixture
+		}
+	}
+	offlineMu.Lock()
+	defer offlineMu.Unlock()
+	offlineEnabled = true
+	offlineDNS, offlineDNSNames, offlineWhois, offlineHTTP = dnsFixtures, names, whoisFixtures, httpFixtures
+	return nil
+}
+
+// OfflineMode reports whether outbound lookups are answered from fixtures.
+func OfflineMode() bool {
+	offlineMu.RLock()
+	defer offlineMu.RUnlock()
+	return offlineEnabled
+}
+
+// dnsRecordTypes are the record types fixtures can hold.
+var dnsRecordTypes = map[string]dnsmessage.Type{
+	"A":     dnsmessage.TypeA,
+	"AAAA":  dnsmessage.TypeAAAA,
+	"CNAME": dnsmessage.TypeCNAME,
+	"NS":    dnsmessage.TypeNS,
+	"MX":    dnsmessage.TypeMX,
+	"TXT":   dnsmessage.TypeTXT,
+	"PTR":   dnsmessage.TypePTR,
+}
+
+// offlineDNSAnswers returns the recorded answer to a query. Names without any fixture do
+// not exist; known names without records of the type have an empty answer.
+func offlineDNSAnswers(name string, qtype dnsmessage.Type) ([]dnsmessage.Resource, error) {
+	name = strings.TrimSuffix(strings.ToLower(name), ".")
+	offlineMu.RLock()
+	known := offlineDNSNames[name]
+	var values []string
+	for typeName, recordType := range dnsRecordTypes {
+		if recordType == qtype {
+			values = offlineDNS[name+" "+typeName]
+		}
+	}
+	offlineMu.RUnlock()
+	if !known {
+		return nil, fmt.Errorf("lookup %s via offline fixtures: %w", name, ErrDNSNameNotFound)
+	}
+
+	var answers []dnsmessage.Resource
+	for _, value := range values {
+		var body dnsmessage.ResourceBody
+		switch qtype {
+		case dnsmessage.TypeA, dnsmessage.TypeAAAA:
+			ip := net.ParseIP(value)
+			if ip == nil {
+				return nil, fmt.Errorf("offline fixture for %s: invalid address %q", name, value)
+			}
+			if ip4 := ip.To4(); qtype == dnsmessage.TypeA && ip4 != nil {
+				body = &dnsmessage.AResource{A: [4]byte(ip4)}
+			} else {
+				body = &dnsmessage.AAAAResource{AAAA: [16]byte(ip.To16())}
+			}
+		case dnsmessage.TypeCNAME:
+			body = &dnsmessage.CNAMEResource{CNAME: dnsName(value)}
+		case dnsmessage.TypeNS:
+			body = &dnsmessage.NSResource{NS: dnsName(value)}
+		case dnsmessage.TypePTR:
+			body = &dnsmessage.PTRResource{PTR: dnsName(value)}
+		case dnsmessage.TypeMX:
+			pref, host, _ := strings.Cut(value, " ")
+			prefValue, err := strconv.ParseUint(pref, 10, 16)
+			if err != nil {
+				return nil, fmt.Errorf("offline fixture for %s: invalid MX %q", name, value)
+			}
+			body = &dnsmessage.MXResource{Pref: uint16(prefValue), MX: dnsName(host)}
+		case dnsmessage.TypeTXT:
+			body = &dnsmessage.TXTResource{TXT: []string{value}}
+		default:
+			body = &dnsmessage.UnknownResource{Type: qtype, Data: []byte(value)}
+		}
+		answers = append(answers, dnsmessage.Resource{
+			Header: dnsmessage.ResourceHeader{Name: dnsName(name), Type: qtype, Class: dnsmessage.ClassINET},
+			Body:   body,
+		})
+	}
+	return answers, nil
+}
+
+// OfflineWhois returns the recorded response to a WHOIS query.
+func OfflineWhois(query string) (string, error) {
+	offlineMu.RLock()
+	defer offlineMu.RUnlock()
+	response, ok := offlineWhois[strings.ToLower(strings.TrimSpace(query))]
+	if !ok {
+		return "", fmt.Errorf("whois %s: %w", query, ErrOffline)
+	}
+	return response, nil
+}
+
+// offlineRoundTrip answers a request with its recorded response.
+func offlineRoundTrip(req *http.Request) (*http.Response, error) {
+	method := req.Method
+	if method == http.MethodHead {
+		method = http.MethodGet
+	}
+	offlineMu.RLock()
+	fixture, ok := offlineHTTP[method+" "+req.URL.String()]
+	offlineMu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("%s %s: %w", req.Method, req.URL, ErrOffline)
+	}
+	header := make(http.Header)
+	for name, value := range fixture.Headers {
+		header.Set(name, value)
+	}
+	body := fixture.Body
+	if req.Method == http.MethodHead {
+		body = ""
+	}
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", fixture.Status, http.StatusText(fixture.Status)),
+		StatusCode:    fixture.Status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          io.NopCloser(bytes.NewReader([]byte(body))),
+		ContentLength: int64(len(body)),
+		Request:       req,
+	}, nil
+}
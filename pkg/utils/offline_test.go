@@ -0,0 +1,70 @@
+package utils
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"testing"
+
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+func TestOfflineMode(t *testing.T) {
+	defer func() {
+		offlineMu.Lock()
+		offlineEnabled = false
+		offlineMu.Unlock()
+	}()
+	err := loadOfflineCassettes(OfflineCassette{
+		DNS: []OfflineDNSFixture{
+			{Name: "shop.test.", Type: "a", Values: []string{"192.0.2.10"}},
+			{Name: "shop.test", Type: "MX", Values: []string{"10 mail.shop.test"}},
+		},
+		Whois: []OfflineWhoisFixture{{Query: "shop.test", Response: "Domain Name: SHOP.TEST\n"}},
+		HTTP: []OfflineHTTPFixture{
+			{URL: "http://shop.test/", Status: http.StatusMovedPermanently, Headers: map[string]string{"Location": "https://shop.test/"}},
+			{URL: "https://shop.test/", Body: "<html>shop</html>"},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	resolver, err := NewDNSResolver("")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	records, lookupErrors := LookupDNSRecordsWithResolver(ctx, resolver, "shop.test", []string{"A", "MX", "TXT"})
+	if len(lookupErrors) > 0 || len(records["A"]) != 1 || records["A"][0].Value != "192.0.2.10" || len(records["MX"]) != 1 || len(records["TXT"]) != 0 {
+		t.Errorf("LookupDNSRecords() = %+v, %v; want the A and MX fixtures", records, lookupErrors)
+	}
+	if _, err := resolver.Query(ctx, "unknown.test", dnsmessage.TypeA); !errors.Is(err, ErrDNSNameNotFound) {
+		t.Errorf("Query(unknown.test) error = %v, want NXDOMAIN", err)
+	}
+
+	if response, err := OfflineWhois("SHOP.test"); err != nil || response != "Domain Name: SHOP.TEST\n" {
+		t.Errorf("OfflineWhois() = %q, %v", response, err)
+	}
+	if _, err := OfflineWhois("unknown.test"); !errors.Is(err, ErrOffline) {
+		t.Errorf("OfflineWhois(unknown.test) error = %v, want ErrOffline", err)
+	}
+
+	client := &http.Client{Transport: NewOutboundTransport()}
+	resp, err := client.Get("http://shop.test/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || resp.Request.URL.String() != "https://shop.test/" || string(body) != "<html>shop</html>" {
+		t.Errorf("GET followed to %s with %d %q, want the recorded page", resp.Request.URL, resp.StatusCode, body)
+	}
+	if _, err := client.Get("https://unknown.test/"); !errors.Is(err, ErrOffline) {
+		t.Errorf("GET of an unrecorded URL error = %v, want ErrOffline", err)
+	}
+	if _, err := PolicyDialContext(nil)(ctx, "tcp", "192.0.2.10:443"); !errors.Is(err, ErrOffline) {
+		t.Errorf("dial error = %v, want ErrOffline", err)
+	}
+}
@@ -0,0 +1,237 @@
+package utils
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// PolicyRuleSet is a list of targets matched by domain (including subdomains), CIDR range or regex.
+type PolicyRuleSet struct {
+	Domains []string `json:"domains,omitempty"`
+	CIDRs   []string `json:"cidrs,omitempty"`
+	Regex   []string `json:"regex,omitempty"`
+
+	networks []*net.IPNet
+	patterns []*regexp.Regexp
+}
+
+// OutboundPolicy decides which targets the API may contact.
+// Deny rules always win; when any allow rule is configured, targets must match one of them.
+type OutboundPolicy struct {
+	Allow PolicyRuleSet `json:"allow"`
+	Deny  PolicyRuleSet `json:"deny"`
+}
+
+// PolicyDeniedError is returned when a target is blocked by the outbound policy.
+type PolicyDeniedError struct {
+	Target string
+	Reason string
+}
+
+func (e *PolicyDeniedError) Error() string {
+	return fmt.Sprintf("outbound request to %s denied by policy: %s", e.Target, e.Reason)
+}
+
+// outboundPolicy is nil when no policy is configured, which allows every target.
+var outboundPolicy *OutboundPolicy
+
+func (rs *PolicyRuleSet) compile() error {
+	for i, d := range rs.Domains {
+		rs.Domains[i] = strings.ToLower(strings.TrimSuffix(strings.TrimSpace(d), "."))
+	}
+	for _, cidr := range rs.CIDRs {
+		_, network, err := net.ParseCIDR(strings.TrimSpace(cidr))
+		if err != nil {
+			return fmt.Errorf("invalid CIDR %q: %w", cidr, err)
+		}
+		rs.networks = append(rs.networks, network)
+	}
+	for _, expr := range rs.Regex {
+		re, err := CompileRegexp(expr)
+		if err != nil {
+			return fmt.Errorf("invalid regex %q: %w", expr, err)
+		}
+		rs.patterns = append(rs.patterns, re)
+	}
+	return nil
+}
+
+func (rs *PolicyRuleSet) isEmpty() bool {
+	return len(rs.Domains) == 0 && len(rs.networks) == 0 && len(rs.patterns) == 0
+}
+
+func (rs *PolicyRuleSet) hasNameRules() bool {
+	return len(rs.Domains) > 0 || len(rs.patterns) > 0
+}
+
+// matchName returns the rule matching a host name (or IP literal), if any.
+func (rs *PolicyRuleSet) matchName(host string) (string, bool) {
+	for _, d := range rs.Domains {
+		if host == d || strings.HasSuffix(host, "."+d) {
+			return "domain " + d, true
+		}
+	}
+	for _, re := range rs.patterns {
+		if re.MatchString(host) {
+			return "regex " + re.String(), true
+		}
+	}
+	return "", false
+}
+
+// matchIP returns the CIDR rule containing ip, if any.
+func (rs *PolicyRuleSet) matchIP(ip net.IP) (string, bool) {
+	for _, network := range rs.networks {
+		if network.Contains(ip) {
+			return "cidr " + network.String(), true
+		}
+	}
+	return "", false
+}
+
+// LoadOutboundPolicy reads the outbound policy from a JSON file. An empty path disables the policy.
+func LoadOutboundPolicy(path string) {
+	if path == "" {
+		return
+	}
+	fileData, err := os.ReadFile(path)
+	if err != nil {
+		log.Fatalf("Could not read outbound policy at %s: %v", path, err)
+	}
+	var policy OutboundPolicy
+	if err := json.Unmarshal(fileData, &policy); err != nil {
+		log.Fatalf("Could not parse outbound policy at %s: %v", path, err)
+	}
+	if err := policy.Allow.compile(); err != nil {
+		log.Fatalf("Invalid allow rule in outbound policy: %v", err)
+	}
+	if err := policy.Deny.compile(); err != nil {
+		log.Fatalf("Invalid deny rule in outbound policy: %v", err)
+	}
+	outboundPolicy = &policy
+	log.Printf("Outbound policy loaded from %s (allow: %d domains, %d CIDRs, %d regex; deny: %d domains, %d CIDRs, %d regex)",
+		path, len(policy.Allow.Domains), len(policy.Allow.networks), len(policy.Allow.patterns),
+		len(policy.Deny.Domains), len(policy.Deny.networks), len(policy.Deny.patterns))
+}
+
+func denyOutbound(target, reason string) error {
+	log.Printf("AUDIT: outbound request denied: target=%s reason=%q", target, reason)
+	return &PolicyDeniedError{Target: target, Reason: reason}
+}
+
+func normalizePolicyHost(host string) string {
+	return strings.ToLower(strings.TrimSuffix(strings.Trim(host, "[]"), "."))
+}
+
+// CheckOutboundName applies the name-based rules to a target that is looked up rather than
+// connected to (DNS queries, WHOIS query strings). CIDR rules are enforced at dial time.
+func CheckOutboundName(name string) error {
+	if outboundPolicy == nil {
+		return nil
+	}
+	host := normalizePolicyHost(name)
+	if ip := net.ParseIP(host); ip != nil {
+		return CheckOutboundAddress(host, []net.IP{ip})
+	}
+	if rule, ok := outboundPolicy.Deny.matchName(host); ok {
+		return denyOutbound(host, "matches deny "+rule)
+	}
+	if outboundPolicy.Allow.hasNameRules() {
+		if _, ok := outboundPolicy.Allow.matchName(host); !ok {
+			return denyOutbound(host, "not in allowlist")
+		}
+	}
+	return nil
+}
+
+// CheckOutboundAddress applies all rules to a host and the IPs it resolved to.
+func CheckOutboundAddress(host string, ips []net.IP) error {
+	if outboundPolicy == nil {
+		return nil
+	}
+	host = normalizePolicyHost(host)
+	if rule, ok := outboundPolicy.Deny.matchName(host); ok {
+		return denyOutbound(host, "matches deny "+rule)
+	}
+	for _, ip := range ips {
+		if rule, ok := outboundPolicy.Deny.matchIP(ip); ok {
+			return denyOutbound(host, fmt.Sprintf("resolved address %s matches deny %s", ip, rule))
+		}
+	}
+
+	if outboundPolicy.Allow.isEmpty() {
+		return nil
+	}
+	if _, ok := outboundPolicy.Allow.matchName(host); ok {
+		return nil
+	}
+	if len(ips) > 0 && len(outboundPolicy.Allow.networks) > 0 {
+		for _, ip := range ips {
+			if _, ok := outboundPolicy.Allow.matchIP(ip); !ok {
+				return denyOutbound(host, fmt.Sprintf("resolved address %s not in allowlist", ip))
+			}
+		}
+		return nil
+	}
+	return denyOutbound(host, "not in allowlist")
+}
+
+// PolicyDialContext wraps a dialer so every outbound connection is checked against the policy.
+// Names are resolved before dialing and the connection is made to the checked address,
+// so the policy cannot be bypassed by DNS answers changing between check and connect.
+func PolicyDialContext(dialer *net.Dialer) func(ctx context.Context, network, addr string) (net.Conn, error) {
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		conn, err := policyDial(ctx, dialer, network, addr, false)
+		if err != nil {
+			TimingRecorderFrom(ctx).AddFailure(err)
+		}
+		return conn, err
+	}
+}
+
+// policyDial checks the target of one connection against the policy, and against the SSRF
+// protection when it is a caller-supplied target, and dials it.
+func policyDial(ctx context.Context, dialer *net.Dialer, network, addr string, target bool) (net.Conn, error) {
+	if OfflineMode() {
+		return nil, fmt.Errorf("connect to %s: %w", addr, ErrOffline)
+	}
+	if (outboundPolicy == nil && !(target && ssrfProtection)) || isOutboundProxy(ctx, addr) {
+		return dialer.DialContext(ctx, network, addr)
+	}
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, err
+	}
+
+	ips, err := resolveTarget(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+	if target {
+		if err := checkSSRF(host, ips); err != nil {
+			return nil, err
+		}
+	}
+	if err := CheckOutboundAddress(host, ips); err != nil {
+		return nil, err
+	}
+
+	if len(ips) == 0 {
+		return nil, fmt.Errorf("no addresses found for %s", host)
+	}
+	var lastErr error
+	for _, ip := range ips {
+		conn, err := dialer.DialContext(ctx, network, net.JoinHostPort(ip.String(), port))
+		if err == nil {
+			return conn, nil
+		}
+		lastErr = err
+	}
+	return nil, lastErr
+}
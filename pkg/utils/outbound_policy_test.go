@@ -0,0 +1,116 @@
+package utils
+
+import (
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// withOutboundPolicy loads a policy from JSON for the duration of the test.
+func withOutboundPolicy(t *testing.T, policyJSON string) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "policy.json")
+	if err := os.WriteFile(path, []byte(policyJSON), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	LoadOutboundPolicy(path)
+	t.Cleanup(func() { outboundPolicy = nil })
+}
+
+func TestCheckOutboundNameWithoutPolicy(t *testing.T) {
+	for _, name := range []string{"example.com", "10.0.0.1", "localhost"} {
+		if err := CheckOutboundName(name); err != nil {
+			t.Errorf("CheckOutboundName(%q) = %v, want nil without a policy", name, err)
+		}
+	}
+}
+
+func TestCheckOutboundName(t *testing.T) {
+	withOutboundPolicy(t, `{
+		"allow": {"domains": ["example.com", "Example.ORG."], "regex": ["^api[0-9]+\\.test$"]},
+		"deny":  {"domains": ["internal.example.com"], "cidrs": ["10.0.0.0/8"]}
+	}`)
+
+	tests := []struct {
+		name    string
+		allowed bool
+	}{
+		{"example.com", true},
+		{"www.example.com", true},
+		{"EXAMPLE.COM.", true},
+		{"example.org", true},
+		{"api7.test", true},
+		{"internal.example.com", false}, // Deny wins over the broader allow rule
+		{"db.internal.example.com", false},
+		{"notexample.com", false}, // Suffix match is on label boundaries
+		{"api.test", false},
+		{"other.net", false},
+		{"10.1.2.3", false}, // IP literals go through the CIDR rules
+		{"[10.1.2.3]", false},
+	}
+	for _, tt := range tests {
+		err := CheckOutboundName(tt.name)
+		if tt.allowed && err != nil {
+			t.Errorf("CheckOutboundName(%q) = %v, want allowed", tt.name, err)
+		}
+		if !tt.allowed {
+			var denied *PolicyDeniedError
+			if !errors.As(err, &denied) {
+				t.Errorf("CheckOutboundName(%q) = %v, want a PolicyDeniedError", tt.name, err)
+			}
+		}
+	}
+}
+
+func TestCheckOutboundAddress(t *testing.T) {
+	withOutboundPolicy(t, `{
+		"allow": {"cidrs": ["203.0.113.0/24", "2001:db8::/32"]},
+		"deny":  {"cidrs": ["203.0.113.128/25"], "domains": ["blocked.test"]}
+	}`)
+
+	tests := []struct {
+		host    string
+		ips     []string
+		allowed bool
+	}{
+		{"a.test", []string{"203.0.113.10"}, true},
+		{"a.test", []string{"2001:db8::1"}, true},
+		{"a.test", []string{"203.0.113.10", "198.51.100.1"}, false}, // Every resolved address must be allowed
+		{"a.test", []string{"203.0.113.200"}, false},                // Deny CIDR inside the allowed range
+		{"blocked.test", []string{"203.0.113.10"}, false},
+		{"a.test", nil, false},
+	}
+	for _, tt := range tests {
+		var ips []net.IP
+		for _, ip := range tt.ips {
+			ips = append(ips, net.ParseIP(ip))
+		}
+		err := CheckOutboundAddress(tt.host, ips)
+		if (err == nil) != tt.allowed {
+			t.Errorf("CheckOutboundAddress(%q, %v) = %v, want allowed=%v", tt.host, tt.ips, err, tt.allowed)
+		}
+	}
+}
+
+func TestOutboundTransportIgnoresProxyEnvironment(t *testing.T) {
+	t.Setenv("HTTP_PROXY", "http://proxy.invalid:3128")
+	t.Setenv("HTTPS_PROXY", "http://proxy.invalid:3128")
+
+	transport, ok := NewOutboundTransport().(*throttledTransport)
+	if !ok {
+		t.Fatalf("NewOutboundTransport() returned %T, want *throttledTransport", NewOutboundTransport())
+	}
+	base, ok := transport.base.(*http.Transport)
+	if !ok {
+		t.Fatalf("throttledTransport.base is %T, want *http.Transport", transport.base)
+	}
+	// Only a proxy set with ConfigureOutboundProxy is used, after the policy checked the target
+	req := httptest.NewRequest(http.MethodGet, "http://example.com/", nil)
+	if proxyURL, err := base.Proxy(req); err != nil || proxyURL != nil {
+		t.Errorf("outbound transport uses proxy %v (err %v) from the environment; policy checks would only see the proxy address", proxyURL, err)
+	}
+}
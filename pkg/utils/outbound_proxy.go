@@ -0,0 +1,122 @@
+package utils
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// outboundProxy routes the outbound HTTP requests through an egress proxy, set by
+// ConfigureOutboundProxy.
+var outboundProxy *url.URL
+
+type outboundProxyKey struct{}
+
+// ParseOutboundProxy parses an http://, https:// or socks5:// proxy URL, with credentials in
+// its user info.
+func ParseOutboundProxy(rawURL string) (*url.URL, error) {
+	proxyURL, err := url.Parse(strings.TrimSpace(rawURL))
+	if err != nil {
+		return nil, fmt.Errorf("invalid proxy URL: %w", err)
+	}
+	switch proxyURL.Scheme {
+	case "http", "https", "socks5", "socks5h":
+	default:
+		return nil, fmt.Errorf("invalid proxy URL: scheme must be http, https or socks5, got %q", proxyURL.Scheme)
+	}
+	if proxyURL.Hostname() == "" {
+		return nil, fmt.Errorf("invalid proxy URL: no host")
+	}
+	return proxyURL, nil
+}
+
+// ConfigureOutboundProxy sends page fetches, redirect resolution, stack analysis and the other
+// outbound HTTP requests through the proxy at rawURL. Other connections (DNS, WHOIS, TLS
+// checks) are not proxied.
+func ConfigureOutboundProxy(rawURL string) {
+	if strings.TrimSpace(rawURL) == "" {
+		return
+	}
+	proxyURL, err := ParseOutboundProxy(rawURL)
+	if err != nil {
+		log.Printf("ERROR: Outbound proxy not used: %v", err)
+		return
+	}
+	outboundProxy = proxyURL
+	log.Printf("Outbound HTTP requests go through the %s proxy at %s", proxyURL.Scheme, proxyURL.Host)
+}
+
+// WithOutboundProxy returns a context whose outbound HTTP requests go through proxyURL instead
+// of the configured proxy.
+func WithOutboundProxy(ctx context.Context, proxyURL *url.URL) context.Context {
+	return context.WithValue(ctx, outboundProxyKey{}, proxyURL)
+}
+
+// OutboundProxyOverridden reports whether ctx carries a proxy set with WithOutboundProxy.
+func OutboundProxyOverridden(ctx context.Context) bool {
+	_, ok := ctx.Value(outboundProxyKey{}).(*url.URL)
+	return ok
+}
+
+// outboundProxyFor returns the proxy a request goes through, or nil to connect directly.
+func outboundProxyFor(ctx context.Context) *url.URL {
+	if proxyURL, ok := ctx.Value(outboundProxyKey{}).(*url.URL); ok {
+		return proxyURL
+	}
+	return outboundProxy
+}
+
+// proxyForRequest is the Proxy function of the outbound transports. As the proxy connects to
+// the target, the target is checked against the outbound policy here, with the addresses
+// it resolves to locally.
+func proxyForRequest(req *http.Request) (*url.URL, error) {
+	return proxyFor(req, false)
+}
+
+// proxyForTargetRequest is the Proxy function of the transports for caller-supplied URLs,
+// which also applies the SSRF protection to the target.
+func proxyForTargetRequest(req *http.Request) (*url.URL, error) {
+	return proxyFor(req, true)
+}
+
+func proxyFor(req *http.Request, target bool) (*url.URL, error) {
+	proxyURL := outboundProxyFor(req.Context())
+	if proxyURL == nil || (outboundPolicy == nil && !(target && ssrfProtection)) {
+		return proxyURL, nil
+	}
+	host := req.URL.Hostname()
+	ips, err := resolveTarget(req.Context(), host)
+	if err != nil {
+		return nil, err
+	}
+	if target {
+		if err := checkSSRF(host, ips); err != nil {
+			return nil, err
+		}
+	}
+	if err := CheckOutboundAddress(host, ips); err != nil {
+		return nil, err
+	}
+	return proxyURL, nil
+}
+
+// isOutboundProxy reports whether addr is the address of the proxy used in ctx, which the
+// operator or a trusted caller chose, so it is dialed without applying the policy.
+func isOutboundProxy(ctx context.Context, addr string) bool {
+	proxyURL := outboundProxyFor(ctx)
+	if proxyURL == nil {
+		return false
+	}
+	port := proxyURL.Port()
+	if port == "" {
+		port = map[string]string{"http": "80", "https": "443"}[proxyURL.Scheme]
+		if port == "" {
+			port = "1080"
+		}
+	}
+	return addr == net.JoinHostPort(proxyURL.Hostname(), port)
+}
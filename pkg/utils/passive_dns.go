@@ -0,0 +1,154 @@
+package utils
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// PassiveDNSProvider looks up the domains historically observed resolving to an IP.
+// Implement it to add a backend, and select it with ConfigurePassiveDNS.
+type PassiveDNSProvider interface {
+	Name() string
+	DomainsForIP(ctx context.Context, ip string) ([]string, error)
+}
+
+// passiveDNSProviders builds the supported providers from an API key.
+var passiveDNSProviders = map[string]func(apiKey string) (PassiveDNSProvider, error){
+	"hackertarget": func(apiKey string) (PassiveDNSProvider, error) {
+		return &hackerTargetProvider{apiKey: apiKey}, nil
+	},
+	"securitytrails": func(apiKey string) (PassiveDNSProvider, error) {
+		if apiKey == "" {
+			return nil, fmt.Errorf("securitytrails requires an API key")
+		}
+		return &securityTrailsProvider{apiKey: apiKey}, nil
+	},
+}
+
+var (
+	passiveDNSProvider PassiveDNSProvider // nil when no provider is configured
+	passiveDNSClient   = &http.Client{Timeout: 20 * time.Second, Transport: NewOutboundTransport()}
+)
+
+// ConfigurePassiveDNS selects the passive DNS provider used by reverse IP lookups.
+// An empty name disables passive DNS, leaving PTR records as the only source.
+func ConfigurePassiveDNS(name, apiKey string) {
+	if name == "" {
+		return
+	}
+	factory, ok := passiveDNSProviders[strings.ToLower(name)]
+	if !ok {
+		log.Printf("ERROR: Unknown passive DNS provider %q. Reverse IP lookups will use PTR records only.", name)
+		return
+	}
+	provider, err := factory(apiKey)
+	if err != nil {
+		log.Printf("ERROR: Could not configure passive DNS provider %s: %v. Reverse IP lookups will use PTR records only.", name, err)
+		return
+	}
+	passiveDNSProvider = provider
+	log.Printf("Passive DNS provider: %s", provider.Name())
+}
+
+// hackerTargetProvider uses the HackerTarget reverse IP API, which returns one domain per
+// line. It works without a key at a low daily quota.
+type hackerTargetProvider struct {
+	apiKey string
+}
+
+func (p *hackerTargetProvider) Name() string { return "hackertarget" }
+
+func (p *hackerTargetProvider) DomainsForIP(ctx context.Context, ip string) ([]string, error) {
+	query := url.Values{"q": {ip}}
+	if p.apiKey != "" {
+		query.Set("apikey", p.apiKey)
+	}
+	req, err := http.NewRequestWithContext(ctx, "GET", "https://api.hackertarget.com/reverseiplookup/?"+query.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+	body, err := doPassiveDNSRequest(req)
+	if err != nil {
+		return nil, err
+	}
+
+	text := strings.TrimSpace(string(body))
+	if strings.HasPrefix(text, "No DNS A records found") {
+		return nil, nil
+	}
+	if strings.HasPrefix(text, "error") || strings.HasPrefix(text, "API count exceeded") {
+		return nil, fmt.Errorf("hackertarget: %s", text)
+	}
+	var domains []string
+	scanner := bufio.NewScanner(strings.NewReader(text))
+	for scanner.Scan() {
+		if domain := strings.TrimSpace(scanner.Text()); domain != "" {
+			domains = append(domains, domain)
+		}
+	}
+	return domains, nil
+}
+
+// securityTrailsProvider uses the SecurityTrails domain search filtered by IPv4 address.
+type securityTrailsProvider struct {
+	apiKey string
+}
+
+func (p *securityTrailsProvider) Name() string { return "securitytrails" }
+
+func (p *securityTrailsProvider) DomainsForIP(ctx context.Context, ip string) ([]string, error) {
+	filter := "ipv4"
+	if strings.Contains(ip, ":") {
+		filter = "ipv6"
+	}
+	payload, _ := json.Marshal(map[string]any{"filter": map[string]string{filter: ip}})
+	req, err := http.NewRequestWithContext(ctx, "POST", "https://api.securitytrails.com/v1/domains/list", bytes.NewReader(payload))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("APIKEY", p.apiKey)
+	req.Header.Set("Content-Type", "application/json")
+	body, err := doPassiveDNSRequest(req)
+	if err != nil {
+		return nil, err
+	}
+
+	var response struct {
+		Records []struct {
+			Hostname string `json:"hostname"`
+		} `json:"records"`
+	}
+	if err := json.Unmarshal(body, &response); err != nil {
+		return nil, fmt.Errorf("securitytrails: invalid response: %w", err)
+	}
+	var domains []string
+	for _, record := range response.Records {
+		domains = append(domains, record.Hostname)
+	}
+	return domains, nil
+}
+
+func doPassiveDNSRequest(req *http.Request) ([]byte, error) {
+	resp, err := passiveDNSClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 5<<20))
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s returned status %d", req.URL.Host, resp.StatusCode)
+	}
+	return body, nil
+}
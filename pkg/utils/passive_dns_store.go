@@ -0,0 +1,238 @@
+package utils
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/vit0-9/utils_api/pkg/utils/eventlog"
+	"github.com/vit0-9/utils_api/pkg/utils/lifecycle"
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+// Passive DNS store limits. When the store is full the least recently seen tenth of the
+// observations is evicted.
+const (
+	passiveDNSMaxObservations = 100000
+	passiveDNSSaveInterval    = time.Minute
+	passiveDNSMaxResults      = 1000
+)
+
+// PassiveDNSObservation is one DNS answer seen by the API, with when it was first and last
+// seen.
+type PassiveDNSObservation struct {
+	Name      string    `json:"name"`
+	Type      string    `json:"type"`
+	Value     string    `json:"value"`
+	FirstSeen time.Time `json:"first_seen"`
+	LastSeen  time.Time `json:"last_seen"`
+	Count     int       `json:"count"` // Number of times the answer was seen
+}
+
+// passiveDNSStore accumulates the answers of every query made through a DNSResolver, saved
+// to a JSON file when a path is configured.
+type passiveDNSStore struct {
+	mu           sync.Mutex
+	path         string
+	dirty        bool
+	observations map[string]*PassiveDNSObservation // By name, type and value
+}
+
+var passiveDNS = &passiveDNSStore{observations: make(map[string]*PassiveDNSObservation)}
+
+// passiveDNSTypeNames names the record types in observations.
+var passiveDNSTypeNames = func() map[dnsmessage.Type]string {
+	names := make(map[dnsmessage.Type]string, len(SupportedDNSRecordTypes))
+	for name, qtype := range SupportedDNSRecordTypes {
+		names[qtype] = name
+	}
+	return names
+}()
+
+// ConfigurePassiveDNSStore loads the local passive DNS history from path and saves it back
+// every minute. With an empty path the history is kept in memory and lost on restart.
+func ConfigurePassiveDNSStore(path string) {
+	if path == "" {
+		return
+	}
+	passiveDNS.mu.Lock()
+	defer passiveDNS.mu.Unlock()
+
+	data, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		log.Printf("ERROR: Could not read passive DNS store %s: %v. Observations will be kept in memory.", path, err)
+		return
+	}
+	if err == nil {
+		var stored []*PassiveDNSObservation
+		if err := json.Unmarshal(data, &stored); err != nil {
+			log.Printf("ERROR: Could not parse passive DNS store %s: %v. Observations will be kept in memory.", path, err)
+			return
+		}
+		for _, observation := range stored {
+			passiveDNS.observations[passiveDNSKey(observation.Name, observation.Type, observation.Value)] = observation
+		}
+	}
+	passiveDNS.path = path
+	log.Printf("Passive DNS observations stored in %s (%d observations)", path, len(passiveDNS.observations))
+
+	go func() {
+		for lifecycle.Sleep(passiveDNSSaveInterval) {
+			passiveDNS.save()
+		}
+	}()
+	lifecycle.Register("passive DNS store", func(context.Context) error {
+		passiveDNS.save() // The observations since the last periodic save
+		return nil
+	})
+}
+
+func passiveDNSKey(name, recordType, value string) string {
+	return name + "\x00" + recordType + "\x00" + value
+}
+
+// recordPassiveDNS adds the answers of a query to the local passive DNS history. Answers
+// seen for the first time also go to the event log.
+func recordPassiveDNS(answers []dnsmessage.Resource) {
+	if len(answers) == 0 {
+		return
+	}
+	now := time.Now().UTC()
+	passiveDNS.mu.Lock()
+	defer passiveDNS.mu.Unlock()
+	for _, answer := range answers {
+		recordType, ok := passiveDNSTypeNames[answer.Header.Type]
+		if !ok {
+			continue
+		}
+		record, ok := dnsRecordFromResource(recordType, answer)
+		if !ok {
+			continue
+		}
+		name := strings.ToLower(strings.TrimSuffix(answer.Header.Name.String(), "."))
+		if name == "" {
+			continue
+		}
+		value := strings.TrimSuffix(record.Value, ".")
+		key := passiveDNSKey(name, recordType, value)
+		observation, ok := passiveDNS.observations[key]
+		if !ok {
+			if len(passiveDNS.observations) >= passiveDNSMaxObservations {
+				passiveDNS.evictLocked()
+			}
+			observation = &PassiveDNSObservation{Name: name, Type: recordType, Value: value, FirstSeen: now}
+			passiveDNS.observations[key] = observation
+			eventlog.Append(eventlog.TypePassiveDNS, name, 1, map[string]any{"type": recordType, "value": value})
+		}
+		observation.LastSeen = now
+		observation.Count++
+		passiveDNS.dirty = true
+	}
+}
+
+// evictLocked drops the least recently seen tenth of the observations.
+func (s *passiveDNSStore) evictLocked() {
+	observations := make([]*PassiveDNSObservation, 0, len(s.observations))
+	for _, observation := range s.observations {
+		observations = append(observations, observation)
+	}
+	sort.Slice(observations, func(i, j int) bool { return observations[i].LastSeen.Before(observations[j].LastSeen) })
+	for _, observation := range observations[:len(observations)/10+1] {
+		delete(s.observations, passiveDNSKey(observation.Name, observation.Type, observation.Value))
+	}
+}
+
+// PassiveDNSByName returns the observations for a name, most recently seen first. A name
+// starting with "*." matches its subdomains as well.
+func PassiveDNSByName(name string) ([]PassiveDNSObservation, error) {
+	name = strings.ToLower(strings.TrimSuffix(strings.TrimSpace(name), "."))
+	base := strings.TrimPrefix(name, "*.")
+	wildcard := base != name
+	if _, err := dnsmessage.NewName(base + "."); err != nil || base == "" || strings.Contains(base, "*") {
+		return nil, fmt.Errorf("invalid name: %q", name)
+	}
+	return passiveDNS.find(func(observation *PassiveDNSObservation) bool {
+		if wildcard {
+			return observation.Name == base || strings.HasSuffix(observation.Name, "."+base)
+		}
+		return observation.Name == name
+	}), nil
+}
+
+// PassiveDNSByIP returns the A and AAAA observations pointing at an IP and the PTR records
+// of its reverse name, most recently seen first.
+func PassiveDNSByIP(ipStr string) ([]PassiveDNSObservation, error) {
+	ip := net.ParseIP(strings.TrimSpace(ipStr))
+	if ip == nil {
+		return nil, fmt.Errorf("invalid IP address: %q", ipStr)
+	}
+	reverse, err := reverseDNSName(ip.String())
+	if err != nil {
+		return nil, err
+	}
+	reverse = strings.TrimSuffix(reverse, ".")
+	return passiveDNS.find(func(observation *PassiveDNSObservation) bool {
+		switch observation.Type {
+		case "A", "AAAA":
+			return ip.Equal(net.ParseIP(observation.Value))
+		case "PTR":
+			return observation.Name == reverse
+		}
+		return false
+	}), nil
+}
+
+func (s *passiveDNSStore) find(match func(*PassiveDNSObservation) bool) []PassiveDNSObservation {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	found := []PassiveDNSObservation{}
+	for _, observation := range s.observations {
+		if match(observation) {
+			found = append(found, *observation)
+		}
+	}
+	sort.Slice(found, func(i, j int) bool {
+		if !found[i].LastSeen.Equal(found[j].LastSeen) {
+			return found[i].LastSeen.After(found[j].LastSeen)
+		}
+		return passiveDNSKey(found[i].Name, found[i].Type, found[i].Value) < passiveDNSKey(found[j].Name, found[j].Type, found[j].Value)
+	})
+	if len(found) > passiveDNSMaxResults {
+		found = found[:passiveDNSMaxResults]
+	}
+	return found
+}
+
+// save writes the observations to the store's file, replacing it atomically, when they
+// changed since the last save.
+func (s *passiveDNSStore) save() {
+	s.mu.Lock()
+	if s.path == "" || !s.dirty {
+		s.mu.Unlock()
+		return
+	}
+	observations := make([]*PassiveDNSObservation, 0, len(s.observations))
+	for _, observation := range s.observations {
+		copied := *observation
+		observations = append(observations, &copied)
+	}
+	s.dirty = false
+	path := s.path
+	s.mu.Unlock()
+
+	data, err := json.Marshal(observations)
+	if err != nil {
+		log.Printf("ERROR: Could not encode passive DNS observations: %v", err)
+		return
+	}
+	if err := WriteFileAtomic(path, data); err != nil {
+		log.Printf("ERROR: Could not save passive DNS observations to %s: %v", path, err)
+	}
+}
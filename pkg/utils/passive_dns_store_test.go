@@ -0,0 +1,85 @@
+package utils
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+// resetPassiveDNS empties the passive DNS store for a test.
+func resetPassiveDNS(t *testing.T) {
+	t.Helper()
+	passiveDNS = &passiveDNSStore{observations: make(map[string]*PassiveDNSObservation)}
+}
+
+func TestPassiveDNSRecordsResolverAnswers(t *testing.T) {
+	resetPassiveDNS(t)
+	resolver := serveZone(t, map[string][]dnsmessage.Resource{
+		"example.com":     {aRecord("192.0.2.10"), txtRecord("v=spf1 -all")},
+		"www.example.com": {aRecord("192.0.2.10")},
+	})
+	resolver.observed = true // Stands in for the system resolver
+	ctx := context.Background()
+	for i := 0; i < 2; i++ {
+		LookupDNSRecordsWithResolver(ctx, resolver, "example.com", []string{"A", "TXT"})
+	}
+	LookupDNSRecordsWithResolver(ctx, resolver, "www.example.com", []string{"A"})
+	LookupDNSRecordsWithResolver(ctx, resolver, "missing.example.com", []string{"A"})
+
+	observations, err := PassiveDNSByName("Example.com.")
+	if err != nil {
+		t.Fatalf("PassiveDNSByName() error = %v", err)
+	}
+	if len(observations) != 2 {
+		t.Fatalf("PassiveDNSByName(example.com) = %+v, want the A and TXT answers", observations)
+	}
+	for _, observation := range observations {
+		if observation.Count != 2 || observation.FirstSeen.After(observation.LastSeen) {
+			t.Errorf("observation = %+v, want it seen twice", observation)
+		}
+	}
+
+	if observations, _ := PassiveDNSByName("*.example.com"); len(observations) != 3 {
+		t.Errorf("PassiveDNSByName(*.example.com) = %+v, want 3 observations including www", observations)
+	}
+	observations, err = PassiveDNSByIP("192.0.2.10")
+	if err != nil || len(observations) != 2 {
+		t.Errorf("PassiveDNSByIP() = %+v, %v, want example.com and www.example.com", observations, err)
+	}
+	for _, bad := range []string{"", "*."} {
+		if _, err := PassiveDNSByName(bad); err == nil {
+			t.Errorf("PassiveDNSByName(%q) error = nil", bad)
+		}
+	}
+	if _, err := PassiveDNSByIP("not-an-ip"); err == nil {
+		t.Error("PassiveDNSByIP(not-an-ip) error = nil")
+	}
+
+	// Answers of a resolver the caller chose are not recorded
+	chosen := serveZone(t, map[string][]dnsmessage.Resource{"example.net": {aRecord("198.51.100.7")}})
+	if records, _ := LookupDNSRecordsWithResolver(ctx, chosen, "example.net", []string{"A"}); len(records) == 0 {
+		t.Fatal("lookup through the chosen resolver returned no records")
+	}
+	if observations, _ := PassiveDNSByName("example.net"); len(observations) != 0 {
+		t.Errorf("PassiveDNSByName(example.net) = %+v, want no answers from a caller-chosen resolver", observations)
+	}
+}
+
+func TestPassiveDNSStoreSavesAndLoads(t *testing.T) {
+	resetPassiveDNS(t)
+	path := filepath.Join(t.TempDir(), "passive-dns.json")
+	ConfigurePassiveDNSStore(path)
+	recordPassiveDNS([]dnsmessage.Resource{{
+		Header: dnsmessage.ResourceHeader{Name: dnsmessage.MustNewName("example.org."), Type: dnsmessage.TypeA},
+		Body:   &dnsmessage.AResource{A: [4]byte{192, 0, 2, 20}},
+	}})
+	passiveDNS.save()
+
+	resetPassiveDNS(t)
+	ConfigurePassiveDNSStore(path)
+	if observations, _ := PassiveDNSByIP("192.0.2.20"); len(observations) != 1 || observations[0].Name != "example.org" {
+		t.Errorf("PassiveDNSByIP() after reload = %+v, want the saved observation", observations)
+	}
+}
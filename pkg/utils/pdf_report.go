@@ -0,0 +1,326 @@
+package utils
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// A4 page layout, in PDF points.
+const (
+	pdfPageWidth   = 595.0
+	pdfPageHeight  = 842.0
+	pdfMargin      = 50.0
+	pdfFontSize    = 9.5
+	pdfLineHeight  = 13.0
+	pdfIndentStep  = 14.0
+	pdfFooterSpace = 30.0
+)
+
+// Glyph widths of the standard Helvetica fonts for characters 32-126, in 1/1000 em.
+var (
+	helveticaWidths = [95]int{
+		278, 278, 355, 556, 556, 889, 667, 191, 333, 333, 389, 584, 278, 333, 278, 278,
+		556, 556, 556, 556, 556, 556, 556, 556, 556, 556, 278, 278, 584, 584, 584, 556,
+		1015, 667, 667, 722, 722, 667, 611, 778, 722, 278, 500, 667, 556, 833, 722, 778,
+		667, 778, 722, 667, 611, 722, 667, 944, 667, 667, 611, 278, 278, 278, 469, 556,
+		333, 556, 556, 500, 556, 556, 278, 556, 556, 222, 222, 500, 222, 833, 556, 556,
+		556, 556, 333, 500, 278, 556, 500, 722, 500, 500, 500, 334, 260, 334, 584,
+	}
+	helveticaBoldWidths = [95]int{
+		278, 333, 474, 556, 556, 889, 722, 238, 333, 333, 389, 584, 278, 333, 278, 278,
+		556, 556, 556, 556, 556, 556, 556, 556, 556, 556, 333, 333, 584, 584, 584, 611,
+		975, 722, 722, 722, 722, 667, 611, 778, 722, 278, 556, 722, 611, 833, 722, 778,
+		667, 778, 722, 667, 611, 722, 667, 944, 667, 667, 611, 333, 278, 333, 584, 556,
+		333, 556, 611, 556, 611, 556, 333, 611, 611, 278, 278, 556, 278, 889, 611, 611,
+		611, 611, 389, 556, 333, 611, 556, 778, 556, 556, 500, 389, 280, 389, 584,
+	}
+)
+
+// pdfFont selects one of the two fonts embedded by name in every report.
+type pdfFont int
+
+const (
+	pdfRegular pdfFont = iota
+	pdfBold
+)
+
+// pdfText is a run of WinAnsi-encoded text placed on a page.
+type pdfText struct {
+	x, y  float64
+	font  pdfFont
+	size  float64
+	color string // PDF fill colour operands, e.g. "0.12 0.16 0.2"
+	text  string
+}
+
+// pdfRule is a horizontal line drawn across the page.
+type pdfRule struct {
+	x1, x2, y float64
+	color     string
+	width     float64
+}
+
+type pdfPage struct {
+	texts []pdfText
+	rules []pdfRule
+}
+
+// pdfLayout places the report on pages from top to bottom.
+type pdfLayout struct {
+	pages []*pdfPage
+	y     float64
+}
+
+const (
+	pdfTextColor  = "0.12 0.16 0.2"
+	pdfLabelColor = "0.24 0.3 0.35"
+	pdfMutedColor = "0.38 0.43 0.49"
+	pdfRuleColor  = "0.8 0.82 0.85"
+	pdfBrandColor = "0.16 0.35 0.62"
+	pdfYesColor   = "0.05 0.49 0.23"
+	pdfNoColor    = "0.71 0.14 0.09"
+)
+
+// RenderPDFReport renders a report response as a printable A4 PDF. It lays out the same
+// node tree as RenderHTMLReport, using the standard Helvetica fonts so the file needs no
+// embedded assets or external tools to produce.
+func RenderPDFReport(title string, report any) ([]byte, error) {
+	root, err := prepareReport(report)
+	if err != nil {
+		return nil, err
+	}
+
+	layout := &pdfLayout{}
+	layout.newPage()
+	page := layout.current()
+	page.rules = append(page.rules, pdfRule{x1: 0, x2: pdfPageWidth, y: pdfPageHeight - 6, color: pdfBrandColor, width: 12})
+	layout.y -= 10
+	layout.write(pdfMargin, pdfBold, 18, pdfTextColor, title)
+	layout.y -= 10
+	layout.write(pdfMargin, pdfRegular, 8.5, pdfMutedColor, "Generated "+time.Now().UTC().Format(time.RFC1123))
+	layout.y -= 4
+	layout.rule(pdfMargin, pdfPageWidth-pdfMargin, pdfRuleColor, 0.75)
+	layout.y -= pdfLineHeight
+	layout.node(root, pdfMargin)
+
+	return layout.encode(title), nil
+}
+
+func (l *pdfLayout) newPage() {
+	l.pages = append(l.pages, &pdfPage{})
+	l.y = pdfPageHeight - pdfMargin
+}
+
+func (l *pdfLayout) current() *pdfPage {
+	return l.pages[len(l.pages)-1]
+}
+
+// advance moves down one line, starting a new page when the current one is full.
+func (l *pdfLayout) advance(height float64) {
+	if l.y-height < pdfMargin+pdfFooterSpace {
+		l.newPage()
+	}
+	l.y -= height
+}
+
+// write places a single line of text without wrapping.
+func (l *pdfLayout) write(x float64, font pdfFont, size float64, color, text string) {
+	l.advance(size * 1.3)
+	l.current().texts = append(l.current().texts, pdfText{x: x, y: l.y, font: font, size: size, color: color, text: winAnsi(text)})
+}
+
+func (l *pdfLayout) rule(x1, x2 float64, color string, width float64) {
+	l.current().rules = append(l.current().rules, pdfRule{x1: x1, x2: x2, y: l.y - 4, color: color, width: width})
+	l.y -= 4
+}
+
+// field writes "label value", wrapping the value under itself when it does not fit.
+func (l *pdfLayout) field(x float64, label, value, valueColor string) {
+	label = winAnsi(label)
+	value = winAnsi(value)
+	valueX := x
+	if label != "" {
+		valueX = x + pdfTextWidth(pdfBold, pdfFontSize, label+"  ")
+		if limit := x + (pdfPageWidth-2*pdfMargin)*0.4; valueX > limit {
+			valueX = limit
+		}
+	}
+	lines := wrapPDFText(value, pdfRegular, pdfFontSize, pdfPageWidth-pdfMargin-valueX)
+	for i, line := range lines {
+		l.advance(pdfLineHeight)
+		page := l.current()
+		if i == 0 && label != "" {
+			page.texts = append(page.texts, pdfText{x: x, y: l.y, font: pdfBold, size: pdfFontSize, color: pdfLabelColor, text: label})
+		}
+		page.texts = append(page.texts, pdfText{x: valueX, y: l.y, font: pdfRegular, size: pdfFontSize, color: valueColor, text: line})
+	}
+}
+
+// node lays out a report node at the given indent.
+func (l *pdfLayout) node(node reportNode, x float64) {
+	switch node.Kind {
+	case "object":
+		for _, field := range node.Fields {
+			switch field.Value.Kind {
+			case "object", "list":
+				l.field(x, field.Label, "", pdfTextColor)
+				l.node(field.Value, x+pdfIndentStep)
+			default:
+				l.field(x, field.Label, field.Value.Text, nodeColor(field.Value))
+			}
+		}
+	case "list":
+		for i, item := range node.Items {
+			switch item.Kind {
+			case "object", "list":
+				if i > 0 {
+					l.rule(x, pdfPageWidth-pdfMargin, pdfRuleColor, 0.5)
+				}
+				l.node(item, x)
+			default:
+				l.field(x, "•", item.Text, nodeColor(item))
+			}
+		}
+	case "text", "bool":
+		l.field(x, "", node.Text, nodeColor(node))
+	}
+}
+
+func nodeColor(node reportNode) string {
+	if node.Kind != "bool" {
+		return pdfTextColor
+	}
+	if node.Text == "Yes" {
+		return pdfYesColor
+	}
+	return pdfNoColor
+}
+
+// encode serializes the laid out pages as a PDF document with a page-number footer.
+func (l *pdfLayout) encode(title string) []byte {
+	// Objects 1-4 are the catalog, page tree, and the two fonts; each page then takes a
+	// page object and a content stream.
+	objects := []string{
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		"",
+		"<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica /Encoding /WinAnsiEncoding >>",
+		"<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica-Bold /Encoding /WinAnsiEncoding >>",
+	}
+	var kids []string
+	for i, page := range l.pages {
+		footer := winAnsi(fmt.Sprintf("%s - page %d of %d", title, i+1, len(l.pages)))
+		page.texts = append(page.texts, pdfText{x: pdfMargin, y: pdfMargin / 2, font: pdfRegular, size: 8, color: pdfMutedColor, text: footer})
+
+		var content bytes.Buffer
+		for _, rule := range page.rules {
+			fmt.Fprintf(&content, "%s RG %.2f w %.2f %.2f m %.2f %.2f l S\n", rule.color, rule.width, rule.x1, rule.y, rule.x2, rule.y)
+		}
+		for _, text := range page.texts {
+			fmt.Fprintf(&content, "BT /F%d %.1f Tf %s rg %.2f %.2f Td (%s) Tj ET\n", text.font+1, text.size, text.color, text.x, text.y, escapePDFString(text.text))
+		}
+		pageObject := len(objects) + 1
+		kids = append(kids, fmt.Sprintf("%d 0 R", pageObject))
+		objects = append(objects,
+			fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %.0f %.0f] /Resources << /Font << /F1 3 0 R /F2 4 0 R >> >> /Contents %d 0 R >>",
+				pdfPageWidth, pdfPageHeight, pageObject+1),
+			fmt.Sprintf("<< /Length %d >>\nstream\n%sendstream", content.Len(), content.String()),
+		)
+	}
+	objects[1] = fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(kids))
+	objects = append(objects, fmt.Sprintf("<< /Title (%s) /Producer (utils_api) >>", escapePDFString(winAnsi(title))))
+
+	var out bytes.Buffer
+	out.WriteString("%PDF-1.4\n%\xe2\xe3\xcf\xd3\n")
+	offsets := make([]int, len(objects))
+	for i, object := range objects {
+		offsets[i] = out.Len()
+		fmt.Fprintf(&out, "%d 0 obj\n%s\nendobj\n", i+1, object)
+	}
+	xref := out.Len()
+	fmt.Fprintf(&out, "xref\n0 %d\n0000000000 65535 f \n", len(objects)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(&out, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(&out, "trailer\n<< /Size %d /Root 1 0 R /Info %d 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(objects)+1, len(objects), xref)
+	return out.Bytes()
+}
+
+// winAnsiPunctuation maps the characters WinAnsi encodes in 0x80-0x9F that reports use.
+var winAnsiPunctuation = map[rune]byte{
+	'€': 0x80, '‚': 0x82, '„': 0x84, '…': 0x85, '‘': 0x91, '’': 0x92, '“': 0x93, '”': 0x94,
+	'•': 0x95, '–': 0x96, '—': 0x97, '™': 0x99,
+}
+
+// winAnsi converts text to the WinAnsi encoding used by the standard fonts, replacing
+// characters it cannot represent with '?'.
+func winAnsi(text string) string {
+	var out strings.Builder
+	for _, r := range text {
+		switch {
+		case r < 0x20:
+			out.WriteByte(' ')
+		case r < 0x7F || (r >= 0xA0 && r <= 0xFF):
+			out.WriteByte(byte(r))
+		case winAnsiPunctuation[r] != 0:
+			out.WriteByte(winAnsiPunctuation[r])
+		default:
+			out.WriteByte('?')
+		}
+	}
+	return out.String()
+}
+
+// escapePDFString escapes a WinAnsi string for use in a PDF literal string.
+func escapePDFString(text string) string {
+	return strings.NewReplacer(`\`, `\\`, `(`, `\(`, `)`, `\)`).Replace(text)
+}
+
+// pdfTextWidth measures WinAnsi text in points.
+func pdfTextWidth(font pdfFont, size float64, text string) float64 {
+	widths := &helveticaWidths
+	if font == pdfBold {
+		widths = &helveticaBoldWidths
+	}
+	total := 0
+	for i := 0; i < len(text); i++ {
+		if c := text[i]; c >= 32 && c <= 126 {
+			total += widths[c-32]
+		} else {
+			total += 556 // Typical width for accented letters and symbols
+		}
+	}
+	return float64(total) * size / 1000
+}
+
+// wrapPDFText splits WinAnsi text into lines that fit the width, breaking at spaces where
+// possible and inside long words such as URLs otherwise.
+func wrapPDFText(text string, font pdfFont, size, width float64) []string {
+	var lines []string
+	line := ""
+	for _, word := range strings.Fields(text) {
+		candidate := word
+		if line != "" {
+			candidate = line + " " + word
+		}
+		if pdfTextWidth(font, size, candidate) <= width {
+			line = candidate
+			continue
+		}
+		if line != "" {
+			lines = append(lines, line)
+			line = ""
+		}
+		for pdfTextWidth(font, size, word) > width {
+			cut := 1
+			for cut < len(word) && pdfTextWidth(font, size, word[:cut+1]) <= width {
+				cut++
+			}
+			lines = append(lines, word[:cut])
+			word = word[cut:]
+		}
+		line = word
+	}
+	return append(lines, line)
+}
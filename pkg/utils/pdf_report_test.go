@@ -0,0 +1,76 @@
+package utils
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestRenderPDFReport(t *testing.T) {
+	report := map[string]any{
+		"domain":  "example.com",
+		"secure":  false,
+		"note":    "Ünïcode (and parens) \\ €",
+		"records": make([]map[string]any, 0, 120),
+	}
+	for i := 0; i < 120; i++ {
+		report["records"] = append(report["records"].([]map[string]any), map[string]any{"type": "A", "value": fmt.Sprintf("192.0.2.%d", i)})
+	}
+
+	document, err := RenderPDFReport("DNS Lookup", report)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.HasPrefix(document, []byte("%PDF-1.4\n")) || !bytes.HasSuffix(document, []byte("%%EOF\n")) {
+		t.Fatal("RenderPDFReport() output is not framed as a PDF")
+	}
+
+	// Every xref entry must point at the start of its object.
+	startxref := regexp.MustCompile(`startxref\n(\d+)\n`).FindSubmatch(document)
+	if startxref == nil {
+		t.Fatal("missing startxref")
+	}
+	xrefOffset, _ := strconv.Atoi(string(startxref[1]))
+	entries := strings.Split(string(document[xrefOffset:]), "\n")[3:]
+	for i, entry := range entries {
+		if !strings.HasSuffix(entry, " n ") {
+			break
+		}
+		offset, _ := strconv.Atoi(entry[:10])
+		if want := fmt.Sprintf("%d 0 obj\n", i+1); !bytes.HasPrefix(document[offset:], []byte(want)) {
+			t.Errorf("xref entry %d points at %q", i+1, document[offset:offset+len(want)])
+		}
+	}
+
+	if count := regexp.MustCompile(`/Type /Pages /Kids \[[^\]]*\] /Count (\d+)`).FindSubmatch(document); count == nil || string(count[1]) == "1" {
+		t.Errorf("120 records should span several pages, got page count %q", count)
+	}
+	for _, want := range []string{"(DNS Lookup)", "(example.com)", "(No)", "(192.0.2.119)", "(\xdcn\xefcode \\(and parens\\) \\\\ \x80)"} {
+		if !bytes.Contains(document, []byte(want)) {
+			t.Errorf("RenderPDFReport() output is missing %q", want)
+		}
+	}
+}
+
+func TestWrapPDFText(t *testing.T) {
+	width := pdfTextWidth(pdfRegular, pdfFontSize, "0123456789") // Digits share one width
+	tests := []struct {
+		text string
+		want []string
+	}{
+		{"", []string{""}},
+		{"0123 5678", []string{"0123 5678"}},
+		{"01234 01234 01234", []string{"01234", "01234", "01234"}},
+		{"01234567890123456789012", []string{"0123456789", "0123456789", "012"}},
+		{"01 0123456789012", []string{"01", "0123456789", "012"}},
+	}
+	for _, tt := range tests {
+		got := wrapPDFText(tt.text, pdfRegular, pdfFontSize, width)
+		if strings.Join(got, "|") != strings.Join(tt.want, "|") {
+			t.Errorf("wrapPDFText(%q) = %q, want %q", tt.text, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,303 @@
+package utils
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"net"
+	"strconv"
+	"syscall"
+	"time"
+
+	"golang.org/x/net/icmp"
+	"golang.org/x/net/ipv4"
+	"golang.org/x/net/ipv6"
+)
+
+// Ping limits and defaults.
+const (
+	DefaultPingCount   = 4
+	MaxPingCount       = 20
+	DefaultPingTCPPort = 443
+
+	pingProbeTimeout    = 2 * time.Second
+	pingProbeInterval   = 500 * time.Millisecond
+	pingEchoPayloadSize = 32
+	pingICMPProtocolV4  = 1  // IANA protocol number for ICMP
+	pingICMPProtocolV6  = 58 // IANA protocol number for ICMPv6
+)
+
+// Ping protocols.
+const (
+	PingProtocolAuto = "auto"
+	PingProtocolICMP = "icmp"
+	PingProtocolTCP  = "tcp"
+)
+
+// PingOptions controls a ping run.
+type PingOptions struct {
+	Count    int    // Number of probes, defaults to DefaultPingCount
+	Protocol string // "auto" (ICMP when the process may open raw sockets, TCP otherwise), "icmp" or "tcp"
+	Port     int    // TCP port for TCP probes, defaults to DefaultPingTCPPort
+}
+
+// PingResult holds per-probe round trip times and their statistics, in milliseconds.
+type PingResult struct {
+	Host       string    `json:"host"`
+	IP         string    `json:"ip"`
+	Protocol   string    `json:"protocol"`
+	Port       int       `json:"port,omitempty"`
+	Sent       int       `json:"sent"`
+	Received   int       `json:"received"`
+	PacketLoss float64   `json:"packet_loss_percent"`
+	MinRTT     float64   `json:"min_rtt_ms"`
+	AvgRTT     float64   `json:"avg_rtt_ms"`
+	MaxRTT     float64   `json:"max_rtt_ms"`
+	StdDevRTT  float64   `json:"stddev_rtt_ms"`
+	RTTs       []float64 `json:"rtts_ms"` // Successful probes, in order
+	// TCP probes answered with a reset: the host replied, so they are counted as received,
+	// but nothing listens on the port
+	Refused     int      `json:"refused,omitempty"`
+	ProbeErrors []string `json:"probe_errors,omitempty"`
+}
+
+// errPingRefused is returned with the round trip time by a TCP probe answered with a reset.
+var errPingRefused = errors.New("connection refused")
+
+// prober sends one probe and returns its round trip time.
+type prober interface {
+	probe(ctx context.Context, seq int) (time.Duration, error)
+	close()
+}
+
+// Ping resolves host (subject to the outbound policy) and sends probes to it.
+func Ping(ctx context.Context, host string, options PingOptions) (*PingResult, error) {
+	if options.Count <= 0 {
+		options.Count = DefaultPingCount
+	}
+	if options.Count > MaxPingCount {
+		return nil, fmt.Errorf("count must be at most %d", MaxPingCount)
+	}
+	if options.Port <= 0 {
+		options.Port = DefaultPingTCPPort
+	}
+	if options.Protocol == "" {
+		options.Protocol = PingProtocolAuto
+	}
+
+	ip, err := resolvePingTarget(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &PingResult{Host: host, IP: ip.String()}
+	var p prober
+	switch options.Protocol {
+	case PingProtocolICMP, PingProtocolAuto:
+		p, err = newICMPProber(ip)
+		if err == nil {
+			result.Protocol = PingProtocolICMP
+			break
+		}
+		if options.Protocol == PingProtocolICMP {
+			return nil, fmt.Errorf("ICMP ping unavailable (requires raw socket privileges): %w", err)
+		}
+		fallthrough
+	case PingProtocolTCP:
+		p = &tcpProber{address: net.JoinHostPort(ip.String(), strconv.Itoa(options.Port))}
+		result.Protocol = PingProtocolTCP
+		result.Port = options.Port
+	default:
+		return nil, fmt.Errorf("unsupported protocol: %s", options.Protocol)
+	}
+	defer p.close()
+
+	for seq := 0; seq < options.Count; seq++ {
+		if seq > 0 {
+			select {
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			case <-time.After(pingProbeInterval):
+			}
+		}
+		result.Sent++
+		rtt, err := p.probe(ctx, seq)
+		if errors.Is(err, errPingRefused) {
+			result.Refused++
+			err = nil
+		}
+		if err != nil {
+			result.ProbeErrors = append(result.ProbeErrors, fmt.Sprintf("probe %d: %v", seq+1, err))
+			continue
+		}
+		result.Received++
+		result.RTTs = append(result.RTTs, float64(rtt.Microseconds())/1000)
+	}
+
+	result.computeStats()
+	return result, nil
+}
+
+// resolvePingTarget resolves host to a single address, preferring IPv4.
+func resolvePingTarget(ctx context.Context, host string) (net.IP, error) {
+	var ips []net.IP
+	if ip := net.ParseIP(host); ip != nil {
+		ips = []net.IP{ip}
+	} else {
+		ipAddrs, err := net.DefaultResolver.LookupIPAddr(ctx, host)
+		if err != nil {
+			return nil, err
+		}
+		for _, ipAddr := range ipAddrs {
+			ips = append(ips, ipAddr.IP)
+		}
+	}
+	if err := CheckOutboundAddress(host, ips); err != nil {
+		return nil, err
+	}
+	if len(ips) == 0 {
+		return nil, fmt.Errorf("no addresses found for %s", host)
+	}
+	for _, ip := range ips {
+		if ip.To4() != nil {
+			return ip, nil
+		}
+	}
+	return ips[0], nil
+}
+
+// computeStats fills in loss and RTT statistics from the collected RTTs.
+func (r *PingResult) computeStats() {
+	if r.Sent > 0 {
+		r.PacketLoss = math.Round(float64(r.Sent-r.Received)/float64(r.Sent)*10000) / 100
+	}
+	if len(r.RTTs) == 0 {
+		return
+	}
+	r.MinRTT, r.MaxRTT = r.RTTs[0], r.RTTs[0]
+	var sum float64
+	for _, rtt := range r.RTTs {
+		sum += rtt
+		r.MinRTT = math.Min(r.MinRTT, rtt)
+		r.MaxRTT = math.Max(r.MaxRTT, rtt)
+	}
+	r.AvgRTT = sum / float64(len(r.RTTs))
+	var variance float64
+	for _, rtt := range r.RTTs {
+		variance += (rtt - r.AvgRTT) * (rtt - r.AvgRTT)
+	}
+	r.StdDevRTT = math.Sqrt(variance / float64(len(r.RTTs)))
+
+	r.AvgRTT = math.Round(r.AvgRTT*1000) / 1000
+	r.StdDevRTT = math.Round(r.StdDevRTT*1000) / 1000
+}
+
+// tcpProber times TCP handshakes (SYN to SYN-ACK) to a port. A reset (SYN to RST) proves the
+// host is up as much as a SYN-ACK does, so it is timed too and returned with errPingRefused.
+type tcpProber struct {
+	address string
+}
+
+func (p *tcpProber) probe(ctx context.Context, seq int) (time.Duration, error) {
+	dialer := &net.Dialer{Timeout: pingProbeTimeout}
+	start := time.Now()
+	conn, err := dialer.DialContext(ctx, "tcp", p.address)
+	rtt := time.Since(start)
+	if errors.Is(err, syscall.ECONNREFUSED) {
+		return rtt, errPingRefused
+	}
+	if err != nil {
+		return 0, err
+	}
+	conn.Close()
+	return rtt, nil
+}
+
+func (p *tcpProber) close() {}
+
+// icmpProber sends ICMP echo requests over a raw socket.
+type icmpProber struct {
+	conn     *icmp.PacketConn
+	target   *net.IPAddr
+	id       int
+	echoType icmp.Type
+	protocol int
+}
+
+func newICMPProber(ip net.IP) (*icmpProber, error) {
+	network, address := "ip4:icmp", "0.0.0.0"
+	var echoType icmp.Type = ipv4.ICMPTypeEcho
+	protocol := pingICMPProtocolV4
+	if ip.To4() == nil {
+		network, address = "ip6:ipv6-icmp", "::"
+		echoType, protocol = ipv6.ICMPTypeEchoRequest, pingICMPProtocolV6
+	}
+	conn, err := icmp.ListenPacket(network, address)
+	if err != nil {
+		return nil, err
+	}
+
+	var idBytes [2]byte
+	rand.Read(idBytes[:])
+	return &icmpProber{
+		conn:     conn,
+		target:   &net.IPAddr{IP: ip},
+		id:       int(binary.BigEndian.Uint16(idBytes[:])),
+		echoType: echoType,
+		protocol: protocol,
+	}, nil
+}
+
+func (p *icmpProber) probe(ctx context.Context, seq int) (time.Duration, error) {
+	request := icmp.Message{
+		Type: p.echoType,
+		Body: &icmp.Echo{ID: p.id, Seq: seq, Data: make([]byte, pingEchoPayloadSize)},
+	}
+	packet, err := request.Marshal(nil)
+	if err != nil {
+		return 0, err
+	}
+
+	deadline := time.Now().Add(pingProbeTimeout)
+	if ctxDeadline, ok := ctx.Deadline(); ok && ctxDeadline.Before(deadline) {
+		deadline = ctxDeadline
+	}
+	p.conn.SetReadDeadline(deadline)
+
+	start := time.Now()
+	if _, err := p.conn.WriteTo(packet, p.target); err != nil {
+		return 0, err
+	}
+
+	buf := make([]byte, 1500)
+	for {
+		n, peer, err := p.conn.ReadFrom(buf)
+		if err != nil {
+			if netErr, ok := err.(net.Error); ok && netErr.Timeout() {
+				return 0, fmt.Errorf("timed out")
+			}
+			return 0, err
+		}
+		rtt := time.Since(start)
+
+		// Raw sockets see every ICMP packet for the host: keep only our echo reply.
+		if peerAddr, ok := peer.(*net.IPAddr); !ok || !peerAddr.IP.Equal(p.target.IP) {
+			continue
+		}
+		reply, err := icmp.ParseMessage(p.protocol, buf[:n])
+		if err != nil {
+			continue
+		}
+		if echo, ok := reply.Body.(*icmp.Echo); ok && echo.ID == p.id && echo.Seq == seq &&
+			(reply.Type == ipv4.ICMPTypeEchoReply || reply.Type == ipv6.ICMPTypeEchoReply) {
+			return rtt, nil
+		}
+	}
+}
+
+func (p *icmpProber) close() {
+	p.conn.Close()
+}
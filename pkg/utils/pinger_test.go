@@ -0,0 +1,87 @@
+package utils
+
+import (
+	"context"
+	"errors"
+	"net"
+	"reflect"
+	"testing"
+)
+
+func TestPingResultComputeStats(t *testing.T) {
+	result := &PingResult{Sent: 4, Received: 3, RTTs: []float64{10, 20, 30}}
+	result.computeStats()
+	want := &PingResult{Sent: 4, Received: 3, RTTs: []float64{10, 20, 30}, PacketLoss: 25, MinRTT: 10, AvgRTT: 20, MaxRTT: 30, StdDevRTT: 8.165}
+	if !reflect.DeepEqual(result, want) {
+		t.Errorf("computeStats() = %+v, want %+v", result, want)
+	}
+
+	result = &PingResult{Sent: 3}
+	result.computeStats()
+	if result.PacketLoss != 100 || result.MinRTT != 0 || result.AvgRTT != 0 {
+		t.Errorf("computeStats() with no replies = %+v, want 100%% loss and no RTTs", result)
+	}
+
+	result = &PingResult{}
+	result.computeStats()
+	if result.PacketLoss != 0 {
+		t.Errorf("computeStats() with no probes: loss = %v, want 0", result.PacketLoss)
+	}
+}
+
+// closedPort returns a loopback address nothing listens on.
+func closedPort(t *testing.T) string {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	address := listener.Addr().String()
+	listener.Close()
+	return address
+}
+
+func TestTCPProber(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+
+	open := &tcpProber{address: listener.Addr().String()}
+	if rtt, err := open.probe(context.Background(), 0); err != nil || rtt <= 0 {
+		t.Errorf("probe(open port) = %v, %v; want a round trip time", rtt, err)
+	}
+
+	closed := &tcpProber{address: closedPort(t)}
+	if rtt, err := closed.probe(context.Background(), 0); !errors.Is(err, errPingRefused) || rtt <= 0 {
+		t.Errorf("probe(closed port) = %v, %v; want a round trip time and errPingRefused", rtt, err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := open.probe(ctx, 0); err == nil || errors.Is(err, errPingRefused) {
+		t.Errorf("probe(canceled) error = %v, want a failure", err)
+	}
+}
+
+func TestPingTCPCountsResetsAsReplies(t *testing.T) {
+	host, port, _ := net.SplitHostPort(closedPort(t))
+	options := PingOptions{Count: 2, Protocol: PingProtocolTCP}
+	options.Port, _ = net.LookupPort("tcp", port)
+
+	result, err := Ping(context.Background(), host, options)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Protocol != PingProtocolTCP || result.Sent != 2 || result.Received != 2 || result.Refused != 2 || result.PacketLoss != 0 || len(result.RTTs) != 2 || len(result.ProbeErrors) != 0 {
+		t.Errorf("Ping(closed port) = %+v, want both resets counted as replies", result)
+	}
+
+	if _, err := Ping(context.Background(), host, PingOptions{Count: MaxPingCount + 1}); err == nil {
+		t.Error("Ping() with too many probes: error = nil")
+	}
+	if _, err := Ping(context.Background(), host, PingOptions{Protocol: "udp"}); err == nil {
+		t.Error("Ping() with an unknown protocol: error = nil")
+	}
+}
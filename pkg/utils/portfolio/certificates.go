@@ -0,0 +1,227 @@
+package portfolio
+
+import (
+	"crypto/x509"
+	"math"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/vit0-9/utils_api/pkg/utils/domain"
+)
+
+// Certificate sources.
+const (
+	SourceDirect = "direct" // Served by the domain during an ssl-check job
+	SourceCT     = "ct"     // Found in Certificate Transparency logs by a ct-scan job
+)
+
+// Certificate is one certificate in the inventory, found for one or more portfolio domains.
+type Certificate struct {
+	FingerprintSHA256  string    `json:"fingerprint_sha256"`
+	Subject            string    `json:"subject"`
+	Issuer             string    `json:"issuer"`
+	SerialNumber       string    `json:"serial_number"`
+	DNSNames           []string  `json:"dns_names"`
+	NotBefore          time.Time `json:"not_before"`
+	NotAfter           time.Time `json:"not_after"`
+	DaysUntilExpiry    int       `json:"days_until_expiry"` // Negative once the certificate has expired
+	PublicKeyAlgorithm string    `json:"public_key_algorithm"`
+	KeySize            int       `json:"key_size"`
+	WeakKey            bool      `json:"weak_key"`     // RSA under 2048 bits, ECDSA under 256 bits or DSA
+	KnownIssuer        bool      `json:"known_issuer"` // See ConfigureKnownIssuers
+	Domains            []string  `json:"domains"`      // Portfolio domains the certificate was found for
+	Sources            []string  `json:"sources"`      // direct and/or ct
+	FirstSeen          time.Time `json:"first_seen"`
+	LastSeen           time.Time `json:"last_seen"`
+
+	chainTrusted bool // Verified against the trusted roots when it was served directly
+}
+
+// CertificateFilter selects certificates from the inventory. Zero values match everything.
+type CertificateFilter struct {
+	Tag                string
+	ExpiringWithinDays *int
+	WeakKeys           bool
+	UnknownIssuers     bool
+}
+
+var (
+	inventoryMu  sync.Mutex
+	inventory    = make(map[string]*Certificate) // By fingerprint
+	knownIssuers []string                        // Lowercased substrings of issuer DNs, from ConfigureKnownIssuers
+)
+
+// issuerOrganizationRegex extracts the O= attribute of a distinguished name as printed by
+// pkix.Name.String, which escapes commas inside values.
+var issuerOrganizationRegex = regexp.MustCompile(`(?:^|,)O=((?:\\.|[^,])*)`)
+
+// ConfigureKnownIssuers sets the issuers the portfolio expects, as comma-separated substrings
+// matched case-insensitively against the issuer DN (e.g. "Let's Encrypt,DigiCert"). Without
+// a list, an issuer is known when its organization also issued a trusted certificate served
+// directly by one of the portfolio domains.
+func ConfigureKnownIssuers(list string) {
+	var issuers []string
+	for _, issuer := range strings.Split(list, ",") {
+		if issuer = strings.ToLower(strings.TrimSpace(issuer)); issuer != "" {
+			issuers = append(issuers, issuer)
+		}
+	}
+	inventoryMu.Lock()
+	knownIssuers = issuers
+	inventoryMu.Unlock()
+}
+
+// recordServedCertificate adds the leaf certificate of an ssl-check result to the inventory.
+func recordServedCertificate(name string, info *domain.SSLInfo) {
+	if info.FingerprintSHA256 == "" {
+		return
+	}
+	recordCertificate(name, SourceDirect, Certificate{
+		FingerprintSHA256:  info.FingerprintSHA256,
+		Subject:            info.Subject,
+		Issuer:             info.Issuer,
+		SerialNumber:       info.SerialNumber,
+		DNSNames:           info.SubjectAltNames,
+		NotBefore:          info.NotBefore,
+		NotAfter:           info.NotAfter,
+		PublicKeyAlgorithm: info.PublicKeyAlgorithm,
+		KeySize:            info.KeySize,
+		chainTrusted:       info.ChainTrusted,
+	})
+}
+
+// recordLoggedCertificate adds a certificate found in the CT logs to the inventory.
+func recordLoggedCertificate(name string, cert *x509.Certificate) {
+	recordCertificate(name, SourceCT, Certificate{
+		FingerprintSHA256:  domain.Fingerprint(cert),
+		Subject:            cert.Subject.String(),
+		Issuer:             cert.Issuer.String(),
+		SerialNumber:       cert.SerialNumber.String(),
+		DNSNames:           cert.DNSNames,
+		NotBefore:          cert.NotBefore,
+		NotAfter:           cert.NotAfter,
+		PublicKeyAlgorithm: cert.PublicKeyAlgorithm.String(),
+		KeySize:            domain.KeySize(cert),
+	})
+}
+
+// recordCertificate merges a sighting into the inventory entry with the same fingerprint.
+func recordCertificate(name, source string, cert Certificate) {
+	now := time.Now().UTC()
+	inventoryMu.Lock()
+	defer inventoryMu.Unlock()
+	entry, ok := inventory[cert.FingerprintSHA256]
+	if !ok {
+		cert.FirstSeen = now
+		entry = &cert
+		inventory[cert.FingerprintSHA256] = entry
+	}
+	entry.LastSeen = now
+	entry.chainTrusted = entry.chainTrusted || cert.chainTrusted
+	entry.Domains = addSorted(entry.Domains, name)
+	entry.Sources = addSorted(entry.Sources, source)
+}
+
+func addSorted(values []string, value string) []string {
+	i := sort.SearchStrings(values, value)
+	if i < len(values) && values[i] == value {
+		return values
+	}
+	values = append(values, "")
+	copy(values[i+1:], values[i:])
+	values[i] = value
+	return values
+}
+
+// Certificates returns the inventory entries matching the filter, soonest expiry first. Only
+// domains still in the portfolio are listed, so removed domains drop out of the inventory.
+func Certificates(filter CertificateFilter) []Certificate {
+	owned := make(map[string]bool)
+	for _, d := range List(filter.Tag) {
+		owned[d.Name] = true
+	}
+	now := time.Now()
+
+	inventoryMu.Lock()
+	defer inventoryMu.Unlock()
+	trustedOrganizations := make(map[string]bool)
+	for _, entry := range inventory {
+		if entry.chainTrusted {
+			trustedOrganizations[issuerOrganization(entry.Issuer)] = true
+		}
+	}
+
+	certificates := []Certificate{}
+	for _, entry := range inventory {
+		cert := *entry
+		cert.Domains = nil
+		for _, name := range entry.Domains {
+			if owned[name] {
+				cert.Domains = append(cert.Domains, name)
+			}
+		}
+		if len(cert.Domains) == 0 {
+			continue
+		}
+		cert.DaysUntilExpiry = int(math.Floor(cert.NotAfter.Sub(now).Hours() / 24))
+		cert.WeakKey = isWeakKey(cert.PublicKeyAlgorithm, cert.KeySize)
+		cert.KnownIssuer = isKnownIssuer(cert.Issuer, trustedOrganizations)
+
+		if filter.ExpiringWithinDays != nil && cert.DaysUntilExpiry > *filter.ExpiringWithinDays {
+			continue
+		}
+		if filter.WeakKeys && !cert.WeakKey {
+			continue
+		}
+		if filter.UnknownIssuers && cert.KnownIssuer {
+			continue
+		}
+		certificates = append(certificates, cert)
+	}
+	sort.Slice(certificates, func(i, j int) bool {
+		if !certificates[i].NotAfter.Equal(certificates[j].NotAfter) {
+			return certificates[i].NotAfter.Before(certificates[j].NotAfter)
+		}
+		return certificates[i].FingerprintSHA256 < certificates[j].FingerprintSHA256
+	})
+	return certificates
+}
+
+// isWeakKey flags keys below current CA/Browser Forum minimums. An unknown size is not weak.
+func isWeakKey(algorithm string, size int) bool {
+	switch algorithm {
+	case "RSA":
+		return size > 0 && size < 2048
+	case "ECDSA":
+		return size > 0 && size < 256
+	case "DSA":
+		return true
+	}
+	return false
+}
+
+// isKnownIssuer must be called with inventoryMu held.
+func isKnownIssuer(issuer string, trustedOrganizations map[string]bool) bool {
+	if len(knownIssuers) > 0 {
+		lower := strings.ToLower(issuer)
+		for _, known := range knownIssuers {
+			if strings.Contains(lower, known) {
+				return true
+			}
+		}
+		return false
+	}
+	return trustedOrganizations[issuerOrganization(issuer)]
+}
+
+// issuerOrganization returns the organization of an issuer DN, or the whole DN when it has
+// none.
+func issuerOrganization(issuer string) string {
+	if match := issuerOrganizationRegex.FindStringSubmatch(issuer); match != nil {
+		return match[1]
+	}
+	return issuer
+}
@@ -0,0 +1,167 @@
+package portfolio
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/vit0-9/utils_api/pkg/utils/domain"
+)
+
+// resetInventory empties the certificate inventory for a test.
+func resetInventory(t *testing.T) {
+	t.Helper()
+	inventoryMu.Lock()
+	inventory = make(map[string]*Certificate)
+	knownIssuers = nil
+	inventoryMu.Unlock()
+}
+
+// newTestCertificate creates a certificate for the names, issued by organization.
+func newTestCertificate(t *testing.T, serial int64, organization string, weak bool, notAfter time.Time, names ...string) *x509.Certificate {
+	t.Helper()
+	var key any
+	var public any
+	if weak {
+		rsaKey, err := rsa.GenerateKey(rand.Reader, 1024)
+		if err != nil {
+			t.Fatal(err)
+		}
+		key, public = rsaKey, &rsaKey.PublicKey
+	} else {
+		ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		if err != nil {
+			t.Fatal(err)
+		}
+		key, public = ecKey, &ecKey.PublicKey
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(serial),
+		Subject:      pkix.Name{CommonName: names[0], Organization: []string{organization}},
+		DNSNames:     names,
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     notAfter,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, public, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cert
+}
+
+func TestCertificateInventory(t *testing.T) {
+	resetPortfolio(t)
+	resetInventory(t)
+	for _, name := range []string{"example.com", "example.org"} {
+		if _, err := Put(name, []string{"prod"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	served := newTestCertificate(t, 1, "Good CA", false, time.Now().Add(90*24*time.Hour), "example.com", "example.org")
+	rogue := newTestCertificate(t, 2, "Rogue CA", true, time.Now().Add(10*24*time.Hour), "login.example.com")
+	servedInfo := &domain.SSLInfo{
+		FingerprintSHA256:  domain.Fingerprint(served),
+		Subject:            served.Subject.String(),
+		Issuer:             served.Issuer.String(),
+		SerialNumber:       served.SerialNumber.String(),
+		NotAfter:           served.NotAfter,
+		PublicKeyAlgorithm: "ECDSA",
+		KeySize:            256,
+		ChainTrusted:       true,
+	}
+	recordServedCertificate("example.com", servedInfo)
+	recordServedCertificate("example.org", servedInfo)
+	recordLoggedCertificate("example.com", served)
+	recordLoggedCertificate("example.com", rogue)
+
+	all := Certificates(CertificateFilter{})
+	if len(all) != 2 {
+		t.Fatalf("Certificates() = %d entries, want 2 deduplicated by fingerprint", len(all))
+	}
+	// Soonest expiry first.
+	if all[0].FingerprintSHA256 != domain.Fingerprint(rogue) || !all[0].WeakKey || all[0].KnownIssuer {
+		t.Errorf("first certificate = %+v, want the weak rogue certificate from an unknown issuer", all[0])
+	}
+	if got := all[1]; len(got.Domains) != 2 || len(got.Sources) != 2 || !got.KnownIssuer || got.WeakKey {
+		t.Errorf("served certificate = %+v, want both domains, both sources and a known issuer", got)
+	}
+
+	days := 30
+	if got := Certificates(CertificateFilter{ExpiringWithinDays: &days}); len(got) != 1 || got[0].SerialNumber != "2" {
+		t.Errorf("Certificates(expiring within 30 days) = %+v, want the rogue certificate", got)
+	}
+	if got := Certificates(CertificateFilter{WeakKeys: true}); len(got) != 1 || got[0].SerialNumber != "2" {
+		t.Errorf("Certificates(weak keys) = %+v, want the rogue certificate", got)
+	}
+	if got := Certificates(CertificateFilter{UnknownIssuers: true}); len(got) != 1 || got[0].SerialNumber != "2" {
+		t.Errorf("Certificates(unknown issuers) = %+v, want the rogue certificate", got)
+	}
+
+	ConfigureKnownIssuers("rogue ca")
+	if got := Certificates(CertificateFilter{UnknownIssuers: true}); len(got) != 1 || got[0].SerialNumber != "1" {
+		t.Errorf("Certificates(unknown issuers) with a configured list = %+v, want the served certificate", got)
+	}
+
+	// Certificates of removed domains drop out of the inventory.
+	if err := Remove("example.com"); err != nil {
+		t.Fatal(err)
+	}
+	if got := Certificates(CertificateFilter{}); len(got) != 1 || len(got[0].Domains) != 1 || got[0].Domains[0] != "example.org" {
+		t.Errorf("Certificates() after removing example.com = %+v, want the served certificate for example.org", got)
+	}
+}
+
+func TestScanCertificateTransparency(t *testing.T) {
+	resetPortfolio(t)
+	resetInventory(t)
+	if _, err := Put("example.com", nil); err != nil {
+		t.Fatal(err)
+	}
+	logged := newTestCertificate(t, 7, "Some CA", false, time.Now().Add(60*24*time.Hour), "www.example.com")
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		switch {
+		case query.Get("q") == "example.com":
+			if query.Get("exclude") != "expired" || query.Get("output") != "json" {
+				t.Errorf("unexpected search query %s", r.URL.RawQuery)
+			}
+			w.Write([]byte(`[{"id": 42}]`))
+		case query.Get("q") == "%.example.com":
+			w.Write([]byte(`[{"id": 42}, {"id": 43}]`))
+		case query.Get("d") == "42":
+			pem.Encode(w, &pem.Block{Type: "CERTIFICATE", Bytes: logged.Raw})
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+	defer func(saved string) { crtshURL = saved }(crtshURL)
+	crtshURL = server.URL + "/"
+
+	result, err := scanCertificateTransparency(context.Background(), "example.com")
+	if err != nil {
+		t.Fatalf("scanCertificateTransparency() error = %v", err)
+	}
+	if result.Logged != 2 || result.Downloaded != 1 || len(result.Errors) != 1 {
+		t.Errorf("scanCertificateTransparency() = %+v, want 2 logged, 1 downloaded and 1 error", result)
+	}
+	if got := Certificates(CertificateFilter{}); len(got) != 1 || got[0].Sources[0] != SourceCT || got[0].SerialNumber != "7" {
+		t.Errorf("Certificates() = %+v, want the logged certificate", got)
+	}
+}
@@ -0,0 +1,127 @@
+package portfolio
+
+import (
+	"context"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/vit0-9/utils_api/pkg/utils"
+)
+
+const (
+	// maxCTCertificates caps the certificates downloaded per domain by a ct-scan job, newest
+	// first. Each one is a separate request to crt.sh.
+	maxCTCertificates = 25
+
+	maxCTResponseBytes = 10 << 20
+)
+
+var (
+	// crtshURL is the crt.sh endpoint used to search the Certificate Transparency logs.
+	crtshURL    = "https://crt.sh/"
+	crtshClient = &http.Client{Timeout: 60 * time.Second, Transport: utils.NewOutboundTransport()}
+)
+
+// crtshEntry is one search result of the crt.sh JSON API.
+type crtshEntry struct {
+	ID int64 `json:"id"`
+}
+
+// CTScanResult summarizes a ct-scan of one domain.
+type CTScanResult struct {
+	Logged     int      `json:"logged"`     // Unexpired certificates listed in the CT logs
+	Downloaded int      `json:"downloaded"` // Certificates added to the inventory
+	Errors     []string `json:"errors,omitempty"`
+}
+
+// scanCertificateTransparency searches the CT logs for unexpired certificates covering the
+// domain or its subdomains and adds them to the inventory.
+func scanCertificateTransparency(ctx context.Context, name string) (*CTScanResult, error) {
+	ids := make(map[int64]bool)
+	for _, query := range []string{name, "%." + name} {
+		entries, err := searchCrtsh(ctx, query)
+		if err != nil {
+			return nil, err
+		}
+		for _, entry := range entries {
+			ids[entry.ID] = true
+		}
+	}
+
+	newest := make([]int64, 0, len(ids))
+	for id := range ids {
+		newest = append(newest, id)
+	}
+	sort.Slice(newest, func(i, j int) bool { return newest[i] > newest[j] })
+	result := &CTScanResult{Logged: len(newest)}
+	if len(newest) > maxCTCertificates {
+		newest = newest[:maxCTCertificates]
+		result.Errors = append(result.Errors, fmt.Sprintf("only the newest %d of %d certificates were downloaded", maxCTCertificates, result.Logged))
+	}
+
+	for _, id := range newest {
+		cert, err := downloadCrtshCertificate(ctx, id)
+		if err != nil {
+			result.Errors = append(result.Errors, fmt.Sprintf("certificate %d: %v", id, err))
+			continue
+		}
+		recordLoggedCertificate(name, cert)
+		result.Downloaded++
+	}
+	if result.Downloaded == 0 && len(newest) > 0 {
+		return result, fmt.Errorf("none of the %d logged certificates could be downloaded", len(newest))
+	}
+	return result, nil
+}
+
+// searchCrtsh lists the unexpired certificates matching an identity, with precertificates
+// folded into their final certificates.
+func searchCrtsh(ctx context.Context, identity string) ([]crtshEntry, error) {
+	query := url.Values{"q": {identity}, "output": {"json"}, "exclude": {"expired"}, "deduplicate": {"Y"}}
+	body, err := getCrtsh(ctx, crtshURL+"?"+query.Encode())
+	if err != nil {
+		return nil, fmt.Errorf("CT log search failed: %w", err)
+	}
+	var entries []crtshEntry
+	if err := json.Unmarshal(body, &entries); err != nil {
+		return nil, fmt.Errorf("CT log search returned invalid JSON: %w", err)
+	}
+	return entries, nil
+}
+
+// downloadCrtshCertificate fetches one certificate, which crt.sh serves as PEM.
+func downloadCrtshCertificate(ctx context.Context, id int64) (*x509.Certificate, error) {
+	body, err := getCrtsh(ctx, crtshURL+"?d="+strconv.FormatInt(id, 10))
+	if err != nil {
+		return nil, err
+	}
+	der := body
+	if block, _ := pem.Decode(body); block != nil {
+		der = block.Bytes
+	}
+	return x509.ParseCertificate(der)
+}
+
+func getCrtsh(ctx context.Context, target string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, target, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := crtshClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("crt.sh returned HTTP %d", resp.StatusCode)
+	}
+	return io.ReadAll(io.LimitReader(resp.Body, maxCTResponseBytes))
+}
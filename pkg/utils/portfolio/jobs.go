@@ -0,0 +1,391 @@
+package portfolio
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/vit0-9/utils_api/pkg/utils"
+	"github.com/vit0-9/utils_api/pkg/utils/callback"
+	"github.com/vit0-9/utils_api/pkg/utils/domain"
+	"github.com/vit0-9/utils_api/pkg/utils/lifecycle"
+	"github.com/vit0-9/utils_api/pkg/utils/notifications"
+	"github.com/vit0-9/utils_api/pkg/utils/storage"
+)
+
+// Portfolio-wide operations.
+const (
+	OperationSSLCheck    = "ssl-check"
+	OperationWhoisExpiry = "whois-expiry"
+	OperationDNSSnapshot = "dns-snapshot"
+	OperationCTScan      = "ct-scan"
+)
+
+// Job statuses.
+const (
+	JobRunning     = "running"
+	JobCompleted   = "completed"
+	JobInterrupted = "interrupted" // Stopped by a shutdown; retry it to check the domains left
+)
+
+// ErrJobNotFound is returned for an unknown or expired job.
+var ErrJobNotFound = errors.New("job not found")
+
+// ErrJobNotInterrupted is returned when retrying a job that was not interrupted.
+var ErrJobNotInterrupted = errors.New("only interrupted jobs can be retried")
+
+const (
+	jobWorkers      = 8
+	jobTimeout      = 15 * time.Minute
+	maxStoredJobs   = 50
+	expiryAlertDays = 30 // Certificates and registrations expiring within this many days raise a monitor.alert
+)
+
+// snapshotRecordTypes are looked up by dns-snapshot jobs.
+var snapshotRecordTypes = []string{"A", "AAAA", "MX", "NS", "TXT", "CAA"}
+
+// JobResult is the outcome of an operation on one domain.
+type JobResult struct {
+	Domain string `json:"domain"`
+	Result any    `json:"result,omitempty"`
+	Error  string `json:"error,omitempty"`
+}
+
+// Job is a portfolio-wide operation running in the background.
+type Job struct {
+	ID          string      `json:"id"`
+	Operation   string      `json:"operation"`
+	Tag         string      `json:"tag,omitempty"`          // Only domains with this tag were included
+	CallbackURL string      `json:"callback_url,omitempty"` // Receives the completed job
+	RetryOf     string      `json:"retry_of,omitempty"`     // The interrupted job this one continues
+	Status      string      `json:"status"`
+	StartedAt   time.Time   `json:"started_at"`
+	FinishedAt  *time.Time  `json:"finished_at,omitempty"`
+	Total       int         `json:"total"`
+	Done        int         `json:"done"`
+	Failed      int         `json:"failed"`
+	Alerts      []string    `json:"alerts,omitempty"`  // Expiring certificates or registrations
+	Results     []JobResult `json:"results,omitempty"` // In domain name order, once the job has completed or was interrupted
+}
+
+var (
+	jobsMu      sync.Mutex
+	jobs        []*Job // Oldest first
+	runningJobs sync.WaitGroup
+	stopOnce    sync.Once
+)
+
+// operations run one portfolio operation on a domain, returning the result and an alert
+// when the domain needs attention.
+var operations = map[string]func(ctx context.Context, name string) (any, string, error){
+	OperationSSLCheck: func(ctx context.Context, name string) (any, string, error) {
+		info, err := domain.GetSSLInfo(ctx, name)
+		if err != nil {
+			return nil, "", err
+		}
+		recordServedCertificate(name, info)
+		alert := ""
+		if info.DaysUntilExpiry <= expiryAlertDays {
+			alert = fmt.Sprintf("%s: certificate expires in %d days", name, info.DaysUntilExpiry)
+		}
+		return info, alert, nil
+	},
+	OperationWhoisExpiry: func(ctx context.Context, name string) (any, string, error) {
+		expiry := domain.GetWhoisExpiry(ctx, name)
+		if expiry.Error != "" {
+			return nil, "", fmt.Errorf("%s", expiry.Error)
+		}
+		alert := ""
+		if *expiry.DaysUntilExpiry <= expiryAlertDays {
+			alert = fmt.Sprintf("%s: registration expires in %d days", name, *expiry.DaysUntilExpiry)
+		}
+		return expiry, alert, nil
+	},
+	OperationDNSSnapshot: func(ctx context.Context, name string) (any, string, error) {
+		records, lookupErrors := utils.LookupDNSRecords(ctx, name, snapshotRecordTypes)
+		if len(records) == 0 && len(lookupErrors) > 0 {
+			return nil, "", fmt.Errorf("all %d DNS lookups failed", len(lookupErrors))
+		}
+		return map[string]any{"records": records, "errors": lookupErrors}, "", nil
+	},
+	OperationCTScan: func(ctx context.Context, name string) (any, string, error) {
+		result, err := scanCertificateTransparency(ctx, name)
+		if result == nil {
+			return nil, "", err
+		}
+		return result, "", err
+	},
+}
+
+// Operations lists the supported operation names.
+func Operations() []string {
+	names := make([]string, 0, len(operations))
+	for name := range operations {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// StartJob runs an operation across the portfolio, or the domains with tag, in the
+// background and returns the job to poll. The completed job is also POSTed to callbackURL,
+// if set.
+func StartJob(operation, tag, callbackURL string) (Job, error) {
+	if _, ok := operations[operation]; !ok {
+		return Job{}, fmt.Errorf("unknown operation %q: use one of %s", operation, strings.Join(Operations(), ", "))
+	}
+	if callbackURL != "" {
+		if err := callback.Validate(callbackURL); err != nil {
+			return Job{}, err
+		}
+	}
+	domains := List(tag)
+	if len(domains) == 0 {
+		return Job{}, fmt.Errorf("no domains in the portfolio match")
+	}
+	return startJob(&Job{Operation: operation, Tag: strings.ToLower(strings.TrimSpace(tag)), CallbackURL: callbackURL}, domains)
+}
+
+// RetryJob starts a job running an interrupted job's operation on the domains it had not
+// checked that are still in the portfolio with its tag.
+func RetryJob(id string) (Job, error) {
+	interrupted, ok := GetJob(id)
+	if !ok {
+		return Job{}, ErrJobNotFound
+	}
+	if interrupted.Status != JobInterrupted {
+		return Job{}, ErrJobNotInterrupted
+	}
+	checked := make(map[string]bool)
+	for job, ok := interrupted, true; ok; job, ok = GetJob(job.RetryOf) { // Including what the jobs it retried checked
+		for _, result := range job.Results {
+			checked[result.Domain] = true
+		}
+	}
+	var domains []Domain
+	for _, d := range List(interrupted.Tag) {
+		if !checked[d.Name] {
+			domains = append(domains, d)
+		}
+	}
+	if len(domains) == 0 {
+		return Job{}, fmt.Errorf("no domains of the job are left to check")
+	}
+	return startJob(&Job{Operation: interrupted.Operation, Tag: interrupted.Tag, CallbackURL: interrupted.CallbackURL, RetryOf: interrupted.ID}, domains)
+}
+
+// startJob fills in and stores a job, then runs it on domains in the background.
+func startJob(job *Job, domains []Domain) (Job, error) {
+	if lifecycle.Stopping() {
+		return Job{}, fmt.Errorf("the server is shutting down")
+	}
+	job.ID = newJobID()
+	job.Status = JobRunning
+	job.StartedAt = time.Now().UTC()
+	job.Total = len(domains)
+	jobsMu.Lock()
+	jobs = append(jobs, job)
+	var expired []*Job
+	if len(jobs) > maxStoredJobs {
+		expired = jobs[:len(jobs)-maxStoredJobs]
+		jobs = jobs[len(jobs)-maxStoredJobs:]
+	}
+	snapshot := *job
+	jobsMu.Unlock()
+	for _, old := range expired {
+		deleteJob(old.ID)
+	}
+
+	saveJob(snapshot) // Kept as interrupted if the process dies before it completes
+	stopOnce.Do(func() {
+		lifecycle.Register("portfolio jobs", func(ctx context.Context) error { return lifecycle.Wait(ctx, &runningJobs) })
+	})
+	runningJobs.Add(1)
+	go job.run(domains, operations[job.Operation])
+	return snapshot, nil
+}
+
+func newJobID() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+func (job *Job) run(domains []Domain, run func(ctx context.Context, name string) (any, string, error)) {
+	defer runningJobs.Done()
+	ctx, cancel := context.WithTimeout(lifecycle.Context(), jobTimeout)
+	defer cancel()
+
+	results := make([]JobResult, len(domains))
+	alerts := make([]string, len(domains))
+	indexes := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < jobWorkers && w < len(domains); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				result, alert, err := run(ctx, domains[i].Name)
+				if err != nil && lifecycle.Stopping() {
+					continue // Cut short by the shutdown: left for the retry
+				}
+				results[i] = JobResult{Domain: domains[i].Name, Result: result}
+				alerts[i] = alert
+				jobsMu.Lock()
+				job.Done++
+				if err != nil {
+					results[i].Error = err.Error()
+					job.Failed++
+				}
+				jobsMu.Unlock()
+			}
+		}()
+	}
+feed:
+	for i := range domains {
+		select {
+		case indexes <- i:
+		case <-lifecycle.Context().Done():
+			break feed
+		}
+	}
+	close(indexes)
+	wg.Wait()
+
+	finished := time.Now().UTC()
+	jobsMu.Lock()
+	job.Status = JobCompleted
+	job.FinishedAt = &finished
+	job.Results = results
+	if lifecycle.Stopping() {
+		job.Status = JobInterrupted
+		job.Results = nil
+		for _, result := range results {
+			if result.Domain != "" {
+				job.Results = append(job.Results, result)
+			}
+		}
+	}
+	for _, alert := range alerts {
+		if alert != "" {
+			job.Alerts = append(job.Alerts, alert)
+		}
+	}
+	summary := *job
+	jobsMu.Unlock()
+	saveJob(summary)
+	if summary.Status == JobInterrupted {
+		log.Printf("Portfolio job %s interrupted after %d of %d domains", summary.ID, summary.Done, summary.Total)
+		return
+	}
+	if summary.CallbackURL != "" {
+		callback.Deliver(summary.CallbackURL, "portfolio.job.completed", summary)
+	}
+
+	duration := finished.Sub(summary.StartedAt)
+	notifications.Notify(notifications.Notification{
+		Event:   notifications.EventJobCompleted,
+		Title:   "Portfolio " + summary.Operation + " completed",
+		Message: fmt.Sprintf("%d domains checked in %s, %d failed", summary.Total, duration.Round(time.Millisecond), summary.Failed),
+		Data:    map[string]any{"job": "portfolio-" + summary.Operation, "id": summary.ID, "count": summary.Total, "failed": summary.Failed, "duration_ms": duration.Milliseconds()},
+	})
+	if len(summary.Alerts) > 0 {
+		notifications.Notify(notifications.Notification{
+			Event:   notifications.EventMonitorAlert,
+			Title:   fmt.Sprintf("%d portfolio domains need attention", len(summary.Alerts)),
+			Message: strings.Join(summary.Alerts, "\n"),
+			Data:    map[string]any{"job": "portfolio-" + summary.Operation, "id": summary.ID, "alerts": summary.Alerts},
+		})
+	}
+}
+
+// GetJob returns a job by ID.
+func GetJob(id string) (Job, bool) {
+	jobsMu.Lock()
+	defer jobsMu.Unlock()
+	for _, job := range jobs {
+		if job.ID == id {
+			return *job, true
+		}
+	}
+	return Job{}, false
+}
+
+// ListJobs returns the recent jobs, newest first, without their per-domain results.
+func ListJobs() []Job {
+	jobsMu.Lock()
+	defer jobsMu.Unlock()
+	list := make([]Job, 0, len(jobs))
+	for i := len(jobs) - 1; i >= 0; i-- {
+		job := *jobs[i]
+		job.Results = nil
+		list = append(list, job)
+	}
+	return list
+}
+
+// loadJobs restores the completed jobs saved to backend, keeping the most recent ones.
+func loadJobs(backend storage.Store) {
+	documents, err := backend.Load(jobsCollection)
+	if err != nil {
+		log.Printf("ERROR: Could not load portfolio jobs: %v", err)
+		return
+	}
+	loaded := make([]*Job, 0, len(documents))
+	for id, data := range documents {
+		var job Job
+		if err := json.Unmarshal(data, &job); err != nil {
+			log.Printf("ERROR: Could not parse portfolio job %s: %v", id, err)
+			continue
+		}
+		if job.Status == JobRunning { // The process stopped without checkpointing it
+			job.Status = JobInterrupted
+		}
+		loaded = append(loaded, &job)
+	}
+	sort.Slice(loaded, func(i, j int) bool { return loaded[i].StartedAt.Before(loaded[j].StartedAt) })
+	if len(loaded) > maxStoredJobs {
+		for _, old := range loaded[:len(loaded)-maxStoredJobs] {
+			if err := backend.Delete(jobsCollection, old.ID); err != nil {
+				log.Printf("ERROR: Could not delete portfolio job %s: %v", old.ID, err)
+			}
+		}
+		loaded = loaded[len(loaded)-maxStoredJobs:]
+	}
+	jobsMu.Lock()
+	jobs = loaded
+	jobsMu.Unlock()
+}
+
+// saveJob writes a completed job to the backend. Jobs still running are not saved, so a
+// restart drops them.
+func saveJob(job Job) {
+	data, err := json.Marshal(job)
+	if err != nil {
+		log.Printf("ERROR: Could not encode portfolio job %s: %v", job.ID, err)
+		return
+	}
+	portfolio.mu.Lock()
+	backend := portfolio.backend
+	portfolio.mu.Unlock()
+	if err := backend.Put(jobsCollection, job.ID, data); err != nil {
+		log.Printf("ERROR: Could not save portfolio job %s: %v", job.ID, err)
+	}
+}
+
+func deleteJob(id string) {
+	portfolio.mu.Lock()
+	backend := portfolio.backend
+	portfolio.mu.Unlock()
+	if err := backend.Delete(jobsCollection, id); err != nil {
+		log.Printf("ERROR: Could not delete portfolio job %s: %v", id, err)
+	}
+}
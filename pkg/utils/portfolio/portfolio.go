@@ -0,0 +1,226 @@
+// Package portfolio keeps the inventory of domains a user owns, with tags, and runs checks
+// across all of them (or a tagged subset) as background jobs.
+package portfolio
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/vit0-9/utils_api/pkg/utils/storage"
+)
+
+// Portfolio limits.
+const (
+	MaxDomains = 1000
+	maxTags    = 20
+)
+
+var (
+	// domainNameRegex accepts host names with at least two labels.
+	domainNameRegex = regexp.MustCompile(`^([a-z0-9]([a-z0-9-]{0,61}[a-z0-9])?\.)+[a-z0-9-]{2,63}$`)
+	tagRegex        = regexp.MustCompile(`^[a-z0-9][a-z0-9_.:-]{0,63}$`)
+)
+
+// ErrNotFound is returned for domains that are not in the portfolio.
+var ErrNotFound = errors.New("domain is not in the portfolio")
+
+// Domain is one domain in the portfolio.
+type Domain struct {
+	Name    string    `json:"name"`
+	Tags    []string  `json:"tags"`
+	AddedAt time.Time `json:"added_at"`
+}
+
+// Storage collections.
+const (
+	domainsCollection = "portfolio_domains"
+	jobsCollection    = "portfolio_jobs"
+)
+
+// store holds the portfolio in memory, saved to a storage backend.
+type store struct {
+	mu      sync.Mutex
+	backend storage.Store
+	domains map[string]*Domain
+}
+
+var portfolio = &store{backend: storage.NewMemory(), domains: make(map[string]*Domain)}
+
+// Configure loads the portfolio and its recent jobs from backend and saves every change
+// back to it. legacyPath names a portfolio file written by older versions (a JSON array of
+// domains) whose domains are imported when backend does not have them yet.
+func Configure(backend storage.Store, legacyPath string) {
+	portfolio.mu.Lock()
+	defer portfolio.mu.Unlock()
+
+	documents, err := backend.Load(domainsCollection)
+	if err != nil {
+		log.Printf("ERROR: Could not load the portfolio: %v. The portfolio will be kept in memory.", err)
+		return
+	}
+	portfolio.backend = backend
+	portfolio.domains = make(map[string]*Domain, len(documents))
+	for name, data := range documents {
+		var d Domain
+		if err := json.Unmarshal(data, &d); err != nil {
+			log.Printf("ERROR: Could not parse portfolio domain %s: %v", name, err)
+			continue
+		}
+		portfolio.domains[d.Name] = &d
+	}
+	if legacyPath != "" {
+		portfolio.importLocked(legacyPath)
+	}
+	loadJobs(backend)
+	log.Printf("Portfolio loaded (%d domains)", len(portfolio.domains))
+}
+
+// importLocked adds the domains of a legacy portfolio file that are missing from the store.
+func (s *store) importLocked(path string) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return
+	}
+	if err != nil {
+		log.Printf("ERROR: Could not read portfolio %s: %v", path, err)
+		return
+	}
+	var stored []*Domain
+	if err := json.Unmarshal(data, &stored); err != nil {
+		log.Printf("ERROR: Could not parse portfolio %s: %v", path, err)
+		return
+	}
+	imported := 0
+	for _, d := range stored {
+		if _, ok := s.domains[d.Name]; ok || len(s.domains) >= MaxDomains {
+			continue
+		}
+		s.domains[d.Name] = d
+		s.saveLocked(d.Name)
+		imported++
+	}
+	if imported > 0 {
+		log.Printf("Imported %d domains from the portfolio file %s", imported, path)
+	}
+}
+
+// NormalizeDomain lowercases and validates a domain name.
+func NormalizeDomain(name string) (string, error) {
+	name = strings.TrimSuffix(strings.ToLower(strings.TrimSpace(name)), ".")
+	if len(name) > 253 || !domainNameRegex.MatchString(name) {
+		return "", fmt.Errorf("invalid domain name: %q", name)
+	}
+	return name, nil
+}
+
+func normalizeTags(tags []string) ([]string, error) {
+	seen := make(map[string]bool)
+	normalized := []string{}
+	for _, tag := range tags {
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		if tag == "" || seen[tag] {
+			continue
+		}
+		if !tagRegex.MatchString(tag) {
+			return nil, fmt.Errorf("invalid tag %q: use up to 64 letters, digits, '_', '.', ':' or '-'", tag)
+		}
+		seen[tag] = true
+		normalized = append(normalized, tag)
+	}
+	if len(normalized) > maxTags {
+		return nil, fmt.Errorf("too many tags: maximum is %d", maxTags)
+	}
+	sort.Strings(normalized)
+	return normalized, nil
+}
+
+// Put adds a domain to the portfolio, or replaces the tags of one already in it.
+func Put(name string, tags []string) (Domain, error) {
+	name, err := NormalizeDomain(name)
+	if err != nil {
+		return Domain{}, err
+	}
+	tags, err = normalizeTags(tags)
+	if err != nil {
+		return Domain{}, err
+	}
+
+	portfolio.mu.Lock()
+	defer portfolio.mu.Unlock()
+	d, ok := portfolio.domains[name]
+	if !ok {
+		if len(portfolio.domains) >= MaxDomains {
+			return Domain{}, fmt.Errorf("the portfolio is full: maximum is %d domains", MaxDomains)
+		}
+		d = &Domain{Name: name, AddedAt: time.Now().UTC()}
+		portfolio.domains[name] = d
+	}
+	d.Tags = tags
+	portfolio.saveLocked(name)
+	return *d, nil
+}
+
+// Remove deletes a domain from the portfolio.
+func Remove(name string) error {
+	name = strings.TrimSuffix(strings.ToLower(strings.TrimSpace(name)), ".")
+	portfolio.mu.Lock()
+	defer portfolio.mu.Unlock()
+	if _, ok := portfolio.domains[name]; !ok {
+		return ErrNotFound
+	}
+	delete(portfolio.domains, name)
+	portfolio.saveLocked(name)
+	return nil
+}
+
+// List returns the portfolio sorted by name, limited to domains with the tag when one is given.
+func List(tag string) []Domain {
+	tag = strings.ToLower(strings.TrimSpace(tag))
+	portfolio.mu.Lock()
+	defer portfolio.mu.Unlock()
+	domains := []Domain{}
+	for _, d := range portfolio.domains {
+		if tag == "" || hasTag(d.Tags, tag) {
+			domains = append(domains, *d)
+		}
+	}
+	sort.Slice(domains, func(i, j int) bool { return domains[i].Name < domains[j].Name })
+	return domains
+}
+
+func hasTag(tags []string, tag string) bool {
+	for _, t := range tags {
+		if t == tag {
+			return true
+		}
+	}
+	return false
+}
+
+// saveLocked writes a domain to the backend, or deletes it there once it has left the
+// portfolio.
+func (s *store) saveLocked(name string) {
+	d, ok := s.domains[name]
+	if !ok {
+		if err := s.backend.Delete(domainsCollection, name); err != nil {
+			log.Printf("ERROR: Could not delete portfolio domain %s: %v", name, err)
+		}
+		return
+	}
+	data, err := json.Marshal(d)
+	if err != nil {
+		log.Printf("ERROR: Could not encode portfolio domain %s: %v", name, err)
+		return
+	}
+	if err := s.backend.Put(domainsCollection, name, data); err != nil {
+		log.Printf("ERROR: Could not save portfolio domain %s: %v", name, err)
+	}
+}
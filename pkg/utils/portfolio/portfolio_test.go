@@ -0,0 +1,195 @@
+package portfolio
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/vit0-9/utils_api/pkg/utils/storage"
+)
+
+// resetPortfolio empties the package state for a test.
+func resetPortfolio(t *testing.T) {
+	t.Helper()
+	portfolio = &store{backend: storage.NewMemory(), domains: make(map[string]*Domain)}
+	jobsMu.Lock()
+	jobs = nil
+	jobsMu.Unlock()
+}
+
+func TestPutListRemove(t *testing.T) {
+	resetPortfolio(t)
+	backend, err := storage.NewFile(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	Configure(backend, "")
+
+	if _, err := Put("Example.COM.", []string{"Prod", "eu", "prod", " "}); err != nil {
+		t.Fatalf("Put() error = %v", err)
+	}
+	if _, err := Put("example.org", []string{"staging"}); err != nil {
+		t.Fatalf("Put() error = %v", err)
+	}
+	for _, bad := range []string{"localhost", "-bad-.com", "exa mple.com"} {
+		if _, err := Put(bad, nil); err == nil {
+			t.Errorf("Put(%q) error = nil, want an invalid domain error", bad)
+		}
+	}
+	if _, err := Put("example.net", []string{"no spaces"}); err == nil {
+		t.Error("Put() with an invalid tag error = nil")
+	}
+
+	if got := List("PROD"); len(got) != 1 || got[0].Name != "example.com" || !reflect.DeepEqual(got[0].Tags, []string{"eu", "prod"}) {
+		t.Errorf("List(prod) = %+v, want example.com with tags [eu prod]", got)
+	}
+	if got := List(""); len(got) != 2 || got[0].Name != "example.com" || got[1].Name != "example.org" {
+		t.Errorf("List() = %+v, want both domains sorted by name", got)
+	}
+
+	// A fresh store configured with the same backend sees the saved portfolio.
+	resetPortfolio(t)
+	Configure(backend, "")
+	if got := List(""); len(got) != 2 {
+		t.Fatalf("List() after reload = %+v, want 2 domains", got)
+	}
+
+	if err := Remove("example.org"); err != nil {
+		t.Errorf("Remove() error = %v", err)
+	}
+	if err := Remove("example.org"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Remove() of a missing domain error = %v, want ErrNotFound", err)
+	}
+}
+
+func TestConfigureImportsLegacyFile(t *testing.T) {
+	resetPortfolio(t)
+	backend := storage.NewMemory()
+	Configure(backend, "")
+	if _, err := Put("kept.example", []string{"new"}); err != nil {
+		t.Fatal(err)
+	}
+
+	path := filepath.Join(t.TempDir(), "portfolio.json")
+	legacy := `[{"name": "kept.example", "tags": ["old"]}, {"name": "legacy.example", "tags": ["old"]}]`
+	if err := os.WriteFile(path, []byte(legacy), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	resetPortfolio(t)
+	Configure(backend, path)
+	got := List("")
+	if len(got) != 2 || !reflect.DeepEqual(got[0].Tags, []string{"new"}) || got[1].Name != "legacy.example" {
+		t.Fatalf("List() = %+v, want kept.example with its stored tags and the imported legacy.example", got)
+	}
+	if documents, _ := backend.Load(domainsCollection); len(documents) != 2 {
+		t.Errorf("backend holds %d domains, want the imported one saved too", len(documents))
+	}
+}
+
+func TestStartJob(t *testing.T) {
+	resetPortfolio(t)
+	defer func(saved map[string]func(context.Context, string) (any, string, error)) { operations = saved }(operations)
+	operations = map[string]func(context.Context, string) (any, string, error){
+		"fake": func(_ context.Context, name string) (any, string, error) {
+			switch name {
+			case "broken.example":
+				return nil, "", errors.New("unreachable")
+			case "expiring.example":
+				return "ok", name + ": expires soon", nil
+			}
+			return "ok", "", nil
+		},
+	}
+	for _, name := range []string{"broken.example", "expiring.example", "fine.example"} {
+		if _, err := Put(name, []string{"all"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if _, err := StartJob("unknown", "", ""); err == nil {
+		t.Error("StartJob(unknown) error = nil")
+	}
+	if _, err := StartJob("fake", "no-such-tag", ""); err == nil {
+		t.Error("StartJob() with no matching domains error = nil")
+	}
+
+	started, err := StartJob("fake", "all", "")
+	if err != nil {
+		t.Fatalf("StartJob() error = %v", err)
+	}
+	var job Job
+	for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+		if job, _ = GetJob(started.ID); job.Status == JobCompleted {
+			break
+		}
+	}
+	if job.Status != JobCompleted || job.Done != 3 || job.Failed != 1 {
+		t.Fatalf("job = %+v, want 3 done and 1 failed", job)
+	}
+	if len(job.Results) != 3 || job.Results[0].Error != "unreachable" || job.Results[2].Result != "ok" {
+		t.Errorf("job results = %+v", job.Results)
+	}
+	if !reflect.DeepEqual(job.Alerts, []string{"expiring.example: expires soon"}) {
+		t.Errorf("job alerts = %v", job.Alerts)
+	}
+	if listed := ListJobs(); len(listed) != 1 || listed[0].Results != nil {
+		t.Errorf("ListJobs() = %+v, want one job without results", listed)
+	}
+
+	// Completed jobs survive a restart.
+	jobsMu.Lock()
+	jobs = nil
+	jobsMu.Unlock()
+	loadJobs(portfolio.backend)
+	if restored, ok := GetJob(started.ID); !ok || restored.Status != JobCompleted || len(restored.Results) != 3 {
+		t.Errorf("GetJob() after reload = %+v, %v; want the completed job", restored, ok)
+	}
+}
+
+func TestRetryJob(t *testing.T) {
+	resetPortfolio(t)
+	defer func(saved map[string]func(context.Context, string) (any, string, error)) { operations = saved }(operations)
+	operations = map[string]func(context.Context, string) (any, string, error){
+		"fake": func(context.Context, string) (any, string, error) { return "ok", "", nil },
+	}
+	for _, name := range []string{"a.example", "b.example", "c.example"} {
+		if _, err := Put(name, []string{"all"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// A job the process stopped during is interrupted after a restart, with the results it
+	// checkpointed.
+	saveJob(Job{ID: "first", Operation: "fake", Tag: "all", Status: JobRunning, Total: 3, Done: 1, Results: []JobResult{{Domain: "a.example", Result: "ok"}}})
+	loadJobs(portfolio.backend)
+	if interrupted, _ := GetJob("first"); interrupted.Status != JobInterrupted {
+		t.Fatalf("status after reload = %q, want interrupted", interrupted.Status)
+	}
+	if _, err := RetryJob("missing"); !errors.Is(err, ErrJobNotFound) {
+		t.Errorf("RetryJob(missing) = %v", err)
+	}
+
+	retry, err := RetryJob("first")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if retry.RetryOf != "first" || retry.Total != 2 {
+		t.Errorf("retry = %+v, want the 2 unchecked domains", retry)
+	}
+	var job Job
+	for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+		if job, _ = GetJob(retry.ID); job.Status == JobCompleted {
+			break
+		}
+	}
+	if len(job.Results) != 2 || job.Results[0].Domain != "b.example" {
+		t.Errorf("retry results = %+v", job.Results)
+	}
+	if _, err := RetryJob(retry.ID); !errors.Is(err, ErrJobNotInterrupted) {
+		t.Errorf("RetryJob(completed) = %v", err)
+	}
+}
@@ -0,0 +1,185 @@
+package qrcode
+
+import (
+	"bytes"
+	"errors"
+	"image/png"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestReedSolomon(t *testing.T) {
+	// "HELLO WORLD" at 1-M, the worked example of the standard's annex
+	data := []byte{32, 91, 11, 120, 209, 114, 220, 77, 67, 64, 236, 17, 236, 17, 236, 17}
+	want := []byte{196, 35, 39, 119, 235, 215, 231, 226, 93, 23}
+	if got := reedSolomonRemainder(data, reedSolomonDivisor(10)); !bytes.Equal(got, want) {
+		t.Errorf("reedSolomonRemainder() = %v, want %v", got, want)
+	}
+}
+
+func TestFormatAndVersionInfo(t *testing.T) {
+	for _, tt := range []struct {
+		level Level
+		mask  int
+		want  int
+	}{
+		{Low, 0, 0b111011111000100},
+		{Low, 4, 0b110011000101111},
+		{Medium, 0, 0b101010000010010},
+		{Medium, 7, 0b100101010100000},
+		{Quartile, 0, 0b011010101011111},
+		{High, 0, 0b001011010001001},
+	} {
+		if got := formatInfo(tt.level, tt.mask); got != tt.want {
+			t.Errorf("formatInfo(%v, %d) = %015b, want %015b", tt.level, tt.mask, got, tt.want)
+		}
+	}
+	if got := versionInfo(7); got != 0b000111110010010100 {
+		t.Errorf("versionInfo(7) = %018b", got)
+	}
+	if got := versionInfo(40); got != 0b101000110001101001 {
+		t.Errorf("versionInfo(40) = %018b", got)
+	}
+}
+
+func TestAlignmentPositions(t *testing.T) {
+	for version, want := range map[int][]int{
+		1:  nil,
+		2:  {6, 18},
+		7:  {6, 22, 38},
+		32: {6, 34, 60, 86, 112, 138},
+		40: {6, 30, 58, 86, 114, 142, 170},
+	} {
+		if got := alignmentPositions(version); !reflect.DeepEqual(got, want) {
+			t.Errorf("alignmentPositions(%d) = %v, want %v", version, got, want)
+		}
+	}
+}
+
+func TestEncodeVersion(t *testing.T) {
+	for _, tt := range []struct {
+		length  int
+		level   Level
+		version int
+	}{
+		{17, Low, 1},
+		{18, Low, 2},
+		{14, Medium, 1},
+		{7, High, 1},
+		{8, High, 2},
+		{271, Low, 10}, // The character count grows to 16 bits from version 10
+		{2953, Low, 40},
+		{1273, High, 40},
+	} {
+		code, err := Encode(bytes.Repeat([]byte("a"), tt.length), tt.level)
+		if err != nil || code.Version != tt.version || code.Size != tt.version*4+17 {
+			t.Errorf("Encode(%d bytes, %v) = version %v, %v; want version %d", tt.length, tt.level, code, err, tt.version)
+		}
+	}
+	if _, err := Encode(bytes.Repeat([]byte("a"), 2954), Low); !errors.Is(err, ErrTooLong) {
+		t.Errorf("Encode() of 2954 bytes at L error = %v, want ErrTooLong", err)
+	}
+}
+
+// readCodewords reads the codewords back from a code's data modules, removing its mask.
+func readCodewords(c *Code) []byte {
+	c.applyMask(c.Mask)
+	defer c.applyMask(c.Mask)
+	var codewords []byte
+	i := 0
+	for right := c.Size - 1; right >= 1; right -= 2 {
+		if right == 6 {
+			right = 5
+		}
+		for vert := range c.Size {
+			for j := range 2 {
+				x, y := right-j, vert
+				if (right+1)&2 == 0 {
+					y = c.Size - 1 - vert
+				}
+				if c.isFunction[y][x] {
+					continue
+				}
+				if i%8 == 0 {
+					codewords = append(codewords, 0)
+				}
+				if c.modules[y][x] {
+					codewords[i/8] |= 1 << (7 - i%8)
+				}
+				i++
+			}
+		}
+	}
+	return codewords[:rawDataModules(c.Version)/8]
+}
+
+func TestEncodeRoundTrip(t *testing.T) {
+	for _, tt := range []struct {
+		data  string
+		level Level
+	}{
+		{"https://example.com", Medium},
+		{"https://example.com/landing?utm_source=newsletter&utm_medium=email&utm_campaign=spring_sale", Quartile},
+		{strings.Repeat("https://example.com/very/long/path/", 30), Low},
+		{strings.Repeat("x", 500), High},
+	} {
+		code, err := Encode([]byte(tt.data), tt.level)
+		if err != nil {
+			t.Fatalf("Encode() error = %v", err)
+		}
+		want := addECCAndInterleave(encodeData([]byte(tt.data), code.Version, tt.level), code.Version, tt.level)
+		if got := readCodewords(code); !bytes.Equal(got, want) {
+			t.Errorf("version %d-%v: the codewords read back differ from those encoded", code.Version, tt.level)
+		}
+		// Finder pattern corners and the dark module
+		if !code.Dark(0, 0) || !code.Dark(code.Size-1, 0) || !code.Dark(0, code.Size-1) || !code.Dark(8, code.Size-8) {
+			t.Errorf("version %d: finder patterns or dark module missing", code.Version)
+		}
+	}
+}
+
+func TestRender(t *testing.T) {
+	code, err := Encode([]byte("https://example.com"), Medium)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := code.PNG(330, DefaultMargin)
+	if err != nil {
+		t.Fatalf("PNG() error = %v", err)
+	}
+	img, err := png.Decode(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("PNG() is not a PNG: %v", err)
+	}
+	// Version 2 is 25 modules, 33 with the margin: 10 pixels a module
+	if code.Version != 2 || img.Bounds().Dx() != 330 || img.Bounds().Dy() != 330 {
+		t.Errorf("PNG() size = %v", img.Bounds())
+	}
+	if r, _, _, _ := img.At(5, 5).RGBA(); r != 0xffff {
+		t.Error("PNG() quiet zone is not white")
+	}
+	if r, _, _, _ := img.At(45, 45).RGBA(); r != 0 {
+		t.Error("PNG() finder pattern corner is not black")
+	}
+	if _, err := code.PNG(20, DefaultMargin); err == nil {
+		t.Error("PNG() smaller than the modules succeeded")
+	}
+
+	svg := string(code.SVG(200, 2))
+	if !strings.HasPrefix(svg, `<svg xmlns="http://www.w3.org/2000/svg" width="200" height="200" viewBox="0 0 29 29"`) ||
+		!strings.Contains(svg, `d="M2 2h7v1h-7z`) {
+		t.Errorf("SVG() = %.200s", svg)
+	}
+}
+
+func TestParseLevel(t *testing.T) {
+	for input, want := range map[string]Level{"L": Low, "m": Medium, "quartile": Quartile, " H ": High} {
+		if got, err := ParseLevel(input); err != nil || got != want {
+			t.Errorf("ParseLevel(%q) = %v, %v; want %v", input, got, err, want)
+		}
+	}
+	if _, err := ParseLevel("x"); err == nil {
+		t.Error("ParseLevel(\"x\") succeeded")
+	}
+}
@@ -0,0 +1,67 @@
+package qrcode
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+)
+
+// DefaultMargin is the quiet zone the standard asks for around the code, in modules.
+const DefaultMargin = 4
+
+// MinPixels returns the smallest image size showing every module, and the margin, with at
+// least one pixel.
+func (c *Code) MinPixels(margin int) int {
+	return c.Size + 2*margin
+}
+
+// PNG renders the code as a black on white PNG of size by size pixels with a quiet zone of
+// margin modules. Modules are spread over the pixels as evenly as possible, so a size that
+// is a multiple of MinPixels gives modules of equal size.
+func (c *Code) PNG(size, margin int) ([]byte, error) {
+	modules := c.MinPixels(margin)
+	if size < modules {
+		return nil, fmt.Errorf("size must be at least %d pixels for this code", modules)
+	}
+	img := image.NewPaletted(image.Rect(0, 0, size, size), color.Palette{color.White, color.Black})
+	for py := range size {
+		y := py*modules/size - margin
+		for px := range size {
+			if c.Dark(px*modules/size-margin, y) {
+				img.SetColorIndex(px, py, 1)
+			}
+		}
+	}
+	var buf bytes.Buffer
+	encoder := png.Encoder{CompressionLevel: png.BestCompression}
+	if err := encoder.Encode(&buf, img); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// SVG renders the code as a black on white SVG of size by size pixels with a quiet zone of
+// margin modules, drawing the dark modules of each row as runs in a single path.
+func (c *Code) SVG(size, margin int) []byte {
+	modules := c.MinPixels(margin)
+	var path bytes.Buffer
+	for y := range c.Size {
+		for x := 0; x < c.Size; x++ {
+			if !c.modules[y][x] {
+				continue
+			}
+			run := 1
+			for x+run < c.Size && c.modules[y][x+run] {
+				run++
+			}
+			fmt.Fprintf(&path, "M%d %dh%dv1h-%dz", x+margin, y+margin, run, run)
+			x += run - 1
+		}
+	}
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d" shape-rendering="crispEdges">`, size, size, modules, modules)
+	fmt.Fprintf(&buf, `<rect width="%d" height="%d" fill="#fff"/><path fill="#000" d="%s"/></svg>`, modules, modules, path.String())
+	return buf.Bytes()
+}
@@ -0,0 +1,64 @@
+package quota
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/vit0-9/utils_api/pkg/utils/redis"
+)
+
+// redisCounterPrefix namespaces the counters in a shared Redis.
+const redisCounterPrefix = "utils_api:quota:"
+
+// redisCounters keep the counters in Redis keys that expire after their period.
+type redisCounters struct {
+	client *redis.Client
+}
+
+// charge increments both counters, then takes the cost back when that went over a limit,
+// so concurrent requests on different replicas cannot overspend together.
+func (c *redisCounters) charge(caller Caller, cost int64, now time.Time) (Usage, error) {
+	ctx := context.Background()
+	day, month, dayEnd, monthEnd := periods(now)
+	dayKey := redisCounterPrefix + caller.ID + ":" + day
+	monthKey := redisCounterPrefix + caller.ID + ":" + month
+	usage := Usage{DailyReset: dayEnd, MonthlyReset: monthEnd}
+
+	var err error
+	if usage.Daily, err = c.increment(ctx, dayKey, cost, dayEnd.Sub(now)+time.Hour); err != nil {
+		return usage, err
+	}
+	if usage.Monthly, err = c.increment(ctx, monthKey, cost, monthEnd.Sub(now)+time.Hour); err != nil {
+		c.increment(ctx, dayKey, -cost, 0)
+		return usage, err
+	}
+	before := Usage{Daily: usage.Daily - cost, Monthly: usage.Monthly - cost}
+	if exceeds(caller.Limits, before, cost) {
+		c.increment(ctx, dayKey, -cost, 0)
+		c.increment(ctx, monthKey, -cost, 0)
+		usage.Daily, usage.Monthly = before.Daily, before.Monthly
+		return usage, ErrExceeded
+	}
+	return usage, nil
+}
+
+// increment adds delta to a counter and returns its value, setting its expiry when ttl is
+// positive.
+func (c *redisCounters) increment(ctx context.Context, key string, delta int64, ttl time.Duration) (int64, error) {
+	reply, err := c.client.Do(ctx, "INCRBY", key, strconv.FormatInt(delta, 10))
+	if err != nil {
+		return 0, err
+	}
+	value, ok := reply.(int64)
+	if !ok {
+		return 0, fmt.Errorf("unexpected reply %v", reply)
+	}
+	if ttl > 0 {
+		if _, err := c.client.Do(ctx, "PEXPIRE", key, strconv.FormatInt(ttl.Milliseconds(), 10)); err != nil {
+			return value, err
+		}
+	}
+	return value, nil
+}
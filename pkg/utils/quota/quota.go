@@ -0,0 +1,283 @@
+// Package quota charges each request a number of cost units, by endpoint, and enforces
+// daily and monthly budgets per API key, so the API can be shared fairly between teams.
+package quota
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/vit0-9/utils_api/pkg/utils/redis"
+)
+
+// APIKeyHeader identifies the caller; requests without a configured key are metered by
+// client IP under the default limits.
+const APIKeyHeader = "X-API-Key"
+
+// apiPrefix is stripped from request paths before matching costs.
+const apiPrefix = "/api/v1/"
+
+// freePaths cost nothing, so callers can always check their health and usage.
+var freePaths = []string{"health", "me"}
+
+// ErrExceeded is returned when a request would go over a budget.
+var ErrExceeded = errors.New("quota exceeded")
+
+// Limits are the cost units a caller may spend per UTC day and month; 0 is unlimited.
+type Limits struct {
+	Daily   int64 `json:"daily"`
+	Monthly int64 `json:"monthly"`
+}
+
+// KeyConfig is a caller identified by an API key.
+type KeyConfig struct {
+	Name string `json:"name"`
+	Key  string `json:"key"`
+	Limits
+}
+
+// Config is the quota configuration file.
+type Config struct {
+	Default Limits           `json:"default"` // For callers without a configured key, per client IP
+	Keys    []KeyConfig      `json:"keys"`
+	Costs   map[string]int64 `json:"costs"` // By endpoint or group relative to /api/v1, e.g. "net/ssl-check"; 1 otherwise
+}
+
+var (
+	mu      sync.RWMutex
+	enabled bool
+	config  Config
+)
+
+// LoadConfig enables quotas with the JSON configuration at path. An empty path leaves
+// quotas off.
+func LoadConfig(path string) {
+	if path == "" {
+		return
+	}
+	fileData, err := os.ReadFile(path)
+	if err != nil {
+		log.Fatalf("Could not read quota config at %s: %v", path, err)
+	}
+	var cfg Config
+	if err := json.Unmarshal(fileData, &cfg); err != nil {
+		log.Fatalf("Could not parse quota config at %s: %v", path, err)
+	}
+	if err := Configure(cfg); err != nil {
+		log.Fatalf("Invalid quota config at %s: %v", path, err)
+	}
+	log.Printf("Quotas loaded from %s (%d keys)", path, len(cfg.Keys))
+}
+
+// Configure enables quotas with cfg.
+func Configure(cfg Config) error {
+	names := make(map[string]bool)
+	for i, key := range cfg.Keys {
+		if key.Name == "" || key.Key == "" {
+			return fmt.Errorf("key %d needs a name and a key", i+1)
+		}
+		if names[key.Name] {
+			return fmt.Errorf("duplicate key name %q", key.Name)
+		}
+		names[key.Name] = true
+		if key.Daily < 0 || key.Monthly < 0 {
+			return fmt.Errorf("key %s: limits cannot be negative", key.Name)
+		}
+	}
+	costs := make(map[string]int64, len(cfg.Costs))
+	for endpoint, cost := range cfg.Costs {
+		if cost < 0 {
+			return fmt.Errorf("cost of %s cannot be negative", endpoint)
+		}
+		costs[strings.Trim(strings.ToLower(endpoint), "/")] = cost
+	}
+	cfg.Costs = costs
+
+	mu.Lock()
+	defer mu.Unlock()
+	config = cfg
+	enabled = true
+	return nil
+}
+
+// Enabled reports whether quotas are enforced.
+func Enabled() bool {
+	mu.RLock()
+	defer mu.RUnlock()
+	return enabled
+}
+
+// Caller is who a request is charged to.
+type Caller struct {
+	ID     string `json:"id"` // "key:<name>" or "ip:<address>"
+	Limits Limits `json:"limits"`
+}
+
+// Identify returns the caller of a request from its API key, falling back to the client IP
+// when the key is missing or unknown.
+func Identify(apiKey, clientIP string) Caller {
+	mu.RLock()
+	defer mu.RUnlock()
+	if apiKey != "" {
+		for _, key := range config.Keys {
+			if subtle.ConstantTimeCompare([]byte(apiKey), []byte(key.Key)) == 1 {
+				return Caller{ID: "key:" + key.Name, Limits: key.Limits}
+			}
+		}
+	}
+	return Caller{ID: "ip:" + clientIP, Limits: config.Default}
+}
+
+// Cost returns the cost units of a request to path: the longest configured endpoint or
+// group matching it, or 1.
+func Cost(path string) int64 {
+	if !strings.HasPrefix(path, apiPrefix) {
+		return 0
+	}
+	relative := strings.TrimSuffix(strings.ToLower(strings.TrimPrefix(path, apiPrefix)), "/")
+	for _, free := range freePaths {
+		if relative == free || strings.HasPrefix(relative, free+"/") {
+			return 0
+		}
+	}
+	mu.RLock()
+	defer mu.RUnlock()
+	cost, matched := int64(1), ""
+	for endpoint, endpointCost := range config.Costs {
+		if (relative == endpoint || strings.HasPrefix(relative, endpoint+"/")) && len(endpoint) > len(matched) {
+			cost, matched = endpointCost, endpoint
+		}
+	}
+	return cost
+}
+
+// Costs returns the configured endpoint costs, sorted by endpoint.
+func Costs() []EndpointCost {
+	mu.RLock()
+	defer mu.RUnlock()
+	costs := make([]EndpointCost, 0, len(config.Costs))
+	for endpoint, cost := range config.Costs {
+		costs = append(costs, EndpointCost{Endpoint: endpoint, Cost: cost})
+	}
+	sort.Slice(costs, func(i, j int) bool { return costs[i].Endpoint < costs[j].Endpoint })
+	return costs
+}
+
+// EndpointCost is the cost of an endpoint or group.
+type EndpointCost struct {
+	Endpoint string `json:"endpoint"`
+	Cost     int64  `json:"cost"`
+}
+
+// Usage is what a caller spent in the current periods.
+type Usage struct {
+	Daily        int64     `json:"daily"`
+	Monthly      int64     `json:"monthly"`
+	DailyReset   time.Time `json:"daily_reset"`   // Start of the next UTC day
+	MonthlyReset time.Time `json:"monthly_reset"` // Start of the next UTC month
+}
+
+// periods returns the keys of the day and month containing now, and when they end.
+func periods(now time.Time) (day, month string, dayEnd, monthEnd time.Time) {
+	now = now.UTC()
+	dayStart := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	monthStart := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+	return dayStart.Format("2006-01-02"), monthStart.Format("2006-01"), dayStart.AddDate(0, 0, 1), monthStart.AddDate(0, 1, 0)
+}
+
+// Charge spends cost units of the caller's budgets and returns the usage after it. When the
+// request would exceed a budget nothing is spent and ErrExceeded is returned with the
+// current usage.
+func Charge(caller Caller, cost int64) (Usage, error) {
+	return currentCounters().charge(caller, cost, time.Now())
+}
+
+// CurrentUsage returns what the caller spent so far in the current periods.
+func CurrentUsage(caller Caller) (Usage, error) {
+	return currentCounters().charge(caller, 0, time.Now())
+}
+
+// Remaining returns the units left under a limit, or -1 when it is unlimited.
+func Remaining(limit, used int64) int64 {
+	if limit == 0 {
+		return -1
+	}
+	if used > limit {
+		return 0
+	}
+	return limit - used
+}
+
+// counters keep the units spent per caller and period.
+type counters interface {
+	charge(caller Caller, cost int64, now time.Time) (Usage, error)
+}
+
+var (
+	countersMu     sync.RWMutex
+	activeCounters counters = newLocalCounters()
+)
+
+func currentCounters() counters {
+	countersMu.RLock()
+	defer countersMu.RUnlock()
+	return activeCounters
+}
+
+// ConfigureSharedCounters keeps the counters in Redis, so every replica enforces the same
+// budgets.
+func ConfigureSharedCounters(client *redis.Client) {
+	if client == nil {
+		return
+	}
+	countersMu.Lock()
+	activeCounters = &redisCounters{client: client}
+	countersMu.Unlock()
+}
+
+// localCounters keep the counters of the current periods in memory.
+type localCounters struct {
+	mu      sync.Mutex
+	day     string
+	month   string
+	daily   map[string]int64 // By caller ID
+	monthly map[string]int64
+}
+
+func newLocalCounters() *localCounters {
+	return &localCounters{daily: make(map[string]int64), monthly: make(map[string]int64)}
+}
+
+func (c *localCounters) charge(caller Caller, cost int64, now time.Time) (Usage, error) {
+	day, month, dayEnd, monthEnd := periods(now)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.day != day {
+		c.day, c.daily = day, make(map[string]int64)
+	}
+	if c.month != month {
+		c.month, c.monthly = month, make(map[string]int64)
+	}
+	usage := Usage{Daily: c.daily[caller.ID], Monthly: c.monthly[caller.ID], DailyReset: dayEnd, MonthlyReset: monthEnd}
+	if exceeds(caller.Limits, usage, cost) {
+		return usage, ErrExceeded
+	}
+	c.daily[caller.ID] += cost
+	c.monthly[caller.ID] += cost
+	usage.Daily += cost
+	usage.Monthly += cost
+	return usage, nil
+}
+
+// exceeds reports whether spending cost on top of usage goes over a limit.
+func exceeds(limits Limits, usage Usage, cost int64) bool {
+	return cost > 0 && ((limits.Daily > 0 && usage.Daily+cost > limits.Daily) ||
+		(limits.Monthly > 0 && usage.Monthly+cost > limits.Monthly))
+}
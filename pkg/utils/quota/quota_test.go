@@ -0,0 +1,101 @@
+package quota
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/vit0-9/utils_api/pkg/utils/redis"
+	"github.com/vit0-9/utils_api/pkg/utils/redis/redistest"
+)
+
+func TestCostAndIdentify(t *testing.T) {
+	err := Configure(Config{
+		Default: Limits{Daily: 10},
+		Keys:    []KeyConfig{{Name: "team-a", Key: "secret", Limits: Limits{Monthly: 100}}},
+		Costs:   map[string]int64{"net/ssl-check": 10, "/Net/": 2, "web": 5},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for path, want := range map[string]int64{
+		"/api/v1/net/ssl-check":      10,
+		"/api/v1/net/ssl-checker":    2,
+		"/api/v1/net/dns-lookup":     2,
+		"/api/v1/web/stack-analyzer": 5,
+		"/api/v1/url/clean":          1,
+		"/api/v1/health":             0,
+		"/api/v1/me/usage":           0,
+		"/swagger/index.html":        0,
+	} {
+		if got := Cost(path); got != want {
+			t.Errorf("Cost(%s) = %d, want %d", path, got, want)
+		}
+	}
+
+	if caller := Identify("secret", "192.0.2.1"); caller.ID != "key:team-a" || caller.Limits.Monthly != 100 {
+		t.Errorf("Identify() with a key = %+v", caller)
+	}
+	if caller := Identify("wrong", "192.0.2.1"); caller.ID != "ip:192.0.2.1" || caller.Limits.Daily != 10 {
+		t.Errorf("Identify() with an unknown key = %+v", caller)
+	}
+
+	if err := Configure(Config{Keys: []KeyConfig{{Name: "a", Key: "x"}, {Name: "a", Key: "y"}}}); err == nil {
+		t.Error("Configure() with duplicate key names error = nil")
+	}
+}
+
+func testCharge(t *testing.T, c counters) {
+	t.Helper()
+	caller := Caller{ID: "key:test", Limits: Limits{Daily: 25, Monthly: 30}}
+	now := time.Date(2026, 3, 31, 23, 0, 0, 0, time.UTC)
+
+	for i, want := range []int64{10, 20} {
+		usage, err := c.charge(caller, 10, now)
+		if err != nil || usage.Daily != want || usage.Monthly != want {
+			t.Fatalf("charge %d = %+v, %v; want %d units spent", i+1, usage, err, want)
+		}
+	}
+	usage, err := c.charge(caller, 10, now)
+	if !errors.Is(err, ErrExceeded) || usage.Daily != 20 {
+		t.Fatalf("charge over the daily limit = %+v, %v; want ErrExceeded with nothing spent", usage, err)
+	}
+	if !usage.DailyReset.Equal(time.Date(2026, 4, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("DailyReset = %s", usage.DailyReset)
+	}
+
+	// The next day the daily budget is fresh, but the month is almost spent.
+	nextDay := now.Add(2 * time.Hour)
+	if usage, err := c.charge(caller, 10, nextDay); err != nil || usage.Daily != 10 || usage.Monthly != 10 {
+		t.Errorf("charge in the next day and month = %+v, %v; want fresh counters", usage, err)
+	}
+	if _, err := c.charge(caller, 10, nextDay); err != nil {
+		t.Fatal(err)
+	}
+	dayAfter := nextDay.Add(24 * time.Hour)
+	if _, err := c.charge(caller, 15, dayAfter); !errors.Is(err, ErrExceeded) {
+		t.Errorf("charge over the monthly limit error = %v, want ErrExceeded", err)
+	}
+	if usage, err := c.charge(caller, 0, dayAfter); err != nil || usage.Daily != 0 || usage.Monthly != 20 {
+		t.Errorf("usage = %+v, %v; want 20 units spent this month", usage, err)
+	}
+}
+
+func TestLocalCounters(t *testing.T) {
+	testCharge(t, newLocalCounters())
+}
+
+func TestRedisCounters(t *testing.T) {
+	server := redistest.NewServer(t)
+	client := redis.New(server.Addr, "", 0)
+	defer client.Close()
+	testCharge(t, &redisCounters{client: client})
+}
+
+func TestRemaining(t *testing.T) {
+	for _, tc := range []struct{ limit, used, want int64 }{{0, 5, -1}, {10, 4, 6}, {10, 12, 0}} {
+		if got := Remaining(tc.limit, tc.used); got != tc.want {
+			t.Errorf("Remaining(%d, %d) = %d, want %d", tc.limit, tc.used, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,150 @@
+// Package redact removes sensitive values from what the API logs and persists: the query
+// strings of configured endpoints, secret query parameters and email addresses, so the
+// utilities can be deployed in privacy-sensitive environments.
+package redact
+
+import (
+	"log"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// apiPrefix is stripped from request paths before matching; entries are relative to it.
+const apiPrefix = "/api/v1/"
+
+// Mask replaces redacted values.
+const Mask = "[REDACTED]"
+
+// defaultParams are query parameters whose values are always masked.
+var defaultParams = []string{"api_key", "apikey", "authorization", "jwt", "key", "password", "proxy", "secret", "token"}
+
+// emailRegex matches email addresses; the local part is masked.
+var emailRegex = regexp.MustCompile(`([A-Za-z0-9._%+-])[A-Za-z0-9._%+-]*@([A-Za-z0-9-]+(\.[A-Za-z0-9-]+)+)`)
+
+var (
+	mu         sync.RWMutex
+	endpoints  []string        // Entries such as "net/whois-lookup" whose queries are never kept
+	params     map[string]bool // Lowercased parameter names whose values are masked
+	maskEmails = true
+)
+
+func init() {
+	Configure("", "", true)
+}
+
+// Configure sets the comma-separated endpoints (a group such as "web", or a single
+// endpoint with its sub-paths) whose query strings are never logged or persisted, the
+// query parameters masked in addition to the defaults, and whether email addresses are
+// masked.
+func Configure(endpointEntries, paramNames string, emails bool) {
+	mu.Lock()
+	defer mu.Unlock()
+	endpoints = nil
+	for _, entry := range strings.Split(endpointEntries, ",") {
+		entry = strings.Trim(strings.ToLower(strings.TrimSpace(entry)), "/")
+		entry = strings.TrimPrefix(entry, strings.Trim(apiPrefix, "/")+"/")
+		if entry != "" {
+			endpoints = append(endpoints, entry)
+		}
+	}
+	sort.Strings(endpoints)
+	params = make(map[string]bool)
+	for _, name := range append(strings.Split(paramNames, ","), defaultParams...) {
+		if name = strings.ToLower(strings.TrimSpace(name)); name != "" {
+			params[name] = true
+		}
+	}
+	maskEmails = emails
+	if len(endpoints) > 0 {
+		log.Printf("Query strings of %s are redacted from logs", strings.Join(endpoints, ", "))
+	}
+}
+
+// Endpoint reports whether nothing of the requests to the endpoint at path, beyond the
+// path itself, may be logged or persisted.
+func Endpoint(path string) bool {
+	if !strings.HasPrefix(path, apiPrefix) {
+		return false
+	}
+	relative := strings.TrimSuffix(strings.ToLower(strings.TrimPrefix(path, apiPrefix)), "/")
+	mu.RLock()
+	defer mu.RUnlock()
+	for _, entry := range endpoints {
+		if relative == entry || strings.HasPrefix(relative, entry+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// Query returns the raw query of a request to path fit for logging: all of it masked for
+// redacted endpoints, otherwise with secret parameters and email addresses masked.
+func Query(path, rawQuery string) string {
+	if rawQuery == "" {
+		return ""
+	}
+	if Endpoint(path) {
+		return Mask
+	}
+	values, err := url.ParseQuery(rawQuery)
+	if err != nil {
+		return Mask
+	}
+	mu.RLock()
+	for name := range values {
+		if params[strings.ToLower(name)] {
+			values[name] = []string{Mask}
+		}
+	}
+	mu.RUnlock()
+	for name, list := range values {
+		for i, value := range list {
+			list[i] = Text(value)
+		}
+		values[name] = list
+	}
+	return values.Encode()
+}
+
+// Text masks the email addresses in s, keeping the first character and the domain, when
+// email masking is on.
+func Text(s string) string {
+	mu.RLock()
+	enabled := maskEmails
+	mu.RUnlock()
+	if !enabled || !strings.Contains(s, "@") {
+		return s
+	}
+	return emailRegex.ReplaceAllString(s, "$1***@$2")
+}
+
+// Value masks the email addresses in the strings within v, which may be a string, a slice
+// or a map as decoded from JSON.
+func Value(v any) any {
+	switch typed := v.(type) {
+	case string:
+		return Text(typed)
+	case []string:
+		masked := make([]string, len(typed))
+		for i, s := range typed {
+			masked[i] = Text(s)
+		}
+		return masked
+	case []any:
+		masked := make([]any, len(typed))
+		for i, item := range typed {
+			masked[i] = Value(item)
+		}
+		return masked
+	case map[string]any:
+		masked := make(map[string]any, len(typed))
+		for key, item := range typed {
+			masked[key] = Value(item)
+		}
+		return masked
+	}
+	return v
+}
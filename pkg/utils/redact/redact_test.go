@@ -0,0 +1,47 @@
+package redact
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestQuery(t *testing.T) {
+	Configure("misc/jwt-decode, /api/v1/net/whois-lookup", "domain_token", true)
+	defer Configure("", "", true)
+
+	for _, tc := range []struct{ path, query, want string }{
+		{"/api/v1/misc/jwt-decode", "token=eyJhbGciOi", Mask},
+		{"/api/v1/net/whois-lookup/bulk", "domain=example.com", Mask},
+		{"/api/v1/net/dns-lookup", "", ""},
+		{"/api/v1/net/dns-lookup", "domain=example.com&api_key=s3cret&Domain_Token=abc", "Domain_Token=%5BREDACTED%5D&api_key=%5BREDACTED%5D&domain=example.com"},
+		{"/api/v1/url/clean", "url=mailto:jane.doe@example.com", "url=mailto%3Aj%2A%2A%2A%40example.com"},
+	} {
+		if got := Query(tc.path, tc.query); got != tc.want {
+			t.Errorf("Query(%s, %s) = %q, want %q", tc.path, tc.query, got, tc.want)
+		}
+	}
+}
+
+func TestValue(t *testing.T) {
+	data := map[string]any{
+		"contact": "Ask jane.doe@example.com or bob@mail.example.org",
+		"emails":  []string{"x@example.net"},
+		"nested":  map[string]any{"items": []any{"root@example.com", 42}},
+		"status":  200,
+	}
+	want := map[string]any{
+		"contact": "Ask j***@example.com or b***@mail.example.org",
+		"emails":  []string{"x***@example.net"},
+		"nested":  map[string]any{"items": []any{"r***@example.com", 42}},
+		"status":  200,
+	}
+	if got := Value(data); !reflect.DeepEqual(got, want) {
+		t.Errorf("Value() = %v, want %v", got, want)
+	}
+
+	Configure("", "", false)
+	defer Configure("", "", true)
+	if got := Text("jane@example.com"); got != "jane@example.com" {
+		t.Errorf("Text() with email masking off = %q", got)
+	}
+}
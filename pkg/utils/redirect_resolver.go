@@ -1,19 +1,26 @@
 package utils
 
 import (
+	"context"
 	"fmt"
 	"net/http"
 	"time"
 )
 
+// redirectClient is shared by all redirect lookups so their connections are pooled.
+var redirectClient = &http.Client{
+	Timeout:   15 * time.Second,
+	Transport: NewTargetTransport(),
+}
+
 // ResolveRedirect follows HTTP redirects for a given URL and returns the final destination URL.
-func ResolveRedirect(initialURL string) (string, error) {
-	client := &http.Client{
-		Timeout: 15 * time.Second,
+func ResolveRedirect(ctx context.Context, initialURL string) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", initialURL, nil)
+	if err != nil {
+		return "", fmt.Errorf("request failed for %s: %w", initialURL, err)
 	}
-
 	// Make a GET request. The client will automatically follow redirects.
-	resp, err := client.Get(initialURL)
+	resp, err := redirectClient.Do(req)
 	if err != nil {
 		if resp != nil && resp.Request != nil && resp.Request.URL != nil {
 			return resp.Request.URL.String(), fmt.Errorf("failed to get final URL, possibly too many redirects or other error: %w. Last known URL: %s", err, resp.Request.URL.String())
@@ -38,3 +45,10 @@ func ResolveRedirect(initialURL string) (string, error) {
 
 	return finalURL, nil
 }
+
+// RedirectCurlCommand returns the curl command equivalent to the request ResolveRedirect sends.
+func RedirectCurlCommand(initialURL string) string {
+	headers := http.Header{"User-Agent": []string{"Go-http-client/1.1"}}
+	// Outbound transports never use a proxy, so the request always goes out directly.
+	return BuildCurlCommand("GET", initialURL, headers, "", true)
+}
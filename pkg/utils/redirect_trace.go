@@ -0,0 +1,116 @@
+package utils
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// maxRedirectHops matches the limit of Go's default redirect policy.
+const maxRedirectHops = 10
+
+// RedirectCookie is a cookie set by one hop of a redirect chain.
+type RedirectCookie struct {
+	Name     string `json:"name"`
+	Value    string `json:"value"`
+	Domain   string `json:"domain,omitempty"`
+	Path     string `json:"path,omitempty"`
+	Secure   bool   `json:"secure"`
+	HttpOnly bool   `json:"http_only"`
+	SameSite string `json:"same_site,omitempty"`
+}
+
+// RedirectHop is one request of a redirect chain and the response it got.
+type RedirectHop struct {
+	URL          string           `json:"url"`
+	StatusCode   int              `json:"status_code"`
+	Location     string           `json:"location,omitempty"`      // Resolved against the hop's URL
+	SchemeChange string           `json:"scheme_change,omitempty"` // "upgrade" (http to https) or "downgrade" (https to http)
+	Cookies      []RedirectCookie `json:"cookies,omitempty"`
+	LatencyMs    float64          `json:"latency_ms"` // From sending the request to receiving the response headers
+}
+
+// RedirectTrace is every hop from a URL to its final destination.
+type RedirectTrace struct {
+	Hops           []RedirectHop `json:"hops"`
+	FinalURL       string        `json:"final_url,omitempty"`
+	Downgraded     bool          `json:"downgraded"` // Some hop redirected from https to http
+	TotalLatencyMs float64       `json:"total_latency_ms"`
+}
+
+// TraceRedirects follows the redirects of a URL like ResolveRedirect, recording each hop as
+// the client's CheckRedirect sees it. On error the trace holds the hops made so far.
+func TraceRedirects(ctx context.Context, initialURL string) (*RedirectTrace, error) {
+	trace := &RedirectTrace{Hops: []RedirectHop{}}
+	start := time.Now()
+	hopStart := start
+	client := &http.Client{
+		Timeout:   redirectClient.Timeout,
+		Transport: redirectClient.Transport,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			trace.addHop(req.Response, time.Since(hopStart))
+			hopStart = time.Now()
+			if len(via) >= maxRedirectHops {
+				return fmt.Errorf("stopped after %d redirects", maxRedirectHops)
+			}
+			return nil
+		},
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "GET", initialURL, nil)
+	if err != nil {
+		return trace, fmt.Errorf("request failed for %s: %w", initialURL, err)
+	}
+	resp, err := client.Do(req)
+	trace.TotalLatencyMs = durationMs(time.Since(start))
+	if err != nil {
+		if len(trace.Hops) > 0 {
+			trace.FinalURL = trace.Hops[len(trace.Hops)-1].Location
+		}
+		return trace, fmt.Errorf("request failed for %s: %w", initialURL, err)
+	}
+	defer resp.Body.Close()
+	trace.addHop(resp, time.Since(hopStart))
+	trace.FinalURL = resp.Request.URL.String()
+	return trace, nil
+}
+
+// addHop records the response to one request of the chain.
+func (t *RedirectTrace) addHop(resp *http.Response, latency time.Duration) {
+	hop := RedirectHop{
+		URL:        resp.Request.URL.String(),
+		StatusCode: resp.StatusCode,
+		LatencyMs:  durationMs(latency),
+	}
+	if location, err := resp.Location(); err == nil {
+		hop.Location = location.String()
+		switch {
+		case resp.Request.URL.Scheme == "http" && location.Scheme == "https":
+			hop.SchemeChange = "upgrade"
+		case resp.Request.URL.Scheme == "https" && location.Scheme == "http":
+			hop.SchemeChange = "downgrade"
+			t.Downgraded = true
+		}
+	}
+	for _, cookie := range resp.Cookies() {
+		redirectCookie := RedirectCookie{
+			Name:     cookie.Name,
+			Value:    cookie.Value,
+			Domain:   cookie.Domain,
+			Path:     cookie.Path,
+			Secure:   cookie.Secure,
+			HttpOnly: cookie.HttpOnly,
+		}
+		switch cookie.SameSite {
+		case http.SameSiteLaxMode:
+			redirectCookie.SameSite = "Lax"
+		case http.SameSiteStrictMode:
+			redirectCookie.SameSite = "Strict"
+		case http.SameSiteNoneMode:
+			redirectCookie.SameSite = "None"
+		}
+		hop.Cookies = append(hop.Cookies, redirectCookie)
+	}
+	t.Hops = append(t.Hops, hop)
+}
@@ -0,0 +1,66 @@
+package utils
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestTraceRedirects(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/start", func(w http.ResponseWriter, r *http.Request) {
+		http.SetCookie(w, &http.Cookie{Name: "session", Value: "abc", Path: "/", HttpOnly: true, SameSite: http.SameSiteLaxMode})
+		http.Redirect(w, r, "/middle", http.StatusFound)
+	})
+	mux.HandleFunc("/middle", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/end", http.StatusMovedPermanently)
+	})
+	mux.HandleFunc("/end", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("done"))
+	})
+	mux.HandleFunc("/loop", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/loop", http.StatusFound)
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	trace, err := TraceRedirects(context.Background(), server.URL+"/start")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(trace.Hops) != 3 || trace.FinalURL != server.URL+"/end" {
+		t.Fatalf("trace = %+v, want three hops ending at /end", trace)
+	}
+	first := trace.Hops[0]
+	if first.StatusCode != http.StatusFound || first.Location != server.URL+"/middle" {
+		t.Errorf("first hop = %+v, want a 302 to /middle", first)
+	}
+	if len(first.Cookies) != 1 || first.Cookies[0].Name != "session" || !first.Cookies[0].HttpOnly || first.Cookies[0].SameSite != "Lax" {
+		t.Errorf("first hop cookies = %+v, want the session cookie", first.Cookies)
+	}
+	if trace.Hops[1].StatusCode != http.StatusMovedPermanently || trace.Hops[2].StatusCode != http.StatusOK || trace.Hops[2].Location != "" {
+		t.Errorf("hops = %+v, want a 301 then the final 200", trace.Hops)
+	}
+	if trace.Downgraded || first.SchemeChange != "" {
+		t.Errorf("trace = %+v, want no scheme change", trace)
+	}
+
+	trace, err = TraceRedirects(context.Background(), server.URL+"/loop")
+	if err == nil || len(trace.Hops) != maxRedirectHops {
+		t.Errorf("redirect loop: %d hops, error %v, want %d hops and an error", len(trace.Hops), err, maxRedirectHops)
+	}
+}
+
+func TestRedirectTraceSchemeChange(t *testing.T) {
+	trace := &RedirectTrace{}
+	resp := &http.Response{
+		StatusCode: http.StatusFound,
+		Header:     http.Header{"Location": []string{"http://example.com/"}},
+	}
+	resp.Request, _ = http.NewRequest("GET", "https://example.com/", nil)
+	trace.addHop(resp, 0)
+	if !trace.Downgraded || trace.Hops[0].SchemeChange != "downgrade" {
+		t.Errorf("trace = %+v, want a downgrade", trace)
+	}
+}
@@ -0,0 +1,140 @@
+package redis
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// serveRedis runs a fake server understanding AUTH, GET, SET and DEL.
+func serveRedis(t *testing.T, password string) string {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { listener.Close() })
+	var mu sync.Mutex
+	values := make(map[string]string)
+	go func() {
+		for {
+			netConn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer netConn.Close()
+				reader := bufio.NewReader(netConn)
+				authed := password == ""
+				for {
+					args, err := readCommand(reader)
+					if err != nil {
+						return
+					}
+					mu.Lock()
+					reply := ""
+					switch cmd := strings.ToUpper(args[0]); {
+					case cmd == "AUTH":
+						authed = args[1] == password
+						reply = "+OK\r\n"
+						if !authed {
+							reply = "-WRONGPASS invalid password\r\n"
+						}
+					case !authed:
+						reply = "-NOAUTH Authentication required.\r\n"
+					case cmd == "SET":
+						values[args[1]] = args[2]
+						reply = "+OK\r\n"
+					case cmd == "GET":
+						value, ok := values[args[1]]
+						reply = "$-1\r\n"
+						if ok {
+							reply = fmt.Sprintf("$%d\r\n%s\r\n", len(value), value)
+						}
+					case cmd == "DEL":
+						_, ok := values[args[1]]
+						delete(values, args[1])
+						reply = ":0\r\n"
+						if ok {
+							reply = ":1\r\n"
+						}
+					default:
+						reply = "-ERR unknown command\r\n"
+					}
+					mu.Unlock()
+					io.WriteString(netConn, reply)
+				}
+			}()
+		}
+	}()
+	return listener.Addr().String()
+}
+
+func readCommand(reader *bufio.Reader) ([]string, error) {
+	reply, err := readReply(reader)
+	if err != nil {
+		return nil, err
+	}
+	items, ok := reply.([]any)
+	if !ok || len(items) == 0 {
+		return nil, errors.New("not a command")
+	}
+	args := make([]string, len(items))
+	for i, item := range items {
+		args[i] = string(item.([]byte))
+	}
+	return args, nil
+}
+
+func TestDo(t *testing.T) {
+	client := New(serveRedis(t, "s3cret"), "s3cret", 0)
+	defer client.Close()
+	ctx := context.Background()
+
+	if reply, err := client.Do(ctx, "SET", "key", "line\r\nbreak"); err != nil || reply != "OK" {
+		t.Fatalf("SET = %v, %v", reply, err)
+	}
+	if reply, err := client.Do(ctx, "GET", "key"); err != nil || string(reply.([]byte)) != "line\r\nbreak" {
+		t.Errorf("GET = %q, %v", reply, err)
+	}
+	if reply, err := client.Do(ctx, "GET", "missing"); err != nil || reply != nil {
+		t.Errorf("GET of a missing key = %v, %v; want nil", reply, err)
+	}
+	if reply, err := client.Do(ctx, "DEL", "key"); err != nil || reply != int64(1) {
+		t.Errorf("DEL = %v, %v", reply, err)
+	}
+	var replyErr Error
+	if _, err := client.Do(ctx, "NOPE"); !errors.As(err, &replyErr) {
+		t.Errorf("unknown command error = %v, want an Error reply", err)
+	}
+	// The connection survives an error reply.
+	if reply, err := client.Do(ctx, "GET", "missing"); err != nil || reply != nil {
+		t.Errorf("GET after an error reply = %v, %v", reply, err)
+	}
+}
+
+func TestDoWrongPassword(t *testing.T) {
+	client := New(serveRedis(t, "s3cret"), "wrong", 0)
+	if _, err := client.Do(context.Background(), "GET", "key"); err == nil || !strings.Contains(err.Error(), "WRONGPASS") {
+		t.Errorf("Do() with a wrong password error = %v", err)
+	}
+}
+
+func TestReadReplyArray(t *testing.T) {
+	reader := bufio.NewReader(strings.NewReader("*3\r\n:42\r\n$-1\r\n+OK\r\n"))
+	reply, err := readReply(reader)
+	items, _ := reply.([]any)
+	if err != nil || len(items) != 3 || items[0] != int64(42) || items[1] != nil || items[2] != "OK" {
+		t.Errorf("readReply() = %#v, %v", reply, err)
+	}
+	if _, err := readReply(bufio.NewReader(strings.NewReader("$" + strconv.Itoa(10) + "\r\nshort\r\n"))); err == nil {
+		t.Error("readReply() of a truncated bulk string error = nil")
+	}
+}
@@ -0,0 +1,232 @@
+// Package redistest runs an in-process fake Redis for tests, understanding the commands the
+// API uses apart from EVAL.
+package redistest
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// Server is a fake Redis listening on a local port.
+type Server struct {
+	Addr string
+
+	mu      sync.Mutex
+	strings map[string]string
+	hashes  map[string]map[string]string
+	lists   map[string][]string
+	expires map[string]time.Time
+}
+
+// NewServer starts a fake Redis that is stopped when the test ends.
+func NewServer(t *testing.T) *Server {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { listener.Close() })
+	s := &Server{
+		Addr:    listener.Addr().String(),
+		strings: make(map[string]string),
+		hashes:  make(map[string]map[string]string),
+		lists:   make(map[string][]string),
+		expires: make(map[string]time.Time),
+	}
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go s.serve(conn)
+		}
+	}()
+	return s
+}
+
+func (s *Server) serve(conn net.Conn) {
+	defer conn.Close()
+	reader := bufio.NewReader(conn)
+	for {
+		args, err := readCommand(reader)
+		if err != nil {
+			return
+		}
+		if _, err := io.WriteString(conn, s.run(args)); err != nil {
+			return
+		}
+	}
+}
+
+// readCommand parses a command sent as an array of bulk strings.
+func readCommand(reader *bufio.Reader) ([]string, error) {
+	line, err := reader.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	count, err := strconv.Atoi(strings.TrimSpace(strings.TrimPrefix(line, "*")))
+	if err != nil || count < 1 {
+		return nil, fmt.Errorf("malformed command %q", line)
+	}
+	args := make([]string, count)
+	for i := range args {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			return nil, err
+		}
+		size, err := strconv.Atoi(strings.TrimSpace(strings.TrimPrefix(line, "$")))
+		if err != nil {
+			return nil, fmt.Errorf("malformed argument %q", line)
+		}
+		data := make([]byte, size+2)
+		if _, err := io.ReadFull(reader, data); err != nil {
+			return nil, err
+		}
+		args[i] = string(data[:size])
+	}
+	return args, nil
+}
+
+func bulk(value string) string {
+	return fmt.Sprintf("$%d\r\n%s\r\n", len(value), value)
+}
+
+// run executes a command. BLPOP polls, so it is the only command run without the lock held.
+func (s *Server) run(args []string) string {
+	command := strings.ToUpper(args[0])
+	if command == "BLPOP" {
+		timeout, _ := strconv.ParseFloat(args[len(args)-1], 64)
+		for deadline := time.Now().Add(time.Duration(timeout * float64(time.Second))); ; {
+			s.mu.Lock()
+			reply, ok := s.popLocked(args[1 : len(args)-1])
+			s.mu.Unlock()
+			if ok {
+				return reply
+			}
+			if time.Now().After(deadline) {
+				return "*-1\r\n"
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for key, expires := range s.expires {
+		if time.Now().After(expires) {
+			s.deleteLocked(key)
+		}
+	}
+	switch command {
+	case "AUTH", "SELECT":
+		return "+OK\r\n"
+	case "PING":
+		return "+PONG\r\n"
+	case "GET":
+		if value, ok := s.strings[args[1]]; ok {
+			return bulk(value)
+		}
+		return "$-1\r\n"
+	case "SET":
+		key, value := args[1], args[2]
+		options := strings.ToUpper(strings.Join(args[3:], " "))
+		if _, exists := s.strings[key]; exists && strings.Contains(options, "NX") {
+			return "$-1\r\n"
+		}
+		s.strings[key] = value
+		delete(s.expires, key)
+		for i := 3; i+1 < len(args); i++ {
+			if strings.EqualFold(args[i], "PX") {
+				millis, _ := strconv.Atoi(args[i+1])
+				s.expires[key] = time.Now().Add(time.Duration(millis) * time.Millisecond)
+			}
+		}
+		return "+OK\r\n"
+	case "INCRBY":
+		current, _ := strconv.ParseInt(s.strings[args[1]], 10, 64)
+		increment, _ := strconv.ParseInt(args[2], 10, 64)
+		s.strings[args[1]] = strconv.FormatInt(current+increment, 10)
+		return ":" + s.strings[args[1]] + "\r\n"
+	case "DEL":
+		deleted := 0
+		for _, key := range args[1:] {
+			if s.deleteLocked(key) {
+				deleted++
+			}
+		}
+		return ":" + strconv.Itoa(deleted) + "\r\n"
+	case "PEXPIRE":
+		millis, _ := strconv.Atoi(args[2])
+		s.expires[args[1]] = time.Now().Add(time.Duration(millis) * time.Millisecond)
+		return ":1\r\n"
+	case "HSET":
+		hash := s.hashes[args[1]]
+		if hash == nil {
+			hash = make(map[string]string)
+			s.hashes[args[1]] = hash
+		}
+		added := 0
+		for i := 2; i+1 < len(args); i += 2 {
+			if _, ok := hash[args[i]]; !ok {
+				added++
+			}
+			hash[args[i]] = args[i+1]
+		}
+		return ":" + strconv.Itoa(added) + "\r\n"
+	case "HGETALL":
+		hash := s.hashes[args[1]]
+		reply := "*" + strconv.Itoa(2*len(hash)) + "\r\n"
+		for field, value := range hash {
+			reply += bulk(field) + bulk(value)
+		}
+		return reply
+	case "HINCRBY", "HINCRBYFLOAT":
+		hash := s.hashes[args[1]]
+		if hash == nil {
+			hash = make(map[string]string)
+			s.hashes[args[1]] = hash
+		}
+		current, _ := strconv.ParseFloat(hash[args[2]], 64)
+		increment, _ := strconv.ParseFloat(args[3], 64)
+		hash[args[2]] = strconv.FormatFloat(current+increment, 'f', -1, 64)
+		if command == "HINCRBY" {
+			return ":" + hash[args[2]] + "\r\n"
+		}
+		return bulk(hash[args[2]])
+	case "RPUSH":
+		s.lists[args[1]] = append(s.lists[args[1]], args[2:]...)
+		return ":" + strconv.Itoa(len(s.lists[args[1]])) + "\r\n"
+	case "LLEN":
+		return ":" + strconv.Itoa(len(s.lists[args[1]])) + "\r\n"
+	}
+	return "-ERR unknown command '" + args[0] + "'\r\n"
+}
+
+func (s *Server) popLocked(keys []string) (string, bool) {
+	for _, key := range keys {
+		if list := s.lists[key]; len(list) > 0 {
+			s.lists[key] = list[1:]
+			return "*2\r\n" + bulk(key) + bulk(list[0]), true
+		}
+	}
+	return "", false
+}
+
+func (s *Server) deleteLocked(key string) bool {
+	_, isString := s.strings[key]
+	_, isHash := s.hashes[key]
+	_, isList := s.lists[key]
+	delete(s.strings, key)
+	delete(s.hashes, key)
+	delete(s.lists, key)
+	delete(s.expires, key)
+	return isString || isHash || isList
+}
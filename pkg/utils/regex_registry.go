@@ -0,0 +1,53 @@
+package utils
+
+import (
+	"regexp"
+	"strconv"
+	"sync"
+)
+
+// maxRegexRegistryEntries bounds the registry; patterns built from remote input (robots.txt
+// rules) would otherwise grow it without limit. Past it patterns are compiled but not kept.
+const maxRegexRegistryEntries = 10000
+
+// regexRegistry holds every pattern compiled through CompileRegexp, keyed by its source.
+var regexRegistry struct {
+	sync.RWMutex
+	patterns map[string]*regexp.Regexp
+}
+
+// CompileRegexp is regexp.Compile, except that each pattern is compiled once and shared: rules
+// configured or built at run time, such as outbound policy regexes and robots.txt paths,
+// cost a map lookup after their first use. Patterns known when the code is written belong in
+// package-level variables instead.
+func CompileRegexp(pattern string) (*regexp.Regexp, error) {
+	regexRegistry.RLock()
+	re, ok := regexRegistry.patterns[pattern]
+	regexRegistry.RUnlock()
+	if ok {
+		return re, nil
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, err
+	}
+	regexRegistry.Lock()
+	defer regexRegistry.Unlock()
+	if regexRegistry.patterns == nil {
+		regexRegistry.patterns = make(map[string]*regexp.Regexp)
+	}
+	if len(regexRegistry.patterns) < maxRegexRegistryEntries {
+		regexRegistry.patterns[pattern] = re
+	}
+	return re, nil
+}
+
+// MustCompileRegexp is CompileRegexp for patterns that cannot fail to compile, such as ones
+// built with regexp.QuoteMeta. It panics on an invalid pattern.
+func MustCompileRegexp(pattern string) *regexp.Regexp {
+	re, err := CompileRegexp(pattern)
+	if err != nil {
+		panic("regexp: Compile(" + strconv.Quote(pattern) + "): " + err.Error())
+	}
+	return re
+}
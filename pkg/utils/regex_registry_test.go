@@ -0,0 +1,27 @@
+package utils
+
+import "testing"
+
+func TestCompileRegexp(t *testing.T) {
+	first, err := CompileRegexp(`^/private/.*\.pdf$`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	second := MustCompileRegexp(`^/private/.*\.pdf$`)
+	if first != second {
+		t.Error("the pattern was compiled twice")
+	}
+	if !second.MatchString("/private/report.pdf") || second.MatchString("/public/report.pdf") {
+		t.Error("the shared pattern does not match as compiled")
+	}
+
+	if _, err := CompileRegexp(`(unclosed`); err == nil {
+		t.Error("CompileRegexp(invalid) error = nil")
+	}
+	defer func() {
+		if recover() == nil {
+			t.Error("MustCompileRegexp(invalid) did not panic")
+		}
+	}()
+	MustCompileRegexp(`(unclosed`)
+}
@@ -0,0 +1,121 @@
+// Package requestlog writes one structured (JSON) log line per API request with its target,
+// outcome class and the time its outbound calls took, so operators can tell which kind of
+// upstream is degrading.
+package requestlog
+
+import (
+	"context"
+	"log"
+	"log/slog"
+	"net/url"
+	"os"
+	"strings"
+	"sync/atomic"
+
+	"github.com/vit0-9/utils_api/pkg/utils"
+	"github.com/vit0-9/utils_api/pkg/utils/redact"
+)
+
+// Outcome classes of a request.
+const (
+	OutcomeSuccess         = "success"
+	OutcomeClientError     = "client-error"     // Refused with a 4xx status
+	OutcomeServerError     = "server-error"     // Failed with a 5xx status without an upstream failure
+	OutcomeUpstreamTimeout = "upstream-timeout" // An outbound dial, query or response timed out
+	OutcomeUpstreamRefused = "upstream-refused" // A target refused an outbound connection
+)
+
+// targetParams are the query parameters naming what a request looks up, in order of
+// preference.
+var targetParams = []string{"url", "domain", "host", "hostname", "ip", "target", "address", "name"}
+
+var (
+	enabled atomic.Bool
+	logger  = slog.New(slog.NewJSONHandler(os.Stderr, nil))
+)
+
+// Configure switches the request log on or off.
+func Configure(on bool) {
+	enabled.Store(on)
+	if on {
+		log.Println("Structured request log enabled")
+	}
+}
+
+// Enabled reports whether requests are logged.
+func Enabled() bool {
+	return enabled.Load()
+}
+
+// Classify returns the outcome class of a request that ended with status and whose outbound
+// calls were recorded by recorder. Client errors take precedence, then upstream timeouts
+// and refusals, even when the handler still answered 200 with an error in the body.
+func Classify(status int, recorder *utils.TimingRecorder) string {
+	switch {
+	case status >= 400 && status < 500:
+		return OutcomeClientError
+	case recorder.Failed(utils.UpstreamTimeout):
+		return OutcomeUpstreamTimeout
+	case recorder.Failed(utils.UpstreamRefused):
+		return OutcomeUpstreamRefused
+	case status >= 500:
+		return OutcomeServerError
+	}
+	return OutcomeSuccess
+}
+
+// Target returns the domain, IP or URL a request to path looks up, from its query, or ""
+// when there is none or the endpoint is redacted.
+func Target(path string, query url.Values) string {
+	if redact.Endpoint(path) {
+		return ""
+	}
+	for _, param := range targetParams {
+		if value := strings.TrimSpace(query.Get(param)); value != "" {
+			return redact.Text(value)
+		}
+	}
+	return ""
+}
+
+// Entry is one logged request.
+type Entry struct {
+	Method  string
+	Path    string
+	Target  string
+	Status  int
+	Outcome string
+	Timing  utils.Timing
+}
+
+// Log writes the entry when the request log is enabled.
+func Log(entry Entry) {
+	if !Enabled() {
+		return
+	}
+	attrs := []any{
+		slog.String("method", entry.Method),
+		slog.String("path", entry.Path),
+		slog.Int("status", entry.Status),
+		slog.String("outcome", entry.Outcome),
+		slog.Float64("duration_ms", entry.Timing.TotalMs),
+		slog.Group("upstream",
+			slog.Float64("dns_ms", entry.Timing.DNSMs),
+			slog.Float64("connect_ms", entry.Timing.ConnectMs),
+			slog.Float64("tls_ms", entry.Timing.TLSMs),
+			slog.Int("retries", entry.Timing.Retries),
+			slog.Bool("cache_hit", entry.Timing.CacheHit),
+		),
+	}
+	if entry.Target != "" {
+		attrs = append(attrs, slog.String("target", entry.Target))
+	}
+	level := slog.LevelInfo
+	switch entry.Outcome {
+	case OutcomeUpstreamTimeout, OutcomeUpstreamRefused:
+		level = slog.LevelWarn
+	case OutcomeServerError:
+		level = slog.LevelError
+	}
+	logger.Log(context.Background(), level, "request", attrs...)
+}
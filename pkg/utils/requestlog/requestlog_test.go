@@ -0,0 +1,82 @@
+package requestlog
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/url"
+	"testing"
+
+	"github.com/vit0-9/utils_api/pkg/utils"
+	"github.com/vit0-9/utils_api/pkg/utils/redact"
+)
+
+func TestClassify(t *testing.T) {
+	_, timedOut := utils.WithTimingRecorder(context.Background())
+	timedOut.AddFailure(context.DeadlineExceeded)
+	_, clean := utils.WithTimingRecorder(context.Background())
+
+	for _, tc := range []struct {
+		status   int
+		recorder *utils.TimingRecorder
+		want     string
+	}{
+		{200, clean, OutcomeSuccess},
+		{200, nil, OutcomeSuccess},
+		{400, timedOut, OutcomeClientError},
+		{200, timedOut, OutcomeUpstreamTimeout}, // Lookup errors are reported in a 200 body
+		{502, timedOut, OutcomeUpstreamTimeout},
+		{500, clean, OutcomeServerError},
+	} {
+		if got := Classify(tc.status, tc.recorder); got != tc.want {
+			t.Errorf("Classify(%d) = %q, want %q", tc.status, got, tc.want)
+		}
+	}
+}
+
+func TestTarget(t *testing.T) {
+	t.Cleanup(func() { redact.Configure("", "", true) })
+	redact.Configure("net/whois-lookup", "", true)
+
+	if got := Target("/api/v1/net/ssl-check", url.Values{"port": {"443"}, "host": {"example.com"}}); got != "example.com" {
+		t.Errorf("Target() = %q, want the host", got)
+	}
+	if got := Target("/api/v1/net/whois-lookup", url.Values{"domain": {"example.com"}}); got != "" {
+		t.Errorf("Target() of a redacted endpoint = %q, want none", got)
+	}
+	if got := Target("/api/v1/me/usage", url.Values{}); got != "" {
+		t.Errorf("Target() without a target = %q", got)
+	}
+}
+
+func TestLog(t *testing.T) {
+	var buf bytes.Buffer
+	original := logger
+	t.Cleanup(func() { logger = original; Configure(false) })
+	logger = slog.New(slog.NewJSONHandler(&buf, nil))
+
+	entry := Entry{Method: "GET", Path: "/api/v1/net/ssl-check", Target: "example.com", Status: 200, Outcome: OutcomeUpstreamRefused, Timing: utils.Timing{TotalMs: 12.5, ConnectMs: 3}}
+	Log(entry)
+	if buf.Len() != 0 {
+		t.Fatal("Log() wrote while disabled")
+	}
+
+	Configure(true)
+	Log(entry)
+	var line struct {
+		Level    string  `json:"level"`
+		Outcome  string  `json:"outcome"`
+		Target   string  `json:"target"`
+		Duration float64 `json:"duration_ms"`
+		Upstream struct {
+			ConnectMs float64 `json:"connect_ms"`
+		} `json:"upstream"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &line); err != nil {
+		t.Fatalf("log line %q is not JSON: %v", buf.String(), err)
+	}
+	if line.Level != "WARN" || line.Outcome != OutcomeUpstreamRefused || line.Target != "example.com" || line.Duration != 12.5 || line.Upstream.ConnectMs != 3 {
+		t.Errorf("log line = %s", buf.String())
+	}
+}
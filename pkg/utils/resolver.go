@@ -0,0 +1,153 @@
+package utils
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+// Resolver answers DNS queries. DNSResolver implements it over the system resolver, plain DNS
+// and DNS over HTTPS; FakeResolver answers from memory, so DNS-dependent code can be tested
+// without a network.
+type Resolver interface {
+	// Name identifies the resolver in results and errors.
+	Name() string
+	// Query returns the answer section for a question. NXDOMAIN is returned as an error
+	// wrapping ErrDNSNameNotFound.
+	Query(ctx context.Context, name string, qtype dnsmessage.Type) ([]dnsmessage.Resource, error)
+	// QueryDNSSEC is Query with the RRSIG records of the answer.
+	QueryDNSSEC(ctx context.Context, name string, qtype dnsmessage.Type) ([]dnsmessage.Resource, error)
+}
+
+var defaultResolver struct {
+	sync.RWMutex
+	resolver Resolver // nil for the system resolver
+}
+
+// SetDefaultResolver replaces the resolver used by lookups made without one, e.g. with a
+// FakeResolver in tests, and returns the previous one. nil restores the system resolver.
+func SetDefaultResolver(resolver Resolver) Resolver {
+	defaultResolver.Lock()
+	defer defaultResolver.Unlock()
+	previous := defaultResolver.resolver
+	defaultResolver.resolver = resolver
+	return previous
+}
+
+// DefaultResolver returns the resolver used when the caller names none: the system resolver
+// unless SetDefaultResolver replaced it.
+func DefaultResolver() Resolver {
+	defaultResolver.RLock()
+	resolver := defaultResolver.resolver
+	defaultResolver.RUnlock()
+	if resolver != nil {
+		return resolver
+	}
+	system, _ := NewDNSResolver("system") // Cannot fail
+	return system
+}
+
+// NewResolver is NewDNSResolver, except that an empty spec selects the default resolver.
+func NewResolver(spec string) (Resolver, error) {
+	if strings.TrimSpace(spec) == "" {
+		return DefaultResolver(), nil
+	}
+	return NewDNSResolver(spec)
+}
+
+// fakeKey is a question a FakeResolver answers.
+type fakeKey struct {
+	name  string
+	qtype dnsmessage.Type
+}
+
+// FakeResolver answers from records added to it. A name without records of the asked type
+// answers empty (NODATA) when it has records of another type, and NXDOMAIN otherwise.
+type FakeResolver struct {
+	mu      sync.Mutex
+	records map[fakeKey][]dnsmessage.Resource
+	errors  map[fakeKey]error
+	queries []string
+}
+
+// NewFakeResolver returns a FakeResolver without records.
+func NewFakeResolver() *FakeResolver {
+	return &FakeResolver{records: make(map[fakeKey][]dnsmessage.Resource), errors: make(map[fakeKey]error)}
+}
+
+func fakeQuestion(name string, qtype dnsmessage.Type) fakeKey {
+	return fakeKey{strings.TrimSuffix(strings.ToLower(name), "."), qtype}
+}
+
+// Add adds records of a type to a name, e.g.
+// Add("example.com", dnsmessage.TypeTXT, &dnsmessage.TXTResource{TXT: []string{"v=spf1 -all"}}).
+func (f *FakeResolver) Add(name string, qtype dnsmessage.Type, bodies ...dnsmessage.ResourceBody) *FakeResolver {
+	key := fakeQuestion(name, qtype)
+	header := dnsmessage.ResourceHeader{Name: dnsmessage.MustNewName(key.name + "."), Type: qtype, Class: dnsmessage.ClassINET, TTL: 300}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, body := range bodies {
+		f.records[key] = append(f.records[key], dnsmessage.Resource{Header: header, Body: body})
+	}
+	return f
+}
+
+// Fail makes queries for a name and type return err, e.g. to test a SERVFAIL or a timeout.
+func (f *FakeResolver) Fail(name string, qtype dnsmessage.Type, err error) *FakeResolver {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.errors[fakeQuestion(name, qtype)] = err
+	return f
+}
+
+// Queries returns the questions asked so far, as "name TYPE", in order.
+func (f *FakeResolver) Queries() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]string(nil), f.queries...)
+}
+
+// Name returns "fake".
+func (f *FakeResolver) Name() string {
+	return "fake"
+}
+
+// Query answers from the added records.
+func (f *FakeResolver) Query(ctx context.Context, name string, qtype dnsmessage.Type) ([]dnsmessage.Resource, error) {
+	key := fakeQuestion(name, qtype)
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.queries = append(f.queries, key.name+" "+strings.TrimPrefix(qtype.String(), "Type"))
+	if err := f.errors[key]; err != nil {
+		return nil, err
+	}
+	if answers, ok := f.records[key]; ok {
+		return append([]dnsmessage.Resource(nil), answers...), nil
+	}
+	for other := range f.records {
+		if other.name == key.name {
+			return nil, nil
+		}
+	}
+	return nil, fmt.Errorf("lookup %s via fake resolver: %w", key.name, ErrDNSNameNotFound)
+}
+
+// QueryDNSSEC answers like Query, with the RRSIG records added to the name that cover qtype.
+func (f *FakeResolver) QueryDNSSEC(ctx context.Context, name string, qtype dnsmessage.Type) ([]dnsmessage.Resource, error) {
+	answers, err := f.Query(ctx, name, qtype)
+	if err != nil {
+		return nil, err
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, signature := range f.records[fakeQuestion(name, dnsTypeRRSIG)] {
+		// The RRSIG RDATA starts with the type covered
+		if rrsig, ok := signature.Body.(*dnsmessage.UnknownResource); ok && len(rrsig.Data) >= 2 && dnsmessage.Type(uint16(rrsig.Data[0])<<8|uint16(rrsig.Data[1])) == qtype {
+			answers = append(answers, signature)
+		}
+	}
+	return answers, nil
+}
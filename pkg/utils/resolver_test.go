@@ -0,0 +1,48 @@
+package utils
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+func TestFakeResolver(t *testing.T) {
+	fake := NewFakeResolver().
+		Add("Example.com.", dnsmessage.TypeA, &dnsmessage.AResource{A: [4]byte{192, 0, 2, 1}}).
+		Add("example.com", dnsmessage.TypeMX, &dnsmessage.MXResource{Pref: 10, MX: dnsmessage.MustNewName("mail.example.com.")}).
+		Fail("broken.example", dnsmessage.TypeA, errors.New("server returned ServerFailure"))
+	previous := SetDefaultResolver(fake)
+	defer SetDefaultResolver(previous)
+
+	records, lookupErrors := LookupDNSRecords(context.Background(), "example.com", []string{"A", "MX", "TXT"})
+	if len(lookupErrors) != 0 {
+		t.Fatalf("lookup errors = %v", lookupErrors)
+	}
+	if len(records["A"]) != 1 || records["A"][0].Value != "192.0.2.1" || len(records["MX"]) != 1 || records["MX"][0].Value != "mail.example.com." {
+		t.Errorf("records = %+v", records)
+	}
+	if len(records["TXT"]) != 0 {
+		t.Errorf("TXT records = %+v, want none (NODATA)", records["TXT"])
+	}
+
+	if _, err := fake.Query(context.Background(), "missing.example", dnsmessage.TypeA); !errors.Is(err, ErrDNSNameNotFound) {
+		t.Errorf("missing name error = %v, want NXDOMAIN", err)
+	}
+	if _, err := fake.Query(context.Background(), "broken.example", dnsmessage.TypeA); err == nil || err.Error() != "server returned ServerFailure" {
+		t.Errorf("failing name error = %v", err)
+	}
+	if want := []string{"example.com A", "example.com MX", "example.com TXT", "missing.example A", "broken.example A"}; !reflect.DeepEqual(fake.Queries(), want) {
+		t.Errorf("queries = %q, want %q", fake.Queries(), want)
+	}
+
+	if resolver, err := NewResolver(""); err != nil || resolver != Resolver(fake) {
+		t.Errorf("NewResolver(\"\") = %v, %v; want the default resolver", resolver, err)
+	}
+	SetDefaultResolver(nil)
+	if resolver := DefaultResolver(); resolver.Name() != "system" {
+		t.Errorf("DefaultResolver() after reset = %s, want system", resolver.Name())
+	}
+}
@@ -0,0 +1,95 @@
+package utils
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+// ReverseIPDomain is a domain found pointing at an IP, with the sources that reported it.
+type ReverseIPDomain struct {
+	Domain  string   `json:"domain"`
+	Sources []string `json:"sources"` // "ptr" and/or the passive DNS provider name
+}
+
+// ReverseIPResult lists the domains known to resolve to an IP.
+type ReverseIPResult struct {
+	IP         string            `json:"ip"`
+	PTR        []string          `json:"ptr,omitempty"`
+	Provider   string            `json:"passive_dns_provider,omitempty"` // Empty when none is configured
+	Domains    []ReverseIPDomain `json:"domains"`
+	Count      int               `json:"count"`
+	Errors     map[string]string `json:"errors,omitempty"` // Keyed by source
+	SharedHost bool              `json:"shared_hosting"`   // More than one domain found
+}
+
+// LookupReverseIP combines the IP's PTR records with the configured passive DNS provider.
+// A failing source is reported in Errors while the other sources' results are kept.
+func LookupReverseIP(ctx context.Context, ipStr string) (*ReverseIPResult, error) {
+	ip := net.ParseIP(strings.TrimSpace(ipStr))
+	if ip == nil {
+		return nil, fmt.Errorf("invalid IP address: %s", ipStr)
+	}
+	if err := CheckOutboundAddress(ip.String(), []net.IP{ip}); err != nil {
+		return nil, err
+	}
+
+	result := &ReverseIPResult{IP: ip.String(), Errors: make(map[string]string)}
+	sources := make(map[string][]string)
+	addDomain := func(domain, source string) {
+		domain = strings.ToLower(strings.TrimSuffix(strings.TrimSpace(domain), "."))
+		if domain == "" {
+			return
+		}
+		for _, existing := range sources[domain] {
+			if existing == source {
+				return
+			}
+		}
+		sources[domain] = append(sources[domain], source)
+	}
+
+	reverseName, err := reverseDNSName(result.IP)
+	if err == nil {
+		var answers []dnsmessage.Resource
+		answers, err = DefaultResolver().Query(ctx, reverseName, dnsmessage.TypePTR)
+		for _, answer := range answers {
+			if ptr, ok := answer.Body.(*dnsmessage.PTRResource); ok {
+				name := strings.TrimSuffix(ptr.PTR.String(), ".")
+				result.PTR = append(result.PTR, name)
+				addDomain(name, "ptr")
+			}
+		}
+	}
+	if err != nil && !errors.Is(err, ErrDNSNameNotFound) {
+		result.Errors["ptr"] = err.Error()
+	}
+
+	if passiveDNSProvider != nil {
+		result.Provider = passiveDNSProvider.Name()
+		domains, err := passiveDNSProvider.DomainsForIP(ctx, result.IP)
+		if err != nil {
+			result.Errors[result.Provider] = err.Error()
+		}
+		for _, domain := range domains {
+			addDomain(domain, result.Provider)
+		}
+	}
+
+	result.Domains = make([]ReverseIPDomain, 0, len(sources))
+	for domain, domainSources := range sources {
+		result.Domains = append(result.Domains, ReverseIPDomain{Domain: domain, Sources: domainSources})
+	}
+	sort.Slice(result.Domains, func(i, j int) bool { return result.Domains[i].Domain < result.Domains[j].Domain })
+	result.Count = len(result.Domains)
+	result.SharedHost = result.Count > 1
+	if len(result.Errors) == 0 {
+		result.Errors = nil
+	}
+	return result, nil
+}
@@ -0,0 +1,23 @@
+package utils
+
+import (
+	"log"
+
+	"github.com/vit0-9/utils_api/pkg/utils/redis"
+)
+
+// sharedRedis holds the state replicas behind a load balancer must agree on, such as the
+// outbound rate limits. Nil keeps that state in each process.
+var sharedRedis *redis.Client
+
+// ConfigureSharedState keeps shared state in the Redis at addr and returns its client, or
+// returns nil and keeps it in process when addr is empty.
+func ConfigureSharedState(addr, password string, db int) *redis.Client {
+	if addr == "" {
+		sharedRedis = nil
+		return nil
+	}
+	sharedRedis = redis.New(addr, password, db)
+	log.Printf("Shared state kept in Redis at %s", addr)
+	return sharedRedis
+}
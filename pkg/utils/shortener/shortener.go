@@ -0,0 +1,277 @@
+// Package shortener maps short slugs to target URLs, with optional expiry and click limits,
+// and counts the clicks on each link. Links are kept in a storage backend, or in Redis so
+// every replica redirects and counts the same links.
+package shortener
+
+import (
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/vit0-9/utils_api/pkg/utils/redis"
+	"github.com/vit0-9/utils_api/pkg/utils/storage"
+)
+
+// Link limits.
+const (
+	MaxURLLength     = 2048
+	MaxExpiry        = 365 * 24 * time.Hour
+	generatedSlugLen = 7
+	slugAttempts     = 5
+)
+
+// slugRegex accepts custom slugs; generated slugs use slugAlphabet.
+var slugRegex = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_-]{2,63}$`)
+
+const slugAlphabet = "abcdefghijkmnopqrstuvwxyzABCDEFGHJKLMNPQRSTUVWXYZ23456789"
+
+var (
+	// ErrNotFound is returned for slugs that do not name a link.
+	ErrNotFound = errors.New("short link not found")
+	// ErrSlugTaken is returned when a custom slug is already in use.
+	ErrSlugTaken = errors.New("slug is already in use")
+	// ErrExpired is returned when following a link past its expiry.
+	ErrExpired = errors.New("short link has expired")
+	// ErrClickLimit is returned when following a link that reached its maximum clicks.
+	ErrClickLimit = errors.New("short link has reached its click limit")
+)
+
+// Link is a short link with its click count.
+type Link struct {
+	Slug        string     `json:"slug" example:"docs"`
+	URL         string     `json:"url" example:"https://example.com/documentation"`
+	CreatedAt   time.Time  `json:"created_at"`
+	ExpiresAt   *time.Time `json:"expires_at,omitempty"`
+	MaxClicks   int64      `json:"max_clicks,omitempty"` // 0 for no limit
+	Clicks      int64      `json:"clicks"`
+	LastClickAt *time.Time `json:"last_click_at,omitempty"`
+}
+
+// expired reports whether the link can no longer be followed at now.
+func (l *Link) expired(now time.Time) bool {
+	return l.ExpiresAt != nil && !now.Before(*l.ExpiresAt)
+}
+
+// Options are the optional settings of a new link.
+type Options struct {
+	Slug      string        // Generated when empty
+	ExpiresIn time.Duration // 0 for no expiry
+	MaxClicks int64         // 0 for no limit
+}
+
+// linkStore keeps the links and their clicks.
+type linkStore interface {
+	// create stores a new link, failing with ErrSlugTaken when its slug is in use.
+	create(link *Link) error
+	// get returns a link with its clicks, or ErrNotFound.
+	get(slug string) (*Link, error)
+	// click counts a click unless the link expired or reached its limit, and returns the
+	// link with the click counted.
+	click(slug string, now time.Time) (*Link, error)
+}
+
+var (
+	storeMu     sync.RWMutex
+	activeStore linkStore = newDocumentStore(storage.NewMemory())
+)
+
+func currentStore() linkStore {
+	storeMu.RLock()
+	defer storeMu.RUnlock()
+	return activeStore
+}
+
+// Configure keeps the links in backend, loading those it already holds.
+func Configure(backend storage.Store) {
+	store := newDocumentStore(backend)
+	if err := store.load(); err != nil {
+		log.Printf("ERROR: Could not load short links: %v. Short links will be kept in memory.", err)
+		return
+	}
+	storeMu.Lock()
+	activeStore = store
+	storeMu.Unlock()
+}
+
+// ConfigureSharedStore keeps the links and their clicks in Redis, so every replica behind a
+// load balancer redirects and counts the same links.
+func ConfigureSharedStore(client *redis.Client) {
+	if client == nil {
+		return
+	}
+	storeMu.Lock()
+	activeStore = &redisStore{client: client}
+	storeMu.Unlock()
+	log.Printf("Short links shared through Redis")
+}
+
+// Shorten creates a short link to an http(s) target URL.
+func Shorten(target string, opts Options) (*Link, error) {
+	target, err := normalizeTarget(target)
+	if err != nil {
+		return nil, err
+	}
+	if opts.ExpiresIn < 0 || opts.ExpiresIn > MaxExpiry {
+		return nil, fmt.Errorf("expiry must be between 0 and %d days", int(MaxExpiry.Hours()/24))
+	}
+	if opts.MaxClicks < 0 {
+		return nil, errors.New("max_clicks must not be negative")
+	}
+	if opts.Slug != "" && !slugRegex.MatchString(opts.Slug) {
+		return nil, errors.New("slug must be 3 to 64 letters, digits, '-' or '_', starting with a letter or digit")
+	}
+
+	now := time.Now().UTC()
+	link := &Link{URL: target, CreatedAt: now, MaxClicks: opts.MaxClicks}
+	if opts.ExpiresIn > 0 {
+		expires := now.Add(opts.ExpiresIn)
+		link.ExpiresAt = &expires
+	}
+	store := currentStore()
+	if opts.Slug != "" {
+		link.Slug = opts.Slug
+		return link, store.create(link)
+	}
+	for range slugAttempts {
+		link.Slug = generateSlug()
+		if err = store.create(link); !errors.Is(err, ErrSlugTaken) {
+			return link, err
+		}
+	}
+	return nil, errors.New("could not generate a free slug, try again")
+}
+
+// Follow counts a click on a link and returns it, failing with ErrExpired or ErrClickLimit
+// when it can no longer be followed.
+func Follow(slug string) (*Link, error) {
+	return currentStore().click(slug, time.Now().UTC())
+}
+
+// Stats returns a link with its clicks, without counting one.
+func Stats(slug string) (*Link, error) {
+	return currentStore().get(slug)
+}
+
+// normalizeTarget checks that a target is an absolute http(s) URL browsers can follow.
+func normalizeTarget(target string) (string, error) {
+	target = strings.TrimSpace(target)
+	if target == "" {
+		return "", errors.New("url is required")
+	}
+	if len(target) > MaxURLLength {
+		return "", fmt.Errorf("url is longer than %d characters", MaxURLLength)
+	}
+	parsed, err := url.Parse(target)
+	if err != nil {
+		return "", fmt.Errorf("invalid url: %w", err)
+	}
+	if parsed.Scheme != "http" && parsed.Scheme != "https" {
+		return "", errors.New("url must use http or https")
+	}
+	if parsed.Host == "" {
+		return "", errors.New("url has no host")
+	}
+	return parsed.String(), nil
+}
+
+func generateSlug() string {
+	random := make([]byte, generatedSlugLen)
+	rand.Read(random)
+	for i, b := range random {
+		random[i] = slugAlphabet[int(b)%len(slugAlphabet)]
+	}
+	return string(random)
+}
+
+// linksCollection is the storage collection of the links.
+const linksCollection = "short_links"
+
+// documentStore keeps the links in memory, saved to a storage backend on every change.
+type documentStore struct {
+	mu      sync.Mutex
+	backend storage.Store
+	links   map[string]*Link
+}
+
+func newDocumentStore(backend storage.Store) *documentStore {
+	return &documentStore{backend: backend, links: make(map[string]*Link)}
+}
+
+func (s *documentStore) load() error {
+	documents, err := s.backend.Load(linksCollection)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for slug, data := range documents {
+		var link Link
+		if err := json.Unmarshal(data, &link); err != nil {
+			log.Printf("ERROR: Could not parse short link %s: %v", slug, err)
+			continue
+		}
+		s.links[link.Slug] = &link
+	}
+	return nil
+}
+
+func (s *documentStore) create(link *Link) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.links[link.Slug]; ok {
+		return ErrSlugTaken
+	}
+	stored := *link
+	if err := s.saveLocked(&stored); err != nil {
+		return err
+	}
+	s.links[link.Slug] = &stored
+	return nil
+}
+
+func (s *documentStore) get(slug string) (*Link, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	link, ok := s.links[slug]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	copied := *link
+	return &copied, nil
+}
+
+func (s *documentStore) click(slug string, now time.Time) (*Link, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	link, ok := s.links[slug]
+	switch {
+	case !ok:
+		return nil, ErrNotFound
+	case link.expired(now):
+		return nil, ErrExpired
+	case link.MaxClicks > 0 && link.Clicks >= link.MaxClicks:
+		return nil, ErrClickLimit
+	}
+	link.Clicks++
+	link.LastClickAt = &now
+	if err := s.saveLocked(link); err != nil {
+		log.Printf("ERROR: Could not save the clicks of short link %s: %v", slug, err)
+	}
+	copied := *link
+	return &copied, nil
+}
+
+func (s *documentStore) saveLocked(link *Link) error {
+	data, err := json.Marshal(link)
+	if err != nil {
+		return err
+	}
+	return s.backend.Put(linksCollection, link.Slug, data)
+}
@@ -0,0 +1,119 @@
+package shortener
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/vit0-9/utils_api/pkg/utils/redis"
+	"github.com/vit0-9/utils_api/pkg/utils/redis/redistest"
+	"github.com/vit0-9/utils_api/pkg/utils/storage"
+)
+
+// useStore makes store the active store for the duration of a test.
+func useStore(t *testing.T, store linkStore) {
+	t.Helper()
+	storeMu.Lock()
+	previous := activeStore
+	activeStore = store
+	storeMu.Unlock()
+	t.Cleanup(func() {
+		storeMu.Lock()
+		activeStore = previous
+		storeMu.Unlock()
+	})
+}
+
+func TestShortenValidation(t *testing.T) {
+	useStore(t, newDocumentStore(storage.NewMemory()))
+	for name, test := range map[string]struct {
+		url  string
+		opts Options
+	}{
+		"empty url":       {url: ""},
+		"relative url":    {url: "/docs"},
+		"javascript url":  {url: "javascript:alert(1)"},
+		"no host":         {url: "https:///docs"},
+		"short slug":      {url: "https://example.com", opts: Options{Slug: "ab"}},
+		"slug with slash": {url: "https://example.com", opts: Options{Slug: "a/b/c"}},
+		"negative clicks": {url: "https://example.com", opts: Options{MaxClicks: -1}},
+		"expiry too long": {url: "https://example.com", opts: Options{ExpiresIn: MaxExpiry + time.Hour}},
+	} {
+		if link, err := Shorten(test.url, test.opts); err == nil {
+			t.Errorf("%s: Shorten() = %+v, want an error", name, link)
+		}
+	}
+}
+
+// testStore runs the same scenario against a store implementation.
+func testStore(t *testing.T, store linkStore) {
+	useStore(t, store)
+
+	link, err := Shorten("https://example.com/docs?page=1", Options{})
+	if err != nil {
+		t.Fatalf("Shorten() error = %v", err)
+	}
+	if len(link.Slug) != generatedSlugLen || link.URL != "https://example.com/docs?page=1" {
+		t.Errorf("Shorten() = %+v", link)
+	}
+
+	limited, err := Shorten("https://example.com/once", Options{Slug: "once", MaxClicks: 2})
+	if err != nil {
+		t.Fatalf("Shorten() with a custom slug error = %v", err)
+	}
+	if _, err := Shorten("https://example.com/other", Options{Slug: "once"}); !errors.Is(err, ErrSlugTaken) {
+		t.Errorf("Shorten() with a taken slug error = %v, want ErrSlugTaken", err)
+	}
+	for want := int64(1); want <= 2; want++ {
+		followed, err := Follow(limited.Slug)
+		if err != nil || followed.Clicks != want || followed.URL != "https://example.com/once" || followed.LastClickAt == nil {
+			t.Fatalf("Follow() = %+v, %v; want click %d", followed, err, want)
+		}
+	}
+	if _, err := Follow(limited.Slug); !errors.Is(err, ErrClickLimit) {
+		t.Errorf("Follow() past max_clicks error = %v, want ErrClickLimit", err)
+	}
+	if stats, err := Stats(limited.Slug); err != nil || stats.Clicks != 2 || stats.MaxClicks != 2 {
+		t.Errorf("Stats() = %+v, %v; want 2 clicks", stats, err)
+	}
+
+	expiring, err := Shorten("https://example.com/soon", Options{ExpiresIn: time.Hour})
+	if err != nil || expiring.ExpiresAt == nil {
+		t.Fatalf("Shorten() with an expiry = %+v, %v", expiring, err)
+	}
+	if _, err := store.click(expiring.Slug, expiring.ExpiresAt.Add(time.Second)); !errors.Is(err, ErrExpired) {
+		t.Errorf("click() after the expiry error = %v, want ErrExpired", err)
+	}
+
+	if _, err := Follow("missing"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Follow() of an unknown slug error = %v, want ErrNotFound", err)
+	}
+	if _, err := Stats("missing"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Stats() of an unknown slug error = %v, want ErrNotFound", err)
+	}
+}
+
+func TestDocumentStore(t *testing.T) {
+	backend := storage.NewMemory()
+	testStore(t, newDocumentStore(backend))
+
+	// Links and their clicks survive a restart.
+	reloaded := newDocumentStore(backend)
+	if err := reloaded.load(); err != nil {
+		t.Fatalf("load() error = %v", err)
+	}
+	if link, err := reloaded.get("once"); err != nil || link.Clicks != 2 {
+		t.Errorf("get() after reloading = %+v, %v; want 2 clicks", link, err)
+	}
+}
+
+func TestRedisStore(t *testing.T) {
+	server := redistest.NewServer(t)
+	testStore(t, &redisStore{client: redis.New(server.Addr, "", 0)})
+
+	// Another replica sees the links and their clicks.
+	other := &redisStore{client: redis.New(server.Addr, "", 0)}
+	if link, err := other.get("once"); err != nil || link.Clicks != 2 || link.LastClickAt == nil {
+		t.Errorf("get() from another replica = %+v, %v; want 2 clicks", link, err)
+	}
+}
@@ -0,0 +1,109 @@
+package shortener
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/vit0-9/utils_api/pkg/utils/redis"
+)
+
+// Redis keys: the link as JSON, its click counter and the time of its last click, which all
+// expire with the link.
+const (
+	redisLinkPrefix      = "utils_api:short:link:"
+	redisClicksPrefix    = "utils_api:short:clicks:"
+	redisLastClickPrefix = "utils_api:short:last_click:"
+)
+
+// redisStore keeps the links in Redis, counting clicks with INCRBY so replicas never lose
+// or overshoot a click.
+type redisStore struct {
+	client *redis.Client
+}
+
+func (s *redisStore) create(link *Link) error {
+	data, err := json.Marshal(link)
+	if err != nil {
+		return err
+	}
+	args := []string{"SET", redisLinkPrefix + link.Slug, string(data), "NX"}
+	if link.ExpiresAt != nil {
+		args = append(args, "PX", strconv.FormatInt(max(time.Until(*link.ExpiresAt).Milliseconds(), 1), 10))
+	}
+	reply, err := s.client.Do(context.Background(), args...)
+	if err != nil {
+		return err
+	}
+	if reply == nil { // NX refused: the slug is in use
+		return ErrSlugTaken
+	}
+	return nil
+}
+
+func (s *redisStore) get(slug string) (*Link, error) {
+	ctx := context.Background()
+	reply, err := s.client.Do(ctx, "GET", redisLinkPrefix+slug)
+	if err != nil {
+		return nil, err
+	}
+	data, ok := reply.([]byte)
+	if !ok {
+		return nil, ErrNotFound
+	}
+	var link Link
+	if err := json.Unmarshal(data, &link); err != nil {
+		return nil, fmt.Errorf("could not parse short link %s: %w", slug, err)
+	}
+	if reply, err = s.client.Do(ctx, "GET", redisClicksPrefix+slug); err != nil {
+		return nil, err
+	}
+	if count, ok := reply.([]byte); ok {
+		link.Clicks, _ = strconv.ParseInt(string(count), 10, 64)
+	}
+	if reply, err = s.client.Do(ctx, "GET", redisLastClickPrefix+slug); err != nil {
+		return nil, err
+	}
+	if value, ok := reply.([]byte); ok {
+		if last, err := time.Parse(time.RFC3339Nano, string(value)); err == nil {
+			link.LastClickAt = &last
+		}
+	}
+	return &link, nil
+}
+
+func (s *redisStore) click(slug string, now time.Time) (*Link, error) {
+	ctx := context.Background()
+	link, err := s.get(slug)
+	if err != nil {
+		return nil, err
+	}
+	if link.expired(now) {
+		return nil, ErrExpired
+	}
+	reply, err := s.client.Do(ctx, "INCRBY", redisClicksPrefix+slug, "1")
+	if err != nil {
+		return nil, err
+	}
+	clicks, ok := reply.(int64)
+	if !ok {
+		return nil, fmt.Errorf("unexpected reply %v", reply)
+	}
+	if link.MaxClicks > 0 && clicks > link.MaxClicks {
+		s.client.Do(ctx, "INCRBY", redisClicksPrefix+slug, "-1")
+		return nil, ErrClickLimit
+	}
+	lastClick := []string{"SET", redisLastClickPrefix + slug, now.Format(time.RFC3339Nano)}
+	if link.ExpiresAt != nil {
+		ttl := strconv.FormatInt(max(link.ExpiresAt.Sub(now).Milliseconds(), 1), 10)
+		s.client.Do(ctx, "PEXPIRE", redisClicksPrefix+slug, ttl)
+		lastClick = append(lastClick, "PX", ttl)
+	}
+	if _, err := s.client.Do(ctx, lastClick...); err != nil {
+		return nil, err
+	}
+	link.Clicks, link.LastClickAt = clicks, &now
+	return link, nil
+}
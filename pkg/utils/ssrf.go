@@ -0,0 +1,161 @@
+package utils
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net"
+	"net/url"
+	"strings"
+)
+
+// ssrfBlockedNetworks are the ranges beyond those the net.IP predicates cover that requests to
+// caller-supplied URLs must not reach.
+var ssrfBlockedNetworks = mustParseCIDRs(
+	"0.0.0.0/8",     // "This" network
+	"100.64.0.0/10", // Carrier-grade NAT, including Alibaba Cloud's metadata service at 100.100.100.200
+	"192.0.0.0/24",  // IETF protocol assignments
+	"198.18.0.0/15", // Benchmarking
+)
+
+var (
+	ssrfProtection bool
+	ssrfAllowed    []*net.IPNet // Internal ranges the operator allows anyway
+)
+
+// SSRFBlockedError is returned when a caller-supplied URL points at an internal address.
+type SSRFBlockedError struct {
+	Target  string
+	Address net.IP
+}
+
+func (e *SSRFBlockedError) Error() string {
+	target := e.Target + " resolves to " + e.Address.String() + ","
+	if net.ParseIP(e.Target).Equal(e.Address) {
+		target = e.Target + " is"
+	}
+	return fmt.Sprintf("%s a private, loopback, link-local or cloud metadata address; requests to internal addresses are blocked", target)
+}
+
+func mustParseCIDRs(cidrs ...string) []*net.IPNet {
+	networks := make([]*net.IPNet, len(cidrs))
+	for i, cidr := range cidrs {
+		_, network, err := net.ParseCIDR(cidr)
+		if err != nil {
+			panic(err)
+		}
+		networks[i] = network
+	}
+	return networks
+}
+
+// ConfigureSSRFProtection blocks page fetches, redirect resolution and other requests to
+// caller-supplied URLs from reaching private, loopback, link-local (which holds the cloud
+// metadata services) and other internal addresses, except those in the comma-separated
+// allowCIDRs.
+func ConfigureSSRFProtection(enabled bool, allowCIDRs string) {
+	ssrfProtection, ssrfAllowed = enabled, nil
+	if !enabled {
+		log.Println("WARN: SSRF protection is disabled; URL-fetching endpoints can reach internal addresses")
+		return
+	}
+	for _, cidr := range strings.Split(allowCIDRs, ",") {
+		if cidr = strings.TrimSpace(cidr); cidr == "" {
+			continue
+		}
+		if !strings.Contains(cidr, "/") { // A single address
+			if ip := net.ParseIP(cidr); ip != nil && ip.To4() != nil {
+				cidr += "/32"
+			} else {
+				cidr += "/128"
+			}
+		}
+		_, network, err := net.ParseCIDR(cidr)
+		if err != nil {
+			log.Printf("ERROR: Ignoring invalid SSRF allowlist entry %q: %v", cidr, err)
+			continue
+		}
+		ssrfAllowed = append(ssrfAllowed, network)
+	}
+	log.Printf("SSRF protection enabled (%d allowed internal ranges)", len(ssrfAllowed))
+}
+
+// ssrfBlocked reports whether requests to caller-supplied URLs must not reach ip.
+func ssrfBlocked(ip net.IP) bool {
+	for _, network := range ssrfAllowed {
+		if network.Contains(ip) {
+			return false
+		}
+	}
+	if ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() || ip.IsLinkLocalUnicast() ||
+		ip.IsLinkLocalMulticast() || ip.IsInterfaceLocalMulticast() {
+		return true
+	}
+	for _, network := range ssrfBlockedNetworks {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// checkSSRF returns an SSRFBlockedError when host resolved to an internal address.
+func checkSSRF(host string, ips []net.IP) error {
+	if !ssrfProtection {
+		return nil
+	}
+	for _, ip := range ips {
+		if ssrfBlocked(ip) {
+			log.Printf("AUDIT: outbound request blocked: target=%s address=%s reason=\"internal address\"", host, ip)
+			return &SSRFBlockedError{Target: normalizePolicyHost(host), Address: ip}
+		}
+	}
+	return nil
+}
+
+// CheckTargetURL returns an SSRFBlockedError when the host of a caller-supplied URL resolves
+// to an internal address, so the request can be refused before anything is fetched. Other
+// problems, such as names that do not resolve, are left for the fetch to report.
+func CheckTargetURL(ctx context.Context, rawURL string) error {
+	if !ssrfProtection || OfflineMode() {
+		return nil
+	}
+	parsed, err := url.Parse(strings.TrimSpace(rawURL))
+	if err != nil || parsed.Hostname() == "" {
+		return nil
+	}
+	ips, err := resolveTarget(ctx, parsed.Hostname())
+	if err != nil {
+		return nil
+	}
+	return checkSSRF(parsed.Hostname(), ips)
+}
+
+// resolveTarget returns the addresses host resolves to, or host itself when it is an IP.
+func resolveTarget(ctx context.Context, host string) ([]net.IP, error) {
+	if ip := net.ParseIP(strings.Trim(host, "[]")); ip != nil {
+		return []net.IP{ip}, nil
+	}
+	ipAddrs, err := net.DefaultResolver.LookupIPAddr(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+	ips := make([]net.IP, len(ipAddrs))
+	for i, ipAddr := range ipAddrs {
+		ips[i] = ipAddr.IP
+	}
+	return ips, nil
+}
+
+// TargetDialContext wraps a dialer for requests to caller-supplied URLs: like
+// PolicyDialContext, and refusing internal addresses when SSRF protection is on. Every
+// connection is checked, including those of redirect hops.
+func TargetDialContext(dialer *net.Dialer) func(ctx context.Context, network, addr string) (net.Conn, error) {
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		conn, err := policyDial(ctx, dialer, network, addr, true)
+		if err != nil {
+			TimingRecorderFrom(ctx).AddFailure(err)
+		}
+		return conn, err
+	}
+}
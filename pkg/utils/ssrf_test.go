@@ -0,0 +1,81 @@
+package utils
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestSSRFBlocked(t *testing.T) {
+	defer ConfigureSSRFProtection(false, "")
+	ConfigureSSRFProtection(true, "10.20.0.0/16, 192.168.1.5")
+	for address, blocked := range map[string]bool{
+		"127.0.0.1":        true,
+		"::1":              true,
+		"10.1.2.3":         true,
+		"172.16.0.1":       true,
+		"192.168.0.1":      true,
+		"169.254.169.254":  true,
+		"100.100.100.200":  true,
+		"0.0.0.0":          true,
+		"fd00:ec2::254":    true,
+		"fe80::1":          true,
+		"::ffff:127.0.0.1": true,
+		"10.20.30.40":      false, // Allowed range
+		"192.168.1.5":      false, // Allowed address
+		"93.184.215.14":    false,
+		"2606:4700::1111":  false,
+	} {
+		if got := ssrfBlocked(net.ParseIP(address)); got != blocked {
+			t.Errorf("ssrfBlocked(%s) = %v, want %v", address, got, blocked)
+		}
+	}
+}
+
+func TestCheckTargetURL(t *testing.T) {
+	defer ConfigureSSRFProtection(false, "")
+	ConfigureSSRFProtection(true, "")
+	var blocked *SSRFBlockedError
+	if err := CheckTargetURL(context.Background(), "http://169.254.169.254/latest/meta-data/"); !errors.As(err, &blocked) {
+		t.Errorf("metadata URL: err = %v", err)
+	}
+	if err := CheckTargetURL(context.Background(), "http://[::1]:8080/"); !errors.As(err, &blocked) {
+		t.Errorf("IPv6 loopback URL: err = %v", err)
+	}
+	if err := CheckTargetURL(context.Background(), "https://93.184.215.14/"); err != nil {
+		t.Errorf("public URL: err = %v", err)
+	}
+
+	ConfigureSSRFProtection(false, "")
+	if err := CheckTargetURL(context.Background(), "http://127.0.0.1/"); err != nil {
+		t.Errorf("protection off: err = %v", err)
+	}
+}
+
+func TestTargetTransportRefusesInternalAddresses(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+	defer ConfigureSSRFProtection(false, "")
+
+	ConfigureSSRFProtection(true, "")
+	var blocked *SSRFBlockedError
+	if _, err := (&http.Client{Transport: NewTargetTransport()}).Get(server.URL); !errors.As(err, &blocked) {
+		t.Errorf("target transport to loopback: err = %v", err)
+	}
+	// The API's own calls are not restricted
+	resp, err := (&http.Client{Transport: NewOutboundTransport()}).Get(server.URL)
+	if err != nil {
+		t.Fatalf("outbound transport to loopback: %v", err)
+	}
+	resp.Body.Close()
+
+	ConfigureSSRFProtection(true, "127.0.0.0/8")
+	resp, err = (&http.Client{Transport: NewTargetTransport()}).Get(server.URL)
+	if err != nil {
+		t.Fatalf("allowed loopback: %v", err)
+	}
+	resp.Body.Close()
+}
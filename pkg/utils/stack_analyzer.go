@@ -1,24 +1,29 @@
 package utils
 
 import (
-	"bytes"         // Added for bytes.NewReader
-	"compress/gzip" // Added for gzip decompression
-	"compress/zlib" // Added for deflate decompression
+	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
-	"io" // Added for io.ReadAll
+	"io"
 	"log"
+	"net/http"
 	"os"
 	"path/filepath"
 	"regexp"
 	"strings"
 	"sync"
+	"time"
 
 	wappalyze "github.com/projectdiscovery/wappalyzergo"
+
+	"github.com/vit0-9/utils_api/pkg/utils/lifecycle"
 )
 
-// Global Wappalyzer client
+// Global Wappalyzer client, swapped under wappalyzerMu when the fingerprints are updated
 var (
 	wappalyzerClient   *wappalyze.Wappalyze
+	wappalyzerMu       sync.RWMutex
 	wappalyzerInitOnce sync.Once
 	wappalyzerInitErr  error
 )
@@ -28,17 +33,221 @@ const htmlResponsesDir = "html_responses"
 
 func initializeWappalyzer() {
 	wappalyzerInitOnce.Do(func() {
-		var err error
-		wappalyzerClient, err = wappalyze.New()
+		client, err := wappalyze.New()
 		if err != nil {
 			wappalyzerInitErr = fmt.Errorf("failed to initialize wappalyzer client: %w", err)
 			log.Println(wappalyzerInitErr)
 			return
 		}
+		wappalyzerMu.Lock()
+		if wappalyzerClient == nil { // Fingerprints loaded from the update cache take precedence
+			wappalyzerClient = client
+			wappalyzerStatus.Technologies = len(client.GetFingerprints().Apps)
+		}
+		wappalyzerMu.Unlock()
 		log.Println("Wappalyzer client initialized successfully.")
 	})
 }
 
+// currentWappalyzer returns the Wappalyzer client in use.
+func currentWappalyzer() (*wappalyze.Wappalyze, error) {
+	initializeWappalyzer()
+	wappalyzerMu.RLock()
+	defer wappalyzerMu.RUnlock()
+	if wappalyzerClient != nil {
+		return wappalyzerClient, nil
+	}
+	if wappalyzerInitErr != nil {
+		return nil, wappalyzerInitErr
+	}
+	return nil, fmt.Errorf("wappalyzer client not available")
+}
+
+// CheckWappalyzer reports why stack analysis is unavailable, if it is.
+func CheckWappalyzer() error {
+	_, err := currentWappalyzer()
+	return err
+}
+
+const (
+	// DefaultWappalyzerUpdateInterval is how often updated fingerprints are downloaded.
+	DefaultWappalyzerUpdateInterval = 24 * time.Hour
+
+	// maxWappalyzerFingerprintsSize caps the size of a downloaded fingerprints file.
+	maxWappalyzerFingerprintsSize = 64 << 20
+)
+
+// ErrWappalyzerUpdatesDisabled is returned when no fingerprints URL is configured.
+var ErrWappalyzerUpdatesDisabled = errors.New("fingerprint updates are disabled: WAPPALYZER_FINGERPRINTS_URL is not set")
+
+// WappalyzerStatus describes the fingerprints in use and the last update attempt.
+type WappalyzerStatus struct {
+	Source       string     `json:"source"`       // embedded, or the URL or file the fingerprints were loaded from
+	Technologies int        `json:"technologies"` // Fingerprinted technologies
+	UpdatedAt    *time.Time `json:"updated_at,omitempty"`
+	UpdateURL    string     `json:"update_url,omitempty"`
+	LastCheck    *time.Time `json:"last_check,omitempty"`
+	LastError    string     `json:"last_error,omitempty"`
+}
+
+var (
+	wappalyzerStatus = WappalyzerStatus{Source: "embedded"} // Guarded by wappalyzerMu
+	wappalyzerUpdate struct {
+		sync.Mutex // Serializes refreshes
+		url        string
+		cachePath  string
+	}
+)
+
+// ConfigureWappalyzerUpdates enables downloading fingerprints from url (in wappalyzergo's
+// fingerprints_data.json format) every interval. Downloads are kept at cachePath, when set,
+// and loaded from there on startup so a restart does not fall back to the embedded ones.
+func ConfigureWappalyzerUpdates(url string, interval time.Duration, cachePath string) {
+	wappalyzerUpdate.Lock()
+	wappalyzerUpdate.url, wappalyzerUpdate.cachePath = url, cachePath
+	wappalyzerUpdate.Unlock()
+	wappalyzerMu.Lock()
+	wappalyzerStatus.UpdateURL = url
+	wappalyzerMu.Unlock()
+
+	if cachePath != "" {
+		if info, err := os.Stat(cachePath); err == nil {
+			if err := loadWappalyzerFingerprints(cachePath, cachePath, info.ModTime()); err != nil {
+				log.Printf("ERROR: Could not load cached Wappalyzer fingerprints from %s: %v. Using the embedded ones.", cachePath, err)
+			}
+		}
+	}
+	if url == "" {
+		return
+	}
+	if interval <= 0 {
+		interval = DefaultWappalyzerUpdateInterval
+	}
+	log.Printf("Wappalyzer fingerprint updates enabled from %s every %s", url, interval)
+	go func() {
+		for {
+			if _, err := RefreshWappalyzerFingerprints(lifecycle.Context()); err != nil && !lifecycle.Stopping() {
+				log.Printf("ERROR: Wappalyzer fingerprint update failed: %v", err)
+			}
+			if !lifecycle.Sleep(interval) {
+				return
+			}
+		}
+	}()
+}
+
+// RefreshWappalyzerFingerprints downloads the configured fingerprints, validates them and
+// swaps them in. On failure the fingerprints in use are kept.
+func RefreshWappalyzerFingerprints(ctx context.Context) (WappalyzerStatus, error) {
+	wappalyzerUpdate.Lock()
+	defer wappalyzerUpdate.Unlock()
+	if wappalyzerUpdate.url == "" {
+		return WappalyzerFingerprintStatus(), ErrWappalyzerUpdatesDisabled
+	}
+
+	err := downloadWappalyzerFingerprints(ctx, wappalyzerUpdate.url, wappalyzerUpdate.cachePath)
+	wappalyzerMu.Lock()
+	lastCheck := time.Now().UTC()
+	wappalyzerStatus.LastCheck = &lastCheck
+	wappalyzerStatus.LastError = ""
+	if err != nil {
+		wappalyzerStatus.LastError = err.Error()
+	}
+	status := wappalyzerStatus
+	wappalyzerMu.Unlock()
+	return status, err
+}
+
+// WappalyzerFingerprintStatus returns the state of the fingerprints in use.
+func WappalyzerFingerprintStatus() WappalyzerStatus {
+	initializeWappalyzer()
+	wappalyzerMu.RLock()
+	defer wappalyzerMu.RUnlock()
+	return wappalyzerStatus
+}
+
+// downloadWappalyzerFingerprints fetches and loads the fingerprints at url.
+func downloadWappalyzerFingerprints(ctx context.Context, url, cachePath string) error {
+	ctx, cancel := context.WithTimeout(ctx, 2*time.Minute)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return fmt.Errorf("invalid fingerprints URL: %w", err)
+	}
+	resp, err := (&http.Client{Transport: NewOutboundTransport()}).Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to download fingerprints: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("failed to download fingerprints: received status %s", resp.Status)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxWappalyzerFingerprintsSize+1))
+	if err != nil {
+		return fmt.Errorf("failed to download fingerprints: %w", err)
+	}
+	if len(data) > maxWappalyzerFingerprintsSize {
+		return fmt.Errorf("fingerprints file exceeds %d MB", maxWappalyzerFingerprintsSize>>20)
+	}
+	if err := validateWappalyzerFingerprints(data); err != nil {
+		return err
+	}
+
+	path := cachePath
+	if path == "" { // wappalyzergo only loads fingerprints from a file
+		tmp, err := os.CreateTemp("", "wappalyzer-fingerprints-*.json")
+		if err != nil {
+			return err
+		}
+		tmp.Close()
+		path = tmp.Name()
+		defer os.Remove(path)
+	}
+	if err := WriteFileAtomic(path, data); err != nil {
+		return fmt.Errorf("failed to store fingerprints: %w", err)
+	}
+	return loadWappalyzerFingerprints(path, url, time.Now().UTC())
+}
+
+// validateWappalyzerFingerprints rejects files that are not fingerprints or would drop a
+// large share of the technologies currently detected, such as a truncated download.
+func validateWappalyzerFingerprints(data []byte) error {
+	var parsed wappalyze.Fingerprints
+	if err := json.Unmarshal(data, &parsed); err != nil {
+		return fmt.Errorf("invalid fingerprints file: %w", err)
+	}
+	if len(parsed.Apps) == 0 {
+		return fmt.Errorf("invalid fingerprints file: no technologies under \"apps\"")
+	}
+	for name, fingerprint := range parsed.Apps {
+		if fingerprint == nil {
+			return fmt.Errorf("invalid fingerprints file: technology %q has no fingerprint", name)
+		}
+	}
+	if current := WappalyzerFingerprintStatus().Technologies; len(parsed.Apps) < current/2 {
+		return fmt.Errorf("fingerprints file has %d technologies, less than half of the %d in use", len(parsed.Apps), current)
+	}
+	return nil
+}
+
+// loadWappalyzerFingerprints builds a client from the fingerprints file at path, merged over
+// the embedded ones, and swaps it in.
+func loadWappalyzerFingerprints(path, source string, updatedAt time.Time) error {
+	client, err := wappalyze.NewFromFile(path, true, true)
+	if err != nil {
+		return fmt.Errorf("failed to load fingerprints: %w", err)
+	}
+	technologies := len(client.GetFingerprints().Apps)
+	wappalyzerMu.Lock()
+	wappalyzerClient = client
+	wappalyzerStatus.Source = source
+	wappalyzerStatus.Technologies = technologies
+	wappalyzerStatus.UpdatedAt = &updatedAt
+	wappalyzerMu.Unlock()
+	log.Printf("Loaded %d Wappalyzer fingerprints from %s", technologies, source)
+	return nil
+}
+
 type DetectedTechnologyInfo struct {
 	Name        string
 	Version     string
@@ -47,13 +256,24 @@ type DetectedTechnologyInfo struct {
 	Website     string
 	Icon        string
 	CPE         string
+
+	Confidence      int            // 0-100 score derived from the evidence below
+	EvidenceSources []string       // Distinct sources that matched: header, cookie, html, script, meta or implied
+	Evidence        []TechEvidence // Every fingerprint pattern that matched
 }
 
+// Patterns of sanitizeFilename.
+var (
+	filenameSchemeRegex      = regexp.MustCompile(`^https?://`)
+	filenameUnsafeRegex      = regexp.MustCompile(`[^a-zA-Z0-9_.-]+`)
+	filenameUnderscoresRegex = regexp.MustCompile(`_+`)
+)
+
 func sanitizeFilename(input string) string {
-	s := regexp.MustCompile(`^https?://`).ReplaceAllString(input, "")
-	s = regexp.MustCompile(`[^a-zA-Z0-9_.-]+`).ReplaceAllString(s, "_")
+	s := filenameSchemeRegex.ReplaceAllString(input, "")
+	s = filenameUnsafeRegex.ReplaceAllString(s, "_")
 	s = strings.Trim(s, "_")
-	s = regexp.MustCompile(`_+`).ReplaceAllString(s, "_")
+	s = filenameUnderscoresRegex.ReplaceAllString(s, "_")
 	if len(s) > 100 {
 		s = s[:100]
 	}
@@ -78,107 +298,33 @@ func saveHTMLToFile(filePath string, content []byte) error {
 
 // AnalyzeStack fetches a URL, decompress its body if needed,
 // analyzes its technology stack, and saves the HTML response.
-func AnalyzeStack(targetURL string) ([]DetectedTechnologyInfo, string, error) {
-	initializeWappalyzer()
-	if wappalyzerInitErr != nil {
-		return nil, targetURL, wappalyzerInitErr
-	}
-	if wappalyzerClient == nil {
-		return nil, targetURL, fmt.Errorf("wappalyzer client not available")
+// The fetch result (final URL, curl command, ...) is returned whenever a fetch was attempted.
+func AnalyzeStack(ctx context.Context, targetURL string) ([]DetectedTechnologyInfo, *FetchResult, error) {
+	if _, err := currentWappalyzer(); err != nil {
+		return nil, nil, err
 	}
 
-	// Assume FetchURL returns a struct FetchResult with fields:
-	// Body []byte, Headers http.Header, FinalURL string, StatusCode int, Status string
-	// If FetchURL is not defined here, this is a placeholder for its expected behavior.
-	// type FetchResult struct {
-	// 	Body       []byte
-	// 	Headers    http.Header // Assuming http.Header or similar map[string][]string
-	// 	FinalURL   string
-	// 	StatusCode int
-	// 	Status     string
-	// }
-	fetchResult, err := FetchURL(targetURL) // This is your existing call
+	fetchResult, err := FetchURL(ctx, targetURL)
 	if err != nil {
-		finalErrURL := targetURL
-		if fetchResult != nil && fetchResult.FinalURL != "" {
-			finalErrURL = fetchResult.FinalURL
-		}
-		return nil, finalErrURL, err
+		return nil, fetchResult, err
 	}
+	results, err := AnalyzeFetchedStack(targetURL, fetchResult)
+	return results, fetchResult, err
+}
 
-	finalURL := fetchResult.FinalURL
+// AnalyzeFetchedStack analyzes the technology stack of an already fetched (or captured) page.
+func AnalyzeFetchedStack(targetURL string, fetchResult *FetchResult) ([]DetectedTechnologyInfo, error) {
+	client, err := currentWappalyzer()
+	if err != nil {
+		return nil, err
+	}
 
 	if fetchResult.StatusCode != 200 { // http.StatusOK
-		return nil, finalURL, fmt.Errorf("failed to fetch %s: received status code %d (%s)", targetURL, fetchResult.StatusCode, fetchResult.Status)
-	}
-
-	// --- Decompression and Body Processing ---
-	var bodyToProcess []byte = fetchResult.Body
-	var errDecompress error
-
-	// Check Content-Encoding header. Note: fetchResult.Headers needs to be accessible
-	// and behave like http.Header or provide a Get method.
-	// If fetchResult.Headers is map[string][]string, access might be:
-	//   contentEncodingValues := fetchResult.Headers["Content-Encoding"]
-	//   if len(contentEncodingValues) > 0 { contentEncoding = contentEncodingValues[0] }
-	// For simplicity, assuming a .Get() method similar to http.Header:
-	contentEncoding := ""
-	if fetchResult.Headers != nil { // Ensure headers map/object is not nil
-		// If fetchResult.Headers is map[string][]string:
-		// if ceValues, ok := fetchResult.Headers["Content-Encoding"]; ok && len(ceValues) > 0 {
-		// 	contentEncoding = strings.ToLower(strings.TrimSpace(ceValues[0]))
-		// }
-		// If fetchResult.Headers has a Get method (like http.Header):
-		contentEncoding = strings.ToLower(strings.TrimSpace(fetchResult.Headers.Get("Content-Encoding")))
-	}
-
-	log.Printf("Response from %s - Content-Encoding: '%s', Content-Type: '%s'", finalURL, contentEncoding, fetchResult.Headers.Get("Content-Type"))
-
-	switch contentEncoding {
-	case "gzip":
-		gzReader, errGzip := gzip.NewReader(bytes.NewReader(fetchResult.Body))
-		if errGzip == nil {
-			defer gzReader.Close()
-			decompressedBody, errRead := io.ReadAll(gzReader)
-			if errRead == nil {
-				bodyToProcess = decompressedBody
-				log.Printf("Successfully decompressed GZIP body for %s", finalURL)
-			} else {
-				errDecompress = fmt.Errorf("failed to read gzip decompressed body: %w", errRead)
-			}
-		} else {
-			errDecompress = fmt.Errorf("failed to create gzip reader: %w", errGzip)
-		}
-	case "deflate":
-		zlibReader, errZlib := zlib.NewReader(bytes.NewReader(fetchResult.Body))
-		if errZlib == nil {
-			defer zlibReader.Close()
-			decompressedBody, errRead := io.ReadAll(zlibReader)
-			if errRead == nil {
-				bodyToProcess = decompressedBody
-				log.Printf("Successfully decompressed DEFLATE body for %s", finalURL)
-			} else {
-				errDecompress = fmt.Errorf("failed to read deflate decompressed body: %w", errRead)
-			}
-		} else {
-			errDecompress = fmt.Errorf("failed to create deflate reader: %w", errZlib)
-		}
-	case "br":
-		log.Printf("Warning: Brotli (br) Content-Encoding detected for %s. Standard library does not support Brotli. Body might remain compressed.", finalURL)
-		// Brotli decompression would require an external library, e.g., github.com/andybalholm/brotli
-		// For now, we'll proceed with the original body if it's brotli.
-	case "":
-		// No Content-Encoding or it's an identity encoding. Body is likely plain.
-		log.Printf("No specific Content-Encoding or identity encoding for %s. Assuming plain body.", finalURL)
-	default:
-		log.Printf("Warning: Unsupported Content-Encoding '%s' for %s. Using original body.", contentEncoding, finalURL)
+		return nil, fmt.Errorf("failed to fetch %s: received status code %d (%s)", targetURL, fetchResult.StatusCode, fetchResult.Status)
 	}
 
-	if errDecompress != nil {
-		log.Printf("Warning: Decompression error for %s (encoding: %s): %v. Attempting to use original body for analysis and saving.", finalURL, contentEncoding, errDecompress)
-		// bodyToProcess is already fetchResult.Body by default, so we proceed with it.
-	}
-	// --- End Decompression and Body Processing ---
+	// Decompress the body if the server honoured our Accept-Encoding header
+	bodyToProcess := DecodeResponseBody(fetchResult)
 
 	// // --- Save HTML content for inspection (using the potentially decompressed body) ---
 	// if len(bodyToProcess) > 0 {
@@ -195,7 +341,9 @@ func AnalyzeStack(targetURL string) ([]DetectedTechnologyInfo, string, error) {
 	// // --- End HTML saving ---
 
 	// Use the processed (ideally decompressed) body for Wappalyzer
-	detectedAppsWithInfo := wappalyzerClient.FingerprintWithInfo(fetchResult.Headers, bodyToProcess)
+	detectedAppsWithInfo := client.FingerprintWithInfo(fetchResult.Headers, bodyToProcess)
+	fingerprints := client.GetFingerprints()
+	parts := extractResponseParts(fetchResult.Headers, bodyToProcess)
 
 	var results []DetectedTechnologyInfo
 	for appKey, appInfo := range detectedAppsWithInfo {
@@ -210,16 +358,21 @@ func AnalyzeStack(targetURL string) ([]DetectedTechnologyInfo, string, error) {
 			}
 		}
 
+		evidence, confidence := collectEvidence(fingerprints.Apps[name], parts)
+
 		results = append(results, DetectedTechnologyInfo{
-			Name:        name,
-			Version:     version,
-			Categories:  appInfo.Categories,
-			Description: appInfo.Description,
-			Website:     appInfo.Website,
-			Icon:        appInfo.Icon,
-			CPE:         appInfo.CPE,
+			Name:            name,
+			Version:         version,
+			Categories:      appInfo.Categories,
+			Description:     appInfo.Description,
+			Website:         appInfo.Website,
+			Icon:            appInfo.Icon,
+			CPE:             appInfo.CPE,
+			Confidence:      confidence,
+			EvidenceSources: evidenceSources(evidence),
+			Evidence:        evidence,
 		})
 	}
 
-	return results, finalURL, nil
+	return results, nil
 }
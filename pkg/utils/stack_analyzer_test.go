@@ -0,0 +1,72 @@
+package utils
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	wappalyze "github.com/projectdiscovery/wappalyzergo"
+)
+
+func TestRefreshWappalyzerFingerprints(t *testing.T) {
+	var embedded wappalyze.Fingerprints
+	if err := json.Unmarshal([]byte(wappalyze.GetFingerprints()), &embedded); err != nil {
+		t.Fatal(err)
+	}
+	embedded.Apps["Refresh Test Server"] = &wappalyze.Fingerprint{HTML: []string{"refresh-test-marker"}}
+	updated, err := json.Marshal(embedded)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	body := updated
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write(body)
+	}))
+	defer server.Close()
+
+	cachePath := filepath.Join(t.TempDir(), "fingerprints.json")
+	wappalyzerUpdate.Lock()
+	wappalyzerUpdate.url, wappalyzerUpdate.cachePath = server.URL, cachePath
+	wappalyzerUpdate.Unlock()
+	defer func() {
+		wappalyzerUpdate.Lock()
+		wappalyzerUpdate.url, wappalyzerUpdate.cachePath = "", ""
+		wappalyzerUpdate.Unlock()
+	}()
+
+	status, err := RefreshWappalyzerFingerprints(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status.Source != server.URL || status.Technologies < len(embedded.Apps) || status.LastError != "" {
+		t.Errorf("status = %+v", status)
+	}
+	fetchResult := &FetchResult{StatusCode: 200, Headers: http.Header{}, Body: []byte(`<html><div class="refresh-test-marker"></div></html>`)}
+	technologies, err := AnalyzeFetchedStack(server.URL, fetchResult)
+	if err != nil {
+		t.Fatal(err)
+	}
+	found := false
+	for _, technology := range technologies {
+		found = found || technology.Name == "Refresh Test Server"
+	}
+	if !found {
+		t.Errorf("updated fingerprint not used: %+v", technologies)
+	}
+
+	// A truncated or foreign file is rejected and the fingerprints in use are kept
+	for _, invalid := range []string{`{"apps": {"Only One": {}}}`, `{"technologies": {}}`, `not json`} {
+		body = []byte(invalid)
+		status, err := RefreshWappalyzerFingerprints(context.Background())
+		if err == nil {
+			t.Errorf("%s: no error", invalid)
+		}
+		if status.Source != server.URL || status.LastError == "" {
+			t.Errorf("%s: status = %+v", invalid, status)
+		}
+	}
+}
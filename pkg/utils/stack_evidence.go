@@ -0,0 +1,247 @@
+package utils
+
+import (
+	"bytes"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	wappalyze "github.com/projectdiscovery/wappalyzergo"
+	"golang.org/x/net/html"
+)
+
+// TechEvidence describes a single fingerprint pattern that matched a response.
+type TechEvidence struct {
+	Source  string `json:"source"`        // "header", "cookie", "html", "script" or "meta"
+	Key     string `json:"key,omitempty"` // Header, cookie or meta name the pattern applied to
+	Pattern string `json:"pattern"`       // Raw wappalyzer pattern that matched
+}
+
+// evidenceWeights is the confidence contributed by the first match from each source.
+// Headers and cookies are set by the server itself and are the hardest to fake by accident;
+// HTML patterns are the most prone to false positives (e.g. a blog post mentioning a product).
+var evidenceWeights = map[string]int{
+	"header": 40,
+	"cookie": 35,
+	"script": 30,
+	"meta":   30,
+	"html":   20,
+}
+
+// impliedConfidence is used for technologies only reported through another technology's "implies" list.
+const impliedConfidence = 25
+
+var nonAlphanumericRegex = regexp.MustCompile(`[^a-z0-9]+`)
+
+// responseParts holds the normalized response data the fingerprints are evaluated against.
+type responseParts struct {
+	headers    map[string]string
+	cookies    map[string]string
+	body       string
+	scriptSrcs []string
+	meta       map[string][]string
+}
+
+func extractResponseParts(headers http.Header, body []byte) responseParts {
+	parts := responseParts{
+		headers: make(map[string]string, len(headers)),
+		cookies: make(map[string]string),
+		body:    strings.ToLower(string(body)),
+		meta:    make(map[string][]string),
+	}
+
+	for key, values := range headers {
+		parts.headers[strings.ToLower(key)] = strings.ToLower(strings.Join(values, ", "))
+	}
+	for _, cookie := range (&http.Response{Header: headers}).Cookies() {
+		parts.cookies[strings.ToLower(cookie.Name)] = strings.ToLower(cookie.Value)
+	}
+
+	tokenizer := html.NewTokenizer(bytes.NewReader(body))
+	for {
+		tt := tokenizer.Next()
+		if tt == html.ErrorToken {
+			break
+		}
+		if tt != html.StartTagToken && tt != html.SelfClosingTagToken {
+			continue
+		}
+		token := tokenizer.Token()
+		switch token.Data {
+		case "script":
+			for _, attr := range token.Attr {
+				if attr.Key == "src" && attr.Val != "" {
+					parts.scriptSrcs = append(parts.scriptSrcs, strings.ToLower(attr.Val))
+				}
+			}
+		case "meta":
+			var name, content string
+			for _, attr := range token.Attr {
+				switch attr.Key {
+				case "name", "property":
+					name = strings.ToLower(attr.Val)
+				case "content":
+					content = strings.ToLower(attr.Val)
+				}
+			}
+			if name != "" {
+				parts.meta[name] = append(parts.meta[name], content)
+			}
+		}
+	}
+	return parts
+}
+
+// parsedPatterns caches compiled wappalyzer patterns by their raw text. Patterns that fail
+// to parse are cached as nil.
+var parsedPatterns sync.Map
+
+// patternMatches reports whether a raw wappalyzer pattern matches the target, and its confidence.
+func patternMatches(pattern, target string) (bool, int) {
+	cached, ok := parsedPatterns.Load(pattern)
+	if !ok {
+		parsed, _ := wappalyze.ParsePattern(pattern) // nil on error
+		cached, _ = parsedPatterns.LoadOrStore(pattern, parsed)
+	}
+	parsed := cached.(*wappalyze.ParsedPattern)
+	if parsed == nil {
+		return false, 0
+	}
+	matched, _ := parsed.Evaluate(target)
+	return matched, parsed.Confidence
+}
+
+// collectEvidence re-evaluates a technology's fingerprint against the response
+// and returns every pattern that matched together with a 0-100 confidence score.
+func collectEvidence(fingerprint *wappalyze.Fingerprint, parts responseParts) ([]TechEvidence, int) {
+	if fingerprint == nil {
+		return nil, impliedConfidence
+	}
+
+	var evidence []TechEvidence
+	// Highest pattern confidence seen per source, used to scale that source's weight.
+	sourceConfidence := make(map[string]int)
+	record := func(source, key, pattern string, confidence int) {
+		evidence = append(evidence, TechEvidence{Source: source, Key: key, Pattern: pattern})
+		if confidence > sourceConfidence[source] {
+			sourceConfidence[source] = confidence
+		}
+	}
+
+	for key, pattern := range fingerprint.Headers {
+		key = strings.ToLower(key)
+		if value, ok := parts.headers[key]; ok {
+			if matched, confidence := patternMatches(pattern, value); matched {
+				record("header", key, pattern, confidence)
+			}
+		}
+	}
+	for key, pattern := range fingerprint.Cookies {
+		key = strings.ToLower(key)
+		if value, ok := parts.cookies[key]; ok {
+			if matched, confidence := patternMatches(pattern, value); matched {
+				record("cookie", key, pattern, confidence)
+			}
+		}
+	}
+	for _, pattern := range fingerprint.HTML {
+		if matched, confidence := patternMatches(pattern, parts.body); matched {
+			record("html", "", pattern, confidence)
+		}
+	}
+	for _, pattern := range fingerprint.ScriptSrc {
+		for _, src := range parts.scriptSrcs {
+			if matched, confidence := patternMatches(pattern, src); matched {
+				record("script", src, pattern, confidence)
+				break
+			}
+		}
+	}
+	for key, patterns := range fingerprint.Meta {
+		key = strings.ToLower(key)
+		for _, pattern := range patterns {
+			for _, content := range parts.meta[key] {
+				if matched, confidence := patternMatches(pattern, content); matched {
+					record("meta", key, pattern, confidence)
+					break
+				}
+			}
+		}
+	}
+
+	if len(evidence) == 0 {
+		return nil, impliedConfidence
+	}
+	// Fingerprint headers, cookies and meta are maps; sort for a stable response.
+	sort.Slice(evidence, func(i, j int) bool {
+		a, b := evidence[i], evidence[j]
+		if a.Source != b.Source {
+			return a.Source < b.Source
+		}
+		if a.Key != b.Key {
+			return a.Key < b.Key
+		}
+		return a.Pattern < b.Pattern
+	})
+
+	score := 0
+	for source, confidence := range sourceConfidence {
+		score += evidenceWeights[source] * confidence / 100
+	}
+	// Each additional matched pattern beyond one per source adds a little more certainty.
+	score += 5 * (len(evidence) - len(sourceConfidence))
+	if score > 100 {
+		score = 100
+	}
+	return evidence, score
+}
+
+// evidenceSources returns the distinct sources of the evidence, sorted.
+func evidenceSources(evidence []TechEvidence) []string {
+	if len(evidence) == 0 {
+		return []string{"implied"}
+	}
+	seen := make(map[string]bool)
+	var sources []string
+	for _, e := range evidence {
+		if !seen[e.Source] {
+			seen[e.Source] = true
+			sources = append(sources, e.Source)
+		}
+	}
+	sort.Strings(sources)
+	return sources
+}
+
+// normalizeCategory makes category names comparable, so "cms", "CMS" and
+// "javascript-frameworks" match "CMS" and "JavaScript frameworks".
+func normalizeCategory(category string) string {
+	return nonAlphanumericRegex.ReplaceAllString(strings.ToLower(category), "")
+}
+
+// FilterTechnologiesByCategory keeps only the technologies in at least one of the given categories.
+// An empty category list returns the input unchanged.
+func FilterTechnologiesByCategory(technologies []DetectedTechnologyInfo, categories []string) []DetectedTechnologyInfo {
+	if len(categories) == 0 {
+		return technologies
+	}
+	wanted := make(map[string]bool, len(categories))
+	for _, category := range categories {
+		if normalized := normalizeCategory(category); normalized != "" {
+			wanted[normalized] = true
+		}
+	}
+
+	filtered := []DetectedTechnologyInfo{}
+	for _, tech := range technologies {
+		for _, category := range tech.Categories {
+			if wanted[normalizeCategory(category)] {
+				filtered = append(filtered, tech)
+				break
+			}
+		}
+	}
+	return filtered
+}
@@ -0,0 +1,79 @@
+package utils
+
+import (
+	"net/http"
+	"reflect"
+	"testing"
+
+	wappalyze "github.com/projectdiscovery/wappalyzergo"
+)
+
+func TestPatternMatches(t *testing.T) {
+	tests := []struct {
+		name           string
+		pattern        string
+		target         string
+		wantMatch      bool
+		wantConfidence int
+	}{
+		{"plain match", "nginx", "nginx/1.25.3", true, 100},
+		{"case insensitive", "WordPress", "powered by wordpress", true, 100},
+		{"no match", "nginx", "apache", false, 100},
+		{"confidence suffix", `wp-content\;confidence:50`, "/wp-content/themes", true, 50},
+		{"empty pattern matches anything", "", "whatever", true, 100},
+		{"invalid regex", "([", "([", false, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Evaluate twice so the second call is served from the cache.
+			for i := 0; i < 2; i++ {
+				matched, confidence := patternMatches(tt.pattern, tt.target)
+				if matched != tt.wantMatch || (matched && confidence != tt.wantConfidence) {
+					t.Errorf("patternMatches(%q, %q) = %v, %d; want %v, %d", tt.pattern, tt.target, matched, confidence, tt.wantMatch, tt.wantConfidence)
+				}
+			}
+		})
+	}
+}
+
+func TestCollectEvidenceIsSorted(t *testing.T) {
+	fingerprint := &wappalyze.Fingerprint{
+		Headers: map[string]string{"X-Powered-By": "php", "Server": "nginx", "X-Generator": "drupal"},
+		Cookies: map[string]string{"sessid": "", "has_js": ""},
+		HTML:    []string{"drupal"},
+		Meta:    map[string][]string{"generator": {"drupal"}},
+	}
+	headers := http.Header{
+		"X-Powered-By": {"PHP/8.2"},
+		"Server":       {"nginx"},
+		"X-Generator":  {"Drupal 10"},
+		"Set-Cookie":   {"sessid=abc", "has_js=1"},
+	}
+	parts := extractResponseParts(headers, []byte(`<html><head><meta name="generator" content="Drupal 10"></head><body>drupal</body></html>`))
+
+	want := []TechEvidence{
+		{Source: "cookie", Key: "has_js"},
+		{Source: "cookie", Key: "sessid"},
+		{Source: "header", Key: "server", Pattern: "nginx"},
+		{Source: "header", Key: "x-generator", Pattern: "drupal"},
+		{Source: "header", Key: "x-powered-by", Pattern: "php"},
+		{Source: "html", Pattern: "drupal"},
+		{Source: "meta", Key: "generator", Pattern: "drupal"},
+	}
+	for i := 0; i < 10; i++ { // Map iteration order varies between runs
+		evidence, score := collectEvidence(fingerprint, parts)
+		if !reflect.DeepEqual(evidence, want) {
+			t.Fatalf("collectEvidence() evidence = %+v, want %+v", evidence, want)
+		}
+		if score != 100 {
+			t.Errorf("collectEvidence() score = %d, want 100", score)
+		}
+	}
+}
+
+func TestCollectEvidenceWithoutFingerprint(t *testing.T) {
+	evidence, score := collectEvidence(nil, responseParts{})
+	if evidence != nil || score != impliedConfidence {
+		t.Errorf("collectEvidence(nil) = %v, %d; want nil, %d", evidence, score, impliedConfidence)
+	}
+}
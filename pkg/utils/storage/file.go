@@ -0,0 +1,117 @@
+package storage
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sync"
+
+	"github.com/vit0-9/utils_api/pkg/utils"
+)
+
+// collectionNameRegex keeps collection names safe to use as file names.
+var collectionNameRegex = regexp.MustCompile(`^[a-z0-9_.-]+$`)
+
+// file keeps each collection as a JSON object of documents in <dir>/<collection>.json,
+// rewritten atomically on every change.
+type file struct {
+	mu          sync.Mutex
+	dir         string
+	collections map[string]map[string]json.RawMessage // Loaded collections
+}
+
+// NewFile returns a Store that saves documents as JSON files in dir.
+func NewFile(dir string) (Store, error) {
+	if dir == "" {
+		return nil, fmt.Errorf("the file backend needs a directory")
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	return &file{dir: dir, collections: make(map[string]map[string]json.RawMessage)}, nil
+}
+
+// collectionLocked returns a collection, reading it from disk on first use.
+func (f *file) collectionLocked(collection string) (map[string]json.RawMessage, error) {
+	if documents, ok := f.collections[collection]; ok {
+		return documents, nil
+	}
+	if !collectionNameRegex.MatchString(collection) {
+		return nil, fmt.Errorf("invalid collection name %q", collection)
+	}
+	documents := make(map[string]json.RawMessage)
+	data, err := os.ReadFile(f.path(collection))
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	if err == nil {
+		if err := json.Unmarshal(data, &documents); err != nil {
+			return nil, fmt.Errorf("could not parse %s: %w", f.path(collection), err)
+		}
+		if documents == nil { // The file held null
+			documents = make(map[string]json.RawMessage)
+		}
+	}
+	f.collections[collection] = documents
+	return documents, nil
+}
+
+func (f *file) path(collection string) string {
+	return filepath.Join(f.dir, collection+".json")
+}
+
+func (f *file) saveLocked(collection string) error {
+	data, err := json.Marshal(f.collections[collection])
+	if err != nil {
+		return err
+	}
+	return utils.WriteFileAtomic(f.path(collection), data)
+}
+
+func (f *file) Load(collection string) (map[string][]byte, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	stored, err := f.collectionLocked(collection)
+	if err != nil {
+		return nil, err
+	}
+	documents := make(map[string][]byte, len(stored))
+	for key, value := range stored {
+		documents[key] = append([]byte(nil), value...)
+	}
+	return documents, nil
+}
+
+func (f *file) Put(collection, key string, value []byte) error {
+	if !json.Valid(value) {
+		return fmt.Errorf("the file backend only stores JSON documents")
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	documents, err := f.collectionLocked(collection)
+	if err != nil {
+		return err
+	}
+	documents[key] = append(json.RawMessage(nil), value...)
+	return f.saveLocked(collection)
+}
+
+func (f *file) Delete(collection, key string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	documents, err := f.collectionLocked(collection)
+	if err != nil {
+		return err
+	}
+	if _, ok := documents[key]; !ok {
+		return nil
+	}
+	delete(documents, key)
+	return f.saveLocked(collection)
+}
+
+func (f *file) Close() error {
+	return nil
+}
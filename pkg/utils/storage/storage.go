@@ -0,0 +1,95 @@
+// Package storage persists JSON documents in named collections behind a Store interface, in
+// memory or in plain files, with no dependencies.
+package storage
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+)
+
+// Backend names accepted by Open.
+const (
+	BackendMemory = "memory"
+	BackendFile   = "file"
+)
+
+// Store keeps documents, usually JSON, by collection and key. Implementations are safe for
+// concurrent use.
+type Store interface {
+	// Load returns every document of a collection by key.
+	Load(collection string) (map[string][]byte, error)
+	// Put creates or replaces a document.
+	Put(collection, key string, value []byte) error
+	// Delete removes a document; deleting a missing document is not an error.
+	Delete(collection, key string) error
+	Close() error
+}
+
+// Open returns the Store for a backend: memory (the default), or file with dsn naming a
+// directory.
+func Open(backend, dsn string) (Store, error) {
+	switch strings.ToLower(strings.TrimSpace(backend)) {
+	case "", BackendMemory:
+		return NewMemory(), nil
+	case BackendFile:
+		return NewFile(dsn)
+	}
+	return nil, fmt.Errorf("unknown storage backend %q: use memory or file", backend)
+}
+
+// Configure opens the configured backend, falling back to memory when it cannot be opened.
+func Configure(backend, dsn string) Store {
+	store, err := Open(backend, dsn)
+	if err != nil {
+		log.Printf("ERROR: Could not open %s storage: %v. State will be kept in memory.", backend, err)
+		return NewMemory()
+	}
+	if backend != "" && backend != BackendMemory {
+		log.Printf("State stored with the %s backend", backend)
+	}
+	return store
+}
+
+// memory keeps documents in maps and loses them on restart.
+type memory struct {
+	mu          sync.Mutex
+	collections map[string]map[string][]byte
+}
+
+// NewMemory returns a Store that keeps documents in memory.
+func NewMemory() Store {
+	return &memory{collections: make(map[string]map[string][]byte)}
+}
+
+func (m *memory) Load(collection string) (map[string][]byte, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	documents := make(map[string][]byte, len(m.collections[collection]))
+	for key, value := range m.collections[collection] {
+		documents[key] = append([]byte(nil), value...)
+	}
+	return documents, nil
+}
+
+func (m *memory) Put(collection, key string, value []byte) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.collections[collection] == nil {
+		m.collections[collection] = make(map[string][]byte)
+	}
+	m.collections[collection][key] = append([]byte(nil), value...)
+	return nil
+}
+
+func (m *memory) Delete(collection, key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.collections[collection], key)
+	return nil
+}
+
+func (m *memory) Close() error {
+	return nil
+}
@@ -0,0 +1,81 @@
+package storage
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// testStore checks the behaviour every backend shares.
+func testStore(t *testing.T, store Store) {
+	t.Helper()
+	if documents, err := store.Load("empty"); err != nil || len(documents) != 0 {
+		t.Errorf("Load() of an empty collection = %v, %v", documents, err)
+	}
+	for key, value := range map[string]string{"a": `{"n":1}`, "b": `{"n":2}`} {
+		if err := store.Put("things", key, []byte(value)); err != nil {
+			t.Fatalf("Put(%s) error = %v", key, err)
+		}
+	}
+	if err := store.Put("things", "a", []byte(`{"n":3}`)); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Put("others", "a", []byte(`{}`)); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Delete("things", "b"); err != nil {
+		t.Errorf("Delete() error = %v", err)
+	}
+	if err := store.Delete("things", "missing"); err != nil {
+		t.Errorf("Delete() of a missing document error = %v", err)
+	}
+	documents, err := store.Load("things")
+	if err != nil || len(documents) != 1 || string(documents["a"]) != `{"n":3}` {
+		t.Errorf("Load() = %q, %v; want only a replaced", documents, err)
+	}
+}
+
+func TestMemory(t *testing.T) {
+	testStore(t, NewMemory())
+}
+
+func TestFile(t *testing.T) {
+	dir := t.TempDir()
+	store, err := NewFile(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	testStore(t, store)
+
+	reopened, _ := NewFile(dir)
+	if documents, err := reopened.Load("things"); err != nil || string(documents["a"]) != `{"n":3}` {
+		t.Errorf("Load() after reopening = %q, %v", documents, err)
+	}
+	if err := reopened.Put("things", "bad", []byte("not json")); err == nil {
+		t.Error("Put() of a non-JSON document error = nil")
+	}
+	if _, err := reopened.Load("../escape"); err == nil {
+		t.Error("Load() with a path in the collection name error = nil")
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, "broken.json"), []byte("{"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := reopened.Load("broken"); err == nil {
+		t.Error("Load() of a corrupt collection error = nil")
+	}
+}
+
+func TestOpen(t *testing.T) {
+	if store, err := Open("", ""); err != nil || store == nil {
+		t.Errorf("Open() default = %v, %v; want memory", store, err)
+	}
+	if _, err := Open("file", ""); err == nil {
+		t.Error("Open(file) without a directory error = nil")
+	}
+	for _, backend := range []string{"postgres", "mongo"} {
+		if _, err := Open(backend, ""); err == nil {
+			t.Errorf("Open(%s) error = nil", backend)
+		}
+	}
+}
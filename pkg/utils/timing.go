@@ -0,0 +1,196 @@
+package utils
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"math"
+	"net"
+	"net/http"
+	"net/http/httptrace"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// Timing breaks down where the time of one API request went. The phase durations are
+// summed over every outbound call the request made, so they can exceed TotalMs when calls
+// ran concurrently.
+type Timing struct {
+	DNSMs     float64 `json:"dns_ms"`
+	ConnectMs float64 `json:"connect_ms"`
+	TLSMs     float64 `json:"tls_ms"`
+	TotalMs   float64 `json:"total_ms"`
+	Retries   int     `json:"retries"`   // Outbound attempts repeated after a failure or truncation
+	CacheHit  bool    `json:"cache_hit"` // Some of the answer came from a cache or a stored capture
+}
+
+// Classes of outbound failures noted by the timing recorder.
+const (
+	UpstreamTimeout = "timeout" // A dial, query or response timed out
+	UpstreamRefused = "refused" // The target refused the connection
+)
+
+// TimingRecorder collects the timing of one API request's outbound calls. The shared
+// HTTP transports, the DNS resolver and the WHOIS and TLS dialers report to the recorder
+// found in the request context. A nil recorder ignores everything.
+type TimingRecorder struct {
+	mu       sync.Mutex
+	dns      time.Duration
+	connect  time.Duration
+	tls      time.Duration
+	retries  int
+	cacheHit bool
+	failures map[string]bool // Classes of the outbound failures seen
+}
+
+type timingRecorderKey struct{}
+
+// WithTimingRecorder returns a context carrying a new recorder.
+func WithTimingRecorder(ctx context.Context) (context.Context, *TimingRecorder) {
+	recorder := &TimingRecorder{}
+	return context.WithValue(ctx, timingRecorderKey{}, recorder), recorder
+}
+
+// TimingRecorderFrom returns the recorder in the context, or nil.
+func TimingRecorderFrom(ctx context.Context) *TimingRecorder {
+	recorder, _ := ctx.Value(timingRecorderKey{}).(*TimingRecorder)
+	return recorder
+}
+
+func (r *TimingRecorder) add(field *time.Duration, d time.Duration) {
+	r.mu.Lock()
+	*field += d
+	r.mu.Unlock()
+}
+
+// AddDNS records time spent resolving names.
+func (r *TimingRecorder) AddDNS(d time.Duration) {
+	if r != nil {
+		r.add(&r.dns, d)
+	}
+}
+
+// AddConnect records time spent establishing TCP connections.
+func (r *TimingRecorder) AddConnect(d time.Duration) {
+	if r != nil {
+		r.add(&r.connect, d)
+	}
+}
+
+// AddTLS records time spent in TLS handshakes.
+func (r *TimingRecorder) AddTLS(d time.Duration) {
+	if r != nil {
+		r.add(&r.tls, d)
+	}
+}
+
+// AddRetry counts an outbound attempt that had to be repeated.
+func (r *TimingRecorder) AddRetry() {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	r.retries++
+	r.mu.Unlock()
+}
+
+// MarkCacheHit notes that a result was served from a cache.
+func (r *TimingRecorder) MarkCacheHit() {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	r.cacheHit = true
+	r.mu.Unlock()
+}
+
+// AddFailure notes an outbound call that failed by timing out or being refused; other
+// errors, such as NXDOMAIN answers or policy denials, are not upstream failures.
+func (r *TimingRecorder) AddFailure(err error) {
+	class := ClassifyUpstreamError(err)
+	if r == nil || class == "" {
+		return
+	}
+	r.mu.Lock()
+	if r.failures == nil {
+		r.failures = make(map[string]bool)
+	}
+	r.failures[class] = true
+	r.mu.Unlock()
+}
+
+// Failed reports whether an outbound call failed with the given class.
+func (r *TimingRecorder) Failed(class string) bool {
+	if r == nil {
+		return false
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.failures[class]
+}
+
+// ClassifyUpstreamError returns UpstreamTimeout or UpstreamRefused for errors of those kinds,
+// and "" otherwise.
+func ClassifyUpstreamError(err error) string {
+	var netErr net.Error
+	switch {
+	case err == nil:
+		return ""
+	case errors.Is(err, context.DeadlineExceeded), errors.As(err, &netErr) && netErr.Timeout():
+		return UpstreamTimeout
+	case errors.Is(err, syscall.ECONNREFUSED):
+		return UpstreamRefused
+	}
+	return ""
+}
+
+// Timing returns the collected timing with the request's total duration.
+func (r *TimingRecorder) Timing(total time.Duration) Timing {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return Timing{
+		DNSMs:     durationMs(r.dns),
+		ConnectMs: durationMs(r.connect),
+		TLSMs:     durationMs(r.tls),
+		TotalMs:   durationMs(total),
+		Retries:   r.retries,
+		CacheHit:  r.cacheHit,
+	}
+}
+
+func durationMs(d time.Duration) float64 {
+	return math.Round(float64(d)/float64(time.Microsecond)) / 1000
+}
+
+// traceTiming attaches an httptrace that reports the request's DNS, connect and TLS phases
+// to the recorder in its context. Requests without a recorder are returned unchanged.
+func traceTiming(req *http.Request) *http.Request {
+	recorder := TimingRecorderFrom(req.Context())
+	if recorder == nil {
+		return req
+	}
+	var dnsStart, tlsStart time.Time
+	var connectMu sync.Mutex
+	connectStarts := make(map[string]time.Time) // Dual-stack dials race several addresses
+	trace := &httptrace.ClientTrace{
+		DNSStart: func(httptrace.DNSStartInfo) { dnsStart = time.Now() },
+		DNSDone:  func(httptrace.DNSDoneInfo) { recorder.AddDNS(time.Since(dnsStart)) },
+		ConnectStart: func(network, addr string) {
+			connectMu.Lock()
+			connectStarts[network+" "+addr] = time.Now()
+			connectMu.Unlock()
+		},
+		ConnectDone: func(network, addr string, _ error) {
+			connectMu.Lock()
+			start, ok := connectStarts[network+" "+addr]
+			connectMu.Unlock()
+			if ok {
+				recorder.AddConnect(time.Since(start))
+			}
+		},
+		TLSHandshakeStart: func() { tlsStart = time.Now() },
+		TLSHandshakeDone:  func(tls.ConnectionState, error) { recorder.AddTLS(time.Since(tlsStart)) },
+	}
+	return req.WithContext(httptrace.WithClientTrace(req.Context(), trace))
+}
@@ -0,0 +1,76 @@
+package utils
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestTimingRecorderCollectsOutboundPhases(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+
+	ctx, recorder := WithTimingRecorder(context.Background())
+	if _, err := FetchURL(ctx, server.URL); err != nil {
+		t.Fatalf("FetchURL() error = %v", err)
+	}
+	recorder.MarkCacheHit()
+	recorder.AddRetry()
+
+	timing := recorder.Timing(2 * time.Second)
+	if timing.ConnectMs <= 0 {
+		t.Errorf("ConnectMs = %v, want the dial to be recorded", timing.ConnectMs)
+	}
+	if timing.TLSMs != 0 {
+		t.Errorf("TLSMs = %v for a plain HTTP fetch, want 0", timing.TLSMs)
+	}
+	if timing.TotalMs != 2000 || timing.Retries != 1 || !timing.CacheHit {
+		t.Errorf("Timing() = %+v, want total 2000ms, 1 retry and a cache hit", timing)
+	}
+}
+
+func TestNilTimingRecorderIgnoresEverything(t *testing.T) {
+	recorder := TimingRecorderFrom(context.Background())
+	if recorder != nil {
+		t.Fatalf("TimingRecorderFrom() = %v for a bare context, want nil", recorder)
+	}
+	recorder.AddDNS(time.Second)
+	recorder.AddConnect(time.Second)
+	recorder.AddTLS(time.Second)
+	recorder.AddRetry()
+	recorder.MarkCacheHit()
+}
+
+func TestDurationMs(t *testing.T) {
+	if got := durationMs(1234567 * time.Nanosecond); got != 1.235 {
+		t.Errorf("durationMs(1.234567ms) = %v, want 1.235", got)
+	}
+}
+
+func TestTimingRecorderClassifiesUpstreamFailures(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	server.Close() // Nothing listens on its port any more
+
+	ctx, recorder := WithTimingRecorder(context.Background())
+	if _, err := FetchURL(ctx, server.URL); err == nil {
+		t.Fatal("FetchURL() of a closed port succeeded")
+	}
+	if !recorder.Failed(UpstreamRefused) || recorder.Failed(UpstreamTimeout) {
+		t.Errorf("refused = %v, timeout = %v; want only the refusal", recorder.Failed(UpstreamRefused), recorder.Failed(UpstreamTimeout))
+	}
+
+	recorder.AddFailure(context.DeadlineExceeded)
+	recorder.AddFailure(ErrDNSNameNotFound) // Not an upstream failure
+	if !recorder.Failed(UpstreamTimeout) {
+		t.Error("a deadline was not recorded as a timeout")
+	}
+	var nilRecorder *TimingRecorder
+	nilRecorder.AddFailure(context.DeadlineExceeded)
+	if nilRecorder.Failed(UpstreamTimeout) {
+		t.Error("a nil recorder reported a failure")
+	}
+}
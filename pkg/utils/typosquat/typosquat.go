@@ -0,0 +1,290 @@
+// Package typosquat generates the lookalike domains a phisher or typosquatter could register
+// for a domain, and checks which of them exist in DNS.
+package typosquat
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"github.com/vit0-9/utils_api/pkg/utils"
+	"golang.org/x/net/dns/dnsmessage"
+	"golang.org/x/net/idna"
+	"golang.org/x/net/publicsuffix"
+)
+
+// Fuzzers, the techniques producing permutations, in the order they are listed.
+const (
+	FuzzerBitsquatting = "bitsquatting" // A bit flipped in a character by a memory error
+	FuzzerHomoglyph    = "homoglyph"    // Characters replaced by ones that look alike
+	FuzzerHyphenation  = "hyphenation"  // A hyphen inserted between two characters
+	FuzzerKeyboard     = "keyboard"     // A character replaced by a neighbouring key
+	FuzzerTLDSwap      = "tld-swap"     // The same name under another public suffix
+)
+
+// Fuzzers lists every fuzzer.
+var Fuzzers = []string{FuzzerBitsquatting, FuzzerHomoglyph, FuzzerHyphenation, FuzzerKeyboard, FuzzerTLDSwap}
+
+// tlds are the public suffixes the TLD swap tries: the most registered, those cheap enough to
+// be popular with phishers and common ccTLDs.
+var tlds = []string{
+	"com", "net", "org", "info", "biz", "co", "io", "me", "app", "dev", "ai", "xyz", "online",
+	"site", "top", "shop", "store", "club", "live", "cc", "us", "co.uk", "de", "fr", "eu", "cn",
+	"ru", "in", "com.br",
+}
+
+// asciiHomoglyphs are ASCII strings read as another: rn for m, 0 for o, ...
+var asciiHomoglyphs = map[string][]string{
+	"a": {"4"}, "b": {"8", "lb"}, "cl": {"d"}, "d": {"cl"}, "e": {"3"}, "g": {"q", "9"},
+	"i": {"1", "l"}, "l": {"1", "i"}, "m": {"rn", "nn"}, "nn": {"m"}, "o": {"0"}, "q": {"g"},
+	"rn": {"m"}, "s": {"5"}, "u": {"v"}, "v": {"u"}, "vv": {"w"}, "w": {"vv"}, "z": {"2"},
+	"0": {"o"}, "1": {"l", "i"},
+}
+
+// qwertyRows are the rows of a QWERTY keyboard, for the keyboard adjacency fuzzer.
+var qwertyRows = []string{"1234567890", "qwertyuiop", "asdfghjkl", "zxcvbnm"}
+
+// keyboardNeighbours maps each key to the keys around it.
+var keyboardNeighbours = func() map[byte]string {
+	neighbours := make(map[byte]string)
+	key := func(row, col int) (byte, bool) {
+		if row < 0 || row >= len(qwertyRows) || col < 0 || col >= len(qwertyRows[row]) {
+			return 0, false
+		}
+		return qwertyRows[row][col], true
+	}
+	for row, keys := range qwertyRows {
+		for col := range len(keys) {
+			var around []byte
+			// The same row, and the keys above and below, which are offset by half a key
+			for _, offset := range [][2]int{{0, -1}, {0, 1}, {-1, 0}, {-1, 1}, {1, -1}, {1, 0}} {
+				if k, ok := key(row+offset[0], col+offset[1]); ok {
+					around = append(around, k)
+				}
+			}
+			neighbours[keys[col]] = string(around)
+		}
+	}
+	return neighbours
+}()
+
+// Permutation is a lookalike of a domain.
+type Permutation struct {
+	Domain  string `json:"domain" example:"examp1e.com"`            // ASCII (punycode) form
+	Unicode string `json:"unicode,omitempty" example:"exаmple.com"` // For internationalized permutations
+	Fuzzer  string `json:"fuzzer" example:"homoglyph"`              // The technique that produced it
+	// Set by Check: whether the name has A or NS records
+	Registered *bool    `json:"registered,omitempty"`
+	A          []string `json:"a,omitempty"`
+	NS         []string `json:"ns,omitempty"`
+	Error      string   `json:"error,omitempty"`
+}
+
+// Generate returns the permutations of domain made by the given fuzzers (every fuzzer when
+// none are given), without duplicates or the domain itself, grouped by fuzzer in the order of
+// Fuzzers and sorted by domain within each. Only the label left of the public suffix is
+// permuted, so for www.example.co.uk the permutations are of example.co.uk.
+func Generate(domain string, fuzzers ...string) ([]Permutation, error) {
+	ascii, err := idna.Lookup.ToASCII(strings.TrimSuffix(strings.TrimSpace(domain), "."))
+	if err != nil {
+		return nil, fmt.Errorf("invalid domain: %w", err)
+	}
+	registrable, err := publicsuffix.EffectiveTLDPlusOne(ascii)
+	if err != nil {
+		return nil, fmt.Errorf("invalid domain: %w", err)
+	}
+	name, suffix, _ := strings.Cut(registrable, ".")
+	if strings.HasPrefix(name, "xn--") {
+		if name, err = idna.Lookup.ToUnicode(name); err != nil {
+			return nil, fmt.Errorf("invalid domain: %w", err)
+		}
+	}
+
+	selected := make(map[string]bool)
+	for _, fuzzer := range fuzzers {
+		if !isFuzzer(fuzzer) {
+			return nil, fmt.Errorf("unknown fuzzer %q: use %s", fuzzer, strings.Join(Fuzzers, ", "))
+		}
+		selected[fuzzer] = true
+	}
+
+	seen := map[string]bool{registrable: true}
+	var permutations []Permutation
+	for _, fuzzer := range Fuzzers {
+		if len(selected) > 0 && !selected[fuzzer] {
+			continue
+		}
+		var candidates []string
+		switch fuzzer {
+		case FuzzerBitsquatting:
+			candidates = withSuffix(bitsquat(name), suffix)
+		case FuzzerHomoglyph:
+			candidates = withSuffix(homoglyph(name), suffix)
+		case FuzzerHyphenation:
+			candidates = withSuffix(hyphenate(name), suffix)
+		case FuzzerKeyboard:
+			candidates = withSuffix(keyboard(name), suffix)
+		case FuzzerTLDSwap:
+			for _, tld := range tlds {
+				candidates = append(candidates, name+"."+tld)
+			}
+		}
+		var found []Permutation
+		for _, candidate := range candidates {
+			permutation, ok := newPermutation(candidate, fuzzer)
+			if !ok || seen[permutation.Domain] {
+				continue
+			}
+			seen[permutation.Domain] = true
+			found = append(found, permutation)
+		}
+		sort.Slice(found, func(i, j int) bool { return found[i].Domain < found[j].Domain })
+		permutations = append(permutations, found...)
+	}
+	return permutations, nil
+}
+
+func isFuzzer(name string) bool {
+	for _, fuzzer := range Fuzzers {
+		if fuzzer == name {
+			return true
+		}
+	}
+	return false
+}
+
+func withSuffix(names []string, suffix string) []string {
+	domains := make([]string, len(names))
+	for i, name := range names {
+		domains[i] = name + "." + suffix
+	}
+	return domains
+}
+
+// newPermutation validates a candidate, which may be in Unicode, as a host name.
+func newPermutation(candidate, fuzzer string) (Permutation, bool) {
+	ascii, err := idna.Lookup.ToASCII(candidate)
+	if err != nil || len(ascii) > 253 {
+		return Permutation{}, false
+	}
+	for _, label := range strings.Split(ascii, ".") {
+		if len(label) > 63 {
+			return Permutation{}, false
+		}
+	}
+	permutation := Permutation{Domain: ascii, Fuzzer: fuzzer}
+	if ascii != candidate {
+		permutation.Unicode = candidate
+	}
+	return permutation, true
+}
+
+// isHostByte reports whether c may appear in an ASCII label.
+func isHostByte(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= '0' && c <= '9' || c == '-'
+}
+
+// bitsquat flips each bit of each character, keeping the results that are still valid in a
+// host name.
+func bitsquat(name string) []string {
+	var variants []string
+	for i := 0; i < len(name); i++ {
+		if name[i] >= 0x80 {
+			continue
+		}
+		for bit := range 8 {
+			// Flips to uppercase are dropped too: they are the same name
+			if c := name[i] ^ 1<<bit; isHostByte(c) {
+				variants = append(variants, name[:i]+string(c)+name[i+1:])
+			}
+		}
+	}
+	return variants
+}
+
+// homoglyph replaces each character, or pair such as rn, with the ones that look like it: the
+// ASCII lookalikes, and the Unicode ones of utils.Homoglyphs one character at a time.
+func homoglyph(name string) []string {
+	var variants []string
+	for i := 0; i < len(name); i++ {
+		for _, length := range []int{1, 2} {
+			if i+length > len(name) {
+				continue
+			}
+			for _, replacement := range asciiHomoglyphs[name[i:i+length]] {
+				variants = append(variants, name[:i]+replacement+name[i+length:])
+			}
+		}
+		for _, r := range utils.Homoglyphs(rune(name[i])) {
+			variants = append(variants, name[:i]+string(r)+name[i+1:])
+		}
+	}
+	return variants
+}
+
+// hyphenate inserts a hyphen between each pair of characters that are not hyphens.
+func hyphenate(name string) []string {
+	var variants []string
+	for i := 1; i < len(name); i++ {
+		if name[i-1] != '-' && name[i] != '-' && utf8.RuneStart(name[i]) {
+			variants = append(variants, name[:i]+"-"+name[i:])
+		}
+	}
+	return variants
+}
+
+// keyboard replaces each character with the keys around it on a QWERTY keyboard.
+func keyboard(name string) []string {
+	var variants []string
+	for i := 0; i < len(name); i++ {
+		for _, c := range []byte(keyboardNeighbours[name[i]]) {
+			variants = append(variants, name[:i]+string(c)+name[i+1:])
+		}
+	}
+	return variants
+}
+
+// Check looks up the A and NS records of each permutation, concurrency at a time, setting
+// Registered when it has either. A lookup that fails for another reason than the name not
+// existing leaves Registered unset and records the error.
+func Check(ctx context.Context, resolver utils.Resolver, permutations []Permutation, concurrency int) {
+	semaphore := make(chan struct{}, max(concurrency, 1))
+	var wg sync.WaitGroup
+	for i := range permutations {
+		semaphore <- struct{}{}
+		wg.Add(1)
+		go func(permutation *Permutation) {
+			defer func() { <-semaphore; wg.Done() }()
+			check(ctx, resolver, permutation)
+		}(&permutations[i])
+	}
+	wg.Wait()
+}
+
+func check(ctx context.Context, resolver utils.Resolver, permutation *Permutation) {
+	for _, qtype := range []dnsmessage.Type{dnsmessage.TypeNS, dnsmessage.TypeA} {
+		answers, err := resolver.Query(ctx, permutation.Domain, qtype)
+		if errors.Is(err, utils.ErrDNSNameNotFound) {
+			break // Neither record exists
+		}
+		if err != nil {
+			permutation.Error = err.Error()
+			return
+		}
+		for _, answer := range answers {
+			switch body := answer.Body.(type) {
+			case *dnsmessage.NSResource:
+				permutation.NS = append(permutation.NS, strings.TrimSuffix(strings.ToLower(body.NS.String()), "."))
+			case *dnsmessage.AResource:
+				permutation.A = append(permutation.A, net.IP(body.A[:]).String())
+			}
+		}
+	}
+	registered := len(permutation.A) > 0 || len(permutation.NS) > 0
+	permutation.Registered = &registered
+}
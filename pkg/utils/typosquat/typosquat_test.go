@@ -0,0 +1,101 @@
+package typosquat
+
+import (
+	"context"
+	"net/netip"
+	"slices"
+	"testing"
+
+	"github.com/vit0-9/utils_api/pkg/utils"
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+func domains(permutations []Permutation) []string {
+	var names []string
+	for _, permutation := range permutations {
+		names = append(names, permutation.Domain)
+	}
+	return names
+}
+
+func TestGenerate(t *testing.T) {
+	tests := []struct {
+		fuzzer string
+		want   []string // Some of the expected permutations
+		absent []string
+	}{
+		{FuzzerBitsquatting, []string{"dxample.com", "exaeple.com", "examplu.com"}, []string{"Example.com"}},
+		{FuzzerHomoglyph, []string{"examp1e.com", "exarnple.com", "xn--exmple-4nf.com"}, nil},
+		{FuzzerHyphenation, []string{"e-xample.com", "exampl-e.com"}, []string{"-example.com", "example-.com"}},
+		{FuzzerKeyboard, []string{"wxample.com", "exsmple.com", "exampke.com"}, nil},
+		{FuzzerTLDSwap, []string{"example.net", "example.co.uk"}, []string{"example.com"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.fuzzer, func(t *testing.T) {
+			permutations, err := Generate("www.Example.com.", tt.fuzzer)
+			if err != nil {
+				t.Fatal(err)
+			}
+			names := domains(permutations)
+			for _, want := range tt.want {
+				if !slices.Contains(names, want) {
+					t.Errorf("missing %s in %v", want, names)
+				}
+			}
+			for _, absent := range append(tt.absent, "example.com") {
+				if slices.Contains(names, absent) {
+					t.Errorf("unexpected %s", absent)
+				}
+			}
+			for _, permutation := range permutations {
+				if permutation.Fuzzer != tt.fuzzer {
+					t.Errorf("%s made by %s", permutation.Domain, permutation.Fuzzer)
+				}
+			}
+			if !slices.IsSorted(names) {
+				t.Errorf("permutations are not sorted: %v", names)
+			}
+		})
+	}
+
+	all, err := Generate("example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	seen := make(map[string]bool)
+	for _, permutation := range all {
+		if seen[permutation.Domain] {
+			t.Errorf("duplicate %s", permutation.Domain)
+		}
+		seen[permutation.Domain] = true
+	}
+	if i := slices.IndexFunc(all, func(p Permutation) bool { return p.Unicode == "exаmple.com" }); i < 0 || all[i].Domain != "xn--exmple-4nf.com" {
+		t.Error("missing exаmple.com with Cyrillic а as xn--exmple-4nf.com")
+	}
+
+	if _, err := Generate("example.com", "soundalike"); err == nil {
+		t.Error("unknown fuzzer: error = nil")
+	}
+	if _, err := Generate("com"); err == nil {
+		t.Error("public suffix: error = nil")
+	}
+}
+
+func TestCheck(t *testing.T) {
+	resolver := utils.NewFakeResolver().
+		Add("exarnple.com", dnsmessage.TypeNS, &dnsmessage.NSResource{NS: dnsmessage.MustNewName("ns1.parking.test.")}).
+		Add("exarnple.com", dnsmessage.TypeA, &dnsmessage.AResource{A: netip.MustParseAddr("192.0.2.7").As4()}).
+		Fail("examp1e.com", dnsmessage.TypeNS, context.DeadlineExceeded)
+	permutations := []Permutation{{Domain: "exarnple.com"}, {Domain: "examp1e.com"}, {Domain: "exampl3.com"}}
+	Check(context.Background(), resolver, permutations, 2)
+
+	if p := permutations[0]; p.Registered == nil || !*p.Registered || !slices.Equal(p.NS, []string{"ns1.parking.test"}) || !slices.Equal(p.A, []string{"192.0.2.7"}) {
+		t.Errorf("registered permutation = %+v", p)
+	}
+	if p := permutations[1]; p.Registered != nil || p.Error == "" {
+		t.Errorf("failed lookup = %+v", p)
+	}
+	if p := permutations[2]; p.Registered == nil || *p.Registered {
+		t.Errorf("unregistered permutation = %+v", p)
+	}
+}
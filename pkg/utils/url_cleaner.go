@@ -5,7 +5,6 @@ import (
 	"encoding/json"
 	"log"
 	"net/url"
-	"sort"
 	"strings"
 	"sync"
 )
@@ -108,14 +107,8 @@ func CleanURL(rawURL string) (CleanURLResult, error) {
 		return result, nil
 	}
 
-	cleanedQuery := url.Values{}
-	var keptKeys []string
-	removedThisRound := make(map[string]bool) // To avoid double-removing if a param matches multiple rules (though unlikely with current logic)
-
+	cleanedQuery := make(url.Values, len(query))
 	for key, values := range query {
-		if removedThisRound[key] {
-			continue
-		}
 		lowercaseKey := strings.ToLower(key)
 		var matchedDetail TrackingParamDetail
 		var foundMatch bool
@@ -141,7 +134,6 @@ func CleanURL(rawURL string) (CleanURLResult, error) {
 		}
 
 		if foundMatch {
-			removedThisRound[key] = true // Mark original key as processed
 			for _, value := range values {
 				result.RemovedParams = append(result.RemovedParams, RemovedParamInfo{
 					Parameter:   key, // Report original key
@@ -155,26 +147,11 @@ func CleanURL(rawURL string) (CleanURLResult, error) {
 			continue // Skip adding to cleanedQuery
 		}
 
-		// Keep non-tracking parameters
-		for _, value := range values {
-			cleanedQuery.Add(key, value)
-		}
-		if len(values) > 0 {
-			keptKeys = append(keptKeys, key)
-		}
+		// Keep non-tracking parameters; Encode sorts them by key
+		cleanedQuery[key] = values
 	}
 
-	sort.Strings(keptKeys)
-
-	finalQueryValues := url.Values{}
-	for _, k := range keptKeys {
-		if vals, ok := cleanedQuery[k]; ok {
-			for _, v := range vals {
-				finalQueryValues.Add(k, v)
-			}
-		}
-	}
-	parsedURL.RawQuery = finalQueryValues.Encode()
+	parsedURL.RawQuery = cleanedQuery.Encode()
 	result.CleanedURL = parsedURL.String()
 
 	return result, nil
@@ -0,0 +1,36 @@
+package utils
+
+import (
+	"testing"
+)
+
+// cleanURLSample is a landing page link with typical tracking parameters.
+const cleanURLSample = "https://shop.example.com/products/boots?id=42&color=brown&utm_source=newsletter&utm_medium=email&utm_campaign=spring&fbclid=IwAR2abc&gclid=Cj0KCQ&ref=homepage"
+
+func TestCleanURL(t *testing.T) {
+	result, err := CleanURL(cleanURLSample)
+	if err != nil {
+		t.Fatalf("CleanURL() error = %v", err)
+	}
+	if want := "https://shop.example.com/products/boots?color=brown&id=42&ref=homepage"; result.CleanedURL != want {
+		t.Errorf("CleanedURL = %q, want %q", result.CleanedURL, want)
+	}
+	removed := map[string]bool{}
+	for _, param := range result.RemovedParams {
+		removed[param.Parameter] = true
+	}
+	for _, param := range []string{"utm_source", "utm_medium", "utm_campaign", "fbclid", "gclid"} {
+		if !removed[param] {
+			t.Errorf("%s was not removed: %+v", param, result.RemovedParams)
+		}
+	}
+}
+
+func BenchmarkCleanURL(b *testing.B) {
+	b.ReportAllocs()
+	for b.Loop() {
+		if _, err := CleanURL(cleanURLSample); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
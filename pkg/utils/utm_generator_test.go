@@ -0,0 +1,14 @@
+package utils
+
+import "testing"
+
+func BenchmarkGenerateUTMLink(b *testing.B) {
+	params := FullUTMParams{Source: "Newsletter", Medium: "Email", Campaign: "Spring Sale 2026", Content: "Hero Banner"}
+	options := &UTMGeneratorOptions{ForceLowercase: true, SpaceReplacement: "_"}
+	b.ReportAllocs()
+	for b.Loop() {
+		if _, err := GenerateUTMLink("https://shop.example.com/products/boots?id=42", params, options); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
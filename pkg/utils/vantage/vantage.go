@@ -0,0 +1,230 @@
+// Package vantage lets instances of the API in other regions act as vantage points for a
+// primary instance. Agents register themselves with the primary, which can then relay a
+// check to several of them and return the results side by side.
+package vantage
+
+import (
+	"bytes"
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/vit0-9/utils_api/pkg/utils"
+)
+
+// Registration timing: agents heartbeat every registerInterval and are dropped from the
+// registry when none was received for agentExpiry.
+const (
+	registerInterval      = 30 * time.Second
+	registerRetryInterval = 5 * time.Second
+	agentExpiry           = 3 * registerInterval
+	relayTimeout          = 60 * time.Second
+	maxRelayBody          = 10 << 20
+)
+
+// SecretHeader carries the shared secret on registrations and relayed requests.
+const SecretHeader = "X-Vantage-Secret"
+
+// Config configures this instance's role. An instance with a PrimaryURL is an agent; one
+// with only a Secret is a primary accepting registrations.
+type Config struct {
+	Name       string // This instance's vantage name, e.g. "eu"
+	PrimaryURL string // Base URL of the primary to register with (agents only)
+	PublicURL  string // Base URL the primary uses to reach this agent
+	Secret     string // Shared secret between the primary and its agents
+}
+
+// Agent is a registered vantage point.
+type Agent struct {
+	Name     string    `json:"name"`
+	URL      string    `json:"url"`
+	LastSeen time.Time `json:"last_seen"`
+}
+
+var (
+	config Config
+
+	agentsMu sync.Mutex
+	agents   = make(map[string]Agent)
+
+	relayClient = &http.Client{Timeout: relayTimeout, Transport: utils.NewOutboundTransport()}
+)
+
+// Configure sets up this instance's vantage role. Agents start registering with the
+// primary in the background.
+func Configure(cfg Config) {
+	cfg.Name = strings.ToLower(strings.TrimSpace(cfg.Name))
+	if cfg.Name == "" {
+		cfg.Name = "local"
+	}
+	cfg.PrimaryURL = strings.TrimSuffix(cfg.PrimaryURL, "/")
+	cfg.PublicURL = strings.TrimSuffix(cfg.PublicURL, "/")
+	config = cfg
+
+	if cfg.PrimaryURL == "" {
+		if cfg.Secret != "" {
+			log.Printf("Vantage point %q accepting agent registrations", cfg.Name)
+		}
+		return
+	}
+	if cfg.PublicURL == "" || cfg.Secret == "" {
+		log.Println("ERROR: VANTAGE_PUBLIC_URL and VANTAGE_SECRET are required to register with a primary. Agent mode disabled.")
+		return
+	}
+	go registerLoop()
+	log.Printf("Vantage agent %q registering with %s", cfg.Name, cfg.PrimaryURL)
+}
+
+// Name returns this instance's vantage name.
+func Name() string {
+	if config.Name == "" {
+		return "local"
+	}
+	return config.Name
+}
+
+// AcceptsRegistrations reports whether a shared secret is configured.
+func AcceptsRegistrations() bool {
+	return config.Secret != ""
+}
+
+// CheckSecret compares a presented secret with the configured one in constant time.
+func CheckSecret(secret string) bool {
+	return config.Secret != "" && subtle.ConstantTimeCompare([]byte(secret), []byte(config.Secret)) == 1
+}
+
+// Register adds or refreshes an agent in the registry.
+func Register(name, agentURL string) error {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if name == "" || name == Name() {
+		return fmt.Errorf("invalid vantage name: %q", name)
+	}
+	parsed, err := url.Parse(agentURL)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		return fmt.Errorf("invalid agent URL: %s", agentURL)
+	}
+
+	agentsMu.Lock()
+	agents[name] = Agent{Name: name, URL: strings.TrimSuffix(agentURL, "/"), LastSeen: time.Now()}
+	agentsMu.Unlock()
+	return nil
+}
+
+// Agents lists the live registered agents, sorted by name.
+func Agents() []Agent {
+	agentsMu.Lock()
+	defer agentsMu.Unlock()
+	list := make([]Agent, 0, len(agents))
+	for name, agent := range agents {
+		if time.Since(agent.LastSeen) > agentExpiry {
+			delete(agents, name)
+			continue
+		}
+		list = append(list, agent)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+	return list
+}
+
+func lookupAgent(name string) (Agent, bool) {
+	for _, agent := range Agents() {
+		if agent.Name == name {
+			return agent, true
+		}
+	}
+	return Agent{}, false
+}
+
+// Result is one vantage point's response to a relayed check.
+type Result struct {
+	Vantage    string          `json:"vantage"`
+	StatusCode int             `json:"status_code,omitempty"`
+	LatencyMs  int64           `json:"latency_ms"`
+	Response   json.RawMessage `json:"response,omitempty"`
+	Error      string          `json:"error,omitempty"`
+}
+
+// Relay runs a GET request (an API path and query string) on a registered agent.
+func Relay(ctx context.Context, name, path, rawQuery string) Result {
+	result := Result{Vantage: name}
+	agent, ok := lookupAgent(name)
+	if !ok {
+		result.Error = "unknown vantage point"
+		return result
+	}
+
+	target := agent.URL + path
+	if rawQuery != "" {
+		target += "?" + rawQuery
+	}
+	req, err := http.NewRequestWithContext(ctx, "GET", target, nil)
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	req.Header.Set(SecretHeader, config.Secret)
+	req.Header.Set("Accept", "application/json")
+
+	start := time.Now()
+	resp, err := relayClient.Do(req)
+	if err != nil {
+		result.Error = fmt.Sprintf("relay to %s failed: %v", name, err)
+		return result
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxRelayBody))
+	result.LatencyMs = time.Since(start).Milliseconds()
+	result.StatusCode = resp.StatusCode
+	if err != nil {
+		result.Error = fmt.Sprintf("failed to read response from %s: %v", name, err)
+		return result
+	}
+	if !json.Valid(body) {
+		result.Error = fmt.Sprintf("%s returned a non-JSON response", name)
+		return result
+	}
+	result.Response = body
+	return result
+}
+
+// registerLoop heartbeats this agent's registration with the primary.
+func registerLoop() {
+	payload, _ := json.Marshal(map[string]string{"name": config.Name, "url": config.PublicURL})
+	for {
+		wait := registerInterval
+		if err := register(payload); err != nil {
+			log.Printf("WARN: Vantage registration with %s failed: %v", config.PrimaryURL, err)
+			wait = registerRetryInterval
+		}
+		time.Sleep(wait)
+	}
+}
+
+func register(payload []byte) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, "POST", config.PrimaryURL+"/api/v1/vantage/register", bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(SecretHeader, config.Secret)
+	resp, err := relayClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("primary returned status %d", resp.StatusCode)
+	}
+	return nil
+}
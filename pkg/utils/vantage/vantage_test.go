@@ -0,0 +1,108 @@
+package vantage
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// useConfig sets the package configuration and an empty registry for one test.
+func useConfig(t *testing.T, cfg Config) {
+	t.Helper()
+	config = cfg
+	agentsMu.Lock()
+	agents = make(map[string]Agent)
+	agentsMu.Unlock()
+	t.Cleanup(func() { config = Config{} })
+}
+
+func TestRegister(t *testing.T) {
+	useConfig(t, Config{Name: "primary", Secret: "s3cret"})
+	tests := []struct {
+		name    string
+		url     string
+		wantErr bool
+	}{
+		{" EU ", "https://eu.example.com/", false},
+		{"us", "http://10.0.0.2:8080", false},
+		{"", "https://x.example.com", true},
+		{"primary", "https://x.example.com", true},
+		{"ap", "ftp://ap.example.com", true},
+		{"ap", "not a url", true},
+	}
+	for _, tt := range tests {
+		if err := Register(tt.name, tt.url); (err != nil) != tt.wantErr {
+			t.Errorf("Register(%q, %q) error = %v, want error %v", tt.name, tt.url, err, tt.wantErr)
+		}
+	}
+
+	agentsMu.Lock()
+	agents["stale"] = Agent{Name: "stale", URL: "https://stale.example.com", LastSeen: time.Now().Add(-agentExpiry - time.Second)}
+	agentsMu.Unlock()
+	list := Agents()
+	if len(list) != 2 || list[0].Name != "eu" || list[0].URL != "https://eu.example.com" || list[1].Name != "us" {
+		t.Errorf("Agents() = %+v, want eu and us without the expired agent", list)
+	}
+}
+
+func TestCheckSecret(t *testing.T) {
+	useConfig(t, Config{})
+	if CheckSecret("") || AcceptsRegistrations() {
+		t.Error("an instance without a secret must not accept registrations")
+	}
+	useConfig(t, Config{Secret: "s3cret"})
+	if !CheckSecret("s3cret") || CheckSecret("s3cre") || CheckSecret("") {
+		t.Error("CheckSecret() must accept only the configured secret")
+	}
+}
+
+func TestRelay(t *testing.T) {
+	agent := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get(SecretHeader) != "s3cret" {
+			w.WriteHeader(http.StatusUnauthorized)
+		}
+		switch r.URL.Path {
+		case "/api/v1/net/dns-lookup":
+			w.Header().Set("Content-Type", "application/json")
+			w.Write([]byte(`{"domain":"` + r.URL.Query().Get("domain") + `"}`))
+		default:
+			w.Write([]byte("<html>"))
+		}
+	}))
+	defer agent.Close()
+	useConfig(t, Config{Name: "primary", Secret: "s3cret"})
+	if err := Register("eu", agent.URL); err != nil {
+		t.Fatal(err)
+	}
+
+	result := Relay(context.Background(), "eu", "/api/v1/net/dns-lookup", "domain=example.com")
+	if result.Error != "" || result.StatusCode != http.StatusOK || string(result.Response) != `{"domain":"example.com"}` {
+		t.Errorf("Relay() = %+v, want the agent's JSON response", result)
+	}
+	if result := Relay(context.Background(), "eu", "/other", ""); result.Error != "eu returned a non-JSON response" {
+		t.Errorf("Relay() to a non-JSON endpoint = %+v", result)
+	}
+	if result := Relay(context.Background(), "us", "/api/v1/net/dns-lookup", ""); result.Error != "unknown vantage point" {
+		t.Errorf("Relay() to an unregistered vantage = %+v", result)
+	}
+}
+
+func TestRegisterWithPrimary(t *testing.T) {
+	primary := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v1/vantage/register" || r.Header.Get(SecretHeader) != "s3cret" {
+			w.WriteHeader(http.StatusUnauthorized)
+		}
+	}))
+	defer primary.Close()
+
+	useConfig(t, Config{Name: "eu", PrimaryURL: primary.URL, Secret: "s3cret"})
+	if err := register([]byte(`{}`)); err != nil {
+		t.Errorf("register() error = %v", err)
+	}
+	useConfig(t, Config{Name: "eu", PrimaryURL: primary.URL, Secret: "wrong"})
+	if err := register([]byte(`{}`)); err == nil {
+		t.Error("register() with the wrong secret succeeded")
+	}
+}
@@ -0,0 +1,191 @@
+package utils
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/vit0-9/utils_api/pkg/utils/cache"
+)
+
+// NVD answers are kept for a day, for the most recently looked up components.
+const (
+	nvdCacheTTL        = 24 * time.Hour
+	nvdCacheMaxEntries = 1000
+)
+
+// VulnerabilitySummary holds the known vulnerability count for a single detected component.
+type VulnerabilitySummary struct {
+	Source string   `json:"source"`          // "offline" or "nvd"
+	CPE    string   `json:"cpe"`             // Versioned CPE that was looked up
+	Count  int      `json:"count"`           // Number of known vulnerabilities
+	IDs    []string `json:"ids,omitempty"`   // CVE identifiers, when the source provides them
+	Error  string   `json:"error,omitempty"` // Lookup error for this component, if any
+}
+
+// offlineVulnEntry is a single record of the offline CVE summary dataset.
+// The dataset is a JSON object keyed by "vendor:product:version", e.g.
+// {"jquery:jquery:1.12.4": {"count": 4, "ids": ["CVE-2015-9251", ...]}}
+type offlineVulnEntry struct {
+	Count int      `json:"count"`
+	IDs   []string `json:"ids,omitempty"`
+}
+
+var (
+	offlineVulnData map[string]offlineVulnEntry
+	nvdAPIURL       string
+	nvdAPIKey       string
+	nvdCache        = cache.NewLRU(nvdCacheMaxEntries)
+)
+
+// ConfigureVulnerabilityLookup sets up the sources used for vulnerability hints.
+// datasetPath points to an offline CVE summary JSON file; apiURL enables NVD lookups
+// (e.g. https://services.nvd.nist.gov/rest/json/cves/2.0) for components missing from it.
+func ConfigureVulnerabilityLookup(datasetPath, apiURL, apiKey string) {
+	if datasetPath != "" {
+		fileData, err := os.ReadFile(datasetPath)
+		if err != nil {
+			log.Printf("ERROR: Could not read CVE summary dataset at %s: %v. Offline vulnerability hints will be disabled.", datasetPath, err)
+		} else {
+			var data map[string]offlineVulnEntry
+			if err := json.Unmarshal(fileData, &data); err != nil {
+				log.Printf("ERROR: Could not parse CVE summary dataset at %s: %v. Offline vulnerability hints will be disabled.", datasetPath, err)
+			} else {
+				offlineVulnData = make(map[string]offlineVulnEntry, len(data))
+				for key, entry := range data {
+					offlineVulnData[strings.ToLower(key)] = entry
+				}
+				log.Printf("Successfully loaded CVE summary dataset from %s (%d components)", datasetPath, len(offlineVulnData))
+			}
+		}
+	}
+
+	nvdAPIURL = apiURL
+	nvdAPIKey = apiKey
+	if nvdAPIURL != "" {
+		log.Printf("NVD vulnerability lookups enabled via %s", nvdAPIURL)
+	}
+}
+
+// VulnerabilityLookupAvailable reports whether any vulnerability source is configured.
+func VulnerabilityLookupAvailable() bool {
+	return len(offlineVulnData) > 0 || nvdAPIURL != ""
+}
+
+// versionedCPE fills the version field of a wappalyzer CPE 2.3 string.
+// It returns the CPE and the "vendor:product:version" key used by the offline dataset.
+func versionedCPE(cpe, version string) (string, string, bool) {
+	parts := strings.Split(cpe, ":")
+	if len(parts) < 6 || parts[0] != "cpe" || parts[1] != "2.3" {
+		return "", "", false
+	}
+	for len(parts) < 13 {
+		parts = append(parts, "*")
+	}
+	parts[5] = version
+	key := strings.ToLower(parts[3] + ":" + parts[4] + ":" + version)
+	return strings.Join(parts, ":"), key, true
+}
+
+// LookupVulnerabilities returns a vulnerability summary for a component detected with a CPE and version.
+// It returns nil when the component cannot be looked up (no CPE, no version, or no configured source).
+func LookupVulnerabilities(cpe, version string) *VulnerabilitySummary {
+	if cpe == "" || version == "" {
+		return nil
+	}
+	fullCPE, key, ok := versionedCPE(cpe, version)
+	if !ok {
+		return nil
+	}
+
+	if entry, found := offlineVulnData[key]; found {
+		return &VulnerabilitySummary{Source: "offline", CPE: fullCPE, Count: entry.Count, IDs: entry.IDs}
+	}
+	if nvdAPIURL == "" {
+		if len(offlineVulnData) > 0 {
+			// Absent from the offline dataset means no known vulnerabilities for this version.
+			return &VulnerabilitySummary{Source: "offline", CPE: fullCPE}
+		}
+		return nil
+	}
+
+	ctx := context.Background()
+	if cached, found, _ := nvdCache.Get(ctx, fullCPE); found {
+		var summary VulnerabilitySummary
+		if err := json.Unmarshal(cached, &summary); err == nil {
+			return &summary
+		}
+	}
+
+	summary := queryNVD(fullCPE)
+	if summary.Error == "" {
+		if encoded, err := json.Marshal(summary); err == nil {
+			nvdCache.Set(ctx, fullCPE, encoded, nvdCacheTTL)
+		}
+	}
+	return &summary
+}
+
+// nvdResponse covers the parts of the NVD CVE API 2.0 response we use.
+type nvdResponse struct {
+	TotalResults    int `json:"totalResults"`
+	Vulnerabilities []struct {
+		CVE struct {
+			ID string `json:"id"`
+		} `json:"cve"`
+	} `json:"vulnerabilities"`
+}
+
+// queryNVD asks the NVD CVE API for vulnerabilities matching a versioned CPE.
+func queryNVD(fullCPE string) VulnerabilitySummary {
+	initializeHTTPClient()
+	summary := VulnerabilitySummary{Source: "nvd", CPE: fullCPE}
+
+	reqURL := nvdAPIURL + "?cpeName=" + url.QueryEscape(fullCPE)
+	req, err := http.NewRequest("GET", reqURL, nil)
+	if err != nil {
+		summary.Error = fmt.Sprintf("failed to create NVD request: %v", err)
+		return summary
+	}
+	req.Header.Set("Accept", "application/json")
+	if nvdAPIKey != "" {
+		req.Header.Set("apiKey", nvdAPIKey)
+	}
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		summary.Error = fmt.Sprintf("NVD request failed: %v", err)
+		return summary
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		summary.Error = fmt.Sprintf("NVD returned status %s", resp.Status)
+		return summary
+	}
+
+	bodyBytes, err := io.ReadAll(resp.Body)
+	if err != nil {
+		summary.Error = fmt.Sprintf("failed to read NVD response: %v", err)
+		return summary
+	}
+
+	var parsed nvdResponse
+	if err := json.Unmarshal(bodyBytes, &parsed); err != nil {
+		summary.Error = fmt.Sprintf("failed to parse NVD response: %v", err)
+		return summary
+	}
+
+	summary.Count = parsed.TotalResults
+	for _, v := range parsed.Vulnerabilities {
+		summary.IDs = append(summary.IDs, v.CVE.ID)
+	}
+	return summary
+}
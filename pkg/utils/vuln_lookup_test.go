@@ -0,0 +1,110 @@
+package utils
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+
+	"github.com/vit0-9/utils_api/pkg/utils/cache"
+)
+
+func TestVersionedCPE(t *testing.T) {
+	tests := []struct {
+		cpe, version, want, key string
+		ok                      bool
+	}{
+		{"cpe:2.3:a:jquery:jquery:*:*:*:*:*:*:*:*", "1.12.4", "cpe:2.3:a:jquery:jquery:1.12.4:*:*:*:*:*:*:*", "jquery:jquery:1.12.4", true},
+		{"cpe:2.3:a:Nginx:Nginx:*", "1.25.0", "cpe:2.3:a:Nginx:Nginx:1.25.0:*:*:*:*:*:*:*", "nginx:nginx:1.25.0", true}, // Short CPEs are padded
+		{"cpe:2.2:a:jquery:jquery:*:*", "1.0", "", "", false},
+		{"cpe:2.3:a:jquery", "1.0", "", "", false},
+	}
+	for _, tt := range tests {
+		got, key, ok := versionedCPE(tt.cpe, tt.version)
+		if got != tt.want || key != tt.key || ok != tt.ok {
+			t.Errorf("versionedCPE(%q, %q) = %q, %q, %v; want %q, %q, %v", tt.cpe, tt.version, got, key, ok, tt.want, tt.key, tt.ok)
+		}
+	}
+}
+
+// configureVulnLookupForTest points the lookup at a dataset and NVD server, restoring the
+// previous sources when the test ends.
+func configureVulnLookupForTest(t *testing.T, dataset, apiURL string) {
+	t.Helper()
+	previousData, previousURL, previousKey, previousCache := offlineVulnData, nvdAPIURL, nvdAPIKey, nvdCache
+	t.Cleanup(func() {
+		offlineVulnData, nvdAPIURL, nvdAPIKey, nvdCache = previousData, previousURL, previousKey, previousCache
+	})
+	offlineVulnData, nvdCache = nil, cache.NewLRU(nvdCacheMaxEntries)
+	path := ""
+	if dataset != "" {
+		path = filepath.Join(t.TempDir(), "cves.json")
+		if err := os.WriteFile(path, []byte(dataset), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	ConfigureVulnerabilityLookup(path, apiURL, "test-key")
+}
+
+func TestLookupVulnerabilitiesOffline(t *testing.T) {
+	configureVulnLookupForTest(t, `{"JQuery:jquery:1.12.4": {"count": 2, "ids": ["CVE-2015-9251", "CVE-2019-11358"]}}`, "")
+
+	got := LookupVulnerabilities("cpe:2.3:a:jquery:jquery:*:*:*:*:*:*:*:*", "1.12.4")
+	if got == nil || got.Source != "offline" || got.Count != 2 || len(got.IDs) != 2 {
+		t.Errorf("listed version = %+v", got)
+	}
+	// Absent from the dataset: no known vulnerabilities
+	got = LookupVulnerabilities("cpe:2.3:a:jquery:jquery:*:*:*:*:*:*:*:*", "3.7.1")
+	if got == nil || got.Source != "offline" || got.Count != 0 {
+		t.Errorf("unlisted version = %+v", got)
+	}
+	for _, missing := range [][2]string{{"", "1.0"}, {"cpe:2.3:a:jquery:jquery:*", ""}, {"not a cpe", "1.0"}} {
+		if got := LookupVulnerabilities(missing[0], missing[1]); got != nil {
+			t.Errorf("LookupVulnerabilities(%q, %q) = %+v, want nil", missing[0], missing[1], got)
+		}
+	}
+}
+
+func TestLookupVulnerabilitiesNVD(t *testing.T) {
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		if r.Header.Get("apiKey") != "test-key" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		switch r.URL.Query().Get("cpeName") {
+		case "cpe:2.3:a:jquery:jquery:1.12.4:*:*:*:*:*:*:*":
+			fmt.Fprint(w, `{"totalResults": 2, "vulnerabilities": [{"cve": {"id": "CVE-2015-9251"}}, {"cve": {"id": "CVE-2019-11358"}}]}`)
+		case "cpe:2.3:a:jquery:jquery:0.0.1:*:*:*:*:*:*:*":
+			fmt.Fprint(w, `not json`)
+		default:
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer server.Close()
+	configureVulnLookupForTest(t, "", server.URL)
+
+	for range 2 {
+		got := LookupVulnerabilities("cpe:2.3:a:jquery:jquery:*:*:*:*:*:*:*:*", "1.12.4")
+		if got == nil || got.Source != "nvd" || got.Count != 2 || len(got.IDs) != 2 || got.IDs[0] != "CVE-2015-9251" || got.Error != "" {
+			t.Errorf("NVD lookup = %+v", got)
+		}
+	}
+	if requests.Load() != 1 {
+		t.Errorf("NVD was queried %d times, want 1 with the answer cached", requests.Load())
+	}
+
+	// Failures are reported per component and not cached
+	for _, version := range []string{"0.0.1", "2.0.0", "2.0.0"} {
+		if got := LookupVulnerabilities("cpe:2.3:a:jquery:jquery:*:*:*:*:*:*:*:*", version); got == nil || got.Error == "" {
+			t.Errorf("version %s: %+v, want an error", version, got)
+		}
+	}
+	if requests.Load() != 4 {
+		t.Errorf("NVD was queried %d times, want failed lookups retried", requests.Load())
+	}
+}
@@ -0,0 +1,190 @@
+package utils
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+// Zone transfer limits. A transfer is only read far enough to prove it is allowed and to
+// return a sample; large zones are cut off rather than downloaded in full.
+const (
+	zoneTransferSampleSize = 20
+	zoneTransferMaxRecords = 5000
+	zoneTransferTimeout    = 20 * time.Second
+)
+
+// zoneTransferPort is the nameserver port AXFR queries go to; tests point it at a fake server.
+var zoneTransferPort = "53"
+
+// ZoneTransferRecord is one record received in a zone transfer.
+type ZoneTransferRecord struct {
+	Name string `json:"name"`
+	DNSRecord
+}
+
+// ZoneTransferServer is the AXFR result of one authoritative nameserver.
+type ZoneTransferServer struct {
+	Nameserver      string               `json:"nameserver"`
+	Address         string               `json:"address,omitempty"` // The address that was connected to
+	TransferAllowed bool                 `json:"transfer_allowed"`
+	RecordCount     int                  `json:"record_count,omitempty"`
+	Truncated       bool                 `json:"truncated,omitempty"` // The transfer was cut off at the record limit
+	Sample          []ZoneTransferRecord `json:"sample,omitempty"`
+	Error           string               `json:"error,omitempty"` // Why the transfer failed or was refused
+}
+
+// ZoneTransferReport lists which of a domain's nameservers allow zone transfers.
+type ZoneTransferReport struct {
+	Nameservers []ZoneTransferServer `json:"nameservers"`
+	Exposed     bool                 `json:"exposed"` // At least one nameserver allowed the transfer
+	Error       string               `json:"error,omitempty"`
+}
+
+// CheckZoneTransfer attempts an AXFR of the domain against each of its authoritative
+// nameservers (found through the resolver) and reports which of them allow it.
+func CheckZoneTransfer(ctx context.Context, resolver Resolver, domain string) *ZoneTransferReport {
+	report := &ZoneTransferReport{Nameservers: []ZoneTransferServer{}}
+	zone := strings.ToLower(strings.TrimSuffix(domain, ".")) + "."
+
+	answers, err := resolver.Query(ctx, zone, dnsmessage.TypeNS)
+	if err != nil {
+		report.Error = fmt.Sprintf("failed to look up nameservers: %v", err)
+		return report
+	}
+	var nameservers []string
+	for _, answer := range answers {
+		if ns, ok := answer.Body.(*dnsmessage.NSResource); ok {
+			nameservers = append(nameservers, strings.ToLower(ns.NS.String()))
+		}
+	}
+	if len(nameservers) == 0 {
+		report.Error = "the domain has no NS records; is it a zone apex?"
+		return report
+	}
+	sort.Strings(nameservers)
+
+	report.Nameservers = make([]ZoneTransferServer, len(nameservers))
+	var wg sync.WaitGroup
+	for i, nameserver := range nameservers {
+		wg.Add(1)
+		go func(i int, nameserver string) {
+			defer wg.Done()
+			report.Nameservers[i] = transferZone(ctx, zone, nameserver)
+		}(i, nameserver)
+	}
+	wg.Wait()
+
+	for _, server := range report.Nameservers {
+		if server.TransferAllowed {
+			report.Exposed = true
+		}
+	}
+	return report
+}
+
+// transferZone requests an AXFR of zone from one nameserver over TCP.
+func transferZone(ctx context.Context, zone, nameserver string) ZoneTransferServer {
+	result := ZoneTransferServer{Nameserver: strings.TrimSuffix(nameserver, ".")}
+	ctx, cancel := context.WithTimeout(ctx, zoneTransferTimeout)
+	defer cancel()
+
+	qname, err := dnsmessage.NewName(zone)
+	if err != nil {
+		result.Error = fmt.Sprintf("invalid zone name: %v", err)
+		return result
+	}
+	var idBytes [2]byte
+	rand.Read(idBytes[:])
+	query := dnsmessage.Message{
+		Header:    dnsmessage.Header{ID: binary.BigEndian.Uint16(idBytes[:])},
+		Questions: []dnsmessage.Question{{Name: qname, Type: dnsmessage.TypeAXFR, Class: dnsmessage.ClassINET}},
+	}
+	packed, err := query.Pack()
+	if err != nil {
+		result.Error = fmt.Sprintf("failed to build query: %v", err)
+		return result
+	}
+
+	dialStart := time.Now()
+	conn, err := PolicyDialContext(&net.Dialer{Timeout: 5 * time.Second})(ctx, "tcp", net.JoinHostPort(result.Nameserver, zoneTransferPort))
+	TimingRecorderFrom(ctx).AddConnect(time.Since(dialStart))
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	defer conn.Close()
+	result.Address = conn.RemoteAddr().String()
+	conn.SetDeadline(connDeadline(ctx))
+
+	framed := binary.BigEndian.AppendUint16(nil, uint16(len(packed)))
+	if _, err := conn.Write(append(framed, packed...)); err != nil {
+		result.Error = err.Error()
+		return result
+	}
+
+	soaSeen := 0
+	for soaSeen < 2 {
+		var length [2]byte
+		if _, err := io.ReadFull(conn, length[:]); err != nil {
+			if result.RecordCount == 0 {
+				result.Error = "the server closed the connection without answering (transfer refused)"
+			} else {
+				result.Error = fmt.Sprintf("transfer ended early: %v", err)
+			}
+			return result
+		}
+		raw := make([]byte, binary.BigEndian.Uint16(length[:]))
+		if _, err := io.ReadFull(conn, raw); err != nil {
+			result.Error = fmt.Sprintf("truncated response: %v", err)
+			return result
+		}
+		var response dnsmessage.Message
+		if err := response.Unpack(raw); err != nil {
+			result.Error = fmt.Sprintf("invalid response: %v", err)
+			return result
+		}
+		if response.ID != query.ID {
+			result.Error = "mismatched response ID"
+			return result
+		}
+		if response.RCode != dnsmessage.RCodeSuccess {
+			result.Error = "transfer refused: server returned " + strings.TrimPrefix(response.RCode.String(), "RCode")
+			return result
+		}
+		if result.RecordCount == 0 && (len(response.Answers) == 0 || response.Answers[0].Header.Type != dnsmessage.TypeSOA) {
+			result.Error = "transfer refused: the response did not start with the zone's SOA record"
+			return result
+		}
+
+		for _, answer := range response.Answers {
+			if answer.Header.Type == dnsmessage.TypeSOA {
+				if soaSeen++; soaSeen == 2 {
+					break // The closing SOA repeats the opening one
+				}
+			}
+			result.RecordCount++
+			if len(result.Sample) < zoneTransferSampleSize {
+				typeName := strings.TrimPrefix(answer.Header.Type.String(), "Type")
+				if record, ok := dnsRecordFromResource(typeName, answer); ok {
+					result.Sample = append(result.Sample, ZoneTransferRecord{Name: answer.Header.Name.String(), DNSRecord: record})
+				}
+			}
+		}
+		result.TransferAllowed = true
+		if result.RecordCount >= zoneTransferMaxRecords && soaSeen < 2 {
+			result.Truncated = true
+			break
+		}
+	}
+	return result
+}
@@ -0,0 +1,100 @@
+package utils
+
+import (
+	"context"
+	"encoding/binary"
+	"io"
+	"net"
+	"testing"
+
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+// serveAXFR accepts one connection on a local listener and answers its AXFR query with
+// the given messages, each built from the query's ID and question.
+func serveAXFR(t *testing.T, reply func(query dnsmessage.Message) []dnsmessage.Message) string {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { listener.Close() })
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		var length [2]byte
+		if _, err := io.ReadFull(conn, length[:]); err != nil {
+			return
+		}
+		raw := make([]byte, binary.BigEndian.Uint16(length[:]))
+		if _, err := io.ReadFull(conn, raw); err != nil {
+			return
+		}
+		var query dnsmessage.Message
+		if err := query.Unpack(raw); err != nil {
+			return
+		}
+		for _, message := range reply(query) {
+			packed, err := message.Pack()
+			if err != nil {
+				return
+			}
+			conn.Write(append(binary.BigEndian.AppendUint16(nil, uint16(len(packed))), packed...))
+		}
+	}()
+	_, port, _ := net.SplitHostPort(listener.Addr().String())
+	return port
+}
+
+func TestTransferZone(t *testing.T) {
+	zone := dnsmessage.MustNewName("example.com.")
+	soa := dnsmessage.Resource{
+		Header: dnsmessage.ResourceHeader{Name: zone, Type: dnsmessage.TypeSOA, Class: dnsmessage.ClassINET, TTL: 3600},
+		Body:   &dnsmessage.SOAResource{NS: dnsmessage.MustNewName("ns1.example.com."), MBox: dnsmessage.MustNewName("hostmaster.example.com."), Serial: 7},
+	}
+	www := dnsmessage.Resource{
+		Header: dnsmessage.ResourceHeader{Name: dnsmessage.MustNewName("www.example.com."), Type: dnsmessage.TypeA, Class: dnsmessage.ClassINET, TTL: 300},
+		Body:   &dnsmessage.AResource{A: [4]byte{192, 0, 2, 1}},
+	}
+	response := func(query dnsmessage.Message, rcode dnsmessage.RCode, answers ...dnsmessage.Resource) dnsmessage.Message {
+		return dnsmessage.Message{
+			Header:    dnsmessage.Header{ID: query.ID, Response: true, Authoritative: true, RCode: rcode},
+			Questions: query.Questions,
+			Answers:   answers,
+		}
+	}
+
+	t.Run("allowed", func(t *testing.T) {
+		zoneTransferPort = serveAXFR(t, func(query dnsmessage.Message) []dnsmessage.Message {
+			// Split over two messages, as servers do for larger zones.
+			return []dnsmessage.Message{response(query, dnsmessage.RCodeSuccess, soa, www), response(query, dnsmessage.RCodeSuccess, soa)}
+		})
+		defer func() { zoneTransferPort = "53" }()
+
+		result := transferZone(context.Background(), "example.com.", "127.0.0.1")
+		if !result.TransferAllowed || result.Error != "" {
+			t.Fatalf("transferZone() = %+v, want an allowed transfer", result)
+		}
+		if result.RecordCount != 2 || len(result.Sample) != 2 {
+			t.Fatalf("transferZone() got %d records and %d samples, want 2 of each", result.RecordCount, len(result.Sample))
+		}
+		if sample := result.Sample[1]; sample.Name != "www.example.com." || sample.Type != "A" || sample.Value != "192.0.2.1" {
+			t.Errorf("sample[1] = %+v, want the www A record", sample)
+		}
+	})
+
+	t.Run("refused", func(t *testing.T) {
+		zoneTransferPort = serveAXFR(t, func(query dnsmessage.Message) []dnsmessage.Message {
+			return []dnsmessage.Message{response(query, dnsmessage.RCodeRefused)}
+		})
+		defer func() { zoneTransferPort = "53" }()
+
+		result := transferZone(context.Background(), "example.com.", "127.0.0.1")
+		if result.TransferAllowed || result.Error != "transfer refused: server returned Refused" {
+			t.Errorf("transferZone() = %+v, want a refused transfer", result)
+		}
+	})
+}